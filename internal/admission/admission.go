@@ -0,0 +1,180 @@
+// Package admission checks the operations of the action runner against
+// organization policies, before anything runs.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/runner"
+)
+
+// Credential describes where a credential of the bundle is given to the
+// invocation image. Its value is never part of the input.
+type Credential struct {
+	Path                string `json:"path,omitempty"`
+	EnvironmentVariable string `json:"env,omitempty"`
+}
+
+// Input is the document submitted to the policies, the input of the policy
+// package completed with where the credentials are given to the invocation
+// image.
+type Input struct {
+	policy.Input
+	Credentials map[string]Credential `json:"credentials"`
+}
+
+// NewInput builds the input of the policies from an attempt of the runner,
+// with the parameters resolved for the operation, in the given environment.
+// The values of the sensitive parameters are masked.
+func NewInput(event runner.HookEvent, env policy.Environment) (Input, error) {
+	sensitive, err := cnab.SensitiveParameters(event.Bundle)
+	if err != nil {
+		return Input{}, err
+	}
+	var installation string
+	parameters := map[string]interface{}{}
+	if event.Claim != nil {
+		installation = event.Claim.Name
+		parameters = event.Claim.Parameters
+	}
+	if op := event.Operation; op != nil {
+		installation = op.Installation
+		parameters = op.Parameters
+	}
+	input := Input{
+		Input:       policy.NewInput(event.Action, installation, event.Bundle, parameters, env, sensitive...),
+		Credentials: map[string]Credential{},
+	}
+	for name, location := range event.Bundle.Credentials {
+		input.Credentials[name] = Credential{Path: location.Path, EnvironmentVariable: location.EnvironmentVariable}
+	}
+	return input, nil
+}
+
+// Denial is a reason why a policy denies an operation.
+type Denial struct {
+	Message string `json:"msg"`
+	// Path is the JSON path of the member of the input the denial is about,
+	// like "$.parameters.port". It is empty if the policy does not tell.
+	Path string `json:"path,omitempty"`
+}
+
+func (d Denial) String() string {
+	if d.Path == "" {
+		return d.Message
+	}
+	return d.Path + ": " + d.Message
+}
+
+// DeniedError is returned when policies deny an operation.
+type DeniedError struct {
+	Denials []Denial
+}
+
+func (e *DeniedError) Error() string {
+	reasons := make([]string, len(e.Denials))
+	for i, d := range e.Denials {
+		reasons[i] = d.String()
+	}
+	return fmt.Sprintf("denied by policy:\n- %s", strings.Join(reasons, "\n- "))
+}
+
+// PolicyEvaluator evaluates organization policies against an operation.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, input Input) ([]Denial, error)
+}
+
+// Policy adapts an evaluator of the policy package. The Rego policies are
+// evaluated with the whole input and their deny reasons can tell the path of
+// the offending member. The violations of other evaluators, like the
+// vulnerability scanner, are denials without path.
+func Policy(evaluator policy.Evaluator) PolicyEvaluator {
+	if rego, ok := evaluator.(*policy.Rego); ok {
+		return regoEvaluator{rego: rego}
+	}
+	return policyEvaluator{evaluator: evaluator}
+}
+
+type policyEvaluator struct {
+	evaluator policy.Evaluator
+}
+
+func (e policyEvaluator) Evaluate(ctx context.Context, input Input) ([]Denial, error) {
+	decision, err := e.evaluator.Evaluate(ctx, input.Input)
+	if err != nil {
+		return nil, err
+	}
+	var denials []Denial
+	for _, violation := range decision.Violations {
+		denials = append(denials, Denial{Message: violation})
+	}
+	return denials, nil
+}
+
+// Check evaluates the input with all the evaluators and returns a
+// DeniedError if any of them denies the operation.
+func Check(ctx context.Context, input Input, evaluators ...PolicyEvaluator) error {
+	var denials []Denial
+	for _, e := range evaluators {
+		d, err := e.Evaluate(ctx, input)
+		if err != nil {
+			return err
+		}
+		denials = append(denials, d...)
+	}
+	if len(denials) == 0 {
+		return nil
+	}
+	sortDenials(denials)
+	return &DeniedError{Denials: denials}
+}
+
+// Hook returns a runner hook vetoing the attempts denied by the evaluators,
+// in the given environment.
+func Hook(env policy.Environment, evaluators ...PolicyEvaluator) runner.Hook {
+	return runner.Hook{
+		BeforeAction: func(ctx context.Context, event runner.HookEvent) error {
+			input, err := NewInput(event, env)
+			if err != nil {
+				return err
+			}
+			return Check(ctx, input, evaluators...)
+		},
+	}
+}
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// JSONPath builds the JSON path of a member of the input from its keys and
+// indexes, like "$.bundle.images.web" or `$.parameters["web.port"]`.
+func JSONPath(segments ...interface{}) string {
+	path := "$"
+	for _, s := range segments {
+		switch s := s.(type) {
+		case string:
+			if identifier.MatchString(s) {
+				path += "." + s
+			} else {
+				path += fmt.Sprintf("[%q]", s)
+			}
+		default:
+			path += fmt.Sprintf("[%v]", s)
+		}
+	}
+	return path
+}
+
+func sortDenials(denials []Denial) {
+	sort.SliceStable(denials, func(i, j int) bool {
+		if denials[i].Path != denials[j].Path {
+			return denials[i].Path < denials[j].Path
+		}
+		return denials[i].Message < denials[j].Message
+	})
+}
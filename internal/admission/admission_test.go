@@ -0,0 +1,193 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/runner"
+	"github.com/docker/app/internal/store"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+type staticEvaluator []Denial
+
+func (s staticEvaluator) Evaluate(context.Context, Input) ([]Denial, error) {
+	return s, nil
+}
+
+func testBundle() *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:             "my-app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "my-app:1.0.0"}}},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"password": {DataType: "string", Destination: &bundle.Location{EnvironmentVariable: "PASSWORD"}},
+			"port":     {DataType: "int", Destination: &bundle.Location{EnvironmentVariable: "PORT"}},
+		},
+		Credentials: map[string]bundle.Location{
+			"docker.context": {Path: "/cnab/app/context.json"},
+			"token":          {EnvironmentVariable: "TOKEN"},
+		},
+		Custom: map[string]interface{}{
+			cnab.SensitiveParametersExtensionKey: []interface{}{"password"},
+		},
+	}
+}
+
+func TestNewInput(t *testing.T) {
+	event := runner.HookEvent{
+		Action: claim.ActionInstall,
+		Bundle: testBundle(),
+		Operation: &driver.Operation{
+			Installation: "my-installation",
+			Parameters:   map[string]interface{}{"password": "secret", "port": 8080},
+		},
+	}
+	input, err := NewInput(event, policy.Environment{TargetContext: "prod"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(input.Installation, "my-installation"))
+	assert.Check(t, is.Equal(input.Environment.TargetContext, "prod"))
+	assert.Check(t, is.DeepEqual(input.Parameters, map[string]interface{}{"password": "******", "port": 8080}))
+	assert.Check(t, is.DeepEqual(input.Credentials, map[string]Credential{
+		"docker.context": {Path: "/cnab/app/context.json"},
+		"token":          {EnvironmentVariable: "TOKEN"},
+	}))
+}
+
+func TestCheck(t *testing.T) {
+	input := Input{Input: policy.Input{Bundle: &bundle.Bundle{}}}
+	assert.NilError(t, Check(context.Background(), input, staticEvaluator(nil)))
+	err := Check(context.Background(), input,
+		staticEvaluator{{Message: "no path"}},
+		staticEvaluator{{Message: "latest tag", Path: "$.bundle.images.web"}},
+		Policy(policyEvaluatorFunc(func(ctx context.Context, input policy.Input) (policy.Decision, error) {
+			return policy.Decision{Violations: []string{"vulnerable image"}}, nil
+		})),
+	)
+	assert.Error(t, err, "denied by policy:\n- no path\n- vulnerable image\n- $.bundle.images.web: latest tag")
+}
+
+type policyEvaluatorFunc func(ctx context.Context, input policy.Input) (policy.Decision, error)
+
+func (f policyEvaluatorFunc) Evaluate(ctx context.Context, input policy.Input) (policy.Decision, error) {
+	return f(ctx, input)
+}
+
+func TestJSONPath(t *testing.T) {
+	assert.Check(t, is.Equal(JSONPath(), "$"))
+	assert.Check(t, is.Equal(JSONPath("bundle", "invocationImages", float64(0), "image"), "$.bundle.invocationImages[0].image"))
+	assert.Check(t, is.Equal(JSONPath("credentials", "docker.context"), `$.credentials["docker.context"]`))
+}
+
+func TestParseDenials(t *testing.T) {
+	denials, err := parseDenials([]interface{}{
+		[]interface{}{
+			"a message",
+			map[string]interface{}{"msg": "not allowed", "path": []interface{}{"parameters", "port"}},
+			map[string]interface{}{"msg": "missing token", "path": "$.credentials.token"},
+		},
+		map[string]interface{}{"from an object rule": []interface{}{"bundle", "images", "my.web"}},
+		nil,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, denials, []Denial{
+		{Message: "a message"},
+		{Message: "from an object rule", Path: `$.bundle.images["my.web"]`},
+		{Message: "missing token", Path: "$.credentials.token"},
+		{Message: "not allowed", Path: "$.parameters.port"},
+	})
+
+	_, err = parseDenials([]interface{}{true})
+	assert.Error(t, err, "unexpected policy evaluation result true")
+	_, err = parseDenials([]interface{}{[]interface{}{map[string]interface{}{"path": "$"}}})
+	assert.Error(t, err, "invalid deny reason map[path:$]: missing msg")
+	_, err = parseDenials([]interface{}{[]interface{}{map[string]interface{}{"msg": "m", "path": 1.0}}})
+	assert.Error(t, err, "invalid deny path 1")
+}
+
+func TestPolicyEvaluatesRego(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake opa binary is a shell script")
+	}
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("opa", `#!/bin/sh
+cat >/dev/null
+echo '{"result":[{"expressions":[{"value":[{"msg":"not allowed","path":["credentials","token"]}]}]}]}'
+`, fs.WithMode(0755)))
+	defer dir.Remove()
+
+	// The Rego deny reasons keep their path
+	denials, err := Policy(&policy.Rego{Binary: dir.Join("opa")}).Evaluate(context.Background(), Input{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, denials, []Denial{{Message: "not allowed", Path: "$.credentials.token"}})
+}
+
+func TestHookVetoesDeniedActions(t *testing.T) {
+	installation, err := store.NewInstallation("my-installation", "my-app:1.0.0")
+	assert.NilError(t, err)
+	installation.Bundle = testBundle()
+	installation.Parameters = map[string]interface{}{"password": "secret", "port": 8080}
+
+	var inputs []Input
+	evaluator := evaluatorFunc(func(ctx context.Context, input Input) ([]Denial, error) {
+		inputs = append(inputs, input)
+		return []Denial{{Message: "tokens are not allowed", Path: "$.credentials.token"}}, nil
+	})
+	d := fake.New()
+	r := &runner.Runner{
+		Installations: store.NewMemoryInstallationStore(),
+		Driver:        d,
+		Hooks:         []runner.Hook{Hook(policy.Environment{}, evaluator)},
+	}
+	err = r.Run(context.Background(), installation, claim.ActionInstall, testCredentials, ioutil.Discard)
+	assert.Check(t, runner.IsVetoed(err))
+	assert.Check(t, is.ErrorContains(err, "$.credentials.token: tokens are not allowed"))
+	assert.Check(t, is.Len(d.Operations(), 0))
+	assert.Assert(t, is.Len(inputs, 1))
+	assert.Check(t, is.Equal(inputs[0].Installation, "my-installation"))
+	assert.Check(t, is.Equal(inputs[0].Parameters["password"], "******"))
+	assert.Check(t, is.DeepEqual(inputs[0].Credentials["token"], Credential{EnvironmentVariable: "TOKEN"}))
+}
+
+func TestHookAllowsActions(t *testing.T) {
+	installation, err := store.NewInstallation("my-installation", "my-app:1.0.0")
+	assert.NilError(t, err)
+	installation.Bundle = testBundle()
+	installation.Parameters = map[string]interface{}{"password": "secret", "port": 8080}
+
+	d := fake.New()
+	r := &runner.Runner{
+		Installations: store.NewMemoryInstallationStore(),
+		Driver:        d,
+		Hooks:         []runner.Hook{Hook(policy.Environment{}, staticEvaluator(nil))},
+	}
+	assert.NilError(t, r.Run(context.Background(), installation, claim.ActionInstall, testCredentials, ioutil.Discard))
+	assert.Check(t, is.Len(d.Operations(), 1))
+}
+
+func TestHookFailsOnEvaluationError(t *testing.T) {
+	evaluator := evaluatorFunc(func(ctx context.Context, input Input) ([]Denial, error) {
+		return nil, errors.New("opa not found")
+	})
+	err := Hook(policy.Environment{}, evaluator).BeforeAction(context.Background(), runner.HookEvent{Bundle: testBundle()})
+	assert.Error(t, err, "opa not found")
+}
+
+var testCredentials = credentials.Set{"docker.context": "{}", "token": "s3cr3t"}
+
+type evaluatorFunc func(ctx context.Context, input Input) ([]Denial, error)
+
+func (f evaluatorFunc) Evaluate(ctx context.Context, input Input) ([]Denial, error) {
+	return f(ctx, input)
+}
@@ -0,0 +1,97 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/app/internal/policy"
+	"github.com/pkg/errors"
+)
+
+// regoEvaluator evaluates Rego policies with the whole input, credentials
+// included. The query, policy.DefaultRegoQuery by default, gives the deny
+// reasons, either as messages or as objects with a "msg" and the "path" of
+// the offending member of the input, for instance:
+//
+//	package docker.app.admission
+//
+//	deny[{"msg": msg, "path": ["parameters", name]}] {
+//		input.parameters[name] == "latest"
+//		msg := "floating tags are not allowed"
+//	}
+//
+// A path is either a JSON path string or an array of keys and indexes.
+type regoEvaluator struct {
+	rego *policy.Rego
+}
+
+// Evaluate runs the Rego policies with the input document and collects the
+// resulting deny reasons.
+func (r regoEvaluator) Evaluate(ctx context.Context, input Input) ([]Denial, error) {
+	values, err := r.rego.Eval(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return parseDenials(values)
+}
+
+// parseDenials collects the deny reasons of the query values, which are
+// either arrays or sets of reasons.
+func parseDenials(values []interface{}) ([]Denial, error) {
+	var denials []Denial
+	for _, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, reason := range v {
+				d, err := parseDenial(reason)
+				if err != nil {
+					return nil, err
+				}
+				denials = append(denials, d)
+			}
+		case map[string]interface{}:
+			// deny[msg] = path
+			for msg, path := range v {
+				p, err := parsePath(path)
+				if err != nil {
+					return nil, err
+				}
+				denials = append(denials, Denial{Message: msg, Path: p})
+			}
+		case nil:
+		default:
+			return nil, errors.Errorf("unexpected policy evaluation result %v", v)
+		}
+	}
+	sortDenials(denials)
+	return denials, nil
+}
+
+func parseDenial(reason interface{}) (Denial, error) {
+	obj, ok := reason.(map[string]interface{})
+	if !ok {
+		return Denial{Message: fmt.Sprint(reason)}, nil
+	}
+	msg, ok := obj["msg"].(string)
+	if !ok {
+		return Denial{}, errors.Errorf("invalid deny reason %v: missing msg", reason)
+	}
+	path, err := parsePath(obj["path"])
+	if err != nil {
+		return Denial{}, err
+	}
+	return Denial{Message: msg, Path: path}, nil
+}
+
+func parsePath(path interface{}) (string, error) {
+	switch p := path.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return p, nil
+	case []interface{}:
+		return JSONPath(p...), nil
+	default:
+		return "", errors.Errorf("invalid deny path %v", p)
+	}
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	"github.com/docker/go/canonical/json"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// Record describes a lifecycle operation run on an installation.
+type Record struct {
+	Time          time.Time `json:"time"`
+	User          string    `json:"user"`
+	Host          string    `json:"host,omitempty"`
+	Action        string    `json:"action"`
+	Installation  string    `json:"installation"`
+	Context       string    `json:"context,omitempty"`
+	Bundle        string    `json:"bundle,omitempty"`
+	BundleVersion string    `json:"bundleVersion,omitempty"`
+	BundleDigest  string    `json:"bundleDigest,omitempty"`
+	Reference     string    `json:"reference,omitempty"`
+	Status        string    `json:"status"`
+	Message       string    `json:"message,omitempty"`
+}
+
+// NewRecord creates a record of an action run by the current user with the
+// given bundle.
+func NewRecord(action, installation string, bndl *bundle.Bundle) Record {
+	r := Record{
+		Time:         time.Now().UTC(),
+		User:         currentUser(),
+		Action:       action,
+		Installation: installation,
+	}
+	r.Host, _ = os.Hostname()
+	if bndl != nil {
+		r.Bundle = bndl.Name
+		r.BundleVersion = bndl.Version
+		if d, err := bundleDigest(bndl); err == nil {
+			r.BundleDigest = d.String()
+		}
+	}
+	return r
+}
+
+func bundleDigest(bndl *bundle.Bundle) (digest.Digest, error) {
+	data, err := json.MarshalCanonical(bndl)
+	if err != nil {
+		return "", err
+	}
+	config, err := checksum.FromEnv()
+	if err != nil {
+		return "", err
+	}
+	return config.FromBytes(data)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Sink persists audit records.
+type Sink interface {
+	Write(r Record) error
+}
+
+// Logger sends records to all its sinks.
+type Logger struct {
+	sinks []Sink
+}
+
+// NewLogger returns a logger writing to all the given sinks.
+func NewLogger(sinks ...Sink) *Logger {
+	return &Logger{sinks: sinks}
+}
+
+// Enabled returns true if the logger has at least one sink.
+func (l *Logger) Enabled() bool {
+	return l != nil && len(l.sinks) > 0
+}
+
+// Log writes the record to every sink, even if some of them fail.
+func (l *Logger) Log(r Record) error {
+	if l == nil {
+		return nil
+	}
+	var errs []string
+	for _, s := range l.sinks {
+		if err := s.Write(r); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return &logError{errs: errs}
+	}
+	return nil
+}
+
+type logError struct {
+	errs []string
+}
+
+func (e *logError) Error() string {
+	return "failed to write audit record: " + strings.Join(e.errs, ", ")
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+type failingSink struct{}
+
+func (failingSink) Write(Record) error { return errors.New("boom") }
+
+func TestNewRecord(t *testing.T) {
+	r := NewRecord("install", "myinstallation", &bundle.Bundle{Name: "myapp", Version: "1.0.0"})
+	assert.Equal(t, r.Bundle, "myapp")
+	assert.Equal(t, r.BundleVersion, "1.0.0")
+	assert.Assert(t, strings.HasPrefix(r.BundleDigest, "sha256:"))
+	assert.Assert(t, r.User != "")
+
+	// the digest only depends on the bundle content
+	other := NewRecord("upgrade", "other", &bundle.Bundle{Name: "myapp", Version: "1.0.0"})
+	assert.Equal(t, r.BundleDigest, other.BundleDigest)
+}
+
+func TestFileSink(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	logger := NewLogger(NewFileSink(dir.Join("audit.log")))
+	assert.Assert(t, logger.Enabled())
+	assert.NilError(t, logger.Log(Record{Action: "install", Installation: "first"}))
+	assert.NilError(t, logger.Log(Record{Action: "uninstall", Installation: "first"}))
+
+	st, err := os.Stat(dir.Join("audit.log"))
+	assert.NilError(t, err)
+	assert.Equal(t, st.Mode().Perm(), os.FileMode(0600))
+	data, err := ioutil.ReadFile(dir.Join("audit.log"))
+	assert.NilError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Assert(t, is.Len(lines, 2))
+	var r Record
+	assert.NilError(t, json.Unmarshal([]byte(lines[1]), &r))
+	assert.Equal(t, r.Action, "uninstall")
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received Record
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+	sink := NewHTTPSink(server.URL, nil)
+	assert.Equal(t, sink.client.Timeout, DefaultHTTPTimeout)
+	assert.NilError(t, sink.Write(Record{Action: "upgrade"}))
+	assert.Equal(t, received.Action, "upgrade")
+}
+
+func TestLoggerWritesToAllSinks(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	logger := NewLogger(failingSink{}, NewFileSink(dir.Join("audit.log")))
+	err := logger.Log(Record{Action: "install"})
+	assert.Error(t, err, "failed to write audit record: boom")
+	_, err = os.Stat(dir.Join("audit.log"))
+	assert.NilError(t, err)
+
+	assert.Assert(t, !NewLogger().Enabled())
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileSink appends records, one JSON document per line, to a file which is
+// only readable by its owner.
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSink returns a sink appending to the file at path.
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Write appends the record to the file.
+func (s *FileSink) Write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open audit log %q", s.path)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return errors.Wrapf(err, "failed to write audit log %q", s.path)
+	}
+	return nil
+}
+
+// DefaultHTTPTimeout bounds the requests of the HTTP sinks created without
+// client.
+const DefaultHTTPTimeout = 10 * time.Second
+
+// HTTPSink posts each record as a JSON document to an HTTP endpoint.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink returns a sink posting records to url. If client is nil, the
+// requests time out after DefaultHTTPTimeout.
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	return &HTTPSink{url: url, client: client}
+}
+
+// Write posts the record.
+func (s *HTTPSink) Write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to send audit record to %q", s.url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to send audit record to %q: %s", s.url, resp.Status)
+	}
+	return nil
+}
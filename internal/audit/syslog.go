@@ -0,0 +1,33 @@
+// +build !windows
+
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+
+	"github.com/pkg/errors"
+)
+
+// SyslogSink writes records as JSON messages to the system logger.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the local system logger.
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to syslog")
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Write sends the record to syslog.
+func (s *SyslogSink) Write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.w.Notice(string(data))
+}
@@ -0,0 +1,16 @@
+package audit
+
+import "errors"
+
+// SyslogSink is not supported on Windows.
+type SyslogSink struct{}
+
+// NewSyslogSink always fails on Windows.
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	return nil, errors.New("syslog is not supported on windows")
+}
+
+// Write always fails on Windows.
+func (s *SyslogSink) Write(r Record) error {
+	return errors.New("syslog is not supported on windows")
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/docker/registry"
+	"github.com/pkg/errors"
+)
+
+// Provider decorates outgoing requests of repository clients with
+// authentication material.
+type Provider interface {
+	// Authorize adds credentials to the request.
+	Authorize(req *http.Request) error
+}
+
+// ProviderFunc is an adapter to use an ordinary function as a Provider.
+type ProviderFunc func(req *http.Request) error
+
+// Authorize calls f(req).
+func (f ProviderFunc) Authorize(req *http.Request) error {
+	return f(req)
+}
+
+// Anonymous is a provider which does not authenticate requests.
+var Anonymous Provider = ProviderFunc(func(*http.Request) error { return nil })
+
+// BearerToken authenticates requests with an OAuth2 style bearer token.
+type BearerToken string
+
+// Authorize sets the Authorization header to the bearer token.
+func (t BearerToken) Authorize(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// Basic authenticates requests using HTTP basic authentication.
+type Basic struct {
+	Username string
+	Password string
+}
+
+// Authorize sets the basic authentication credentials on the request.
+func (b Basic) Authorize(req *http.Request) error {
+	req.SetBasicAuth(b.Username, b.Password)
+	return nil
+}
+
+// Headers adds a fixed set of custom headers, for instance API keys, to the
+// requests.
+type Headers map[string]string
+
+// Authorize sets all the headers on the request.
+func (h Headers) Authorize(req *http.Request) error {
+	for k, v := range h {
+		req.Header.Set(k, v)
+	}
+	return nil
+}
+
+// Hosts selects a provider based on the host of the request. The Default
+// provider, if any, is used for the hosts which are not configured.
+type Hosts struct {
+	Providers map[string]Provider
+	Default   Provider
+}
+
+// Authorize delegates the request to the provider configured for its host.
+func (h Hosts) Authorize(req *http.Request) error {
+	host := strings.ToLower(req.URL.Host)
+	if p, ok := h.Providers[host]; ok {
+		return p.Authorize(req)
+	}
+	// a host configured without its port matches any port
+	if p, ok := h.Providers[req.URL.Hostname()]; ok {
+		return p.Authorize(req)
+	}
+	if h.Default != nil {
+		return h.Default.Authorize(req)
+	}
+	return nil
+}
+
+// FromDockerConfig returns a provider resolving credentials per host from a
+// docker config.json, including configured credential helpers.
+func FromDockerConfig(config *configfile.ConfigFile) Provider {
+	return ProviderFunc(func(req *http.Request) error {
+		host := req.URL.Host
+		if host == registry.DefaultNamespace || host == registry.IndexHostname {
+			host = registry.IndexServer
+		}
+		authConfig, err := config.GetAuthConfig(host)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve credentials for %q", req.URL.Host)
+		}
+		switch {
+		case authConfig.RegistryToken != "":
+			return BearerToken(authConfig.RegistryToken).Authorize(req)
+		case authConfig.Username != "" || authConfig.Password != "":
+			return Basic{Username: authConfig.Username, Password: authConfig.Password}.Authorize(req)
+		}
+		return nil
+	})
+}
+
+// NewTransport returns a round tripper authorizing every request with the
+// given provider before delegating it to base (http.DefaultTransport if nil).
+func NewTransport(base http.RoundTripper, provider Provider) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, provider: provider}
+}
+
+type transport struct {
+	base     http.RoundTripper
+	provider Provider
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the original request
+	authorized := new(http.Request)
+	*authorized = *req
+	authorized.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		authorized.Header[k] = append([]string(nil), v...)
+	}
+	if err := t.provider.Authorize(authorized); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(authorized)
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/types"
+	"gotest.tools/assert"
+)
+
+func newRequest(t *testing.T, url string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NilError(t, err)
+	return req
+}
+
+func TestProviders(t *testing.T) {
+	req := newRequest(t, "https://example.com/index.json")
+	assert.NilError(t, BearerToken("my-token").Authorize(req))
+	assert.Equal(t, req.Header.Get("Authorization"), "Bearer my-token")
+
+	req = newRequest(t, "https://example.com/index.json")
+	assert.NilError(t, Basic{Username: "user", Password: "pass"}.Authorize(req))
+	username, password, ok := req.BasicAuth()
+	assert.Assert(t, ok)
+	assert.Equal(t, username, "user")
+	assert.Equal(t, password, "pass")
+
+	req = newRequest(t, "https://example.com/index.json")
+	assert.NilError(t, Headers{"X-Api-Key": "key"}.Authorize(req))
+	assert.Equal(t, req.Header.Get("X-Api-Key"), "key")
+}
+
+func TestHosts(t *testing.T) {
+	hosts := Hosts{
+		Providers: map[string]Provider{
+			"example.com":        BearerToken("example"),
+			"other.example:5000": BearerToken("other"),
+		},
+		Default: BearerToken("default"),
+	}
+	for url, expected := range map[string]string{
+		"https://example.com/index.json":      "Bearer example",
+		"https://example.com:443/index.json":  "Bearer example",
+		"http://other.example:5000/feed.json": "Bearer other",
+		"http://other.example/feed.json":      "Bearer default",
+	} {
+		req := newRequest(t, url)
+		assert.NilError(t, hosts.Authorize(req))
+		assert.Equal(t, req.Header.Get("Authorization"), expected, url)
+	}
+}
+
+func TestFromDockerConfig(t *testing.T) {
+	config := configfile.New("config.json")
+	config.AuthConfigs = map[string]types.AuthConfig{
+		"registry.example.com": {Username: "user", Password: "pass"},
+		"token.example.com":    {RegistryToken: "token"},
+	}
+	provider := FromDockerConfig(config)
+
+	req := newRequest(t, "https://registry.example.com/v2/")
+	assert.NilError(t, provider.Authorize(req))
+	username, _, ok := req.BasicAuth()
+	assert.Assert(t, ok)
+	assert.Equal(t, username, "user")
+
+	req = newRequest(t, "https://token.example.com/v2/")
+	assert.NilError(t, provider.Authorize(req))
+	assert.Equal(t, req.Header.Get("Authorization"), "Bearer token")
+
+	req = newRequest(t, "https://unknown.example.com/v2/")
+	assert.NilError(t, provider.Authorize(req))
+	assert.Equal(t, req.Header.Get("Authorization"), "")
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, BearerToken("secret"))}
+	req := newRequest(t, server.URL)
+	resp, err := client.Do(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	// the original request is left untouched
+	assert.Assert(t, !strings.Contains(req.Header.Get("Authorization"), "secret"))
+}
@@ -0,0 +1,134 @@
+// Package batch applies an action to many installations at once.
+package batch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/docker/app/internal/store"
+	"github.com/pkg/errors"
+)
+
+// Filter selects the installations to run the action on.
+type Filter func(installation *store.Installation) bool
+
+// WithBundle selects the installations of the named bundle.
+func WithBundle(name string) Filter {
+	return func(installation *store.Installation) bool {
+		return installation.Bundle != nil && installation.Bundle.Name == name
+	}
+}
+
+// WithStatus selects the installations whose last action has the given status.
+func WithStatus(status string) Filter {
+	return func(installation *store.Installation) bool {
+		return installation.Result.Status == status
+	}
+}
+
+// Action runs on a single installation.
+type Action func(ctx context.Context, installation *store.Installation) error
+
+// Options tunes a batch run.
+type Options struct {
+	// Concurrency is the maximum number of installations processed at once,
+	// 1 if not set.
+	Concurrency int
+	// MaxFailures aborts the batch once that many installations have failed.
+	// Zero never aborts.
+	MaxFailures int
+}
+
+// Result is the outcome of the action on an installation.
+type Result struct {
+	Installation string
+	Err          error
+	// Skipped is set if the batch was aborted before reaching the installation.
+	Skipped bool
+}
+
+// Run applies the action to the installations matching all the filters, and
+// returns the result of each, in the order of the store listing. An error is
+// returned if the installations cannot be listed or if the batch is aborted.
+func Run(ctx context.Context, installations store.InstallationStore, action Action, opts Options, filters ...Filter) ([]Result, error) {
+	targets, err := selectInstallations(installations, filters)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]Result, len(targets))
+	var (
+		mu       sync.Mutex
+		failures int
+		aborted  bool
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, installation := range targets {
+		results[i].Installation = installation.Name
+		sem <- struct{}{}
+		mu.Lock()
+		stop := aborted || ctx.Err() != nil
+		mu.Unlock()
+		if stop {
+			<-sem
+			results[i].Skipped = true
+			continue
+		}
+		wg.Add(1)
+		go func(i int, installation *store.Installation) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := action(ctx, installation)
+			mu.Lock()
+			defer mu.Unlock()
+			results[i].Err = err
+			if err != nil {
+				failures++
+				if opts.MaxFailures > 0 && failures >= opts.MaxFailures {
+					aborted = true
+				}
+			}
+		}(i, installation)
+	}
+	wg.Wait()
+	if aborted {
+		return results, errors.Errorf("batch aborted after %d failures", failures)
+	}
+	return results, ctx.Err()
+}
+
+func selectInstallations(installations store.InstallationStore, filters []Filter) ([]*store.Installation, error) {
+	names, err := installations.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list installations")
+	}
+	var selected []*store.Installation
+	for _, name := range names {
+		installation, err := installations.Read(name)
+		if err != nil {
+			return nil, err
+		}
+		if matches(installation, filters) {
+			selected = append(selected, installation)
+		}
+	}
+	return selected, nil
+}
+
+func matches(installation *store.Installation, filters []Filter) bool {
+	for _, filter := range filters {
+		if !filter(installation) {
+			return false
+		}
+	}
+	return true
+}
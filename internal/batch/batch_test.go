@@ -0,0 +1,78 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/store"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func newStore(t *testing.T) store.InstallationStore {
+	installations := store.NewMemoryInstallationStore()
+	for i := 0; i < 6; i++ {
+		installation, err := store.NewInstallation(fmt.Sprintf("installation-%d", i), "")
+		assert.NilError(t, err)
+		installation.Bundle = &bundle.Bundle{Name: "app"}
+		if i == 5 {
+			installation.Bundle.Name = "other"
+		}
+		installation.Result.Status = claim.StatusSuccess
+		assert.NilError(t, installations.Store(installation))
+	}
+	return installations
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		running int
+		max     int
+	)
+	action := func(ctx context.Context, installation *store.Installation) error {
+		mu.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		if installation.Name == "installation-1" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	results, err := Run(context.Background(), newStore(t), action, Options{Concurrency: 2}, WithBundle("app"), WithStatus(claim.StatusSuccess))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(results, 5))
+	assert.Check(t, max <= 2)
+	for i, result := range results {
+		assert.Check(t, is.Equal(result.Installation, fmt.Sprintf("installation-%d", i)))
+		assert.Check(t, !result.Skipped)
+	}
+	assert.Check(t, is.Error(results[1].Err, "boom"))
+	assert.Check(t, results[2].Err)
+}
+
+func TestRunAbort(t *testing.T) {
+	action := func(ctx context.Context, installation *store.Installation) error {
+		return errors.New("boom")
+	}
+	results, err := Run(context.Background(), newStore(t), action, Options{MaxFailures: 2})
+	assert.Check(t, is.Error(err, "batch aborted after 2 failures"))
+	assert.Assert(t, is.Len(results, 6))
+	assert.Check(t, is.Error(results[1].Err, "boom"))
+	for _, result := range results[2:] {
+		assert.Check(t, result.Skipped, result.Installation)
+	}
+}
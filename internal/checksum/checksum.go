@@ -0,0 +1,110 @@
+package checksum
+
+import (
+	_ "crypto/sha256" // register sha256
+	_ "crypto/sha512" // register sha384 and sha512
+	"io"
+	"os"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AlgorithmEnvVar selects the algorithm used to compute digests.
+	AlgorithmEnvVar = "DOCKER_APP_DIGEST_ALGORITHM"
+	// FIPSEnvVar restricts the algorithms to the FIPS 180-4 approved ones
+	// when set to "1" or "true".
+	FIPSEnvVar = "DOCKER_APP_FIPS"
+)
+
+// fipsApproved lists the algorithms approved by FIPS 180-4.
+var fipsApproved = map[digest.Algorithm]bool{
+	digest.SHA256: true,
+	digest.SHA384: true,
+	digest.SHA512: true,
+}
+
+// Config selects how digests are computed and which algorithms are accepted
+// when verifying them.
+type Config struct {
+	// Algorithm is used to compute new digests, digest.SHA256 by default.
+	Algorithm digest.Algorithm
+	// FIPS rejects the algorithms which are not FIPS approved.
+	FIPS bool
+}
+
+// Default is the configuration used when none is specified.
+var Default = Config{Algorithm: digest.SHA256}
+
+// FromEnv returns the configuration set through the environment.
+func FromEnv() (Config, error) {
+	c := Default
+	if alg := os.Getenv(AlgorithmEnvVar); alg != "" {
+		c.Algorithm = digest.Algorithm(strings.ToLower(alg))
+	}
+	switch strings.ToLower(os.Getenv(FIPSEnvVar)) {
+	case "", "0", "false":
+	case "1", "true":
+		c.FIPS = true
+	default:
+		return Config{}, errors.Errorf("invalid value %q for %s", os.Getenv(FIPSEnvVar), FIPSEnvVar)
+	}
+	return c, c.Validate()
+}
+
+// Validate checks the configured algorithm is available and allowed.
+func (c Config) Validate() error {
+	return c.checkAlgorithm(c.algorithm())
+}
+
+// FromBytes computes the digest of data.
+func (c Config) FromBytes(data []byte) (digest.Digest, error) {
+	alg := c.algorithm()
+	if err := c.checkAlgorithm(alg); err != nil {
+		return "", err
+	}
+	return alg.FromBytes(data), nil
+}
+
+// FromReader computes the digest of the content of r.
+func (c Config) FromReader(r io.Reader) (digest.Digest, error) {
+	alg := c.algorithm()
+	if err := c.checkAlgorithm(alg); err != nil {
+		return "", err
+	}
+	return alg.FromReader(r)
+}
+
+// Verify checks that data matches the expected digest, which may use any
+// allowed algorithm.
+func (c Config) Verify(expected digest.Digest, data []byte) error {
+	if err := expected.Validate(); err != nil {
+		return errors.Wrapf(err, "invalid digest %q", expected)
+	}
+	if err := c.checkAlgorithm(expected.Algorithm()); err != nil {
+		return err
+	}
+	if actual := expected.Algorithm().FromBytes(data); actual != expected {
+		return errors.Errorf("digest mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+func (c Config) algorithm() digest.Algorithm {
+	if c.Algorithm == "" {
+		return digest.Canonical
+	}
+	return c.Algorithm
+}
+
+func (c Config) checkAlgorithm(alg digest.Algorithm) error {
+	if !alg.Available() {
+		return errors.Errorf("unsupported digest algorithm %q", alg)
+	}
+	if c.FIPS && !fipsApproved[alg] {
+		return errors.Errorf("digest algorithm %q is not FIPS approved", alg)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package checksum
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func patchEnv(t *testing.T, key, value string) func() {
+	t.Helper()
+	old, ok := os.LookupEnv(key)
+	assert.NilError(t, os.Setenv(key, value))
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	defer patchEnv(t, AlgorithmEnvVar, "")()
+	defer patchEnv(t, FIPSEnvVar, "")()
+	c, err := FromEnv()
+	assert.NilError(t, err)
+	assert.Equal(t, c, Default)
+
+	os.Setenv(AlgorithmEnvVar, "SHA512")
+	os.Setenv(FIPSEnvVar, "true")
+	c, err = FromEnv()
+	assert.NilError(t, err)
+	assert.Equal(t, c, Config{Algorithm: digest.SHA512, FIPS: true})
+
+	os.Setenv(AlgorithmEnvVar, "md5")
+	_, err = FromEnv()
+	assert.Error(t, err, `unsupported digest algorithm "md5"`)
+
+	os.Setenv(AlgorithmEnvVar, "")
+	os.Setenv(FIPSEnvVar, "maybe")
+	_, err = FromEnv()
+	assert.Check(t, is.ErrorContains(err, "invalid value"))
+}
+
+func TestFromBytes(t *testing.T) {
+	for _, alg := range []digest.Algorithm{digest.SHA256, digest.SHA384, digest.SHA512} {
+		d, err := Config{Algorithm: alg, FIPS: true}.FromBytes([]byte("data"))
+		assert.NilError(t, err)
+		assert.Equal(t, d.Algorithm(), alg)
+		fromReader, err := Config{Algorithm: alg}.FromReader(strings.NewReader("data"))
+		assert.NilError(t, err)
+		assert.Equal(t, fromReader, d)
+	}
+	d, err := Config{}.FromBytes([]byte("data"))
+	assert.NilError(t, err)
+	assert.Equal(t, d.Algorithm(), digest.SHA256)
+}
+
+func TestVerify(t *testing.T) {
+	data := []byte("data")
+	assert.NilError(t, Default.Verify(digest.SHA384.FromBytes(data), data))
+	err := Default.Verify(digest.SHA256.FromBytes([]byte("other")), data)
+	assert.Check(t, is.ErrorContains(err, "digest mismatch"))
+	err = Default.Verify(digest.Digest("sha256:invalid"), data)
+	assert.Check(t, is.ErrorContains(err, "invalid digest"))
+}
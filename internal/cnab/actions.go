@@ -0,0 +1,54 @@
+package cnab
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+)
+
+// coreActions are the actions every invocation image implements, with their
+// metadata.
+var coreActions = map[string]bundle.Action{
+	claim.ActionInstall:   {Modifies: true, Description: "Install the application"},
+	claim.ActionUpgrade:   {Modifies: true, Description: "Upgrade the application"},
+	claim.ActionUninstall: {Modifies: true, Description: "Uninstall the application"},
+}
+
+// actionName is the pattern of custom action names: letters, digits, dots,
+// dashes and underscores, starting with a letter or a digit, like
+// "io.cnab.status".
+var actionName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// IsCoreAction returns true for the install, upgrade and uninstall actions,
+// which are built in and cannot be declared by bundles.
+func IsCoreAction(name string) bool {
+	_, ok := coreActions[name]
+	return ok
+}
+
+// CoreActions returns the sorted names of the core actions.
+func CoreActions() []string {
+	names := make([]string, 0, len(coreActions))
+	for name := range coreActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupAction returns the metadata of a core action or of a custom action
+// of the bundle.
+func LookupAction(b *bundle.Bundle, name string) (bundle.Action, bool) {
+	if action, ok := coreActions[name]; ok {
+		return action, true
+	}
+	action, ok := b.Actions[name]
+	return action, ok
+}
+
+func isKnownAction(b *bundle.Bundle, name string) bool {
+	_, ok := LookupAction(b, name)
+	return ok
+}
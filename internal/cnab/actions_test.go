@@ -0,0 +1,44 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestLookupAction(t *testing.T) {
+	b := &bundle.Bundle{Actions: map[string]bundle.Action{"io.cnab.status": {Stateless: true}}}
+	assert.Check(t, is.DeepEqual(CoreActions(), []string{"install", "uninstall", "upgrade"}))
+	assert.Check(t, IsCoreAction("upgrade"))
+	assert.Check(t, !IsCoreAction("io.cnab.status"))
+
+	action, ok := LookupAction(b, "install")
+	assert.Check(t, ok)
+	assert.Check(t, action.Modifies)
+	action, ok = LookupAction(b, "io.cnab.status")
+	assert.Check(t, ok)
+	assert.Check(t, action.Stateless)
+	_, ok = LookupAction(b, "backup")
+	assert.Check(t, !ok)
+}
+
+func TestValidateActions(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0"}}},
+		Actions: map[string]bundle.Action{
+			"install":        {},
+			"io.cnab.status": {Stateless: true},
+			"../backup":      {},
+			"migrate":        {Stateless: true, Modifies: true},
+		},
+	}
+	errs := Validate(b)
+	assert.Check(t, is.Equal(errs.Error(), `$.actions["../backup"]: invalid action name "../backup", it must only contain letters, digits, '.', '-' and '_'
+$.actions["install"]: action "install" is built in and cannot be redefined
+$.actions["migrate"]: action "migrate" is stateless and cannot modify resources`))
+	assert.Check(t, is.Len(errs.Errors(), 3))
+}
@@ -0,0 +1,161 @@
+package cnab
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// Kinds of external references.
+const (
+	ReferenceInvocationImage = "invocationImage"
+	ReferenceImage           = "image"
+	ReferenceBundle          = "bundle"
+	ReferenceURL             = "url"
+)
+
+// ExternalReference is something a bundle fetches at runtime.
+type ExternalReference struct {
+	Kind      string `json:"kind"`
+	Reference string `json:"reference"`
+	Digest    string `json:"digest,omitempty"`
+	// Bundle is the name of the bundle declaring the reference.
+	Bundle string `json:"bundle"`
+	// Path is the JSON path of the declaration in that bundle.
+	Path string `json:"path"`
+}
+
+// AirgapManifest lists everything a bundle needs to run on a disconnected
+// site, so it can be staged beforehand.
+type AirgapManifest struct {
+	Bundle     string              `json:"bundle"`
+	Version    string              `json:"version"`
+	References []ExternalReference `json:"references"`
+}
+
+// NewAirgapManifest enumerates the images, the dependency and child bundles,
+// and the URLs used as parameter defaults, of the bundle. If fetch is not nil,
+// the referenced bundles are fetched and their own references are listed too.
+// Each reference is listed once, where it is first found.
+func NewAirgapManifest(b *bundle.Bundle, fetch BundleFetcher) (*AirgapManifest, error) {
+	c := &airgapCollector{
+		fetch: fetch,
+		seen:  map[string]bool{},
+	}
+	if err := c.collect(b); err != nil {
+		return nil, err
+	}
+	return &AirgapManifest{
+		Bundle:     b.Name,
+		Version:    b.Version,
+		References: c.references,
+	}, nil
+}
+
+type airgapCollector struct {
+	fetch      BundleFetcher
+	references []ExternalReference
+	seen       map[string]bool
+}
+
+func (c *airgapCollector) add(ref ExternalReference) bool {
+	key := ref.Kind + " " + ref.Reference
+	if c.seen[key] {
+		return false
+	}
+	c.seen[key] = true
+	c.references = append(c.references, ref)
+	return true
+}
+
+func (c *airgapCollector) collect(b *bundle.Bundle) error {
+	for i, img := range b.InvocationImages {
+		c.add(ExternalReference{
+			Kind:      ReferenceInvocationImage,
+			Reference: img.Image,
+			Digest:    img.Digest,
+			Bundle:    b.Name,
+			Path:      fmt.Sprintf("$.invocationImages[%d]", i),
+		})
+	}
+	for _, name := range ImageKeys(b) {
+		img := b.Images[name]
+		c.add(ExternalReference{
+			Kind:      ReferenceImage,
+			Reference: img.Image,
+			Digest:    img.Digest,
+			Bundle:    b.Name,
+			Path:      fmt.Sprintf("$.images[%q]", name),
+		})
+	}
+	for _, name := range ParameterNames(b) {
+		s, ok := b.Parameters[name].Default.(string)
+		if !ok {
+			continue
+		}
+		if u, err := url.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			c.add(ExternalReference{
+				Kind:      ReferenceURL,
+				Reference: s,
+				Bundle:    b.Name,
+				Path:      fmt.Sprintf("$.parameters[%q].default", name),
+			})
+		}
+	}
+
+	deps, err := ReadDependencies(b)
+	if err != nil {
+		return err
+	}
+	if deps != nil {
+		aliases := make([]string, 0, len(deps.Requires))
+		for alias := range deps.Requires {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+		for _, alias := range aliases {
+			path := fmt.Sprintf("$.custom[%q].requires[%q]", DependenciesExtensionKey, alias)
+			if err := c.collectBundle(b, deps.Requires[alias].Bundle, path); err != nil {
+				return err
+			}
+		}
+	}
+	composite, err := ReadComposite(b)
+	if err != nil {
+		return err
+	}
+	if composite != nil {
+		for i, child := range composite.Children {
+			if child.Embedded != nil {
+				if err := c.collect(child.Embedded); err != nil {
+					return err
+				}
+				continue
+			}
+			path := fmt.Sprintf("$.custom[%q].children[%d]", CompositeExtensionKey, i)
+			if err := c.collectBundle(b, child.Bundle, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *airgapCollector) collectBundle(parent *bundle.Bundle, name, path string) error {
+	ref, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return errors.Wrapf(err, "invalid bundle reference at %s", path)
+	}
+	if !c.add(ExternalReference{Kind: ReferenceBundle, Reference: ref.String(), Bundle: parent.Name, Path: path}) || c.fetch == nil {
+		return nil
+	}
+	b, err := c.fetch(ref)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch bundle %s", ref)
+	}
+	return c.collect(b)
+}
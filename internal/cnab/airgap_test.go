@@ -0,0 +1,44 @@
+package cnab_test
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+)
+
+func TestAirgapManifest(t *testing.T) {
+	db := bundletest.NewTestBundle(bundletest.WithName("db"), bundletest.WithImages(1),
+		// cycles are only walked once
+		withDependencies(map[string]cnab.Dependency{"app": {Bundle: "app:0.1.0"}}))
+	b := bundletest.NewTestBundle(
+		bundletest.WithName("app"),
+		bundletest.WithImages(1),
+		bundletest.WithParameter("chart", bundle.ParameterDefinition{DataType: "string", Default: "https://charts.example.com/app.tgz"}),
+		bundletest.WithParameter("name", bundle.ParameterDefinition{DataType: "string", Default: "app"}),
+		withDependencies(map[string]cnab.Dependency{"db": {Bundle: "db:1.0.0"}}),
+	)
+	b.InvocationImages[0].Digest = "sha256:beef"
+	bundles := map[string]*bundle.Bundle{
+		"docker.io/library/db:1.0.0":  db,
+		"docker.io/library/app:0.1.0": b,
+	}
+
+	manifest, err := cnab.NewAirgapManifest(b, fetcher(bundles))
+	assert.NilError(t, err)
+	assert.Equal(t, manifest.Bundle, "app")
+	assert.DeepEqual(t, manifest.References, []cnab.ExternalReference{
+		{Kind: cnab.ReferenceInvocationImage, Reference: "test/test-bundle-invoc:0.1.0", Digest: "sha256:beef", Bundle: "app", Path: "$.invocationImages[0]"},
+		{Kind: cnab.ReferenceImage, Reference: "test/image-0:0.1.0", Bundle: "app", Path: `$.images["image-0"]`},
+		{Kind: cnab.ReferenceURL, Reference: "https://charts.example.com/app.tgz", Bundle: "app", Path: `$.parameters["chart"].default`},
+		{Kind: cnab.ReferenceBundle, Reference: "docker.io/library/db:1.0.0", Bundle: "app", Path: `$.custom["io.cnab.dependencies"].requires["db"]`},
+		{Kind: cnab.ReferenceBundle, Reference: "docker.io/library/app:0.1.0", Bundle: "db", Path: `$.custom["io.cnab.dependencies"].requires["app"]`},
+	})
+
+	// Without a fetcher, only the direct references are listed
+	manifest, err = cnab.NewAirgapManifest(b, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(manifest.References), 4)
+}
@@ -0,0 +1,162 @@
+package cnab
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// ActionArgumentsExtensionKey is the custom extension declaring the
+// arguments of custom actions, like the target of a backup action. They are
+// parameter definitions scoped to an action, keyed by action then argument
+// name:
+//
+//	"com.docker.app.action-arguments": {
+//	  "backup": {
+//	    "target": {"type": "string", "required": true}
+//	  }
+//	}
+//
+// Arguments without destination are injected in CNAB_ARG_<NAME>. Their
+// apply-to list must be empty, as they only apply to their action.
+const ActionArgumentsExtensionKey = internal.Namespace + "action-arguments"
+
+// ArgumentEnvPrefix prefixes the environment variables of the arguments
+// without destination.
+const ArgumentEnvPrefix = "CNAB_ARG_"
+
+// argumentName is the pattern of argument names, which must make valid
+// environment variable names once uppercased.
+var argumentName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ActionArguments are the argument definitions of the custom actions, keyed
+// by action then argument name.
+type ActionArguments map[string]map[string]bundle.ParameterDefinition
+
+// ReadActionArguments returns the argument definitions of the custom actions
+// of the bundle, or nil if it has none.
+func ReadActionArguments(b *bundle.Bundle) (ActionArguments, error) {
+	var args ActionArguments
+	found, err := GetCustomExtension(b, ActionArgumentsExtensionKey, &args)
+	if err != nil || !found {
+		return nil, err
+	}
+	for _, action := range sortedActionArgumentKeys(args) {
+		if _, ok := b.Actions[action]; !ok {
+			return nil, errors.Errorf("invalid %s extension: %q is not a custom action of the bundle", ActionArgumentsExtensionKey, action)
+		}
+		for _, name := range sortedParameterNames(args[action]) {
+			def, err := checkArgument(name, args[action][name])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s extension: argument %q of action %q", ActionArgumentsExtensionKey, name, action)
+			}
+			args[action][name] = def
+		}
+	}
+	return args, nil
+}
+
+// checkArgument checks an argument definition and returns it with its
+// default value converted to its type, see NormalizeParameterValue.
+func checkArgument(name string, def bundle.ParameterDefinition) (bundle.ParameterDefinition, error) {
+	if !argumentName.MatchString(name) {
+		return def, errors.New("invalid name, it must only contain letters, digits and '_', not starting with a digit")
+	}
+	if len(def.ApplyTo) > 0 {
+		return def, errors.New("arguments apply to their action only, apply-to must be empty")
+	}
+	if err := ValidateDestination(def.Destination); err != nil {
+		return def, err
+	}
+	if def.Default != nil {
+		value, err := NormalizeParameterValue(def, def.Default)
+		if err == nil {
+			err = def.ValidateParameterValue(value)
+		}
+		if err != nil {
+			return def, errors.Wrap(err, "invalid default value")
+		}
+		def.Default = value
+	}
+	return def, nil
+}
+
+// ArgumentLocation returns where the argument is injected in the invocation
+// image.
+func ArgumentLocation(name string, def bundle.ParameterDefinition) bundle.Location {
+	if def.Destination != nil {
+		return *def.Destination
+	}
+	return bundle.Location{EnvironmentVariable: ArgumentEnvPrefix + strings.ToUpper(name)}
+}
+
+// ResolveActionArguments checks the arguments given to an action against its
+// definitions, and returns them converted to their types along with the
+// defaults of the ones not given. Undeclared and missing required arguments
+// are errors.
+func ResolveActionArguments(b *bundle.Bundle, action string, values map[string]interface{}) (map[string]interface{}, error) {
+	args, err := ReadActionArguments(b)
+	if err != nil {
+		return nil, err
+	}
+	defs := args[action]
+	var undeclared []string
+	for name := range values {
+		if _, ok := defs[name]; !ok {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return nil, errors.Errorf("action %q has no argument %s", action, quoteJoin(undeclared))
+	}
+	resolved := map[string]interface{}{}
+	for _, name := range sortedParameterNames(defs) {
+		def := defs[name]
+		value, ok := values[name]
+		if !ok {
+			if def.Required {
+				return nil, errors.Errorf("missing required argument %q of action %q", name, action)
+			}
+			if def.Default != nil {
+				resolved[name] = def.Default
+			}
+			continue
+		}
+		if s, ok := value.(string); ok && def.DataType != "string" {
+			value, err = def.ConvertValue(s)
+		} else {
+			value, err = NormalizeParameterValue(def, value)
+		}
+		if err == nil {
+			err = def.ValidateParameterValue(value)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for argument %q of action %q", name, action)
+		}
+		resolved[name] = value
+	}
+	return resolved, nil
+}
+
+func sortedActionArgumentKeys(args ActionArguments) []string {
+	actions := make([]string, 0, len(args))
+	for action := range args {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func quoteJoin(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,109 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func argumentsBundle(args interface{}) *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:             "myapp",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "myapp:1.0.0"}}},
+		Actions:          map[string]bundle.Action{"backup": {}},
+		Parameters:       map[string]bundle.ParameterDefinition{"port": {DataType: "int"}},
+		Custom:           map[string]interface{}{ActionArgumentsExtensionKey: args},
+	}
+}
+
+func TestReadActionArguments(t *testing.T) {
+	b := argumentsBundle(map[string]interface{}{
+		"backup": map[string]interface{}{
+			"target":  map[string]interface{}{"type": "string", "required": true},
+			"retries": map[string]interface{}{"type": "int", "default": 3},
+		},
+	})
+	args, err := ReadActionArguments(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(args, ActionArguments{"backup": {
+		"target":  {DataType: "string", Required: true},
+		"retries": {DataType: "int", Default: 3},
+	}}))
+	assert.Check(t, is.DeepEqual(ArgumentLocation("target", args["backup"]["target"]), bundle.Location{EnvironmentVariable: "CNAB_ARG_TARGET"}))
+
+	args, err = ReadActionArguments(&bundle.Bundle{})
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(args))
+}
+
+func TestReadActionArgumentsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		args     interface{}
+		expected string
+	}{
+		{map[string]interface{}{"install": map[string]interface{}{}}, `"install" is not a custom action of the bundle`},
+		{map[string]interface{}{"backup": map[string]interface{}{"the-target": map[string]interface{}{"type": "string"}}}, `argument "the-target" of action "backup": invalid name`},
+		{map[string]interface{}{"backup": map[string]interface{}{"target": map[string]interface{}{"type": "string", "apply-to": []string{"backup"}}}}, "apply-to must be empty"},
+		{map[string]interface{}{"backup": map[string]interface{}{"target": map[string]interface{}{"type": "string", "destination": map[string]interface{}{}}}}, "empty destination"},
+		{map[string]interface{}{"backup": map[string]interface{}{"retries": map[string]interface{}{"type": "int", "default": 1.5}}}, "invalid default value: 1.5 is not an integer"},
+		{"backup", "invalid " + ActionArgumentsExtensionKey + " extension"},
+	} {
+		b := argumentsBundle(tc.args)
+		_, err := ReadActionArguments(b)
+		assert.Check(t, is.ErrorContains(err, tc.expected))
+		errs := Validate(b)
+		assert.Check(t, is.Len(errs, 1), tc.expected)
+		if len(errs) == 1 {
+			assert.Check(t, is.Equal(errs[0].Code, CodeInvalidActionArgument))
+		}
+	}
+}
+
+func TestResolveActionArguments(t *testing.T) {
+	b := argumentsBundle(map[string]interface{}{
+		"backup": map[string]interface{}{
+			"target":  map[string]interface{}{"type": "string", "required": true},
+			"retries": map[string]interface{}{"type": "int", "default": 3, "maxValue": 10},
+			"verify":  map[string]interface{}{"type": "bool"},
+		},
+	})
+	resolved, err := ResolveActionArguments(b, "backup", map[string]interface{}{"target": "s3://bucket", "verify": "true"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(resolved, map[string]interface{}{"target": "s3://bucket", "retries": 3, "verify": true}))
+
+	_, err = ResolveActionArguments(b, "backup", map[string]interface{}{"target": "s3://bucket", "retries": "20"})
+	assert.Check(t, is.ErrorContains(err, `invalid value for argument "retries" of action "backup": value is too high`))
+	_, err = ResolveActionArguments(b, "backup", map[string]interface{}{"target": "s3://bucket", "port": 80, "force": true})
+	assert.Check(t, is.Error(err, `action "backup" has no argument "force", "port"`))
+	_, err = ResolveActionArguments(b, "backup", nil)
+	assert.Check(t, is.Error(err, `missing required argument "target" of action "backup"`))
+
+	resolved, err = ResolveActionArguments(b, "install", nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(resolved, 0))
+}
+
+func TestActionArgumentLocationConflicts(t *testing.T) {
+	b := argumentsBundle(map[string]interface{}{
+		"backup": map[string]interface{}{
+			"port":   map[string]interface{}{"type": "string", "destination": map[string]interface{}{"env": "CNAB_P_PORT"}},
+			"target": map[string]interface{}{"type": "string"},
+		},
+	})
+	errs := Validate(b)
+	assert.Assert(t, is.Len(errs, 1))
+	assert.Check(t, is.Equal(errs[0].Code, CodeLocationConflict))
+	assert.Check(t, is.Equal(errs[0].Message, `parameter "port" and argument "port" of action "backup" are both injected in the environment variable CNAB_P_PORT`))
+
+	// Arguments of different actions do not conflict.
+	b.Actions["restore"] = bundle.Action{}
+	b.Parameters = nil
+	b.Custom[ActionArgumentsExtensionKey] = map[string]interface{}{
+		"backup":  map[string]interface{}{"target": map[string]interface{}{"type": "string"}},
+		"restore": map[string]interface{}{"target": map[string]interface{}{"type": "string"}},
+	}
+	assert.Check(t, is.Len(Validate(b), 0))
+}
@@ -0,0 +1,137 @@
+package cnab
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/yaml"
+	"github.com/pkg/errors"
+)
+
+// MaxAutoInputSize bounds the size of the documents read by ParseAuto, after
+// decompression.
+const MaxAutoInputSize = 64 << 20
+
+// ParseAuto reads a bundle in any of the supported formats, detected from
+// the content: JSON, YAML, gzip compressed, or a thick bundle archive (a
+// possibly compressed tar holding a bundle.json file).
+func ParseAuto(r io.Reader) (*bundle.Bundle, error) {
+	return parseAuto(r, true)
+}
+
+func parseAuto(r io.Reader, allowCompressed bool) (*bundle.Bundle, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to read bundle")
+	}
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		if !allowCompressed {
+			return nil, errors.New("invalid bundle: nested compression")
+		}
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid compressed bundle")
+		}
+		defer gz.Close()
+		return parseAuto(gz, false)
+	case isTar(header):
+		return parseThickBundle(br)
+	}
+	data, err := ReadLimited(br, MaxAutoInputSize)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("invalid bundle: empty document")
+	}
+	if trimmed[0] == '{' {
+		return ParseReaderLimited(bytes.NewReader(trimmed), MaxAutoInputSize)
+	}
+	return parseYAML(trimmed)
+}
+
+// isTar checks the magic of the ustar header, at offset 257.
+func isTar(header []byte) bool {
+	return len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar"))
+}
+
+func parseThickBundle(r io.Reader) (*bundle.Bundle, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("invalid thick bundle: no bundle.json found")
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid thick bundle")
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Base(hdr.Name) != "bundle.json" {
+			continue
+		}
+		if hdr.Size > MaxAutoInputSize {
+			return nil, fmt.Errorf("invalid thick bundle: bundle.json larger than %d bytes", MaxAutoInputSize)
+		}
+		return ParseReaderLimited(tr, MaxAutoInputSize)
+	}
+}
+
+func parseYAML(data []byte) (*bundle.Bundle, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	converted, err := jsonCompatible(doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	if _, ok := converted.(map[string]interface{}); !ok {
+		return nil, errors.New("invalid bundle: not a JSON or YAML object")
+	}
+	jsonData, err := json.Marshal(converted)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	return ParseReaderLimited(bytes.NewReader(jsonData), MaxAutoInputSize)
+}
+
+// jsonCompatible converts the maps decoded from YAML, whose keys may be of
+// any type, to maps with string keys.
+func jsonCompatible(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v", key)
+			}
+			converted, err := jsonCompatible(value)
+			if err != nil {
+				return nil, err
+			}
+			m[s] = converted
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			converted, err := jsonCompatible(value)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = converted
+		}
+		return l, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,89 @@
+package cnab
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const testJSONBundle = `{"name":"foo","version":"1.0.0","schemaVersion":"v1.0.0-WD","invocationImages":[{"imageType":"docker","image":"foo/bar:1.0.0"}]}`
+
+const testYAMLBundle = `
+name: foo
+version: 1.0.0
+schemaVersion: v1.0.0-WD
+invocationImages:
+- imageType: docker
+  image: foo/bar:1.0.0
+parameters:
+  port:
+    type: int
+    default: 8080
+`
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	buf := bytes.NewBuffer(nil)
+	w := gzip.NewWriter(buf)
+	_, err := w.Write(data)
+	assert.NilError(t, err)
+	assert.NilError(t, w.Close())
+	return buf.Bytes()
+}
+
+func thickBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := bytes.NewBuffer(nil)
+	w := tar.NewWriter(buf)
+	for name, content := range files {
+		assert.NilError(t, w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := w.Write([]byte(content))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestParseAuto(t *testing.T) {
+	archive := thickBundle(t, map[string]string{"artifacts/layout.json": "{}", "bundle/bundle.json": testJSONBundle})
+	for name, input := range map[string][]byte{
+		"json":           []byte("\n " + testJSONBundle),
+		"yaml":           []byte(testYAMLBundle),
+		"gzip json":      gzipped(t, []byte(testJSONBundle)),
+		"gzip yaml":      gzipped(t, []byte(testYAMLBundle)),
+		"thick bundle":   archive,
+		"gzip thick tgz": gzipped(t, archive),
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, err := ParseAuto(bytes.NewReader(input))
+			assert.NilError(t, err)
+			assert.Equal(t, b.Name, "foo")
+			assert.Equal(t, b.InvocationImages[0].Image, "foo/bar:1.0.0")
+		})
+	}
+}
+
+func TestParseAutoErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input    []byte
+		expected string
+	}{
+		"empty":              {[]byte("  \n"), "empty document"},
+		"scalar":             {[]byte("foo"), "not a JSON or YAML object"},
+		"invalid yaml":       {[]byte("foo: [bar"), "invalid bundle"},
+		"no bundle.json":     {thickBundle(t, map[string]string{"other.json": "{}"}), "no bundle.json found"},
+		"nested compression": {gzipped(t, gzipped(t, []byte(testJSONBundle))), "nested compression"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseAuto(bytes.NewReader(tc.input))
+			assert.Check(t, is.ErrorContains(err, tc.expected))
+		})
+	}
+	_, err := ParseAuto(strings.NewReader(`{"name":`))
+	assert.Check(t, is.ErrorContains(err, "invalid bundle"))
+}
@@ -0,0 +1,182 @@
+package cnab
+
+import (
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// Builder constructs a bundle, checking its invariants as it goes. The first
+// error is kept and returned by Build, the following calls being ignored, so
+// calls can be chained:
+//
+//	b, err := cnab.NewBuilder("app", "1.0.0").
+//		AddInvocationImage(image).
+//		AddParameter("port", port).
+//		Build()
+type Builder struct {
+	bundle bundle.Bundle
+	err    error
+}
+
+// NewBuilder starts building a bundle with the given name and semantic
+// version.
+func NewBuilder(name, version string) *Builder {
+	b := &Builder{bundle: bundle.Bundle{
+		Name:        name,
+		Version:     version,
+		Images:      map[string]bundle.Image{},
+		Parameters:  map[string]bundle.ParameterDefinition{},
+		Credentials: map[string]bundle.Location{},
+	}}
+	if name == "" {
+		b.err = errors.New("bundle name is required")
+		return b
+	}
+	b.err = ValidateVersion(&b.bundle)
+	return b
+}
+
+// Description sets the description of the bundle.
+func (b *Builder) Description(description string) *Builder {
+	b.bundle.Description = description
+	return b
+}
+
+// AddInvocationImage adds an invocation image. Docker and OCI images must
+// have a tag or a digest.
+func (b *Builder) AddInvocationImage(image bundle.InvocationImage) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if isDockerish(image.BaseImage) {
+		if _, err := ParsedReference(image); err != nil {
+			b.err = errors.Wrapf(err, "invalid invocation image %q", image.Image)
+			return b
+		}
+	}
+	b.bundle.InvocationImages = append(b.bundle.InvocationImages, image)
+	return b
+}
+
+// AddImage adds a component image.
+func (b *Builder) AddImage(name string, image bundle.Image) *Builder {
+	if b.err != nil {
+		return b
+	}
+	_, exists := b.bundle.Images[name]
+	if !b.checkName("image", name, exists) {
+		return b
+	}
+	if isDockerish(image.BaseImage) {
+		if _, err := reference.ParseNormalizedNamed(image.Image); err != nil {
+			b.err = errors.Wrapf(err, "invalid image %q", name)
+			return b
+		}
+	}
+	b.bundle.Images[name] = image
+	return b
+}
+
+// AddParameter adds a parameter. Its default value, if any, must be valid.
+func (b *Builder) AddParameter(name string, def bundle.ParameterDefinition) *Builder {
+	if b.err != nil {
+		return b
+	}
+	_, exists := b.bundle.Parameters[name]
+	if !b.checkName("parameter", name, exists) {
+		return b
+	}
+	if def.Default != nil {
+		if err := def.ValidateParameterValue(def.Default); err != nil {
+			b.err = errors.Wrapf(err, "invalid default value of parameter %q", name)
+			return b
+		}
+	}
+	if err := ValidateDestination(def.Destination); err != nil {
+		b.err = errors.Wrapf(err, "parameter %q", name)
+		return b
+	}
+	b.bundle.Parameters[name] = def
+	return b
+}
+
+// AddCredential adds a credential, which must have an environment variable
+// or a path.
+func (b *Builder) AddCredential(name string, location bundle.Location) *Builder {
+	if b.err != nil {
+		return b
+	}
+	_, exists := b.bundle.Credentials[name]
+	if !b.checkName("credential", name, exists) {
+		return b
+	}
+	if location.EnvironmentVariable == "" && location.Path == "" {
+		b.err = errors.Errorf("credential %q must have an environment variable or a path", name)
+		return b
+	}
+	b.bundle.Credentials[name] = location
+	return b
+}
+
+// AddAction adds a custom action. The install, upgrade and uninstall actions
+// are built in and cannot be redefined.
+func (b *Builder) AddAction(name string, action bundle.Action) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if IsCoreAction(name) {
+		b.err = errors.Errorf("action %q is built in and cannot be redefined", name)
+		return b
+	}
+	_, exists := b.bundle.Actions[name]
+	if !b.checkName("action", name, exists) {
+		return b
+	}
+	if b.bundle.Actions == nil {
+		b.bundle.Actions = map[string]bundle.Action{}
+	}
+	b.bundle.Actions[name] = action
+	return b
+}
+
+// AddCustom adds a custom extension.
+func (b *Builder) AddCustom(key string, value interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+	_, exists := b.bundle.Custom[key]
+	if !b.checkName("custom extension", key, exists) {
+		return b
+	}
+	if b.bundle.Custom == nil {
+		b.bundle.Custom = map[string]interface{}{}
+	}
+	b.bundle.Custom[key] = value
+	return b
+}
+
+// Build returns the bundle, or the first error met while building it. The
+// returned bundle is validated, and is not modified by later calls to the
+// builder.
+func (b *Builder) Build() (*bundle.Bundle, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	built := DeepCopy(&b.bundle)
+	if err := Validate(built).Err(); err != nil {
+		return nil, err
+	}
+	return built, nil
+}
+
+// checkName records an error if the name is empty or already used.
+func (b *Builder) checkName(kind, name string, exists bool) bool {
+	switch {
+	case name == "":
+		b.err = errors.Errorf("%s name is required", kind)
+	case exists:
+		b.err = errors.Errorf("%s %q is already defined", kind, name)
+	}
+	return b.err == nil
+}
@@ -0,0 +1,85 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+var testInvocationImage = bundle.InvocationImage{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app-installer:1.0.0"}}
+
+func TestBuilder(t *testing.T) {
+	builder := NewBuilder("app", "1.0.0").
+		Description("An app").
+		AddInvocationImage(testInvocationImage).
+		AddImage("web", bundle.Image{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "nginx"}}).
+		AddParameter("port", bundle.ParameterDefinition{DataType: "string", Default: "8080"}).
+		AddCredential("token", bundle.Location{EnvironmentVariable: "TOKEN"}).
+		AddAction("logs", bundle.Action{Stateless: true}).
+		AddCustom(SensitiveParametersExtensionKey, []string{"port"})
+	b, err := builder.Build()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Name, "app"))
+	assert.Check(t, is.Equal(b.Description, "An app"))
+	assert.Check(t, is.DeepEqual(b.InvocationImages, []bundle.InvocationImage{testInvocationImage}))
+	assert.Check(t, is.Len(b.Images, 1))
+	assert.Check(t, is.Equal(b.Parameters["port"].Default, "8080"))
+	assert.Check(t, is.Equal(b.Credentials["token"].EnvironmentVariable, "TOKEN"))
+	assert.Check(t, b.Actions["logs"].Stateless)
+
+	// Later calls do not modify the built bundle
+	builder.AddParameter("host", bundle.ParameterDefinition{DataType: "string"})
+	assert.Check(t, is.Len(b.Parameters, 1))
+}
+
+func TestBuilderErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		builder  *Builder
+		expected string
+	}{
+		{"invalid version", NewBuilder("app", "v1"), `invalid version of bundle "app": invalid version "v1": not a semantic version`},
+		{"missing name", NewBuilder("", "1.0.0"), "bundle name is required"},
+		{"missing invocation image", NewBuilder("app", "1.0.0"), "at least one invocation image must be defined in the bundle"},
+		{
+			"untagged invocation image",
+			NewBuilder("app", "1.0.0").AddInvocationImage(bundle.InvocationImage{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app"}}),
+			`invalid invocation image "app": tag or digest is required`,
+		},
+		{
+			"duplicate parameter",
+			NewBuilder("app", "1.0.0").
+				AddParameter("port", bundle.ParameterDefinition{DataType: "string"}).
+				AddParameter("port", bundle.ParameterDefinition{DataType: "string"}),
+			`parameter "port" is already defined`,
+		},
+		{
+			"invalid default",
+			NewBuilder("app", "1.0.0").AddParameter("port", bundle.ParameterDefinition{DataType: "int", Default: "http"}),
+			`invalid default value of parameter "port"`,
+		},
+		{
+			"empty credential",
+			NewBuilder("app", "1.0.0").AddCredential("token", bundle.Location{}),
+			`credential "token" must have an environment variable or a path`,
+		},
+		{
+			"built in action",
+			NewBuilder("app", "1.0.0").AddAction("install", bundle.Action{}),
+			`action "install" is built in and cannot be redefined`,
+		},
+		{
+			"first error is kept",
+			NewBuilder("app", "1.0.0").AddImage("", bundle.Image{}).AddCredential("token", bundle.Location{}),
+			"image name is required",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.builder.Build()
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package buildgen renders bundles from templates, so large bundles with
+// many images and parameters can be generated from a few values instead of
+// being maintained by hand.
+//
+// Templates use the Go text/template syntax and render a JSON or YAML bundle
+// document. CUE is not supported, as it is not vendored.
+package buildgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"text/template"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/yaml"
+	"github.com/pkg/errors"
+)
+
+// funcs are the functions available to templates, on top of the text/template
+// builtins.
+var funcs = template.FuncMap{
+	// json encodes a value, for instance to write a list of values from the
+	// values file as a JSON array.
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Render executes the template with the values and returns the validated
+// bundle. Values missing from the values are errors.
+func Render(name string, source []byte, values map[string]interface{}) (*bundle.Bundle, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(string(source))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid bundle template %q", name)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buf, values); err != nil {
+		return nil, errors.Wrapf(err, "failed to render bundle template %q", name)
+	}
+	b, err := cnab.ParseAuto(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "bundle template %q rendered an invalid document", name)
+	}
+	if err := cnab.Validate(b).Err(); err != nil {
+		return nil, errors.Wrapf(err, "bundle template %q rendered an invalid bundle", name)
+	}
+	return b, nil
+}
+
+// RenderFile renders the template file with the values of a YAML or JSON
+// values file, which may be empty.
+func RenderFile(templatePath, valuesPath string) (*bundle.Bundle, error) {
+	source, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if valuesPath != "" {
+		if values, err = LoadValues(valuesPath); err != nil {
+			return nil, err
+		}
+	}
+	return Render(filepath.Base(templatePath), source, values)
+}
+
+// LoadValues reads a YAML or JSON values file.
+func LoadValues(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrapf(err, "invalid values file %q", path)
+	}
+	if raw == nil {
+		return map[string]interface{}{}, nil
+	}
+	converted, err := stringKeys(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid values file %q", path)
+	}
+	values, ok := converted.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid values file %q: not a map", path)
+	}
+	return values, nil
+}
+
+// stringKeys converts the maps decoded from YAML to maps with string keys, so
+// the values can be encoded as JSON.
+func stringKeys(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			converted, err := stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = converted
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			converted, err := stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = converted
+		}
+		return l, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,58 @@
+package buildgen
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+const bundleTemplate = `name: {{.name}}
+version: {{.version}}
+invocationImages:
+  - imageType: docker
+    image: {{.registry}}/{{.name}}-invoc:{{.version}}
+images:
+{{- range .services}}
+  {{.}}:
+    imageType: docker
+    image: {{$.registry}}/{{.}}:{{$.version}}
+{{- end}}
+parameters:
+  services:
+    type: string
+    default: {{json (json .services)}}
+`
+
+func TestRenderFile(t *testing.T) {
+	dir := fs.NewDir(t, "buildgen",
+		fs.WithFile("bundle.yml.tmpl", bundleTemplate),
+		fs.WithFile("values.yml", `
+name: shop
+version: 1.2.0
+registry: example.com/shop
+services: [front, cart, payment]
+`))
+	defer dir.Remove()
+
+	b, err := RenderFile(dir.Join("bundle.yml.tmpl"), dir.Join("values.yml"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Name, "shop"))
+	assert.Check(t, is.Equal(b.InvocationImages[0].Image, "example.com/shop/shop-invoc:1.2.0"))
+	assert.Check(t, is.Len(b.Images, 3))
+	assert.Check(t, is.Equal(b.Images["cart"].Image, "example.com/shop/cart:1.2.0"))
+	assert.Check(t, is.Equal(b.Parameters["services"].Default, `["front","cart","payment"]`))
+}
+
+func TestRenderErrors(t *testing.T) {
+	_, err := Render("missing", []byte(bundleTemplate), map[string]interface{}{"name": "shop"})
+	assert.Check(t, is.ErrorContains(err, `failed to render bundle template "missing"`))
+
+	_, err = Render("invalid", []byte(`{"name": "shop", "version": "latest", "invocationImages": []}`), nil)
+	assert.Check(t, is.ErrorContains(err, `bundle template "invalid" rendered an invalid bundle`))
+	assert.Check(t, is.ErrorContains(err, "at least one invocation image must be defined"))
+
+	_, err = Render("broken", []byte(`{{.name`), nil)
+	assert.Check(t, is.ErrorContains(err, `invalid bundle template "broken"`))
+}
@@ -0,0 +1,98 @@
+package cnab
+
+import (
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// BundleReference is the structured form of the reference of a bundle in a
+// registry, as it is exchanged on the wire:
+//
+//	[registry/]repository[:version][@digest]
+//
+// It is shared by pull, the bundle store, the installations and the
+// dependency resolution, so they all agree on what refers to a bundle.
+type BundleReference struct {
+	// Registry is the registry domain, "docker.io" by default.
+	Registry string
+	// Repository is the repository path in the registry, like
+	// "library/myapp".
+	Repository string
+	// Version is the tag of the bundle, "latest" by default unless the
+	// reference has a digest.
+	Version string
+	// Digest pins the content of the bundle, if set.
+	Digest string
+}
+
+// ParseBundleReference parses and normalizes a bundle reference: the
+// registry defaults to docker.io, the repositories of docker.io without
+// namespace belong to "library" and the version defaults to "latest" if the
+// reference has no digest. The normalized reference is returned by String.
+func ParseBundleReference(s string) (BundleReference, error) {
+	named, err := reference.ParseNormalizedNamed(s)
+	if err != nil {
+		return BundleReference{}, errors.Wrapf(err, "invalid bundle reference %q", s)
+	}
+	return BundleReferenceFromNamed(named), nil
+}
+
+// BundleReferenceFromNamed returns the bundle reference of a docker
+// reference, normalized as ParseBundleReference does.
+func BundleReferenceFromNamed(named reference.Named) BundleReference {
+	named = reference.TagNameOnly(named)
+	ref := BundleReference{
+		Registry:   reference.Domain(named),
+		Repository: reference.Path(named),
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		ref.Version = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		ref.Digest = digested.Digest().String()
+	}
+	return ref
+}
+
+// Name returns the registry and repository of the reference, without
+// version nor digest, identifying the bundle whatever its version.
+func (r BundleReference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the normalized reference, which ParseBundleReference parses
+// back to the same reference.
+func (r BundleReference) String() string {
+	var s strings.Builder
+	s.WriteString(r.Name())
+	if r.Version != "" {
+		s.WriteString(":" + r.Version)
+	}
+	if r.Digest != "" {
+		s.WriteString("@" + r.Digest)
+	}
+	return s.String()
+}
+
+// FamiliarString returns the shortest form of the reference, as displayed to
+// users, like "myapp:1.2.3".
+func (r BundleReference) FamiliarString() string {
+	named, err := r.Named()
+	if err != nil {
+		return r.String()
+	}
+	return reference.FamiliarString(named)
+}
+
+// Named returns the docker reference used by the registry clients and the
+// bundle store. It fails if the reference was not built by
+// ParseBundleReference and is invalid.
+func (r BundleReference) Named() (reference.Named, error) {
+	named, err := reference.ParseNormalizedNamed(r.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid bundle reference %q", r.String())
+	}
+	return named, nil
+}
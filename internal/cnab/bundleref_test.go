@@ -0,0 +1,79 @@
+package cnab
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParseBundleReference(t *testing.T) {
+	const digest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+	for _, tc := range []struct {
+		ref        string
+		expected   BundleReference
+		normalized string
+		familiar   string
+		err        string
+	}{
+		{
+			ref:        "myapp",
+			expected:   BundleReference{Registry: "docker.io", Repository: "library/myapp", Version: "latest"},
+			normalized: "docker.io/library/myapp:latest",
+			familiar:   "myapp:latest",
+		},
+		{
+			ref:        "user/myapp:1.2.3",
+			expected:   BundleReference{Registry: "docker.io", Repository: "user/myapp", Version: "1.2.3"},
+			normalized: "docker.io/user/myapp:1.2.3",
+			familiar:   "user/myapp:1.2.3",
+		},
+		{
+			ref:        "localhost:5000/apps/myapp:1.2.3@" + digest,
+			expected:   BundleReference{Registry: "localhost:5000", Repository: "apps/myapp", Version: "1.2.3", Digest: digest},
+			normalized: "localhost:5000/apps/myapp:1.2.3@" + digest,
+			familiar:   "localhost:5000/apps/myapp:1.2.3@" + digest,
+		},
+		{
+			ref:        "example.com/myapp@" + digest,
+			expected:   BundleReference{Registry: "example.com", Repository: "myapp", Digest: digest},
+			normalized: "example.com/myapp@" + digest,
+			familiar:   "example.com/myapp@" + digest,
+		},
+		{ref: "myapp:", err: `invalid bundle reference "myapp:": invalid reference format`},
+		{ref: "MyApp:1.0", err: "invalid reference format"},
+		{ref: "myapp@sha256:abc", err: "invalid reference format"},
+	} {
+		t.Run(tc.ref, func(t *testing.T) {
+			ref, err := ParseBundleReference(tc.ref)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Check(t, is.DeepEqual(ref, tc.expected))
+			assert.Check(t, is.Equal(ref.String(), tc.normalized))
+			assert.Check(t, is.Equal(ref.FamiliarString(), tc.familiar))
+
+			// The normalized form parses back to the same reference
+			again, err := ParseBundleReference(ref.String())
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(again, ref))
+			named, err := ref.Named()
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(BundleReferenceFromNamed(named), ref))
+		})
+	}
+}
+
+func TestBundleReferenceName(t *testing.T) {
+	a, err := ParseBundleReference("myapp:1.0.0")
+	assert.NilError(t, err)
+	b, err := ParseBundleReference("docker.io/library/myapp:2.0.0")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(a.Name(), b.Name()))
+	assert.Check(t, is.Equal(a.Name(), "docker.io/library/myapp"))
+
+	_, err = BundleReference{Registry: "docker.io", Repository: "My App"}.Named()
+	assert.Check(t, is.ErrorContains(err, "invalid bundle reference"))
+}
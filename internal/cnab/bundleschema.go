@@ -0,0 +1,32 @@
+package cnab
+
+import (
+	"encoding/json"
+
+	"github.com/docker/app/specification"
+	"github.com/pkg/errors"
+)
+
+// ValidateSchema validates a raw bundle document against the CNAB bundle
+// JSON schema of its schema version, or of the latest one if it has none. It
+// reports the violations decoding tolerates, like values of the wrong type
+// or missing required fields.
+func ValidateSchema(data []byte) error {
+	if err := CheckNesting(data); err != nil {
+		return errors.Wrap(err, "invalid bundle")
+	}
+	var doc struct {
+		SchemaVersion *string `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return errors.Wrap(err, "invalid bundle")
+	}
+	version := specification.BundleSchemaVersion
+	if doc.SchemaVersion != nil {
+		version = *doc.SchemaVersion
+	}
+	if err := specification.ValidateBundle(data, version); err != nil {
+		return errors.Wrap(err, "bundle does not match the CNAB schema")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package cnab
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestValidateSchema(t *testing.T) {
+	assert.NilError(t, ValidateSchema([]byte(`{
+		"schemaVersion": "v1.0.0-WD",
+		"name": "app",
+		"version": "1.0.0",
+		"description": "",
+		"invocationImages": [{"imageType": "docker", "image": "app:1.0.0"}],
+		"images": null,
+		"parameters": {"port": {"type": "int", "default": 80, "destination": {"env": "PORT"}}},
+		"credentials": {"token": {"env": "TOKEN"}},
+		"custom": {"com.example": {"anything": true}}
+	}`)))
+}
+
+func TestValidateSchemaViolations(t *testing.T) {
+	for document, expected := range map[string]string{
+		`{"name": "app", "version": "1.0.0"}`:                                                                                                           "- invocationImages: invocationImages is required",
+		`{"name": "app", "version": "1.0.0", "invocationImages": {"image": "app:1.0.0"}}`:                                                               "- invocationImages: Invalid type. Expected: array, given: object",
+		`{"name": "app", "version": "1.0.0", "invocationImages": [{"image": "app:1.0.0"}], "images": []}`:                                               "- images: Invalid type. Expected: [object,null], given: array",
+		`{"name": "app", "version": "1.0.0", "invocationImages": [{"image": "app:1.0.0"}], "parameters": {"port": {"type": "int", "required": "yes"}}}`: "parameters.required: Invalid type. Expected: boolean, given: string",
+		`{"schemaVersion": "v2", "name": "app"}`:                                                                                                        "unsupported bundle schema version: v2",
+	} {
+		assert.ErrorContains(t, ValidateSchema([]byte(document)), expected, document)
+	}
+}
@@ -0,0 +1,117 @@
+package cnab
+
+import (
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
+)
+
+// Canonical wraps a bundle so that encoding/json serializes it in canonical
+// form, byte for byte like bundle.WriteFile and Digest do. Use it wherever a
+// bundle is marshaled, alone or as part of another document:
+//
+//	data, err := json.Marshal(cnab.Canonical{Bundle: b})
+//
+// Note json.Marshal escapes the <, > and & characters, which canonical JSON
+// does not, so documents holding them must be written by an Encoder with
+// SetEscapeHTML(false) to stay canonical.
+type Canonical struct {
+	*bundle.Bundle
+}
+
+// MarshalJSON returns the canonical JSON document of the bundle.
+func (c Canonical) MarshalJSON() ([]byte, error) {
+	return canonicaljson.MarshalCanonical(c.Bundle)
+}
+
+// UnmarshalJSON decodes a bundle document as Parse does.
+func (c *Canonical) UnmarshalJSON(data []byte) error {
+	b, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	c.Bundle = b
+	return nil
+}
+
+// Canonicalizer serializes documents in a canonical form, the same bytes for
+// the same content whatever the key order or formatting, so they can be
+// hashed and signed.
+type Canonicalizer interface {
+	// Name identifies the canonicalization scheme, like "jcs".
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+}
+
+var (
+	// DockerCanonicalJSON is the canonical JSON of docker/go, used by
+	// default.
+	DockerCanonicalJSON Canonicalizer = dockerCanonicalJSON{}
+	// JCS is the JSON Canonicalization Scheme of RFC 8785, implemented by
+	// tools of other ecosystems. Numbers are serialized as IEEE 754 doubles,
+	// as in JavaScript, so integers beyond 2^53 lose precision.
+	JCS Canonicalizer = jcs{}
+)
+
+// Canonicalizers lists the supported canonicalization schemes.
+var Canonicalizers = []Canonicalizer{DockerCanonicalJSON, JCS}
+
+// LookupCanonicalizer returns the canonicalization scheme with the given
+// name, or the default one if the name is empty.
+func LookupCanonicalizer(name string) (Canonicalizer, error) {
+	if name == "" {
+		return DockerCanonicalJSON, nil
+	}
+	for _, c := range Canonicalizers {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf("unknown canonicalization %q, expected %q or %q", name, DockerCanonicalJSON.Name(), JCS.Name())
+}
+
+// CanonicalOptions select how documents are canonicalized.
+type CanonicalOptions struct {
+	// Canonicalizer defaults to DockerCanonicalJSON.
+	Canonicalizer Canonicalizer
+	// Checksum selects the algorithm of the digests, see Digest. It
+	// defaults to the configuration of the environment.
+	Checksum *checksum.Config
+}
+
+// WithCanonicalizer selects the canonicalization scheme.
+func WithCanonicalizer(c Canonicalizer) func(*CanonicalOptions) {
+	return func(o *CanonicalOptions) {
+		o.Canonicalizer = c
+	}
+}
+
+// SelectCanonicalizer returns the canonicalization scheme selected by the
+// options.
+func SelectCanonicalizer(opts ...func(*CanonicalOptions)) Canonicalizer {
+	var o CanonicalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Canonicalizer == nil {
+		return DockerCanonicalJSON
+	}
+	return o.Canonicalizer
+}
+
+// MarshalCanonical returns the canonical form of the document, with the
+// selected canonicalization scheme.
+func MarshalCanonical(v interface{}, opts ...func(*CanonicalOptions)) ([]byte, error) {
+	return SelectCanonicalizer(opts...).Marshal(v)
+}
+
+type dockerCanonicalJSON struct{}
+
+func (dockerCanonicalJSON) Name() string {
+	return "docker"
+}
+
+func (dockerCanonicalJSON) Marshal(v interface{}) ([]byte, error) {
+	return canonicaljson.MarshalCanonical(v)
+}
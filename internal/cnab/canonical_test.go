@@ -0,0 +1,46 @@
+package cnab
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	canonicaljson "github.com/docker/go/canonical/json"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCanonical(t *testing.T) {
+	b, err := Parse([]byte(testBundle))
+	assert.NilError(t, err)
+	expected, err := canonicaljson.MarshalCanonical(b)
+	assert.NilError(t, err)
+
+	data, err := json.Marshal(Canonical{Bundle: b})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), string(expected)))
+
+	// Nested in another document
+	data, err = json.Marshal(map[string]interface{}{"bundle": Canonical{Bundle: b}})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), `{"bundle":`+string(expected)+`}`))
+
+	var decoded struct {
+		Bundle Canonical `json:"bundle"`
+	}
+	assert.NilError(t, json.Unmarshal(data, &decoded))
+	assert.Check(t, is.DeepEqual(decoded.Bundle.Bundle, b))
+}
+
+func TestCanonicalHTMLCharacters(t *testing.T) {
+	b, err := Parse([]byte(`{"name": "a<b>&c", "version": "1.0.0"}`))
+	assert.NilError(t, err)
+	expected, err := canonicaljson.MarshalCanonical(b)
+	assert.NilError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	assert.NilError(t, enc.Encode(Canonical{Bundle: b}))
+	assert.Check(t, is.Equal(buf.String(), string(expected)+"\n"))
+}
@@ -0,0 +1,97 @@
+package cnab
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/semver"
+	"github.com/pkg/errors"
+)
+
+// ChangelogExtensionKey is the custom extension holding the changelog of a
+// bundle.
+const ChangelogExtensionKey = internal.Namespace + "changelog"
+
+// ChangelogEntry describes the changes of a version.
+type ChangelogEntry struct {
+	Version string `json:"version"`
+	Notes   string `json:"notes"`
+	// Breaking is set if upgrading to this version requires care.
+	Breaking bool `json:"breaking,omitempty"`
+}
+
+// ReadChangelog returns the changelog of the bundle, or nil if it has none.
+func ReadChangelog(b *bundle.Bundle) ([]ChangelogEntry, error) {
+	var entries []ChangelogEntry
+	found, err := GetCustomExtension(b, ChangelogExtensionKey, &entries)
+	if err != nil || !found {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// UpgradeNotes returns the changelog entries of the versions an upgrade from
+// installedVersion to the target bundle goes through, oldest first.
+func UpgradeNotes(installedVersion string, target *bundle.Bundle) ([]ChangelogEntry, error) {
+	entries, err := ReadChangelog(target)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	from, err := semver.Parse(installedVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot select the upgrade notes from %s", installedVersion)
+	}
+	to, err := semver.Parse(target.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot select the upgrade notes to %s", target.Version)
+	}
+	type versioned struct {
+		version semver.Version
+		entry   ChangelogEntry
+	}
+	var selected []versioned
+	for _, entry := range entries {
+		v, err := semver.Parse(entry.Version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s extension", ChangelogExtensionKey)
+		}
+		if v.Compare(from) > 0 && v.Compare(to) <= 0 {
+			selected = append(selected, versioned{v, entry})
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].version.Compare(selected[j].version) < 0
+	})
+	notes := make([]ChangelogEntry, len(selected))
+	for i, s := range selected {
+		notes[i] = s.entry
+	}
+	return notes, nil
+}
+
+// RenderUpgradeNotes writes the notes for display, flagging the breaking
+// changes.
+func RenderUpgradeNotes(w io.Writer, notes []ChangelogEntry) error {
+	for _, entry := range notes {
+		title := entry.Version
+		if entry.Breaking {
+			title += " (BREAKING)"
+		}
+		if _, err := fmt.Fprintf(w, "%s\n%s\n", title, strings.Repeat("-", len(title))); err != nil {
+			return err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(entry.Notes), "\n") {
+			if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
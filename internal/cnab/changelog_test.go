@@ -0,0 +1,45 @@
+package cnab_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestUpgradeNotes(t *testing.T) {
+	target := bundletest.NewTestBundle(bundletest.WithVersion("1.3.0"), bundletest.WithCustom(cnab.ChangelogExtensionKey, []cnab.ChangelogEntry{
+		{Version: "1.3.0", Notes: "Add a cache"},
+		{Version: "1.1.0", Notes: "Fix a leak"},
+		{Version: "1.2.0", Notes: "Rename the port parameter\nUpdate your parameters files", Breaking: true},
+		{Version: "1.4.0", Notes: "Not released yet"},
+	}))
+
+	notes, err := cnab.UpgradeNotes("1.1.0", target)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, notes, []cnab.ChangelogEntry{
+		{Version: "1.2.0", Notes: "Rename the port parameter\nUpdate your parameters files", Breaking: true},
+		{Version: "1.3.0", Notes: "Add a cache"},
+	})
+	buf := bytes.NewBuffer(nil)
+	assert.NilError(t, cnab.RenderUpgradeNotes(buf, notes))
+	assert.Equal(t, buf.String(), `1.2.0 (BREAKING)
+----------------
+  Rename the port parameter
+  Update your parameters files
+
+1.3.0
+-----
+  Add a cache
+
+`)
+
+	notes, err = cnab.UpgradeNotes("1.0.0", bundletest.NewTestBundle())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(notes, 0))
+	_, err = cnab.UpgradeNotes("dev", target)
+	assert.Check(t, is.ErrorContains(err, "cannot select the upgrade notes from dev"))
+}
@@ -0,0 +1,210 @@
+package cnab
+
+import (
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// CompositeExtensionKey is the custom extension declaring the child bundles
+// of a composite bundle.
+const CompositeExtensionKey = internal.Namespace + "composite"
+
+// Composite is the content of the composite extension. A composite bundle
+// ships a whole stack: installing it installs each of its children.
+type Composite struct {
+	Children []CompositeChild `json:"children"`
+}
+
+// CompositeChild is a bundle embedded in, or referenced by, a composite
+// bundle.
+type CompositeChild struct {
+	// Name identifies the child in the composite bundle. The child
+	// installation is named after the parent installation and this name.
+	Name string `json:"name"`
+	// Bundle is the reference of the child bundle, when it is not embedded.
+	Bundle string `json:"bundle,omitempty"`
+	// Embedded is the child bundle itself.
+	Embedded *bundle.Bundle `json:"embedded,omitempty"`
+	// Parameters maps the child parameters to the parent parameters they
+	// take their value from.
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Credentials maps the child credentials to the parent credentials they
+	// take their value from.
+	Credentials map[string]string `json:"credentials,omitempty"`
+	// DependsOn lists the children which must be installed before this one.
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// ReadComposite returns the children declared by the bundle, or nil if it is
+// not a composite bundle.
+func ReadComposite(b *bundle.Bundle) (*Composite, error) {
+	var composite Composite
+	found, err := GetCustomExtension(b, CompositeExtensionKey, &composite)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &composite, nil
+}
+
+// CompositeStep is the execution of a child bundle.
+type CompositeStep struct {
+	Child        string
+	Installation string
+	// Reference is empty for embedded bundles.
+	Reference   string
+	Bundle      *bundle.Bundle
+	Parameters  map[string]interface{}
+	Credentials credentials.Set
+}
+
+// CompositePlan lists the child executions of a composite bundle, in
+// installation order.
+type CompositePlan struct {
+	Steps []CompositeStep
+}
+
+// Run runs the steps in installation order, stopping at the first failure.
+func (p *CompositePlan) Run(run func(step CompositeStep) error) error {
+	return runSteps(p.Steps, run)
+}
+
+// RunReverse runs the steps in uninstallation order, stopping at the first
+// failure.
+func (p *CompositePlan) RunReverse(run func(step CompositeStep) error) error {
+	steps := make([]CompositeStep, len(p.Steps))
+	for i, step := range p.Steps {
+		steps[len(steps)-1-i] = step
+	}
+	return runSteps(steps, run)
+}
+
+func runSteps(steps []CompositeStep, run func(step CompositeStep) error) error {
+	for _, step := range steps {
+		if err := run(step); err != nil {
+			return errors.Wrapf(err, "failed to run child bundle %q", step.Child)
+		}
+	}
+	return nil
+}
+
+// PlanComposite builds the execution plan of a composite bundle installed as
+// installation with the given parameters and credentials. Referenced children
+// are fetched, and the parent values are mapped to each child. Children are
+// ordered so that each one comes after those it depends on, in declaration
+// order otherwise.
+func PlanComposite(parent *bundle.Bundle, installation string, parameters map[string]interface{}, creds credentials.Set, fetch BundleFetcher) (*CompositePlan, error) {
+	composite, err := ReadComposite(parent)
+	if err != nil {
+		return nil, err
+	}
+	if composite == nil {
+		return nil, errors.Errorf("bundle %q is not a composite bundle", parent.Name)
+	}
+	children := map[string]CompositeChild{}
+	for _, child := range composite.Children {
+		if child.Name == "" {
+			return nil, errors.New("invalid composite bundle: child name is empty")
+		}
+		if _, ok := children[child.Name]; ok {
+			return nil, errors.Errorf("invalid composite bundle: duplicate child %q", child.Name)
+		}
+		if (child.Bundle == "") == (child.Embedded == nil) {
+			return nil, errors.Errorf("invalid composite bundle: child %q must either reference or embed a bundle", child.Name)
+		}
+		children[child.Name] = child
+	}
+	order, err := orderChildren(composite.Children, children)
+	if err != nil {
+		return nil, err
+	}
+	plan := &CompositePlan{}
+	for _, child := range order {
+		step, err := planChild(child, installation, parameters, creds, fetch)
+		if err != nil {
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan, nil
+}
+
+func orderChildren(declared []CompositeChild, children map[string]CompositeChild) ([]CompositeChild, error) {
+	state := map[string]int{}
+	var order []CompositeChild
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+		switch state[name] {
+		case visiting:
+			return errors.Errorf("invalid composite bundle: dependency cycle between children %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range children[name].DependsOn {
+			if _, ok := children[dep]; !ok {
+				return errors.Errorf("invalid composite bundle: child %q depends on unknown child %q", name, dep)
+			}
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, children[name])
+		return nil
+	}
+	for _, child := range declared {
+		if err := visit(child.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func planChild(child CompositeChild, installation string, parameters map[string]interface{}, creds credentials.Set, fetch BundleFetcher) (CompositeStep, error) {
+	step := CompositeStep{
+		Child:        child.Name,
+		Installation: installation + "-" + child.Name,
+		Bundle:       child.Embedded,
+		Parameters:   map[string]interface{}{},
+		Credentials:  credentials.Set{},
+	}
+	if child.Bundle != "" {
+		bundleRef, err := ParseBundleReference(child.Bundle)
+		if err != nil {
+			return step, errors.Wrapf(err, "invalid reference of child bundle %q", child.Name)
+		}
+		ref, err := bundleRef.Named()
+		if err != nil {
+			return step, errors.Wrapf(err, "invalid reference of child bundle %q", child.Name)
+		}
+		if step.Bundle, err = fetch(ref); err != nil {
+			return step, errors.Wrapf(err, "failed to fetch child bundle %q", child.Name)
+		}
+		step.Reference = ref.String()
+	}
+	for childParam, parentParam := range child.Parameters {
+		if _, ok := step.Bundle.Parameters[childParam]; !ok {
+			return step, errors.Errorf("child bundle %q has no parameter %q", child.Name, childParam)
+		}
+		// Unset parent parameters leave the child default value
+		if value, ok := parameters[parentParam]; ok {
+			step.Parameters[childParam] = value
+		}
+	}
+	for childCred, parentCred := range child.Credentials {
+		if _, ok := step.Bundle.Credentials[childCred]; !ok {
+			return step, errors.Errorf("child bundle %q has no credential %q", child.Name, childCred)
+		}
+		value, ok := creds[parentCred]
+		if !ok {
+			return step, errors.Errorf("credential %q required by child bundle %q is missing", parentCred, child.Name)
+		}
+		step.Credentials[childCred] = value
+	}
+	return step, nil
+}
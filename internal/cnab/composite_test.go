@@ -0,0 +1,90 @@
+package cnab_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func compositeBundle(children ...cnab.CompositeChild) *bundle.Bundle {
+	return bundletest.NewTestBundle(bundletest.WithName("stack"),
+		bundletest.WithCustom(cnab.CompositeExtensionKey, cnab.Composite{Children: children}))
+}
+
+func TestPlanComposite(t *testing.T) {
+	db := bundletest.NewTestBundle(bundletest.WithName("db"), bundletest.WithParameters(2), bundletest.WithCredentials(1))
+	parent := compositeBundle(
+		cnab.CompositeChild{
+			Name:      "web",
+			Embedded:  bundletest.NewTestBundle(bundletest.WithName("web"), bundletest.WithParameters(1)),
+			DependsOn: []string{"db"},
+			Parameters: map[string]string{
+				"param-0": "web-port",
+			},
+		},
+		cnab.CompositeChild{
+			Name:        "db",
+			Bundle:      "db:1.2.0",
+			Parameters:  map[string]string{"param-0": "db-size", "param-1": "unset"},
+			Credentials: map[string]string{"cred-0": "db-password"},
+		},
+	)
+	parameters := map[string]interface{}{"web-port": "8080", "db-size": "10G"}
+	creds := credentials.Set{"db-password": "secret"}
+
+	plan, err := cnab.PlanComposite(parent, "prod", parameters, creds, fetcher(map[string]*bundle.Bundle{"docker.io/library/db:1.2.0": db}))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(plan.Steps, 2))
+	assert.Equal(t, plan.Steps[0].Installation, "prod-db")
+	assert.Equal(t, plan.Steps[0].Reference, "docker.io/library/db:1.2.0")
+	assert.DeepEqual(t, plan.Steps[0].Parameters, map[string]interface{}{"param-0": "10G"})
+	assert.DeepEqual(t, plan.Steps[0].Credentials, credentials.Set{"cred-0": "secret"})
+	assert.Equal(t, plan.Steps[1].Installation, "prod-web")
+	assert.Equal(t, plan.Steps[1].Bundle.Name, "web")
+	assert.DeepEqual(t, plan.Steps[1].Parameters, map[string]interface{}{"param-0": "8080"})
+
+	var ran []string
+	assert.NilError(t, plan.Run(func(step cnab.CompositeStep) error {
+		ran = append(ran, step.Child)
+		return nil
+	}))
+	err = plan.RunReverse(func(step cnab.CompositeStep) error {
+		ran = append(ran, step.Child)
+		return errors.New("boom")
+	})
+	assert.Check(t, is.Error(err, `failed to run child bundle "web": boom`))
+	assert.DeepEqual(t, ran, []string{"db", "web", "web"})
+}
+
+func TestPlanCompositeErrors(t *testing.T) {
+	embedded := bundletest.NewTestBundle(bundletest.WithCredentials(1))
+	testCases := []struct {
+		name     string
+		parent   *bundle.Bundle
+		expected string
+	}{
+		{"not composite", bundletest.NewTestBundle(), `bundle "test-bundle" is not a composite bundle`},
+		{"no bundle", compositeBundle(cnab.CompositeChild{Name: "a"}), `child "a" must either reference or embed a bundle`},
+		{"duplicate", compositeBundle(cnab.CompositeChild{Name: "a", Embedded: embedded}, cnab.CompositeChild{Name: "a", Embedded: embedded}), `duplicate child "a"`},
+		{"unknown dependency", compositeBundle(cnab.CompositeChild{Name: "a", Embedded: embedded, DependsOn: []string{"b"}}), `child "a" depends on unknown child "b"`},
+		{"cycle", compositeBundle(
+			cnab.CompositeChild{Name: "a", Embedded: embedded, DependsOn: []string{"b"}},
+			cnab.CompositeChild{Name: "b", Embedded: embedded, DependsOn: []string{"a"}},
+		), "dependency cycle between children a -> b -> a"},
+		{"unknown parameter", compositeBundle(cnab.CompositeChild{Name: "a", Embedded: embedded, Parameters: map[string]string{"foo": "bar"}}), `child bundle "a" has no parameter "foo"`},
+		{"missing credential", compositeBundle(cnab.CompositeChild{Name: "a", Embedded: embedded, Credentials: map[string]string{"cred-0": "password"}}), `credential "password" required by child bundle "a" is missing`},
+		{"missing bundle", compositeBundle(cnab.CompositeChild{Name: "a", Bundle: "a:1.0.0"}), `failed to fetch child bundle "a"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cnab.PlanComposite(tc.parent, "prod", nil, nil, fetcher(nil))
+			assert.Check(t, is.ErrorContains(err, tc.expected))
+		})
+	}
+}
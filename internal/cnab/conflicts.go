@@ -0,0 +1,112 @@
+package cnab
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+)
+
+// reservedPaths are the files of the invocation image written by the
+// runtime, which parameters and credentials cannot be injected in.
+var reservedPaths = []string{
+	"/cnab/bundle.json",
+	"/cnab/claim.json",
+	"/cnab/app/image-map.json",
+}
+
+// injectedLocation is where a parameter or a credential is injected, for
+// the actions it applies to, empty for all.
+type injectedLocation struct {
+	source   string
+	path     string
+	location string
+	applyTo  []string
+}
+
+// locationConflicts reports the parameters and credentials injected in the
+// same environment variable or file as another one for a common action, or
+// in a variable or file reserved by the runtime. Parameters without
+// destination are injected in CNAB_P_<NAME>. The arguments of the custom
+// actions are checked as well, invalid arguments being reported apart.
+func locationConflicts(b *bundle.Bundle) ValidationErrors {
+	var locations []injectedLocation
+	addLocation := func(source, path string, loc bundle.Location, applyTo []string) {
+		if loc.EnvironmentVariable != "" {
+			locations = append(locations, injectedLocation{source, path + ".env", "environment variable " + loc.EnvironmentVariable, applyTo})
+		}
+		if loc.Path != "" {
+			locations = append(locations, injectedLocation{source, path + ".path", "file " + loc.Path, applyTo})
+		}
+	}
+	for _, name := range ParameterNames(b) {
+		def := b.Parameters[name]
+		path := fmt.Sprintf("$.parameters[%q].destination", name)
+		loc := bundle.Location{EnvironmentVariable: "CNAB_P_" + strings.ToUpper(name)}
+		if def.Destination != nil {
+			loc = *def.Destination
+		}
+		addLocation(fmt.Sprintf("parameter %q", name), path, loc, def.ApplyTo)
+	}
+	for _, name := range CredentialNames(b) {
+		addLocation(fmt.Sprintf("credential %q", name), fmt.Sprintf("$.credentials[%q]", name), b.Credentials[name], nil)
+	}
+	args, _ := ReadActionArguments(b)
+	for _, action := range sortedActionArgumentKeys(args) {
+		for _, name := range sortedParameterNames(args[action]) {
+			path := fmt.Sprintf("$.custom[%q][%q][%q].destination", ActionArgumentsExtensionKey, action, name)
+			addLocation(fmt.Sprintf("argument %q of action %q", name, action), path, ArgumentLocation(name, args[action][name]), []string{action})
+		}
+	}
+
+	var errs ValidationErrors
+	add := func(path, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Path: path, Code: CodeLocationConflict, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+	}
+	for i, loc := range locations {
+		if reserved, ok := reservedLocation(loc.location); ok {
+			add(loc.path, "%s cannot be injected in the %s, which is %s", loc.source, loc.location, reserved)
+			continue
+		}
+		for _, other := range locations[:i] {
+			if other.location == loc.location && actionsOverlap(other.applyTo, loc.applyTo) {
+				add(loc.path, "%s and %s are both injected in the %s", other.source, loc.source, loc.location)
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// reservedLocation tells why the location is reserved, if it is.
+func reservedLocation(location string) (string, bool) {
+	if env := strings.TrimPrefix(location, "environment variable "); env != location {
+		if strings.HasPrefix(env, "CNAB_") && !strings.HasPrefix(env, "CNAB_P_") && !strings.HasPrefix(env, ArgumentEnvPrefix) {
+			return "reserved by the CNAB runtime", true
+		}
+		return "", false
+	}
+	path := strings.TrimPrefix(location, "file ")
+	for _, reserved := range reservedPaths {
+		if path == reserved {
+			return "written by the CNAB runtime", true
+		}
+	}
+	return "", false
+}
+
+// actionsOverlap returns true if two apply-to lists have an action in
+// common, an empty list applying to all actions.
+func actionsOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
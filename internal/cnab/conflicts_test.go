@@ -0,0 +1,67 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestValidateLocationConflicts(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0"}}},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"port":     {DataType: "int"},
+			"override": {DataType: "int", Destination: &bundle.Location{EnvironmentVariable: "CNAB_P_PORT"}},
+			"config":   {DataType: "string", Destination: &bundle.Location{Path: "/cnab/app/config.yaml"}},
+			"action":   {DataType: "string", Destination: &bundle.Location{EnvironmentVariable: "CNAB_ACTION"}},
+			"images":   {DataType: "string", Destination: &bundle.Location{Path: "/cnab/app/image-map.json"}},
+		},
+		Credentials: map[string]bundle.Location{
+			"kubeconfig": {Path: "/cnab/app/config.yaml"},
+			"token":      {EnvironmentVariable: "CNAB_BUNDLE_NAME"},
+		},
+	}
+	errs := Validate(b)
+	var paths []string
+	for _, err := range errs {
+		assert.Check(t, is.Equal(err.Code, CodeLocationConflict))
+		assert.Check(t, is.Equal(err.Severity, SeverityError))
+		paths = append(paths, err.Path)
+	}
+	assert.Check(t, is.DeepEqual(paths, []string{
+		`$.parameters["action"].destination.env`,
+		`$.parameters["images"].destination.path`,
+		`$.parameters["port"].destination.env`,
+		`$.credentials["kubeconfig"].path`,
+		`$.credentials["token"].env`,
+	}))
+	assert.Check(t, is.ErrorContains(errs.Err(), `$.parameters["port"].destination.env: parameter "override" and parameter "port" are both injected in the environment variable CNAB_P_PORT`))
+	assert.Check(t, is.ErrorContains(errs.Err(), `$.credentials["kubeconfig"].path: parameter "config" and credential "kubeconfig" are both injected in the file /cnab/app/config.yaml`))
+	assert.Check(t, is.ErrorContains(errs.Err(), `$.credentials["token"].env: credential "token" cannot be injected in the environment variable CNAB_BUNDLE_NAME, which is reserved by the CNAB runtime`))
+	assert.Check(t, is.ErrorContains(errs.Err(), `$.parameters["images"].destination.path: parameter "images" cannot be injected in the file /cnab/app/image-map.json, which is written by the CNAB runtime`))
+}
+
+func TestValidateLocationConflictsOfDisjointActions(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0"}}},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"install-config": {DataType: "string", ApplyTo: []string{"install"}, Destination: &bundle.Location{Path: "/cnab/app/config.yaml"}},
+			"upgrade-config": {DataType: "string", ApplyTo: []string{"upgrade"}, Destination: &bundle.Location{Path: "/cnab/app/config.yaml"}},
+			"debug":          {DataType: "boolean", ApplyTo: []string{"install", "upgrade"}, Destination: &bundle.Location{EnvironmentVariable: "DEBUG"}},
+			"verbose":        {DataType: "boolean", ApplyTo: []string{"upgrade"}, Destination: &bundle.Location{EnvironmentVariable: "DEBUG"}},
+			"templated":      {DataType: "string", Destination: &bundle.Location{Path: "/cnab/app/{{ .CNAB_INSTALLATION_NAME }}.yaml"}},
+		},
+		Credentials: map[string]bundle.Location{
+			"templated": {Path: "/cnab/app/{{.CNAB_INSTALLATION_NAME}}/token"},
+		},
+	}
+	errs := Validate(b)
+	assert.Check(t, is.Len(errs, 1))
+	assert.Check(t, is.ErrorContains(errs.Err(), `$.parameters["verbose"].destination.env: parameter "debug" and parameter "verbose" are both injected in the environment variable DEBUG`))
+}
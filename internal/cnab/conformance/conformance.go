@@ -0,0 +1,178 @@
+// Package conformance runs a corpus of bundle documents through decoding and
+// validation and reports, for each spec rule, which documents were accepted
+// or rejected as expected.
+//
+// A corpus is a directory with one sub-directory per rule, each holding a
+// "valid" and an "invalid" directory of JSON documents:
+//
+//	corpus/
+//	  invocation-images/
+//	    valid/one-image.json
+//	    invalid/no-image.json
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/pkg/errors"
+)
+
+// Case is a single corpus document.
+type Case struct {
+	Rule string
+	Name string
+	// Valid tells whether the document complies with the rule.
+	Valid bool
+	Path  string
+}
+
+// Result is the outcome of a case.
+type Result struct {
+	Case
+	// Err is the decoding or validation error, if any.
+	Err error
+}
+
+// Passed returns true if the document was accepted if and only if it is
+// valid.
+func (r Result) Passed() bool {
+	return r.Valid == (r.Err == nil)
+}
+
+// Report holds the results of a corpus run, sorted by rule and name.
+type Report struct {
+	Results []Result
+}
+
+// Passed returns true if all the cases passed.
+func (r Report) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the cases which did not pass.
+func (r Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if !result.Passed() {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// Rules returns, for each rule, whether all its cases passed.
+func (r Report) Rules() map[string]bool {
+	rules := map[string]bool{}
+	for _, result := range r.Results {
+		passed, ok := rules[result.Rule]
+		rules[result.Rule] = result.Passed() && (passed || !ok)
+	}
+	return rules
+}
+
+// Print writes a human readable summary of the report.
+func (r Report) Print(w io.Writer) error {
+	rules := r.Rules()
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		status := "PASS"
+		if !rules[name] {
+			status = "FAIL"
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", status, name); err != nil {
+			return err
+		}
+	}
+	for _, failure := range r.Failures() {
+		expected := "rejected"
+		if failure.Valid {
+			expected = "accepted"
+		}
+		if _, err := fmt.Fprintf(w, "  %s/%s: expected to be %s (error: %v)\n", failure.Rule, failure.Name, expected, failure.Err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCorpus lists the cases of the corpus rooted at dir.
+func LoadCorpus(dir string) ([]Case, error) {
+	rules, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read conformance corpus %q", dir)
+	}
+	var cases []Case
+	for _, rule := range rules {
+		if !rule.IsDir() {
+			continue
+		}
+		for _, kind := range []string{"valid", "invalid"} {
+			matches, err := filepath.Glob(filepath.Join(dir, rule.Name(), kind, "*.json"))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(matches)
+			for _, path := range matches {
+				cases = append(cases, Case{
+					Rule:  rule.Name(),
+					Name:  strings.TrimSuffix(filepath.Base(path), ".json"),
+					Valid: kind == "valid",
+					Path:  path,
+				})
+			}
+		}
+	}
+	if len(cases) == 0 {
+		return nil, errors.Errorf("conformance corpus %q is empty", dir)
+	}
+	return cases, nil
+}
+
+// Run loads the corpus rooted at dir and checks every case.
+func Run(dir string) (Report, error) {
+	cases, err := LoadCorpus(dir)
+	if err != nil {
+		return Report{}, err
+	}
+	var report Report
+	for _, c := range cases {
+		report.Results = append(report.Results, Check(c))
+	}
+	return report, nil
+}
+
+// Check decodes and validates a case document.
+func Check(c Case) Result {
+	data, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return Result{Case: c, Err: err}
+	}
+	b, err := cnab.Parse(data)
+	if err == nil {
+		err = b.Validate()
+	}
+	return Result{Case: c, Err: err}
+}
+
+// CorpusEnvVar points the conformance tests to an external corpus, such as
+// a checkout of the published CNAB spec examples.
+const CorpusEnvVar = "DOCKER_APP_CONFORMANCE_CORPUS"
+
+// CorpusDir returns the corpus set in the environment, or def.
+func CorpusDir(def string) string {
+	if dir := os.Getenv(CorpusEnvVar); dir != "" {
+		return dir
+	}
+	return def
+}
@@ -0,0 +1,48 @@
+package conformance
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCorpus(t *testing.T) {
+	report, err := Run(CorpusDir("testdata/corpus"))
+	assert.NilError(t, err)
+	out := bytes.NewBuffer(nil)
+	assert.NilError(t, report.Print(out))
+	assert.Assert(t, report.Passed(), out.String())
+}
+
+func TestReportFailures(t *testing.T) {
+	cases, err := LoadCorpus("testdata/corpus")
+	assert.NilError(t, err)
+	// Flip the expectation of a case, to check failures are reported
+	var results []Result
+	for _, c := range cases {
+		if c.Rule == "version" {
+			c.Valid = !c.Valid
+		}
+		results = append(results, Check(c))
+	}
+	report := Report{Results: results}
+	assert.Check(t, !report.Passed())
+	assert.Check(t, is.Len(report.Failures(), 2))
+	assert.DeepEqual(t, report.Rules(), map[string]bool{
+		"image-tag":         true,
+		"invocation-images": true,
+		"json":              true,
+		"version":           false,
+	})
+	out := bytes.NewBuffer(nil)
+	assert.NilError(t, report.Print(out))
+	assert.Check(t, is.Contains(out.String(), "FAIL\tversion\n"))
+	assert.Check(t, is.Contains(out.String(), "version/latest: expected to be accepted"))
+}
+
+func TestEmptyCorpus(t *testing.T) {
+	_, err := Run("testdata/corpus/json/valid")
+	assert.Check(t, is.ErrorContains(err, "is empty"))
+}
@@ -0,0 +1,198 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/compose"
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/docker/cli/cli/compose/template"
+	"github.com/pkg/errors"
+)
+
+// ComposeOptions tunes the conversion of a Compose file.
+type ComposeOptions struct {
+	// Name and Version are the name and version of the bundle, required.
+	Name    string
+	Version string
+	// InvocationImage is the image deploying the Compose file, which
+	// defaults to "<name>-installer:<version>".
+	InvocationImage string
+}
+
+// ComposeResult is a bundle converted from a Compose file.
+type ComposeResult struct {
+	Bundle *bundle.Bundle
+	// Warnings describe the constructs of the Compose file the bundle cannot
+	// represent, like bind mounts or services built from sources, sorted.
+	Warnings []string
+}
+
+// ComposeSecretsDirectory is where the invocation image receives the secrets
+// of the Compose file, as credentials.
+const ComposeSecretsDirectory = "/run/secrets/"
+
+// Compose generates a bundle skeleton from a Compose file:
+//
+//   - the images of the services are the images of the bundle, named after
+//     the services,
+//   - the variables of the file, like ${PORT:-80}, are parameters with their
+//     default values,
+//   - the environment variables of the services are parameters with their
+//     values as default, named like "web.environment.LOG_LEVEL",
+//   - the secrets are credentials, injected in ComposeSecretsDirectory.
+//
+// The bundle is validated, it still needs an invocation image deploying the
+// file.
+func Compose(data []byte, opts ComposeOptions) (*ComposeResult, error) {
+	if opts.Name == "" || opts.Version == "" {
+		return nil, errors.New("the bundle name and version are required")
+	}
+	config, err := loader.ParseYAML(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the Compose file")
+	}
+	invocationImage := opts.InvocationImage
+	if invocationImage == "" {
+		invocationImage = opts.Name + "-installer:" + opts.Version
+	}
+	builder := cnab.NewBuilder(opts.Name, opts.Version).
+		AddInvocationImage(bundle.InvocationImage{BaseImage: bundle.BaseImage{ImageType: "docker", Image: invocationImage}})
+	c := &composeConverter{parameters: map[string]bundle.ParameterDefinition{}}
+
+	variables := template.ExtractVariables(config, compose.ExtrapolationPattern)
+	for name, value := range variables {
+		c.parameters[name] = bundle.ParameterDefinition{DataType: "string", Default: value}
+	}
+	services, _ := config["services"].(map[string]interface{})
+	for _, name := range sortedMapKeys(services) {
+		service, ok := services[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if image := c.serviceImage(name, service); image != "" {
+			builder.AddImage(name, bundle.Image{Description: image, BaseImage: bundle.BaseImage{ImageType: "docker", Image: image}})
+		}
+		if err := c.serviceEnvironment(name, service); err != nil {
+			return nil, err
+		}
+		c.checkService(name, service)
+	}
+	for _, name := range cnab.ParameterNames(&bundle.Bundle{Parameters: c.parameters}) {
+		builder.AddParameter(name, c.parameters[name])
+	}
+	secrets, _ := config["secrets"].(map[string]interface{})
+	for _, name := range sortedMapKeys(secrets) {
+		builder.AddCredential(name, bundle.Location{Path: ComposeSecretsDirectory + name})
+	}
+	configs, _ := config["configs"].(map[string]interface{})
+	for _, name := range sortedMapKeys(configs) {
+		if cfg, ok := configs[name].(map[string]interface{}); ok && cfg["file"] != nil {
+			c.warn("config %q is read from the file %v, which is not part of the bundle", name, cfg["file"])
+		}
+	}
+
+	b, err := builder.Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert the Compose file")
+	}
+	sort.Strings(c.warnings)
+	return &ComposeResult{Bundle: b, Warnings: c.warnings}, nil
+}
+
+type composeConverter struct {
+	parameters map[string]bundle.ParameterDefinition
+	warnings   []string
+}
+
+func (c *composeConverter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// serviceImage returns the image of the service, if the bundle can hold it.
+func (c *composeConverter) serviceImage(name string, service map[string]interface{}) string {
+	image, _ := service["image"].(string)
+	switch {
+	case image == "" && service["build"] != nil:
+		c.warn("service %q is built from sources, it needs an image to be part of the bundle", name)
+	case image == "":
+		c.warn("service %q has no image", name)
+	case compose.ExtrapolationPattern.MatchString(image):
+		c.warn("service %q has a variable image %q, which cannot be part of the bundle", name, image)
+	default:
+		return image
+	}
+	return ""
+}
+
+// serviceEnvironment adds a parameter per environment variable of the
+// service, but those set from variables of the file.
+func (c *composeConverter) serviceEnvironment(name string, service map[string]interface{}) error {
+	environment := map[string]interface{}{}
+	switch env := service["environment"].(type) {
+	case map[string]interface{}:
+		environment = env
+	case []interface{}:
+		for _, entry := range env {
+			split := strings.SplitN(fmt.Sprint(entry), "=", 2)
+			if len(split) == 2 {
+				environment[split[0]] = split[1]
+			} else {
+				environment[split[0]] = nil
+			}
+		}
+	}
+	for key, value := range environment {
+		if s, ok := value.(string); ok && compose.ExtrapolationPattern.MatchString(s) {
+			continue
+		}
+		def, err := parameterDefinition(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid environment variable %s of service %q", key, name)
+		}
+		c.parameters[name+".environment."+key] = def
+	}
+	return nil
+}
+
+// checkService flags the settings of the service which tie it to a host.
+func (c *composeConverter) checkService(name string, service map[string]interface{}) {
+	if privileged, _ := service["privileged"].(bool); privileged {
+		c.warn("service %q is privileged", name)
+	}
+	for _, key := range []string{"network_mode", "pid", "ipc"} {
+		if mode, _ := service[key].(string); mode == "host" {
+			c.warn("service %q uses the %s of the host", name, key)
+		}
+	}
+	if service["devices"] != nil {
+		c.warn("service %q uses devices of the host", name)
+	}
+	volumes, _ := service["volumes"].([]interface{})
+	for _, volume := range volumes {
+		source := ""
+		switch v := volume.(type) {
+		case string:
+			source = strings.SplitN(v, ":", 2)[0]
+		case map[string]interface{}:
+			if t, _ := v["type"].(string); t == "bind" {
+				source, _ = v["source"].(string)
+			}
+		}
+		if strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") {
+			c.warn("service %q bind mounts %s, which is not part of the bundle", name, source)
+		}
+	}
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
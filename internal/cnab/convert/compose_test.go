@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCompose(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/docker-compose.yml")
+	assert.NilError(t, err)
+	result, err := Compose(data, ComposeOptions{Name: "myapp", Version: "0.1.0"})
+	assert.NilError(t, err)
+	b := result.Bundle
+	assert.Check(t, is.Equal(b.InvocationImages[0].Image, "myapp-installer:0.1.0"))
+
+	images := map[string]string{}
+	for name, img := range b.Images {
+		images[name] = img.Image
+	}
+	assert.Check(t, is.DeepEqual(images, map[string]string{"web": "example/web:1.0", "db": "postgres:11"}))
+
+	defaults := map[string]interface{}{}
+	for name, def := range b.Parameters {
+		defaults[name] = def.Default
+	}
+	assert.Check(t, is.DeepEqual(defaults, map[string]interface{}{
+		"PORT":                         "8080",
+		"DATABASE_URL":                 "",
+		"TOOL_VERSION":                 "latest",
+		"web.environment.LOG_LEVEL":    "info",
+		"web.environment.WORKERS":      4,
+		"web.environment.DEBUG":        "false",
+		"db.environment.POSTGRES_USER": "app",
+		"db.environment.POSTGRES_DB":   nil,
+	}))
+	assert.Check(t, is.Equal(b.Parameters["web.environment.WORKERS"].DataType, "int"))
+
+	assert.Check(t, is.DeepEqual(b.Credentials, map[string]bundle.Location{
+		"db_password": {Path: "/run/secrets/db_password"},
+	}))
+
+	assert.Check(t, is.DeepEqual(result.Warnings, []string{
+		`config "nginx" is read from the file ./nginx.conf, which is not part of the bundle`,
+		`service "tool" has a variable image "example/tool:${TOOL_VERSION:-latest}", which cannot be part of the bundle`,
+		`service "web" bind mounts ./static, which is not part of the bundle`,
+		`service "worker" is built from sources, it needs an image to be part of the bundle`,
+		`service "worker" is privileged`,
+		`service "worker" uses the network_mode of the host`,
+	}))
+}
+
+func TestComposeErrors(t *testing.T) {
+	_, err := Compose([]byte("services: {}\n"), ComposeOptions{Name: "myapp"})
+	assert.Check(t, is.Error(err, "the bundle name and version are required"))
+
+	_, err = Compose([]byte("- not a mapping\n"), ComposeOptions{Name: "myapp", Version: "1.0.0"})
+	assert.Check(t, is.ErrorContains(err, "failed to parse the Compose file"))
+
+	_, err = Compose([]byte("services:\n  web:\n    image: Not A Reference\n"), ComposeOptions{Name: "myapp", Version: "1.0.0"})
+	assert.Check(t, is.ErrorContains(err, "cannot convert the Compose file"))
+}
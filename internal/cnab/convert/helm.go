@@ -0,0 +1,249 @@
+// Package convert generates bundle skeletons from other packaging formats,
+// to script the migration of existing applications to bundles.
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/yaml"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// Files of a Helm chart directory.
+const (
+	HelmChartFile  = "Chart.yaml"
+	HelmValuesFile = "values.yaml"
+)
+
+// HelmOptions tunes the conversion of a Helm chart.
+type HelmOptions struct {
+	// InvocationImage is the image installing the chart, which defaults to
+	// "<chart name>-installer:<chart version>".
+	InvocationImage string
+}
+
+// helmChart is the metadata of a chart, from its Chart.yaml.
+type helmChart struct {
+	Name        string   `yaml:"name"`
+	Version     string   `yaml:"version"`
+	AppVersion  string   `yaml:"appVersion"`
+	Description string   `yaml:"description"`
+	Keywords    []string `yaml:"keywords"`
+	Maintainers []struct {
+		Name  string `yaml:"name"`
+		Email string `yaml:"email"`
+		URL   string `yaml:"url"`
+	} `yaml:"maintainers"`
+}
+
+// HelmChart generates a bundle from the Chart.yaml and the optional
+// values.yaml of a chart directory, see HelmChartFiles.
+func HelmChart(dir string, opts HelmOptions) (*bundle.Bundle, error) {
+	chart, err := ioutil.ReadFile(filepath.Join(dir, HelmChartFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the chart")
+	}
+	values, err := ioutil.ReadFile(filepath.Join(dir, HelmValuesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read the chart values")
+	}
+	return HelmChartFiles(chart, values, opts)
+}
+
+// HelmChartFiles generates a bundle skeleton from the Chart.yaml and
+// values.yaml documents of a chart:
+//
+//   - the name, version, description, keywords and maintainers of the chart
+//     are the ones of the bundle,
+//   - each value is a parameter with the value as default, the keys of nested
+//     values being joined with dots like "image.tag". Lists and other values
+//     are passed as JSON strings, and values without default as strings,
+//   - the images the values refer to, either as an "image" string or as an
+//     "image" object with a "repository", and optionally a "registry" and a
+//     "tag" defaulting to the version of the application, are the images of
+//     the bundle. They are named after the values holding them, like "web"
+//     for "web.image", or after the chart for the top level one.
+//
+// The bundle is validated, it still needs an invocation image running Helm.
+func HelmChartFiles(chartYAML, valuesYAML []byte, opts HelmOptions) (*bundle.Bundle, error) {
+	var chart helmChart
+	if err := yaml.Unmarshal(chartYAML, &chart); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", HelmChartFile)
+	}
+	if chart.Name == "" || chart.Version == "" {
+		return nil, errors.Errorf("invalid %s: the chart name and version are required", HelmChartFile)
+	}
+	var values map[interface{}]interface{}
+	if err := yaml.Unmarshal(valuesYAML, &values); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", HelmValuesFile)
+	}
+
+	invocationImage := opts.InvocationImage
+	if invocationImage == "" {
+		invocationImage = chart.Name + "-installer:" + chart.Version
+	}
+	builder := cnab.NewBuilder(chart.Name, chart.Version).
+		Description(chart.Description).
+		AddInvocationImage(bundle.InvocationImage{BaseImage: bundle.BaseImage{ImageType: "docker", Image: invocationImage}})
+
+	parameters := map[string]bundle.ParameterDefinition{}
+	if err := flattenValues("", values, parameters); err != nil {
+		return nil, err
+	}
+	for _, name := range cnab.ParameterNames(&bundle.Bundle{Parameters: parameters}) {
+		builder.AddParameter(name, parameters[name])
+	}
+
+	images := map[string]string{}
+	if err := findImages(chart, "", values, images); err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(images) {
+		builder.AddImage(name, bundle.Image{BaseImage: bundle.BaseImage{ImageType: "docker", Image: images[name]}})
+	}
+
+	b, err := builder.Build()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot convert chart %s", chart.Name)
+	}
+	b.Keywords = chart.Keywords
+	for _, m := range chart.Maintainers {
+		b.Maintainers = append(b.Maintainers, bundle.Maintainer{Name: m.Name, Email: m.Email, URL: m.URL})
+	}
+	return b, nil
+}
+
+// flattenValues adds a parameter per value.
+func flattenValues(prefix string, values map[interface{}]interface{}, parameters map[string]bundle.ParameterDefinition) error {
+	for key, value := range values {
+		name := prefix + fmt.Sprint(key)
+		if nested, ok := value.(map[interface{}]interface{}); ok && len(nested) > 0 {
+			if err := flattenValues(name+".", nested, parameters); err != nil {
+				return err
+			}
+			continue
+		}
+		def, err := parameterDefinition(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value %q", name)
+		}
+		parameters[name] = def
+	}
+	return nil
+}
+
+func parameterDefinition(value interface{}) (bundle.ParameterDefinition, error) {
+	switch v := value.(type) {
+	case nil:
+		return bundle.ParameterDefinition{DataType: "string"}, nil
+	case string:
+		return bundle.ParameterDefinition{DataType: "string", Default: v}, nil
+	case bool:
+		return bundle.ParameterDefinition{DataType: "bool", Default: v}, nil
+	case int:
+		return bundle.ParameterDefinition{DataType: "int", Default: v}, nil
+	case float64:
+		return bundle.ParameterDefinition{DataType: "string", Default: fmt.Sprint(v)}, nil
+	default:
+		data, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			return bundle.ParameterDefinition{}, err
+		}
+		return bundle.ParameterDefinition{DataType: "string", Default: string(data)}, nil
+	}
+}
+
+// jsonValue converts the maps decoded from YAML, keyed by interfaces, to
+// maps keyed by strings which can be encoded to JSON.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			l[i] = jsonValue(value)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// findImages collects the images the values refer to, keyed by image name.
+func findImages(chart helmChart, path string, values map[interface{}]interface{}, images map[string]string) error {
+	for key, value := range values {
+		k := fmt.Sprint(key)
+		valuePath := strings.TrimPrefix(path+"."+k, ".")
+		if k == "image" {
+			ref, ok := imageReference(chart, value)
+			if ok {
+				name := path
+				if name == "" {
+					name = chart.Name
+				}
+				if _, err := reference.ParseNormalizedNamed(ref); err != nil {
+					return errors.Wrapf(err, "invalid image %q of value %q", ref, valuePath)
+				}
+				images[name] = ref
+				continue
+			}
+		}
+		if nested, ok := value.(map[interface{}]interface{}); ok {
+			if err := findImages(chart, valuePath, nested, images); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// imageReference returns the reference of an image value, either a string or
+// an object with a repository.
+func imageReference(chart helmChart, value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case map[interface{}]interface{}:
+		repository, _ := v["repository"].(string)
+		if repository == "" {
+			return "", false
+		}
+		ref := repository
+		if registry, _ := v["registry"].(string); registry != "" {
+			ref = strings.TrimSuffix(registry, "/") + "/" + ref
+		}
+		tag := chart.AppVersion
+		if t, ok := v["tag"]; ok && t != nil && fmt.Sprint(t) != "" {
+			tag = fmt.Sprint(t)
+		}
+		if tag != "" {
+			ref += ":" + tag
+		}
+		return ref, true
+	default:
+		return "", false
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
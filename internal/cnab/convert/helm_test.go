@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestHelmChart(t *testing.T) {
+	b, err := HelmChart("testdata/mychart", HelmOptions{})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Name, "mychart"))
+	assert.Check(t, is.Equal(b.Version, "1.2.3"))
+	assert.Check(t, is.Equal(b.Description, "A web application with a cache"))
+	assert.Check(t, is.DeepEqual(b.Keywords, []string{"web", "cache"}))
+	assert.Check(t, is.DeepEqual(b.Maintainers, []bundle.Maintainer{{Name: "Jane", Email: "jane@example.com", URL: "https://example.com/jane"}}))
+	assert.Check(t, is.Equal(b.InvocationImages[0].Image, "mychart-installer:1.2.3"))
+
+	images := map[string]string{}
+	for name, img := range b.Images {
+		images[name] = img.Image
+	}
+	assert.Check(t, is.DeepEqual(images, map[string]string{
+		"mychart": "example/mychart:4.5",
+		"cache":   "quay.io/example/redis:5.0.7",
+		"sidecar": "busybox:1.31",
+	}))
+
+	defaults := map[string]interface{}{}
+	types := map[string]string{}
+	for name, def := range b.Parameters {
+		defaults[name] = def.Default
+		types[name] = def.DataType
+	}
+	assert.Check(t, is.DeepEqual(defaults, map[string]interface{}{
+		"replicaCount":           2,
+		"image.repository":       "example/mychart",
+		"image.pullPolicy":       "IfNotPresent",
+		"image.tag":              "",
+		"cache.enabled":          true,
+		"cache.image.registry":   "quay.io",
+		"cache.image.repository": "example/redis",
+		"cache.image.tag":        "5.0.7",
+		"sidecar.image":          "busybox:1.31",
+		"service.type":           "ClusterIP",
+		"service.port":           80,
+		"resources":              "{}",
+		"tolerations":            "[]",
+		"ingress.hosts":          `[{"host":"chart-example.local","paths":["/"]}]`,
+		"ratio":                  "0.5",
+		"nodeName":               nil,
+	}))
+	assert.Check(t, is.Equal(types["replicaCount"], "int"))
+	assert.Check(t, is.Equal(types["cache.enabled"], "bool"))
+	assert.Check(t, is.Equal(types["nodeName"], "string"))
+}
+
+func TestHelmChartWithoutValues(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("Chart.yaml", "name: empty\nversion: 0.1.0\n"))
+	defer dir.Remove()
+	b, err := HelmChart(dir.Path(), HelmOptions{InvocationImage: "example/helm-installer:3.0.0"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.InvocationImages[0].Image, "example/helm-installer:3.0.0"))
+	assert.Check(t, is.Len(b.Parameters, 0))
+	assert.Check(t, is.Len(b.Images, 0))
+}
+
+func TestHelmChartErrors(t *testing.T) {
+	_, err := HelmChart("testdata/missing", HelmOptions{})
+	assert.Check(t, is.ErrorContains(err, "failed to read the chart"))
+
+	_, err = HelmChartFiles([]byte("name: mychart\n"), nil, HelmOptions{})
+	assert.Check(t, is.Error(err, "invalid Chart.yaml: the chart name and version are required"))
+
+	_, err = HelmChartFiles([]byte("name: mychart\nversion: 1.0.0\n"), []byte("image: Not A Reference\n"), HelmOptions{})
+	assert.Check(t, is.ErrorContains(err, `invalid image "Not A Reference" of value "image"`))
+
+	_, err = HelmChartFiles([]byte("name: mychart\nversion: latest\n"), nil, HelmOptions{})
+	assert.Check(t, is.ErrorContains(err, "latest"))
+}
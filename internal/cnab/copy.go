@@ -0,0 +1,128 @@
+package cnab
+
+import (
+	"github.com/deislabs/cnab-go/bundle"
+)
+
+// DeepCopy returns a copy of the bundle sharing nothing with it: the maps,
+// slices and pointers are copied, as well as the JSON like trees of the
+// custom extensions and parameter values. Custom extensions set to other
+// types, like typed structs, are copied by assignment.
+func DeepCopy(b *bundle.Bundle) *bundle.Bundle {
+	if b == nil {
+		return nil
+	}
+	c := *b
+	if b.Keywords != nil {
+		c.Keywords = append([]string(nil), b.Keywords...)
+	}
+	if b.Maintainers != nil {
+		c.Maintainers = append([]bundle.Maintainer(nil), b.Maintainers...)
+	}
+	if b.InvocationImages != nil {
+		c.InvocationImages = make([]bundle.InvocationImage, len(b.InvocationImages))
+		for i, image := range b.InvocationImages {
+			image.BaseImage = copyBaseImage(image.BaseImage)
+			c.InvocationImages[i] = image
+		}
+	}
+	if b.Images != nil {
+		c.Images = make(map[string]bundle.Image, len(b.Images))
+		for name, image := range b.Images {
+			image.BaseImage = copyBaseImage(image.BaseImage)
+			c.Images[name] = image
+		}
+	}
+	if b.Actions != nil {
+		c.Actions = make(map[string]bundle.Action, len(b.Actions))
+		for name, action := range b.Actions {
+			c.Actions[name] = action
+		}
+	}
+	if b.Parameters != nil {
+		c.Parameters = make(map[string]bundle.ParameterDefinition, len(b.Parameters))
+		for name, def := range b.Parameters {
+			c.Parameters[name] = copyParameterDefinition(def)
+		}
+	}
+	if b.Credentials != nil {
+		c.Credentials = make(map[string]bundle.Location, len(b.Credentials))
+		for name, location := range b.Credentials {
+			c.Credentials[name] = location
+		}
+	}
+	if b.Custom != nil {
+		c.Custom = copyValue(b.Custom).(map[string]interface{})
+	}
+	return &c
+}
+
+func copyBaseImage(image bundle.BaseImage) bundle.BaseImage {
+	if image.Platform != nil {
+		platform := *image.Platform
+		image.Platform = &platform
+	}
+	return image
+}
+
+func copyParameterDefinition(def bundle.ParameterDefinition) bundle.ParameterDefinition {
+	def.Default = copyValue(def.Default)
+	if def.AllowedValues != nil {
+		def.AllowedValues = copyValue(def.AllowedValues).([]interface{})
+	}
+	def.MinValue = copyInt(def.MinValue)
+	def.MaxValue = copyInt(def.MaxValue)
+	def.MinLength = copyInt(def.MinLength)
+	def.MaxLength = copyInt(def.MaxLength)
+	if def.Metadata != nil {
+		metadata := *def.Metadata
+		def.Metadata = &metadata
+	}
+	if def.Destination != nil {
+		destination := *def.Destination
+		def.Destination = &destination
+	}
+	if def.ApplyTo != nil {
+		def.ApplyTo = append([]string(nil), def.ApplyTo...)
+	}
+	return def
+}
+
+func copyInt(i *int) *int {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	return &c
+}
+
+// copyValue copies the maps and slices of a decoded JSON value.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		c := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			c[key] = copyValue(item)
+		}
+		return c
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copyValue(item)
+		}
+		return c
+	case []string:
+		if v == nil {
+			return v
+		}
+		return append([]string(nil), v...)
+	default:
+		return value
+	}
+}
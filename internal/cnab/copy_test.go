@@ -0,0 +1,41 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestDeepCopy(t *testing.T) {
+	max := 10
+	original, err := Parse([]byte(`{
+		"name": "app",
+		"version": "1.0.0",
+		"keywords": ["web"],
+		"invocationImages": [{"imageType": "docker", "image": "app:1.0.0", "platform": {"os": "linux"}}],
+		"images": {"web": {"imageType": "docker", "image": "nginx:1.17"}},
+		"parameters": {"port": {"type": "string", "default": "80", "allowedValues": ["80", "8080"], "destination": {"env": "PORT"}, "apply-to": ["install"]}},
+		"credentials": {"token": {"env": "TOKEN"}},
+		"custom": {"com.example": {"nested": {"list": [1, {"key": "value"}]}}}
+	}`))
+	assert.NilError(t, err)
+	port := original.Parameters["port"]
+	port.MaxLength = &max
+	original.Parameters["port"] = port
+	expected := DeepCopy(original)
+	c := DeepCopy(original)
+	assert.Check(t, is.DeepEqual(c, original))
+
+	c.Keywords[0] = "changed"
+	c.InvocationImages[0].Platform.OS = "windows"
+	c.Images["web"] = bundle.Image{}
+	c.Parameters["port"].Destination.EnvironmentVariable = "CHANGED"
+	*c.Parameters["port"].MaxLength = 20
+	c.Parameters["port"].AllowedValues[0] = "changed"
+	c.Parameters["port"].ApplyTo[0] = "changed"
+	c.Credentials["token"] = bundle.Location{}
+	c.Custom["com.example"].(map[string]interface{})["nested"].(map[string]interface{})["list"].([]interface{})[1].(map[string]interface{})["key"] = "changed"
+	assert.Check(t, is.DeepEqual(original, expected))
+}
@@ -0,0 +1,105 @@
+package cnab
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/pkg/errors"
+)
+
+// CredentialsExtensionKey is the custom extension describing the bundle
+// credentials, which can be optional.
+const CredentialsExtensionKey = internal.Namespace + "credentials"
+
+// Credential is a bundle credential with its description.
+type Credential struct {
+	bundle.Location
+	// Required credentials must be supplied to run any action. Credentials
+	// are required unless stated otherwise.
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// UnmarshalJSON decodes a credential, which is required by default, so plain
+// locations are decoded as required credentials.
+func (c *Credential) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		bundle.Location
+		Required    *bool  `json:"required"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = Credential{Location: raw.Location, Required: true, Description: raw.Description}
+	if raw.Required != nil {
+		c.Required = *raw.Required
+	}
+	return nil
+}
+
+// ReadCredentials returns the credentials of the bundle, with the description
+// and the required flag from the credentials extension, if any.
+func ReadCredentials(b *bundle.Bundle) (map[string]Credential, error) {
+	result := make(map[string]Credential, len(b.Credentials))
+	for name, location := range b.Credentials {
+		result[name] = Credential{Location: location, Required: true}
+	}
+	var described map[string]Credential
+	found, err := GetCustomExtension(b, CredentialsExtensionKey, &described)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return result, nil
+	}
+	for name, credential := range described {
+		location, ok := b.Credentials[name]
+		if !ok {
+			return nil, errors.Errorf("invalid %s extension: undefined credential %q", CredentialsExtensionKey, name)
+		}
+		if credential.Location != (bundle.Location{}) && credential.Location != location {
+			return nil, errors.Errorf("invalid %s extension: location of credential %q differs from the bundle one", CredentialsExtensionKey, name)
+		}
+		credential.Location = location
+		result[name] = credential
+	}
+	return result, nil
+}
+
+// ValidateCredentials fails with a MissingCredentialsError if required
+// credentials of the bundle are not supplied, listing all of them. Missing optional credentials are set to an
+// empty value, as the invocation image receives every credential.
+func ValidateCredentials(b *bundle.Bundle, creds credentials.Set) (err error) {
+	_, op := telemetry.Start(context.Background(), telemetry.SpanValidate, telemetry.Fields{"bundle": b.Name, "kind": "credentials"})
+	defer func() { op.End(err) }()
+	described, err := ReadCredentials(b)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for name, credential := range described {
+		if _, ok := creds[name]; ok {
+			continue
+		}
+		if credential.Required {
+			missing = append(missing, name)
+			continue
+		}
+		creds[name] = ""
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	errs := make(MissingCredentialsError, len(missing))
+	for i, name := range missing {
+		errs[i] = &CredentialMissingError{Name: name}
+	}
+	return errs
+}
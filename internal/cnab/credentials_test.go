@@ -0,0 +1,57 @@
+package cnab_test
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestReadCredentials(t *testing.T) {
+	b := bundletest.NewTestBundle(
+		bundletest.WithCredentials(3),
+		bundletest.WithCustom(cnab.CredentialsExtensionKey, map[string]interface{}{
+			"cred-0": map[string]interface{}{"description": "The API token"},
+			"cred-1": map[string]interface{}{"required": false, "description": "A proxy password"},
+		}),
+	)
+	creds, err := cnab.ReadCredentials(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(creds, map[string]cnab.Credential{
+		"cred-0": {Location: bundle.Location{Path: "/cnab/app/cred-0"}, Required: true, Description: "The API token"},
+		"cred-1": {Location: bundle.Location{Path: "/cnab/app/cred-1"}, Required: false, Description: "A proxy password"},
+		"cred-2": {Location: bundle.Location{Path: "/cnab/app/cred-2"}, Required: true},
+	}))
+
+	b.Custom[cnab.CredentialsExtensionKey] = map[string]interface{}{"unknown": map[string]interface{}{}}
+	_, err = cnab.ReadCredentials(b)
+	assert.Check(t, is.ErrorContains(err, `undefined credential "unknown"`))
+	b.Custom[cnab.CredentialsExtensionKey] = map[string]interface{}{"cred-0": map[string]interface{}{"env": "TOKEN"}}
+	_, err = cnab.ReadCredentials(b)
+	assert.Check(t, is.ErrorContains(err, `location of credential "cred-0" differs`))
+}
+
+func TestCredentialUnmarshalOldShape(t *testing.T) {
+	var c cnab.Credential
+	assert.NilError(t, c.UnmarshalJSON([]byte(`{"env": "TOKEN"}`)))
+	assert.Check(t, is.DeepEqual(c, cnab.Credential{Location: bundle.Location{EnvironmentVariable: "TOKEN"}, Required: true}))
+}
+
+func TestValidateCredentials(t *testing.T) {
+	b := bundletest.NewTestBundle(
+		bundletest.WithCredentials(3),
+		bundletest.WithCustom(cnab.CredentialsExtensionKey, map[string]interface{}{
+			"cred-2": map[string]interface{}{"required": false},
+		}),
+	)
+	err := cnab.ValidateCredentials(b, credentials.Set{})
+	assert.Check(t, is.ErrorContains(err, "bundle requires credentials for cred-0, cred-1"))
+
+	creds := credentials.Set{"cred-0": "a", "cred-1": "b"}
+	assert.NilError(t, cnab.ValidateCredentials(b, creds))
+	assert.Check(t, is.DeepEqual(creds, credentials.Set{"cred-0": "a", "cred-1": "b", "cred-2": ""}))
+}
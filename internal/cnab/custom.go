@@ -0,0 +1,168 @@
+package cnab
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+)
+
+// CustomLimits bounds the custom extensions of a bundle. Their content is
+// free form and may come from an untrusted source, so it is checked before
+// being used.
+type CustomLimits struct {
+	// MaxSize is the maximum size, in bytes, of the JSON encoding of all the
+	// custom extensions.
+	MaxSize int
+	// MaxEntrySize is the maximum size, in bytes, of the JSON encoding of
+	// each custom extension.
+	MaxEntrySize int
+	// MaxExtensions is the maximum number of custom extensions.
+	MaxExtensions int
+	// MaxDepth is the maximum nesting of objects and arrays in each
+	// extension.
+	MaxDepth int
+	// MaxStringLength is the maximum length of any key or string value.
+	MaxStringLength int
+	// MaxElements is the maximum number of values, including the objects and
+	// arrays, in all the custom extensions.
+	MaxElements int
+}
+
+// DefaultCustomLimits are the limits applied to the bundles loaded by the
+// docker app commands.
+var DefaultCustomLimits = CustomLimits{
+	MaxSize:         1 << 20,
+	MaxEntrySize:    256 << 10,
+	MaxExtensions:   128,
+	MaxDepth:        32,
+	MaxStringLength: 64 << 10,
+	MaxElements:     100000,
+}
+
+// Check returns an error if the custom extensions of the bundle exceed the
+// limits, or contain keys or strings which are not valid printable UTF-8.
+// A zero limit is not enforced.
+func (l CustomLimits) Check(b *bundle.Bundle) error {
+	if len(b.Custom) == 0 {
+		return nil
+	}
+	if l.MaxExtensions > 0 && len(b.Custom) > l.MaxExtensions {
+		return errors.Errorf("too many custom extensions: %d, maximum is %d", len(b.Custom), l.MaxExtensions)
+	}
+	data, err := json.Marshal(b.Custom)
+	if err != nil {
+		return errors.Wrap(err, "invalid custom extensions")
+	}
+	if l.MaxSize > 0 && len(data) > l.MaxSize {
+		return errors.Errorf("custom extensions are too large: %d bytes, maximum is %d", len(data), l.MaxSize)
+	}
+	elements := 0
+	for _, name := range customNames(b) {
+		if err := l.checkEntry(name, b.Custom[name], &elements); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sanitize removes the custom extensions which are larger or nested deeper
+// than the limits of a single extension, or which hold keys or strings
+// which are not valid printable UTF-8. It returns why each extension was
+// removed, in name order. The limits of all the extensions together are
+// left to Check.
+func (l CustomLimits) Sanitize(b *bundle.Bundle) []error {
+	var removed []error
+	for _, name := range customNames(b) {
+		elements := 0
+		if err := l.checkEntry(name, b.Custom[name], &elements); err != nil {
+			delete(b.Custom, name)
+			removed = append(removed, err)
+		}
+	}
+	return removed
+}
+
+// checkEntry checks a custom extension against the limits of a single
+// extension, counting its elements.
+func (l CustomLimits) checkEntry(name string, value interface{}, elements *int) error {
+	if err := l.checkString(name); err != nil {
+		return errors.Wrapf(err, "invalid custom extension name %q", name)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid custom extension %q", name)
+	}
+	if l.MaxEntrySize > 0 && len(data) > l.MaxEntrySize {
+		return errors.Errorf("custom extension %q is too large: %d bytes, maximum is %d", name, len(data), l.MaxEntrySize)
+	}
+	if err := l.checkValue(value, 1, elements); err != nil {
+		return errors.Wrapf(err, "invalid custom extension %q", name)
+	}
+	return nil
+}
+
+func customNames(b *bundle.Bundle) []string {
+	names := make([]string, 0, len(b.Custom))
+	for name := range b.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (l CustomLimits) checkValue(value interface{}, depth int, elements *int) error {
+	*elements++
+	if l.MaxElements > 0 && *elements > l.MaxElements {
+		return errors.Errorf("too many elements, maximum is %d", l.MaxElements)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if l.MaxDepth > 0 && depth > l.MaxDepth {
+			return errors.Errorf("nested too deeply, maximum depth is %d", l.MaxDepth)
+		}
+		for k, item := range v {
+			if err := l.checkString(k); err != nil {
+				return errors.Wrapf(err, "key %q", k)
+			}
+			if err := l.checkValue(item, depth+1, elements); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if l.MaxDepth > 0 && depth > l.MaxDepth {
+			return errors.Errorf("nested too deeply, maximum depth is %d", l.MaxDepth)
+		}
+		for _, item := range v {
+			if err := l.checkValue(item, depth+1, elements); err != nil {
+				return err
+			}
+		}
+	case string:
+		return l.checkString(v)
+	}
+	return nil
+}
+
+func (l CustomLimits) checkString(s string) error {
+	if l.MaxStringLength > 0 && len(s) > l.MaxStringLength {
+		return errors.Errorf("string is too long: %d bytes, maximum is %d", len(s), l.MaxStringLength)
+	}
+	if !utf8.ValidString(s) {
+		return errors.New("string is not valid UTF-8")
+	}
+	for _, r := range s {
+		if isForbiddenControl(r) {
+			return fmt.Errorf("string contains the control character %U", r)
+		}
+	}
+	return nil
+}
+
+func isForbiddenControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\t' && r != '\r'
+}
@@ -0,0 +1,96 @@
+package cnab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCustomLimits(t *testing.T) {
+	limits := CustomLimits{MaxSize: 100, MaxEntrySize: 60, MaxExtensions: 2, MaxDepth: 2, MaxStringLength: 10, MaxElements: 6}
+	for _, tc := range []struct {
+		name     string
+		custom   map[string]interface{}
+		expected string
+	}{
+		{name: "empty"},
+		{name: "valid", custom: map[string]interface{}{"ext": map[string]interface{}{"key": "value\n"}}},
+		{
+			name:     "too many extensions",
+			custom:   map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			expected: "too many custom extensions: 3, maximum is 2",
+		},
+		{
+			name:     "too large",
+			custom:   map[string]interface{}{"a": strings.Repeat("a", 100)},
+			expected: "custom extensions are too large",
+		},
+		{
+			name:     "extension too large",
+			custom:   map[string]interface{}{"a": []interface{}{"0123456789", "0123456789", "0123456789", "0123456789", "0123456789"}},
+			expected: `custom extension "a" is too large: 66 bytes, maximum is 60`,
+		},
+		{
+			name:     "too deep",
+			custom:   map[string]interface{}{"a": []interface{}{[]interface{}{[]interface{}{1}}}},
+			expected: `invalid custom extension "a": nested too deeply, maximum depth is 2`,
+		},
+		{
+			name:     "too many elements",
+			custom:   map[string]interface{}{"a": []interface{}{1, 2, 3}, "b": []interface{}{4, 5}},
+			expected: "too many elements, maximum is 6",
+		},
+		{
+			name:     "string too long",
+			custom:   map[string]interface{}{"a": "01234567890"},
+			expected: "string is too long: 11 bytes, maximum is 10",
+		},
+		{
+			name:     "control character",
+			custom:   map[string]interface{}{"a": map[string]interface{}{"k\x1b": "v"}},
+			expected: `key "k\x1b": string contains the control character U+001B`,
+		},
+		{
+			name:     "invalid utf-8",
+			custom:   map[string]interface{}{"a\xff": 1},
+			expected: "string is not valid UTF-8",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := limits.Check(&bundle.Bundle{Custom: tc.custom})
+			if tc.expected == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Check(t, is.ErrorContains(err, tc.expected))
+			}
+		})
+	}
+}
+
+func TestSanitizeCustom(t *testing.T) {
+	limits := CustomLimits{MaxEntrySize: 24, MaxDepth: 2, MaxExtensions: 10}
+	b := &bundle.Bundle{Custom: map[string]interface{}{
+		"com.example.valid":   map[string]interface{}{"text": "line\nnext"},
+		"com.example.control": []interface{}{"a\x1b[31mb"},
+		"com.example.utf8":    "next\xff",
+		"com.example.large":   strings.Repeat("a", 30),
+		"com.example.deep":    []interface{}{[]interface{}{[]interface{}{1}}},
+		"com.example\x00":     1,
+	}}
+	removed := limits.Sanitize(b)
+	assert.DeepEqual(t, b.Custom, map[string]interface{}{
+		"com.example.valid": map[string]interface{}{"text": "line\nnext"},
+	})
+	assert.Assert(t, is.Len(removed, 5))
+	assert.Check(t, is.ErrorContains(removed[0], `invalid custom extension name "com.example\x00"`))
+	assert.Check(t, is.ErrorContains(removed[1], `invalid custom extension "com.example.control": string contains the control character U+001B`))
+	assert.Check(t, is.ErrorContains(removed[2], `invalid custom extension "com.example.deep": nested too deeply`))
+	assert.Check(t, is.ErrorContains(removed[3], `custom extension "com.example.large" is too large: 32 bytes, maximum is 24`))
+	assert.Check(t, is.ErrorContains(removed[4], `invalid custom extension "com.example.utf8": string is not valid UTF-8`))
+	assert.NilError(t, limits.Check(b))
+
+	assert.Check(t, is.Len(limits.Sanitize(&bundle.Bundle{}), 0))
+}
@@ -0,0 +1,234 @@
+package cnab
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/semver"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// DependenciesExtensionKey is the custom extension declaring the bundles a
+// bundle depends on.
+const DependenciesExtensionKey = "io.cnab.dependencies"
+
+// Dependencies is the content of the dependencies extension.
+type Dependencies struct {
+	// Requires maps an alias to a required bundle.
+	Requires map[string]Dependency `json:"requires,omitempty"`
+}
+
+// Dependency is a bundle required by another one.
+type Dependency struct {
+	// Bundle is the reference of the required bundle.
+	Bundle  string             `json:"bundle"`
+	Version *DependencyVersion `json:"version,omitempty"`
+	// Parameters maps parameters of the required bundle to the parameters
+	// of the requiring bundle whose values they share.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// DependencyVersion constrains the version of a required bundle.
+type DependencyVersion struct {
+	Prereleases bool     `json:"prereleases,omitempty"`
+	Ranges      []string `json:"ranges,omitempty"`
+}
+
+// ReadDependencies returns the dependencies declared by the bundle, or nil if
+// it has none.
+func ReadDependencies(b *bundle.Bundle) (*Dependencies, error) {
+	var deps Dependencies
+	found, err := GetCustomExtension(b, DependenciesExtensionKey, &deps)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &deps, nil
+}
+
+// WriteDependencies sets the dependencies extension of the bundle, or
+// removes it if deps is nil.
+func WriteDependencies(b *bundle.Bundle, deps *Dependencies) {
+	if deps == nil {
+		delete(b.Custom, DependenciesExtensionKey)
+		return
+	}
+	if b.Custom == nil {
+		b.Custom = map[string]interface{}{}
+	}
+	b.Custom[DependenciesExtensionKey] = *deps
+}
+
+// BundleFetcher returns the bundle with the given reference, for instance
+// from the bundle store or a registry.
+type BundleFetcher func(ref reference.Named) (*bundle.Bundle, error)
+
+// DependencyNode is a bundle of a dependency graph.
+type DependencyNode struct {
+	// Reference is the reference the bundle was fetched with.
+	Reference reference.Named
+	Bundle    *bundle.Bundle
+	// Requires lists the repository names of the direct dependencies.
+	Requires []string
+}
+
+// DependencyPlan is the resolved dependency graph of a root bundle.
+type DependencyPlan struct {
+	// Install lists the bundles in installation order: every bundle comes
+	// after its dependencies and the root bundle comes last.
+	Install []DependencyNode
+}
+
+// Uninstall returns the bundles in uninstallation order, the reverse of the
+// installation order.
+func (p *DependencyPlan) Uninstall() []DependencyNode {
+	nodes := make([]DependencyNode, len(p.Install))
+	for i, node := range p.Install {
+		nodes[len(nodes)-1-i] = node
+	}
+	return nodes
+}
+
+// ResolveDependencies fetches the dependencies of the root bundle,
+// transitively, and returns the installation plan. A bundle required several
+// times is only installed once, so all the requirements must agree on its
+// reference and accept its version. Dependency cycles are rejected.
+func ResolveDependencies(root *bundle.Bundle, rootRef reference.Named, fetch BundleFetcher) (*DependencyPlan, error) {
+	r := &resolver{
+		fetch: fetch,
+		nodes: map[string]*DependencyNode{},
+		state: map[string]int{},
+		plan:  &DependencyPlan{},
+	}
+	name := BundleReferenceFromNamed(rootRef).Name()
+	r.nodes[name] = &DependencyNode{Reference: rootRef, Bundle: root}
+	if err := r.visit(name, nil); err != nil {
+		return nil, err
+	}
+	return r.plan, nil
+}
+
+const (
+	unvisited = iota
+	visiting
+	visited
+)
+
+type resolver struct {
+	fetch BundleFetcher
+	nodes map[string]*DependencyNode
+	state map[string]int
+	plan  *DependencyPlan
+}
+
+// visit walks the dependencies depth first, adding each bundle to the plan
+// once all its dependencies have been.
+func (r *resolver) visit(name string, path []string) error {
+	path = append(path, name)
+	switch r.state[name] {
+	case visiting:
+		return errors.Errorf("dependency cycle detected: %s", strings.Join(path, " -> "))
+	case visited:
+		return nil
+	}
+	r.state[name] = visiting
+	node := r.nodes[name]
+	deps, err := ReadDependencies(node.Bundle)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve dependencies of %s", name)
+	}
+	if deps != nil {
+		aliases := make([]string, 0, len(deps.Requires))
+		for alias := range deps.Requires {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+		for _, alias := range aliases {
+			depName, err := r.require(name, alias, deps.Requires[alias])
+			if err != nil {
+				return err
+			}
+			node.Requires = append(node.Requires, depName)
+			if err := r.visit(depName, path); err != nil {
+				return err
+			}
+		}
+	}
+	r.state[name] = visited
+	r.plan.Install = append(r.plan.Install, *node)
+	return nil
+}
+
+// require fetches a dependency, if not already known, and checks it satisfies
+// the requirement.
+func (r *resolver) require(parent, alias string, dep Dependency) (string, error) {
+	bundleRef, err := ParseBundleReference(dep.Bundle)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid dependency %q of %s", alias, parent)
+	}
+	ref, err := bundleRef.Named()
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid dependency %q of %s", alias, parent)
+	}
+	name := bundleRef.Name()
+	node, ok := r.nodes[name]
+	if ok {
+		if node.Reference.String() != ref.String() {
+			return "", errors.Errorf("conflicting dependency %q of %s: %s is already required as %s", alias, parent, ref, node.Reference)
+		}
+	} else {
+		bndl, err := r.fetch(ref)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to fetch dependency %q of %s", alias, parent)
+		}
+		node = &DependencyNode{Reference: ref, Bundle: bndl}
+		r.nodes[name] = node
+	}
+	if err := checkDependencyVersion(node.Bundle, dep.Version); err != nil {
+		return "", errors.Wrapf(err, "conflicting dependency %q of %s", alias, parent)
+	}
+	if err := checkSharedParameters(r.nodes[parent].Bundle, node.Bundle, dep.Parameters); err != nil {
+		return "", errors.Wrapf(err, "invalid dependency %q of %s", alias, parent)
+	}
+	return name, nil
+}
+
+// checkSharedParameters checks the parameters shared with a dependency are
+// declared by both bundles.
+func checkSharedParameters(parent, dependency *bundle.Bundle, shared map[string]string) error {
+	names := make([]string, 0, len(shared))
+	for name := range shared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := dependency.Parameters[name]; !ok {
+			return errors.Errorf("parameter %q is not defined by %s", name, dependency.Name)
+		}
+		if _, ok := parent.Parameters[shared[name]]; !ok {
+			return errors.Errorf("shared parameter %q is not defined by %s", shared[name], parent.Name)
+		}
+	}
+	return nil
+}
+
+func checkDependencyVersion(b *bundle.Bundle, constraint *DependencyVersion) error {
+	if constraint == nil || len(constraint.Ranges) == 0 {
+		return nil
+	}
+	version, err := semver.Parse(b.Version)
+	if err != nil {
+		return err
+	}
+	for _, rng := range constraint.Ranges {
+		r, err := semver.ParseRange(rng)
+		if err != nil {
+			return err
+		}
+		if r.Contains(version, constraint.Prereleases) {
+			return nil
+		}
+	}
+	return errors.Errorf("version %s of %s does not match %s", b.Version, b.Name, strings.Join(constraint.Ranges, " or "))
+}
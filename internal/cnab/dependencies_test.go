@@ -0,0 +1,159 @@
+package cnab_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"github.com/docker/distribution/reference"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func withDependencies(deps map[string]cnab.Dependency) bundletest.Option {
+	return bundletest.WithCustom(cnab.DependenciesExtensionKey, cnab.Dependencies{Requires: deps})
+}
+
+func fetcher(bundles map[string]*bundle.Bundle) cnab.BundleFetcher {
+	return func(ref reference.Named) (*bundle.Bundle, error) {
+		if b, ok := bundles[ref.String()]; ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s not found", ref)
+	}
+}
+
+func mustParse(t *testing.T, s string) reference.Named {
+	t.Helper()
+	ref, err := reference.ParseNormalizedNamed(s)
+	assert.NilError(t, err)
+	return ref
+}
+
+func planNames(nodes []cnab.DependencyNode) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.Bundle.Name)
+	}
+	return names
+}
+
+func TestResolveDependencies(t *testing.T) {
+	bundles := map[string]*bundle.Bundle{
+		"docker.io/library/db:1.2.0": bundletest.NewTestBundle(bundletest.WithName("db"), bundletest.WithVersion("1.2.0")),
+		"docker.io/library/cache:2.0.0": bundletest.NewTestBundle(bundletest.WithName("cache"), bundletest.WithVersion("2.0.0"),
+			withDependencies(map[string]cnab.Dependency{"db": {Bundle: "db:1.2.0"}})),
+	}
+	root := bundletest.NewTestBundle(bundletest.WithName("app"), withDependencies(map[string]cnab.Dependency{
+		"storage": {Bundle: "db:1.2.0", Version: &cnab.DependencyVersion{Ranges: []string{"^1.0"}}},
+		"cache":   {Bundle: "cache:2.0.0"},
+	}))
+
+	plan, err := cnab.ResolveDependencies(root, mustParse(t, "app:0.1.0"), fetcher(bundles))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, planNames(plan.Install), []string{"db", "cache", "app"})
+	assert.DeepEqual(t, planNames(plan.Uninstall()), []string{"app", "cache", "db"})
+	assert.DeepEqual(t, plan.Install[2].Requires, []string{"docker.io/library/cache", "docker.io/library/db"})
+}
+
+func TestResolveDependenciesDecodedExtension(t *testing.T) {
+	root, err := cnab.Parse([]byte(`{"name":"app","version":"0.1.0","custom":{"io.cnab.dependencies":{"requires":{"db":{"bundle":"db:1.2.0"}}}}}`))
+	assert.NilError(t, err)
+	bundles := map[string]*bundle.Bundle{"docker.io/library/db:1.2.0": bundletest.NewTestBundle(bundletest.WithName("db"))}
+	plan, err := cnab.ResolveDependencies(root, mustParse(t, "app:0.1.0"), fetcher(bundles))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, planNames(plan.Install), []string{"db", "app"})
+}
+
+func TestWriteDependencies(t *testing.T) {
+	b := &bundle.Bundle{Name: "app"}
+	deps := &cnab.Dependencies{Requires: map[string]cnab.Dependency{
+		"db": {Bundle: "db:1.2.0", Parameters: map[string]string{"password": "db-password"}},
+	}}
+	cnab.WriteDependencies(b, deps)
+	read, err := cnab.ReadDependencies(b)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, read, deps)
+
+	cnab.WriteDependencies(b, nil)
+	read, err = cnab.ReadDependencies(b)
+	assert.NilError(t, err)
+	assert.Check(t, read == nil)
+}
+
+func TestResolveDependenciesSharedParameters(t *testing.T) {
+	bundles := map[string]*bundle.Bundle{
+		"docker.io/library/db:1.2.0": bundletest.NewTestBundle(bundletest.WithName("db"),
+			bundletest.WithParameter("password", bundle.ParameterDefinition{DataType: "string"})),
+	}
+	root := bundletest.NewTestBundle(bundletest.WithName("app"),
+		bundletest.WithParameter("db-password", bundle.ParameterDefinition{DataType: "string"}),
+		withDependencies(map[string]cnab.Dependency{
+			"db": {Bundle: "db:1.2.0", Parameters: map[string]string{"password": "db-password"}},
+		}))
+	_, err := cnab.ResolveDependencies(root, mustParse(t, "app:0.1.0"), fetcher(bundles))
+	assert.NilError(t, err)
+
+	cnab.WriteDependencies(root, &cnab.Dependencies{Requires: map[string]cnab.Dependency{
+		"db": {Bundle: "db:1.2.0", Parameters: map[string]string{"user": "db-password"}},
+	}})
+	_, err = cnab.ResolveDependencies(root, mustParse(t, "app:0.1.0"), fetcher(bundles))
+	assert.Error(t, err, `invalid dependency "db" of docker.io/library/app: parameter "user" is not defined by db`)
+
+	cnab.WriteDependencies(root, &cnab.Dependencies{Requires: map[string]cnab.Dependency{
+		"db": {Bundle: "db:1.2.0", Parameters: map[string]string{"password": "unknown"}},
+	}})
+	_, err = cnab.ResolveDependencies(root, mustParse(t, "app:0.1.0"), fetcher(bundles))
+	assert.Error(t, err, `invalid dependency "db" of docker.io/library/app: shared parameter "unknown" is not defined by app`)
+}
+
+func TestResolveDependenciesErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		bundles  map[string]*bundle.Bundle
+		root     map[string]cnab.Dependency
+		expected string
+	}{
+		{
+			name: "cycle",
+			bundles: map[string]*bundle.Bundle{
+				"docker.io/library/a:1.0.0": bundletest.NewTestBundle(bundletest.WithName("a"), withDependencies(map[string]cnab.Dependency{"b": {Bundle: "b:1.0.0"}})),
+				"docker.io/library/b:1.0.0": bundletest.NewTestBundle(bundletest.WithName("b"), withDependencies(map[string]cnab.Dependency{"a": {Bundle: "a:1.0.0"}})),
+			},
+			root:     map[string]cnab.Dependency{"a": {Bundle: "a:1.0.0"}},
+			expected: "dependency cycle detected: docker.io/library/app -> docker.io/library/a -> docker.io/library/b -> docker.io/library/a",
+		},
+		{
+			name: "version out of range",
+			bundles: map[string]*bundle.Bundle{
+				"docker.io/library/db:1.2.0": bundletest.NewTestBundle(bundletest.WithName("db"), bundletest.WithVersion("1.2.0")),
+			},
+			root:     map[string]cnab.Dependency{"db": {Bundle: "db:1.2.0", Version: &cnab.DependencyVersion{Ranges: []string{">=2.0.0"}}}},
+			expected: `conflicting dependency "db" of docker.io/library/app: version 1.2.0 of db does not match >=2.0.0`,
+		},
+		{
+			name: "conflicting references",
+			bundles: map[string]*bundle.Bundle{
+				"docker.io/library/db:1.2.0":    bundletest.NewTestBundle(bundletest.WithName("db")),
+				"docker.io/library/db:1.3.0":    bundletest.NewTestBundle(bundletest.WithName("db")),
+				"docker.io/library/cache:1.0.0": bundletest.NewTestBundle(bundletest.WithName("cache"), withDependencies(map[string]cnab.Dependency{"db": {Bundle: "db:1.3.0"}})),
+			},
+			root:     map[string]cnab.Dependency{"db": {Bundle: "db:1.2.0"}, "cache": {Bundle: "cache:1.0.0"}},
+			expected: "docker.io/library/db:1.2.0 is already required as docker.io/library/db:1.3.0",
+		},
+		{
+			name:     "missing bundle",
+			root:     map[string]cnab.Dependency{"db": {Bundle: "db:1.2.0"}},
+			expected: `failed to fetch dependency "db" of docker.io/library/app: docker.io/library/db:1.2.0 not found`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := bundletest.NewTestBundle(bundletest.WithName("app"), withDependencies(tc.root))
+			_, err := cnab.ResolveDependencies(root, mustParse(t, "app:0.1.0"), fetcher(tc.bundles))
+			assert.Check(t, is.ErrorContains(err, tc.expected))
+		})
+	}
+}
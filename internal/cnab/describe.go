@@ -0,0 +1,270 @@
+package cnab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+)
+
+// DescribeFormat is a format of bundle summaries.
+type DescribeFormat string
+
+// Formats of bundle summaries.
+const (
+	// DescribeText is a plain text summary with aligned tables, for terminals.
+	DescribeText DescribeFormat = "text"
+	// DescribeJSON is the Summary as JSON, for tools.
+	DescribeJSON DescribeFormat = "json"
+	// DescribeMarkdown is a Markdown summary, for generated docs.
+	DescribeMarkdown DescribeFormat = "markdown"
+)
+
+// maskedValue replaces the default values of sensitive parameters.
+const maskedValue = "*****"
+
+// Summary is what a user needs to know to run a bundle.
+type Summary struct {
+	Name        string              `json:"name"`
+	Version     string              `json:"version"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []ParameterSummary  `json:"parameters"`
+	Credentials []CredentialSummary `json:"credentials"`
+	Actions     []ActionSummary     `json:"actions"`
+	Images      []ImageSummary      `json:"images"`
+}
+
+// ParameterSummary describes a parameter. The default value of a sensitive
+// parameter is masked.
+type ParameterSummary struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Required    bool        `json:"required"`
+	Sensitive   bool        `json:"sensitive,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// CredentialSummary describes where a credential is injected.
+type CredentialSummary struct {
+	Name string `json:"name"`
+	Env  string `json:"env,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// ActionSummary describes an action, the core ones included.
+type ActionSummary struct {
+	Name        string `json:"name"`
+	Modifies    bool   `json:"modifies"`
+	Stateless   bool   `json:"stateless,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ImageSummary describes an image of the bundle. Invocation images are
+// named after their index, like "invocation[0]".
+type ImageSummary struct {
+	Name        string `json:"name"`
+	Image       string `json:"image"`
+	Digest      string `json:"digest,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Summarize returns the summary of the bundle, sorted by names.
+func Summarize(b *bundle.Bundle) (*Summary, error) {
+	sensitive, err := SensitiveParameters(b)
+	if err != nil {
+		return nil, err
+	}
+	masked := map[string]bool{}
+	for _, name := range sensitive {
+		masked[name] = true
+	}
+	s := &Summary{
+		Name:        b.Name,
+		Version:     b.Version,
+		Description: b.Description,
+		Parameters:  []ParameterSummary{},
+		Credentials: []CredentialSummary{},
+		Actions:     []ActionSummary{},
+		Images:      []ImageSummary{},
+	}
+	for _, name := range ParameterNames(b) {
+		def := b.Parameters[name]
+		p := ParameterSummary{
+			Name:      name,
+			Type:      def.DataType,
+			Default:   def.Default,
+			Required:  def.Required,
+			Sensitive: masked[name],
+		}
+		if def.Metadata != nil {
+			p.Description = def.Metadata.Description
+		}
+		if p.Sensitive && p.Default != nil {
+			p.Default = maskedValue
+		}
+		s.Parameters = append(s.Parameters, p)
+	}
+	for _, name := range CredentialNames(b) {
+		loc := b.Credentials[name]
+		s.Credentials = append(s.Credentials, CredentialSummary{Name: name, Env: loc.EnvironmentVariable, Path: loc.Path})
+	}
+	for _, name := range CoreActions() {
+		if _, ok := b.Actions[name]; !ok {
+			s.Actions = append(s.Actions, ActionSummary{Name: name, Modifies: true})
+		}
+	}
+	for _, name := range ActionNames(b) {
+		action := b.Actions[name]
+		s.Actions = append(s.Actions, ActionSummary{Name: name, Modifies: action.Modifies, Stateless: action.Stateless, Description: action.Description})
+	}
+	for i, img := range b.InvocationImages {
+		s.Images = append(s.Images, ImageSummary{Name: fmt.Sprintf("invocation[%d]", i), Image: img.Image, Digest: img.Digest})
+	}
+	for _, name := range ImageKeys(b) {
+		img := b.Images[name]
+		s.Images = append(s.Images, ImageSummary{Name: name, Image: img.Image, Digest: img.Digest, Description: img.Description})
+	}
+	return s, nil
+}
+
+// Describe renders the summary of the bundle in the given format.
+func Describe(b *bundle.Bundle, format DescribeFormat) ([]byte, error) {
+	s, err := Summarize(b)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	switch format {
+	case DescribeJSON:
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteString("\n")
+	case DescribeText:
+		err = describeText(&buf, s)
+	case DescribeMarkdown:
+		err = describeMarkdown(&buf, s)
+	default:
+		return nil, errors.Errorf("unknown summary format %q, expected %q, %q or %q", format, DescribeText, DescribeJSON, DescribeMarkdown)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// summaryTable is a section of a summary, one row per item.
+type summaryTable struct {
+	title   string
+	headers []string
+	rows    [][]string
+}
+
+func summaryTables(s *Summary) []summaryTable {
+	parameters := summaryTable{title: "Parameters", headers: []string{"NAME", "TYPE", "DEFAULT", "REQUIRED", "DESCRIPTION"}}
+	for _, p := range s.Parameters {
+		parameters.rows = append(parameters.rows, []string{p.Name, p.Type, summaryValue(p.Default), yesNo(p.Required), p.Description})
+	}
+	credentials := summaryTable{title: "Credentials", headers: []string{"NAME", "ENV", "PATH"}}
+	for _, c := range s.Credentials {
+		credentials.rows = append(credentials.rows, []string{c.Name, c.Env, c.Path})
+	}
+	actions := summaryTable{title: "Actions", headers: []string{"NAME", "MODIFIES", "STATELESS", "DESCRIPTION"}}
+	for _, a := range s.Actions {
+		actions.rows = append(actions.rows, []string{a.Name, yesNo(a.Modifies), yesNo(a.Stateless), a.Description})
+	}
+	images := summaryTable{title: "Images", headers: []string{"NAME", "IMAGE", "DIGEST", "DESCRIPTION"}}
+	for _, img := range s.Images {
+		images.rows = append(images.rows, []string{img.Name, img.Image, img.Digest, img.Description})
+	}
+	return []summaryTable{parameters, credentials, actions, images}
+}
+
+func describeText(w io.Writer, s *Summary) error {
+	fmt.Fprintf(w, "Name:        %s\nVersion:     %s\n", s.Name, s.Version)
+	if s.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", s.Description)
+	}
+	for _, table := range summaryTables(s) {
+		fmt.Fprintf(w, "\n%s (%d)\n", table.title, len(table.rows))
+		if len(table.rows) == 0 {
+			continue
+		}
+		tab := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tab, strings.Join(table.headers, "\t"))
+		for _, row := range table.rows {
+			fmt.Fprintln(tab, strings.Join(row, "\t"))
+		}
+		if err := tab.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func describeMarkdown(w io.Writer, s *Summary) error {
+	fmt.Fprintf(w, "# %s %s\n", s.Name, s.Version)
+	if s.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", s.Description)
+	}
+	for _, table := range summaryTables(s) {
+		fmt.Fprintf(w, "\n## %s\n\n", table.title)
+		if len(table.rows) == 0 {
+			fmt.Fprintf(w, "None.\n")
+			continue
+		}
+		headers := make([]string, len(table.headers))
+		separators := make([]string, len(table.headers))
+		for i, header := range table.headers {
+			headers[i] = strings.Title(strings.ToLower(header))
+			separators[i] = "---"
+		}
+		fmt.Fprintf(w, "| %s |\n| %s |\n", strings.Join(headers, " | "), strings.Join(separators, " | "))
+		for _, row := range table.rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = markdownCell(cell)
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		}
+	}
+	return nil
+}
+
+// summaryValue displays a default value, strings as they are and other
+// values as JSON.
+func summaryValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// markdownCell escapes the characters breaking a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
@@ -0,0 +1,113 @@
+package cnab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func describedBundle() *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:        "myapp",
+		Version:     "1.0.0",
+		Description: "My app",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "myapp-installer:1.0.0"}},
+		},
+		Images: map[string]bundle.Image{
+			"web": {BaseImage: bundle.BaseImage{ImageType: "docker", Image: "nginx:1.17", Digest: "sha256:abc"}, Description: "web | proxy"},
+		},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"port":     {DataType: "int", Default: 8080, Metadata: &bundle.ParameterMetadata{Description: "Port to expose"}},
+			"password": {DataType: "string", Default: "secret", Required: true},
+		},
+		Credentials: map[string]bundle.Location{
+			"kubeconfig": {Path: "/root/.kube/config"},
+		},
+		Actions: map[string]bundle.Action{
+			"status": {Stateless: true, Description: "Show the status"},
+		},
+		Custom: map[string]interface{}{
+			SensitiveParametersExtensionKey: []string{"password"},
+		},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s, err := Summarize(describedBundle())
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(s.Parameters, []ParameterSummary{
+		{Name: "password", Type: "string", Default: "*****", Required: true, Sensitive: true},
+		{Name: "port", Type: "int", Default: 8080, Description: "Port to expose"},
+	}))
+	assert.Check(t, is.DeepEqual(s.Credentials, []CredentialSummary{{Name: "kubeconfig", Path: "/root/.kube/config"}}))
+	assert.Check(t, is.DeepEqual(s.Actions, []ActionSummary{
+		{Name: "install", Modifies: true},
+		{Name: "uninstall", Modifies: true},
+		{Name: "upgrade", Modifies: true},
+		{Name: "status", Stateless: true, Description: "Show the status"},
+	}))
+	assert.Check(t, is.DeepEqual(s.Images, []ImageSummary{
+		{Name: "invocation[0]", Image: "myapp-installer:1.0.0"},
+		{Name: "web", Image: "nginx:1.17", Digest: "sha256:abc", Description: "web | proxy"},
+	}))
+}
+
+func TestDescribeText(t *testing.T) {
+	out, err := Describe(describedBundle(), DescribeText)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(out), `Name:        myapp
+Version:     1.0.0
+Description: My app
+
+Parameters (2)
+NAME      TYPE    DEFAULT  REQUIRED  DESCRIPTION
+password  string  *****    yes       
+port      int     8080     no        Port to expose
+
+Credentials (1)
+NAME        ENV  PATH
+kubeconfig       /root/.kube/config
+
+Actions (4)
+NAME       MODIFIES  STATELESS  DESCRIPTION
+install    yes       no         
+uninstall  yes       no         
+upgrade    yes       no         
+status     no        yes        Show the status
+
+Images (2)
+NAME           IMAGE                  DIGEST      DESCRIPTION
+invocation[0]  myapp-installer:1.0.0              
+web            nginx:1.17             sha256:abc  web | proxy
+`))
+}
+
+func TestDescribeMarkdown(t *testing.T) {
+	b := describedBundle()
+	b.Credentials = nil
+	out, err := Describe(b, DescribeMarkdown)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(out), "# myapp 1.0.0\n\nMy app\n"))
+	assert.Check(t, is.Contains(string(out), "## Credentials\n\nNone.\n"))
+	assert.Check(t, is.Contains(string(out), "| Name | Type | Default | Required | Description |\n| --- | --- | --- | --- | --- |\n| password | string | ***** | yes |  |\n"))
+	assert.Check(t, is.Contains(string(out), `| web | nginx:1.17 | sha256:abc | web \| proxy |`))
+}
+
+func TestDescribeJSON(t *testing.T) {
+	out, err := Describe(describedBundle(), DescribeJSON)
+	assert.NilError(t, err)
+	var s Summary
+	assert.NilError(t, json.Unmarshal(out, &s))
+	assert.Check(t, is.Equal(s.Name, "myapp"))
+	assert.Check(t, is.Len(s.Parameters, 2))
+	assert.Check(t, is.Equal(s.Parameters[0].Default, "*****"))
+}
+
+func TestDescribeUnknownFormat(t *testing.T) {
+	_, err := Describe(describedBundle(), "yaml")
+	assert.Check(t, is.Error(err, `unknown summary format "yaml", expected "text", "json" or "markdown"`))
+}
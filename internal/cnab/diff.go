@@ -0,0 +1,216 @@
+package cnab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	canonicaljson "github.com/docker/go/canonical/json"
+)
+
+// ChangeKind is the kind of change of a bundle element.
+type ChangeKind string
+
+// Kinds of changes.
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "changed"
+)
+
+// Change is a change of a bundle element between two bundles.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Path is the JSON path of the element, like $.parameters["port"].
+	Path string `json:"path"`
+	// Fields lists the fields of a changed element which differ, like
+	// "digest" when only the digest of an image changed.
+	Fields []string    `json:"fields,omitempty"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+// ChangeSet lists the changes between two bundles, sorted by path.
+type ChangeSet struct {
+	Parameters  []Change `json:"parameters,omitempty"`
+	Credentials []Change `json:"credentials,omitempty"`
+	// Images lists the changes of invocation images and images.
+	Images  []Change `json:"images,omitempty"`
+	Actions []Change `json:"actions,omitempty"`
+}
+
+// Empty returns true if there is no change.
+func (c *ChangeSet) Empty() bool {
+	return len(c.Parameters)+len(c.Credentials)+len(c.Images)+len(c.Actions) == 0
+}
+
+// Diff returns the changes of the parameters, credentials, images and
+// actions from the old bundle to the new one.
+func Diff(old, new *bundle.Bundle) (*ChangeSet, error) {
+	cs := &ChangeSet{}
+	var err error
+	if cs.Parameters, err = diffElements("$.parameters[%q]", parameterElements(old), parameterElements(new)); err != nil {
+		return nil, err
+	}
+	if cs.Credentials, err = diffElements("$.credentials[%q]", credentialElements(old), credentialElements(new)); err != nil {
+		return nil, err
+	}
+	if cs.Images, err = diffElements("$.invocationImages[%s]", invocationImageElements(old), invocationImageElements(new)); err != nil {
+		return nil, err
+	}
+	images, err := diffElements("$.images[%q]", imageElements(old), imageElements(new))
+	if err != nil {
+		return nil, err
+	}
+	cs.Images = append(cs.Images, images...)
+	if cs.Actions, err = diffElements("$.actions[%q]", actionElements(old), actionElements(new)); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func parameterElements(b *bundle.Bundle) map[string]interface{} {
+	elements := map[string]interface{}{}
+	for name, def := range b.Parameters {
+		elements[name] = def
+	}
+	return elements
+}
+
+func credentialElements(b *bundle.Bundle) map[string]interface{} {
+	elements := map[string]interface{}{}
+	for name, location := range b.Credentials {
+		elements[name] = location
+	}
+	return elements
+}
+
+func invocationImageElements(b *bundle.Bundle) map[string]interface{} {
+	elements := map[string]interface{}{}
+	for i, image := range b.InvocationImages {
+		elements[fmt.Sprint(i)] = image
+	}
+	return elements
+}
+
+func imageElements(b *bundle.Bundle) map[string]interface{} {
+	elements := map[string]interface{}{}
+	for name, image := range b.Images {
+		elements[name] = image
+	}
+	return elements
+}
+
+func actionElements(b *bundle.Bundle) map[string]interface{} {
+	elements := map[string]interface{}{}
+	for name, action := range b.Actions {
+		elements[name] = action
+	}
+	return elements
+}
+
+// diffElements compares elements by key, formatting their path with the key.
+func diffElements(pathFormat string, old, new map[string]interface{}) ([]Change, error) {
+	keys := map[string]bool{}
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range new {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []Change
+	for _, key := range sorted {
+		path := fmt.Sprintf(pathFormat, key)
+		o, inOld := old[key]
+		n, inNew := new[key]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Kind: ChangeAdded, Path: path, New: n})
+		case !inNew:
+			changes = append(changes, Change{Kind: ChangeRemoved, Path: path, Old: o})
+		default:
+			fields, err := changedFields(o, n)
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) > 0 {
+				changes = append(changes, Change{Kind: ChangeModified, Path: path, Fields: fields, Old: o, New: n})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// changedFields returns the sorted JSON fields whose canonical values differ.
+func changedFields(old, new interface{}) ([]string, error) {
+	o, err := jsonFieldsOf(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := jsonFieldsOf(new)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	for field, value := range o {
+		if !bytes.Equal(value, n[field]) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range n {
+		if _, ok := o[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// jsonFieldsOf returns the canonical JSON encoding of each field of a value.
+func jsonFieldsOf(value interface{}) (map[string][]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	fields := make(map[string][]byte, len(raw))
+	for field, value := range raw {
+		var decoded interface{}
+		if err := json.Unmarshal(value, &decoded); err != nil {
+			return nil, err
+		}
+		if fields[field], err = canonicaljson.MarshalCanonical(decoded); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// RenderChangeSet writes a human readable summary of the changes.
+func RenderChangeSet(w io.Writer, cs *ChangeSet) error {
+	for _, changes := range [][]Change{cs.Parameters, cs.Credentials, cs.Images, cs.Actions} {
+		for _, c := range changes {
+			line := fmt.Sprintf("%-8s %s", c.Kind, c.Path)
+			if len(c.Fields) > 0 {
+				line += " (" + strings.Join(c.Fields, ", ") + ")"
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cnab
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestDiff(t *testing.T) {
+	old, err := Parse([]byte(`{
+		"name": "app",
+		"version": "1.0.0",
+		"invocationImages": [{"imageType": "docker", "image": "app:1.0.0", "digest": "sha256:1"}],
+		"images": {"web": {"imageType": "docker", "image": "nginx:1.17", "digest": "sha256:a"}, "cache": {"imageType": "docker", "image": "redis:5"}},
+		"parameters": {"port": {"type": "string", "default": "80"}, "debug": {"type": "boolean"}},
+		"credentials": {"token": {"env": "TOKEN"}},
+		"actions": {"logs": {"stateless": true}}
+	}`))
+	assert.NilError(t, err)
+	new, err := Parse([]byte(`{
+		"name": "app",
+		"version": "1.1.0",
+		"invocationImages": [{"imageType": "docker", "image": "app:1.1.0", "digest": "sha256:2"}],
+		"images": {"web": {"imageType": "docker", "image": "nginx:1.17", "digest": "sha256:b"}, "cache": {"imageType": "docker", "image": "redis:5"}},
+		"parameters": {"port": {"type": "string", "default": "8080"}, "host": {"type": "string"}},
+		"credentials": {"token": {"env": "TOKEN"}},
+		"actions": {"logs": {"stateless": true}}
+	}`))
+	assert.NilError(t, err)
+
+	changes, err := Diff(old, new)
+	assert.NilError(t, err)
+	assert.Check(t, !changes.Empty())
+	assert.Check(t, is.Len(changes.Credentials, 0))
+	assert.Check(t, is.Len(changes.Actions, 0))
+
+	var out bytes.Buffer
+	assert.NilError(t, RenderChangeSet(&out, changes))
+	assert.Check(t, is.Equal(out.String(), `removed  $.parameters["debug"]
+added    $.parameters["host"]
+changed  $.parameters["port"] (default)
+changed  $.invocationImages[0] (digest, image)
+changed  $.images["web"] (digest)
+`))
+
+	changes, err = Diff(old, old)
+	assert.NilError(t, err)
+	assert.Check(t, changes.Empty())
+}
@@ -0,0 +1,67 @@
+package cnab
+
+import (
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// WithDigestConfig selects the algorithm of the digests and the ones
+// accepted when matching them (by default the configuration is read from the
+// environment, see checksum.FromEnv).
+func WithDigestConfig(config checksum.Config) func(*CanonicalOptions) {
+	return func(o *CanonicalOptions) {
+		o.Checksum = &config
+	}
+}
+
+// DigestConfig returns the checksum configuration selected by the options.
+func DigestConfig(opts ...func(*CanonicalOptions)) (checksum.Config, error) {
+	var o CanonicalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Checksum != nil {
+		return *o.Checksum, nil
+	}
+	return checksum.FromEnv()
+}
+
+// Digest returns the digest of the canonical JSON serialization of the
+// bundle, in the "<algorithm>:<hex>" form, SHA-256 unless configured
+// otherwise, see WithDigestConfig. Two bundles with the same content have
+// the same digest, whatever their key order or indentation. The
+// canonicalization scheme can be selected, see WithCanonicalizer.
+func Digest(b *bundle.Bundle, opts ...func(*CanonicalOptions)) (string, error) {
+	config, err := DigestConfig(opts...)
+	if err != nil {
+		return "", err
+	}
+	data, err := MarshalCanonical(b, opts...)
+	if err != nil {
+		return "", err
+	}
+	d, err := config.FromBytes(data)
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
+
+// MatchesDigest returns true if the bundle content matches the given digest,
+// which may use any allowed algorithm. An invalid digest never matches.
+func MatchesDigest(b *bundle.Bundle, expected string, opts ...func(*CanonicalOptions)) bool {
+	d, err := digest.Parse(expected)
+	if err != nil {
+		return false
+	}
+	config, err := DigestConfig(opts...)
+	if err != nil {
+		return false
+	}
+	data, err := MarshalCanonical(b, opts...)
+	if err != nil {
+		return false
+	}
+	return config.Verify(d, data) == nil
+}
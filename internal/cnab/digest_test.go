@@ -0,0 +1,61 @@
+package cnab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	digest "github.com/opencontainers/go-digest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestDigestIgnoresFormatting(t *testing.T) {
+	compact, err := bundle.Unmarshal([]byte(`{"name":"app","version":"0.1.0","schemaVersion":"v1.0.0-WD","invocationImages":[{"imageType":"docker","image":"app:0.1.0"}]}`))
+	assert.NilError(t, err)
+	indented, err := bundle.Unmarshal([]byte(`{
+		"version": "0.1.0",
+		"invocationImages": [{"image": "app:0.1.0", "imageType": "docker"}],
+		"schemaVersion": "v1.0.0-WD",
+		"name": "app"
+	}`))
+	assert.NilError(t, err)
+
+	d1, err := Digest(compact)
+	assert.NilError(t, err)
+	d2, err := Digest(indented)
+	assert.NilError(t, err)
+	assert.Equal(t, d1, d2)
+	assert.Assert(t, strings.HasPrefix(d1, "sha256:"))
+	assert.Assert(t, MatchesDigest(indented, d1))
+
+	indented.Version = "0.2.0"
+	assert.Assert(t, !MatchesDigest(indented, d1))
+}
+
+func TestMatchesDigestInvalid(t *testing.T) {
+	b := &bundle.Bundle{Name: "app"}
+	d, err := Digest(b)
+	assert.NilError(t, err)
+	assert.Check(t, !MatchesDigest(b, ""))
+	assert.Check(t, !MatchesDigest(b, "not-a-digest"))
+	assert.Check(t, !MatchesDigest(b, strings.TrimPrefix(d, "sha256:")))
+	assert.Check(t, is.Equal(len(d), len("sha256:")+64))
+}
+
+func TestDigestConfiguredAlgorithm(t *testing.T) {
+	b := &bundle.Bundle{Name: "app", Version: "0.1.0"}
+	sha256, err := Digest(b)
+	assert.NilError(t, err)
+	sha512, err := Digest(b, WithDigestConfig(checksum.Config{Algorithm: digest.SHA512, FIPS: true}))
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasPrefix(sha512, "sha512:"))
+
+	// Any allowed algorithm matches, whatever the configured one
+	assert.Check(t, MatchesDigest(b, sha512))
+	assert.Check(t, MatchesDigest(b, sha256, WithDigestConfig(checksum.Config{Algorithm: digest.SHA384})))
+
+	_, err = Digest(b, WithDigestConfig(checksum.Config{Algorithm: "md5"}))
+	assert.Check(t, is.ErrorContains(err, `unsupported digest algorithm "md5"`))
+}
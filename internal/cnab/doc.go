@@ -0,0 +1,8 @@
+// Package cnab provides helpers around CNAB bundles on top of the cnab-go
+// library.
+//
+// The functions decoding bundles (Parse, Load, LoadData, Decrypt) are safe to
+// use on untrusted input: whatever the input, they return an error rather
+// than panic or exhaust the stack. The Fuzz function, built with the gofuzz
+// tag, checks this guarantee with go-fuzz.
+package cnab
@@ -0,0 +1,193 @@
+package cnab
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// EncryptedFormat identifies an encrypted bundle document.
+	EncryptedFormat = "application/vnd.docker.app.encrypted-bundle.v1+json"
+
+	encryptionCipher = "aes-256-gcm"
+	encryptionKDF    = "pbkdf2-sha256"
+	kdfIterations    = 100000
+	saltSize         = 16
+	keySize          = 32
+)
+
+// MaxEncryptedSize is the maximum size of the encrypted bundle documents.
+// Their ciphertext is base64 encoded, so it is larger than the MaxBundleSize
+// bound of the plaintext.
+const MaxEncryptedSize = MaxBundleSize/3*4 + 4096
+
+// KeyFunc returns the passphrase the key of an encrypted bundle is derived
+// from, for instance by prompting for it or reading it from a secret store.
+// It is only called once the passphrase is needed.
+type KeyFunc func() ([]byte, error)
+
+// encryptedBundle is the envelope of a bundle encrypted at rest. The format
+// and the key derivation parameters are authenticated along the encrypted
+// canonical bundle.
+type encryptedBundle struct {
+	Format     string `json:"format"`
+	Cipher     string `json:"cipher"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Encrypt serializes the bundle in its canonical form and encrypts it with
+// a key derived from the passphrase.
+func Encrypt(b *bundle.Bundle, passphrase []byte) ([]byte, error) {
+	plaintext, err := MarshalCanonical(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt bundle")
+	}
+	return encrypt(plaintext, passphrase)
+}
+
+func encrypt(plaintext, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("failed to encrypt bundle, passphrase is empty")
+	}
+	env := encryptedBundle{
+		Format:     EncryptedFormat,
+		Cipher:     encryptionCipher,
+		KDF:        encryptionKDF,
+		Iterations: kdfIterations,
+		Salt:       make([]byte, saltSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, env.Salt); err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt bundle")
+	}
+	aead, err := env.aead(passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt bundle")
+	}
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, env.Nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt bundle")
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, plaintext, env.additionalData())
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// Decrypt decrypts a bundle encrypted by Encrypt and parses it, see Parse.
+func Decrypt(data []byte, passphrase []byte) (*bundle.Bundle, error) {
+	plaintext, err := DecryptDocument(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(plaintext)
+}
+
+// DecryptDocument decrypts a bundle encrypted by Encrypt and returns its
+// document, for the callers migrating or validating it before parsing it.
+func DecryptDocument(data []byte, passphrase []byte) ([]byte, error) {
+	var env encryptedBundle
+	if err := json.Unmarshal(data, &env); err != nil || env.Format != EncryptedFormat {
+		return nil, errors.New("failed to decrypt bundle, not an encrypted bundle")
+	}
+	if env.Cipher != encryptionCipher || env.KDF != encryptionKDF {
+		return nil, errors.Errorf("failed to decrypt bundle, unsupported encryption %s/%s", env.Cipher, env.KDF)
+	}
+	// The iterations are fixed, a crafted document could otherwise make the
+	// key derivation run for ever
+	if env.Iterations != kdfIterations || len(env.Salt) != saltSize {
+		return nil, errors.New("failed to decrypt bundle, invalid key derivation parameters")
+	}
+	aead, err := env.aead(passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt bundle")
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, errors.New("failed to decrypt bundle, invalid nonce")
+	}
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, env.additionalData())
+	if err != nil {
+		return nil, errors.New("failed to decrypt bundle, wrong passphrase or corrupted content")
+	}
+	if len(plaintext) > MaxBundleSize {
+		return nil, errors.Errorf("invalid bundle: larger than %d bytes", MaxBundleSize)
+	}
+	return plaintext, nil
+}
+
+// IsEncrypted returns true if data looks like an encrypted bundle.
+func IsEncrypted(data []byte) bool {
+	if !bytes.Contains(data, []byte(EncryptedFormat)) {
+		return false
+	}
+	var env encryptedBundle
+	return json.Unmarshal(data, &env) == nil && env.Format == EncryptedFormat
+}
+
+// WriteFileEncrypted encrypts the canonical JSON document of the bundle with
+// the passphrase returned by key and writes it to a file, as WriteFile does.
+func WriteFileEncrypted(b *bundle.Bundle, path string, mode os.FileMode, opts WriteFileOptions, key KeyFunc) error {
+	plaintext, err := MarshalCanonical(b, WithCanonicalizer(opts.Canonicalizer))
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt bundle")
+	}
+	passphrase, err := key()
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt bundle")
+	}
+	data, err := encrypt(plaintext, passphrase)
+	if err != nil {
+		return err
+	}
+	return WriteDocument(data, path, mode, opts)
+}
+
+// ReadFileEncrypted reads a bundle file written by WriteFileEncrypted and
+// decrypts it with the passphrase returned by key.
+func ReadFileEncrypted(path string, key KeyFunc) (*bundle.Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := ReadLimited(f, MaxEncryptedSize)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(data) {
+		return nil, errors.New("failed to decrypt bundle, not an encrypted bundle")
+	}
+	passphrase, err := key()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt bundle")
+	}
+	return Decrypt(data, passphrase)
+}
+
+func (e encryptedBundle) aead(passphrase []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key(passphrase, e.Salt, e.Iterations, keySize, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (e encryptedBundle) additionalData() []byte {
+	header := e
+	header.Nonce = nil
+	header.Ciphertext = nil
+	data, _ := json.Marshal(header)
+	return data
+}
@@ -0,0 +1,80 @@
+package cnab
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	b := &bundle.Bundle{Name: "myapp", Version: "1.0.0", Credentials: map[string]bundle.Location{"secret": {Path: "/secret"}}}
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	key := func(passphrase string) KeyFunc {
+		return func() ([]byte, error) { return []byte(passphrase), nil }
+	}
+
+	assert.NilError(t, WriteFileEncrypted(b, dir.Join("bundle.json.enc"), 0600, WriteFileOptions{}, key("passphrase")))
+	actual, err := ReadFileEncrypted(dir.Join("bundle.json.enc"), key("passphrase"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, actual, b)
+
+	_, err = ReadFileEncrypted(dir.Join("bundle.json.enc"), key("wrong"))
+	assert.Error(t, err, "failed to decrypt bundle, wrong passphrase or corrupted content")
+	_, err = ReadFileEncrypted(dir.Join("bundle.json.enc"), func() ([]byte, error) { return nil, errors.New("no key") })
+	assert.Error(t, err, "failed to decrypt bundle: no key")
+
+	// The destination is not overwritten unless forced
+	err = WriteFileEncrypted(b, dir.Join("bundle.json.enc"), 0600, WriteFileOptions{}, key("passphrase"))
+	assert.Check(t, is.ErrorContains(err, "already exists"))
+}
+
+func TestDecryptDetectsTampering(t *testing.T) {
+	data, err := Encrypt(&bundle.Bundle{Name: "myapp"}, []byte("passphrase"))
+	assert.NilError(t, err)
+	assert.Assert(t, IsEncrypted(data))
+
+	var env encryptedBundle
+	assert.NilError(t, json.Unmarshal(data, &env))
+	env.Iterations = 1
+	tampered, err := json.Marshal(env)
+	assert.NilError(t, err)
+	_, err = Decrypt(tampered, []byte("passphrase"))
+	assert.Error(t, err, "failed to decrypt bundle, invalid key derivation parameters")
+
+	// The key derivation cost is not read from the document
+	env.Iterations = 1 << 30
+	tampered, err = json.Marshal(env)
+	assert.NilError(t, err)
+	_, err = Decrypt(tampered, []byte("passphrase"))
+	assert.Error(t, err, "failed to decrypt bundle, invalid key derivation parameters")
+
+	env.Iterations = kdfIterations
+	env.Format = "other"
+	tampered, err = json.Marshal(env)
+	assert.NilError(t, err)
+	_, err = Decrypt(tampered, []byte("passphrase"))
+	assert.Error(t, err, "failed to decrypt bundle, not an encrypted bundle")
+}
+
+func TestDecryptParsesBundle(t *testing.T) {
+	// The decrypted document goes through the hardening of Parse
+	data, err := encrypt([]byte(`{"name":"myapp","parameters":`+strings.Repeat("[", 1000)+strings.Repeat("]", 1000)+`}`), []byte("passphrase"))
+	assert.NilError(t, err)
+	_, err = Decrypt(data, []byte("passphrase"))
+	assert.Check(t, is.ErrorContains(err, "nest"))
+}
+
+func TestEncryptRequiresPassphrase(t *testing.T) {
+	_, err := Encrypt(&bundle.Bundle{}, nil)
+	assert.Error(t, err, "failed to encrypt bundle, passphrase is empty")
+	assert.Assert(t, !IsEncrypted([]byte(`{"name":"myapp"}`)))
+	_, err = Decrypt([]byte(`{"name":"myapp"}`), []byte("passphrase"))
+	assert.Error(t, err, "failed to decrypt bundle, not an encrypted bundle")
+}
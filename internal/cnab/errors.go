@@ -0,0 +1,88 @@
+package cnab
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The errors of this package can be told apart without matching their
+// messages: sentinel errors are compared to the cause of an error, as given
+// by errors.Cause, and error types are asserted on it.
+
+// ErrMissingInvocationImage is the cause of the errors about a bundle
+// without invocation image, or without one for a platform.
+var ErrMissingInvocationImage = errors.New("missing invocation image")
+
+// sentinelError annotates a sentinel error with a message.
+type sentinelError struct {
+	msg string
+	err error
+}
+
+func (e *sentinelError) Error() string {
+	return e.msg
+}
+
+// Cause returns the sentinel error.
+func (e *sentinelError) Cause() error {
+	return e.err
+}
+
+// withSentinel returns an error with the formatted message, whose cause is
+// the sentinel error.
+func withSentinel(sentinel error, format string, args ...interface{}) error {
+	return &sentinelError{msg: fmt.Sprintf(format, args...), err: sentinel}
+}
+
+// ParameterValidationError is returned when the value of a parameter is
+// rejected.
+type ParameterValidationError struct {
+	Name string
+	// Reason tells why the value is rejected.
+	Reason string
+}
+
+func (e *ParameterValidationError) Error() string {
+	return fmt.Sprintf("invalid value for parameter %q: %s", e.Name, e.Reason)
+}
+
+// IsParameterValidation returns true if the cause of the error is a
+// ParameterValidationError.
+func IsParameterValidation(err error) bool {
+	_, ok := errors.Cause(err).(*ParameterValidationError)
+	return ok
+}
+
+// CredentialMissingError is returned when a credential required by a bundle
+// is not supplied.
+type CredentialMissingError struct {
+	Name string
+}
+
+func (e *CredentialMissingError) Error() string {
+	return fmt.Sprintf("bundle requires credential %q", e.Name)
+}
+
+// MissingCredentialsError lists the required credentials which are not
+// supplied, sorted by name.
+type MissingCredentialsError []*CredentialMissingError
+
+func (e MissingCredentialsError) Error() string {
+	names := make([]string, len(e))
+	for i, err := range e {
+		names[i] = err.Name
+	}
+	return fmt.Sprintf("bundle requires credentials for %s", strings.Join(names, ", "))
+}
+
+// IsCredentialMissing returns true if the cause of the error is a
+// CredentialMissingError or a MissingCredentialsError.
+func IsCredentialMissing(err error) bool {
+	switch errors.Cause(err).(type) {
+	case *CredentialMissingError, MissingCredentialsError:
+		return true
+	}
+	return false
+}
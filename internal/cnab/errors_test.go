@@ -0,0 +1,55 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestErrMissingInvocationImage(t *testing.T) {
+	_, err := SelectInvocationImage(&bundle.Bundle{Name: "app"}, "linux", "amd64")
+	assert.Check(t, is.Equal(errors.Cause(err), ErrMissingInvocationImage))
+	assert.Check(t, is.Equal(errors.Cause(errors.Wrap(err, "failed")), ErrMissingInvocationImage))
+
+	errs := Validate(&bundle.Bundle{Name: "app", Version: "1.0.0"})
+	assert.Check(t, errs.HasCode(CodeMissingInvocationImage))
+	assert.Check(t, !errs[0:0].HasCode(CodeMissingInvocationImage))
+}
+
+func TestParameterValidationError(t *testing.T) {
+	b := &bundle.Bundle{
+		Parameters: map[string]bundle.ParameterDefinition{"port": {DataType: "integer"}},
+		Custom: map[string]interface{}{
+			ParameterSchemasExtensionKey: map[string]interface{}{
+				"parameters": map[string]interface{}{"port": map[string]interface{}{"type": "integer", "minimum": 1}},
+			},
+		},
+	}
+	err := ValidateParameters(b, map[string]interface{}{"port": 0})
+	target, ok := errors.Cause(errors.Wrap(err, "failed")).(*ParameterValidationError)
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal(target.Name, "port"))
+	assert.Check(t, is.Equal(target.Reason, "0 is lower than the minimum 1"))
+	assert.Check(t, IsParameterValidation(errors.Wrap(err, "failed")))
+}
+
+func TestCredentialMissingError(t *testing.T) {
+	b := &bundle.Bundle{
+		Credentials: map[string]bundle.Location{
+			"token":      {EnvironmentVariable: "TOKEN"},
+			"kubeconfig": {Path: "/root/.kube/config"},
+		},
+	}
+	err := ValidateCredentials(b, credentials.Set{})
+	assert.Check(t, is.Error(err, "bundle requires credentials for kubeconfig, token"))
+	assert.Check(t, IsCredentialMissing(errors.Wrap(err, "failed")))
+	missing, ok := errors.Cause(errors.Wrap(err, "failed")).(MissingCredentialsError)
+	assert.Assert(t, ok)
+	assert.Assert(t, is.Len(missing, 2))
+	assert.Check(t, is.Equal(missing[0].Name, "kubeconfig"))
+	assert.Check(t, !IsCredentialMissing(errors.New("failed")))
+}
@@ -0,0 +1,126 @@
+package cnab
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+)
+
+// Well known CNAB extensions.
+const (
+	// DockerExtensionKey is the extension configuring the docker driver.
+	DockerExtensionKey = "io.cnab.docker"
+	// ParameterSourcesExtensionKey is the extension declaring where the
+	// values of parameters come from, like the outputs of a previous action.
+	ParameterSourcesExtensionKey = "io.cnab.parameter-sources"
+)
+
+// DockerExtension is the content of the docker extension.
+type DockerExtension struct {
+	// Privileged runs the invocation image as a privileged container.
+	Privileged bool `json:"privileged,omitempty"`
+}
+
+// ParameterSources is the content of the parameter sources extension, by
+// parameter name.
+type ParameterSources map[string]ParameterSource
+
+// ParameterSource declares where the value of a parameter comes from.
+type ParameterSource struct {
+	// Priority lists the source kinds, like "output", the first one
+	// providing a value is used.
+	Priority []string `json:"priority"`
+	// Sources maps a source kind to its definition.
+	Sources map[string]ParameterSourceDefinition `json:"sources"`
+}
+
+// ParameterSourceDefinition identifies a source of a parameter value, like
+// the name of an output.
+type ParameterSourceDefinition struct {
+	Name string `json:"name"`
+	// Dependency is the name of the dependency producing the output, for
+	// the dependency output sources.
+	Dependency string `json:"dependency,omitempty"`
+}
+
+// ExtensionFactory returns a pointer to a new value of the type of an
+// extension, which the extension content is decoded into.
+type ExtensionFactory func() interface{}
+
+var (
+	extensionsMu sync.RWMutex
+	extensions   = map[string]ExtensionFactory{}
+)
+
+func init() {
+	RegisterExtension(DockerExtensionKey, func() interface{} { return &DockerExtension{} })
+	RegisterExtension(ParameterSourcesExtensionKey, func() interface{} { return &ParameterSources{} })
+}
+
+// RegisterExtension registers the type of a custom extension, so it can be
+// read with ReadCustomExtension. It panics if the extension is already
+// registered.
+func RegisterExtension(key string, factory ExtensionFactory) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	if factory == nil {
+		panic(fmt.Sprintf("extension %s registered without factory", key))
+	}
+	if _, ok := extensions[key]; ok {
+		panic(fmt.Sprintf("extension %s registered twice", key))
+	}
+	extensions[key] = factory
+}
+
+// RegisteredExtensions returns the sorted keys of the registered extensions.
+func RegisteredExtensions() []string {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+	keys := make([]string, 0, len(extensions))
+	for key := range extensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetCustomExtension decodes a custom extension of the bundle into target,
+// which must be a pointer. It returns false if the bundle does not have the
+// extension.
+func GetCustomExtension(b *bundle.Bundle, key string, target interface{}) (bool, error) {
+	value, ok := b.Custom[key]
+	if !ok {
+		return false, nil
+	}
+	// The extension is a generic map when the bundle has been decoded from JSON
+	data, err := json.Marshal(value)
+	if err != nil {
+		return true, errors.Wrapf(err, "invalid %s extension", key)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return true, errors.Wrapf(err, "invalid %s extension", key)
+	}
+	return true, nil
+}
+
+// ReadCustomExtension decodes a registered custom extension of the bundle,
+// returning a pointer to a value of its registered type. It returns nil if
+// the bundle does not have the extension.
+func ReadCustomExtension(b *bundle.Bundle, key string) (interface{}, error) {
+	extensionsMu.RLock()
+	factory, ok := extensions[key]
+	extensionsMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("unknown extension %s", key)
+	}
+	target := factory()
+	found, err := GetCustomExtension(b, key, target)
+	if err != nil || !found {
+		return nil, err
+	}
+	return target, nil
+}
@@ -0,0 +1,69 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestGetCustomExtension(t *testing.T) {
+	b, err := Parse([]byte(`{"name":"app","custom":{
+		"io.cnab.docker": {"privileged": true},
+		"io.cnab.parameter-sources": {"tfstate": {"priority": ["output"], "sources": {"output": {"name": "tfstate"}}}}
+	}}`))
+	assert.NilError(t, err)
+
+	var docker DockerExtension
+	found, err := GetCustomExtension(b, DockerExtensionKey, &docker)
+	assert.NilError(t, err)
+	assert.Check(t, found)
+	assert.Check(t, docker.Privileged)
+
+	sources, err := ReadCustomExtension(b, ParameterSourcesExtensionKey)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(sources, &ParameterSources{
+		"tfstate": {
+			Priority: []string{"output"},
+			Sources:  map[string]ParameterSourceDefinition{"output": {Name: "tfstate"}},
+		},
+	}))
+
+	found, err = GetCustomExtension(&bundle.Bundle{}, DockerExtensionKey, &docker)
+	assert.NilError(t, err)
+	assert.Check(t, !found)
+	value, err := ReadCustomExtension(&bundle.Bundle{}, DockerExtensionKey)
+	assert.NilError(t, err)
+	assert.Check(t, value == nil)
+}
+
+func TestReadCustomExtensionErrors(t *testing.T) {
+	b := &bundle.Bundle{Custom: map[string]interface{}{DockerExtensionKey: "privileged"}}
+	_, err := ReadCustomExtension(b, DockerExtensionKey)
+	assert.ErrorContains(t, err, "invalid io.cnab.docker extension")
+
+	_, err = ReadCustomExtension(b, "com.example.unknown")
+	assert.Error(t, err, "unknown extension com.example.unknown")
+}
+
+func TestRegisterExtension(t *testing.T) {
+	const key = "com.example.test-extension"
+	type testExtension struct {
+		Value string `json:"value"`
+	}
+	RegisterExtension(key, func() interface{} { return &testExtension{} })
+	defer func() {
+		extensionsMu.Lock()
+		delete(extensions, key)
+		extensionsMu.Unlock()
+	}()
+	assert.Check(t, is.Contains(RegisteredExtensions(), key))
+	assert.Check(t, is.Contains(RegisteredExtensions(), DockerExtensionKey))
+
+	value, err := ReadCustomExtension(&bundle.Bundle{Custom: map[string]interface{}{key: map[string]interface{}{"value": "v"}}}, key)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(value, &testExtension{Value: "v"}))
+
+	assert.Check(t, is.Panics(func() { RegisterExtension(key, func() interface{} { return &testExtension{} }) }))
+}
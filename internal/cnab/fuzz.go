@@ -0,0 +1,29 @@
+// +build gofuzz
+
+package cnab
+
+import "io/ioutil"
+
+// Fuzz is the go-fuzz entry point for the bundle decoder.
+//
+//	go-fuzz-build -tags gofuzz github.com/docker/app/internal/cnab
+//	go-fuzz -bin cnab-fuzz.zip -workdir fuzz
+//
+// With Go 1.18 or later, prefer the native fuzz targets of fuzz_test.go.
+func Fuzz(data []byte) int {
+	b, err := Parse(data)
+	if err != nil {
+		if b != nil {
+			panic("bundle returned along with an error")
+		}
+		return 0
+	}
+	// a successfully decoded bundle must survive the operations run on
+	// untrusted bundles
+	b.Validate()                 //nolint:errcheck
+	DefaultCustomLimits.Check(b) //nolint:errcheck
+	if _, err := b.WriteTo(ioutil.Discard); err != nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,66 @@
+//go:build go1.18
+// +build go1.18
+
+package cnab_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+)
+
+// The fuzz targets below are seeded with the bundletest corpus, which runs
+// as regular tests. Run them with the native fuzzer:
+//
+//	go test -run '^$' -fuzz FuzzParse ./internal/cnab
+
+func addCorpus(f *testing.F) {
+	for _, doc := range bundletest.ValidDocuments() {
+		f.Add(doc.Data)
+	}
+	for _, doc := range bundletest.InvalidDocuments() {
+		f.Add(doc.Data)
+	}
+}
+
+// checkDecoded fails if a bundle is returned along with an error, and runs
+// the operations run on untrusted bundles on the decoded ones.
+func checkDecoded(t *testing.T, b *bundle.Bundle, err error) {
+	if err != nil {
+		if b != nil {
+			t.Fatalf("bundle returned along with error %v", err)
+		}
+		return
+	}
+	b.Validate()                      //nolint:errcheck
+	cnab.DefaultCustomLimits.Check(b) //nolint:errcheck
+	b.WriteTo(ioutil.Discard)         //nolint:errcheck
+}
+
+func FuzzParse(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, err := cnab.Parse(data)
+		checkDecoded(t, b, err)
+	})
+}
+
+func FuzzParseReaderLimited(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, err := cnab.ParseReaderLimited(bytes.NewReader(data), 1<<20)
+		checkDecoded(t, b, err)
+	})
+}
+
+func FuzzUnmarshalStrict(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b, err := cnab.UnmarshalStrict(data)
+		checkDecoded(t, b, err)
+	})
+}
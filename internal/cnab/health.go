@@ -0,0 +1,99 @@
+package cnab
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/deislabs/cnab-go/action"
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal"
+)
+
+// StatusActionNames are the conventional names of the action reporting the
+// health of an installation, by order of preference.
+var StatusActionNames = []string{
+	internal.ActionStatusName,
+	internal.ActionStatusNameDeprecated,
+	internal.CnabNamespace + "health",
+	"status",
+	"health",
+}
+
+// Health statuses reported by a status action.
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+	HealthUnknown   = "unknown"
+)
+
+// HealthReport is the structured output of a status action.
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Message    string            `json:"message,omitempty"`
+	Components []ComponentHealth `json:"components,omitempty"`
+}
+
+// ComponentHealth is the health of a part of an installation, for instance a
+// service.
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// StatusAction returns the name of the status action of the bundle, or an
+// empty string if it has none. A status action must not modify the
+// installation.
+func StatusAction(b *bundle.Bundle) string {
+	for _, name := range StatusActionNames {
+		if a, ok := b.Actions[name]; ok && !a.Modifies {
+			return name
+		}
+	}
+	return ""
+}
+
+// ParseHealthReport parses the output of a status action. A JSON object with
+// a status is decoded as is, any other output is kept as the message of a
+// report with an unknown status.
+func ParseHealthReport(output []byte) *HealthReport {
+	var report HealthReport
+	if err := json.Unmarshal(bytes.TrimSpace(output), &report); err == nil && report.Status != "" {
+		report.Status = strings.ToLower(report.Status)
+		return &report
+	}
+	return &HealthReport{
+		Status:  HealthUnknown,
+		Message: strings.TrimSpace(string(output)),
+	}
+}
+
+// RunStatus runs the status action of the installation, streaming its output
+// to w, and returns the parsed report. The claim is left untouched, so
+// checking the status never creates a new revision.
+func RunStatus(c claim.Claim, creds credentials.Set, d driver.Driver, w io.Writer) (*HealthReport, error) {
+	name := StatusAction(c.Bundle)
+	if name == "" {
+		return nil, action.ErrUndefinedAction
+	}
+	output := bytes.NewBuffer(nil)
+	if w != nil {
+		w = io.MultiWriter(w, output)
+	} else {
+		w = output
+	}
+	status := &action.RunCustom{
+		Action: name,
+		Driver: d,
+	}
+	if err := status.Run(&c, creds, w); err != nil {
+		return nil, err
+	}
+	return ParseHealthReport(output.Bytes()), nil
+}
@@ -0,0 +1,58 @@
+package cnab_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/deislabs/cnab-go/action"
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestStatusAction(t *testing.T) {
+	assert.Equal(t, cnab.StatusAction(bundletest.NewTestBundle()), "")
+	assert.Equal(t, cnab.StatusAction(bundletest.NewTestBundle(
+		bundletest.WithAction("health", bundle.Action{Stateless: true}),
+		bundletest.WithAction(internal.ActionStatusNameDeprecated, bundle.Action{}),
+	)), internal.ActionStatusNameDeprecated)
+	// status actions must not modify the installation
+	assert.Equal(t, cnab.StatusAction(bundletest.NewTestBundle(
+		bundletest.WithAction("status", bundle.Action{Modifies: true}),
+	)), "")
+}
+
+func TestParseHealthReport(t *testing.T) {
+	report := cnab.ParseHealthReport([]byte(`{"status":"Degraded","components":[{"name":"web","status":"unhealthy","message":"0/1 replicas"}]}`))
+	assert.DeepEqual(t, report, &cnab.HealthReport{
+		Status:     cnab.HealthDegraded,
+		Components: []cnab.ComponentHealth{{Name: "web", Status: "unhealthy", Message: "0/1 replicas"}},
+	})
+	report = cnab.ParseHealthReport([]byte("ID  NAME  REPLICAS\n"))
+	assert.DeepEqual(t, report, &cnab.HealthReport{Status: cnab.HealthUnknown, Message: "ID  NAME  REPLICAS"})
+}
+
+func TestRunStatus(t *testing.T) {
+	c, err := claim.New("my-installation")
+	assert.NilError(t, err)
+	c.Bundle = bundletest.NewTestBundle(bundletest.WithAction(internal.ActionStatusName, bundle.Action{}))
+	revision := c.Revision
+	d := fake.New().Script(internal.ActionStatusName, fake.Result{Output: `{"status":"healthy"}`})
+	out := bytes.NewBuffer(nil)
+
+	report, err := cnab.RunStatus(*c, nil, d, out)
+	assert.NilError(t, err)
+	assert.Equal(t, report.Status, cnab.HealthHealthy)
+	assert.Equal(t, out.String(), `{"status":"healthy"}`)
+	assert.Equal(t, c.Revision, revision)
+	assert.Equal(t, len(d.Operations()), 1)
+
+	c.Bundle = bundletest.NewTestBundle()
+	_, err = cnab.RunStatus(*c, nil, d, nil)
+	assert.Check(t, is.Equal(err, action.ErrUndefinedAction))
+}
@@ -0,0 +1,148 @@
+package cnab
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// defaultImageType is the type of the images without image type, per the
+// CNAB specification.
+const defaultImageType = "oci"
+
+// ImageType checks the images of a type, like "docker", "wasm" or "helm".
+type ImageType struct {
+	// ParseReference parses the reference of an image, failing if it is
+	// malformed. It is required.
+	ParseReference func(image string) (fmt.Stringer, error)
+	// Validate checks the other properties of an image, once its reference
+	// is parsed. It is optional.
+	Validate func(image bundle.BaseImage) error
+}
+
+// UnknownImageTypeError is returned when checking an image whose type is not
+// registered.
+type UnknownImageTypeError struct {
+	ImageType string
+}
+
+func (e UnknownImageTypeError) Error() string {
+	return fmt.Sprintf("unknown image type %q", e.ImageType)
+}
+
+// IsUnknownImageType returns true if the error is an UnknownImageTypeError.
+func IsUnknownImageType(err error) bool {
+	_, ok := errors.Cause(err).(UnknownImageTypeError)
+	return ok
+}
+
+var (
+	imageTypesMu sync.RWMutex
+	imageTypes   = map[string]ImageType{}
+)
+
+func init() {
+	dockerish := ImageType{ParseReference: func(image string) (fmt.Stringer, error) {
+		return reference.ParseNormalizedNamed(image)
+	}}
+	RegisterImageType("docker", dockerish)
+	RegisterImageType("oci", dockerish)
+	RegisterImageType(ImageTypeWasm, ImageType{ParseReference: func(image string) (fmt.Stringer, error) {
+		if err := ValidateWasmReference(image); err != nil {
+			return nil, err
+		}
+		return url.Parse(image)
+	}})
+}
+
+// RegisterImageType registers an image type, so its images are checked by
+// Validate. It panics if the type is already registered or has no reference
+// parser.
+func RegisterImageType(name string, imageType ImageType) {
+	imageTypesMu.Lock()
+	defer imageTypesMu.Unlock()
+	if imageType.ParseReference == nil {
+		panic(fmt.Sprintf("image type %s registered without reference parser", name))
+	}
+	if _, ok := imageTypes[name]; ok {
+		panic(fmt.Sprintf("image type %s registered twice", name))
+	}
+	imageTypes[name] = imageType
+}
+
+func imageTypesCount() int {
+	imageTypesMu.RLock()
+	defer imageTypesMu.RUnlock()
+	return len(imageTypes)
+}
+
+// RegisteredImageTypes returns the sorted names of the registered image
+// types.
+func RegisteredImageTypes() []string {
+	imageTypesMu.RLock()
+	defer imageTypesMu.RUnlock()
+	names := make([]string, 0, len(imageTypes))
+	for name := range imageTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseImage parses the reference of an image with the parser of its type.
+// Images without type are OCI images.
+func ParseImage(image bundle.BaseImage) (fmt.Stringer, error) {
+	imageType, err := lookupImageType(image)
+	if err != nil {
+		return nil, err
+	}
+	return imageType.ParseReference(image.Image)
+}
+
+// ValidateImage checks an image with the parser and validator of its type.
+// It returns an UnknownImageTypeError if the type is not registered.
+func ValidateImage(image bundle.BaseImage) error {
+	imageType, err := lookupImageType(image)
+	if err != nil {
+		return err
+	}
+	if _, err := imageType.ParseReference(image.Image); err != nil {
+		return err
+	}
+	if imageType.Validate != nil {
+		return imageType.Validate(image)
+	}
+	return nil
+}
+
+// ValidateInvocationImage checks an invocation image as ValidateImage does.
+// Docker and OCI invocation images must also have a tag or a digest.
+func ValidateInvocationImage(image bundle.InvocationImage) error {
+	if err := ValidateImage(image.BaseImage); err != nil {
+		return err
+	}
+	if isDockerish(image.BaseImage) {
+		_, err := ParsedReference(image)
+		return err
+	}
+	return nil
+}
+
+func lookupImageType(image bundle.BaseImage) (ImageType, error) {
+	name := image.ImageType
+	if name == "" {
+		name = defaultImageType
+	}
+	imageTypesMu.RLock()
+	defer imageTypesMu.RUnlock()
+	imageType, ok := imageTypes[name]
+	if !ok {
+		return ImageType{}, UnknownImageTypeError{ImageType: name}
+	}
+	return imageType, nil
+}
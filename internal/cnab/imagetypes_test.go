@@ -0,0 +1,89 @@
+package cnab
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type zipReference string
+
+func (r zipReference) String() string { return string(r) }
+
+// registerZip registers a zip image type, requiring .zip references and a
+// digest, until the returned function is called.
+func registerZip() func() {
+	RegisterImageType("zip", ImageType{
+		ParseReference: func(image string) (fmt.Stringer, error) {
+			if !strings.HasSuffix(image, ".zip") {
+				return nil, errors.New("expected a .zip file")
+			}
+			return zipReference(image), nil
+		},
+		Validate: func(image bundle.BaseImage) error {
+			if image.Digest == "" {
+				return errors.New("digest is required")
+			}
+			return nil
+		},
+	})
+	return func() {
+		imageTypesMu.Lock()
+		defer imageTypesMu.Unlock()
+		delete(imageTypes, "zip")
+	}
+}
+
+func TestRegisterImageType(t *testing.T) {
+	defer registerZip()()
+	assert.Check(t, is.DeepEqual(RegisteredImageTypes(), []string{"docker", "oci", "wasm", "zip"}))
+	assert.Check(t, is.Panics(func() { registerZip() }))
+	assert.Check(t, is.Panics(func() { RegisterImageType("qcow2", ImageType{}) }))
+
+	ref, err := ParseImage(bundle.BaseImage{ImageType: "zip", Image: "installer.zip"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(ref.String(), "installer.zip"))
+	assert.Check(t, ValidateImage(bundle.BaseImage{ImageType: "zip", Image: "installer.zip", Digest: "sha256:abc"}))
+	assert.Check(t, is.ErrorContains(ValidateImage(bundle.BaseImage{ImageType: "zip", Image: "installer"}), "expected a .zip file"))
+	assert.Check(t, is.ErrorContains(ValidateImage(bundle.BaseImage{ImageType: "zip", Image: "installer.zip"}), "digest is required"))
+}
+
+func TestParseImage(t *testing.T) {
+	ref, err := ParseImage(bundle.BaseImage{Image: "nginx"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(ref.String(), "docker.io/library/nginx"))
+	ref, err = ParseImage(bundle.BaseImage{ImageType: "wasm", Image: "file:///installer.wasm"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(ref.String(), "file:///installer.wasm"))
+
+	_, err = ParseImage(bundle.BaseImage{ImageType: "helm", Image: "chart"})
+	assert.Check(t, is.Error(err, `unknown image type "helm"`))
+	assert.Check(t, IsUnknownImageType(errors.Wrap(err, "invalid image")))
+}
+
+func TestValidateUnknownImageType(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "helm", Image: "chart"}}},
+		Images:           map[string]bundle.Image{"disk": {BaseImage: bundle.BaseImage{ImageType: "zip", Image: "disk"}}},
+	}
+	errs := Validate(b)
+	assert.Check(t, is.Len(errs.Warnings(), 2))
+	assert.Check(t, is.Equal(errs[0].Code, CodeUnknownImageType))
+	assert.Check(t, is.Equal(errs[0].Path, "$.invocationImages[0].imageType"))
+	assert.NilError(t, errs.Err())
+
+	errs = Validate(b, WithStrictImageTypes())
+	assert.Check(t, is.Len(errs.Errors(), 2))
+
+	defer registerZip()()
+	errs = Validate(b)
+	assert.Check(t, is.Len(errs.Warnings(), 1))
+	assert.Check(t, is.Error(errs.Errors(), `$.images["disk"].image: image "disk": expected a .zip file`))
+}
@@ -0,0 +1,88 @@
+package cnab
+
+import (
+	"fmt"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// InventoryImage is an image of a bundle, as listed by ImageInventory.
+type InventoryImage struct {
+	// Path is the JSON path of the image in the bundle, like
+	// "$.invocationImages[0]" or `$.images["web"]`.
+	Path string `json:"path"`
+	// Name is the name of a component image, empty for invocation images.
+	Name      string `json:"name,omitempty"`
+	ImageType string `json:"imageType"`
+	// Reference is the normalized reference of the image, like
+	// "docker.io/library/nginx:1.17" for docker and OCI images.
+	Reference string `json:"reference"`
+	// Digest is the content digest of the image, either set by the bundle
+	// or part of its reference.
+	Digest string `json:"digest,omitempty"`
+	// Platform is the "os/arch" the image is built for, if set.
+	Platform  string `json:"platform,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// Invocation returns true for invocation images.
+func (i InventoryImage) Invocation() bool {
+	return i.Name == ""
+}
+
+// ImageInventory lists every image of the bundle, the invocation images
+// first then the component images sorted by name, with their references
+// normalized by the parser of their image type. The references of images of
+// unregistered types are kept as is.
+func ImageInventory(b *bundle.Bundle) ([]InventoryImage, error) {
+	inventory := make([]InventoryImage, 0, len(b.InvocationImages)+len(b.Images))
+	for i, img := range b.InvocationImages {
+		item, err := inventoryImage(fmt.Sprintf("$.invocationImages[%d]", i), "", img.BaseImage)
+		if err != nil {
+			return nil, err
+		}
+		inventory = append(inventory, item)
+	}
+	for _, name := range ImageKeys(b) {
+		item, err := inventoryImage(fmt.Sprintf("$.images[%q]", name), name, b.Images[name].BaseImage)
+		if err != nil {
+			return nil, err
+		}
+		inventory = append(inventory, item)
+	}
+	return inventory, nil
+}
+
+func inventoryImage(path, name string, img bundle.BaseImage) (InventoryImage, error) {
+	item := InventoryImage{
+		Path:      path,
+		Name:      name,
+		ImageType: img.ImageType,
+		Reference: img.Image,
+		Digest:    img.Digest,
+		MediaType: img.MediaType,
+	}
+	if item.ImageType == "" {
+		item.ImageType = defaultImageType
+	}
+	if p := img.Platform; p != nil && (p.OS != "" || p.Architecture != "") {
+		item.Platform = platformName(p)
+	}
+	ref, err := ParseImage(img)
+	switch {
+	case IsUnknownImageType(err):
+		return item, nil
+	case err != nil:
+		return InventoryImage{}, errors.Wrapf(err, "invalid image %q at %s", img.Image, path)
+	}
+	if named, ok := ref.(reference.Named); ok {
+		ref = reference.TagNameOnly(named)
+		if digested, ok := named.(reference.Digested); ok && item.Digest == "" {
+			item.Digest = digested.Digest().String()
+		}
+	}
+	item.Reference = ref.String()
+	return item, nil
+}
@@ -0,0 +1,37 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestImageInventory(t *testing.T) {
+	const digest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+	b := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app-invoc:1.0.0", Platform: &bundle.ImagePlatform{OS: "linux", Architecture: "amd64"}}},
+		},
+		Images: map[string]bundle.Image{
+			"web":    {BaseImage: bundle.BaseImage{Image: "example.com/web@" + digest, MediaType: "application/vnd.oci.image.manifest.v1+json"}},
+			"db":     {BaseImage: bundle.BaseImage{ImageType: "docker", Image: "postgres", Digest: digest}},
+			"plugin": {BaseImage: bundle.BaseImage{ImageType: "vm", Image: "https://example.com/plugin.qcow2"}},
+		},
+	}
+	inventory, err := ImageInventory(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(inventory, []InventoryImage{
+		{Path: "$.invocationImages[0]", ImageType: "docker", Reference: "docker.io/library/app-invoc:1.0.0", Platform: "linux/amd64"},
+		{Path: `$.images["db"]`, Name: "db", ImageType: "docker", Reference: "docker.io/library/postgres:latest", Digest: digest},
+		{Path: `$.images["plugin"]`, Name: "plugin", ImageType: "vm", Reference: "https://example.com/plugin.qcow2"},
+		{Path: `$.images["web"]`, Name: "web", ImageType: "oci", Reference: "example.com/web@" + digest, Digest: digest, MediaType: "application/vnd.oci.image.manifest.v1+json"},
+	}))
+	assert.Check(t, inventory[0].Invocation())
+	assert.Check(t, !inventory[1].Invocation())
+
+	b.Images["web"] = bundle.Image{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "Example.com/Web"}}
+	_, err = ImageInventory(b)
+	assert.Check(t, is.ErrorContains(err, `invalid image "Example.com/Web" at $.images["web"]`))
+}
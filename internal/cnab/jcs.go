@@ -0,0 +1,192 @@
+package cnab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// jcs implements the JSON Canonicalization Scheme of RFC 8785. The document
+// is first serialized by encoding/json, then written again with the object
+// members sorted by their UTF-16 code units, the numbers formatted as
+// ECMAScript does, and the minimal string escaping.
+type jcs struct{}
+
+func (jcs) Name() string {
+	return "jcs"
+}
+
+func (jcs) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := writeJCS(buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJCS(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return errors.Errorf("number %s cannot be canonicalized: it is out of the IEEE 754 range", v)
+		}
+		s, err := formatJCSNumber(f)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case string:
+		writeJCSString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJCS(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sortUTF16(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJCSString(buf, key)
+			buf.WriteByte(':')
+			if err := writeJCS(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return errors.Errorf("unexpected JSON value %T", v)
+	}
+	return nil
+}
+
+// formatJCSNumber formats a number as the ECMAScript Number.prototype.toString
+// does, as required by RFC 8785.
+func formatJCSNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", errors.Errorf("number %v cannot be canonicalized", f)
+	}
+	if f == 0 {
+		// including -0
+		return "0", nil
+	}
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+	// The shortest digits identifying the number, like "1.2345e+02"
+	mantissa, exp := splitExponent(strconv.FormatFloat(f, 'e', -1, 64))
+	digits := strings.Replace(mantissa, ".", "", 1)
+	// The number is 0.<digits> * 10^n
+	n := exp + 1
+	k := len(digits)
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k), nil
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:], nil
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits, nil
+	}
+	e := n - 1
+	expSign := "+"
+	if e < 0 {
+		expSign = "-"
+		e = -e
+	}
+	if k == 1 {
+		return fmt.Sprintf("%s%se%s%d", sign, digits, expSign, e), nil
+	}
+	return fmt.Sprintf("%s%s.%se%s%d", sign, digits[:1], digits[1:], expSign, e), nil
+}
+
+// splitExponent splits a number formatted by strconv.FormatFloat with the
+// 'e' format.
+func splitExponent(s string) (string, int) {
+	i := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[i+1:])
+	return s[:i], exp
+}
+
+func writeJCSString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// sortUTF16 sorts the strings by their UTF-16 code units, as required by
+// RFC 8785, which differs from the UTF-8 byte order for the characters
+// outside of the basic multilingual plane.
+func sortUTF16(keys []string) {
+	encoded := make(map[string][]uint16, len(keys))
+	for _, key := range keys {
+		encoded[key] = utf16.Encode([]rune(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := encoded[keys[i]], encoded[keys[j]]
+		for x := 0; x < len(a) && x < len(b); x++ {
+			if a[x] != b[x] {
+				return a[x] < b[x]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
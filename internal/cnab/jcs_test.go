@@ -0,0 +1,159 @@
+package cnab
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func jcsString(t *testing.T, document string) string {
+	t.Helper()
+	var v interface{}
+	assert.NilError(t, json.Unmarshal([]byte(document), &v))
+	data, err := JCS.Marshal(v)
+	assert.NilError(t, err)
+	return string(data)
+}
+
+// The examples of RFC 8785, section 3.2
+func TestJCSExamples(t *testing.T) {
+	actual := jcsString(t, `{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`)
+	assert.Check(t, is.Equal(actual, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`))
+
+	actual = jcsString(t, `{
+		"\u20ac": "Euro Sign",
+		"\r": "Carriage Return",
+		"\ufb33": "Hebrew Letter Dalet With Dagesh",
+		"1": "One",
+		"\ud83d\ude00": "Emoji: Grinning Face",
+		"\u0080": "Control",
+		"\u00f6": "Latin Small Letter O With Diaeresis"
+	}`)
+	assert.Check(t, is.Equal(actual, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"דּ\":\"Hebrew Letter Dalet With Dagesh\"}"))
+}
+
+// The number serialization samples of RFC 8785, appendix B
+func TestJCSNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		bits     uint64
+		expected string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		actual, err := formatJCSNumber(math.Float64frombits(tc.bits))
+		assert.Check(t, err, "%x", tc.bits)
+		assert.Check(t, is.Equal(actual, tc.expected), "%x", tc.bits)
+	}
+
+	_, err := formatJCSNumber(math.NaN())
+	assert.Check(t, is.Error(err, "number NaN cannot be canonicalized"))
+	_, err = formatJCSNumber(math.Inf(1))
+	assert.Check(t, is.Error(err, "number +Inf cannot be canonicalized"))
+	_, err = JCS.Marshal(json.RawMessage(`1e400`))
+	assert.Check(t, is.Error(err, "number 1e400 cannot be canonicalized: it is out of the IEEE 754 range"))
+}
+
+func TestJCSEscaping(t *testing.T) {
+	actual := jcsString(t, `["<a href=\"x\">&amp;</a>", "\b\f\t\u0001\u001f", "\u2028"]`)
+	assert.Check(t, is.Equal(actual, "[\"<a href=\\\"x\\\">&amp;</a>\",\"\\b\\f\\t\\u0001\\u001f\",\"\u2028\"]"))
+}
+
+// Bundles made of strings, integers and booleans, like most bundles, have
+// the same canonical form with both schemes, so their digests and
+// signatures interoperate.
+func TestJCSMatchesDockerCanonicalJSON(t *testing.T) {
+	b, err := Parse([]byte(testBundle))
+	assert.NilError(t, err)
+	b.Custom = map[string]interface{}{
+		"com.example.extension": map[string]interface{}{"b": []interface{}{1, true, nil, "<&>"}, "a": "é"},
+	}
+	expected, err := canonicaljson.MarshalCanonical(b)
+	assert.NilError(t, err)
+	actual, err := MarshalCanonical(b, WithCanonicalizer(JCS))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(actual), string(expected)))
+
+	d1, err := Digest(b)
+	assert.NilError(t, err)
+	d2, err := Digest(b, WithCanonicalizer(JCS))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(d1, d2))
+
+	// The JCS document is a valid bundle, whose canonical form is stable
+	decoded, err := Parse(actual)
+	assert.NilError(t, err)
+	again, err := JCS.Marshal(decoded)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(again), string(actual)))
+}
+
+func TestJCSDiffersFromDockerCanonicalJSON(t *testing.T) {
+	// The docker canonical JSON writes control characters unescaped and
+	// sorts the keys by their UTF-8 bytes
+	b := &bundle.Bundle{Name: "app", Version: "0.1.0", Custom: map[string]interface{}{
+		"control":    "a\u0001b",
+		"\ufb33":     "dalet",
+		"\U0001F600": "grinning face",
+	}}
+	docker, err := Digest(b)
+	assert.NilError(t, err)
+	jcs, err := Digest(b, WithCanonicalizer(JCS))
+	assert.NilError(t, err)
+	assert.Check(t, docker != jcs)
+	assert.Check(t, MatchesDigest(b, jcs, WithCanonicalizer(JCS)))
+	assert.Check(t, !MatchesDigest(b, jcs))
+	data, err := JCS.Marshal(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(data), "{\"control\":\"a\\u0001b\",\"\U0001F600\":\"grinning face\",\"\ufb33\":\"dalet\"}"))
+
+	// Only JCS supports fractional numbers
+	b.Custom = map[string]interface{}{"ratio": 0.0000001}
+	_, err = Digest(b)
+	assert.Check(t, is.ErrorContains(err, "unsupported value"))
+	data, err = JCS.Marshal(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(data), `"ratio":1e-7`))
+}
+
+func TestLookupCanonicalizer(t *testing.T) {
+	c, err := LookupCanonicalizer("")
+	assert.NilError(t, err)
+	assert.Check(t, c == DockerCanonicalJSON)
+	c, err = LookupCanonicalizer("jcs")
+	assert.NilError(t, err)
+	assert.Check(t, c == JCS)
+	_, err = LookupCanonicalizer("unknown")
+	assert.Check(t, is.Error(err, `unknown canonicalization "unknown", expected "docker" or "jcs"`))
+}
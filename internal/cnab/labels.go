@@ -0,0 +1,95 @@
+package cnab
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// LabelsExtensionKey is the custom extension holding the labels of a
+// bundle, which installations of the bundle are labeled with by default.
+const LabelsExtensionKey = internal.Namespace + "labels"
+
+// Labels are key values organizing bundles and installations, like
+// "team=payments" or "env=staging". Keys are a name, optionally prefixed by
+// a DNS subdomain and a slash, like "example.com/team". Names and values
+// are at most 63 characters, alphanumerics, '-', '_' or '.', starting and
+// ending with an alphanumeric. Values can also be empty.
+var (
+	labelName   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]{0,61}[A-Za-z0-9])?$`)
+	labelPrefix = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+)
+
+const maxLabelPrefixLength = 253
+
+// ValidateLabelKey checks the syntax of a label key.
+func ValidateLabelKey(key string) error {
+	name := key
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] != '/' {
+			continue
+		}
+		prefix := key[:i]
+		if len(prefix) > maxLabelPrefixLength || !labelPrefix.MatchString(prefix) {
+			return errors.Errorf("invalid label key %q, its prefix must be a lowercase DNS subdomain", key)
+		}
+		name = key[i+1:]
+		break
+	}
+	if !labelName.MatchString(name) {
+		return errors.Errorf("invalid label key %q, it must be at most 63 alphanumerics, '-', '_' or '.', starting and ending with an alphanumeric", key)
+	}
+	return nil
+}
+
+// ValidateLabels checks the syntax of the keys and values of labels,
+// reporting the first invalid one in key order.
+func ValidateLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ValidateLabelKey(key); err != nil {
+			return err
+		}
+		if value := labels[key]; value != "" && !labelName.MatchString(value) {
+			return errors.Errorf("invalid value %q of label %q, it must be at most 63 alphanumerics, '-', '_' or '.', starting and ending with an alphanumeric", value, key)
+		}
+	}
+	return nil
+}
+
+// ReadLabels returns the labels of the bundle, or nil if it has none.
+func ReadLabels(b *bundle.Bundle) (map[string]string, error) {
+	var labels map[string]string
+	found, err := GetCustomExtension(b, LabelsExtensionKey, &labels)
+	if err != nil || !found {
+		return nil, err
+	}
+	if err := ValidateLabels(labels); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s extension", LabelsExtensionKey)
+	}
+	return labels, nil
+}
+
+// WriteLabels sets the labels of the bundle, or removes them if labels is
+// empty.
+func WriteLabels(b *bundle.Bundle, labels map[string]string) error {
+	if len(labels) == 0 {
+		delete(b.Custom, LabelsExtensionKey)
+		return nil
+	}
+	if err := ValidateLabels(labels); err != nil {
+		return err
+	}
+	if b.Custom == nil {
+		b.Custom = map[string]interface{}{}
+	}
+	b.Custom[LabelsExtensionKey] = labels
+	return nil
+}
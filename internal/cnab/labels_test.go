@@ -0,0 +1,55 @@
+package cnab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestValidateLabelKey(t *testing.T) {
+	for _, key := range []string{"team", "env", "app.kubernetes.io/name", "example.com/tier", "a", "Team_1.x-y", strings.Repeat("a", 63)} {
+		assert.Check(t, ValidateLabelKey(key), key)
+	}
+	for _, key := range []string{"", "-team", "team-", "te am", "/team", "example.com/", "Example.com/team", "a/b/c", strings.Repeat("a", 64), "éq"} {
+		assert.Check(t, ValidateLabelKey(key) != nil, key)
+	}
+	assert.Check(t, is.Error(ValidateLabelKey("Example.com/team"), `invalid label key "Example.com/team", its prefix must be a lowercase DNS subdomain`))
+}
+
+func TestValidateLabels(t *testing.T) {
+	assert.NilError(t, ValidateLabels(nil))
+	assert.NilError(t, ValidateLabels(map[string]string{"team": "payments", "env": ""}))
+	err := ValidateLabels(map[string]string{"zone": "-eu", "team": "pay/ments"})
+	assert.Check(t, is.ErrorContains(err, `invalid value "pay/ments" of label "team"`))
+}
+
+func TestBundleLabels(t *testing.T) {
+	b := &bundle.Bundle{}
+	labels, err := ReadLabels(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(labels))
+
+	assert.NilError(t, WriteLabels(b, map[string]string{"team": "payments"}))
+	labels, err = ReadLabels(roundTrip(t, b))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(labels, map[string]string{"team": "payments"}))
+
+	assert.Check(t, is.ErrorContains(WriteLabels(b, map[string]string{"bad key": ""}), "invalid label key"))
+	assert.NilError(t, WriteLabels(b, nil))
+	_, ok := b.Custom[LabelsExtensionKey]
+	assert.Check(t, !ok)
+
+	b.Custom[LabelsExtensionKey] = map[string]interface{}{"bad key": "x"}
+	errs := Validate(b)
+	var found bool
+	for _, err := range errs {
+		if err.Code == CodeInvalidLabel {
+			found = true
+			assert.Check(t, is.Equal(err.Path, `$.custom["com.docker.app.labels"]`))
+		}
+	}
+	assert.Check(t, found)
+}
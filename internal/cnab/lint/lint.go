@@ -0,0 +1,330 @@
+// Package lint checks bundles and reports the findings in a machine readable
+// form, as JSON or SARIF, for CI systems and code review tools.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+)
+
+// Rule is a check run on bundles.
+type Rule struct {
+	ID          string        `json:"id"`
+	Description string        `json:"description"`
+	Severity    cnab.Severity `json:"severity"`
+	check       func(b *bundle.Bundle) []Finding
+}
+
+// Finding is a rule violation. Its code is the ID of the rule.
+type Finding = cnab.ValidationError
+
+// Rules are all the rules run by Lint.
+var Rules = []Rule{
+	{
+		ID:          "CNAB001",
+		Description: "At least one invocation image must be defined",
+		Severity:    cnab.SeverityError,
+		check: func(b *bundle.Bundle) []Finding {
+			if len(b.InvocationImages) == 0 {
+				return []Finding{{Path: "$.invocationImages", Message: "at least one invocation image must be defined in the bundle"}}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "CNAB002",
+		Description: "The bundle version must not be 'latest'",
+		Severity:    cnab.SeverityError,
+		check: func(b *bundle.Bundle) []Finding {
+			if b.Version == "latest" {
+				return []Finding{{Path: "$.version", Message: "'latest' is not a valid bundle version"}}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "CNAB003",
+		Description: "Invocation images must have a valid reference, with a tag or a digest",
+		Severity:    cnab.SeverityError,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			for i, img := range b.InvocationImages {
+				if err := cnab.ValidateInvocationImage(img); err != nil && !cnab.IsUnknownImageType(err) {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.invocationImages[%d].image", i),
+						Message: fmt.Sprintf("invocation image %q: %s", img.Image, err),
+					})
+				}
+			}
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB004",
+		Description: "Custom extensions must stay within limits and be valid UTF-8",
+		Severity:    cnab.SeverityError,
+		check: func(b *bundle.Bundle) []Finding {
+			if err := cnab.DefaultCustomLimits.Check(b); err != nil {
+				return []Finding{{Path: "$.custom", Message: err.Error()}}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "CNAB005",
+		Description: "Extensions known by docker app must be well formed",
+		Severity:    cnab.SeverityError,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			for _, ext := range []struct {
+				key  string
+				read func(*bundle.Bundle) error
+			}{
+				{cnab.DependenciesExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadDependencies(b); return err }},
+				{cnab.CompositeExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadComposite(b); return err }},
+				{cnab.UpgradeFromExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadUpgradeFrom(b); return err }},
+				{cnab.SchedulesExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadSchedules(b); return err }},
+				{cnab.RequirementsExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadRequirements(b); return err }},
+				{cnab.ChangelogExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadChangelog(b); return err }},
+				{cnab.ParameterSchemasExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadParameterSchemas(b); return err }},
+				{cnab.OutputsExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadOutputs(b); return err }},
+				{cnab.CredentialsExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadCredentials(b); return err }},
+				{cnab.SensitiveParametersExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.SensitiveParameters(b); return err }},
+				{cnab.RequiredExtensionsKey, func(b *bundle.Bundle) error { _, err := cnab.RequiredExtensions(b); return err }},
+				{cnab.MetadataExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadMetadata(b); return err }},
+				{cnab.SBOMExtensionKey, func(b *bundle.Bundle) error { _, err := cnab.ReadSBOM(b); return err }},
+			} {
+				if err := ext.read(b); err != nil {
+					findings = append(findings, Finding{Path: fmt.Sprintf("$.custom[%q]", ext.key), Message: err.Error()})
+				}
+			}
+			for _, key := range cnab.RegisteredExtensions() {
+				if _, err := cnab.ReadCustomExtension(b, key); err != nil {
+					findings = append(findings, Finding{Path: fmt.Sprintf("$.custom[%q]", key), Message: err.Error()})
+				}
+			}
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB006",
+		Description: "The bundle version should be a semantic version",
+		Severity:    cnab.SeverityWarning,
+		check: func(b *bundle.Bundle) []Finding {
+			if err := cnab.ValidateVersion(b); err != nil && b.Version != "latest" {
+				return []Finding{{Path: "$.version", Message: err.Error()}}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "CNAB007",
+		Description: "Parameters should have a destination",
+		Severity:    cnab.SeverityWarning,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			for _, name := range sortedKeys(b.Parameters) {
+				if b.Parameters[name].Destination == nil {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.parameters[%q].destination", name),
+						Message: fmt.Sprintf("parameter %q is only passed as CNAB_P_%s", name, strings.ToUpper(name)),
+					})
+				}
+			}
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB008",
+		Description: "Images should be pinned by digest",
+		Severity:    cnab.SeverityNote,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			for i, img := range b.InvocationImages {
+				if img.Digest == "" && !strings.Contains(img.Image, "@") {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.invocationImages[%d]", i),
+						Message: fmt.Sprintf("invocation image %q is not pinned by digest", img.Image),
+					})
+				}
+			}
+			for _, name := range cnab.ImageKeys(b) {
+				img := b.Images[name]
+				if img.Digest == "" && !strings.Contains(img.Image, "@") {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.images[%q]", name),
+						Message: fmt.Sprintf("image %q is not pinned by digest", img.Image),
+					})
+				}
+			}
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB009",
+		Description: "Parameter destinations must set an environment variable or a path",
+		Severity:    cnab.SeverityError,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			for _, name := range sortedKeys(b.Parameters) {
+				if err := cnab.ValidateDestination(b.Parameters[name].Destination); err != nil {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.parameters[%q].destination", name),
+						Message: fmt.Sprintf("parameter %q: %s", name, err),
+					})
+				}
+			}
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB010",
+		Description: "The bundle should have a description",
+		Severity:    cnab.SeverityWarning,
+		check: func(b *bundle.Bundle) []Finding {
+			if strings.TrimSpace(b.Description) == "" {
+				return []Finding{{Path: "$.description", Message: fmt.Sprintf("bundle %q has no description", b.Name)}}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "CNAB011",
+		Description: "Parameters should have a description, and a default value unless they are required",
+		Severity:    cnab.SeverityWarning,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			for _, name := range sortedKeys(b.Parameters) {
+				def := b.Parameters[name]
+				if def.Metadata == nil || strings.TrimSpace(def.Metadata.Description) == "" {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.parameters[%q].metadata.description", name),
+						Message: fmt.Sprintf("parameter %q has no description", name),
+					})
+				}
+				if def.Default == nil && !def.Required {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.parameters[%q].default", name),
+						Message: fmt.Sprintf("optional parameter %q has no default value", name),
+					})
+				}
+			}
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB012",
+		Description: "Credentials should have a description",
+		Severity:    cnab.SeverityWarning,
+		check: func(b *bundle.Bundle) []Finding {
+			credentials, err := cnab.ReadCredentials(b)
+			if err != nil {
+				// Reported by CNAB005
+				return nil
+			}
+			names := make([]string, 0, len(credentials))
+			for name := range credentials {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			var findings []Finding
+			for _, name := range names {
+				if strings.TrimSpace(credentials[name].Description) == "" {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.custom[%q][%q].description", cnab.CredentialsExtensionKey, name),
+						Message: fmt.Sprintf("credential %q has no description", name),
+					})
+				}
+			}
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB013",
+		Description: "Names should be lowercase",
+		Severity:    cnab.SeverityWarning,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			check := func(kind, path, name string) {
+				if name != strings.ToLower(name) {
+					findings = append(findings, Finding{Path: path, Message: fmt.Sprintf("%s name %q is not lowercase", kind, name)})
+				}
+			}
+			check("bundle", "$.name", b.Name)
+			for _, name := range cnab.ParameterNames(b) {
+				check("parameter", fmt.Sprintf("$.parameters[%q]", name), name)
+			}
+			for _, name := range cnab.CredentialNames(b) {
+				check("credential", fmt.Sprintf("$.credentials[%q]", name), name)
+			}
+			for _, name := range cnab.ImageKeys(b) {
+				check("image", fmt.Sprintf("$.images[%q]", name), name)
+			}
+			for _, name := range cnab.ActionNames(b) {
+				check("action", fmt.Sprintf("$.actions[%q]", name), name)
+			}
+			sort.Slice(findings, func(i, j int) bool { return findings[i].Path < findings[j].Path })
+			return findings
+		},
+	},
+	{
+		ID:          "CNAB014",
+		Description: "Custom extensions should be small",
+		Severity:    cnab.SeverityWarning,
+		check: func(b *bundle.Bundle) []Finding {
+			var findings []Finding
+			names := make([]string, 0, len(b.Custom))
+			for name := range b.Custom {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, key := range names {
+				data, err := json.Marshal(b.Custom[key])
+				if err != nil {
+					// Reported by CNAB004
+					continue
+				}
+				if len(data) > maxCustomExtensionSize {
+					findings = append(findings, Finding{
+						Path:    fmt.Sprintf("$.custom[%q]", key),
+						Message: fmt.Sprintf("custom extension %q is %d bytes, it should not exceed %d bytes", key, len(data), maxCustomExtensionSize),
+					})
+				}
+			}
+			return findings
+		},
+	},
+}
+
+// maxCustomExtensionSize is the size in bytes above which a custom extension
+// is reported as oversized, well below the hard limit of
+// cnab.DefaultCustomLimits.
+const maxCustomExtensionSize = 64 << 10
+
+func sortedKeys(parameters map[string]bundle.ParameterDefinition) []string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lint runs all the rules on the bundle. Source names the linted document in
+// the report, it may be empty.
+func Lint(b *bundle.Bundle, source string) *Report {
+	report := &Report{Source: source, Findings: []Finding{}}
+	for _, rule := range Rules {
+		for _, finding := range rule.check(b) {
+			finding.Code = rule.ID
+			finding.Severity = rule.Severity
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+	return report
+}
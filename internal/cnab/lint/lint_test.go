@@ -0,0 +1,107 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestLintValidBundle(t *testing.T) {
+	b := bundletest.NewTestBundle(bundletest.WithParameters(1))
+	b.InvocationImages[0].Digest = "sha256:beef"
+	b.Parameters["param-0"] = described(b.Parameters["param-0"])
+	report := Lint(b, "bundle.json")
+	assert.Check(t, !report.HasErrors())
+	assert.Check(t, is.Len(report.Findings, 0))
+}
+
+func TestLintFindings(t *testing.T) {
+	b := bundletest.NewTestBundle(
+		bundletest.WithUntaggedInvocationImage(),
+		bundletest.WithVersion("dev"),
+		bundletest.WithParameter("no-destination", described(bundle.ParameterDefinition{DataType: "string", Required: true})),
+		bundletest.WithParameter("empty-destination", described(bundle.ParameterDefinition{DataType: "string", Required: true, Destination: &bundle.Location{}})),
+		bundletest.WithCustom(cnab.SchedulesExtensionKey, map[string]cnab.Schedule{"backup": {Frequency: "daily"}}),
+	)
+	report := Lint(b, "bundle.json")
+	assert.Check(t, report.HasErrors())
+	var ids []string
+	for _, f := range report.Findings {
+		ids = append(ids, f.Code)
+	}
+	assert.DeepEqual(t, ids, []string{"CNAB003", "CNAB005", "CNAB006", "CNAB007", "CNAB008", "CNAB009"})
+	assert.DeepEqual(t, report.Findings[0], Finding{
+		Code:     "CNAB003",
+		Severity: cnab.SeverityError,
+		Path:     "$.invocationImages[0].image",
+		Message:  `invocation image "test/test-bundle-invoc": tag or digest is required`,
+	})
+	assert.Equal(t, report.Findings[1].Path, `$.custom["com.docker.app.schedules"]`)
+	assert.Equal(t, report.Findings[3].Path, `$.parameters["no-destination"].destination`)
+	assert.Equal(t, report.Findings[3].Message, `parameter "no-destination" is only passed as CNAB_P_NO-DESTINATION`)
+	assert.Equal(t, report.Findings[5].Path, `$.parameters["empty-destination"].destination`)
+
+	text := bytes.NewBuffer(nil)
+	assert.NilError(t, report.WriteText(text))
+	assert.Check(t, is.Contains(text.String(), "warning\tCNAB006\t$.version\t"))
+
+	var decoded Report
+	buf := bytes.NewBuffer(nil)
+	assert.NilError(t, report.WriteJSON(buf))
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.DeepEqual(t, &decoded, report)
+}
+
+func TestLintBestPractices(t *testing.T) {
+	b := bundletest.NewTestBundle(
+		bundletest.WithName("Test-Bundle"),
+		bundletest.WithParameter("port", bundle.ParameterDefinition{DataType: "int", Destination: &bundle.Location{EnvironmentVariable: "PORT"}}),
+		bundletest.WithCredentials(1),
+		bundletest.WithCustom("com.example.blob", strings.Repeat("x", maxCustomExtensionSize)),
+	)
+	b.Description = ""
+	b.InvocationImages[0].Digest = "sha256:beef"
+	report := Lint(b, "bundle.json")
+	assert.Check(t, !report.HasErrors())
+	var findings []string
+	for _, f := range report.Findings {
+		findings = append(findings, f.Code+" "+f.Path)
+	}
+	assert.DeepEqual(t, findings, []string{
+		"CNAB010 $.description",
+		`CNAB011 $.parameters["port"].metadata.description`,
+		`CNAB011 $.parameters["port"].default`,
+		`CNAB012 $.custom["com.docker.app.credentials"]["cred-0"].description`,
+		"CNAB013 $.name",
+		`CNAB014 $.custom["com.example.blob"]`,
+	})
+}
+
+func described(def bundle.ParameterDefinition) bundle.ParameterDefinition {
+	def.Metadata = &bundle.ParameterMetadata{Description: "a parameter"}
+	return def
+}
+
+func TestWriteSARIF(t *testing.T) {
+	report := Lint(bundletest.NewTestBundle(bundletest.WithLatestVersion()), "bundle.json")
+	buf := bytes.NewBuffer(nil)
+	assert.NilError(t, report.WriteSARIF(buf))
+
+	var log sarifLog
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, log.Version, "2.1.0")
+	assert.Assert(t, is.Len(log.Runs, 1))
+	assert.Check(t, is.Len(log.Runs[0].Tool.Driver.Rules, len(Rules)))
+	result := log.Runs[0].Results[0]
+	assert.Equal(t, result.RuleID, "CNAB002")
+	assert.Equal(t, result.Level, cnab.SeverityError)
+	assert.Equal(t, result.Locations[0].PhysicalLocation.ArtifactLocation.URI, "bundle.json")
+	assert.Equal(t, result.Locations[0].LogicalLocations[0].FullyQualifiedName, "$.version")
+}
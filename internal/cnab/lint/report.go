@@ -0,0 +1,143 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/app/internal/cnab"
+)
+
+// Report aggregates the findings on a bundle.
+type Report struct {
+	// Source is the file the bundle was read from.
+	Source   string    `json:"source,omitempty"`
+	Findings []Finding `json:"findings"`
+}
+
+// HasErrors returns true if any finding is an error.
+func (r *Report) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == cnab.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes one line per finding.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, f := range r.Findings {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Severity, f.Code, f.Path, f.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// SARIF 2.1.0 subset, see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    sarifConfig  `json:"defaultConfiguration"`
+}
+
+type sarifConfig struct {
+	Level cnab.Severity `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     cnab.Severity   `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF writes the report in the SARIF 2.1.0 format. The JSON path of
+// each finding is reported as a logical location.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	driver := sarifDriver{
+		Name:           "docker-app",
+		InformationURI: "https://github.com/docker/app",
+		Rules:          []sarifRule{},
+	}
+	for _, rule := range Rules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               rule.ID,
+			ShortDescription: sarifMessage{Text: rule.Description},
+			DefaultConfig:    sarifConfig{Level: rule.Severity},
+		})
+	}
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: []sarifResult{}}
+	for _, f := range r.Findings {
+		location := sarifLocation{
+			LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: f.Path, Kind: "member"}},
+		}
+		if r.Source != "" {
+			location.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: r.Source}}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.Code,
+			Level:     f.Severity,
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
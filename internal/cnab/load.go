@@ -0,0 +1,117 @@
+package cnab
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// LoadOptions contains options for loading bundles
+type LoadOptions struct {
+	checksum       digest.Digest
+	checksumConfig *checksum.Config
+	strict         bool
+}
+
+// WithChecksum verifies the raw content of the loaded bundle matches the
+// given digest before parsing it
+func WithChecksum(expected digest.Digest) func(*LoadOptions) {
+	return func(o *LoadOptions) {
+		o.checksum = expected
+	}
+}
+
+// WithChecksumConfig selects the accepted digest algorithms (by default the
+// configuration is read from the environment)
+func WithChecksumConfig(config checksum.Config) func(*LoadOptions) {
+	return func(o *LoadOptions) {
+		o.checksumConfig = &config
+	}
+}
+
+// WithStrict makes Load and LoadData reject unknown fields, see
+// UnmarshalStrict.
+func WithStrict() func(*LoadOptions) {
+	return func(o *LoadOptions) {
+		o.strict = true
+	}
+}
+
+// Load reads a bundle from a local file or an http(s) URL.
+func Load(source string, opts ...func(*LoadOptions)) (*bundle.Bundle, error) {
+	data, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	b, err := LoadData(data, opts...)
+	return b, errors.Wrapf(err, "failed to load bundle %q", source)
+}
+
+// LoadData parses a bundle from its raw content. As Parse, it never panics on
+// malformed input.
+func LoadData(data []byte, opts ...func(*LoadOptions)) (*bundle.Bundle, error) {
+	var o LoadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.checksum != "" {
+		config := o.checksumConfig
+		if config == nil {
+			c, err := checksum.FromEnv()
+			if err != nil {
+				return nil, err
+			}
+			config = &c
+		}
+		if err := config.Verify(o.checksum, data); err != nil {
+			return nil, err
+		}
+	}
+	if !o.strict {
+		return ParseReaderLimited(bytes.NewReader(data), MaxBundleSize)
+	}
+	// The strict documents are bounded as the lenient ones
+	if len(data) > MaxBundleSize {
+		return nil, errors.Errorf("invalid bundle: larger than %d bytes", MaxBundleSize)
+	}
+	b, err := UnmarshalStrict(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := DefaultCustomLimits.Check(b); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	return b, nil
+}
+
+// readSource reads the document of a bundle, failing if it is larger than
+// MaxBundleSize.
+func readSource(source string) ([]byte, error) {
+	if _, err := os.Stat(source); err == nil {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ReadLimited(f, MaxBundleSize)
+	}
+	u, err := url.ParseRequestURI(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.Errorf("bundle %q not found", source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot download bundle %q", source)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("cannot download bundle %q: %s", source, resp.Status)
+	}
+	return ReadLimited(resp.Body, MaxBundleSize)
+}
@@ -0,0 +1,52 @@
+package cnab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/app/internal/checksum"
+	digest "github.com/opencontainers/go-digest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+const testBundle = `{"name":"myapp","version":"1.0.0"}`
+
+func TestLoadWithChecksum(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("bundle.json", testBundle))
+	defer dir.Remove()
+
+	b, err := Load(dir.Join("bundle.json"), WithChecksum(digest.FromString(testBundle)))
+	assert.NilError(t, err)
+	assert.Equal(t, b.Name, "myapp")
+
+	_, err = Load(dir.Join("bundle.json"), WithChecksum(digest.FromString("other")))
+	assert.Check(t, is.ErrorContains(err, "digest mismatch"))
+
+	sha512 := digest.SHA512.FromString(testBundle)
+	_, err = Load(dir.Join("bundle.json"), WithChecksum(sha512), WithChecksumConfig(checksum.Config{FIPS: true}))
+	assert.NilError(t, err)
+}
+
+func TestLoadFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bundle.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testBundle)) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	b, err := Load(server.URL+"/bundle.json", WithChecksum(digest.FromString(testBundle)))
+	assert.NilError(t, err)
+	assert.Equal(t, b.Version, "1.0.0")
+
+	_, err = Load(server.URL + "/missing.json")
+	assert.Check(t, is.ErrorContains(err, "404 Not Found"))
+
+	_, err = Load("missing.json")
+	assert.Error(t, err, `bundle "missing.json" not found`)
+}
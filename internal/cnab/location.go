@@ -0,0 +1,89 @@
+package cnab
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LocationVariables are the variables the paths of parameter and credential
+// locations can refer to, like "/cnab/app/{{ .CNAB_INSTALLATION_NAME }}/config.yaml",
+// so that an invocation image can serve many installations. They are
+// expanded when the operation is built.
+var LocationVariables = []string{
+	"CNAB_ACTION",
+	"CNAB_BUNDLE_NAME",
+	"CNAB_BUNDLE_VERSION",
+	"CNAB_INSTALLATION_NAME",
+}
+
+var templateVariable = regexp.MustCompile(`^\.([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// ValidateLocationTemplate checks the templates of a location path only
+// refer to LocationVariables, as "{{ .NAME }}".
+func ValidateLocationTemplate(path string) error {
+	_, err := expandLocationPath(path, func(name string) (string, error) {
+		return "", nil
+	})
+	return err
+}
+
+// ExpandLocationPath replaces the templates of a location path with the
+// values of the variables. The values cannot contain path separators nor be
+// "." or "..", so the expanded path cannot escape the directory the template
+// was written for.
+func ExpandLocationPath(path string, vars map[string]string) (string, error) {
+	return expandLocationPath(path, func(name string) (string, error) {
+		value := vars[name]
+		if value == "" || value == "." || value == ".." || strings.ContainsAny(value, `/\`) {
+			return "", errors.Errorf("invalid value %q of %s in path %q", value, name, path)
+		}
+		return value, nil
+	})
+}
+
+// expandLocationPath parses the templates of the path, without evaluating
+// anything but the allowed variables.
+func expandLocationPath(path string, value func(name string) (string, error)) (string, error) {
+	var expanded strings.Builder
+	rest := path
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			if strings.Contains(rest, "}}") {
+				return "", errors.Errorf("invalid template in path %q: unexpected }}", path)
+			}
+			expanded.WriteString(rest)
+			return expanded.String(), nil
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return "", errors.Errorf("invalid template in path %q: unclosed {{", path)
+		}
+		expanded.WriteString(rest[:start])
+		expr := strings.TrimSpace(rest[start+2 : start+end])
+		match := templateVariable.FindStringSubmatch(expr)
+		if match == nil {
+			return "", errors.Errorf("invalid template in path %q: %q is not a variable, like {{ .CNAB_INSTALLATION_NAME }}", path, expr)
+		}
+		if !isLocationVariable(match[1]) {
+			return "", errors.Errorf("invalid template in path %q: unknown variable %s, expected one of %s", path, match[1], strings.Join(LocationVariables, ", "))
+		}
+		v, err := value(match[1])
+		if err != nil {
+			return "", err
+		}
+		expanded.WriteString(v)
+		rest = rest[start+end+2:]
+	}
+}
+
+func isLocationVariable(name string) bool {
+	for _, v := range LocationVariables {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestExpandLocationPath(t *testing.T) {
+	vars := map[string]string{
+		"CNAB_INSTALLATION_NAME": "myapp",
+		"CNAB_ACTION":            "install",
+		"CNAB_BUNDLE_VERSION":    "..",
+	}
+	for _, tc := range []struct {
+		path     string
+		expected string
+		err      string
+	}{
+		{path: "/etc/app/config.yaml", expected: "/etc/app/config.yaml"},
+		{path: "/etc/{{ .CNAB_INSTALLATION_NAME }}/config.yaml", expected: "/etc/myapp/config.yaml"},
+		{path: "/etc/{{.CNAB_INSTALLATION_NAME}}-{{ .CNAB_ACTION }}.yaml", expected: "/etc/myapp-install.yaml"},
+		{path: "/etc/{{ .CNAB_INSTALLATION_NAME }", err: "unclosed {{"},
+		{path: "/etc/app}}", err: "unexpected }}"},
+		{path: "/etc/{{ .PATH }}", err: "unknown variable PATH"},
+		{path: `/etc/{{ printf "%s" .CNAB_ACTION }}`, err: "is not a variable"},
+		{path: "/etc/{{ .CNAB_BUNDLE_VERSION }}/passwd", err: `invalid value ".." of CNAB_BUNDLE_VERSION`},
+		{path: "/etc/{{ .CNAB_BUNDLE_NAME }}/config", err: `invalid value "" of CNAB_BUNDLE_NAME`},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			path, err := ExpandLocationPath(tc.path, vars)
+			if tc.err != "" {
+				assert.Check(t, is.ErrorContains(err, tc.err))
+				return
+			}
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(path, tc.expected))
+		})
+	}
+
+	// Values cannot add path elements
+	_, err := ExpandLocationPath("/etc/{{ .CNAB_ACTION }}", map[string]string{"CNAB_ACTION": "a/b"})
+	assert.Check(t, is.ErrorContains(err, `invalid value "a/b"`))
+}
+
+func TestValidateLocationTemplates(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0"}}},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"config": {DataType: "string", Destination: &bundle.Location{Path: "/etc/{{ .CNAB_INSTALLATION_NAME }}/config"}},
+			"other":  {DataType: "string", Destination: &bundle.Location{Path: "/etc/{{ .USER }}/config"}},
+		},
+		Credentials: map[string]bundle.Location{
+			"key": {Path: "/cnab/{{ .CNAB_INSTALLATION_NAME"},
+		},
+	}
+	errs := Validate(b)
+	assert.Assert(t, is.Len(errs, 2))
+	assert.Check(t, is.Equal(errs[0].Code, CodeInvalidTemplate))
+	assert.Check(t, is.Equal(errs[0].Path, `$.parameters["other"].destination.path`))
+	assert.Check(t, is.Equal(errs[1].Path, `$.credentials["key"].path`))
+	assert.Check(t, is.ErrorContains(errs[1], `credential "key": invalid template in path "/cnab/{{ .CNAB_INSTALLATION_NAME": unclosed {{`))
+}
@@ -0,0 +1,222 @@
+package cnab
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/deislabs/cnab-go/bundle"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
+)
+
+// LosslessBundle is a bundle decoded along with the fields of its document
+// which are not part of the bundle format, for instance written by a newer
+// tool. The unknown fields are written back by MarshalCanonical, so reading,
+// modifying and writing a bundle does not destroy the data it does not
+// understand.
+type LosslessBundle struct {
+	*bundle.Bundle
+	unknown *unknownFields
+}
+
+// unknownFields are the unknown fields of an object, and of the objects
+// nested in it, keyed by object key. The elements of arrays are matched by
+// identity, see elementIdentity, so removing an element does not move the
+// unknown fields of the next ones onto it.
+type unknownFields struct {
+	fields   map[string]interface{}
+	children map[string]*unknownFields
+	elements []unknownElement
+}
+
+// unknownElement are the unknown fields of an array element.
+type unknownElement struct {
+	index    int
+	identity string
+	*unknownFields
+}
+
+// ParseLossless decodes a bundle document as Parse does, retaining its
+// unknown fields. Unknown numbers are kept as written, without going through
+// float64.
+func ParseLossless(data []byte) (*LosslessBundle, error) {
+	b, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	document, err := decodeLossless(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	return &LosslessBundle{Bundle: b, unknown: collectUnknownValues(document, reflect.TypeOf(bundle.Bundle{}))}, nil
+}
+
+// decodeLossless decodes a JSON document, with its numbers decoded as
+// canonicaljson.Number, which are marshaled back unchanged.
+func decodeLossless(data []byte) (interface{}, error) {
+	dec := canonicaljson.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var document interface{}
+	if err := dec.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// UnknownFields returns the sorted JSON paths of the retained unknown fields.
+func (l *LosslessBundle) UnknownFields() []string {
+	var paths []string
+	l.unknown.paths("$", &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+// MarshalCanonical returns the canonical JSON document of the bundle, with
+// the unknown fields of the elements which still exist. Without unknown
+// fields, it is the same as the canonical JSON of the bundle.
+func (l *LosslessBundle) MarshalCanonical() ([]byte, error) {
+	data, err := canonicaljson.MarshalCanonical(l.Bundle)
+	if err != nil || l.unknown == nil {
+		return data, err
+	}
+	document, err := decodeLossless(data)
+	if err != nil {
+		return nil, err
+	}
+	l.unknown.restore(document)
+	return canonicaljson.MarshalCanonical(document)
+}
+
+// collectUnknownValues walks a decoded JSON value along the Go type it is
+// decoded into, as collectUnknownFields does, and returns the unknown fields
+// or nil if there is none.
+func collectUnknownValues(value interface{}, t reflect.Type) *unknownFields {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	u := &unknownFields{}
+	addChild := func(key string, child *unknownFields) {
+		if child == nil {
+			return
+		}
+		if u.children == nil {
+			u.children = map[string]*unknownFields{}
+		}
+		u.children[key] = child
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for key, v := range object {
+			field, ok := fields[key]
+			if !ok {
+				if u.fields == nil {
+					u.fields = map[string]interface{}{}
+				}
+				u.fields[key] = v
+				continue
+			}
+			addChild(key, collectUnknownValues(v, field))
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for key, v := range object {
+			addChild(key, collectUnknownValues(v, t.Elem()))
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, v := range array {
+			if child := collectUnknownValues(v, t.Elem()); child != nil {
+				u.elements = append(u.elements, unknownElement{index: i, identity: elementIdentity(v), unknownFields: child})
+			}
+		}
+	}
+	if u.fields == nil && u.children == nil && u.elements == nil {
+		return nil
+	}
+	return u
+}
+
+// restore adds the unknown fields back into a decoded JSON value. Known
+// fields are never overwritten.
+func (u *unknownFields) restore(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range u.fields {
+			if _, ok := v[key]; !ok {
+				v[key] = field
+			}
+		}
+		for key, child := range u.children {
+			if item, ok := v[key]; ok {
+				child.restore(item)
+			}
+		}
+	case []interface{}:
+		for _, e := range u.elements {
+			if i := e.find(v); i >= 0 {
+				e.restore(v[i])
+			}
+		}
+	}
+}
+
+// find returns the index of the element in the array, or -1 if it was
+// removed. Elements without identity are found by index.
+func (e unknownElement) find(array []interface{}) int {
+	if e.identity == "" {
+		if e.index < len(array) && elementIdentity(array[e.index]) == "" {
+			return e.index
+		}
+		return -1
+	}
+	for i, v := range array {
+		if elementIdentity(v) == e.identity {
+			return i
+		}
+	}
+	return -1
+}
+
+// elementIdentity identifies an array element by its name, like the
+// maintainers, or its image, like the invocation images. It is empty for the
+// other elements.
+func elementIdentity(value interface{}) string {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"name", "image"} {
+		if s, ok := object[key].(string); ok && s != "" {
+			return key + "=" + s
+		}
+	}
+	return ""
+}
+
+func (u *unknownFields) paths(path string, paths *[]string) {
+	if u == nil {
+		return
+	}
+	for key := range u.fields {
+		*paths = append(*paths, path+"."+key)
+	}
+	for key, child := range u.children {
+		child.paths(path+"."+key, paths)
+	}
+	for _, e := range u.elements {
+		e.paths(path+"["+strconv.Itoa(e.index)+"]", paths)
+	}
+}
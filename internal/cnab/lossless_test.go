@@ -0,0 +1,81 @@
+package cnab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const newerBundle = `{
+	"name": "myapp",
+	"version": "1.0.0",
+	"license": "Apache-2.0",
+	"invocationImages": [{"imageType": "docker", "image": "myapp-invoc:1.0.0", "labels": {"a": "b"}}],
+	"parameters": {"port": {"type": "int", "default": 80, "sensitive": false}},
+	"credentials": {"token": {"env": "TOKEN", "description": "API token"}}
+}`
+
+func TestParseLossless(t *testing.T) {
+	l, err := ParseLossless([]byte(newerBundle))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(l.UnknownFields(), []string{
+		"$.credentials.token.description",
+		"$.invocationImages[0].labels",
+		"$.license",
+		"$.parameters.port.sensitive",
+	}))
+
+	// Modify the bundle, the unknown fields of the remaining elements stay
+	l.Version = "1.1.0"
+	l.Parameters["replicas"] = bundle.ParameterDefinition{DataType: "int"}
+	delete(l.Credentials, "token")
+	data, err := l.MarshalCanonical()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), `{"credentials":{},"description":"","images":null,`+
+		`"invocationImages":[{"image":"myapp-invoc:1.0.0","imageType":"docker","labels":{"a":"b"}}],"license":"Apache-2.0","name":"myapp",`+
+		`"parameters":{"port":{"default":80,"destination":null,"sensitive":false,"type":"int"},"replicas":{"destination":null,"type":"int"}},"version":"1.1.0"}`))
+}
+
+func TestParseLosslessWithoutUnknownFields(t *testing.T) {
+	l, err := ParseLossless([]byte(testBundle))
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(l.UnknownFields(), 0))
+	data, err := l.MarshalCanonical()
+	assert.NilError(t, err)
+	expected, err := canonicaljson.MarshalCanonical(l.Bundle)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), string(expected)))
+}
+
+func TestParseLosslessRemovedElement(t *testing.T) {
+	l, err := ParseLossless([]byte(`{
+		"name": "myapp",
+		"version": "1.0.0",
+		"invocationImages": [
+			{"imageType": "docker", "image": "myapp-invoc:1.0.0"},
+			{"imageType": "docker", "image": "myapp-invoc-arm:1.0.0", "labels": {"arch": "arm64"}}
+		],
+		"size": 12345678901234567890,
+		"ratio": 1.50
+	}`))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(l.UnknownFields(), []string{"$.invocationImages[1].labels", "$.ratio", "$.size"}))
+
+	// The unknown fields follow their element, and the numbers are kept as written
+	l.InvocationImages = l.InvocationImages[1:]
+	data, err := l.MarshalCanonical()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), `{"credentials":null,"description":"","images":null,`+
+		`"invocationImages":[{"image":"myapp-invoc-arm:1.0.0","imageType":"docker","labels":{"arch":"arm64"}}],"name":"myapp",`+
+		`"parameters":null,"ratio":1.50,"size":12345678901234567890,"version":"1.0.0"}`))
+
+	// Removed elements do not give their unknown fields to the next ones
+	l.InvocationImages = []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "other:1.0.0"}}}
+	data, err = l.MarshalCanonical()
+	assert.NilError(t, err)
+	assert.Check(t, !strings.Contains(string(data), "arm64"), string(data))
+}
@@ -0,0 +1,124 @@
+package cnab
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// ArtifactKind is the kind of content referenced by an image of a bundle,
+// told by its media type.
+type ArtifactKind string
+
+const (
+	// ArtifactContainerImage is a container image, or an index of container
+	// images. Images without media type are container images.
+	ArtifactContainerImage ArtifactKind = "container-image"
+	// ArtifactHelmChart is a Helm chart stored as an OCI artifact.
+	ArtifactHelmChart ArtifactKind = "helm-chart"
+	// ArtifactWasmModule is a WebAssembly module stored as an OCI artifact.
+	ArtifactWasmModule ArtifactKind = "wasm-module"
+	// ArtifactBlob is a plain blob, referenced by digest. It is the kind of
+	// all the media types not listed here.
+	ArtifactBlob ArtifactKind = "blob"
+)
+
+// Media types of the artifacts which are not container images.
+const (
+	MediaTypeHelmChart      = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	MediaTypeHelmConfig     = "application/vnd.cncf.helm.config.v1+json"
+	MediaTypeWasmLayer      = "application/vnd.wasm.content.layer.v1+wasm"
+	MediaTypeWasmModule     = "application/vnd.module.wasm.content.layer.v1+wasm"
+	MediaTypeWasm           = "application/wasm"
+	MediaTypeOctetStream    = "application/octet-stream"
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerSchema1  = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+)
+
+// ArtifactKindOf returns the kind of artifact of a media type.
+func ArtifactKindOf(mediaType string) ArtifactKind {
+	switch mediaType {
+	case "", mediaTypeOCIManifest, mediaTypeOCIIndex, mediaTypeDockerManifest, mediaTypeDockerList, mediaTypeDockerSchema1:
+		return ArtifactContainerImage
+	case MediaTypeHelmChart, MediaTypeHelmConfig:
+		return ArtifactHelmChart
+	case MediaTypeWasmLayer, MediaTypeWasmModule, MediaTypeWasm:
+		return ArtifactWasmModule
+	default:
+		return ArtifactBlob
+	}
+}
+
+// IsContainerImage returns true if the image is a container image, which can
+// be pulled, saved and run by a container engine.
+func IsContainerImage(image bundle.BaseImage) bool {
+	return ArtifactKindOf(image.MediaType) == ArtifactContainerImage
+}
+
+// ValidateMediaType checks the media type of an image is well formed and
+// consistent with the image. Only container images can be invocation
+// images. The other artifacts must have the "oci" image type, and plain
+// blobs, which cannot be tagged, must be referenced by digest.
+func ValidateMediaType(image bundle.BaseImage, invocation bool) error {
+	if image.MediaType == "" {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(image.MediaType)
+	if err != nil || len(params) > 0 || mediaType != image.MediaType || !strings.Contains(mediaType, "/") {
+		return fmt.Errorf("invalid media type %q", image.MediaType)
+	}
+	kind := ArtifactKindOf(image.MediaType)
+	if kind == ArtifactContainerImage {
+		return nil
+	}
+	if invocation {
+		return fmt.Errorf("media type %q is a %s, only container images can be invocation images", image.MediaType, kind)
+	}
+	if image.ImageType != "" && image.ImageType != "oci" {
+		return fmt.Errorf("media type %q is a %s, which must have the oci image type", image.MediaType, kind)
+	}
+	if kind == ArtifactBlob && artifactDigest(image) == "" {
+		return fmt.Errorf("media type %q is a blob, which must be referenced by digest", image.MediaType)
+	}
+	return nil
+}
+
+// artifactDigest returns the digest of an image, from its reference or its
+// digest field.
+func artifactDigest(image bundle.BaseImage) string {
+	if named, err := reference.ParseNormalizedNamed(image.Image); err == nil {
+		if digested, ok := named.(reference.Digested); ok {
+			return digested.Digest().String()
+		}
+	}
+	return image.Digest
+}
+
+// ArtifactReference returns the reference to pull a non-container artifact
+// from, pinned to its digest if it has one.
+func ArtifactReference(image bundle.BaseImage) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image.Image)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid artifact reference %q", image.Image)
+	}
+	if _, ok := named.(reference.Digested); ok || image.Digest == "" {
+		return reference.TagNameOnly(named).String(), nil
+	}
+	d, err := digest.Parse(image.Digest)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid digest of artifact %q", image.Image)
+	}
+	pinned, err := reference.WithDigest(named, d)
+	if err != nil {
+		return "", err
+	}
+	return pinned.String(), nil
+}
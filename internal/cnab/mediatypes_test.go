@@ -0,0 +1,106 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const testBlobDigest = "sha256:adf661469d0119cf55870710b038e1a440b53ab543a711bad72a14dd611b1e3d"
+
+func TestArtifactKindOf(t *testing.T) {
+	for mediaType, expected := range map[string]ArtifactKind{
+		"": ArtifactContainerImage,
+		"application/vnd.oci.image.manifest.v1+json":                ArtifactContainerImage,
+		"application/vnd.docker.distribution.manifest.list.v2+json": ArtifactContainerImage,
+		MediaTypeHelmChart:   ArtifactHelmChart,
+		MediaTypeWasmModule:  ArtifactWasmModule,
+		MediaTypeWasm:        ArtifactWasmModule,
+		MediaTypeOctetStream: ArtifactBlob,
+		"text/plain":         ArtifactBlob,
+	} {
+		assert.Check(t, is.Equal(ArtifactKindOf(mediaType), expected), mediaType)
+	}
+}
+
+func TestValidateMediaType(t *testing.T) {
+	for _, tc := range []struct {
+		image      bundle.BaseImage
+		invocation bool
+		expected   string
+	}{
+		{image: bundle.BaseImage{Image: "nginx:1.17"}},
+		{image: bundle.BaseImage{ImageType: "docker", Image: "app:1.0", MediaType: "application/vnd.oci.image.manifest.v1+json"}, invocation: true},
+		{image: bundle.BaseImage{ImageType: "oci", Image: "charts/wordpress:8.1.0", MediaType: MediaTypeHelmChart}},
+		{image: bundle.BaseImage{Image: "data/seed", Digest: testBlobDigest, MediaType: MediaTypeOctetStream}},
+		{
+			image:    bundle.BaseImage{Image: "app:1.0", MediaType: "not a media type"},
+			expected: `invalid media type "not a media type"`,
+		},
+		{
+			image:    bundle.BaseImage{Image: "app:1.0", MediaType: "text/plain; charset=utf-8"},
+			expected: `invalid media type "text/plain; charset=utf-8"`,
+		},
+		{
+			image:      bundle.BaseImage{Image: "module:1.0", MediaType: MediaTypeWasm},
+			invocation: true,
+			expected:   `media type "application/wasm" is a wasm-module, only container images can be invocation images`,
+		},
+		{
+			image:    bundle.BaseImage{ImageType: "docker", Image: "charts/wordpress:8.1.0", MediaType: MediaTypeHelmChart},
+			expected: `media type "application/vnd.cncf.helm.chart.content.v1.tar+gzip" is a helm-chart, which must have the oci image type`,
+		},
+		{
+			image:    bundle.BaseImage{Image: "data/seed:latest", MediaType: MediaTypeOctetStream},
+			expected: `media type "application/octet-stream" is a blob, which must be referenced by digest`,
+		},
+	} {
+		err := ValidateMediaType(tc.image, tc.invocation)
+		if tc.expected == "" {
+			assert.Check(t, err, tc.image.Image)
+		} else {
+			assert.Check(t, is.Error(err, tc.expected), tc.image.Image)
+		}
+	}
+}
+
+func TestValidateReportsInvalidMediaTypes(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:    "app",
+		Version: "1.0.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0", MediaType: MediaTypeWasm}},
+		},
+		Images: map[string]bundle.Image{
+			"seed": {BaseImage: bundle.BaseImage{Image: "data/seed:latest", MediaType: MediaTypeOctetStream}},
+		},
+	}
+	var paths []string
+	for _, err := range Validate(b) {
+		if err.Code == CodeInvalidMediaType {
+			paths = append(paths, err.Path)
+		}
+	}
+	assert.Check(t, is.DeepEqual(paths, []string{"$.invocationImages[0].mediaType", `$.images["seed"].mediaType`}))
+}
+
+func TestArtifactReference(t *testing.T) {
+	ref, err := ArtifactReference(bundle.BaseImage{Image: "charts/wordpress"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(ref, "docker.io/charts/wordpress:latest"))
+
+	ref, err = ArtifactReference(bundle.BaseImage{Image: "data/seed:1.0", Digest: testBlobDigest})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(ref, "docker.io/data/seed:1.0@"+testBlobDigest))
+
+	ref, err = ArtifactReference(bundle.BaseImage{Image: "data/seed@" + testBlobDigest, Digest: "ignored"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(ref, "docker.io/data/seed@"+testBlobDigest))
+
+	_, err = ArtifactReference(bundle.BaseImage{Image: "data/seed", Digest: "sha256:invalid"})
+	assert.Check(t, is.ErrorContains(err, `invalid digest of artifact "data/seed"`))
+	_, err = ArtifactReference(bundle.BaseImage{Image: "Invalid Reference"})
+	assert.Check(t, is.ErrorContains(err, `invalid artifact reference "Invalid Reference"`))
+}
@@ -0,0 +1,159 @@
+package cnab
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+)
+
+// Cache memoizes the validation and digests of bundles by the hash of their
+// content, so that services checking the same bundles over and over do the
+// work once per unique document. It keeps the results of the most recently
+// used documents and is safe for concurrent use.
+//
+// Bundles are keyed by their digest, which saves the validation but not the
+// serialization of the bundle. Raw documents are keyed by the hash of their
+// bytes, which saves the parsing and the serialization as well.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key   string
+	value interface{}
+}
+
+// schemaResult is the memoized result of ValidateSchema.
+type schemaResult struct {
+	err error
+}
+
+// digestResult is the memoized result of digesting a raw document.
+type digestResult struct {
+	digest string
+	err    error
+}
+
+// NewCache returns a cache keeping the results of the given number of
+// computations at most.
+func NewCache(size int) *Cache {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache{size: size, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+// WithCache makes Validate memoize its result in the cache.
+func WithCache(c *Cache) func(*ValidateOptions) {
+	return func(o *ValidateOptions) {
+		o.cache = c
+	}
+}
+
+// Len returns the number of results in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// ValidateSchema is a memoized ValidateSchema.
+func (c *Cache) ValidateSchema(data []byte) error {
+	key, err := dataKey("schema:", data)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.get(key); ok {
+		return v.(schemaResult).err
+	}
+	err = ValidateSchema(data)
+	c.put(key, schemaResult{err})
+	return err
+}
+
+// DigestData returns the digest of the bundle parsed from the raw document,
+// see Digest. A document which does not parse has no digest.
+func (c *Cache) DigestData(data []byte) (string, error) {
+	key, err := dataKey("digest:", data)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := c.get(key); ok {
+		r := v.(digestResult)
+		return r.digest, r.err
+	}
+	var r digestResult
+	b, err := Parse(data)
+	if err == nil {
+		r.digest, r.err = Digest(b)
+	} else {
+		r.err = err
+	}
+	c.put(key, r)
+	return r.digest, r.err
+}
+
+// dataKey returns the key of a raw document, its digest with the configured
+// algorithm, which also tells apart the digests of the bundle computed with
+// different algorithms.
+func dataKey(prefix string, data []byte) (string, error) {
+	config, err := checksum.FromEnv()
+	if err != nil {
+		return "", err
+	}
+	d, err := config.FromBytes(data)
+	if err != nil {
+		return "", err
+	}
+	return prefix + d.String(), nil
+}
+
+// validate memoizes the validation of the bundle, which also depends on the
+// options and on the registered image types. The latter are only ever added,
+// their count telling them apart.
+func (c *Cache) validate(b *bundle.Bundle, o ValidateOptions, compute func() ValidationErrors) ValidationErrors {
+	d, err := Digest(b)
+	if err != nil {
+		return compute()
+	}
+	key := fmt.Sprintf("validate:%s:%t:%d", d, o.strictImageTypes, imageTypesCount())
+	if v, ok := c.get(key); ok {
+		return append(ValidationErrors(nil), v.(ValidationErrors)...)
+	}
+	errs := compute()
+	c.put(key, append(ValidationErrors(nil), errs...))
+	return errs
+}
+
+func (c *Cache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).value, true
+}
+
+func (c *Cache) put(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheEntry).value = value
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key, value})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,157 @@
+package cnab
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCacheValidate(t *testing.T) {
+	c := NewCache(10)
+	b := &bundle.Bundle{Name: "myapp", Version: "not a version"}
+	expected := Validate(b)
+	assert.Assert(t, expected.Err() != nil)
+
+	assert.Check(t, is.DeepEqual(Validate(b, WithCache(c)), expected))
+	assert.Check(t, is.Equal(c.Len(), 1))
+	// The cached result is returned, and cannot be altered by the caller.
+	cached := Validate(b, WithCache(c))
+	assert.Check(t, is.DeepEqual(cached, expected))
+	cached[0].Message = "altered"
+	assert.Check(t, is.DeepEqual(Validate(b, WithCache(c)), expected))
+	assert.Check(t, is.Equal(c.Len(), 1))
+
+	// Options and content changes are different entries.
+	Validate(b, WithCache(c), WithStrictImageTypes())
+	assert.Check(t, is.Equal(c.Len(), 2))
+	b.Version = "1.0.0"
+	assert.Check(t, is.DeepEqual(Validate(b, WithCache(c)), Validate(b)))
+	assert.Check(t, is.Equal(c.Len(), 3))
+}
+
+func TestCacheValidateSchema(t *testing.T) {
+	c := NewCache(10)
+	valid := []byte(`{"name":"myapp","version":"1.0.0","schemaVersion":"v1.0.0-WD","invocationImages":[{"imageType":"docker","image":"myapp:1.0.0"}]}`)
+	invalid := []byte(`{"name":1}`)
+	assert.Check(t, c.ValidateSchema(valid))
+	assert.Check(t, c.ValidateSchema(valid))
+	assert.Check(t, is.Error(c.ValidateSchema(invalid), ValidateSchema(invalid).Error()))
+	assert.Check(t, is.Error(c.ValidateSchema(invalid), ValidateSchema(invalid).Error()))
+	assert.Check(t, is.Equal(c.Len(), 2))
+}
+
+func TestCacheDigestData(t *testing.T) {
+	c := NewCache(10)
+	b := &bundle.Bundle{Name: "myapp", Version: "1.0.0"}
+	data, err := json.Marshal(b)
+	assert.NilError(t, err)
+	expected, err := Digest(b)
+	assert.NilError(t, err)
+	for i := 0; i < 2; i++ {
+		d, err := c.DigestData(data)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(d, expected))
+	}
+	_, err = c.DigestData([]byte("{"))
+	assert.Check(t, err != nil)
+	assert.Check(t, is.Equal(c.Len(), 2))
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache(2)
+	c.put("a", 1)
+	c.put("b", 2)
+	_, _ = c.get("a")
+	c.put("c", 3)
+	assert.Check(t, is.Equal(c.Len(), 2))
+	_, ok := c.get("b")
+	assert.Check(t, !ok, "the least recently used entry is evicted")
+	v, ok := c.get("a")
+	assert.Check(t, ok)
+	assert.Check(t, is.Equal(v, 1))
+}
+
+// largeBundle returns a bundle with 5000 parameters.
+func largeBundle() *bundle.Bundle {
+	b := &bundle.Bundle{
+		Name:             "large",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "large:1.0.0"}}},
+		Parameters:       map[string]bundle.ParameterDefinition{},
+	}
+	for i := 0; i < 5000; i++ {
+		b.Parameters[fmt.Sprintf("param%d", i)] = bundle.ParameterDefinition{DataType: "string", Default: "value"}
+	}
+	return b
+}
+
+func BenchmarkValidate(b *testing.B) {
+	bndl := largeBundle()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Validate(bndl)
+	}
+}
+
+func BenchmarkValidateCached(b *testing.B) {
+	bndl := largeBundle()
+	c := NewCache(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Validate(bndl, WithCache(c))
+	}
+}
+
+func largeDocument(b *testing.B) []byte {
+	data, err := json.Marshal(largeBundle())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func BenchmarkValidateSchema(b *testing.B) {
+	data := largeDocument(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ValidateSchema(data)
+	}
+}
+
+func BenchmarkValidateSchemaCached(b *testing.B) {
+	data := largeDocument(b)
+	c := NewCache(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.ValidateSchema(data)
+	}
+}
+
+func BenchmarkDigestData(b *testing.B) {
+	data := largeDocument(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bndl, err := Parse(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := Digest(bndl); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDigestDataCached(b *testing.B) {
+	data := largeDocument(b)
+	c := NewCache(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.DigestData(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package cnab
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// MetadataExtensionKey is the custom extension carrying the license and the
+// provenance annotations of the bundle.
+const MetadataExtensionKey = internal.Namespace + "metadata"
+
+// Well known annotations, following the OCI image annotations.
+const (
+	// AnnotationRevision is the source control revision the bundle was
+	// built from, like a git commit SHA.
+	AnnotationRevision = "org.opencontainers.image.revision"
+	// AnnotationCreated is the RFC 3339 date and time the bundle was built.
+	AnnotationCreated = "org.opencontainers.image.created"
+	// AnnotationSource is the URL of the source code of the bundle.
+	AnnotationSource = "org.opencontainers.image.source"
+	// AnnotationPipeline is the URL of the build pipeline which built the
+	// bundle.
+	AnnotationPipeline = internal.Namespace + "pipeline"
+)
+
+// Metadata is the content of the metadata extension.
+type Metadata struct {
+	// License is the SPDX license expression of the bundle, like
+	// "Apache-2.0" or "MIT OR Apache-2.0".
+	License string `json:"license,omitempty"`
+	// Annotations are arbitrary key values, like the well known provenance
+	// annotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+var (
+	licenseExpression = regexp.MustCompile(`^[A-Za-z0-9.+\-() ]+$`)
+	revision          = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+)
+
+// ReadMetadata returns the metadata of the bundle, or nil if it has none.
+// The license and the well known annotations are validated.
+func ReadMetadata(b *bundle.Bundle) (*Metadata, error) {
+	var metadata Metadata
+	found, err := GetCustomExtension(b, MetadataExtensionKey, &metadata)
+	if err != nil || !found {
+		return nil, err
+	}
+	if err := metadata.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s extension", MetadataExtensionKey)
+	}
+	return &metadata, nil
+}
+
+// WriteMetadata sets the metadata extension of the bundle, or removes it if
+// metadata is nil.
+func WriteMetadata(b *bundle.Bundle, metadata *Metadata) {
+	if metadata == nil {
+		delete(b.Custom, MetadataExtensionKey)
+		return
+	}
+	if b.Custom == nil {
+		b.Custom = map[string]interface{}{}
+	}
+	b.Custom[MetadataExtensionKey] = *metadata
+}
+
+// Validate checks the license is a license expression, and the values of
+// the well known annotations.
+func (m *Metadata) Validate() error {
+	if m.License != "" && !licenseExpression.MatchString(m.License) {
+		return errors.Errorf("invalid license %q, expected an SPDX license expression", m.License)
+	}
+	keys := make([]string, 0, len(m.Annotations))
+	for key := range m.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "" {
+			return errors.New("annotation key is required")
+		}
+		if err := validateAnnotation(key, m.Annotations[key]); err != nil {
+			return errors.Wrapf(err, "invalid annotation %s", key)
+		}
+	}
+	return nil
+}
+
+func validateAnnotation(key, value string) error {
+	switch key {
+	case AnnotationRevision:
+		if !revision.MatchString(value) {
+			return errors.Errorf("%q is not a revision", value)
+		}
+	case AnnotationCreated:
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return errors.Errorf("%q is not an RFC 3339 date", value)
+		}
+	case AnnotationSource, AnnotationPipeline:
+		if u, err := url.Parse(value); err != nil || !u.IsAbs() || u.Host == "" {
+			return errors.Errorf("%q is not an absolute URL", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cnab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestReadMetadata(t *testing.T) {
+	b := &bundle.Bundle{}
+	metadata, err := ReadMetadata(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(metadata))
+
+	WriteMetadata(b, &Metadata{
+		License: "MIT OR Apache-2.0",
+		Annotations: map[string]string{
+			AnnotationRevision: "3f2a9c1",
+			AnnotationCreated:  "2019-10-01T12:00:00Z",
+			AnnotationSource:   "https://github.com/docker/app",
+			AnnotationPipeline: "https://ci.example.com/builds/42",
+			"team":             "platform",
+		},
+	})
+	// Round trip through JSON, as a loaded bundle
+	data, err := json.Marshal(b)
+	assert.NilError(t, err)
+	loaded, err := Parse(data)
+	assert.NilError(t, err)
+	metadata, err = ReadMetadata(loaded)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(metadata.License, "MIT OR Apache-2.0"))
+	assert.Check(t, is.Equal(metadata.Annotations["team"], "platform"))
+
+	WriteMetadata(b, nil)
+	assert.Check(t, is.Len(b.Custom, 0))
+}
+
+func TestMetadataValidate(t *testing.T) {
+	for expected, metadata := range map[string]Metadata{
+		`invalid license "MIT;rm", expected an SPDX license expression`:                            {License: "MIT;rm"},
+		`invalid annotation org.opencontainers.image.revision: "main" is not a revision`:           {Annotations: map[string]string{AnnotationRevision: "main"}},
+		`invalid annotation org.opencontainers.image.created: "yesterday" is not an RFC 3339 date`: {Annotations: map[string]string{AnnotationCreated: "yesterday"}},
+		`invalid annotation com.docker.app.pipeline: "builds/42" is not an absolute URL`:           {Annotations: map[string]string{AnnotationPipeline: "builds/42"}},
+		`annotation key is required`: {Annotations: map[string]string{"": "value"}},
+	} {
+		assert.Check(t, is.Error(metadata.Validate(), expected))
+	}
+
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0"}}},
+	}
+	WriteMetadata(b, &Metadata{License: "MIT;rm"})
+	errs := Validate(b)
+	assert.Check(t, is.Len(errs, 1))
+	assert.Check(t, is.Equal(errs[0].Code, CodeInvalidMetadata))
+}
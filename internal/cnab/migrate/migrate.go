@@ -0,0 +1,157 @@
+// Package migrate reads bundle documents written with older revisions of the
+// bundle format and upgrades them, in memory, to the latest schema version.
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/specification"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
+)
+
+// SchemaVersionField is the field of bundle documents holding their schema
+// version.
+const SchemaVersionField = "schemaVersion"
+
+// LatestSchemaVersion is the schema version of the bundles handled by this
+// tree.
+const LatestSchemaVersion = specification.BundleSchemaVersion
+
+// Document is a bundle read from a possibly older document.
+type Document struct {
+	// SchemaVersion is the schema version the document was written with. It
+	// is empty for documents predating schema versions.
+	SchemaVersion string
+	Bundle        *bundle.Bundle
+	// Data is the migrated document, stamped with the latest schema
+	// version, so it can be validated against the CNAB schema.
+	Data []byte
+	// Warnings describe the deprecated constructs which were migrated.
+	Warnings []string
+}
+
+// migration upgrades a decoded document from a schema version to the next
+// one, returning warnings about deprecated constructs.
+type migration struct {
+	from, to string
+	apply    func(doc map[string]interface{}) ([]string, error)
+}
+
+// migrations are applied in order, from the document version to the latest.
+var migrations = []migration{
+	{from: "", to: LatestSchemaVersion, apply: migrateUnversioned},
+}
+
+// Migrate reads a bundle document of any supported schema version and
+// upgrades it to the latest one. Documents of unknown, for instance newer,
+// schema versions are rejected.
+func Migrate(data []byte) (*Document, error) {
+	if err := cnab.CheckNesting(data); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	version := ""
+	if v, ok := doc[SchemaVersionField]; ok {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return nil, errors.Errorf("invalid bundle: %s must be a non empty string", SchemaVersionField)
+		}
+		version = s
+	}
+	result := &Document{SchemaVersion: version}
+	current := version
+	for _, m := range migrations {
+		if m.from != current {
+			continue
+		}
+		warnings, err := m.apply(doc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate bundle to schema version %s", m.to)
+		}
+		result.Warnings = append(result.Warnings, warnings...)
+		current = m.to
+	}
+	if current != LatestSchemaVersion {
+		return nil, errors.Errorf("unsupported bundle schema version %q, the latest supported version is %q", version, LatestSchemaVersion)
+	}
+	doc[SchemaVersionField] = LatestSchemaVersion
+	var err error
+	if result.Data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	delete(doc, SchemaVersionField)
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if result.Bundle, err = cnab.ParseReaderLimited(bytes.NewReader(migrated), cnab.MaxBundleSize); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Marshal returns the canonical JSON document of a bundle, stamped with the
+// latest schema version.
+func Marshal(b *bundle.Bundle) ([]byte, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc[SchemaVersionField] = LatestSchemaVersion
+	return canonicaljson.MarshalCanonical(doc)
+}
+
+// migrateUnversioned upgrades the documents written before schema versions
+// were introduced:
+//   - images used to be a list, they are now keyed by name
+//   - the invocation image type used to default to docker
+func migrateUnversioned(doc map[string]interface{}) ([]string, error) {
+	var warnings []string
+	if list, ok := doc["images"].([]interface{}); ok {
+		images := map[string]interface{}{}
+		for i, item := range list {
+			image, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("images[%d] is not an object", i)
+			}
+			name, _ := image["name"].(string)
+			if name == "" {
+				return nil, errors.Errorf("images[%d] has no name", i)
+			}
+			if _, ok := images[name]; ok {
+				return nil, errors.Errorf("duplicate image %q", name)
+			}
+			delete(image, "name")
+			images[name] = image
+		}
+		doc["images"] = images
+		warnings = append(warnings, "images as a list are deprecated, images are now keyed by name")
+	}
+	if list, ok := doc["invocationImages"].([]interface{}); ok {
+		for i, item := range list {
+			image, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if t, _ := image["imageType"].(string); t == "" {
+				image["imageType"] = "docker"
+				warnings = append(warnings, fmt.Sprintf("invocationImages[%d] has no imageType, assuming docker", i))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return warnings, nil
+}
@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/docker/app/internal/cnab"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestMigrateUnversioned(t *testing.T) {
+	doc, err := Migrate([]byte(`{
+		"name": "myapp",
+		"version": "1.0.0",
+		"invocationImages": [{"image": "myapp-invoc:1.0.0"}],
+		"images": [{"name": "web", "image": "nginx:1.17", "imageType": "docker", "description": "web server"}]
+	}`))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(doc.SchemaVersion, ""))
+	assert.Check(t, is.DeepEqual(doc.Warnings, []string{
+		"images as a list are deprecated, images are now keyed by name",
+		"invocationImages[0] has no imageType, assuming docker",
+	}))
+	assert.Check(t, is.Equal(doc.Bundle.InvocationImages[0].ImageType, "docker"))
+	assert.Check(t, is.Equal(doc.Bundle.Images["web"].Image, "nginx:1.17"))
+	assert.Check(t, is.Equal(doc.Bundle.Images["web"].Description, "web server"))
+	// The migrated document matches the latest schema
+	assert.Check(t, cnab.ValidateSchema(doc.Data))
+	assert.Check(t, is.Contains(string(doc.Data), `"schemaVersion":"`+LatestSchemaVersion+`"`))
+}
+
+func TestMigrateLatest(t *testing.T) {
+	doc, err := Migrate([]byte(`{"schemaVersion": "v1.0.0-WD", "name": "myapp", "images": {"web": {"image": "nginx:1.17"}}}`))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(doc.SchemaVersion, LatestSchemaVersion))
+	assert.Check(t, is.Len(doc.Warnings, 0))
+	assert.Check(t, is.Equal(doc.Bundle.Images["web"].Image, "nginx:1.17"))
+}
+
+func TestMigrateErrors(t *testing.T) {
+	for input, expected := range map[string]string{
+		`{"schemaVersion": "v2.0.0"}`:                `unsupported bundle schema version "v2.0.0"`,
+		`{"schemaVersion": 1}`:                       "schemaVersion must be a non empty string",
+		`{"images": [{"image": "nginx"}]}`:           "images[0] has no name",
+		`{"images": [{"name": "a"}, {"name": "a"}]}`: `duplicate image "a"`,
+		`{"name": ["myapp"]}`:                        "invalid bundle",
+		`[`:                                          "invalid bundle",
+	} {
+		_, err := Migrate([]byte(input))
+		assert.Check(t, is.ErrorContains(err, expected), input)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	doc, err := Migrate([]byte(`{"name": "myapp", "version": "1.0.0", "invocationImages": [{"imageType": "docker", "image": "myapp-invoc:1.0.0"}]}`))
+	assert.NilError(t, err)
+	data, err := Marshal(doc.Bundle)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(data), `"schemaVersion":"v1.0.0-WD"`))
+
+	// Marshaled documents are read back as is, even in strict mode
+	again, err := Migrate(data)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(again.Bundle, doc.Bundle))
+	_, err = cnab.UnmarshalStrict(data)
+	assert.NilError(t, err)
+	assert.NilError(t, cnab.ValidateSchema(data))
+}
@@ -0,0 +1,63 @@
+package cnab
+
+import (
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+)
+
+// The bundle maps are iterated in random order. The helpers below enumerate
+// them sorted instead, so that reports, errors and operations built from a
+// bundle are the same from one run to the other.
+
+// ParameterNames returns the sorted names of the parameters of the bundle.
+func ParameterNames(b *bundle.Bundle) []string {
+	return sortedParameterNames(b.Parameters)
+}
+
+// CredentialNames returns the sorted names of the credentials of the bundle.
+func CredentialNames(b *bundle.Bundle) []string {
+	return sortedCredentialNames(b.Credentials)
+}
+
+// ImageKeys returns the sorted keys of the component images of the bundle.
+func ImageKeys(b *bundle.Bundle) []string {
+	return sortedImageNames(b.Images)
+}
+
+// ActionNames returns the sorted names of the custom actions of the bundle.
+func ActionNames(b *bundle.Bundle) []string {
+	names := make([]string, 0, len(b.Actions))
+	for name := range b.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedImageNames(images map[string]bundle.Image) []string {
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedParameterNames(parameters map[string]bundle.ParameterDefinition) []string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedCredentialNames(credentials map[string]bundle.Location) []string {
+	names := make([]string, 0, len(credentials))
+	for name := range credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,37 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestSortedNames(t *testing.T) {
+	b := &bundle.Bundle{
+		Parameters:  map[string]bundle.ParameterDefinition{"b": {}, "c": {}, "a": {}},
+		Credentials: map[string]bundle.Location{"token": {}, "kubeconfig": {}},
+		Images:      map[string]bundle.Image{"web": {}, "db": {}, "cache": {}},
+		Actions:     map[string]bundle.Action{"status": {}, "logs": {}},
+	}
+	assert.Check(t, is.DeepEqual(ParameterNames(b), []string{"a", "b", "c"}))
+	assert.Check(t, is.DeepEqual(CredentialNames(b), []string{"kubeconfig", "token"}))
+	assert.Check(t, is.DeepEqual(ImageKeys(b), []string{"cache", "db", "web"}))
+	assert.Check(t, is.DeepEqual(ActionNames(b), []string{"logs", "status"}))
+
+	empty := &bundle.Bundle{}
+	assert.Check(t, is.Len(ParameterNames(empty), 0))
+	assert.Check(t, is.Len(ImageKeys(empty), 0))
+}
+
+func TestValuesOrDefaultsReportsTheFirstMissingParameter(t *testing.T) {
+	b := &bundle.Bundle{Parameters: map[string]bundle.ParameterDefinition{}}
+	for _, name := range []string{"e", "d", "c", "b", "a"} {
+		b.Parameters[name] = bundle.ParameterDefinition{DataType: "string", Required: true}
+	}
+	for i := 0; i < 10; i++ {
+		_, err := ValuesOrDefaults(nil, b, "install")
+		assert.Check(t, is.ErrorContains(err, `"a"`))
+	}
+}
@@ -0,0 +1,80 @@
+package cnab
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+)
+
+// maxExactFloat is the largest integer float64 holds exactly, 2^53.
+const maxExactFloat = 1 << 53
+
+// NormalizeParameterValue converts a number decoded from JSON or YAML to the
+// type of the parameter. Numbers should be decoded as json.Number, see
+// json.Decoder.UseNumber, as float64 cannot hold the integers above 2^53:
+//
+//   - for an "int" parameter, the number must be an integer, possibly
+//     written like 1e3 or 3.0, which fits an int,
+//   - for a "string" parameter, the number is kept as written, so that
+//     10000000000000001 is not turned into 1e+16.
+//
+// Other values are returned unchanged.
+func NormalizeParameterValue(def bundle.ParameterDefinition, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		switch def.DataType {
+		case "int":
+			return numberToInt(v)
+		case "string":
+			return v.String(), nil
+		}
+	case float64:
+		switch def.DataType {
+		case "int":
+			if v != math.Trunc(v) || math.IsInf(v, 0) {
+				return nil, errors.Errorf("%v is not an integer", v)
+			}
+			if math.Abs(v) > maxExactFloat {
+				return nil, errors.Errorf("%v is too large to be an exact integer, it was decoded as a float", v)
+			}
+			return int(v), nil
+		case "string":
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	case int64:
+		if def.DataType == "int" && int64(int(v)) == v {
+			return int(v), nil
+		}
+	case int32:
+		if def.DataType == "int" {
+			return int(v), nil
+		}
+	}
+	return value, nil
+}
+
+// numberToInt converts a JSON number to an int, exactly.
+func numberToInt(n json.Number) (int, error) {
+	if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+		if int64(int(i)) != i {
+			return 0, errors.Errorf("%s is out of the int range", n)
+		}
+		return int(i), nil
+	}
+	f, _, err := big.ParseFloat(n.String(), 10, 256, big.ToNearestEven)
+	if err != nil {
+		return 0, errors.Errorf("%s is not a number", n)
+	}
+	if f.Acc() != big.Exact || !f.IsInt() {
+		return 0, errors.Errorf("%s is not an integer", n)
+	}
+	i, accuracy := f.Int64()
+	if accuracy != big.Exact || int64(int(i)) != i {
+		return 0, errors.Errorf("%s is out of the int range", n)
+	}
+	return int(i), nil
+}
@@ -0,0 +1,62 @@
+package cnab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestNormalizeParameterValue(t *testing.T) {
+	intDef := bundle.ParameterDefinition{DataType: "int"}
+	stringDef := bundle.ParameterDefinition{DataType: "string"}
+	boolDef := bundle.ParameterDefinition{DataType: "bool"}
+	for _, tc := range []struct {
+		def      bundle.ParameterDefinition
+		value    interface{}
+		expected interface{}
+		err      string
+	}{
+		{def: intDef, value: json.Number("10000000000000001"), expected: 10000000000000001},
+		{def: intDef, value: json.Number("-42"), expected: -42},
+		{def: intDef, value: json.Number("1e3"), expected: 1000},
+		{def: intDef, value: json.Number("2.5E1"), expected: 25},
+		{def: intDef, value: json.Number("3.0"), expected: 3},
+		{def: intDef, value: json.Number("3.5"), err: "3.5 is not an integer"},
+		{def: intDef, value: json.Number("1e19"), err: "1e19 is out of the int range"},
+		{def: intDef, value: float64(3), expected: 3},
+		{def: intDef, value: float64(3.5), err: "3.5 is not an integer"},
+		{def: intDef, value: float64(1e16), err: "too large to be an exact integer"},
+		{def: intDef, value: int64(7), expected: 7},
+		{def: intDef, value: "7", expected: "7"},
+		{def: stringDef, value: json.Number("10000000000000001"), expected: "10000000000000001"},
+		{def: stringDef, value: json.Number("1e3"), expected: "1e3"},
+		{def: stringDef, value: float64(1e21), expected: "1000000000000000000000"},
+		{def: boolDef, value: json.Number("1"), expected: json.Number("1")},
+	} {
+		value, err := NormalizeParameterValue(tc.def, tc.value)
+		if tc.err != "" {
+			assert.Check(t, is.ErrorContains(err, tc.err), "%v", tc.value)
+			continue
+		}
+		assert.Check(t, err, "%v", tc.value)
+		assert.Check(t, is.DeepEqual(value, tc.expected), "%v", tc.value)
+	}
+}
+
+func TestValuesOrDefaultsNumbers(t *testing.T) {
+	b := &bundle.Bundle{Parameters: map[string]bundle.ParameterDefinition{
+		"replicas": {DataType: "int"},
+		"enabled":  {DataType: "bool"},
+	}}
+	vals, err := ValuesOrDefaults(map[string]interface{}{"replicas": json.Number("10000000000000001")}, b, "install")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(vals["replicas"], 10000000000000001))
+
+	_, err = ValuesOrDefaults(map[string]interface{}{"replicas": json.Number("1.5")}, b, "install")
+	assert.Check(t, is.ErrorContains(err, "1.5 is not an integer"))
+	_, err = ValuesOrDefaults(map[string]interface{}{"enabled": json.Number("1")}, b, "install")
+	assert.Check(t, is.ErrorContains(err, "value is not a boolean"))
+}
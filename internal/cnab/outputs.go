@@ -0,0 +1,131 @@
+package cnab
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// OutputsExtensionKey is the custom extension declaring the outputs produced
+// by the invocation images.
+const OutputsExtensionKey = internal.Namespace + "outputs"
+
+// OutputsDirectory is the directory of the invocation image where outputs
+// must be written.
+const OutputsDirectory = "/cnab/app/outputs"
+
+// Output is a value produced by the invocation image during an action.
+type Output struct {
+	// Definition is the name of the schema of the output, in the definitions
+	// of the parameter schemas extension.
+	Definition  string `json:"definition"`
+	Description string `json:"description,omitempty"`
+	// Path is the file the invocation image writes the output to.
+	Path string `json:"path"`
+	// ApplyTo restricts the output to some actions, all actions produce the
+	// output if it is empty.
+	ApplyTo []string `json:"applyTo,omitempty"`
+	// Sensitive outputs, like generated passwords, must never be displayed
+	// nor logged.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// AppliesTo returns true if the output is produced by the given action.
+func (o Output) AppliesTo(action string) bool {
+	if len(o.ApplyTo) == 0 {
+		return true
+	}
+	for _, a := range o.ApplyTo {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOutputs returns the outputs declared by the bundle, or nil if it has
+// none. Outputs must reference an existing definition, be written in the
+// outputs directory and apply to known actions.
+func ReadOutputs(b *bundle.Bundle) (map[string]Output, error) {
+	var outputs map[string]Output
+	found, err := GetCustomExtension(b, OutputsExtensionKey, &outputs)
+	if err != nil || !found {
+		return nil, err
+	}
+	schemas, err := ReadParameterSchemas(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedOutputNames(outputs) {
+		output := outputs[name]
+		if output.Definition == "" {
+			return nil, errors.Errorf("invalid %s extension: output %q has no definition", OutputsExtensionKey, name)
+		}
+		if schemas == nil || schemas.Definitions[output.Definition] == nil {
+			return nil, errors.Errorf("invalid %s extension: output %q references undefined definition %q", OutputsExtensionKey, name, output.Definition)
+		}
+		if !isOutputPath(output.Path) {
+			return nil, errors.Errorf("invalid %s extension: path %q of output %q must be in %s", OutputsExtensionKey, output.Path, name, OutputsDirectory)
+		}
+		for _, action := range output.ApplyTo {
+			if !isKnownAction(b, action) {
+				return nil, errors.Errorf("invalid %s extension: output %q applies to undefined action %q", OutputsExtensionKey, name, action)
+			}
+		}
+	}
+	return outputs, nil
+}
+
+// ValidateOutputs checks the outputs collected after an action against their
+// definition. Outputs the action should have produced are reported missing.
+// Values are decoded as JSON when possible, and used as strings otherwise.
+func ValidateOutputs(b *bundle.Bundle, action string, values map[string]string) error {
+	outputs, err := ReadOutputs(b)
+	if err != nil || outputs == nil {
+		return err
+	}
+	schemas, err := ReadParameterSchemas(b)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedOutputNames(outputs) {
+		output := outputs[name]
+		if !output.AppliesTo(action) {
+			continue
+		}
+		raw, ok := values[name]
+		if !ok {
+			return errors.Errorf("output %q was not produced by action %q", name, action)
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		if err := schemas.validate(schemas.Definitions[output.Definition], value, "", 0); err != nil {
+			// Strings which happen to be valid JSON, like numbers, are
+			// accepted by string definitions
+			if err2 := schemas.validate(schemas.Definitions[output.Definition], raw, "", 0); err2 != nil {
+				return errors.Wrapf(err, "invalid value for output %q", name)
+			}
+		}
+	}
+	return nil
+}
+
+func isOutputPath(p string) bool {
+	return path.IsAbs(p) && strings.HasPrefix(path.Clean(p), OutputsDirectory+"/")
+}
+
+func sortedOutputNames(outputs map[string]Output) []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,69 @@
+package cnab_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func outputsBundle(t *testing.T, outputs string) *bundle.Bundle {
+	t.Helper()
+	var value interface{}
+	assert.NilError(t, json.Unmarshal([]byte(outputs), &value))
+	return bundletest.NewTestBundle(
+		bundletest.WithAction("status", bundle.Action{}),
+		bundletest.WithCustom(cnab.ParameterSchemasExtensionKey, map[string]interface{}{
+			"definitions": map[string]interface{}{
+				"url":  map[string]interface{}{"type": "string", "pattern": "^https?://"},
+				"port": map[string]interface{}{"type": "integer", "minimum": 1},
+			},
+		}),
+		bundletest.WithCustom(cnab.OutputsExtensionKey, value),
+	)
+}
+
+func TestReadOutputs(t *testing.T) {
+	outputs, err := cnab.ReadOutputs(bundletest.NewTestBundle())
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(outputs))
+
+	outputs, err = cnab.ReadOutputs(outputsBundle(t, `{
+		"endpoint": {"definition": "url", "path": "/cnab/app/outputs/endpoint", "applyTo": ["install", "status"]}
+	}`))
+	assert.NilError(t, err)
+	assert.Check(t, outputs["endpoint"].AppliesTo("status"))
+	assert.Check(t, !outputs["endpoint"].AppliesTo("uninstall"))
+
+	for expected, outputs := range map[string]string{
+		`output "endpoint" has no definition`:                        `{"endpoint": {"path": "/cnab/app/outputs/endpoint"}}`,
+		`output "endpoint" references undefined definition "host"`:   `{"endpoint": {"definition": "host", "path": "/cnab/app/outputs/endpoint"}}`,
+		`path "/tmp/endpoint" of output "endpoint" must be in`:       `{"endpoint": {"definition": "url", "path": "/tmp/endpoint"}}`,
+		`path "/cnab/app/outputs/../x" of output "endpoint" must be`: `{"endpoint": {"definition": "url", "path": "/cnab/app/outputs/../x"}}`,
+		`output "endpoint" applies to undefined action "backup"`:     `{"endpoint": {"definition": "url", "path": "/cnab/app/outputs/endpoint", "applyTo": ["backup"]}}`,
+	} {
+		_, err := cnab.ReadOutputs(outputsBundle(t, outputs))
+		assert.Check(t, is.ErrorContains(err, expected))
+	}
+}
+
+func TestValidateOutputs(t *testing.T) {
+	b := outputsBundle(t, `{
+		"endpoint": {"definition": "url", "path": "/cnab/app/outputs/endpoint", "applyTo": ["install"]},
+		"port": {"definition": "port", "path": "/cnab/app/outputs/port"}
+	}`)
+	assert.NilError(t, cnab.ValidateOutputs(b, "install", map[string]string{"endpoint": "https://example.com", "port": "8080"}))
+	// The endpoint is only produced on install
+	assert.NilError(t, cnab.ValidateOutputs(b, "upgrade", map[string]string{"port": "8080"}))
+
+	err := cnab.ValidateOutputs(b, "install", map[string]string{"port": "8080"})
+	assert.Check(t, is.ErrorContains(err, `output "endpoint" was not produced by action "install"`))
+	err = cnab.ValidateOutputs(b, "upgrade", map[string]string{"port": "0"})
+	assert.Check(t, is.ErrorContains(err, `invalid value for output "port": 0 is lower than the minimum 1`))
+	err = cnab.ValidateOutputs(b, "install", map[string]string{"endpoint": "ftp://example.com", "port": "1"})
+	assert.Check(t, is.ErrorContains(err, `invalid value for output "endpoint"`))
+}
@@ -0,0 +1,206 @@
+package cnab
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+)
+
+// ValidateDestination fails if the destination of a parameter or of an action
+// argument has neither an environment variable nor a path. A nil destination
+// is valid, the value is then passed as a CNAB_P_<NAME> environment variable.
+func ValidateDestination(dest *bundle.Location) error {
+	if dest != nil && dest.EnvironmentVariable == "" && dest.Path == "" {
+		return errors.New("empty destination, an environment variable or a path must be set")
+	}
+	return nil
+}
+
+// ValidateParameterDestinations fails if a parameter has a destination
+// with neither an environment variable nor a path, see ValidateDestination.
+func ValidateParameterDestinations(b *bundle.Bundle) error {
+	for _, name := range ParameterNames(b) {
+		if err := ValidateDestination(b.Parameters[name].Destination); err != nil {
+			return errors.Wrapf(err, "parameter %q", name)
+		}
+	}
+	return nil
+}
+
+// ValuesOptions contains options for resolving parameter values.
+type ValuesOptions struct {
+	ignoreUndeclared bool
+}
+
+// IgnoreUndeclared makes ValuesOrDefaults drop the values of parameters the
+// bundle does not declare, instead of failing.
+func IgnoreUndeclared() func(*ValuesOptions) {
+	return func(o *ValuesOptions) {
+		o.ignoreUndeclared = true
+	}
+}
+
+// UndeclaredParametersError is returned when values are given for parameters
+// the bundle does not declare, which are most likely typos.
+type UndeclaredParametersError struct {
+	// Names are the undeclared parameter names, sorted.
+	Names []string
+	// Suggestions maps undeclared names to the closest declared parameter
+	// name, when one is close enough.
+	Suggestions map[string]string
+}
+
+func (e *UndeclaredParametersError) Error() string {
+	quoted := make([]string, len(e.Names))
+	var suggestions []string
+	for i, name := range e.Names {
+		quoted[i] = fmt.Sprintf("%q", name)
+		if suggestion, ok := e.Suggestions[name]; ok {
+			suggestions = append(suggestions, fmt.Sprintf("%q instead of %q", suggestion, name))
+		}
+	}
+	var msg string
+	if len(quoted) == 1 {
+		msg = fmt.Sprintf("parameter %s is not defined in the bundle", quoted[0])
+		if suggestion, ok := e.Suggestions[e.Names[0]]; ok {
+			return fmt.Sprintf("%s, did you mean %q?", msg, suggestion)
+		}
+		return msg
+	}
+	msg = fmt.Sprintf("parameters %s are not defined in the bundle", strings.Join(quoted, ", "))
+	if len(suggestions) > 0 {
+		msg += fmt.Sprintf(", did you mean %s?", strings.Join(suggestions, ", "))
+	}
+	return msg
+}
+
+// CheckDeclaredParameters returns an UndeclaredParametersError if some of the
+// names are not parameters of the bundle, with suggestions of declared names.
+func CheckDeclaredParameters(b *bundle.Bundle, names ...string) error {
+	var undeclared []string
+	for _, name := range names {
+		if _, ok := b.Parameters[name]; !ok {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(undeclared) == 0 {
+		return nil
+	}
+	sort.Strings(undeclared)
+	err := &UndeclaredParametersError{Names: undeclared, Suggestions: map[string]string{}}
+	for _, name := range undeclared {
+		if suggestion := closestParameter(b, name); suggestion != "" {
+			err.Suggestions[name] = suggestion
+		}
+	}
+	return err
+}
+
+// maxSuggestionDistance is the maximum edit distance between a typo and the
+// suggested parameter name.
+const maxSuggestionDistance = 2
+
+func closestParameter(b *bundle.Bundle, name string) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for candidate := range b.Parameters {
+		d := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// ParameterAppliesTo returns true if the parameter is used by the given
+// action. Parameters without apply-to restriction apply to all actions.
+func ParameterAppliesTo(def bundle.ParameterDefinition, action string) bool {
+	if len(def.ApplyTo) == 0 {
+		return true
+	}
+	for _, a := range def.ApplyTo {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ValuesOrDefaults is bundle.ValuesOrDefaults for a given action: parameters
+// which do not apply to the action get no default value and are not required.
+// Values given for them are still validated and kept, so they are not lost
+// for the next actions. Values of undeclared parameters are rejected with an
+// UndeclaredParametersError, unless IgnoreUndeclared is used, and invalid or
+// missing required values with a ParameterValidationError.
+func ValuesOrDefaults(vals map[string]interface{}, b *bundle.Bundle, action string, opts ...func(*ValuesOptions)) (map[string]interface{}, error) {
+	var o ValuesOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	res := map[string]interface{}{}
+	if !o.ignoreUndeclared {
+		names := make([]string, 0, len(vals))
+		for name := range vals {
+			names = append(names, name)
+		}
+		if err := CheckDeclaredParameters(b, names...); err != nil {
+			return res, err
+		}
+	}
+	for _, name := range ParameterNames(b) {
+		def := b.Parameters[name]
+		if val, ok := vals[name]; ok {
+			val, err := NormalizeParameterValue(def, val)
+			if err != nil {
+				return res, &ParameterValidationError{Name: name, Reason: err.Error()}
+			}
+			if err := def.ValidateParameterValue(val); err != nil {
+				return res, &ParameterValidationError{Name: name, Reason: fmt.Sprintf("can't use %v: %s", val, err)}
+			}
+			res[name] = def.CoerceValue(val)
+			continue
+		}
+		if !ParameterAppliesTo(def, action) {
+			continue
+		}
+		if def.Required {
+			return res, &ParameterValidationError{Name: name, Reason: "a value is required"}
+		}
+		res[name] = def.Default
+	}
+	return res, nil
+}
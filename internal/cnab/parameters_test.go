@@ -0,0 +1,85 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestValuesOrDefaults(t *testing.T) {
+	b := &bundle.Bundle{
+		Parameters: map[string]bundle.ParameterDefinition{
+			"replicas":  {DataType: "int", Default: 1},
+			"password":  {DataType: "string", Required: true, ApplyTo: []string{"install"}},
+			"format":    {DataType: "string", Default: "yaml", ApplyTo: []string{"render"}},
+			"namespace": {DataType: "string", Default: "default", ApplyTo: []string{"install", "upgrade"}},
+		},
+	}
+
+	values, err := ValuesOrDefaults(map[string]interface{}{}, b, "upgrade")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(values, map[string]interface{}{"replicas": 1, "namespace": "default"}))
+
+	_, err = ValuesOrDefaults(map[string]interface{}{}, b, "install")
+	assert.Check(t, is.Error(err, `invalid value for parameter "password": a value is required`))
+	assert.Check(t, is.DeepEqual(err, &ParameterValidationError{Name: "password", Reason: "a value is required"}))
+
+	// Values of parameters which do not apply are kept
+	values, err = ValuesOrDefaults(map[string]interface{}{"password": "s3cr3t", "format": "json"}, b, "upgrade")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(values, map[string]interface{}{"replicas": 1, "namespace": "default", "password": "s3cr3t", "format": "json"}))
+
+	_, err = ValuesOrDefaults(map[string]interface{}{"format": 1}, b, "upgrade")
+	assert.Check(t, is.ErrorContains(err, `invalid value for parameter "format": can't use 1`))
+	assert.Check(t, IsParameterValidation(err))
+}
+
+func TestValuesOrDefaultsRejectsUndeclared(t *testing.T) {
+	b := &bundle.Bundle{
+		Parameters: map[string]bundle.ParameterDefinition{
+			"replicas": {DataType: "int", Default: 1},
+			"port":     {DataType: "int", Default: 80},
+		},
+	}
+	_, err := ValuesOrDefaults(map[string]interface{}{"replcias": 3}, b, "install")
+	assert.Check(t, is.Error(err, `parameter "replcias" is not defined in the bundle, did you mean "replicas"?`))
+
+	_, err = ValuesOrDefaults(map[string]interface{}{"Port": 8080, "unrelated": 1, "replicas": 3}, b, "install")
+	assert.Check(t, is.Error(err, `parameters "Port", "unrelated" are not defined in the bundle, did you mean "port" instead of "Port"?`))
+	undeclared, ok := err.(*UndeclaredParametersError)
+	assert.Assert(t, ok)
+	assert.Check(t, is.DeepEqual(undeclared.Names, []string{"Port", "unrelated"}))
+
+	values, err := ValuesOrDefaults(map[string]interface{}{"replcias": 3}, b, "install", IgnoreUndeclared())
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(values, map[string]interface{}{"replicas": 1, "port": 80}))
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"replicas", "replcias", 2},
+		{"kitten", "sitting", 3},
+	} {
+		assert.Check(t, is.Equal(editDistance(tc.a, tc.b), tc.expected), "%s/%s", tc.a, tc.b)
+	}
+}
+
+func TestValidateParameterDestinations(t *testing.T) {
+	b := &bundle.Bundle{
+		Parameters: map[string]bundle.ParameterDefinition{
+			"default": {DataType: "string"},
+			"env":     {DataType: "string", Destination: &bundle.Location{EnvironmentVariable: "ENV"}},
+			"path":    {DataType: "string", Destination: &bundle.Location{Path: "/cnab/app/path"}},
+		},
+	}
+	assert.NilError(t, ValidateParameterDestinations(b))
+	b.Parameters["empty"] = bundle.ParameterDefinition{DataType: "string", Destination: &bundle.Location{}}
+	assert.Check(t, is.Error(ValidateParameterDestinations(b), `parameter "empty": empty destination, an environment variable or a path must be set`))
+}
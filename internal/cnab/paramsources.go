@@ -0,0 +1,116 @@
+package cnab
+
+import (
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+)
+
+// Kinds of parameter sources.
+const (
+	// ParameterSourceOutput is an output of the previous action of the
+	// installation.
+	ParameterSourceOutput = "output"
+	// ParameterSourceDependencyOutput is an output of a dependency of the
+	// bundle.
+	ParameterSourceDependencyOutput = "dependencies.output"
+)
+
+// OutputLookup returns the value of an output of the installation, or of one
+// of its dependencies if dependency is not empty. It returns false if the
+// output has not been produced.
+type OutputLookup func(dependency, output string) (string, bool)
+
+// ReadParameterSources returns the parameter sources declared by the bundle,
+// or nil if it has none. Sources must be of a known kind, for parameters and
+// dependencies the bundle declares.
+func ReadParameterSources(b *bundle.Bundle) (ParameterSources, error) {
+	var sources ParameterSources
+	found, err := GetCustomExtension(b, ParameterSourcesExtensionKey, &sources)
+	if err != nil || !found {
+		return nil, err
+	}
+	deps, err := ReadDependencies(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedParameterSourceNames(sources) {
+		if _, ok := b.Parameters[name]; !ok {
+			return nil, errors.Errorf("invalid %s extension: undefined parameter %q", ParameterSourcesExtensionKey, name)
+		}
+		source := sources[name]
+		for _, kind := range source.Priority {
+			def, ok := source.Sources[kind]
+			if !ok {
+				return nil, errors.Errorf("invalid %s extension: parameter %q has no %s source", ParameterSourcesExtensionKey, name, kind)
+			}
+			switch kind {
+			case ParameterSourceOutput:
+			case ParameterSourceDependencyOutput:
+				if deps == nil || !hasDependency(deps, def.Dependency) {
+					return nil, errors.Errorf("invalid %s extension: parameter %q comes from undefined dependency %q", ParameterSourcesExtensionKey, name, def.Dependency)
+				}
+			default:
+				return nil, errors.Errorf("invalid %s extension: unknown source %q of parameter %q", ParameterSourcesExtensionKey, kind, name)
+			}
+			if def.Name == "" {
+				return nil, errors.Errorf("invalid %s extension: %s source of parameter %q has no output name", ParameterSourcesExtensionKey, kind, name)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// ResolveParameterSources returns the values, completed with the values of
+// the parameters without value taken from their sources, in priority order.
+// It is meant to be called before ValuesOrDefaults, so the parameters whose
+// sources produced nothing get their default value.
+func ResolveParameterSources(b *bundle.Bundle, values map[string]interface{}, lookup OutputLookup) (map[string]interface{}, error) {
+	sources, err := ReadParameterSources(b)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		res[name] = value
+	}
+	for _, name := range sortedParameterSourceNames(sources) {
+		if _, ok := res[name]; ok {
+			continue
+		}
+		source := sources[name]
+		for _, kind := range source.Priority {
+			def := source.Sources[kind]
+			dependency := ""
+			if kind == ParameterSourceDependencyOutput {
+				dependency = def.Dependency
+			}
+			raw, ok := lookup(dependency, def.Name)
+			if !ok {
+				continue
+			}
+			value, err := b.Parameters[name].ConvertValue(raw)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value of output %q for parameter %q", def.Name, name)
+			}
+			res[name] = value
+			break
+		}
+	}
+	return res, nil
+}
+
+func hasDependency(deps *Dependencies, name string) bool {
+	_, ok := deps.Requires[name]
+	return ok
+}
+
+func sortedParameterSourceNames(sources ParameterSources) []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,82 @@
+package cnab_test
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func sourcesBundle(sources cnab.ParameterSources) *bundle.Bundle {
+	return bundletest.NewTestBundle(
+		bundletest.WithParameter("endpoint", bundle.ParameterDefinition{DataType: "string"}),
+		bundletest.WithParameter("port", bundle.ParameterDefinition{DataType: "int", Default: 5432}),
+		withDependencies(map[string]cnab.Dependency{"db": {Bundle: "db:1.2.0"}}),
+		bundletest.WithCustom(cnab.ParameterSourcesExtensionKey, sources),
+	)
+}
+
+func outputLookup(outputs map[string]map[string]string) cnab.OutputLookup {
+	return func(dependency, output string) (string, bool) {
+		value, ok := outputs[dependency][output]
+		return value, ok
+	}
+}
+
+func TestResolveParameterSources(t *testing.T) {
+	b := sourcesBundle(cnab.ParameterSources{
+		"endpoint": {
+			Priority: []string{cnab.ParameterSourceOutput, cnab.ParameterSourceDependencyOutput},
+			Sources: map[string]cnab.ParameterSourceDefinition{
+				cnab.ParameterSourceOutput:           {Name: "endpoint"},
+				cnab.ParameterSourceDependencyOutput: {Name: "url", Dependency: "db"},
+			},
+		},
+		"port": {
+			Priority: []string{cnab.ParameterSourceDependencyOutput},
+			Sources: map[string]cnab.ParameterSourceDefinition{
+				cnab.ParameterSourceDependencyOutput: {Name: "port", Dependency: "db"},
+			},
+		},
+	})
+
+	values, err := cnab.ResolveParameterSources(b, nil, outputLookup(map[string]map[string]string{
+		"db": {"url": "postgres://db", "port": "5433"},
+	}))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(values, map[string]interface{}{"endpoint": "postgres://db", "port": 5433}))
+
+	values, err = cnab.ResolveParameterSources(b, map[string]interface{}{"port": 80}, outputLookup(map[string]map[string]string{
+		"": {"endpoint": "http://app"},
+	}))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(values, map[string]interface{}{"endpoint": "http://app", "port": 80}))
+
+	values, err = cnab.ValuesOrDefaults(values, b, "upgrade")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(values["port"], 80))
+
+	_, err = cnab.ResolveParameterSources(b, nil, outputLookup(map[string]map[string]string{"db": {"port": "high"}}))
+	assert.Check(t, is.ErrorContains(err, `invalid value of output "port" for parameter "port"`))
+}
+
+func TestReadParameterSourcesInvalid(t *testing.T) {
+	for expected, sources := range map[string]cnab.ParameterSources{
+		`undefined parameter "replicas"`:        {"replicas": {}},
+		`parameter "port" has no output source`: {"port": {Priority: []string{"output"}}},
+		`unknown source "env" of parameter "port"`: {"port": {
+			Priority: []string{"env"},
+			Sources:  map[string]cnab.ParameterSourceDefinition{"env": {Name: "PORT"}},
+		}},
+		`parameter "port" comes from undefined dependency "cache"`: {"port": {
+			Priority: []string{cnab.ParameterSourceDependencyOutput},
+			Sources:  map[string]cnab.ParameterSourceDefinition{cnab.ParameterSourceDependencyOutput: {Name: "port", Dependency: "cache"}},
+		}},
+	} {
+		_, err := cnab.ReadParameterSources(sourcesBundle(sources))
+		assert.Check(t, is.ErrorContains(err, expected))
+	}
+}
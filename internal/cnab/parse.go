@@ -0,0 +1,151 @@
+package cnab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+	"unicode/utf8"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/pkg/errors"
+)
+
+// MaxNestingDepth is the maximum nesting of objects and arrays accepted in a
+// bundle document. Decoding deeper documents could exhaust the stack.
+const MaxNestingDepth = 64
+
+// MaxBundleSize is the maximum size of the bundle documents read from files,
+// URLs and registries.
+const MaxBundleSize = 64 << 20
+
+// Parse decodes a bundle document. It is safe to use on untrusted input:
+// malformed documents never make it panic nor exhaust the stack, an error is
+// returned instead. Documents which are not valid UTF-8 are rejected rather
+// than decoded with replacement characters.
+func Parse(data []byte) (*bundle.Bundle, error) {
+	return parse(context.Background(), data)
+}
+
+// parse is Parse, traced as a child of the span of the context.
+func parse(ctx context.Context, data []byte) (b *bundle.Bundle, err error) {
+	_, op := telemetry.Start(ctx, telemetry.SpanParse, telemetry.Fields{"size": len(data)})
+	started := time.Now()
+	defer func() {
+		op.End(err)
+		telemetry.Observe(telemetry.MetricParseDuration, time.Since(started).Seconds(), telemetry.Labels{"result": telemetry.Result(err, false)})
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			b, err = nil, fmt.Errorf("invalid bundle: %v", r)
+		}
+	}()
+	if err := checkDocument(data); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	b, err = bundle.Unmarshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	op.SetAttributes(telemetry.Fields{"bundle": b.Name, "version": b.Version})
+	return b, nil
+}
+
+// ParseReaderLimited decodes a bundle document from an untrusted source,
+// like a registry or an HTTP request. Documents larger than maxBytes are
+// rejected without being buffered entirely, and the custom extensions must
+// stay within DefaultCustomLimits.
+func ParseReaderLimited(r io.Reader, maxBytes int64) (*bundle.Bundle, error) {
+	return parseReaderLimited(context.Background(), r, maxBytes)
+}
+
+// ParseReaderContext is ParseReaderLimited, stopping to read when the
+// context is done, for instance to cancel slow network reads.
+func ParseReaderContext(ctx context.Context, r io.Reader, maxBytes int64) (*bundle.Bundle, error) {
+	return parseReaderLimited(ctx, &contextReader{ctx: ctx, r: r}, maxBytes)
+}
+
+func parseReaderLimited(ctx context.Context, r io.Reader, maxBytes int64) (*bundle.Bundle, error) {
+	data, err := ReadLimited(r, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	b, err := parse(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := DefaultCustomLimits.Check(b); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	return b, nil
+}
+
+// ReadLimited reads a bundle document, failing without buffering it entirely
+// if it is larger than maxBytes.
+func ReadLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bundle")
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("invalid bundle: larger than %d bytes", maxBytes)
+	}
+	return data, nil
+}
+
+// contextReader fails reads once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// checkDocument fails if the document is not valid UTF-8, which the JSON
+// decoder would silently replace, or if it is nested too deeply.
+func checkDocument(data []byte) error {
+	if !utf8.Valid(data) {
+		return errors.New("document is not valid UTF-8")
+	}
+	return checkNesting(data, MaxNestingDepth)
+}
+
+// CheckNesting fails if objects and arrays of a JSON document are nested
+// deeper than MaxNestingDepth, so it can be safely decoded.
+func CheckNesting(data []byte) error {
+	return checkNesting(data, MaxNestingDepth)
+}
+
+// checkNesting walks the JSON tokens of data, without recursion, and fails if
+// objects and arrays are nested deeper than maxDepth.
+func checkNesting(data []byte, maxDepth int) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > maxDepth {
+				return errors.Errorf("document is nested too deeply, maximum depth is %d", maxDepth)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package cnab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/app/pkg/telemetry/telemetrytest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParse(t *testing.T) {
+	b, err := Parse([]byte(testBundle))
+	assert.NilError(t, err)
+	assert.Equal(t, b.Name, "myapp")
+}
+
+func TestParseMalformedInput(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "empty", input: "", expected: "invalid bundle"},
+		{name: "truncated", input: `{"name":`, expected: "invalid bundle"},
+		{name: "wrong type", input: `{"name":["a"]}`, expected: "invalid bundle"},
+		{name: "wrong nested type", input: `{"images":{"a":{"size":-1}}}`, expected: "invalid bundle"},
+		{name: "huge number", input: `{"parameters":{"p":{"type":"int","minValue":1e400}}}`, expected: "invalid bundle"},
+		{name: "invalid UTF-8", input: "{\"name\":\"my\xffapp\"}", expected: "document is not valid UTF-8"},
+		{
+			name:     "deep nesting",
+			input:    `{"custom":{"a":` + strings.Repeat("[", 100000) + strings.Repeat("]", 100000) + "}}",
+			expected: "document is nested too deeply",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := Parse([]byte(tc.input))
+			assert.Check(t, is.ErrorContains(err, tc.expected))
+			assert.Check(t, b == nil)
+		})
+	}
+}
+
+func TestParseReaderLimited(t *testing.T) {
+	b, err := ParseReaderLimited(strings.NewReader(testBundle), int64(len(testBundle)))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Name, "myapp"))
+
+	_, err = ParseReaderLimited(strings.NewReader(testBundle), int64(len(testBundle)-1))
+	assert.Check(t, is.ErrorContains(err, fmt.Sprintf("larger than %d bytes", len(testBundle)-1)))
+
+	elements := strings.Repeat("1,", DefaultCustomLimits.MaxElements)
+	_, err = ParseReaderLimited(strings.NewReader(`{"custom":{"a":[`+elements+`1]}}`), 1<<20)
+	assert.Check(t, is.ErrorContains(err, "too many elements"))
+}
+
+func TestParseReaderContext(t *testing.T) {
+	b, err := ParseReaderContext(context.Background(), strings.NewReader(testBundle), 1<<20)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Name, "myapp"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ParseReaderContext(ctx, strings.NewReader(testBundle), 1<<20)
+	assert.Check(t, is.ErrorContains(err, "context canceled"))
+}
+
+func TestParseTraced(t *testing.T) {
+	recorder, restore := telemetrytest.Install()
+	defer restore()
+
+	ctx, op := telemetry.Start(context.Background(), "pull", nil)
+	_, err := ParseReaderContext(ctx, strings.NewReader(testBundle), 1<<20)
+	assert.NilError(t, err)
+	op.End(nil)
+	_, err = Parse([]byte(`{"name":`))
+	assert.Check(t, err != nil)
+
+	spans := recorder.Spans()
+	assert.Assert(t, is.Len(spans, 3))
+	assert.Check(t, is.Equal(spans[1].Name, telemetry.SpanParse))
+	assert.Check(t, is.Equal(spans[1].Parent, "pull"))
+	assert.Check(t, is.Equal(spans[1].Attributes["bundle"], "myapp"))
+	assert.Check(t, is.Equal(spans[2].Parent, ""))
+	assert.Check(t, is.ErrorContains(spans[2].Err, "invalid bundle"))
+
+	durations := recorder.Measures(telemetry.MetricParseDuration)
+	assert.Assert(t, is.Len(durations, 2))
+	assert.Check(t, is.Equal(durations[0].Labels["result"], telemetry.ResultSuccess))
+	assert.Check(t, is.Equal(durations[1].Labels["result"], telemetry.ResultFailure))
+}
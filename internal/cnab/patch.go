@@ -0,0 +1,409 @@
+package cnab
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
+)
+
+// ApplyPatch applies a patch to the canonical document of the bundle and
+// returns the patched bundle, once validated. The patch is either a JSON
+// patch (RFC 6902), an array of operations like
+//
+//	[{"op": "replace", "path": "/parameters/port/default", "value": 8080}]
+//
+// or a JSON merge patch (RFC 7386), an object like
+//
+//	{"images": {"web": {"digest": "sha256:..."}}}
+//
+// The bundle is left untouched.
+func ApplyPatch(b *bundle.Bundle, patch []byte) (*bundle.Bundle, error) {
+	if err := checkDocument(patch); err != nil {
+		return nil, errors.Wrap(err, "invalid patch")
+	}
+	data, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSONValue(data)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeJSONValue(patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid patch")
+	}
+	switch p := p.(type) {
+	case []interface{}:
+		if doc, err = applyJSONPatch(doc, p); err != nil {
+			return nil, err
+		}
+	case map[string]interface{}:
+		doc = applyMergePatch(doc, p)
+	default:
+		return nil, errors.New("invalid patch: expected a JSON patch array or a merge patch object")
+	}
+	if data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	patched, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "patched bundle")
+	}
+	if err := Validate(patched).Err(); err != nil {
+		return nil, errors.Wrap(err, "patched bundle is invalid")
+	}
+	return patched, nil
+}
+
+// decodeJSONValue decodes a JSON document keeping numbers as they are
+// written, so that the values the patch does not touch are not altered.
+func decodeJSONValue(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON document")
+	}
+	return value, nil
+}
+
+// applyMergePatch merges the patch into the target as described by RFC 7386:
+// null members are removed, objects are merged and other values replaced.
+func applyMergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = applyMergePatch(t[key], value)
+	}
+	return t
+}
+
+// patchOperation is an operation of a JSON patch.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies the operations of a JSON patch in order, as
+// described by RFC 6902. It stops at the first failing operation.
+func applyJSONPatch(doc interface{}, operations []interface{}) (interface{}, error) {
+	for i, raw := range operations {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		var op patchOperation
+		if err := json.Unmarshal(data, &op); err != nil {
+			return nil, errors.Wrapf(err, "invalid patch operation %d", i)
+		}
+		if doc, err = applyPatchOperation(doc, op); err != nil {
+			return nil, errors.Wrapf(err, "patch operation %d (%s)", i, op.Op)
+		}
+	}
+	return doc, nil
+}
+
+func applyPatchOperation(doc interface{}, op patchOperation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, errors.New("missing path")
+	}
+	path, err := parseJSONPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		value, err := decodeJSONValue(op.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid value")
+		}
+		switch op.Op {
+		case "add":
+			return addJSONValue(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, _, err = removeJSONValue(doc, path); err != nil {
+				return nil, err
+			}
+			return addJSONValue(doc, path, value)
+		default:
+			current, err := getJSONValue(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !jsonEqual(current, value) {
+				return nil, errors.Errorf("value at %q differs", *op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err := removeJSONValue(doc, path)
+		return doc, err
+	case "move", "copy":
+		if op.From == nil {
+			return nil, errors.New("missing from")
+		}
+		from, err := parseJSONPointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if op.Op == "move" {
+			if *op.Path == *op.From {
+				return doc, nil
+			}
+			if strings.HasPrefix(*op.Path, *op.From+"/") {
+				return nil, errors.Errorf("cannot move %q into itself", *op.From)
+			}
+			doc, value, err = removeJSONValue(doc, from)
+		} else {
+			value, err = getJSONValue(doc, from)
+			if err == nil {
+				value, err = copyJSONValue(value)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return addJSONValue(doc, path, value)
+	default:
+		return nil, errors.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// jsonPointer is a parsed JSON pointer (RFC 6901), the empty pointer
+// designating the whole document.
+type jsonPointer []string
+
+func parseJSONPointer(s string) (jsonPointer, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, errors.Errorf("invalid JSON pointer %q, it must start with /", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func (p jsonPointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteString("/")
+		b.WriteString(strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1))
+	}
+	return b.String()
+}
+
+// updateJSONParent calls update with the container holding the value the
+// pointer designates and the last token, replacing the container with the
+// one it returns.
+func updateJSONParent(node interface{}, path jsonPointer, depth int, update func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if depth == len(path)-1 {
+		return update(node, path[depth])
+	}
+	token := path[depth]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, errors.Errorf("%q does not exist", path[:depth+1])
+		}
+		child, err := updateJSONParent(child, path, depth+1, update)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n), false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q", path[:depth+1])
+		}
+		child, err := updateJSONParent(n[i], path, depth+1, update)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	default:
+		return nil, errors.Errorf("%q is not an object nor an array", path[:depth])
+	}
+}
+
+func getJSONValue(doc interface{}, path jsonPointer) (interface{}, error) {
+	if len(path) == 0 {
+		return doc, nil
+	}
+	var value interface{}
+	_, err := updateJSONParent(doc, path, 0, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			v, ok := c[token]
+			if !ok {
+				return nil, errors.Errorf("%q does not exist", path)
+			}
+			value = v
+		case []interface{}:
+			i, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%q", path)
+			}
+			value = c[i]
+		default:
+			return nil, errors.Errorf("%q does not exist", path)
+		}
+		return container, nil
+	})
+	return value, err
+}
+
+func addJSONValue(doc interface{}, path jsonPointer, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateJSONParent(doc, path, 0, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%q", path)
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		default:
+			return nil, errors.Errorf("%q is not an object nor an array", path[:len(path)-1])
+		}
+	})
+}
+
+func removeJSONValue(doc interface{}, path jsonPointer) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	var removed interface{}
+	doc, err := updateJSONParent(doc, path, 0, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			v, ok := c[token]
+			if !ok {
+				return nil, errors.Errorf("%q does not exist", path)
+			}
+			removed = v
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%q", path)
+			}
+			removed = c[i]
+			return append(c[:i], c[i+1:]...), nil
+		default:
+			return nil, errors.Errorf("%q does not exist", path)
+		}
+	})
+	return doc, removed, err
+}
+
+// arrayIndex parses an array index of a JSON pointer. The index equal to
+// the length, or "-", designates the end of the array, where values can
+// only be added.
+func arrayIndex(token string, length int, adding bool) (int, error) {
+	if adding && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, errors.Errorf("invalid array index %q", token)
+	}
+	if i > length || (i == length && !adding) {
+		return 0, errors.Errorf("array index %d is out of bounds", i)
+	}
+	return i, nil
+}
+
+func copyJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONValue(data)
+}
+
+// jsonEqual compares two decoded JSON values, numbers by value.
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okA := new(big.Float).SetString(a.String())
+		y, okB := new(big.Float).SetString(b.String())
+		return okA && okB && x.Cmp(y) == 0
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,79 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func patchableBundle() *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0"}}},
+		Images: map[string]bundle.Image{
+			"web": {BaseImage: bundle.BaseImage{ImageType: "docker", Image: "nginx:1.17"}},
+		},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"port": {DataType: "int", Default: 80},
+		},
+		Keywords: []string{"web", "demo"},
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	b := patchableBundle()
+	patched, err := ApplyPatch(b, []byte(`[
+		{"op": "test", "path": "/parameters/port/default", "value": 80.0},
+		{"op": "replace", "path": "/parameters/port/default", "value": 8080},
+		{"op": "add", "path": "/images/web/digest", "value": "sha256:2b2a1e1d3c26aafbb3bd5a3c4b0e3f8ad3e0c1c2b4a1a3e8c9d0f1a2b3c4d5e6"},
+		{"op": "add", "path": "/keywords/1", "value": "nginx"},
+		{"op": "move", "from": "/keywords/0", "path": "/keywords/-"},
+		{"op": "copy", "from": "/version", "path": "/description"},
+		{"op": "remove", "path": "/keywords/2"}
+	]`))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(patched.Parameters["port"].Default, 8080.0))
+	assert.Check(t, is.Equal(patched.Images["web"].Digest, "sha256:2b2a1e1d3c26aafbb3bd5a3c4b0e3f8ad3e0c1c2b4a1a3e8c9d0f1a2b3c4d5e6"))
+	assert.Check(t, is.DeepEqual(patched.Keywords, []string{"nginx", "demo"}))
+	assert.Check(t, is.Equal(patched.Description, "1.0.0"))
+	// The bundle is left untouched
+	assert.Check(t, is.DeepEqual(b, patchableBundle()))
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	patched, err := ApplyPatch(patchableBundle(), []byte(`{"parameters": {"port": {"default": 8080}}, "keywords": null, "description": "patched"}`))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(patched.Parameters["port"].Default, 8080.0))
+	assert.Check(t, is.Equal(patched.Parameters["port"].DataType, "int"))
+	assert.Check(t, is.Len(patched.Keywords, 0))
+	assert.Check(t, is.Equal(patched.Description, "patched"))
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{"not a patch", `"replace"`, "invalid patch: expected a JSON patch array or a merge patch object"},
+		{"malformed", `[{"op": "add"`, "invalid patch"},
+		{"unknown operation", `[{"op": "patch", "path": "/name"}]`, `patch operation 0 (patch): unknown operation "patch"`},
+		{"missing value", `[{"op": "add", "path": "/name"}]`, "patch operation 0 (add): missing value"},
+		{"missing target", `[{"op": "replace", "path": "/parameters/host/default", "value": "a"}]`, `patch operation 0 (replace): "/parameters/host" does not exist`},
+		{"out of bounds", `[{"op": "remove", "path": "/keywords/2"}]`, `patch operation 0 (remove): "/keywords/2": array index 2 is out of bounds`},
+		{"leading zero", `[{"op": "remove", "path": "/keywords/01"}]`, `invalid array index "01"`},
+		{"invalid pointer", `[{"op": "remove", "path": "keywords"}]`, `invalid JSON pointer "keywords"`},
+		{"failed test", `[{"op": "test", "path": "/version", "value": "2.0.0"}]`, `patch operation 0 (test): value at "/version" differs`},
+		{"move into itself", `[{"op": "move", "from": "/images", "path": "/images/web/images"}]`, `cannot move "/images" into itself`},
+		{"invalid bundle", `{"invocationImages": null}`, "patched bundle is invalid: $.invocationImages: at least one invocation image must be defined in the bundle"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ApplyPatch(patchableBundle(), []byte(tc.patch))
+			assert.Check(t, is.ErrorContains(err, tc.expected))
+		})
+	}
+}
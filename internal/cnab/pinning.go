@@ -0,0 +1,145 @@
+package cnab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ImageResolver resolves an image reference to the descriptor served by the
+// registry. It is implemented by the containerd remotes resolvers.
+type ImageResolver interface {
+	Resolve(ctx context.Context, ref string) (name string, desc ocispec.Descriptor, err error)
+}
+
+// ImageError reports an image of a bundle which could not be processed.
+type ImageError struct {
+	// Path is the JSON path of the image in the bundle.
+	Path  string
+	Image string
+	Err   error
+}
+
+func (e ImageError) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.Path, e.Image, e.Err)
+}
+
+// ImagesError lists the images of a bundle which could not be processed.
+type ImagesError []ImageError
+
+func (e ImagesError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// PinDigests looks up in the registry the digest and size of every image of
+// the bundle lacking a digest, and records them in the bundle. Images which
+// could not be resolved are reported in an ImagesError, the others are
+// pinned anyway. In offline mode, the images already pinned are kept and the
+// first offline.ErrOfflineMode of the resolver is returned. The images are
+// resolved concurrently, see ResolveOptions.
+func PinDigests(ctx context.Context, b *bundle.Bundle, resolver ImageResolver, opts ...func(*ResolveOptions)) error {
+	resolved, offlineErr := resolveImages(ctx, b, resolver, newResolveOptions(opts), func(image bundle.BaseImage) bool {
+		return image.Digest == ""
+	})
+	var errs ImagesError
+	forEachImage(b, func(path string, image *bundle.BaseImage) {
+		r, ok := resolved[image.Image]
+		if image.Digest != "" || !ok {
+			return
+		}
+		if r.err != nil {
+			errs = append(errs, ImageError{Path: path, Image: image.Image, Err: r.err})
+			return
+		}
+		image.Digest = r.desc.Digest.String()
+		image.Size = uint64(r.desc.Size)
+		if image.MediaType == "" {
+			image.MediaType = r.desc.MediaType
+		}
+	})
+	if offlineErr != nil {
+		return offlineErr
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// forEachImage calls fn with each invocation image and image of the bundle,
+// in a stable order. The changes fn makes to the image are kept.
+func forEachImage(b *bundle.Bundle, fn func(path string, image *bundle.BaseImage)) {
+	for i := range b.InvocationImages {
+		fn(fmt.Sprintf("$.invocationImages[%d]", i), &b.InvocationImages[i].BaseImage)
+	}
+	for _, name := range ImageKeys(b) {
+		image := b.Images[name]
+		fn(fmt.Sprintf("$.images[%q]", name), &image.BaseImage)
+		b.Images[name] = image
+	}
+}
+
+// resolveImage returns the descriptor the registry serves for the image
+// reference, by tag unless the reference is digested.
+func resolveImage(ctx context.Context, resolver ImageResolver, image string) (desc ocispec.Descriptor, err error) {
+	ctx, op := telemetry.Start(ctx, telemetry.SpanResolve, telemetry.Fields{"image": image})
+	defer func() { op.End(err) }()
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "invalid image reference")
+	}
+	_, desc, err = resolver.Resolve(ctx, reference.TagNameOnly(named).String())
+	if err != nil {
+		return ocispec.Descriptor{}, errors.Wrap(err, "failed to resolve image")
+	}
+	op.SetAttributes(telemetry.Fields{"digest": desc.Digest.String()})
+	return desc, nil
+}
+
+// VerifyImageDigests checks the digest declared by each image of the bundle
+// is the one the registry serves for its reference, so an image tag moved
+// since the bundle was built is detected. The mismatching images, and the
+// ones which could not be resolved, are reported in an ImagesError. Images
+// without a digest are not checked. The verification stops at the first
+// offline.ErrOfflineMode of the resolver. The images are resolved
+// concurrently, see ResolveOptions.
+func VerifyImageDigests(ctx context.Context, b *bundle.Bundle, resolver ImageResolver, opts ...func(*ResolveOptions)) error {
+	resolved, offlineErr := resolveImages(ctx, b, resolver, newResolveOptions(opts), func(image bundle.BaseImage) bool {
+		return image.Digest != ""
+	})
+	if offlineErr != nil {
+		return offlineErr
+	}
+	var errs ImagesError
+	forEachImage(b, func(path string, image *bundle.BaseImage) {
+		r, ok := resolved[image.Image]
+		if image.Digest == "" || !ok {
+			return
+		}
+		if r.err != nil {
+			errs = append(errs, ImageError{Path: path, Image: image.Image, Err: r.err})
+			return
+		}
+		if r.desc.Digest.String() != image.Digest {
+			errs = append(errs, ImageError{
+				Path:  path,
+				Image: image.Image,
+				Err:   errors.Errorf("digest mismatch, the bundle declares %s but the registry serves %s", image.Digest, r.desc.Digest),
+			})
+		}
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package cnab
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/offline"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// fakeResolver serves the descriptors by normalized reference.
+type fakeResolver map[string]ocispec.Descriptor
+
+func (r fakeResolver) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	desc, ok := r[ref]
+	if !ok {
+		return "", ocispec.Descriptor{}, errors.New("not found")
+	}
+	return ref, desc, nil
+}
+
+func TestPinDigests(t *testing.T) {
+	installer := digest.FromString("installer")
+	web := digest.FromString("web")
+	resolver := fakeResolver{
+		"docker.io/library/app-installer:latest": {Digest: installer, Size: 42, MediaType: ocispec.MediaTypeImageManifest},
+		"docker.io/library/nginx:1.17":           {Digest: web, Size: 1024, MediaType: ocispec.MediaTypeImageIndex},
+	}
+	b := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "app-installer"}},
+		},
+		Images: map[string]bundle.Image{
+			"web":     {BaseImage: bundle.BaseImage{Image: "nginx:1.17"}},
+			"pinned":  {BaseImage: bundle.BaseImage{Image: "redis:5", Digest: "sha256:abc"}},
+			"missing": {BaseImage: bundle.BaseImage{Image: "unknown:1.0"}},
+		},
+	}
+
+	err := PinDigests(context.Background(), b, resolver)
+	assert.Error(t, err, `$.images["missing"] "unknown:1.0": failed to resolve image: not found`)
+	assert.Check(t, is.DeepEqual(b.InvocationImages[0].BaseImage, bundle.BaseImage{
+		Image:     "app-installer",
+		Digest:    installer.String(),
+		Size:      42,
+		MediaType: ocispec.MediaTypeImageManifest,
+	}))
+	assert.Check(t, is.Equal(b.Images["web"].Digest, web.String()))
+	assert.Check(t, is.Equal(b.Images["web"].Size, uint64(1024)))
+	assert.Check(t, is.Equal(b.Images["pinned"].Digest, "sha256:abc"))
+	assert.Check(t, is.Equal(b.Images["missing"].Digest, ""))
+}
+
+func TestVerifyImageDigests(t *testing.T) {
+	installer := digest.FromString("installer")
+	moved := digest.FromString("moved")
+	resolver := fakeResolver{
+		"docker.io/library/app-installer:0.1.0": {Digest: installer},
+		"docker.io/library/nginx:1.17":          {Digest: moved},
+	}
+	b := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "app-installer:0.1.0", Digest: installer.String()}},
+		},
+		Images: map[string]bundle.Image{
+			"web":      {BaseImage: bundle.BaseImage{Image: "nginx:1.17", Digest: digest.FromString("web").String()}},
+			"unpinned": {BaseImage: bundle.BaseImage{Image: "redis:5"}},
+		},
+	}
+	err := VerifyImageDigests(context.Background(), b, resolver)
+	assert.Assert(t, is.ErrorContains(err, ""))
+	errs := err.(ImagesError)
+	assert.Assert(t, is.Len(errs, 1))
+	assert.Check(t, is.Equal(errs[0].Path, `$.images["web"]`))
+	assert.Check(t, is.ErrorContains(errs[0], "digest mismatch"))
+
+	b.Images["web"] = bundle.Image{BaseImage: bundle.BaseImage{Image: "nginx:1.17", Digest: moved.String()}}
+	assert.NilError(t, VerifyImageDigests(context.Background(), b, resolver))
+}
+
+// offlineResolver fails as the registry client does in offline mode.
+type offlineResolver struct {
+	calls int
+}
+
+func (r *offlineResolver) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	r.calls++
+	return "", ocispec.Descriptor{}, offline.New("reaching the registry of %q", ref)
+}
+
+func TestPinDigestsOffline(t *testing.T) {
+	b := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "app-installer"}},
+		},
+		Images: map[string]bundle.Image{
+			"web":    {BaseImage: bundle.BaseImage{Image: "nginx:1.17"}},
+			"pinned": {BaseImage: bundle.BaseImage{Image: "redis:5", Digest: "sha256:abc"}},
+		},
+	}
+	resolver := &offlineResolver{}
+	err := PinDigests(context.Background(), b, resolver)
+	assert.Check(t, offline.IsOffline(err))
+	// It fails fast, keeping the images already pinned
+	assert.Check(t, is.Equal(resolver.calls, 1))
+	assert.Check(t, is.Equal(b.Images["pinned"].Digest, "sha256:abc"))
+
+	resolver = &offlineResolver{}
+	err = VerifyImageDigests(context.Background(), b, resolver)
+	assert.Check(t, offline.IsOffline(err))
+	assert.Check(t, is.Equal(resolver.calls, 1))
+}
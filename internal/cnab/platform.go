@@ -0,0 +1,58 @@
+package cnab
+
+import (
+	"fmt"
+
+	"github.com/deislabs/cnab-go/bundle"
+)
+
+// SelectInvocationImage returns the invocation image best matching the
+// platform: the image built for the exact operating system and architecture,
+// then an image built for the operating system and any architecture, then an
+// image without platform. Among images matching equally, the first one is
+// selected.
+func SelectInvocationImage(b *bundle.Bundle, os, arch string) (bundle.InvocationImage, error) {
+	best, bestScore := -1, 0
+	for i, img := range b.InvocationImages {
+		if score := platformScore(img.Platform, os, arch); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return bundle.InvocationImage{}, withSentinel(ErrMissingInvocationImage, "bundle %q has no invocation image for platform %s/%s", b.Name, os, arch)
+	}
+	return b.InvocationImages[best], nil
+}
+
+// platformScore rates how well a platform matches, 0 meaning it does not.
+func platformScore(p *bundle.ImagePlatform, os, arch string) int {
+	switch {
+	case p == nil || (p.OS == "" && p.Architecture == ""):
+		return 1
+	case p.OS != "" && p.OS != os:
+		return 0
+	case p.Architecture == "":
+		return 2
+	case p.Architecture != arch:
+		return 0
+	case p.OS == "":
+		return 2
+	default:
+		return 3
+	}
+}
+
+// platformName names the platform of an image, for messages.
+func platformName(p *bundle.ImagePlatform) string {
+	if p == nil || (p.OS == "" && p.Architecture == "") {
+		return "any platform"
+	}
+	os, arch := p.OS, p.Architecture
+	if os == "" {
+		os = "*"
+	}
+	if arch == "" {
+		arch = "*"
+	}
+	return fmt.Sprintf("%s/%s", os, arch)
+}
@@ -0,0 +1,61 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func invocationImage(image, os, arch string) bundle.InvocationImage {
+	img := bundle.InvocationImage{BaseImage: bundle.BaseImage{ImageType: "docker", Image: image}}
+	if os != "" || arch != "" {
+		img.Platform = &bundle.ImagePlatform{OS: os, Architecture: arch}
+	}
+	return img
+}
+
+func TestSelectInvocationImage(t *testing.T) {
+	b := &bundle.Bundle{
+		Name: "app",
+		InvocationImages: []bundle.InvocationImage{
+			invocationImage("generic:1", "", ""),
+			invocationImage("linux:1", "linux", ""),
+			invocationImage("linux-arm64:1", "linux", "arm64"),
+			invocationImage("windows-amd64:1", "windows", "amd64"),
+		},
+	}
+	for _, tc := range []struct{ os, arch, expected string }{
+		{"linux", "arm64", "linux-arm64:1"},
+		{"linux", "amd64", "linux:1"},
+		{"windows", "amd64", "windows-amd64:1"},
+		{"windows", "arm64", "generic:1"},
+		{"darwin", "amd64", "generic:1"},
+	} {
+		img, err := SelectInvocationImage(b, tc.os, tc.arch)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(img.Image, tc.expected), "%s/%s", tc.os, tc.arch)
+	}
+
+	b.InvocationImages = b.InvocationImages[2:]
+	_, err := SelectInvocationImage(b, "darwin", "amd64")
+	assert.Check(t, is.Error(err, `bundle "app" has no invocation image for platform darwin/amd64`))
+}
+
+func TestValidateDuplicatePlatforms(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:    "app",
+		Version: "1.0.0",
+		InvocationImages: []bundle.InvocationImage{
+			invocationImage("generic:1", "", ""),
+			invocationImage("linux-arm64:1", "linux", "arm64"),
+			invocationImage("other:1", "linux", "arm64"),
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "empty:1", Platform: &bundle.ImagePlatform{}}},
+		},
+	}
+	errs := Validate(b)
+	assert.Check(t, is.Len(errs, 2))
+	assert.Check(t, is.Equal(errs[0].Error(), "$.invocationImages[2].platform: invocation images 1 and 2 are both built for linux/arm64"))
+	assert.Check(t, is.Equal(errs[1].Error(), "$.invocationImages[3].platform: invocation images 0 and 3 are both built for any platform"))
+}
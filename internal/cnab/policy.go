@@ -0,0 +1,85 @@
+package cnab
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+)
+
+// Codes of the violations of the policy rules provided by this package.
+const (
+	CodeMaintainerDomain = "maintainer-domain"
+	CodeRequiredKeyword  = "required-keyword"
+)
+
+// PolicyRule is a rule an organization enforces on the bundles it accepts,
+// for instance before publishing them in its catalog.
+type PolicyRule interface {
+	// Check returns the violations of the rule by the bundle.
+	Check(b *bundle.Bundle) ValidationErrors
+}
+
+// PolicyRuleFunc adapts a function to a PolicyRule.
+type PolicyRuleFunc func(b *bundle.Bundle) ValidationErrors
+
+// Check calls f(b).
+func (f PolicyRuleFunc) Check(b *bundle.Bundle) ValidationErrors {
+	return f(b)
+}
+
+// Policy is a set of rules.
+type Policy []PolicyRule
+
+// ValidatePolicy returns the violations of all the rules of the policy by
+// the bundle, in the order of the rules. Their Err method returns an error
+// if any of them is an error.
+func ValidatePolicy(b *bundle.Bundle, policy Policy) ValidationErrors {
+	var errs ValidationErrors
+	for _, rule := range policy {
+		errs = append(errs, rule.Check(b)...)
+	}
+	return errs
+}
+
+// RequireMaintainerDomain is a rule requiring at least one maintainer with
+// an email address of one of the given domains, like a corporate domain.
+func RequireMaintainerDomain(domains ...string) PolicyRule {
+	return PolicyRuleFunc(func(b *bundle.Bundle) ValidationErrors {
+		for _, m := range b.Maintainers {
+			at := strings.LastIndex(m.Email, "@")
+			if at < 0 {
+				continue
+			}
+			for _, domain := range domains {
+				if strings.EqualFold(m.Email[at+1:], domain) {
+					return nil
+				}
+			}
+		}
+		return ValidationErrors{{
+			Path:     "$.maintainers",
+			Code:     CodeMaintainerDomain,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("at least one maintainer must have an email address of %s", strings.Join(domains, " or ")),
+		}}
+	})
+}
+
+// RequireKeywordPrefix is a rule requiring at least one keyword starting
+// with the prefix, like a "team:" tag naming the owners of the bundle.
+func RequireKeywordPrefix(prefix string) PolicyRule {
+	return PolicyRuleFunc(func(b *bundle.Bundle) ValidationErrors {
+		for _, keyword := range b.Keywords {
+			if strings.HasPrefix(keyword, prefix) && len(keyword) > len(prefix) {
+				return nil
+			}
+		}
+		return ValidationErrors{{
+			Path:     "$.keywords",
+			Code:     CodeRequiredKeyword,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("the keywords must include a %q tag", prefix),
+		}}
+	})
+}
@@ -0,0 +1,40 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	policy := Policy{
+		RequireMaintainerDomain("example.com", "example.org"),
+		RequireKeywordPrefix("team:"),
+		PolicyRuleFunc(func(b *bundle.Bundle) ValidationErrors {
+			if b.Description == "" {
+				return ValidationErrors{{Path: "$.description", Code: "description", Severity: SeverityWarning, Message: "the description should be set"}}
+			}
+			return nil
+		}),
+	}
+	b := &bundle.Bundle{
+		Name:        "app",
+		Maintainers: []bundle.Maintainer{{Name: "Jane", Email: "jane@gmail.com"}},
+		Keywords:    []string{"team:"},
+	}
+	errs := ValidatePolicy(b, policy)
+	assert.Assert(t, is.Len(errs, 3))
+	assert.Check(t, is.Error(errs.Err(), `$.maintainers: at least one maintainer must have an email address of example.com or example.org
+$.keywords: the keywords must include a "team:" tag`))
+	assert.Check(t, is.Len(errs.Warnings(), 1))
+
+	b.Maintainers = append(b.Maintainers, bundle.Maintainer{Name: "Ops", Email: "ops@Example.ORG"})
+	b.Keywords = append(b.Keywords, "team:payments")
+	b.Description = "An app"
+	assert.Check(t, is.Len(ValidatePolicy(b, policy), 0))
+
+	// An empty policy accepts any bundle
+	assert.Check(t, is.Len(ValidatePolicy(&bundle.Bundle{}, nil), 0))
+}
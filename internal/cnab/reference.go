@@ -0,0 +1,92 @@
+package cnab
+
+import (
+	"fmt"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// ImageReference is the structured form of an image reference.
+type ImageReference struct {
+	// Registry is the registry domain, "docker.io" by default.
+	Registry string
+	// Repository is the repository path in the registry, like
+	// "library/nginx".
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// String returns the reference in its normalized form.
+func (r ImageReference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// ParseImageReference parses an image reference, which must have a tag or a
+// digest, so the image it refers to is explicit.
+func ParseImageReference(image string) (ImageReference, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ImageReference{}, err
+	}
+	ref := ImageReference{
+		Registry:   reference.Domain(named),
+		Repository: reference.Path(named),
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		ref.Tag = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		ref.Digest = digested.Digest().String()
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		return ImageReference{}, errors.New("tag or digest is required")
+	}
+	return ref, nil
+}
+
+// ParsedReference returns the structured reference of an invocation image.
+func ParsedReference(img bundle.InvocationImage) (ImageReference, error) {
+	return ParseImageReference(img.Image)
+}
+
+// ValidateImageReferences checks the references of the docker and OCI
+// images of the bundle are well formed. Invocation images must also have a
+// tag or a digest, while images default to the "latest" tag.
+func ValidateImageReferences(b *bundle.Bundle) error {
+	var errs ImagesError
+	for i, img := range b.InvocationImages {
+		if !isDockerish(img.BaseImage) {
+			continue
+		}
+		if _, err := ParsedReference(img); err != nil {
+			errs = append(errs, ImageError{Path: fmt.Sprintf("$.invocationImages[%d]", i), Image: img.Image, Err: err})
+		}
+	}
+	for _, name := range ImageKeys(b) {
+		img := b.Images[name]
+		if !isDockerish(img.BaseImage) {
+			continue
+		}
+		if _, err := reference.ParseNormalizedNamed(img.Image); err != nil {
+			errs = append(errs, ImageError{Path: fmt.Sprintf("$.images[%q]", name), Image: img.Image, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func isDockerish(image bundle.BaseImage) bool {
+	return image.ImageType == "docker" || image.ImageType == "oci"
+}
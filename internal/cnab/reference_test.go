@@ -0,0 +1,56 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParseImageReference(t *testing.T) {
+	const digest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+	for _, tc := range []struct {
+		image    string
+		expected ImageReference
+		err      string
+	}{
+		{image: "nginx:1.17", expected: ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.17"}},
+		{image: "localhost:5000/app/installer:0.1.0", expected: ImageReference{Registry: "localhost:5000", Repository: "app/installer", Tag: "0.1.0"}},
+		{image: "example.com/app@" + digest, expected: ImageReference{Registry: "example.com", Repository: "app", Digest: digest}},
+		{image: "example.com/app:0.1.0@" + digest, expected: ImageReference{Registry: "example.com", Repository: "app", Tag: "0.1.0", Digest: digest}},
+		{image: "nginx", err: "tag or digest is required"},
+		{image: "nginx:", err: "invalid reference format"},
+		{image: "Example.com/App:1.0", err: "invalid reference format"},
+	} {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := ParseImageReference(tc.image)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Check(t, is.DeepEqual(ref, tc.expected))
+		})
+	}
+}
+
+func TestValidateImageReferences(t *testing.T) {
+	b := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app-installer"}},
+			{BaseImage: bundle.BaseImage{ImageType: "other", Image: "whatever"}},
+		},
+		Images: map[string]bundle.Image{
+			"web":    {BaseImage: bundle.BaseImage{ImageType: "docker", Image: "nginx"}},
+			"broken": {BaseImage: bundle.BaseImage{ImageType: "oci", Image: "nginx:"}},
+		},
+	}
+	err := ValidateImageReferences(b)
+	assert.Error(t, err, `$.invocationImages[0] "app-installer": tag or digest is required
+$.images["broken"] "nginx:": invalid reference format`)
+
+	b.InvocationImages[0].Image = "app-installer:0.1.0"
+	delete(b.Images, "broken")
+	assert.NilError(t, ValidateImageReferences(b))
+}
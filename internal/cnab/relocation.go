@@ -0,0 +1,102 @@
+package cnab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// RelocationMap maps the original image references of a bundle to the
+// references of their copies, when the images were copied to another
+// registry. It is serialized as the CNAB relocation mapping JSON object.
+type RelocationMap map[string]string
+
+// LoadRelocationMap reads a relocation mapping file.
+func LoadRelocationMap(path string) (RelocationMap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m RelocationMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrapf(err, "invalid relocation mapping %q", path)
+	}
+	return m, nil
+}
+
+// Save writes the relocation mapping file.
+func (m RelocationMap) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Relocate returns a copy of the bundle whose invocation images and images
+// references are rewritten with the relocation map. The references before
+// relocation are kept as original images, unless already set by a previous
+// relocation. The given bundle is not modified.
+func Relocate(b *bundle.Bundle, m RelocationMap) *bundle.Bundle {
+	relocated := DeepCopy(b)
+	for i, image := range relocated.InvocationImages {
+		relocated.InvocationImages[i].BaseImage = m.relocate(image.BaseImage)
+	}
+	for name, image := range relocated.Images {
+		image.BaseImage = m.relocate(image.BaseImage)
+		relocated.Images[name] = image
+	}
+	return relocated
+}
+
+func (m RelocationMap) relocate(image bundle.BaseImage) bundle.BaseImage {
+	target, ok := m[image.Image]
+	if !ok || target == image.Image {
+		return image
+	}
+	if image.OriginalImage == "" {
+		image.OriginalImage = image.Image
+	}
+	image.Image = target
+	return image
+}
+
+// ValidateRelocation checks the relocation map keeps the content of the
+// non-container artifacts of the bundle: their relocated references must
+// not pin another digest, and the ones of plain blobs, which cannot be
+// tagged, must pin their digest.
+func ValidateRelocation(b *bundle.Bundle, m RelocationMap) error {
+	var errs ImagesError
+	for _, name := range ImageKeys(b) {
+		img := b.Images[name]
+		kind := ArtifactKindOf(img.MediaType)
+		target, ok := m[img.Image]
+		if kind == ArtifactContainerImage || !ok {
+			continue
+		}
+		path := fmt.Sprintf("$.images[%q]", name)
+		named, err := reference.ParseNormalizedNamed(target)
+		if err != nil {
+			errs = append(errs, ImageError{Path: path, Image: img.Image, Err: errors.Wrapf(err, "invalid relocated reference %q", target)})
+			continue
+		}
+		var actual string
+		if digested, ok := named.(reference.Digested); ok {
+			actual = digested.Digest().String()
+		}
+		switch expected := artifactDigest(img.BaseImage); {
+		case actual != "" && expected != "" && actual != expected:
+			errs = append(errs, ImageError{Path: path, Image: img.Image, Err: errors.Errorf("relocated reference %q does not match the digest %s of the %s", target, expected, kind)})
+		case actual == "" && kind == ArtifactBlob:
+			errs = append(errs, ImageError{Path: path, Image: img.Image, Err: errors.Errorf("relocated reference %q of the blob must be referenced by digest", target)})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
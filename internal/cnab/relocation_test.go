@@ -0,0 +1,86 @@
+package cnab
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestRelocate(t *testing.T) {
+	b := &bundle.Bundle{
+		Name: "app",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "app-installer:0.1.0"}},
+		},
+		Images: map[string]bundle.Image{
+			"web":   {BaseImage: bundle.BaseImage{Image: "nginx:1.17", OriginalImage: "docker.io/library/nginx:1.17"}},
+			"cache": {BaseImage: bundle.BaseImage{Image: "redis:5"}},
+		},
+	}
+	m := RelocationMap{
+		"app-installer:0.1.0": "registry.local/app-installer:0.1.0",
+		"nginx:1.17":          "registry.local/nginx:1.17",
+	}
+	relocated := Relocate(b, m)
+
+	assert.Check(t, is.DeepEqual(relocated.InvocationImages[0].BaseImage, bundle.BaseImage{
+		Image:         "registry.local/app-installer:0.1.0",
+		OriginalImage: "app-installer:0.1.0",
+	}))
+	assert.Check(t, is.DeepEqual(relocated.Images["web"].BaseImage, bundle.BaseImage{
+		Image:         "registry.local/nginx:1.17",
+		OriginalImage: "docker.io/library/nginx:1.17",
+	}))
+	assert.Check(t, is.DeepEqual(relocated.Images["cache"].BaseImage, bundle.BaseImage{Image: "redis:5"}))
+	// The original bundle is left untouched
+	assert.Check(t, is.Equal(b.InvocationImages[0].Image, "app-installer:0.1.0"))
+	assert.Check(t, is.Equal(b.Images["web"].Image, "nginx:1.17"))
+}
+
+func TestRelocationMapSaveLoad(t *testing.T) {
+	dir := fs.NewDir(t, "relocation")
+	defer dir.Remove()
+	path := filepath.Join(dir.Path(), "relocation-mapping.json")
+
+	m := RelocationMap{"nginx:1.17": "registry.local/nginx:1.17"}
+	assert.NilError(t, m.Save(path))
+	loaded, err := LoadRelocationMap(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(loaded, m))
+
+	dir = fs.NewDir(t, "relocation", fs.WithFile("invalid.json", "[]"))
+	defer dir.Remove()
+	_, err = LoadRelocationMap(dir.Join("invalid.json"))
+	assert.ErrorContains(t, err, "invalid relocation mapping")
+}
+
+func TestValidateRelocation(t *testing.T) {
+	b := &bundle.Bundle{
+		Name: "app",
+		Images: map[string]bundle.Image{
+			"web":   {BaseImage: bundle.BaseImage{Image: "nginx:1.17"}},
+			"chart": {BaseImage: bundle.BaseImage{ImageType: "oci", Image: "charts/wordpress:8.1.0", MediaType: MediaTypeHelmChart, Digest: testBlobDigest}},
+			"seed":  {BaseImage: bundle.BaseImage{Image: "data/seed@" + testBlobDigest, MediaType: MediaTypeOctetStream}},
+		},
+	}
+	assert.NilError(t, ValidateRelocation(b, RelocationMap{
+		"nginx:1.17":                  "registry.local/nginx:1.17",
+		"charts/wordpress:8.1.0":      "registry.local/charts/wordpress:8.1.0",
+		"data/seed@" + testBlobDigest: "registry.local/data/seed@" + testBlobDigest,
+	}))
+
+	other := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	err := ValidateRelocation(b, RelocationMap{
+		"charts/wordpress:8.1.0":      "registry.local/charts/wordpress@" + other,
+		"data/seed@" + testBlobDigest: "registry.local/data/seed:latest",
+	})
+	assert.Check(t, is.ErrorContains(err, `relocated reference "registry.local/charts/wordpress@`+other+`" does not match the digest `+testBlobDigest+` of the helm-chart`))
+	assert.Check(t, is.ErrorContains(err, `relocated reference "registry.local/data/seed:latest" of the blob must be referenced by digest`))
+
+	err = ValidateRelocation(b, RelocationMap{"charts/wordpress:8.1.0": "Invalid Reference"})
+	assert.Check(t, is.ErrorContains(err, `invalid relocated reference "Invalid Reference"`))
+}
@@ -0,0 +1,203 @@
+// Package repo maintains an index of published bundles, to host a catalog of
+// bundles and look them up by name, keyword or version.
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/semver"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/pkg/errors"
+)
+
+// APIVersion is the version of the index format.
+const APIVersion = "v1"
+
+// Entry is a published version of a bundle.
+type Entry struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Description string   `json:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	// Digest is the digest of the canonical bundle document, see cnab.Digest.
+	Digest string `json:"digest"`
+	// Reference is where the bundle is published, like a registry reference.
+	Reference string `json:"reference,omitempty"`
+}
+
+// Index lists the published versions of bundles, keyed by bundle name.
+type Index struct {
+	APIVersion string             `json:"apiVersion"`
+	Entries    map[string][]Entry `json:"entries"`
+	// Policy is enforced on the bundles added to the index, if set. It is
+	// not part of the index document.
+	Policy cnab.Policy `json:"-"`
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{APIVersion: APIVersion, Entries: map[string][]Entry{}}
+}
+
+// Add indexes a version of a bundle, published at the given reference. The
+// version must be a semantic version, and cannot be indexed twice with a
+// different content. Bundles violating the policy of the index are rejected.
+func (i *Index) Add(b *bundle.Bundle, reference string) error {
+	if err := cnab.ValidateVersion(b); err != nil {
+		return err
+	}
+	if err := cnab.ValidatePolicy(b, i.Policy).Err(); err != nil {
+		return errors.Wrapf(err, "bundle %q %s is rejected by the index policy", b.Name, b.Version)
+	}
+	digest, err := cnab.Digest(b)
+	if err != nil {
+		return err
+	}
+	entry := Entry{
+		Name:        b.Name,
+		Version:     b.Version,
+		Description: b.Description,
+		Keywords:    append([]string(nil), b.Keywords...),
+		Digest:      digest,
+		Reference:   reference,
+	}
+	entries := i.Entries[b.Name]
+	for n, existing := range entries {
+		if existing.Version != b.Version {
+			continue
+		}
+		if existing.Digest != digest {
+			return errors.Errorf("version %s of bundle %q is already indexed with digest %s", b.Version, b.Name, existing.Digest)
+		}
+		entries[n] = entry
+		return nil
+	}
+	entries = append(entries, entry)
+	sortEntries(entries)
+	i.Entries[b.Name] = entries
+	return nil
+}
+
+// Search returns the latest version of the bundles whose name, description
+// or keywords contain the query, ignoring case, sorted by name. An empty
+// query matches all the bundles.
+func (i *Index) Search(query string) []Entry {
+	query = strings.ToLower(query)
+	var results []Entry
+	for _, entries := range i.Entries {
+		if len(entries) == 0 {
+			continue
+		}
+		latest := entries[0]
+		if matches(latest, query) {
+			results = append(results, latest)
+		}
+	}
+	sort.Slice(results, func(a, b int) bool { return results[a].Name < results[b].Name })
+	return results
+}
+
+func matches(e Entry, query string) bool {
+	if strings.Contains(strings.ToLower(e.Name), query) || strings.Contains(strings.ToLower(e.Description), query) {
+		return true
+	}
+	for _, keyword := range e.Keywords {
+		if strings.Contains(strings.ToLower(keyword), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLatest returns the latest version of a bundle in the version range,
+// like "^1.2" or ">=1.0.0 <2.0.0". Prerelease versions only match an empty
+// range, which matches all versions.
+func (i *Index) GetLatest(name, constraint string) (Entry, error) {
+	entries, ok := i.Entries[name]
+	if !ok || len(entries) == 0 {
+		return Entry{}, errors.Errorf("bundle %q is not indexed", name)
+	}
+	if constraint == "" {
+		return entries[0], nil
+	}
+	r, err := semver.ParseRange(constraint)
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		v, err := semver.Parse(e.Version)
+		if err != nil {
+			continue
+		}
+		if r.Contains(v, false) {
+			return e, nil
+		}
+	}
+	return Entry{}, errors.Errorf("no version of bundle %q matches %q", name, constraint)
+}
+
+// sortEntries sorts the entries from the latest version to the oldest.
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(a, b int) bool {
+		va, errA := semver.Parse(entries[a].Version)
+		vb, errB := semver.Parse(entries[b].Version)
+		if errA != nil || errB != nil {
+			return errB != nil && errA == nil
+		}
+		return va.Compare(vb) > 0
+	})
+}
+
+// Load reads an index.
+func Load(r io.Reader) (*Index, error) {
+	var index Index
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle index")
+	}
+	if index.APIVersion != APIVersion {
+		return nil, errors.Errorf("unsupported bundle index version %q", index.APIVersion)
+	}
+	if index.Entries == nil {
+		index.Entries = map[string][]Entry{}
+	}
+	for _, entries := range index.Entries {
+		sortEntries(entries)
+	}
+	return &index, nil
+}
+
+// LoadFile reads an index file.
+func LoadFile(path string) (*Index, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	index, err := Load(bytes.NewReader(data))
+	return index, errors.Wrapf(err, "failed to load %q", path)
+}
+
+// WriteTo writes the index as JSON.
+func (i *Index) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// WriteFile writes the index to a file atomically.
+func (i *Index) WriteFile(path string) error {
+	buf := bytes.NewBuffer(nil)
+	if _, err := i.WriteTo(buf); err != nil {
+		return err
+	}
+	return ioutils.AtomicWriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func testIndex(t *testing.T) *Index {
+	index := NewIndex()
+	for _, version := range []string{"1.0.0", "1.2.0", "2.0.0-beta.1", "1.10.0"} {
+		b := bundletest.NewTestBundle(bundletest.WithName("shop"), bundletest.WithVersion(version))
+		b.Keywords = []string{"ecommerce"}
+		assert.NilError(t, index.Add(b, "example.com/shop:"+version))
+	}
+	b := bundletest.NewTestBundle(bundletest.WithName("blog"))
+	b.Description = "A simple Blog"
+	assert.NilError(t, index.Add(b, "example.com/blog:0.1.0"))
+	return index
+}
+
+func TestIndexAdd(t *testing.T) {
+	index := testIndex(t)
+	var versions []string
+	for _, e := range index.Entries["shop"] {
+		versions = append(versions, e.Version)
+	}
+	assert.Check(t, is.DeepEqual(versions, []string{"2.0.0-beta.1", "1.10.0", "1.2.0", "1.0.0"}))
+
+	// Adding the same content again is fine, a different one is not
+	b := bundletest.NewTestBundle(bundletest.WithName("blog"))
+	b.Description = "A simple Blog"
+	assert.NilError(t, index.Add(b, "example.com/blog:0.1.0"))
+	b.Description = "Changed"
+	assert.Check(t, is.ErrorContains(index.Add(b, ""), `version 0.1.0 of bundle "blog" is already indexed with digest sha256:`))
+
+	assert.Check(t, is.ErrorContains(index.Add(bundletest.NewTestBundle(bundletest.WithVersion("latest")), ""), "invalid version"))
+}
+
+func TestIndexAddPolicy(t *testing.T) {
+	index := NewIndex()
+	index.Policy = cnab.Policy{cnab.RequireKeywordPrefix("team:")}
+	b := bundletest.NewTestBundle(bundletest.WithName("shop"))
+	err := index.Add(b, "")
+	assert.Check(t, is.ErrorContains(err, `bundle "shop" 0.1.0 is rejected by the index policy: $.keywords: the keywords must include a "team:" tag`))
+	assert.Check(t, is.Len(index.Entries, 0))
+
+	b.Keywords = []string{"team:shop"}
+	assert.NilError(t, index.Add(b, ""))
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := testIndex(t)
+	var names []string
+	for _, e := range index.Search("") {
+		names = append(names, e.Name+":"+e.Version)
+	}
+	assert.Check(t, is.DeepEqual(names, []string{"blog:0.1.0", "shop:2.0.0-beta.1"}))
+	assert.Check(t, is.Len(index.Search("blog"), 1))
+	assert.Check(t, is.Len(index.Search("SIMPLE"), 1))
+	assert.Check(t, is.Len(index.Search("commerce"), 1))
+	assert.Check(t, is.Len(index.Search("nothing"), 0))
+}
+
+func TestIndexGetLatest(t *testing.T) {
+	index := testIndex(t)
+	for constraint, expected := range map[string]string{
+		"":        "2.0.0-beta.1",
+		">=1.0.0": "1.10.0",
+		"~1.2":    "1.2.0",
+		"<1.1.0":  "1.0.0",
+	} {
+		e, err := index.GetLatest("shop", constraint)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(e.Version, expected), constraint)
+	}
+	_, err := index.GetLatest("shop", "^3")
+	assert.Check(t, is.ErrorContains(err, `no version of bundle "shop" matches "^3"`))
+	_, err = index.GetLatest("unknown", "")
+	assert.Check(t, is.ErrorContains(err, `bundle "unknown" is not indexed`))
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	index := testIndex(t)
+	dir := fs.NewDir(t, "repo")
+	defer dir.Remove()
+	assert.NilError(t, index.WriteFile(dir.Join("index.json")))
+	loaded, err := LoadFile(dir.Join("index.json"))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(loaded, index))
+
+	_, err = Load(bytes.NewBufferString(`{"apiVersion": "v2"}`))
+	assert.Check(t, is.ErrorContains(err, `unsupported bundle index version "v2"`))
+}
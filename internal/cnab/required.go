@@ -0,0 +1,64 @@
+package cnab
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// RequiredExtensionsKey is the custom extension listing the extensions a
+// runtime must implement to run the bundle. The bundle type of the vendored
+// cnab-go library has no requiredExtensions field, so they are declared as
+// an extension.
+const RequiredExtensionsKey = internal.Namespace + "required-extensions"
+
+// SupportedExtensions lists the extensions docker app honors when running a
+// bundle.
+var SupportedExtensions = []string{
+	ActionArgumentsExtensionKey,
+	ChangelogExtensionKey,
+	CredentialsExtensionKey,
+	OutputsExtensionKey,
+	ParameterSchemasExtensionKey,
+	ParameterSourcesExtensionKey,
+	RequirementsExtensionKey,
+	SensitiveParametersExtensionKey,
+	UpgradeFromExtensionKey,
+}
+
+// RequiredExtensions returns the extensions required by the bundle.
+func RequiredExtensions(b *bundle.Bundle) ([]string, error) {
+	var required []string
+	if _, err := GetCustomExtension(b, RequiredExtensionsKey, &required); err != nil {
+		return nil, err
+	}
+	return required, nil
+}
+
+// CheckRequiredExtensions returns an error if the bundle requires extensions
+// which are not in the supported ones, rather than running the bundle while
+// ignoring the extensions.
+func CheckRequiredExtensions(b *bundle.Bundle, supported []string) error {
+	required, err := RequiredExtensions(b)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, ext := range supported {
+		known[ext] = true
+	}
+	var missing []string
+	for _, ext := range required {
+		if !known[ext] {
+			missing = append(missing, ext)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return errors.Errorf("bundle %q requires unsupported extensions: %s", b.Name, strings.Join(missing, ", "))
+}
@@ -0,0 +1,27 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+)
+
+func TestCheckRequiredExtensions(t *testing.T) {
+	b := &bundle.Bundle{Name: "app"}
+	assert.NilError(t, CheckRequiredExtensions(b, nil))
+
+	b.Custom = map[string]interface{}{
+		RequiredExtensionsKey: []interface{}{"io.cnab.docker", UpgradeFromExtensionKey, "com.example.b", "com.example.a"},
+	}
+	assert.NilError(t, CheckRequiredExtensions(b, append(SupportedExtensions, "io.cnab.docker", "com.example.a", "com.example.b")))
+	assert.Error(t, CheckRequiredExtensions(b, SupportedExtensions),
+		`bundle "app" requires unsupported extensions: com.example.a, com.example.b, io.cnab.docker`)
+
+	// The extensions implemented by the runner are supported
+	b.Custom[RequiredExtensionsKey] = []interface{}{OutputsExtensionKey, ParameterSourcesExtensionKey}
+	assert.NilError(t, CheckRequiredExtensions(b, SupportedExtensions))
+
+	b.Custom[RequiredExtensionsKey] = "io.cnab.docker"
+	assert.ErrorContains(t, CheckRequiredExtensions(b, SupportedExtensions), "invalid com.docker.app.required-extensions extension")
+}
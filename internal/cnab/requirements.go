@@ -0,0 +1,95 @@
+package cnab
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/semver"
+	"github.com/pkg/errors"
+)
+
+// RequirementsExtensionKey is the custom extension declaring the minimum
+// environment a bundle needs to run.
+const RequirementsExtensionKey = internal.Namespace + "requirements"
+
+// Requirements is the content of the requirements extension.
+type Requirements struct {
+	// MinRuntimeVersion is the minimum version of the tool running the bundle.
+	MinRuntimeVersion string `json:"minRuntimeVersion,omitempty"`
+	// DriverCapabilities must all be supported by the driver.
+	DriverCapabilities []string `json:"driverCapabilities,omitempty"`
+	// MinKubernetesVersion is the minimum version of the target cluster.
+	MinKubernetesVersion string `json:"minKubernetesVersion,omitempty"`
+}
+
+// Descriptor describes the environment a bundle is about to run in. Empty
+// values are unknown and never reported as incompatible.
+type Descriptor struct {
+	RuntimeVersion     string
+	DriverCapabilities []string
+	KubernetesVersion  string
+}
+
+// ReadRequirements returns the requirements of the bundle, or nil if it has
+// none.
+func ReadRequirements(b *bundle.Bundle) (*Requirements, error) {
+	var requirements Requirements
+	found, err := GetCustomExtension(b, RequirementsExtensionKey, &requirements)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &requirements, nil
+}
+
+// CheckEnvironment returns an error listing every requirement of the bundle
+// the environment does not meet, so incompatibilities are reported before
+// anything runs.
+func CheckEnvironment(b *bundle.Bundle, env Descriptor) error {
+	requirements, err := ReadRequirements(b)
+	if err != nil || requirements == nil {
+		return err
+	}
+	var problems []string
+	if problem := checkMinVersion("runtime", requirements.MinRuntimeVersion, env.RuntimeVersion); problem != "" {
+		problems = append(problems, problem)
+	}
+	if problem := checkMinVersion("Kubernetes", requirements.MinKubernetesVersion, env.KubernetesVersion); problem != "" {
+		problems = append(problems, problem)
+	}
+	if env.DriverCapabilities != nil {
+		supported := map[string]bool{}
+		for _, c := range env.DriverCapabilities {
+			supported[c] = true
+		}
+		for _, c := range requirements.DriverCapabilities {
+			if !supported[c] {
+				problems = append(problems, fmt.Sprintf("driver capability %q is not supported", c))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("bundle %q cannot run in this environment:\n- %s", b.Name, strings.Join(problems, "\n- "))
+	}
+	return nil
+}
+
+func checkMinVersion(what, required, actual string) string {
+	if required == "" || actual == "" {
+		return ""
+	}
+	min, err := semver.Parse(required)
+	if err != nil {
+		return fmt.Sprintf("invalid minimum %s version: %s", what, err)
+	}
+	version, err := semver.Parse(actual)
+	if err != nil {
+		// development builds do not have a semantic version
+		return ""
+	}
+	if version.Compare(min) < 0 {
+		return fmt.Sprintf("%s version %s is required, got %s", what, required, actual)
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package cnab_test
+
+import (
+	"testing"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCheckEnvironment(t *testing.T) {
+	b := bundletest.NewTestBundle(bundletest.WithCustom(cnab.RequirementsExtensionKey, cnab.Requirements{
+		MinRuntimeVersion:    "0.9.0",
+		DriverCapabilities:   []string{"docker"},
+		MinKubernetesVersion: "1.14.0",
+	}))
+
+	assert.Check(t, cnab.CheckEnvironment(b, cnab.Descriptor{
+		RuntimeVersion:     "v0.9.0",
+		DriverCapabilities: []string{"docker", "oci"},
+		KubernetesVersion:  "v1.15.2",
+	}))
+	// Unknown values are not checked
+	assert.Check(t, cnab.CheckEnvironment(b, cnab.Descriptor{RuntimeVersion: "unknown"}))
+	assert.Check(t, cnab.CheckEnvironment(bundletest.NewTestBundle(), cnab.Descriptor{RuntimeVersion: "0.1.0"}))
+
+	err := cnab.CheckEnvironment(b, cnab.Descriptor{
+		RuntimeVersion:     "v0.8.1",
+		DriverCapabilities: []string{"qcow"},
+		KubernetesVersion:  "1.13.0",
+	})
+	assert.Check(t, is.Error(err, `bundle "test-bundle" cannot run in this environment:
+- runtime version 0.9.0 is required, got v0.8.1
+- Kubernetes version 1.14.0 is required, got 1.13.0
+- driver capability "docker" is not supported`))
+}
@@ -0,0 +1,156 @@
+package cnab
+
+import (
+	"context"
+	"sync"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Default concurrency of the image resolutions.
+const (
+	DefaultResolveWorkers      = 8
+	DefaultRegistryConcurrency = 4
+)
+
+// ResolveOptions bounds the concurrency of the image resolutions of
+// PinDigests and VerifyImageDigests: the number of images resolved at once,
+// and the number of them resolved at once against the same registry, so
+// slow registries are not hammered.
+type ResolveOptions struct {
+	workers     int
+	perRegistry int
+}
+
+// WithResolveWorkers sets the number of images resolved at once, by default
+// DefaultResolveWorkers. 1 resolves the images one after the other.
+func WithResolveWorkers(workers int) func(*ResolveOptions) {
+	return func(o *ResolveOptions) {
+		o.workers = workers
+	}
+}
+
+// WithRegistryConcurrency sets the number of images resolved at once
+// against the same registry, by default DefaultRegistryConcurrency.
+func WithRegistryConcurrency(n int) func(*ResolveOptions) {
+	return func(o *ResolveOptions) {
+		o.perRegistry = n
+	}
+}
+
+func newResolveOptions(opts []func(*ResolveOptions)) ResolveOptions {
+	o := ResolveOptions{workers: DefaultResolveWorkers, perRegistry: DefaultRegistryConcurrency}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	if o.perRegistry < 1 {
+		o.perRegistry = 1
+	}
+	return o
+}
+
+// resolution is the result of resolving an image reference.
+type resolution struct {
+	desc ocispec.Descriptor
+	err  error
+}
+
+// resolveImages resolves concurrently the references of the images of the
+// bundle selected by the filter, each reference once, and returns the
+// resolutions by reference. The first image is resolved alone, so that in
+// offline mode the resolution fails fast without reaching for the others.
+// The first offline.ErrOfflineMode of the resolver cancels the resolutions
+// left and is returned.
+func resolveImages(ctx context.Context, b *bundle.Bundle, resolver ImageResolver, o ResolveOptions, filter func(bundle.BaseImage) bool) (map[string]resolution, error) {
+	var images []string
+	seen := map[string]bool{}
+	forEachImage(b, func(_ string, image *bundle.BaseImage) {
+		if filter(*image) && !seen[image.Image] {
+			seen[image.Image] = true
+			images = append(images, image.Image)
+		}
+	})
+
+	resolved := map[string]resolution{}
+	if len(images) == 0 {
+		return resolved, nil
+	}
+	desc, err := resolveImage(ctx, resolver, images[0])
+	if offline.IsOffline(err) {
+		return resolved, err
+	}
+	resolved[images[0]] = resolution{desc, err}
+	images = images[1:]
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu         sync.Mutex
+		offlineErr error
+		registries = map[string]chan struct{}{}
+	)
+	// registry returns the semaphore of the registry serving the image.
+	registry := func(image string) chan struct{} {
+		domain := ""
+		if named, err := reference.ParseNormalizedNamed(image); err == nil {
+			domain = reference.Domain(named)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sem, ok := registries[domain]
+		if !ok {
+			sem = make(chan struct{}, o.perRegistry)
+			registries[domain] = sem
+		}
+		return sem
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < o.workers && i < len(images); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range jobs {
+				desc, err := resolveImageLimited(ctx, resolver, image, registry(image))
+				mu.Lock()
+				if offline.IsOffline(err) {
+					if offlineErr == nil {
+						offlineErr = err
+					}
+					cancel()
+				} else {
+					resolved[image] = resolution{desc, err}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, image := range images {
+		jobs <- image
+	}
+	close(jobs)
+	wg.Wait()
+	return resolved, offlineErr
+}
+
+// resolveImageLimited resolves the image once the semaphore of its registry
+// is acquired.
+func resolveImageLimited(ctx context.Context, resolver ImageResolver, image string, sem chan struct{}) (ocispec.Descriptor, error) {
+	if err := ctx.Err(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return ocispec.Descriptor{}, ctx.Err()
+	}
+	defer func() { <-sem }()
+	return resolveImage(ctx, resolver, image)
+}
@@ -0,0 +1,126 @@
+package cnab
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// slowResolver serves every reference after a delay, except the ones
+// failing, and records the resolutions in flight.
+type slowResolver struct {
+	mu          sync.Mutex
+	calls       map[string]int
+	inFlight    map[string]int
+	maxInFlight map[string]int
+	total       int
+	maxTotal    int
+	failing     map[string]bool
+}
+
+func newSlowResolver() *slowResolver {
+	return &slowResolver{calls: map[string]int{}, inFlight: map[string]int{}, maxInFlight: map[string]int{}, failing: map[string]bool{}}
+}
+
+func (r *slowResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	if err := ctx.Err(); err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	domain := reference.Domain(named)
+	r.mu.Lock()
+	r.calls[ref]++
+	r.inFlight[domain]++
+	r.total++
+	if r.inFlight[domain] > r.maxInFlight[domain] {
+		r.maxInFlight[domain] = r.inFlight[domain]
+	}
+	if r.total > r.maxTotal {
+		r.maxTotal = r.total
+	}
+	r.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	r.mu.Lock()
+	r.inFlight[domain]--
+	r.total--
+	failing := r.failing[ref]
+	r.mu.Unlock()
+	if failing {
+		return "", ocispec.Descriptor{}, fmt.Errorf("timeout")
+	}
+	return ref, ocispec.Descriptor{Digest: digest.FromString(ref)}, nil
+}
+
+func manyImagesBundle() *bundle.Bundle {
+	b := &bundle.Bundle{
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{Image: "registry.example.com/installer:1.0"}}},
+		Images:           map[string]bundle.Image{},
+	}
+	for i := 0; i < 12; i++ {
+		b.Images[fmt.Sprintf("a%02d", i)] = bundle.Image{BaseImage: bundle.BaseImage{Image: fmt.Sprintf("registry.example.com/image%d:1.0", i)}}
+		b.Images[fmt.Sprintf("b%02d", i)] = bundle.Image{BaseImage: bundle.BaseImage{Image: fmt.Sprintf("other.example.com/image%d:1.0", i)}}
+	}
+	// The same reference is resolved once.
+	b.Images["copy"] = bundle.Image{BaseImage: bundle.BaseImage{Image: "registry.example.com/image0:1.0"}}
+	return b
+}
+
+func TestPinDigestsConcurrently(t *testing.T) {
+	b := manyImagesBundle()
+	resolver := newSlowResolver()
+	resolver.failing["registry.example.com/image3:1.0"] = true
+	resolver.failing["other.example.com/image7:1.0"] = true
+
+	err := PinDigests(context.Background(), b, resolver, WithResolveWorkers(6), WithRegistryConcurrency(2))
+	assert.Assert(t, is.ErrorContains(err, ""))
+	errs := err.(ImagesError)
+	assert.Assert(t, is.Len(errs, 2))
+	assert.Check(t, is.Equal(errs[0].Path, `$.images["a03"]`))
+	assert.Check(t, is.Equal(errs[1].Path, `$.images["b07"]`))
+
+	assert.Check(t, is.Equal(resolver.maxInFlight["registry.example.com"], 2))
+	assert.Check(t, is.Equal(resolver.maxInFlight["other.example.com"], 2))
+	assert.Check(t, resolver.maxTotal <= 4)
+	for ref, calls := range resolver.calls {
+		assert.Check(t, is.Equal(calls, 1), ref)
+	}
+	assert.Check(t, is.Equal(b.Images["copy"].Digest, b.Images["a00"].Digest))
+	assert.Check(t, is.Equal(b.Images["b11"].Digest, digest.FromString("other.example.com/image11:1.0").String()))
+	assert.Check(t, is.Equal(b.Images["a03"].Digest, ""))
+}
+
+func TestVerifyImageDigestsConcurrently(t *testing.T) {
+	b := manyImagesBundle()
+	resolver := newSlowResolver()
+	assert.NilError(t, PinDigests(context.Background(), b, resolver))
+
+	image := b.Images["b05"]
+	image.Digest = digest.FromString("moved").String()
+	b.Images["b05"] = image
+	resolver = newSlowResolver()
+	err := VerifyImageDigests(context.Background(), b, resolver, WithResolveWorkers(1))
+	assert.Assert(t, is.ErrorContains(err, "digest mismatch"))
+	assert.Check(t, is.Len(err.(ImagesError), 1))
+	assert.Check(t, is.Equal(resolver.maxTotal, 1))
+}
+
+func TestResolveImagesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := PinDigests(ctx, manyImagesBundle(), newSlowResolver(), WithResolveWorkers(4), WithRegistryConcurrency(1))
+	assert.Assert(t, is.ErrorContains(err, ""))
+	// Every image is reported, none silently skipped.
+	assert.Check(t, is.Len(err.(ImagesError), 26))
+}
@@ -0,0 +1,145 @@
+package cnab
+
+import (
+	"encoding/json"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// SBOMExtensionKey is the custom extension attaching a software bill of
+// materials to the bundle.
+const SBOMExtensionKey = internal.Namespace + "sbom"
+
+// Media types of the supported SBOM documents.
+const (
+	MediaTypeSPDX      = "application/spdx+json"
+	MediaTypeCycloneDX = "application/vnd.cyclonedx+json"
+)
+
+// SBOM is a software bill of materials attached to a bundle, either embedded
+// or stored as an OCI artifact.
+type SBOM struct {
+	// MediaType is the type of the document, MediaTypeSPDX or
+	// MediaTypeCycloneDX.
+	MediaType string        `json:"mediaType"`
+	Digest    digest.Digest `json:"digest"`
+	Size      int64         `json:"size"`
+	// Data is the embedded document.
+	Data []byte `json:"data,omitempty"`
+	// Reference is the OCI artifact holding the document, when it is not
+	// embedded.
+	Reference string `json:"reference,omitempty"`
+}
+
+// SBOMFetcher returns the content of the OCI artifact holding an SBOM.
+type SBOMFetcher func(reference string, dgst digest.Digest) ([]byte, error)
+
+// AttachSBOM embeds an SPDX or CycloneDX JSON document in the bundle,
+// replacing any attached SBOM.
+func AttachSBOM(b *bundle.Bundle, doc []byte) (*SBOM, error) {
+	mediaType, err := sbomMediaType(doc)
+	if err != nil {
+		return nil, err
+	}
+	sbom := &SBOM{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(doc),
+		Size:      int64(len(doc)),
+		Data:      doc,
+	}
+	writeSBOM(b, sbom)
+	return sbom, nil
+}
+
+// AttachSBOMReference references an SBOM stored as an OCI artifact, with
+// the digest and size of the document, replacing any attached SBOM.
+func AttachSBOMReference(b *bundle.Bundle, reference, mediaType string, dgst digest.Digest, size int64) (*SBOM, error) {
+	if mediaType != MediaTypeSPDX && mediaType != MediaTypeCycloneDX {
+		return nil, errors.Errorf("unsupported SBOM media type %q", mediaType)
+	}
+	if reference == "" {
+		return nil, errors.New("SBOM reference is required")
+	}
+	if err := dgst.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid SBOM digest")
+	}
+	sbom := &SBOM{MediaType: mediaType, Digest: dgst, Size: size, Reference: reference}
+	writeSBOM(b, sbom)
+	return sbom, nil
+}
+
+// ReadSBOM returns the SBOM attached to the bundle, or nil if it has none.
+func ReadSBOM(b *bundle.Bundle) (*SBOM, error) {
+	var sbom SBOM
+	found, err := GetCustomExtension(b, SBOMExtensionKey, &sbom)
+	if err != nil || !found {
+		return nil, err
+	}
+	if err := sbom.Digest.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s extension", SBOMExtensionKey)
+	}
+	if (sbom.Data == nil) == (sbom.Reference == "") {
+		return nil, errors.Errorf("invalid %s extension: the SBOM must be either embedded or referenced", SBOMExtensionKey)
+	}
+	return &sbom, nil
+}
+
+// SBOMDocument returns the SBOM document attached to the bundle, fetching
+// it if it is referenced, once its digest and size are verified. It returns
+// nil if the bundle has no SBOM.
+func SBOMDocument(b *bundle.Bundle, fetch SBOMFetcher) ([]byte, error) {
+	sbom, err := ReadSBOM(b)
+	if err != nil || sbom == nil {
+		return nil, err
+	}
+	doc := sbom.Data
+	if doc == nil {
+		if fetch == nil {
+			return nil, errors.Errorf("SBOM %s is not embedded in the bundle", sbom.Reference)
+		}
+		if doc, err = fetch(sbom.Reference, sbom.Digest); err != nil {
+			return nil, errors.Wrapf(err, "cannot fetch SBOM %s", sbom.Reference)
+		}
+	}
+	if int64(len(doc)) != sbom.Size {
+		return nil, errors.Errorf("SBOM size mismatch: expected %d bytes, got %d", sbom.Size, len(doc))
+	}
+	verifier := sbom.Digest.Verifier()
+	if _, err := verifier.Write(doc); err != nil {
+		return nil, err
+	}
+	if !verifier.Verified() {
+		return nil, errors.Errorf("SBOM digest mismatch: expected %s", sbom.Digest)
+	}
+	return doc, nil
+}
+
+func writeSBOM(b *bundle.Bundle, sbom *SBOM) {
+	if b.Custom == nil {
+		b.Custom = map[string]interface{}{}
+	}
+	b.Custom[SBOMExtensionKey] = *sbom
+}
+
+// sbomMediaType detects whether a JSON document is an SPDX or a CycloneDX
+// SBOM.
+func sbomMediaType(doc []byte) (string, error) {
+	var header struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(doc, &header); err != nil {
+		return "", errors.Wrap(err, "invalid SBOM, expected an SPDX or CycloneDX JSON document")
+	}
+	switch {
+	case header.SPDXVersion != "":
+		return MediaTypeSPDX, nil
+	case header.BOMFormat == "CycloneDX":
+		return MediaTypeCycloneDX, nil
+	default:
+		return "", errors.New("invalid SBOM, expected an SPDX or CycloneDX JSON document")
+	}
+}
@@ -0,0 +1,83 @@
+package cnab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const spdxDocument = `{"spdxVersion": "SPDX-2.2", "name": "app", "packages": []}`
+
+// roundTrip encodes and decodes the bundle, as when it is pushed and pulled.
+func roundTrip(t *testing.T, b *bundle.Bundle) *bundle.Bundle {
+	t.Helper()
+	data, err := json.Marshal(b)
+	assert.NilError(t, err)
+	loaded, err := Parse(data)
+	assert.NilError(t, err)
+	return loaded
+}
+
+func TestAttachSBOM(t *testing.T) {
+	b := &bundle.Bundle{}
+	doc, err := SBOMDocument(b, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(doc))
+
+	sbom, err := AttachSBOM(b, []byte(spdxDocument))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(sbom.MediaType, MediaTypeSPDX))
+	assert.Check(t, is.Equal(sbom.Digest, digest.FromString(spdxDocument)))
+
+	loaded := roundTrip(t, b)
+	doc, err = SBOMDocument(loaded, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(doc), spdxDocument))
+
+	_, err = AttachSBOM(b, []byte(`{"bomFormat": "CycloneDX", "specVersion": "1.4"}`))
+	assert.NilError(t, err)
+	read, err := ReadSBOM(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(read.MediaType, MediaTypeCycloneDX))
+
+	_, err = AttachSBOM(b, []byte(`{"name": "app"}`))
+	assert.Check(t, is.ErrorContains(err, "expected an SPDX or CycloneDX JSON document"))
+}
+
+func TestSBOMDocumentVerifiesDigest(t *testing.T) {
+	b := &bundle.Bundle{}
+	_, err := AttachSBOM(b, []byte(spdxDocument))
+	assert.NilError(t, err)
+	sbom := b.Custom[SBOMExtensionKey].(SBOM)
+	sbom.Data = []byte(`{"spdxVersion": "SPDX-2.2", "name": "bad", "packages": []}`)
+	b.Custom[SBOMExtensionKey] = sbom
+	_, err = SBOMDocument(b, nil)
+	assert.Check(t, is.Error(err, "SBOM digest mismatch: expected "+digest.FromString(spdxDocument).String()))
+}
+
+func TestAttachSBOMReference(t *testing.T) {
+	b := &bundle.Bundle{}
+	dgst := digest.FromString(spdxDocument)
+	_, err := AttachSBOMReference(b, "registry.example.com/app-sbom@"+dgst.String(), MediaTypeSPDX, dgst, int64(len(spdxDocument)))
+	assert.NilError(t, err)
+	b = roundTrip(t, b)
+
+	_, err = SBOMDocument(b, nil)
+	assert.Check(t, is.ErrorContains(err, "is not embedded in the bundle"))
+	doc, err := SBOMDocument(b, func(reference string, d digest.Digest) ([]byte, error) {
+		assert.Check(t, is.Equal(d, dgst))
+		return []byte(spdxDocument), nil
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(doc), spdxDocument))
+	_, err = SBOMDocument(b, func(string, digest.Digest) ([]byte, error) { return nil, errors.New("not found") })
+	assert.Check(t, is.ErrorContains(err, "not found"))
+
+	_, err = AttachSBOMReference(b, "app-sbom", "text/plain", dgst, 1)
+	assert.Check(t, is.Error(err, `unsupported SBOM media type "text/plain"`))
+}
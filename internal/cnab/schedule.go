@@ -0,0 +1,180 @@
+package cnab
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// SchedulesExtensionKey is the custom extension suggesting when the
+// maintenance actions of a bundle, such as a backup, should run.
+const SchedulesExtensionKey = internal.Namespace + "schedules"
+
+// Schedule is a hint on when to run an action. Operators and controllers
+// decide whether to follow it.
+type Schedule struct {
+	// Cron is a standard 5 fields cron expression, or one of the @hourly,
+	// @daily, @midnight, @weekly, @monthly, @yearly and @annually macros.
+	Cron string `json:"cron,omitempty"`
+	// Frequency is a suggested interval between two runs, as a duration
+	// ("12h") or one of hourly, daily, nightly, weekly and monthly.
+	Frequency string `json:"frequency,omitempty"`
+}
+
+var frequencies = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"nightly": 24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+// Interval returns the frequency as a duration, or 0 if none is set.
+func (s Schedule) Interval() (time.Duration, error) {
+	if s.Frequency == "" {
+		return 0, nil
+	}
+	if d, ok := frequencies[strings.ToLower(s.Frequency)]; ok {
+		return d, nil
+	}
+	d, err := time.ParseDuration(s.Frequency)
+	if err != nil || d <= 0 {
+		return 0, errors.Errorf("invalid frequency %q", s.Frequency)
+	}
+	return d, nil
+}
+
+// Validate checks the cron expression and the frequency.
+func (s Schedule) Validate() error {
+	if s.Cron == "" && s.Frequency == "" {
+		return errors.New("schedule requires a cron expression or a frequency")
+	}
+	if s.Cron != "" {
+		if err := validateCron(s.Cron); err != nil {
+			return err
+		}
+	}
+	_, err := s.Interval()
+	return err
+}
+
+// ReadSchedules returns the schedules of the bundle actions, keyed by action
+// name. Each schedule is validated and must refer to an action of the bundle.
+func ReadSchedules(b *bundle.Bundle) (map[string]Schedule, error) {
+	var schedules map[string]Schedule
+	found, err := GetCustomExtension(b, SchedulesExtensionKey, &schedules)
+	if err != nil || !found {
+		return nil, err
+	}
+	for name, schedule := range schedules {
+		if _, ok := b.Actions[name]; !ok {
+			return nil, errors.Errorf("invalid %s extension: undefined action %q", SchedulesExtensionKey, name)
+		}
+		if err := schedule.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid schedule of action %q", name)
+		}
+	}
+	return schedules, nil
+}
+
+// ActionSchedule returns the schedule of an action, or nil if it has none.
+func ActionSchedule(b *bundle.Bundle, action string) (*Schedule, error) {
+	schedules, err := ReadSchedules(b)
+	if err != nil {
+		return nil, err
+	}
+	schedule, ok := schedules[action]
+	if !ok {
+		return nil, nil
+	}
+	return &schedule, nil
+}
+
+var cronMacros = map[string]bool{
+	"@hourly": true, "@daily": true, "@midnight": true, "@weekly": true,
+	"@monthly": true, "@yearly": true, "@annually": true,
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+func validateCron(expr string) error {
+	if strings.HasPrefix(expr, "@") {
+		if !cronMacros[expr] {
+			return errors.Errorf("invalid cron expression %q: unknown macro", expr)
+		}
+		return nil
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return errors.Errorf("invalid cron expression %q: expected %d fields, got %d", expr, len(cronFields), len(fields))
+	}
+	for i, field := range fields {
+		for _, item := range strings.Split(field, ",") {
+			if err := cronFields[i].validate(item); err != nil {
+				return errors.Wrapf(err, "invalid cron expression %q", expr)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks a list item: "*", "a", "a-b", each optionally followed by
+// a "/step".
+func (f cronField) validate(item string) error {
+	rng := item
+	if i := strings.IndexByte(item, '/'); i >= 0 {
+		rng = item[:i]
+		step, err := strconv.Atoi(item[i+1:])
+		if err != nil || step <= 0 {
+			return errors.Errorf("invalid step in %s %q", f.name, item)
+		}
+	}
+	if rng == "*" {
+		return nil
+	}
+	bounds := strings.SplitN(rng, "-", 2)
+	values := make([]int, len(bounds))
+	for i, bound := range bounds {
+		value, err := f.value(bound)
+		if err != nil {
+			return errors.Errorf("invalid %s %q", f.name, item)
+		}
+		values[i] = value
+	}
+	if len(values) == 2 && values[0] > values[1] {
+		return errors.Errorf("invalid %s range %q", f.name, item)
+	}
+	return nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.ToLower(s) == name {
+			return f.min + i, nil
+		}
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if value < f.min || value > f.max {
+		return 0, errors.Errorf("%d out of range", value)
+	}
+	return value, nil
+}
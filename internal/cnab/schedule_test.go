@@ -0,0 +1,62 @@
+package cnab_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestActionSchedule(t *testing.T) {
+	b := bundletest.NewTestBundle(
+		bundletest.WithAction("backup", bundle.Action{}),
+		bundletest.WithAction("vacuum", bundle.Action{}),
+		bundletest.WithCustom(cnab.SchedulesExtensionKey, map[string]cnab.Schedule{
+			"backup": {Cron: "0 2 * * *", Frequency: "nightly"},
+		}),
+	)
+	schedule, err := cnab.ActionSchedule(b, "backup")
+	assert.NilError(t, err)
+	assert.Equal(t, schedule.Cron, "0 2 * * *")
+	interval, err := schedule.Interval()
+	assert.NilError(t, err)
+	assert.Equal(t, interval, 24*time.Hour)
+
+	schedule, err = cnab.ActionSchedule(b, "vacuum")
+	assert.NilError(t, err)
+	assert.Check(t, schedule == nil)
+
+	b.Custom[cnab.SchedulesExtensionKey] = map[string]cnab.Schedule{"restore": {Frequency: "daily"}}
+	_, err = cnab.ReadSchedules(b)
+	assert.Check(t, is.ErrorContains(err, `undefined action "restore"`))
+}
+
+func TestScheduleValidate(t *testing.T) {
+	for _, valid := range []cnab.Schedule{
+		{Cron: "*/15 * * * *"},
+		{Cron: "0 0-6/2 1,15 jan-jun MON-FRI"},
+		{Cron: "30 4 * * 7"},
+		{Cron: "@weekly"},
+		{Frequency: "90m"},
+		{Frequency: "Weekly"},
+	} {
+		assert.Check(t, valid.Validate(), "%+v", valid)
+	}
+	for schedule, expected := range map[cnab.Schedule]string{
+		{}:                         "schedule requires a cron expression or a frequency",
+		{Cron: "* * * *"}:          "expected 5 fields, got 4",
+		{Cron: "60 * * * *"}:       `invalid minute "60"`,
+		{Cron: "* 5-2 * * *"}:      `invalid hour range "5-2"`,
+		{Cron: "* * * foo *"}:      `invalid month "foo"`,
+		{Cron: "*/0 * * * *"}:      `invalid step in minute "*/0"`,
+		{Cron: "@sometimes"}:       "unknown macro",
+		{Frequency: "fortnightly"}: `invalid frequency "fortnightly"`,
+		{Frequency: "-1h"}:         `invalid frequency "-1h"`,
+	} {
+		assert.Check(t, is.ErrorContains(schedule.Validate(), expected))
+	}
+}
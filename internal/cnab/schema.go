@@ -0,0 +1,311 @@
+package cnab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/pkg/errors"
+)
+
+// ParameterSchemasExtensionKey is the custom extension holding JSON Schema
+// definitions of the bundle parameters, for constraints the parameter
+// definitions cannot express.
+const ParameterSchemasExtensionKey = internal.Namespace + "parameter-schemas"
+
+// ParameterSchemas is the content of the parameter schemas extension.
+type ParameterSchemas struct {
+	// Definitions are shared schemas, referenced as "#/definitions/<name>".
+	Definitions map[string]*Schema `json:"definitions,omitempty"`
+	// Parameters maps parameter names to their schema.
+	Parameters map[string]*Schema `json:"parameters,omitempty"`
+}
+
+// Schema is the subset of JSON Schema used to validate parameter values.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// ReadParameterSchemas returns the parameter schemas of the bundle, or nil if
+// it has none. References to undefined definitions are reported.
+func ReadParameterSchemas(b *bundle.Bundle) (*ParameterSchemas, error) {
+	var schemas ParameterSchemas
+	found, err := GetCustomExtension(b, ParameterSchemasExtensionKey, &schemas)
+	if err != nil || !found {
+		return nil, err
+	}
+	for name, schema := range schemas.Parameters {
+		if _, ok := b.Parameters[name]; !ok {
+			return nil, errors.Errorf("invalid %s extension: undefined parameter %q", ParameterSchemasExtensionKey, name)
+		}
+		if err := schemas.checkRefs(schema, 0); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s extension", ParameterSchemasExtensionKey)
+		}
+	}
+	return &schemas, nil
+}
+
+// maxRefDepth bounds the resolution of references, which may be recursive.
+const maxRefDepth = 32
+
+func (s *ParameterSchemas) checkRefs(schema *Schema, depth int) error {
+	if schema == nil {
+		return nil
+	}
+	if depth > maxRefDepth {
+		return errors.New("schema nested too deeply")
+	}
+	if schema.Ref != "" {
+		target, err := s.resolve(schema.Ref)
+		if err != nil {
+			return err
+		}
+		return s.checkRefs(target, depth+1)
+	}
+	if schema.Pattern != "" {
+		if _, err := regexp.Compile(schema.Pattern); err != nil {
+			return errors.Wrapf(err, "invalid pattern %q", schema.Pattern)
+		}
+	}
+	if err := s.checkRefs(schema.Items, depth+1); err != nil {
+		return err
+	}
+	for _, property := range schema.Properties {
+		if err := s.checkRefs(property, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ParameterSchemas) resolve(ref string) (*Schema, error) {
+	const prefix = "#/definitions/"
+	if !strings.HasPrefix(ref, prefix) {
+		return nil, errors.Errorf("unsupported reference %q, only %s<name> is supported", ref, prefix)
+	}
+	schema, ok := s.Definitions[strings.TrimPrefix(ref, prefix)]
+	if !ok {
+		return nil, errors.Errorf("undefined reference %q", ref)
+	}
+	return schema, nil
+}
+
+// ValidateParameters validates the given parameter values against their
+// schema, if any. A rejected value is reported in a
+// ParameterValidationError.
+func ValidateParameters(b *bundle.Bundle, values map[string]interface{}) (err error) {
+	_, op := telemetry.Start(context.Background(), telemetry.SpanValidate, telemetry.Fields{"bundle": b.Name, "kind": "parameters"})
+	defer func() { op.End(err) }()
+	schemas, err := ReadParameterSchemas(b)
+	if err != nil || schemas == nil {
+		return err
+	}
+	names := make([]string, 0, len(schemas.Parameters))
+	for name := range schemas.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if err := schemas.validate(schemas.Parameters[name], value, "", 0); err != nil {
+			return &ParameterValidationError{Name: name, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+func (s *ParameterSchemas) validate(schema *Schema, value interface{}, path string, depth int) error {
+	if schema == nil {
+		return nil
+	}
+	if depth > maxRefDepth {
+		return errors.New("schema nested too deeply")
+	}
+	if schema.Ref != "" {
+		target, err := s.resolve(schema.Ref)
+		if err != nil {
+			return err
+		}
+		return s.validate(target, value, path, depth+1)
+	}
+	fail := func(format string, args ...interface{}) error {
+		msg := fmt.Sprintf(format, args...)
+		if path != "" {
+			return errors.Errorf("%s: %s", path, msg)
+		}
+		return errors.New(msg)
+	}
+	// Structured values may be given as JSON strings
+	if s, ok := value.(string); ok && (schema.Type == "object" || schema.Type == "array") {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return fail("expected %s, got invalid JSON: %s", schema.Type, err)
+		}
+		value = decoded
+	}
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		return fail("expected %s, got %T", schema.Type, value)
+	}
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		return fail("value %v is not one of %v", value, schema.Enum)
+	}
+	if n, ok := toFloat(value); ok {
+		switch {
+		case schema.Minimum != nil && n < *schema.Minimum:
+			return fail("%v is lower than the minimum %v", value, *schema.Minimum)
+		case schema.Maximum != nil && n > *schema.Maximum:
+			return fail("%v is greater than the maximum %v", value, *schema.Maximum)
+		case schema.ExclusiveMinimum != nil && n <= *schema.ExclusiveMinimum:
+			return fail("%v must be greater than %v", value, *schema.ExclusiveMinimum)
+		case schema.ExclusiveMaximum != nil && n >= *schema.ExclusiveMaximum:
+			return fail("%v must be lower than %v", value, *schema.ExclusiveMaximum)
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return fail("%q is shorter than %d characters", v, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fail("%q is longer than %d characters", v, *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			re, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return errors.Wrapf(err, "invalid pattern %q", schema.Pattern)
+			}
+			if !re.MatchString(v) {
+				return fail("%q does not match %q", v, schema.Pattern)
+			}
+		}
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			return fail("expected at least %d items, got %d", *schema.MinItems, len(v))
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			return fail("expected at most %d items, got %d", *schema.MaxItems, len(v))
+		}
+		for i, item := range v {
+			if err := s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, required := range schema.Required {
+			if _, ok := v[required]; !ok {
+				return fail("missing property %q", required)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := schema.Properties[key]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fail("unexpected property %q", key)
+				}
+				continue
+			}
+			if err := s.validate(property, v[key], path+"."+key, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasType(value interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		if number, ok := value.(json.Number); ok {
+			f, _, err := big.ParseFloat(number.String(), 10, 256, big.ToNearestEven)
+			return err == nil && f.IsInt()
+		}
+		n, ok := toFloat(value)
+		return ok && n == float64(int64(n))
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	n, isNumber := toFloat(value)
+	for _, candidate := range enum {
+		if c, ok := toFloat(candidate); ok && isNumber {
+			if c == n {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package cnab_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const testSchemas = `{
+	"definitions": {
+		"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+		"service": {
+			"type": "object",
+			"required": ["name"],
+			"additionalProperties": false,
+			"properties": {
+				"name": {"type": "string", "pattern": "^[a-z]+$", "maxLength": 8},
+				"port": {"$ref": "#/definitions/port"}
+			}
+		}
+	},
+	"parameters": {
+		"port": {"$ref": "#/definitions/port"},
+		"mode": {"type": "string", "enum": ["dev", "prod"]},
+		"services": {"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/service"}}
+	}
+}`
+
+func schemaBundle(t *testing.T, schemas string) *bundle.Bundle {
+	t.Helper()
+	var value interface{}
+	assert.NilError(t, json.Unmarshal([]byte(schemas), &value))
+	return bundletest.NewTestBundle(
+		bundletest.WithParameter("port", bundle.ParameterDefinition{DataType: "int"}),
+		bundletest.WithParameter("mode", bundle.ParameterDefinition{DataType: "string"}),
+		bundletest.WithParameter("services", bundle.ParameterDefinition{DataType: "string"}),
+		bundletest.WithCustom(cnab.ParameterSchemasExtensionKey, value),
+	)
+}
+
+func TestValidateParameters(t *testing.T) {
+	b := schemaBundle(t, testSchemas)
+	assert.NilError(t, cnab.ValidateParameters(b, map[string]interface{}{
+		"port":     8080,
+		"mode":     "prod",
+		"services": `[{"name": "web", "port": 80}]`,
+	}))
+	// Values decoded from JSON are numbers
+	assert.NilError(t, cnab.ValidateParameters(b, map[string]interface{}{"port": float64(443)}))
+
+	for expected, values := range map[string]map[string]interface{}{
+		`invalid value for parameter "port": 0 is lower than the minimum 1`:                   {"port": 0},
+		`invalid value for parameter "port": expected integer, got float64`:                   {"port": 1.5},
+		`invalid value for parameter "mode": value test is not one of [dev prod]`:             {"mode": "test"},
+		`invalid value for parameter "services": expected at least 1 items, got 0`:            {"services": `[]`},
+		`invalid value for parameter "services": [0]: missing property "name"`:                {"services": `[{"port": 80}]`},
+		`invalid value for parameter "services": [0].name: "Web" does not match "^[a-z]+$"`:   {"services": `[{"name": "Web"}]`},
+		`invalid value for parameter "services": [0].port: 70000 is greater than the maximum`: {"services": `[{"name": "web", "port": 70000}]`},
+		`invalid value for parameter "services": [0]: unexpected property "image"`:            {"services": `[{"name": "web", "image": "nginx"}]`},
+		`invalid value for parameter "services": expected array, got invalid JSON`:            {"services": `nope`},
+	} {
+		assert.Check(t, is.ErrorContains(cnab.ValidateParameters(b, values), expected))
+	}
+}
+
+func TestReadParameterSchemasErrors(t *testing.T) {
+	_, err := cnab.ReadParameterSchemas(schemaBundle(t, `{"parameters": {"port": {"$ref": "#/definitions/missing"}}}`))
+	assert.Check(t, is.ErrorContains(err, `undefined reference "#/definitions/missing"`))
+	_, err = cnab.ReadParameterSchemas(schemaBundle(t, `{"parameters": {"unknown": {"type": "string"}}}`))
+	assert.Check(t, is.ErrorContains(err, `undefined parameter "unknown"`))
+	_, err = cnab.ReadParameterSchemas(schemaBundle(t, `{"parameters": {"mode": {"type": "string", "pattern": "("}}}`))
+	assert.Check(t, is.ErrorContains(err, `invalid pattern "("`))
+	_, err = cnab.ReadParameterSchemas(schemaBundle(t, `{"definitions": {"loop": {"$ref": "#/definitions/loop"}}, "parameters": {"mode": {"$ref": "#/definitions/loop"}}}`))
+	assert.Check(t, is.ErrorContains(err, "schema nested too deeply"))
+}
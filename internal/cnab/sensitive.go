@@ -0,0 +1,47 @@
+package cnab
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// SensitiveParametersExtensionKey is the custom extension listing the
+// parameters whose values must never be displayed nor logged, like passwords.
+const SensitiveParametersExtensionKey = internal.Namespace + "sensitive-parameters"
+
+// SensitiveParameters returns the sorted names of the sensitive parameters
+// of the bundle.
+func SensitiveParameters(b *bundle.Bundle) ([]string, error) {
+	var names []string
+	found, err := GetCustomExtension(b, SensitiveParametersExtensionKey, &names)
+	if err != nil || !found {
+		return nil, err
+	}
+	for _, name := range names {
+		if _, ok := b.Parameters[name]; !ok {
+			return nil, errors.Errorf("invalid %s extension: undefined parameter %q", SensitiveParametersExtensionKey, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SensitiveParameterValues returns the values of the sensitive parameters, as
+// displayed, so they can be masked in outputs.
+func SensitiveParameterValues(b *bundle.Bundle, values map[string]interface{}) ([]string, error) {
+	names, err := SensitiveParameters(b)
+	if err != nil {
+		return nil, err
+	}
+	var secrets []string
+	for _, name := range names {
+		if value, ok := values[name]; ok && value != nil {
+			secrets = append(secrets, fmt.Sprintf("%v", value))
+		}
+	}
+	return secrets, nil
+}
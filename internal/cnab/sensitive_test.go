@@ -0,0 +1,35 @@
+package cnab_test
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestSensitiveParameters(t *testing.T) {
+	b := bundletest.NewTestBundle(
+		bundletest.WithParameter("user", bundle.ParameterDefinition{DataType: "string"}),
+		bundletest.WithParameter("password", bundle.ParameterDefinition{DataType: "string"}),
+		bundletest.WithParameter("pin", bundle.ParameterDefinition{DataType: "int"}),
+		bundletest.WithCustom(cnab.SensitiveParametersExtensionKey, []interface{}{"pin", "password"}),
+	)
+	names, err := cnab.SensitiveParameters(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(names, []string{"password", "pin"}))
+
+	values, err := cnab.SensitiveParameterValues(b, map[string]interface{}{"user": "admin", "password": "s3cr3t", "pin": 1234})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(values, []string{"s3cr3t", "1234"}))
+
+	names, err = cnab.SensitiveParameters(bundletest.NewTestBundle())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(names, 0))
+
+	b.Custom[cnab.SensitiveParametersExtensionKey] = []interface{}{"token"}
+	_, err = cnab.SensitiveParameters(b)
+	assert.Check(t, is.ErrorContains(err, `undefined parameter "token"`))
+}
@@ -0,0 +1,64 @@
+package cnab
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/pkg/errors"
+)
+
+// StampExtensionKey is the custom extension recording how a bundle was
+// built, for provenance.
+const StampExtensionKey = internal.Namespace + "stamp"
+
+// StampInfo is the build metadata of a bundle.
+type StampInfo struct {
+	// Builder identifies who or what built the bundle, like a CI job or
+	// "docker-app v0.9.0".
+	Builder string `json:"builder"`
+	// GitCommit is the commit of the sources the bundle was built from.
+	GitCommit string    `json:"gitCommit,omitempty"`
+	BuildTime time.Time `json:"buildTime"`
+	// Toolchain is the version of the tool that built the bundle.
+	Toolchain string `json:"toolchain,omitempty"`
+}
+
+// CurrentStampInfo returns the stamp of a bundle built now by this binary,
+// from the given git commit of the application sources.
+func CurrentStampInfo(gitCommit string) StampInfo {
+	return StampInfo{
+		Builder:   "docker-app " + internal.Version,
+		GitCommit: gitCommit,
+		BuildTime: time.Now().UTC(),
+		Toolchain: runtime.Version(),
+	}
+}
+
+// Stamp records the build metadata in the bundle, replacing any previous
+// stamp. The build time is stored in UTC, truncated to the second.
+func Stamp(b *bundle.Bundle, info StampInfo) {
+	info.BuildTime = info.BuildTime.UTC().Truncate(time.Second)
+	if b.Custom == nil {
+		b.Custom = map[string]interface{}{}
+	}
+	b.Custom[StampExtensionKey] = info
+}
+
+// ReadStamp returns the build metadata of the bundle, or nil if it is not
+// stamped.
+func ReadStamp(b *bundle.Bundle) (*StampInfo, error) {
+	var info StampInfo
+	found, err := GetCustomExtension(b, StampExtensionKey, &info)
+	if err != nil || !found {
+		return nil, err
+	}
+	if info.Builder == "" {
+		return nil, errors.Errorf("invalid %s extension: the builder is required", StampExtensionKey)
+	}
+	if info.BuildTime.IsZero() {
+		return nil, errors.Errorf("invalid %s extension: the build time is required", StampExtensionKey)
+	}
+	return &info, nil
+}
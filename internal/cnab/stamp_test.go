@@ -0,0 +1,49 @@
+package cnab
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestStamp(t *testing.T) {
+	b := &bundle.Bundle{}
+	info, err := ReadStamp(b)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(info))
+
+	buildTime := time.Date(2019, 6, 12, 10, 30, 15, 500, time.FixedZone("CEST", 2*60*60))
+	Stamp(b, StampInfo{Builder: "ci/release#42", GitCommit: "0123abc", BuildTime: buildTime, Toolchain: "go1.12.5"})
+	info, err = ReadStamp(roundTrip(t, b))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(*info, StampInfo{
+		Builder:   "ci/release#42",
+		GitCommit: "0123abc",
+		BuildTime: time.Date(2019, 6, 12, 8, 30, 15, 0, time.UTC),
+		Toolchain: "go1.12.5",
+	}))
+}
+
+func TestCurrentStampInfo(t *testing.T) {
+	info := CurrentStampInfo("0123abc")
+	assert.Check(t, is.Equal(info.Builder, "docker-app unknown"))
+	assert.Check(t, is.Equal(info.GitCommit, "0123abc"))
+	assert.Check(t, is.Equal(info.Toolchain, runtime.Version()))
+	assert.Check(t, !info.BuildTime.IsZero())
+}
+
+func TestReadStampInvalid(t *testing.T) {
+	b := &bundle.Bundle{Custom: map[string]interface{}{
+		StampExtensionKey: map[string]interface{}{"buildTime": "2019-06-12T08:30:15Z"},
+	}}
+	_, err := ReadStamp(b)
+	assert.Check(t, is.Error(err, "invalid "+StampExtensionKey+" extension: the builder is required"))
+
+	b.Custom[StampExtensionKey] = map[string]interface{}{"builder": "ci", "buildTime": "yesterday"}
+	_, err = ReadStamp(b)
+	assert.Check(t, is.ErrorContains(err, "invalid "+StampExtensionKey+" extension"))
+}
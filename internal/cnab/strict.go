@@ -0,0 +1,142 @@
+package cnab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+)
+
+// UnknownFieldsError is returned by UnmarshalStrict when a bundle document
+// contains fields which are not part of the bundle format.
+type UnknownFieldsError struct {
+	// Paths are the JSON paths of the unknown fields, for instance
+	// "$.invocationimages" or "$.parameters.port.typ".
+	Paths []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("unknown fields in bundle: %s", strings.Join(e.Paths, ", "))
+}
+
+// UnmarshalStrict decodes a bundle document as Parse does, but rejects the
+// fields which are not part of the bundle format, so typos are not silently
+// ignored. Field names must match exactly, where encoding/json would accept
+// any case. Custom extensions can hold any field.
+func UnmarshalStrict(data []byte) (b *bundle.Bundle, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, err = nil, fmt.Errorf("invalid bundle: %v", r)
+		}
+	}()
+	if err := checkDocument(data); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	// The schema version is not part of the bundle structure
+	if object, ok := document.(map[string]interface{}); ok {
+		if _, ok := object["schemaVersion"]; ok {
+			delete(object, "schemaVersion")
+			if data, err = json.Marshal(object); err != nil {
+				return nil, errors.Wrap(err, "invalid bundle")
+			}
+		}
+	}
+	var paths []string
+	collectUnknownFields(document, reflect.TypeOf(bundle.Bundle{}), "$", &paths)
+	if len(paths) > 0 {
+		sort.Strings(paths)
+		return nil, &UnknownFieldsError{Paths: paths}
+	}
+	b = &bundle.Bundle{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(b); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	return b, nil
+}
+
+// collectUnknownFields walks a decoded JSON value along the Go type it is
+// decoded into, and appends the paths of the object keys matching no field.
+// Type mismatches are left to the decoder to report.
+func collectUnknownFields(value interface{}, t reflect.Type, path string, paths *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, v := range object {
+			field, ok := fields[key]
+			if !ok {
+				*paths = append(*paths, path+"."+key)
+				continue
+			}
+			collectUnknownFields(v, field, path+"."+key, paths)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, v := range object {
+			collectUnknownFields(v, t.Elem(), path+"."+key, paths)
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, v := range array {
+			collectUnknownFields(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i), paths)
+		}
+	}
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// jsonFields returns the types of the fields of a struct, keyed by their JSON
+// name, including the fields promoted from embedded structs. Types decoding
+// themselves are not walked.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ft := f.Type
+		if reflect.PtrTo(ft).Implements(unmarshalerType) || ft.Implements(unmarshalerType) {
+			ft = reflect.TypeOf((*interface{})(nil)).Elem()
+		}
+		fields[name] = ft
+	}
+	return fields
+}
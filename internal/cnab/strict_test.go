@@ -0,0 +1,63 @@
+package cnab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestUnmarshalStrict(t *testing.T) {
+	b, err := UnmarshalStrict([]byte(`{
+		"schemaVersion": "v1.0.0-WD",
+		"name": "myapp",
+		"version": "1.0.0",
+		"invocationImages": [{"imageType": "docker", "image": "myapp:1.0.0"}],
+		"parameters": {"port": {"type": "int", "destination": {"env": "PORT"}}},
+		"custom": {"com.example.anything": {"free": "form"}}
+	}`))
+	assert.NilError(t, err)
+	assert.Equal(t, b.InvocationImages[0].Image, "myapp:1.0.0")
+	assert.Equal(t, b.Parameters["port"].Destination.EnvironmentVariable, "PORT")
+
+	_, err = UnmarshalStrict([]byte(`{
+		"name": "myapp",
+		"invocationimages": [],
+		"invocationImages": [{"imageType": "docker", "image": "myapp:1.0.0", "digets": "sha256:abc"}],
+		"parameters": {"port": {"type": "int", "destination": {"environment": "PORT"}}}
+	}`))
+	unknown, ok := errors.Cause(err).(*UnknownFieldsError)
+	assert.Assert(t, ok, "unexpected error %v", err)
+	assert.Check(t, is.DeepEqual(unknown.Paths, []string{
+		"$.invocationImages[0].digets",
+		"$.invocationimages",
+		"$.parameters.port.destination.environment",
+	}))
+	assert.Check(t, is.ErrorContains(err, "unknown fields in bundle: $.invocationImages[0].digets, "))
+
+	_, err = UnmarshalStrict([]byte(`{"name": ["myapp"]}`))
+	assert.Check(t, is.ErrorContains(err, "invalid bundle"))
+}
+
+func TestLoadDataStrict(t *testing.T) {
+	data := []byte(`{"name":"myapp","versoin":"1.0.0"}`)
+	_, err := LoadData(data)
+	assert.NilError(t, err)
+	_, err = LoadData(data, WithStrict())
+	assert.Check(t, is.ErrorContains(err, "unknown fields in bundle: $.versoin"))
+}
+
+func TestLoadDataStrictLimits(t *testing.T) {
+	// Both modes reject the documents breaking the custom extension limits
+	data := []byte(`{"name":"myapp","version":"1.0.0","custom":{"com.example.deep":` + strings.Repeat("[", 40) + strings.Repeat("]", 40) + `}}`)
+	_, err := LoadData(data)
+	assert.Check(t, is.ErrorContains(err, "invalid bundle"))
+	_, err = LoadData(data, WithStrict())
+	assert.Check(t, is.ErrorContains(err, "invalid bundle"))
+
+	large := make([]byte, MaxBundleSize+1)
+	_, err = LoadData(large, WithStrict())
+	assert.Check(t, is.ErrorContains(err, "larger than"))
+}
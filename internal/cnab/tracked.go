@@ -0,0 +1,259 @@
+package cnab
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
+)
+
+// trackedSections are the top-level members of the bundle document, sorted
+// by name as canonical JSON sorts them. Value returns nil for the members
+// omitted when empty.
+var trackedSections = []struct {
+	name  string
+	value func(b *bundle.Bundle) interface{}
+}{
+	{"actions", func(b *bundle.Bundle) interface{} { return omitEmpty(len(b.Actions), b.Actions) }},
+	{"credentials", func(b *bundle.Bundle) interface{} { return b.Credentials }},
+	{"custom", nil},
+	{"description", func(b *bundle.Bundle) interface{} { return b.Description }},
+	{"images", func(b *bundle.Bundle) interface{} { return b.Images }},
+	{"invocationImages", func(b *bundle.Bundle) interface{} { return b.InvocationImages }},
+	{"keywords", func(b *bundle.Bundle) interface{} { return omitEmpty(len(b.Keywords), b.Keywords) }},
+	{"maintainers", func(b *bundle.Bundle) interface{} { return omitEmpty(len(b.Maintainers), b.Maintainers) }},
+	{"name", func(b *bundle.Bundle) interface{} { return b.Name }},
+	{"parameters", func(b *bundle.Bundle) interface{} { return b.Parameters }},
+	{"version", func(b *bundle.Bundle) interface{} { return b.Version }},
+}
+
+func omitEmpty(length int, value interface{}) interface{} {
+	if length == 0 {
+		return nil
+	}
+	return value
+}
+
+// TrackedBundle caches the canonical JSON document and the digest of a
+// bundle, and only serializes again the sections marked as changed, so
+// rewriting a large bundle after a small change does not cost a full
+// serialization. Custom extensions are tracked one by one, as they hold the
+// largest payloads.
+//
+// The bundle is not watched: the sections changed through Bundle must be
+// marked with MarkDirty, or the document is stale. SetCustom and
+// DeleteCustom mark the extensions they change.
+type TrackedBundle struct {
+	b        *bundle.Bundle
+	sections map[string][]byte
+	custom   map[string][]byte
+	document []byte
+	digest   string
+	// digestConfig is the configuration digest was computed with
+	digestConfig checksum.Config
+}
+
+// Track wraps the bundle, whose document is serialized on first use.
+func Track(b *bundle.Bundle) *TrackedBundle {
+	return &TrackedBundle{b: b, sections: map[string][]byte{}, custom: map[string][]byte{}}
+}
+
+// Bundle returns the tracked bundle.
+func (t *TrackedBundle) Bundle() *bundle.Bundle {
+	return t.b
+}
+
+// MarkDirty marks top-level sections of the document as changed, by their
+// JSON names like "parameters" or "custom". Without names, the whole
+// document is marked as changed.
+func (t *TrackedBundle) MarkDirty(sections ...string) error {
+	if len(sections) == 0 {
+		t.sections = map[string][]byte{}
+		t.custom = map[string][]byte{}
+		t.document = nil
+		return nil
+	}
+	for _, name := range sections {
+		if !isTrackedSection(name) {
+			return errors.Errorf("unknown bundle section %q", name)
+		}
+	}
+	for _, name := range sections {
+		delete(t.sections, name)
+		if name == "custom" {
+			t.custom = map[string][]byte{}
+		}
+	}
+	t.document = nil
+	return nil
+}
+
+// SetCustom sets a custom extension, only this extension being serialized
+// again.
+func (t *TrackedBundle) SetCustom(key string, value interface{}) {
+	if t.b.Custom == nil {
+		t.b.Custom = map[string]interface{}{}
+	}
+	t.b.Custom[key] = value
+	t.dirtyCustom(key)
+}
+
+// DeleteCustom removes a custom extension.
+func (t *TrackedBundle) DeleteCustom(key string) {
+	delete(t.b.Custom, key)
+	t.dirtyCustom(key)
+}
+
+func (t *TrackedBundle) dirtyCustom(key string) {
+	delete(t.custom, key)
+	delete(t.sections, "custom")
+	t.document = nil
+}
+
+// Canonical returns the canonical JSON document of the bundle, the same as
+// canonicaljson.MarshalCanonical. It must not be modified.
+func (t *TrackedBundle) Canonical() ([]byte, error) {
+	if t.document != nil {
+		return t.document, nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, section := range trackedSections {
+		data, err := t.section(section.name, section.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to serialize the %s of the bundle", section.name)
+		}
+		if data == nil {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.WriteString(`"` + section.name + `":`)
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	t.document = buf.Bytes()
+	t.digest = ""
+	return t.document, nil
+}
+
+// Digest returns the digest of the bundle, with the algorithm configured by
+// the environment, see Digest.
+func (t *TrackedBundle) Digest() (string, error) {
+	document, err := t.Canonical()
+	if err != nil {
+		return "", err
+	}
+	config, err := checksum.FromEnv()
+	if err != nil {
+		return "", err
+	}
+	if t.digest == "" || t.digestConfig != config {
+		d, err := config.FromBytes(document)
+		if err != nil {
+			return "", err
+		}
+		t.digest, t.digestConfig = d.String(), config
+	}
+	return t.digest, nil
+}
+
+// WriteTo writes the canonical JSON document of the bundle.
+func (t *TrackedBundle) WriteTo(w io.Writer) (int64, error) {
+	data, err := t.Canonical()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// WriteFile writes the canonical JSON document of the bundle to a file, as
+// WriteFile does. Only the default canonicalization is tracked, the bundle
+// is entirely serialized with the other ones.
+func (t *TrackedBundle) WriteFile(path string, mode os.FileMode, opts WriteFileOptions) error {
+	if opts.Canonicalizer != nil && opts.Canonicalizer != DockerCanonicalJSON {
+		return WriteFile(t.b, path, mode, opts)
+	}
+	data, err := t.Canonical()
+	if err != nil {
+		return err
+	}
+	return WriteDocument(data, path, mode, opts)
+}
+
+// section returns the cached serialization of a section, nil if the section
+// is omitted.
+func (t *TrackedBundle) section(name string, value func(b *bundle.Bundle) interface{}) ([]byte, error) {
+	if data, ok := t.sections[name]; ok {
+		return data, nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if value == nil {
+		data, err = t.customSection()
+	} else if v := value(t.b); v != nil {
+		data, err = canonicaljson.MarshalCanonical(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.sections[name] = data
+	return data, nil
+}
+
+// customSection serializes the custom extensions, reusing the cached
+// serialization of the unchanged ones.
+func (t *TrackedBundle) customSection() ([]byte, error) {
+	if len(t.b.Custom) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(t.b.Custom))
+	for key := range t.b.Custom {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		data, ok := t.custom[key]
+		if !ok {
+			var err error
+			if data, err = canonicaljson.MarshalCanonical(t.b.Custom[key]); err != nil {
+				return nil, errors.Wrapf(err, "extension %q", key)
+			}
+			t.custom[key] = data
+		}
+		name, err := canonicaljson.MarshalCanonical(key)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func isTrackedSection(name string) bool {
+	for _, section := range trackedSections {
+		if section.name == name {
+			return true
+		}
+	}
+	return false
+}
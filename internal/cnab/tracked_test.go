@@ -0,0 +1,154 @@
+package cnab
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func assertTrackedCanonical(t *testing.T, tracked *TrackedBundle) {
+	t.Helper()
+	expected, err := canonicaljson.MarshalCanonical(tracked.Bundle())
+	assert.NilError(t, err)
+	actual, err := tracked.Canonical()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(actual), string(expected)))
+	d, err := tracked.Digest()
+	assert.NilError(t, err)
+	expectedDigest, err := Digest(tracked.Bundle())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(d, expectedDigest))
+}
+
+func TestTrackedBundle(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "myapp",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "myapp:1.0.0"}}},
+		Parameters:       map[string]bundle.ParameterDefinition{"port": {DataType: "int", Default: 80}},
+		Custom:           map[string]interface{}{"b": "<&>", "a": map[string]interface{}{"z": 1, "y": []interface{}{"x"}}},
+	}
+	tracked := Track(b)
+	assertTrackedCanonical(t, tracked)
+
+	tracked.SetCustom("c", "new")
+	assertTrackedCanonical(t, tracked)
+	tracked.DeleteCustom("a")
+	assertTrackedCanonical(t, tracked)
+
+	b.Parameters["host"] = bundle.ParameterDefinition{DataType: "string"}
+	b.Keywords = []string{"web"}
+	assert.NilError(t, tracked.MarkDirty("parameters", "keywords"))
+	assertTrackedCanonical(t, tracked)
+
+	b.Actions = map[string]bundle.Action{"status": {Stateless: true}}
+	b.Custom = nil
+	assert.NilError(t, tracked.MarkDirty())
+	assertTrackedCanonical(t, tracked)
+
+	assert.Check(t, is.Error(tracked.MarkDirty("parameters", "nope"), `unknown bundle section "nope"`))
+}
+
+func TestTrackedBundleStaleUntilMarked(t *testing.T) {
+	b := &bundle.Bundle{Name: "myapp", Version: "1.0.0"}
+	tracked := Track(b)
+	before, err := tracked.Canonical()
+	assert.NilError(t, err)
+	b.Version = "2.0.0"
+	stale, err := tracked.Canonical()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(stale), string(before)))
+	assert.NilError(t, tracked.MarkDirty("version"))
+	assertTrackedCanonical(t, tracked)
+}
+
+func TestTrackedBundleWrite(t *testing.T) {
+	tracked := Track(&bundle.Bundle{Name: "myapp", Version: "1.0.0"})
+	expected, err := tracked.Canonical()
+	assert.NilError(t, err)
+
+	var buf bytes.Buffer
+	n, err := tracked.WriteTo(&buf)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(n, int64(len(expected))))
+	assert.Check(t, is.Equal(buf.String(), string(expected)))
+
+	dir := fs.NewDir(t, "tracked")
+	defer dir.Remove()
+	path := filepath.Join(dir.Path(), "bundle.json")
+	assert.NilError(t, tracked.WriteFile(path, 0644, WriteFileOptions{}))
+	data, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), string(expected)))
+	assert.Check(t, is.ErrorContains(tracked.WriteFile(path, 0644, WriteFileOptions{}), "already exists"))
+	_, err = os.Stat(path)
+	assert.NilError(t, err)
+}
+
+// hugeCustomBundle returns a bundle with a large custom extension, along
+// with a small one changed by the benchmarks.
+func hugeCustomBundle() *bundle.Bundle {
+	b := largeBundle()
+	payload := map[string]interface{}{}
+	for i := 0; i < 20000; i++ {
+		payload[fmt.Sprintf("key%05d", i)] = strings.Repeat("v", 200)
+	}
+	b.Custom = map[string]interface{}{"com.example.payload": payload, "com.example.revision": 0}
+	return b
+}
+
+func BenchmarkWriteAfterCustomChange(b *testing.B) {
+	bndl := hugeCustomBundle()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bndl.Custom["com.example.revision"] = i
+		if _, err := canonicaljson.MarshalCanonical(bndl); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrackedWriteAfterCustomChange(b *testing.B) {
+	tracked := Track(hugeCustomBundle())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tracked.SetCustom("com.example.revision", i)
+		if _, err := tracked.Canonical(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTrackedBundleDigestAlgorithm(t *testing.T) {
+	old, ok := os.LookupEnv(checksum.AlgorithmEnvVar)
+	defer func() {
+		if ok {
+			os.Setenv(checksum.AlgorithmEnvVar, old)
+		} else {
+			os.Unsetenv(checksum.AlgorithmEnvVar)
+		}
+	}()
+	tracked := Track(&bundle.Bundle{Name: "myapp", Version: "1.0.0"})
+	assert.NilError(t, os.Setenv(checksum.AlgorithmEnvVar, "sha512"))
+	d, err := tracked.Digest()
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasPrefix(d, "sha512:"))
+	assertTrackedCanonical(t, tracked)
+
+	// The cached digest follows the configuration
+	assert.NilError(t, os.Setenv(checksum.AlgorithmEnvVar, "sha256"))
+	d, err = tracked.Digest()
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasPrefix(d, "sha256:"))
+}
@@ -0,0 +1,143 @@
+package cnab
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/semver"
+	"github.com/pkg/errors"
+)
+
+// UpgradeFromExtensionKey is the custom extension declaring the versions a
+// bundle can be upgraded from.
+const UpgradeFromExtensionKey = internal.Namespace + "upgrade-from"
+
+// UpgradeFrom is the content of the upgrade-from extension.
+type UpgradeFrom struct {
+	// Ranges are the semantic version ranges of the supported prior versions.
+	Ranges      []string `json:"ranges"`
+	Prereleases bool     `json:"prereleases,omitempty"`
+}
+
+// ReadUpgradeFrom returns the upgrade constraints of the bundle, or nil if it
+// has none.
+func ReadUpgradeFrom(b *bundle.Bundle) (*UpgradeFrom, error) {
+	var upgradeFrom UpgradeFrom
+	found, err := GetCustomExtension(b, UpgradeFromExtensionKey, &upgradeFrom)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &upgradeFrom, nil
+}
+
+// BreakingChangeKind is the kind of a breaking change between two versions
+// of a bundle.
+type BreakingChangeKind string
+
+// Kinds of breaking changes.
+const (
+	// BreakingRequiredParameter is a new required parameter without default,
+	// which the installation has no value for.
+	BreakingRequiredParameter BreakingChangeKind = "required-parameter"
+	// BreakingParameterType is a parameter whose type changed, so the value
+	// of the installation may not be valid anymore.
+	BreakingParameterType BreakingChangeKind = "parameter-type"
+	// BreakingRemovedCredential is a credential the installation supplies
+	// which the new version does not accept anymore.
+	BreakingRemovedCredential BreakingChangeKind = "removed-credential"
+	// BreakingMajorVersion is an upgrade to a new major version.
+	BreakingMajorVersion BreakingChangeKind = "major-version"
+)
+
+// BreakingChange is a change of a bundle which may break its installations
+// when they are upgraded.
+type BreakingChange struct {
+	Kind BreakingChangeKind `json:"kind"`
+	// Path is the JSON path of the changed element, like
+	// $.parameters["port"].
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// UpgradeReport lists the breaking changes of an upgrade, sorted by path.
+type UpgradeReport struct {
+	BreakingChanges []BreakingChange `json:"breakingChanges,omitempty"`
+}
+
+// Breaking returns true if the upgrade has breaking changes.
+func (r *UpgradeReport) Breaking() bool {
+	return len(r.BreakingChanges) > 0
+}
+
+// CheckUpgrade returns an error if the target bundle declares it cannot be
+// upgraded from the version of the installed bundle. Bundles without upgrade
+// constraints can be upgraded from any version. Upgrades which are allowed
+// are reported with their breaking changes, for the caller to require an
+// explicit confirmation. The values are the effective parameter values of
+// the upgrade: the new required parameters which have one do not break it.
+func CheckUpgrade(installed, target *bundle.Bundle, values map[string]interface{}) (*UpgradeReport, error) {
+	if err := checkUpgradeFrom(installed, target); err != nil {
+		return nil, err
+	}
+	report := &UpgradeReport{}
+	for name, def := range target.Parameters {
+		path := fmt.Sprintf("$.parameters[%q]", name)
+		old, existed := installed.Parameters[name]
+		if existed && old.DataType != def.DataType {
+			report.add(BreakingParameterType, path, "parameter %q changed from type %s to %s", name, old.DataType, def.DataType)
+		}
+		if _, ok := values[name]; !ok && def.Required && def.Default == nil && (!existed || !old.Required) {
+			report.add(BreakingRequiredParameter, path, "parameter %q is now required and has no default value", name)
+		}
+	}
+	for name := range installed.Credentials {
+		if _, ok := target.Credentials[name]; !ok {
+			report.add(BreakingRemovedCredential, fmt.Sprintf("$.credentials[%q]", name), "credential %q supplied by the installation is removed", name)
+		}
+	}
+	from, err := semver.Parse(installed.Version)
+	if err == nil {
+		to, err := semver.Parse(target.Version)
+		if err == nil && to.Major > from.Major {
+			report.add(BreakingMajorVersion, "$.version", "major version upgrade from %s to %s", installed.Version, target.Version)
+		}
+	}
+	sort.Slice(report.BreakingChanges, func(i, j int) bool {
+		a, b := report.BreakingChanges[i], report.BreakingChanges[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Kind < b.Kind
+	})
+	return report, nil
+}
+
+func (r *UpgradeReport) add(kind BreakingChangeKind, path, format string, args ...interface{}) {
+	r.BreakingChanges = append(r.BreakingChanges, BreakingChange{Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkUpgradeFrom checks the upgrade-from constraints of the target bundle.
+func checkUpgradeFrom(installed, target *bundle.Bundle) error {
+	upgradeFrom, err := ReadUpgradeFrom(target)
+	if err != nil || upgradeFrom == nil || len(upgradeFrom.Ranges) == 0 {
+		return err
+	}
+	version, err := semver.Parse(installed.Version)
+	if err != nil {
+		return errors.Wrapf(err, "cannot check the upgrade of %s to %s", installed.Name, target.Version)
+	}
+	for _, rng := range upgradeFrom.Ranges {
+		r, err := semver.ParseRange(rng)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s extension", UpgradeFromExtensionKey)
+		}
+		if r.Contains(version, upgradeFrom.Prereleases) {
+			return nil
+		}
+	}
+	return errors.Errorf("%s %s cannot be upgraded to %s: only upgrades from versions %s are supported",
+		installed.Name, installed.Version, target.Version, strings.Join(upgradeFrom.Ranges, " or "))
+}
@@ -0,0 +1,70 @@
+package cnab_test
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCheckUpgrade(t *testing.T) {
+	target := bundletest.NewTestBundle(bundletest.WithVersion("2.0.0"),
+		bundletest.WithCustom(cnab.UpgradeFromExtensionKey, cnab.UpgradeFrom{Ranges: []string{"^1.4", "2.0.0-rc.1"}}))
+
+	for _, supported := range []string{"1.4.0", "1.9.3"} {
+		_, err := cnab.CheckUpgrade(bundletest.NewTestBundle(bundletest.WithVersion(supported)), target, nil)
+		assert.Check(t, err, supported)
+	}
+	_, err := cnab.CheckUpgrade(bundletest.NewTestBundle(bundletest.WithVersion("1.3.9")), target, nil)
+	assert.Check(t, is.Error(err, "test-bundle 1.3.9 cannot be upgraded to 2.0.0: only upgrades from versions ^1.4 or 2.0.0-rc.1 are supported"))
+	_, err = cnab.CheckUpgrade(bundletest.NewTestBundle(bundletest.WithVersion("2.0.0-rc.1")), target, nil)
+	assert.Check(t, is.ErrorContains(err, "cannot be upgraded"))
+	_, err = cnab.CheckUpgrade(bundletest.NewTestBundle(bundletest.WithVersion("dev")), target, nil)
+	assert.Check(t, is.ErrorContains(err, "cannot check the upgrade of test-bundle to 2.0.0"))
+
+	// Bundles without constraints can be upgraded from any version
+	_, err = cnab.CheckUpgrade(bundletest.NewTestBundle(bundletest.WithVersion("dev")), bundletest.NewTestBundle(), nil)
+	assert.Check(t, err)
+}
+
+func TestCheckUpgradeBreakingChanges(t *testing.T) {
+	installed := bundletest.NewTestBundle(bundletest.WithVersion("1.2.0"), bundletest.WithCredentials(2),
+		bundletest.WithParameter("port", bundle.ParameterDefinition{DataType: "int", Default: 80}),
+		bundletest.WithParameter("host", bundle.ParameterDefinition{DataType: "string"}),
+		bundletest.WithParameter("debug", bundle.ParameterDefinition{DataType: "bool", Required: true}),
+	)
+	target := bundletest.NewTestBundle(bundletest.WithVersion("2.0.0"), bundletest.WithCredentials(1),
+		bundletest.WithParameter("port", bundle.ParameterDefinition{DataType: "string", Default: "80"}),
+		bundletest.WithParameter("host", bundle.ParameterDefinition{DataType: "string", Required: true}),
+		bundletest.WithParameter("debug", bundle.ParameterDefinition{DataType: "bool", Required: true}),
+		bundletest.WithParameter("token", bundle.ParameterDefinition{DataType: "string", Required: true}),
+		bundletest.WithParameter("region", bundle.ParameterDefinition{DataType: "string", Required: true, Default: "eu"}),
+	)
+	report, err := cnab.CheckUpgrade(installed, target, nil)
+	assert.NilError(t, err)
+	assert.Check(t, report.Breaking())
+	assert.Check(t, is.DeepEqual(report.BreakingChanges, []cnab.BreakingChange{
+		{Kind: cnab.BreakingRemovedCredential, Path: `$.credentials["cred-1"]`, Message: `credential "cred-1" supplied by the installation is removed`},
+		{Kind: cnab.BreakingRequiredParameter, Path: `$.parameters["host"]`, Message: `parameter "host" is now required and has no default value`},
+		{Kind: cnab.BreakingParameterType, Path: `$.parameters["port"]`, Message: `parameter "port" changed from type int to string`},
+		{Kind: cnab.BreakingRequiredParameter, Path: `$.parameters["token"]`, Message: `parameter "token" is now required and has no default value`},
+		{Kind: cnab.BreakingMajorVersion, Path: "$.version", Message: "major version upgrade from 1.2.0 to 2.0.0"},
+	}))
+
+	// The new required parameters given a value do not break the upgrade
+	report, err = cnab.CheckUpgrade(installed, target, map[string]interface{}{"host": "example.com", "token": "s3cr3t"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(report.BreakingChanges, []cnab.BreakingChange{
+		{Kind: cnab.BreakingRemovedCredential, Path: `$.credentials["cred-1"]`, Message: `credential "cred-1" supplied by the installation is removed`},
+		{Kind: cnab.BreakingParameterType, Path: `$.parameters["port"]`, Message: `parameter "port" changed from type int to string`},
+		{Kind: cnab.BreakingMajorVersion, Path: "$.version", Message: "major version upgrade from 1.2.0 to 2.0.0"},
+	}))
+
+	// Compatible upgrades have no breaking changes
+	report, err = cnab.CheckUpgrade(installed, bundletest.NewTestBundle(bundletest.WithVersion("1.3.0"), bundletest.WithCredentials(3)), nil)
+	assert.NilError(t, err)
+	assert.Check(t, !report.Breaking())
+}
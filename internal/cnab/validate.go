@@ -0,0 +1,273 @@
+package cnab
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/semver"
+	"github.com/docker/app/pkg/telemetry"
+)
+
+// Severity is the importance of a validation error.
+type Severity string
+
+// Severities of validation errors, matching the SARIF levels. Only errors
+// make a bundle invalid.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// Codes of validation errors.
+const (
+	CodeMissingInvocationImage = "missing-invocation-image"
+	CodeLatestVersion          = "latest-version"
+	CodeInvalidVersion         = "invalid-version"
+	CodeInvalidImage           = "invalid-image"
+	CodeUnknownImageType       = "unknown-image-type"
+	CodeDuplicatePlatform      = "duplicate-platform"
+	CodeInvalidDefault         = "invalid-default"
+	CodeEmptyDestination       = "empty-destination"
+	CodeInvalidCredential      = "invalid-credential"
+	CodeCoreAction             = "core-action"
+	CodeInvalidActionName      = "invalid-action-name"
+	CodeStatelessModifies      = "stateless-modifies"
+	CodeInvalidMetadata        = "invalid-metadata"
+	CodeInvalidMaintainer      = "invalid-maintainer"
+	CodeInvalidTemplate        = "invalid-template"
+	CodeLocationConflict       = "location-conflict"
+	CodeInvalidLabel           = "invalid-label"
+	CodeInvalidActionArgument  = "invalid-action-argument"
+	CodeInvalidMediaType       = "invalid-media-type"
+)
+
+// ValidationError is a violation found in a bundle.
+type ValidationError struct {
+	// Path is the JSON path of the offending element, for instance
+	// "$.invocationImages[0].image".
+	Path     string   `json:"path"`
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors are all the violations found in a bundle.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// HasCode returns true if one of the violations has the code, for instance
+// CodeMissingInvocationImage.
+func (e ValidationErrors) HasCode(code string) bool {
+	for _, err := range e {
+		if err.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns the violations with the error severity.
+func (e ValidationErrors) Errors() ValidationErrors {
+	return e.filter(SeverityError)
+}
+
+// Warnings returns the violations with the warning severity.
+func (e ValidationErrors) Warnings() ValidationErrors {
+	return e.filter(SeverityWarning)
+}
+
+// Err returns the violations with the error severity, or nil if there is
+// none, so warnings alone do not fail.
+func (e ValidationErrors) Err() error {
+	if errs := e.Errors(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (e ValidationErrors) filter(severity Severity) ValidationErrors {
+	var filtered ValidationErrors
+	for _, err := range e {
+		if err.Severity == severity {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+// ValidateOptions contains options for validating bundles
+type ValidateOptions struct {
+	strictImageTypes bool
+	cache            *Cache
+}
+
+// WithStrictImageTypes makes Validate report images of unregistered types as
+// errors instead of warnings, see RegisterImageType.
+func WithStrictImageTypes() func(*ValidateOptions) {
+	return func(o *ValidateOptions) {
+		o.strictImageTypes = true
+	}
+}
+
+// Validate checks the bundle like bundle.Validate, along with its image
+// references, parameters and credentials, but reports all the violations
+// instead of the first one. Images are checked by the registered image type
+// they belong to. See WithCache to memoize the result.
+func Validate(b *bundle.Bundle, opts ...func(*ValidateOptions)) ValidationErrors {
+	var o ValidateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.cache != nil {
+		return o.cache.validate(b, o, func() ValidationErrors { return validate(b, o) })
+	}
+	return validate(b, o)
+}
+
+func validate(b *bundle.Bundle, o ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+	add := func(path, code string, severity Severity, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Path: path, Code: code, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	addImageError := func(path, kind string, img bundle.BaseImage, err error) {
+		switch {
+		case err == nil:
+		case IsUnknownImageType(err):
+			severity := SeverityWarning
+			if o.strictImageTypes {
+				severity = SeverityError
+			}
+			add(path+".imageType", CodeUnknownImageType, severity, "%s %q: %s", kind, img.Image, err)
+		default:
+			add(path+".image", CodeInvalidImage, SeverityError, "%s %q: %s", kind, img.Image, err)
+		}
+	}
+
+	if len(b.InvocationImages) == 0 {
+		add("$.invocationImages", CodeMissingInvocationImage, SeverityError, "at least one invocation image must be defined in the bundle")
+	}
+	if b.Version == "latest" {
+		add("$.version", CodeLatestVersion, SeverityError, "'latest' is not a valid bundle version")
+	} else if _, err := semver.ParseStrict(b.Version); err != nil {
+		add("$.version", CodeInvalidVersion, SeverityWarning, "%s", err)
+	}
+
+	for i, m := range b.Maintainers {
+		path := fmt.Sprintf("$.maintainers[%d]", i)
+		if strings.TrimSpace(m.Name) == "" {
+			add(path+".name", CodeInvalidMaintainer, SeverityError, "maintainer %d has no name", i)
+		}
+		if m.Email != "" && !validEmail(m.Email) {
+			add(path+".email", CodeInvalidMaintainer, SeverityError, "invalid email %q of maintainer %q", m.Email, m.Name)
+		}
+		if m.URL != "" && !validWebURL(m.URL) {
+			add(path+".url", CodeInvalidMaintainer, SeverityError, "invalid URL %q of maintainer %q, it must be an http or https URL", m.URL, m.Name)
+		}
+	}
+
+	for i, img := range b.InvocationImages {
+		path := fmt.Sprintf("$.invocationImages[%d]", i)
+		addImageError(path, "invocation image", img.BaseImage, ValidateInvocationImage(img))
+		if err := ValidateMediaType(img.BaseImage, true); err != nil {
+			add(path+".mediaType", CodeInvalidMediaType, SeverityError, "invocation image %q: %s", img.Image, err)
+		}
+	}
+	platforms := map[string]int{}
+	for i, img := range b.InvocationImages {
+		platform := platformName(img.Platform)
+		if first, ok := platforms[platform]; ok {
+			add(fmt.Sprintf("$.invocationImages[%d].platform", i), CodeDuplicatePlatform, SeverityError, "invocation images %d and %d are both built for %s", first, i, platform)
+			continue
+		}
+		platforms[platform] = i
+	}
+	for _, name := range ImageKeys(b) {
+		img := b.Images[name]
+		path := fmt.Sprintf("$.images[%q]", name)
+		addImageError(path, "image", img.BaseImage, ValidateImage(img.BaseImage))
+		if err := ValidateMediaType(img.BaseImage, false); err != nil {
+			add(path+".mediaType", CodeInvalidMediaType, SeverityError, "image %q: %s", img.Image, err)
+		}
+	}
+
+	for _, name := range ParameterNames(b) {
+		def := b.Parameters[name]
+		if def.Default != nil {
+			if err := def.ValidateParameterValue(def.Default); err != nil {
+				add(fmt.Sprintf("$.parameters[%q].default", name), CodeInvalidDefault, SeverityError, "invalid default value of parameter %q: %s", name, err)
+			}
+		}
+		if err := ValidateDestination(def.Destination); err != nil {
+			add(fmt.Sprintf("$.parameters[%q].destination", name), CodeEmptyDestination, SeverityError, "parameter %q: %s", name, err)
+		}
+		if dest := def.Destination; dest != nil {
+			if err := ValidateLocationTemplate(dest.Path); err != nil {
+				add(fmt.Sprintf("$.parameters[%q].destination.path", name), CodeInvalidTemplate, SeverityError, "parameter %q: %s", name, err)
+			}
+		}
+	}
+
+	for _, name := range ActionNames(b) {
+		path := fmt.Sprintf("$.actions[%q]", name)
+		switch {
+		case IsCoreAction(name):
+			add(path, CodeCoreAction, SeverityError, "action %q is built in and cannot be redefined", name)
+		case !actionName.MatchString(name):
+			add(path, CodeInvalidActionName, SeverityError, "invalid action name %q, it must only contain letters, digits, '.', '-' and '_'", name)
+		}
+		if action := b.Actions[name]; action.Stateless && action.Modifies {
+			add(path, CodeStatelessModifies, SeverityError, "action %q is stateless and cannot modify resources", name)
+		}
+	}
+
+	for _, name := range CredentialNames(b) {
+		if location := b.Credentials[name]; location.EnvironmentVariable == "" && location.Path == "" {
+			add(fmt.Sprintf("$.credentials[%q]", name), CodeInvalidCredential, SeverityError, "credential %q must have an environment variable or a path", name)
+		}
+		if err := ValidateLocationTemplate(b.Credentials[name].Path); err != nil {
+			add(fmt.Sprintf("$.credentials[%q].path", name), CodeInvalidTemplate, SeverityError, "credential %q: %s", name, err)
+		}
+	}
+	errs = append(errs, locationConflicts(b)...)
+	if _, err := ReadMetadata(b); err != nil {
+		add(fmt.Sprintf("$.custom[%q]", MetadataExtensionKey), CodeInvalidMetadata, SeverityError, "%s", err)
+	}
+	if _, err := ReadLabels(b); err != nil {
+		add(fmt.Sprintf("$.custom[%q]", LabelsExtensionKey), CodeInvalidLabel, SeverityError, "%s", err)
+	}
+	if _, err := ReadActionArguments(b); err != nil {
+		add(fmt.Sprintf("$.custom[%q]", ActionArgumentsExtensionKey), CodeInvalidActionArgument, SeverityError, "%s", err)
+	}
+	for _, err := range errs {
+		telemetry.Add(telemetry.MetricValidationFailures, 1, telemetry.Labels{"code": err.Code, "severity": string(err.Severity)})
+	}
+	return errs
+}
+
+// validEmail returns true if the value is a bare email address, without
+// display name nor angle brackets.
+func validEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
+
+// validWebURL returns true if the value is an absolute http or https URL.
+func validWebURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
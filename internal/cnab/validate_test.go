@@ -0,0 +1,98 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/app/pkg/telemetry/telemetrytest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestValidateReportsAllViolations(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:    "app",
+		Version: "1.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app"}},
+		},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"port": {DataType: "int", Default: "80"},
+			"host": {DataType: "string", Destination: &bundle.Location{}},
+		},
+		Credentials: map[string]bundle.Location{
+			"token": {},
+		},
+	}
+	errs := Validate(b)
+	var codes, paths []string
+	for _, err := range errs {
+		codes = append(codes, err.Code)
+		paths = append(paths, err.Path)
+	}
+	assert.Check(t, is.DeepEqual(codes, []string{CodeInvalidVersion, CodeInvalidImage, CodeEmptyDestination, CodeInvalidDefault, CodeInvalidCredential}))
+	assert.Check(t, is.DeepEqual(paths, []string{
+		"$.version",
+		"$.invocationImages[0].image",
+		`$.parameters["host"].destination`,
+		`$.parameters["port"].default`,
+		`$.credentials["token"]`,
+	}))
+	assert.Check(t, is.Len(errs.Warnings(), 1))
+	assert.Check(t, is.Len(errs.Errors(), 4))
+	assert.Check(t, is.ErrorContains(errs.Err(), `$.invocationImages[0].image: invocation image "app": tag or digest is required`))
+}
+
+func TestValidateWarningsDoNotFail(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0"}}},
+	}
+	errs := Validate(b)
+	assert.Check(t, is.Len(errs, 1))
+	assert.Check(t, is.Equal(errs[0].Severity, SeverityWarning))
+	assert.NilError(t, errs.Err())
+}
+
+func TestValidateMissingInvocationImage(t *testing.T) {
+	errs := Validate(&bundle.Bundle{Name: "app", Version: "latest"})
+	assert.Check(t, is.Equal(errs.Error(), "$.invocationImages: at least one invocation image must be defined in the bundle\n$.version: 'latest' is not a valid bundle version"))
+}
+
+func TestValidateMetrics(t *testing.T) {
+	recorder, restore := telemetrytest.Install()
+	defer restore()
+	Validate(&bundle.Bundle{Name: "app", Version: "latest"})
+	assert.Check(t, is.Equal(recorder.Sum(telemetry.MetricValidationFailures, telemetry.Labels{"code": CodeMissingInvocationImage, "severity": "error"}), 1.0))
+	assert.Check(t, is.Equal(recorder.Sum(telemetry.MetricValidationFailures, telemetry.Labels{"code": CodeLatestVersion}), 1.0))
+	assert.Check(t, is.Equal(recorder.Sum(telemetry.MetricValidationFailures, nil), 2.0))
+}
+
+func TestValidateMaintainers(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:1.0.0"}}},
+		Maintainers: []bundle.Maintainer{
+			{Name: "Jane", Email: "jane@example.com", URL: "https://example.com/jane"},
+			{Name: "John", Email: "John <john@example.com>", URL: "ftp://example.com"},
+			{Email: "not an email", URL: "example.com"},
+		},
+	}
+	errs := Validate(b)
+	var paths []string
+	for _, err := range errs {
+		assert.Check(t, is.Equal(err.Code, CodeInvalidMaintainer))
+		paths = append(paths, err.Path)
+	}
+	assert.Check(t, is.DeepEqual(paths, []string{
+		"$.maintainers[1].email",
+		"$.maintainers[1].url",
+		"$.maintainers[2].name",
+		"$.maintainers[2].email",
+		"$.maintainers[2].url",
+	}))
+	assert.Check(t, is.ErrorContains(errs.Err(), `$.maintainers[1].url: invalid URL "ftp://example.com" of maintainer "John", it must be an http or https URL`))
+}
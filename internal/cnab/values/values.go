@@ -0,0 +1,210 @@
+// Package values loads the values of bundle parameters from files, merges
+// them and converts them to the types of the parameter definitions.
+package values
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/yaml"
+	"github.com/pkg/errors"
+)
+
+// Format is the format of a values file.
+type Format string
+
+// Formats of values files.
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	// FormatEnv is a file of KEY=VALUE lines, where blank lines and lines
+	// starting with # are ignored.
+	FormatEnv Format = "env"
+)
+
+// Values are parameter values keyed by parameter name. Nested objects are
+// flattened, their keys being joined with dots as in parameter names.
+type Values map[string]interface{}
+
+// FormatOf returns the format of a file from its extension: .json, .yml,
+// .yaml or .env.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yml", ".yaml":
+		return FormatYAML, nil
+	case ".env":
+		return FormatEnv, nil
+	default:
+		return "", errors.Errorf("unknown format of values file %q, expected a .json, .yml, .yaml or .env file", path)
+	}
+}
+
+// LoadFiles loads and merges values files, the values of a file taking
+// precedence over the values of the files before it.
+func LoadFiles(paths ...string) (Values, error) {
+	sources := make([]Values, len(paths))
+	for i, path := range paths {
+		format, err := FormatOf(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if sources[i], err = Load(bytes.NewReader(data), format); err != nil {
+			return nil, errors.Wrapf(err, "failed to load values file %q", path)
+		}
+	}
+	return Merge(sources...), nil
+}
+
+// Load reads values in the given format. JSON numbers are decoded as
+// json.Number, so they keep their precision until Coerce converts them.
+func Load(r io.Reader, format Format) (Values, error) {
+	switch format {
+	case FormatJSON:
+		var m map[string]interface{}
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		if err := dec.Decode(&m); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return flatten(m), nil
+	case FormatYAML:
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]interface{}
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		return flatten(m), nil
+	case FormatEnv:
+		return loadEnv(r)
+	default:
+		return nil, errors.Errorf("unknown values format %q", format)
+	}
+}
+
+func loadEnv(r io.Reader) (Values, error) {
+	values := Values{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return nil, errors.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		values[key] = unquote(strings.TrimSpace(kv[1]))
+	}
+	return values, scanner.Err()
+}
+
+// unquote removes the single or double quotes around a value.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// flatten joins the keys of nested objects with dots. YAML objects, decoded
+// with interface keys, are flattened as well.
+func flatten(m map[string]interface{}) Values {
+	values := Values{}
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for k, item := range v {
+				walk(prefix+k+".", item)
+			}
+		case map[interface{}]interface{}:
+			for k, item := range v {
+				walk(prefix+fmt.Sprint(k)+".", item)
+			}
+		default:
+			values[strings.TrimSuffix(prefix, ".")] = value
+		}
+	}
+	walk("", m)
+	delete(values, "")
+	return values
+}
+
+// Merge merges values, the values of a source taking precedence over the
+// values of the sources before it.
+func Merge(sources ...Values) Values {
+	merged := Values{}
+	for _, source := range sources {
+		for name, value := range source {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// Coerce converts the values to the types of the parameters of the bundle
+// and validates them. The sorted names of the values which are not
+// parameters of the bundle are returned, so they can be reported.
+func Coerce(b *bundle.Bundle, values Values) (map[string]interface{}, []string, error) {
+	coerced := map[string]interface{}{}
+	var unused []string
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def, ok := b.Parameters[name]
+		if !ok {
+			unused = append(unused, name)
+			continue
+		}
+		value, err := coerce(def, values[name])
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid value for parameter %q", name)
+		}
+		if err := def.ValidateParameterValue(value); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid value for parameter %q", name)
+		}
+		coerced[name] = value
+	}
+	return coerced, unused, nil
+}
+
+func coerce(def bundle.ParameterDefinition, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if def.DataType == "int" || def.DataType == "bool" {
+			return def.ConvertValue(v)
+		}
+		return v, nil
+	case nil, map[string]interface{}, []interface{}:
+		return value, nil
+	case json.Number, float64:
+		return cnab.NormalizeParameterValue(def, v)
+	default:
+		if def.DataType == "string" {
+			return fmt.Sprint(v), nil
+		}
+		return cnab.NormalizeParameterValue(def, v)
+	}
+}
@@ -0,0 +1,101 @@
+package values
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestLoad(t *testing.T) {
+	for format, data := range map[Format]string{
+		FormatJSON: `{"services": {"web": {"port": 80}}, "debug": true, "name": "app"}`,
+		FormatYAML: "services:\n  web:\n    port: 80\ndebug: true\nname: app\n",
+		FormatEnv:  "# web service\nservices.web.port=80\n\ndebug = true\nname=\"app\"\n",
+	} {
+		values, err := Load(strings.NewReader(data), format)
+		assert.NilError(t, err, format)
+		assert.Check(t, is.Len(values, 3), format)
+		assert.Check(t, is.Equal(values["name"], "app"), format)
+		_, ok := values["services.web.port"]
+		assert.Check(t, ok, format)
+	}
+
+	_, err := Load(strings.NewReader("port"), FormatEnv)
+	assert.Check(t, is.Error(err, "line 1: expected KEY=VALUE"))
+	_, err = Load(strings.NewReader(""), Format("toml"))
+	assert.Check(t, is.Error(err, `unknown values format "toml"`))
+}
+
+func TestLoadFilesPrecedence(t *testing.T) {
+	dir := fs.NewDir(t, "values",
+		fs.WithFile("defaults.yml", "port: 80\nname: app\n"),
+		fs.WithFile("prod.json", `{"port": 443}`),
+		fs.WithFile("local.env", "name=local\n"),
+		fs.WithFile("values.toml", ""),
+	)
+	defer dir.Remove()
+
+	values, err := LoadFiles(dir.Join("defaults.yml"), dir.Join("prod.json"), dir.Join("local.env"))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(values, Values{"port": json.Number("443"), "name": "local"}))
+
+	_, err = LoadFiles(dir.Join("values.toml"))
+	assert.Check(t, is.ErrorContains(err, "unknown format of values file"))
+}
+
+func TestCoerce(t *testing.T) {
+	b := &bundle.Bundle{Parameters: map[string]bundle.ParameterDefinition{
+		"port":    {DataType: "int"},
+		"debug":   {DataType: "bool"},
+		"version": {DataType: "string"},
+		"mode":    {DataType: "string", AllowedValues: []interface{}{"fast", "safe"}},
+	}}
+	coerced, unused, err := Coerce(b, Values{"port": float64(80), "debug": "true", "version": 2, "replicas": "3", "cache": 1})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(coerced, map[string]interface{}{"port": 80, "debug": true, "version": "2"}))
+	assert.Check(t, is.DeepEqual(unused, []string{"cache", "replicas"}))
+
+	_, _, err = Coerce(b, Values{"port": "http"})
+	assert.Check(t, is.ErrorContains(err, `invalid value for parameter "port"`))
+	_, _, err = Coerce(b, Values{"mode": "slow"})
+	assert.Check(t, is.ErrorContains(err, `invalid value for parameter "mode"`))
+}
+
+func TestCoerceNumbers(t *testing.T) {
+	b := &bundle.Bundle{Parameters: map[string]bundle.ParameterDefinition{
+		"big":      {DataType: "int"},
+		"exponent": {DataType: "int"},
+		"float":    {DataType: "int"},
+		"id":       {DataType: "string"},
+		"ratio":    {DataType: "string"},
+	}}
+	values, err := Load(strings.NewReader(`{"big": 10000000000000001, "exponent": 1e3, "float": 3.0, "id": 10000000000000001, "ratio": 0.5}`), FormatJSON)
+	assert.NilError(t, err)
+	coerced, _, err := Coerce(b, values)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(coerced, map[string]interface{}{
+		"big":      10000000000000001,
+		"exponent": 1000,
+		"float":    3,
+		"id":       "10000000000000001",
+		"ratio":    "0.5",
+	}))
+	data, err := json.Marshal(coerced)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), `{"big":10000000000000001,"exponent":1000,"float":3,"id":"10000000000000001","ratio":"0.5"}`))
+
+	for value, expected := range map[string]string{
+		"3.5":                 "3.5 is not an integer",
+		"1e100":               "1e100 is out of the int range",
+		"9223372036854775808": "9223372036854775808 is out of the int range",
+		"1.00000000000000000000000000000000000000000000000000000000000000000000000000000000001": "is not an integer",
+	} {
+		_, _, err := Coerce(b, Values{"big": json.Number(value)})
+		assert.Check(t, is.ErrorContains(err, expected), value)
+	}
+}
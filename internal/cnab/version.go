@@ -0,0 +1,42 @@
+package cnab
+
+import (
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/semver"
+	"github.com/pkg/errors"
+)
+
+// ValidateVersion checks the bundle version is a strict semantic version.
+func ValidateVersion(b *bundle.Bundle) error {
+	_, err := semver.ParseStrict(b.Version)
+	return errors.Wrapf(err, "invalid version of bundle %q", b.Name)
+}
+
+// CompareVersions compares the semantic versions of two bundles, returning
+// -1, 0 or 1 if the version of a is lower than, equal to or greater than the
+// version of b.
+func CompareVersions(a, b *bundle.Bundle) (int, error) {
+	va, err := semver.Parse(a.Version)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := semver.Parse(b.Version)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// MatchesConstraint returns true if the bundle version is in the version
+// range, like ">=1.2.0 <2.0.0" or "^1.2". Prerelease versions never match.
+func MatchesConstraint(b *bundle.Bundle, constraint string) (bool, error) {
+	version, err := semver.Parse(b.Version)
+	if err != nil {
+		return false, err
+	}
+	r, err := semver.ParseRange(constraint)
+	if err != nil {
+		return false, err
+	}
+	return r.Contains(version, false), nil
+}
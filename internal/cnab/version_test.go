@@ -0,0 +1,48 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestValidateVersion(t *testing.T) {
+	assert.NilError(t, ValidateVersion(&bundle.Bundle{Name: "app", Version: "1.2.3-rc.1+build"}))
+	assert.Error(t, ValidateVersion(&bundle.Bundle{Name: "app", Version: "v1.2"}),
+		`invalid version of bundle "app": invalid version "v1.2": not a semantic version`)
+}
+
+func TestCompareVersions(t *testing.T) {
+	older := &bundle.Bundle{Version: "1.2.0"}
+	newer := &bundle.Bundle{Version: "1.10.0"}
+	c, err := CompareVersions(older, newer)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(c, -1))
+	c, err = CompareVersions(newer, older)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(c, 1))
+
+	_, err = CompareVersions(older, &bundle.Bundle{Version: "latest"})
+	assert.ErrorContains(t, err, "invalid version")
+}
+
+func TestMatchesConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		version    string
+		constraint string
+		expected   bool
+	}{
+		{"1.2.0", ">=1.2.0 <2.0.0", true},
+		{"2.0.0", ">=1.2.0 <2.0.0", false},
+		{"1.5.3", "^1.2", true},
+		{"1.5.0-beta", ">=1.2.0 <2.0.0", false},
+	} {
+		matches, err := MatchesConstraint(&bundle.Bundle{Version: tc.version}, tc.constraint)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(matches, tc.expected), "%s %s", tc.version, tc.constraint)
+	}
+	_, err := MatchesConstraint(&bundle.Bundle{Version: "1.0.0"}, ">=foo")
+	assert.ErrorContains(t, err, "invalid version range")
+}
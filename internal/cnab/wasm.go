@@ -0,0 +1,39 @@
+package cnab
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImageTypeWasm is the image type of invocation images which are WebAssembly
+// modules, run with a WASI runtime instead of a container runtime.
+const ImageTypeWasm = "wasm"
+
+// ValidateWasmReference checks the reference of a WebAssembly module is a
+// path, or a file or https URL, to a .wasm file.
+func ValidateWasmReference(image string) error {
+	path := image
+	if strings.Contains(image, "://") {
+		u, err := url.Parse(image)
+		if err != nil {
+			return errors.Wrapf(err, "invalid wasm module reference %q", image)
+		}
+		switch u.Scheme {
+		case "file":
+			path = u.Path
+		case "https":
+			if u.Host == "" {
+				return errors.Errorf("invalid wasm module reference %q: missing host", image)
+			}
+			path = u.Path
+		default:
+			return errors.Errorf("invalid wasm module reference %q: unsupported scheme %q, expected file or https", image, u.Scheme)
+		}
+	}
+	if !strings.HasSuffix(path, ".wasm") || len(path) == len(".wasm") {
+		return errors.Errorf("invalid wasm module reference %q: expected a .wasm file", image)
+	}
+	return nil
+}
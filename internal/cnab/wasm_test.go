@@ -0,0 +1,43 @@
+package cnab
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestValidateWasmReference(t *testing.T) {
+	for _, valid := range []string{
+		"installer.wasm",
+		"/opt/modules/installer.wasm",
+		"file:///opt/modules/installer.wasm",
+		"https://example.com/installer.wasm",
+	} {
+		assert.Check(t, ValidateWasmReference(valid), valid)
+	}
+	for invalid, expected := range map[string]string{
+		"installer":                             "expected a .wasm file",
+		".wasm":                                 "expected a .wasm file",
+		"http://example.com/installer.wasm":     `unsupported scheme "http"`,
+		"https:///installer.wasm":               "missing host",
+		"https://example.com/installer.wasm/ui": "expected a .wasm file",
+	} {
+		assert.Check(t, is.ErrorContains(ValidateWasmReference(invalid), expected), invalid)
+	}
+}
+
+func TestValidateWasmInvocationImage(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:    "app",
+		Version: "1.0.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: ImageTypeWasm, Image: "installer"}},
+		},
+	}
+	errs := Validate(b)
+	assert.Assert(t, is.Len(errs, 1))
+	assert.Check(t, is.Equal(errs[0].Path, "$.invocationImages[0].image"))
+	assert.Check(t, is.ErrorContains(errs[0], "expected a .wasm file"))
+}
@@ -0,0 +1,85 @@
+package cnab
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/pkg/errors"
+)
+
+// WriteFileOptions are the options of WriteFile.
+type WriteFileOptions struct {
+	// Force overwrites the destination file if it exists.
+	Force bool
+	// Canonicalizer defaults to DockerCanonicalJSON.
+	Canonicalizer Canonicalizer
+}
+
+// WriteFile writes the canonical JSON document of the bundle to a file.
+// Unlike bundle.WriteFile, the document is written to a temporary file in
+// the same directory and synced before being moved to the destination, so a
+// crash never leaves a truncated bundle behind. Existing files are only
+// overwritten with the Force option, otherwise the temporary file is hard
+// linked to the destination, which fails atomically if it exists.
+func WriteFile(b *bundle.Bundle, path string, mode os.FileMode, opts WriteFileOptions) error {
+	data, err := MarshalCanonical(b, WithCanonicalizer(opts.Canonicalizer))
+	if err != nil {
+		return err
+	}
+	return WriteDocument(data, path, mode, opts)
+}
+
+// WriteDocument writes an already marshaled bundle document to a file, as
+// WriteFile does, for the documents which are not canonical JSON.
+func WriteDocument(data []byte, path string, mode os.FileMode, opts WriteFileOptions) error {
+	if opts.Force {
+		return ioutils.AtomicWriteFile(path, data, mode)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // the file is linked to the destination or left unused
+	if err := writeSynced(tmp, data, mode); err != nil {
+		return err
+	}
+	err = os.Link(tmp.Name(), path)
+	switch {
+	case err == nil:
+		return nil
+	case os.IsExist(err):
+		return errors.Errorf("%q already exists", path)
+	}
+	// Some file systems do not support hard links, the destination is then
+	// created exclusively and written in place
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if os.IsExist(err) {
+		return errors.Errorf("%q already exists", path)
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeSynced(f, data, mode); err != nil {
+		os.Remove(path) //nolint:errcheck // the write error is more relevant
+		return err
+	}
+	return nil
+}
+
+// writeSynced writes the data to the file, syncs and closes it.
+func writeSynced(f *os.File, data []byte, mode os.FileMode) error {
+	_, err := f.Write(data)
+	if err == nil {
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package cnab
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := fs.NewDir(t, "write")
+	defer dir.Remove()
+	path := dir.Join("bundle.json")
+	b := &bundle.Bundle{Name: "app", Version: "1.0.0"}
+
+	assert.NilError(t, WriteFile(b, path, 0600, WriteFileOptions{}))
+	data, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	parsed, err := Parse(data)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(parsed.Name, "app"))
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(info.Mode().Perm(), os.FileMode(0600)))
+
+	b.Version = "1.1.0"
+	assert.Check(t, is.ErrorContains(WriteFile(b, path, 0600, WriteFileOptions{}), "already exists"))
+	assert.NilError(t, WriteFile(b, path, 0600, WriteFileOptions{Force: true}))
+	data, err = ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(data), `"version":"1.1.0"`))
+
+	// No temporary file is left behind
+	files, err := ioutil.ReadDir(dir.Path())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(files, 1))
+}
+
+func TestWriteFileConcurrently(t *testing.T) {
+	dir := fs.NewDir(t, "write")
+	defer dir.Remove()
+	path := dir.Join("bundle.json")
+
+	// Only one of the writers creates the file, the others fail
+	errs := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func(i int) {
+			errs <- WriteFile(&bundle.Bundle{Name: "app", Version: fmt.Sprintf("1.0.%d", i)}, path, 0644, WriteFileOptions{})
+		}(i)
+	}
+	written := 0
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err == nil {
+			written++
+		} else {
+			assert.Check(t, is.ErrorContains(err, "already exists"))
+		}
+	}
+	assert.Check(t, is.Equal(written, 1))
+	files, err := ioutil.ReadDir(dir.Path())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(files, 1))
+}
@@ -0,0 +1,45 @@
+package cnab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/yaml"
+	"github.com/pkg/errors"
+)
+
+// ParseYAML reads a bundle written in YAML. The document is converted to
+// JSON and decoded as Parse does, so YAML and JSON bundles share the same
+// model. Comments are ignored.
+func ParseYAML(r io.Reader) (*bundle.Bundle, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxAutoInputSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bundle")
+	}
+	if len(data) > MaxAutoInputSize {
+		return nil, fmt.Errorf("invalid bundle: larger than %d bytes", MaxAutoInputSize)
+	}
+	return parseYAML(data)
+}
+
+// WriteYAML writes the bundle as a YAML document, with the fields of the
+// JSON document sorted by name.
+func WriteYAML(w io.Writer, b *bundle.Bundle) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,56 @@
+package cnab_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+	b := bundletest.NewTestBundle(
+		bundletest.WithParameters(2),
+		bundletest.WithParameter("replicas", bundle.ParameterDefinition{DataType: "int", Default: float64(3)}),
+		bundletest.WithImages(1),
+		bundletest.WithCredentials(1),
+		bundletest.WithCustom("com.example", map[string]interface{}{"enabled": true, "version": "1.0"}),
+	)
+	buf := bytes.NewBuffer(nil)
+	assert.NilError(t, cnab.WriteYAML(buf, b))
+	assert.Check(t, is.Contains(buf.String(), "name: test-bundle\n"))
+
+	parsed, err := cnab.ParseYAML(buf)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(parsed, b))
+}
+
+func TestParseYAMLWithComments(t *testing.T) {
+	b, err := cnab.ParseYAML(strings.NewReader(`# The application bundle
+name: myapp
+version: 1.0.0 # bumped on release
+invocationImages:
+  - imageType: docker
+    image: myapp-invoc:1.0.0
+parameters:
+  port:
+    type: int
+    default: 8080
+`))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Name, "myapp"))
+	assert.Check(t, is.Equal(b.Version, "1.0.0"))
+	assert.Check(t, is.Equal(b.InvocationImages[0].Image, "myapp-invoc:1.0.0"))
+	assert.Check(t, is.Equal(b.Parameters["port"].Default, float64(8080)))
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	_, err := cnab.ParseYAML(strings.NewReader("- a list"))
+	assert.Check(t, is.ErrorContains(err, "not a JSON or YAML object"))
+	_, err = cnab.ParseYAML(strings.NewReader("name: [unclosed"))
+	assert.Check(t, is.ErrorContains(err, "invalid bundle"))
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 
 	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
 	"github.com/docker/app/internal/packager"
 	"github.com/docker/app/internal/store"
 	"github.com/docker/app/types"
@@ -24,8 +25,9 @@ import (
 )
 
 type bundleOptions struct {
-	out string
-	tag string
+	out     string
+	tag     string
+	encrypt bool
 }
 
 func bundleCmd(dockerCli command.Cli) *cobra.Command {
@@ -42,6 +44,7 @@ func bundleCmd(dockerCli command.Cli) *cobra.Command {
 
 	cmd.Flags().StringVarP(&opts.out, "output", "o", "bundle.json", "Output file (- for stdout)")
 	cmd.Flags().StringVarP(&opts.tag, "tag", "t", "", "Name and optionally a tag in the 'name:tag' format")
+	cmd.Flags().BoolVar(&opts.encrypt, "encrypt", false, "Encrypt the bundle with the passphrase of the "+bundlePassphraseEnvVar+" environment variable")
 	return cmd
 }
 
@@ -63,6 +66,9 @@ func runBundle(dockerCli command.Cli, appName string, opts bundleOptions) error
 	}
 
 	fmt.Fprintf(os.Stdout, "Invocation image %q successfully built\n", bundle.InvocationImages[0].Image)
+	if opts.encrypt {
+		return writeEncryptedBundle(dockerCli, bundle, opts.out)
+	}
 	bundleBytes, err := json.MarshalIndent(bundle, "", "\t")
 	if err != nil {
 		return err
@@ -71,7 +77,25 @@ func runBundle(dockerCli command.Cli, appName string, opts bundleOptions) error
 		_, err = dockerCli.Out().Write(bundleBytes)
 		return err
 	}
-	return ioutil.WriteFile(opts.out, bundleBytes, 0644)
+	return cnab.WriteDocument(bundleBytes, opts.out, 0644, cnab.WriteFileOptions{Force: true})
+}
+
+// writeEncryptedBundle encrypts the bundle with the passphrase of the
+// environment and writes it to a file, or to the standard output.
+func writeEncryptedBundle(dockerCli command.Cli, bndl *bundle.Bundle, out string) error {
+	if out != "-" {
+		return cnab.WriteFileEncrypted(bndl, out, 0644, cnab.WriteFileOptions{Force: true}, bundlePassphrase)
+	}
+	passphrase, err := bundlePassphrase()
+	if err != nil {
+		return err
+	}
+	data, err := cnab.Encrypt(bndl, passphrase)
+	if err != nil {
+		return err
+	}
+	_, err = dockerCli.Out().Write(data)
+	return err
 }
 
 func makeBundle(dockerCli command.Cli, appName string, refOverride reference.NamedTagged) (*bundle.Bundle, error) {
@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/distribution/reference"
+	"github.com/spf13/cobra"
+)
+
+type promoteOptions struct {
+	policies []string
+}
+
+func channelCmd(dockerCli command.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "channel COMMAND",
+		Short: "Publish and promote the bundles of the bundle store between channels",
+		Long: `Track which version of a bundle is available on each channel, like "staging" or "prod".
+Promoting a version only moves the channel, the bundle is neither copied nor pushed again, and every promotion is kept in the history of the bundle.`,
+		Args: cli.NoArgs,
+		RunE: command.ShowHelp(dockerCli.Err()),
+	}
+	cmd.AddCommand(
+		channelPublishCmd(dockerCli),
+		channelPromoteCmd(dockerCli),
+		channelLookupCmd(dockerCli),
+		channelHistoryCmd(dockerCli),
+	)
+	return cmd
+}
+
+func channelPublishCmd(dockerCli command.Cli) *cobra.Command {
+	return &cobra.Command{
+		Use:     "publish APP_NAME:VERSION CHANNEL",
+		Short:   "Publish a version of a bundle on a channel",
+		Example: `$ docker app channel publish myrepo/myapp:1.0.0 staging`,
+		Args:    cli.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChannelPublish(dockerCli, args[0], args[1])
+		},
+	}
+}
+
+func channelPromoteCmd(dockerCli command.Cli) *cobra.Command {
+	var opts promoteOptions
+	cmd := &cobra.Command{
+		Use:   "promote APP_NAME:VERSION FROM_CHANNEL TO_CHANNEL [--policy POLICY]",
+		Short: "Promote the version of a bundle published on a channel to another channel",
+		Long: `Promote the version of a bundle published on a channel to another channel.
+The version must be the one currently published on the source channel. The promotion is checked against the Rego policies, if any, with the target channel as the "channel" of the environment.`,
+		Example: `$ docker app channel promote myrepo/myapp:1.0.0 staging prod --policy promotion.rego`,
+		Args:    cli.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChannelPromote(dockerCli, args[0], args[1], args[2], opts)
+		},
+	}
+	cmd.Flags().StringArrayVar(&opts.policies, "policy", nil, "Check the promotion against the Rego policies of this file or directory (requires opa)")
+	return cmd
+}
+
+func channelLookupCmd(dockerCli command.Cli) *cobra.Command {
+	return &cobra.Command{
+		Use:     "lookup APP_NAME CHANNEL",
+		Short:   "Print the version of a bundle published on a channel",
+		Example: `$ docker app channel lookup myrepo/myapp prod`,
+		Args:    cli.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChannelLookup(dockerCli, args[0], args[1])
+		},
+	}
+}
+
+func channelHistoryCmd(dockerCli command.Cli) *cobra.Command {
+	return &cobra.Command{
+		Use:     "history APP_NAME",
+		Short:   "List the publications and promotions of a bundle",
+		Example: `$ docker app channel history myrepo/myapp`,
+		Args:    cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChannelHistory(dockerCli, args[0])
+		},
+	}
+}
+
+func runChannelPublish(dockerCli command.Cli, appName, channel string) error {
+	ref, err := getNamedTagged(appName)
+	if err != nil {
+		return err
+	}
+	channelStore, err := openChannelStore()
+	if err != nil {
+		return err
+	}
+	if err := channelStore.Publish(ref.Name(), ref.Tag(), channel); err != nil {
+		return err
+	}
+	fmt.Fprintf(dockerCli.Out(), "Published %s on channel %q\n", reference.FamiliarString(ref), channel)
+	return nil
+}
+
+func runChannelPromote(dockerCli command.Cli, appName, fromChannel, toChannel string, opts promoteOptions) error {
+	ref, err := getNamedTagged(appName)
+	if err != nil {
+		return err
+	}
+	channelStore, err := openChannelStore(opts.promotionPolicies()...)
+	if err != nil {
+		return err
+	}
+	if err := channelStore.Promote(ref.Name(), ref.Tag(), fromChannel, toChannel); err != nil {
+		return err
+	}
+	fmt.Fprintf(dockerCli.Out(), "Promoted %s from channel %q to channel %q\n", reference.FamiliarString(ref), fromChannel, toChannel)
+	return nil
+}
+
+func runChannelLookup(dockerCli command.Cli, appName, channel string) error {
+	name, err := getName(appName)
+	if err != nil {
+		return err
+	}
+	channelStore, err := openChannelStore()
+	if err != nil {
+		return err
+	}
+	version, err := channelStore.Lookup(name, channel)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(dockerCli.Out(), version)
+	return nil
+}
+
+func runChannelHistory(dockerCli command.Cli, appName string) error {
+	name, err := getName(appName)
+	if err != nil {
+		return err
+	}
+	channelStore, err := openChannelStore()
+	if err != nil {
+		return err
+	}
+	history, err := channelStore.History(name)
+	if err != nil {
+		return err
+	}
+	printChannelHistory(dockerCli.Out(), history)
+	return nil
+}
+
+func printChannelHistory(out io.Writer, history []store.Promotion) {
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tFROM\tTO\tDATE")
+	for _, p := range history {
+		from := p.From
+		if from == "" {
+			from = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Version, from, p.To, p.Date.Format("2006-01-02T15:04:05Z"))
+	}
+	w.Flush() //nolint:errcheck // the output errors are not actionable
+}
+
+// promotionPolicies returns the store policies checking the promotions
+// against the Rego policies of the options.
+func (o *promoteOptions) promotionPolicies() []store.PromotionPolicy {
+	if len(o.policies) == 0 {
+		return nil
+	}
+	rego := &policy.Rego{Modules: o.policies}
+	return []store.PromotionPolicy{func(bndl *bundle.Bundle, fromChannel, toChannel string) error {
+		input := policy.NewInput("promote", "", bndl, nil, policy.Environment{Channel: toChannel})
+		return policy.Check(context.Background(), input, rego)
+	}}
+}
+
+func openChannelStore(policies ...store.PromotionPolicy) (store.ChannelStore, error) {
+	appstore, err := store.NewApplicationStore(config.Dir())
+	if err != nil {
+		return nil, err
+	}
+	return appstore.ChannelStore(policies...)
+}
+
+// getName returns the normalized name of a bundle, which must not be tagged.
+func getName(appName string) (string, error) {
+	ref, err := reference.ParseNormalizedNamed(appName)
+	if err != nil {
+		return "", err
+	}
+	if !reference.IsNameOnly(ref) {
+		return "", fmt.Errorf("%q must be a bundle name, without a tag or digest", appName)
+	}
+	return ref.Name(), nil
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/distribution/reference"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestChannelPromotion(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	defer config.SetDir(config.Dir())
+	config.SetDir(dir.Path())
+
+	appstore, err := store.NewApplicationStore(dir.Path())
+	assert.NilError(t, err)
+	bundleStore, err := appstore.BundleStore()
+	assert.NilError(t, err)
+	ref, err := reference.ParseNormalizedNamed("myrepo/myapp:1.0.0")
+	assert.NilError(t, err)
+	assert.NilError(t, bundleStore.Store(ref, &bundle.Bundle{Name: "myapp", Version: "1.0.0"}))
+
+	var out bytes.Buffer
+	dockerCli, err := command.NewDockerCli(command.WithOutputStream(&out))
+	assert.NilError(t, err)
+
+	assert.NilError(t, runChannelPublish(dockerCli, "myrepo/myapp:1.0.0", "staging"))
+	assert.NilError(t, runChannelPromote(dockerCli, "myrepo/myapp:1.0.0", "staging", "prod", promoteOptions{}))
+	assert.Check(t, is.Contains(out.String(), `Promoted myrepo/myapp:1.0.0 from channel "staging" to channel "prod"`))
+
+	out.Reset()
+	assert.NilError(t, runChannelLookup(dockerCli, "myrepo/myapp", "prod"))
+	assert.Check(t, is.Equal(out.String(), "1.0.0\n"))
+
+	out.Reset()
+	assert.NilError(t, runChannelHistory(dockerCli, "myrepo/myapp"))
+	assert.Check(t, is.Contains(out.String(), "1.0.0   -       staging"))
+	assert.Check(t, is.Contains(out.String(), "1.0.0   staging prod"))
+
+	err = runChannelLookup(dockerCli, "myrepo/myapp:1.0.0", "prod")
+	assert.Check(t, is.ErrorContains(err, "must be a bundle name, without a tag or digest"))
+}
@@ -8,7 +8,9 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/deislabs/cnab-go/action"
 	"github.com/deislabs/cnab-go/bundle"
@@ -16,12 +18,26 @@ import (
 	"github.com/deislabs/cnab-go/credentials"
 	"github.com/deislabs/cnab-go/driver"
 	duffleDriver "github.com/deislabs/duffle/pkg/driver"
-	"github.com/deislabs/duffle/pkg/loader"
 	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/audit"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/cnab/migrate"
+	"github.com/docker/app/internal/drivers"
+	dockerDriver "github.com/docker/app/internal/drivers/docker"
+	"github.com/docker/app/internal/drivers/ssh"
+	"github.com/docker/app/internal/drivers/wasm"
+	"github.com/docker/app/internal/notify"
+	"github.com/docker/app/internal/offline"
 	"github.com/docker/app/internal/packager"
+	"github.com/docker/app/internal/registryclient"
+	"github.com/docker/app/internal/runner"
+	"github.com/docker/app/internal/secrets"
+	"github.com/docker/app/internal/signature"
 	appstore "github.com/docker/app/internal/store"
+	"github.com/docker/app/internal/verification"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/cli/cli/context/docker"
 	"github.com/docker/cli/cli/context/store"
 	contextstore "github.com/docker/cli/cli/context/store"
@@ -65,6 +81,9 @@ func addNamedCredentialSets(credStore appstore.CredentialStore, namedCredentials
 			if err != nil {
 				return err
 			}
+			if err := resolveSecretReferences(c, values, secrets.Default()); err != nil {
+				return err
+			}
 			if err := creds.Merge(values); err != nil {
 				return err
 			}
@@ -73,6 +92,24 @@ func addNamedCredentialSets(credStore appstore.CredentialStore, namedCredentials
 	}
 }
 
+// resolveSecretReferences replaces the literal values of a credential set
+// referencing a secret manager, like "vault://secret/data/db#password", with
+// the secret.
+func resolveSecretReferences(c *credentials.CredentialSet, values credentials.Set, registry *secrets.Registry) error {
+	for _, cred := range c.Credentials {
+		value := values[cred.Name]
+		if value != cred.Source.Value || !registry.IsReference(value) {
+			continue
+		}
+		secret, err := registry.Resolve(context.Background(), value)
+		if err != nil {
+			return errors.Wrapf(err, "credential %q", cred.Name)
+		}
+		values[cred.Name] = secret
+	}
+	return nil
+}
+
 func parseCommandlineCredential(c string) (string, string, error) {
 	split := strings.SplitN(c, "=", 2)
 	if len(split) != 2 || split[0] == "" {
@@ -123,7 +160,8 @@ func addRegistryCredentials(shouldPopulate bool, dockerCli command.Cli) credenti
 
 		registryCreds := map[string]types.AuthConfig{}
 		if shouldPopulate {
-			for _, img := range b.Images {
+			for _, name := range cnab.ImageKeys(b) {
+				img := b.Images[name]
 				named, err := reference.ParseNormalizedNamed(img.Image)
 				if err != nil {
 					return err
@@ -164,6 +202,27 @@ func prepareCredentialSet(b *bundle.Bundle, opts ...credentialSetOpt) (map[strin
 	return creds, nil
 }
 
+// credentialSecrets returns the resolved credential values, which must never
+// be displayed verbatim.
+func credentialSecrets(creds credentials.Set) []string {
+	values := make([]string, 0, len(creds))
+	for _, value := range creds {
+		values = append(values, value)
+	}
+	return values
+}
+
+// actionSecrets returns the credential values and the values of the
+// sensitive parameters of an installation, which must never be displayed
+// verbatim.
+func actionSecrets(installation *appstore.Installation, creds credentials.Set) ([]string, error) {
+	values, err := cnab.SensitiveParameterValues(installation.Bundle, installation.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	return append(credentialSecrets(creds), values...), nil
+}
+
 func getTargetContext(optstargetContext, currentContext string) string {
 	var targetContext string
 	switch {
@@ -178,8 +237,34 @@ func getTargetContext(optstargetContext, currentContext string) string {
 	return targetContext
 }
 
-// prepareDriver prepares a driver per the user's request.
+// checkEnvironment fails if the bundle requires a more recent docker app, or
+// driver capabilities the docker driver does not have.
+func checkEnvironment(bndl *bundle.Bundle) error {
+	return cnab.CheckEnvironment(bndl, cnab.Descriptor{
+		RuntimeVersion:     internal.Version,
+		DriverCapabilities: []string{driver.ImageTypeDocker, driver.ImageTypeOCI},
+	})
+}
+
+// prepareDriver prepares a driver per the user's request. The container
+// invocation images are run on the engine of the docker CLI, or on the host
+// of the "ssh-driver-host" plugin setting, see ssh.ParseConfiguration.
 func prepareDriver(dockerCli command.Cli, bindMount bindMount, stdout io.Writer) (driver.Driver, *bytes.Buffer, error) {
+	// The wasm invocation images are run when the bundle has no container one
+	wasmDriver := &wasm.Driver{}
+	if cfg := dockerCli.ConfigFile(); cfg != nil {
+		wasmDriver.Runtime, _ = cfg.PluginConfig("app", "wasm-driver-runtime")
+		sshDriver, err := ssh.ParseConfiguration(func(key string) (string, bool) {
+			return cfg.PluginConfig("app", key)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if sshDriver != nil {
+			return drivers.Multi{sshDriver, wasmDriver}, bytes.NewBuffer(nil), nil
+		}
+	}
+
 	driverImpl, err := duffleDriver.Lookup("docker")
 	if err != nil {
 		return driverImpl, nil, err
@@ -191,17 +276,19 @@ func prepareDriver(dockerCli command.Cli, bindMount bindMount, stdout io.Writer)
 			d.SetContainerOut(stdout)
 		}
 		d.SetContainerErr(errBuf)
+		containerConfig, err := dockerDriverConfiguration(dockerCli)
+		if err != nil {
+			return nil, nil, err
+		}
+		d.AddConfigurationOptions(containerConfig.Option())
 		if bindMount.required {
 			d.AddConfigurationOptions(func(config *container.Config, hostConfig *container.HostConfig) error {
 				config.User = "0:0"
-				mounts := []mount.Mount{
-					{
-						Type:   mount.TypeBind,
-						Source: bindMount.endpoint,
-						Target: bindMount.endpoint,
-					},
-				}
-				hostConfig.Mounts = mounts
+				hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+					Type:   mount.TypeBind,
+					Source: bindMount.endpoint,
+					Target: bindMount.endpoint,
+				})
 				return nil
 			})
 		}
@@ -215,10 +302,113 @@ func prepareDriver(dockerCli command.Cli, bindMount bindMount, stdout io.Writer)
 		}
 		configurable.SetConfig(driverCfg)
 	}
+	// Stop the invocation image containers of interrupted operations
+	if d, ok := driverImpl.(*duffleDriver.DockerDriver); ok {
+		driverImpl = dockerDriver.NewCancellableDriver(d, dockerCli.Client(), drivers.DefaultGracePeriod)
+	}
+	driverImpl = drivers.Multi{driverImpl, wasmDriver}
 
 	return driverImpl, errBuf, err
 }
 
+// prepareRunner returns the runner of the modifying actions, storing the
+// installation after every attempt and collecting the outputs of the
+// successful ones, if outputs is not nil. The retry policy is read from the
+// "app" plugin section of the docker CLI configuration file:
+// - "action-max-attempts" is the maximum number of attempts of an action
+// - "action-retry-backoff" is the delay before the second attempt, like
+// "10s", doubled for each of the following ones
+func prepareRunner(dockerCli command.Cli, installationStore appstore.InstallationStore, driverImpl driver.Driver, outputs *outputsCollector) (*runner.Runner, error) {
+	r := &runner.Runner{
+		Installations: installationStore,
+		Driver:        driverImpl,
+	}
+	if outputs != nil {
+		r.Succeeded = outputs.collect
+	}
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return r, nil
+	}
+	if value, ok := cfg.PluginConfig("app", "action-max-attempts"); ok && value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid action-max-attempts %q: must be a positive integer", value)
+		}
+		r.Retry.MaxAttempts = n
+	}
+	if value, ok := cfg.PluginConfig("app", "action-retry-backoff"); ok && value != "" {
+		backoff, err := time.ParseDuration(value)
+		if err != nil || backoff < 0 {
+			return nil, fmt.Errorf("invalid action-retry-backoff %q: must be a positive duration", value)
+		}
+		r.Retry.Backoff = backoff
+	}
+	return r, nil
+}
+
+// outputsCollector collects the outputs the operations of a driver copied to
+// a temporary directory.
+type outputsCollector struct {
+	dir string
+}
+
+// prepareOutputs makes the driver copy the outputs of its operations to a
+// temporary directory. Nothing is collected if the driver cannot copy them.
+func prepareOutputs(driverImpl driver.Driver) (*outputsCollector, error) {
+	d, ok := driverImpl.(drivers.OutputsDriver)
+	if !ok {
+		return &outputsCollector{}, nil
+	}
+	dir, err := ioutil.TempDir("", "docker-app-outputs")
+	if err != nil {
+		return nil, err
+	}
+	d.SetOutputsDir(dir)
+	return &outputsCollector{dir: dir}, nil
+}
+
+// collect records in the installation the outputs its bundle declares for
+// the action, which succeeded. The action having succeeded, invalid or
+// missing outputs are only reported as warnings.
+func (c *outputsCollector) collect(installation *appstore.Installation, action string) {
+	if c.dir == "" {
+		return
+	}
+	result, err := drivers.CollectOutputs(installation.Bundle, action, drivers.DirOutputOpener(c.dir), drivers.DefaultMaxOutputSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to collect the outputs: %s\n", err)
+		return
+	}
+	installation.Outputs = nil
+	if len(result.Outputs) > 0 {
+		installation.Outputs = result.Outputs
+	}
+	if err := cnab.ValidateOutputs(installation.Bundle, action, result.Outputs); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+	}
+}
+
+// remove removes the temporary directory of the outputs.
+func (c *outputsCollector) remove() {
+	if c.dir != "" {
+		os.RemoveAll(c.dir) //nolint:errcheck // the directory is only left behind
+	}
+}
+
+// dockerDriverConfiguration reads the configuration of the invocation image
+// containers from the "app" plugin section of the docker CLI configuration
+// file, see dockerDriver.ParseConfiguration for the keys.
+func dockerDriverConfiguration(dockerCli command.Cli) (dockerDriver.Configuration, error) {
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return dockerDriver.Configuration{}, nil
+	}
+	return dockerDriver.ParseConfiguration(func(key string) (string, bool) {
+		return cfg.PluginConfig("app", key)
+	})
+}
+
 func getAppNameKind(name string) (string, nameKind) {
 	if name == "" {
 		return name, nameKindEmpty
@@ -257,6 +447,17 @@ func resolveBundle(dockerCli command.Cli, bundleStore appstore.BundleStore, name
 	// - the name has a .json or .cnab extension and refers to an existing file or web resource: load the bundle
 	// - name matches a bundle name:version stored in the bundle store: use it
 	// - pull the bundle from the registry and add it to the bundle store
+	// - "-" reads the bundle from the standard input, in any supported format
+	if name == "-" {
+		if pullRef {
+			return nil, "", errors.New("cannot pull when reading a bundle from the standard input")
+		}
+		bndl, err := cnab.ParseAuto(dockerCli.In())
+		if err != nil {
+			return nil, "", err
+		}
+		return bndl, "", sanitizeCustom(bndl)
+	}
 	name, kind := getAppNameKind(name)
 	switch kind {
 	case nameKindFile:
@@ -266,8 +467,11 @@ func resolveBundle(dockerCli command.Cli, bundleStore appstore.BundleStore, name
 		if strings.HasSuffix(name, internal.AppExtension) {
 			return extractAndLoadAppBasedBundle(dockerCli, name)
 		}
-		bndl, err := loader.NewLoader().Load(name)
-		return bndl, "", err
+		bndl, err := loadBundleFile(name)
+		if err != nil {
+			return nil, "", err
+		}
+		return bndl, "", sanitizeCustom(bndl)
 	case nameKindDir, nameKindEmpty:
 		if pullRef {
 			if kind == nameKindDir {
@@ -277,26 +481,111 @@ func resolveBundle(dockerCli command.Cli, bundleStore appstore.BundleStore, name
 		}
 		return extractAndLoadAppBasedBundle(dockerCli, name)
 	case nameKindReference:
-		ref, err := reference.ParseNormalizedNamed(name)
+		ref, err := cnab.ParseBundleReference(name)
+		if err != nil {
+			return nil, "", err
+		}
+		named, err := ref.Named()
+		if err != nil {
+			return nil, "", err
+		}
+		if verifiesSignatures(dockerCli) {
+			// The bundle is read from the manifest whose signature is verified,
+			// rather than from a tag which could be moved in between
+			if named, err = bundleStore.ResolveDigest(named, dockerCli.ConfigFile(), insecureRegistries); err != nil {
+				return nil, "", err
+			}
+			ref = cnab.BundleReferenceFromNamed(named)
+		}
+		bndl, err := bundleStore.LookupOrPullBundle(named, pullRef, dockerCli.ConfigFile(), insecureRegistries)
 		if err != nil {
-			return nil, "", errors.Wrap(err, name)
+			return nil, "", err
 		}
-		tagRef := reference.TagNameOnly(ref)
-		bndl, err := bundleStore.LookupOrPullBundle(tagRef, pullRef, dockerCli.ConfigFile(), insecureRegistries)
-		return bndl, tagRef.String(), err
+		return bndl, ref.String(), cnab.DefaultCustomLimits.Check(bndl)
 	}
 	return nil, "", fmt.Errorf("could not resolve bundle %q", name)
 }
 
-func requiredClaimBindMount(c claim.Claim, targetContextName string, dockerCli command.Cli) (bindMount, error) {
-	var specifiedOrchestrator string
-	if rawOrchestrator, ok := c.Parameters[internal.ParameterOrchestratorName]; ok {
-		specifiedOrchestrator = rawOrchestrator.(string)
+// sanitizeCustom removes, with a warning, the custom extensions of a local
+// bundle exceeding the limits of a single extension, and checks the others.
+// The bundles of the registries are only checked, as removing extensions
+// would change their digest.
+func sanitizeCustom(bndl *bundle.Bundle) error {
+	for _, err := range cnab.DefaultCustomLimits.Sanitize(bndl) {
+		fmt.Fprintf(os.Stderr, "WARNING: ignoring %s\n", err)
+	}
+	return cnab.DefaultCustomLimits.Check(bndl)
+}
+
+// loadBundleFile reads a bundle file, migrating it from older schema
+// versions. Deprecated constructs are reported as warnings, and the
+// documents are validated against the CNAB schema.
+func loadBundleFile(name string) (*bundle.Bundle, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Encrypted bundles are larger than their plaintext, the plaintext is
+	// bounded once decrypted
+	data, err := cnab.ReadLimited(f, cnab.MaxEncryptedSize)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load bundle %q", name)
+	}
+	if !cnab.IsEncrypted(data) && len(data) > cnab.MaxBundleSize {
+		return nil, errors.Errorf("failed to load bundle %q: invalid bundle: larger than %d bytes", name, cnab.MaxBundleSize)
+	}
+	if cnab.IsEncrypted(data) {
+		passphrase, err := bundlePassphrase()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load bundle %q", name)
+		}
+		if data, err = cnab.DecryptDocument(data, passphrase); err != nil {
+			return nil, errors.Wrapf(err, "failed to load bundle %q", name)
+		}
+	}
+	doc, err := migrate.Migrate(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load bundle %q", name)
+	}
+	// Documents predating schema versions are validated once migrated
+	if doc.SchemaVersion == "" {
+		data = doc.Data
+	}
+	if err := cnab.ValidateSchema(data); err != nil {
+		return nil, errors.Wrapf(err, "failed to load bundle %q", name)
 	}
+	for _, warning := range doc.Warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s: %s\n", name, warning)
+	}
+	return doc.Bundle, nil
+}
+
+// bundlePassphraseEnvVar holds the passphrase of the encrypted bundles.
+const bundlePassphraseEnvVar = "DOCKER_APP_BUNDLE_PASSPHRASE"
 
+// bundlePassphrase returns the passphrase of the encrypted bundles, read
+// from the environment.
+func bundlePassphrase() ([]byte, error) {
+	passphrase := os.Getenv(bundlePassphraseEnvVar)
+	if passphrase == "" {
+		return nil, errors.Errorf("no passphrase for the encrypted bundle, set %s", bundlePassphraseEnvVar)
+	}
+	return []byte(passphrase), nil
+}
+
+func requiredClaimBindMount(c claim.Claim, targetContextName string, dockerCli command.Cli) (bindMount, error) {
+	specifiedOrchestrator := stringParameter(c.Parameters, internal.ParameterOrchestratorName)
 	return requiredBindMount(targetContextName, specifiedOrchestrator, dockerCli.ContextStore())
 }
 
+// stringParameter returns the value of a string parameter, or an empty string
+// if it is not set.
+func stringParameter(parameters map[string]interface{}, name string) string {
+	value, _ := parameters[name].(string)
+	return value
+}
+
 func requiredBindMount(targetContextName string, targetOrchestrator string, s store.Store) (bindMount, error) {
 	if targetOrchestrator == "kubernetes" {
 		return bindMount{}, nil
@@ -365,7 +654,7 @@ func prepareCustomAction(actionName string, dockerCli command.Cli, appname strin
 	}
 	installation.Bundle = bundle
 
-	if err := mergeBundleParameters(installation,
+	if err := mergeBundleParameters(installation, actionName,
 		withFileParameters(paramsOpts.parametersFiles),
 		withCommandLineParameters(paramsOpts.overrides),
 	); err != nil {
@@ -374,11 +663,222 @@ func prepareCustomAction(actionName string, dockerCli command.Cli, appname strin
 
 	a := &action.RunCustom{
 		Action: actionName,
-		Driver: driverImpl,
+		Driver: drivers.WithContext(context.Background(), driverImpl),
 	}
 	return a, installation, errBuf, nil
 }
 
+// auditAction records an action run on an installation in the audit log
+// configured in the docker CLI configuration file, if any.
+func auditAction(dockerCli command.Cli, actionName, targetContext string, installation *appstore.Installation) {
+	logger := newAuditLogger(dockerCli)
+	if !logger.Enabled() {
+		return
+	}
+	r := audit.NewRecord(actionName, installation.Name, installation.Bundle)
+	r.Context = targetContext
+	r.Reference = installation.Reference
+	r.Status = installation.Result.Status
+	r.Message = installation.Result.Message
+	if err := logger.Log(r); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+	}
+}
+
+// newAuditLogger configures the audit sinks from the "app" plugin section of
+// the docker CLI configuration file:
+// - "audit-file" is the path of a file the records are appended to
+// - "audit-syslog" set to "true" sends the records to syslog
+// - "audit-url" is an HTTP endpoint the records are posted to
+func newAuditLogger(dockerCli command.Cli) *audit.Logger {
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return audit.NewLogger()
+	}
+	var sinks []audit.Sink
+	if path, ok := cfg.PluginConfig("app", "audit-file"); ok && path != "" {
+		sinks = append(sinks, audit.NewFileSink(path))
+	}
+	if enabled, ok := cfg.PluginConfig("app", "audit-syslog"); ok && enabled == "true" {
+		sink, err := audit.NewSyslogSink("docker-app")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+	if url, ok := cfg.PluginConfig("app", "audit-url"); ok && url != "" {
+		sinks = append(sinks, audit.NewHTTPSink(url, nil))
+	}
+	return audit.NewLogger(sinks...)
+}
+
+// notifyAction sends a notification about an action run on an installation
+// to the webhooks configured in the "app" plugin section of the docker CLI
+// configuration file:
+// - "notify-webhook" is an HTTP endpoint the events are posted to, as JSON
+// - "notify-webhook-template" optionally renders the webhook payload
+// - "notify-slack" is a Slack compatible incoming webhook
+// - "notify-on" set to "failure" only notifies failed actions
+func notifyAction(dockerCli command.Cli, actionName, targetContext string, installation *appstore.Installation, start time.Time) {
+	notifier, err := newNotifier(dockerCli)
+	if err == nil && notifier.Enabled() {
+		e := notify.NewEvent(actionName, installation.Name, installation.Bundle, installation.Result, start)
+		e.Context = targetContext
+		err = notifier.Notify(context.Background(), e)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+	}
+}
+
+func newNotifier(dockerCli command.Cli) (*notify.Notifier, error) {
+	notifier := &notify.Notifier{}
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return notifier, nil
+	}
+	if url, ok := cfg.PluginConfig("app", "notify-webhook"); ok && url != "" {
+		webhook := &notify.Webhook{URL: url}
+		if text, ok := cfg.PluginConfig("app", "notify-webhook-template"); ok && text != "" {
+			tmpl, err := notify.ParseTemplate(text)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid notify-webhook-template")
+			}
+			webhook.Template = tmpl
+		}
+		notifier.Senders = append(notifier.Senders, webhook)
+	}
+	if url, ok := cfg.PluginConfig("app", "notify-slack"); ok && url != "" {
+		notifier.Senders = append(notifier.Senders, &notify.Slack{URL: url})
+	}
+	if on, ok := cfg.PluginConfig("app", "notify-on"); ok && on == "failure" {
+		notifier.OnlyFailures = true
+	}
+	return notifier, nil
+}
+
+// validateBundle reports all the violations of the bundle, printing the
+// warnings and failing on the errors.
+func validateBundle(bndl *bundle.Bundle) error {
+	errs := cnab.Validate(bndl)
+	for _, warning := range errs.Warnings() {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", warning)
+	}
+	return errs.Err()
+}
+
+// verifiesSignatures returns true if the signatures or the provenance
+// attestations of the bundles are verified, see verifyBundleSignatures and
+// verifyBundleProvenance.
+func verifiesSignatures(dockerCli command.Cli) bool {
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return false
+	}
+	for _, key := range []string{"cosign-verify", "slsa-verify"} {
+		if enabled, ok := cfg.PluginConfig("app", key); ok && enabled == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyBundleSignatures checks the sigstore signatures of a bundle and of
+// its invocation images, when enabled in the "app" plugin section of the
+// docker CLI configuration file:
+// - "cosign-verify" set to "true" enables the verification
+// - "cosign-key" is the path or KMS URI of the public key, signatures are
+// verified keyless otherwise
+// - "cosign-certificate-identity" and "cosign-certificate-oidc-issuer" are
+// the identity keyless signing certificates must be issued to
+func verifyBundleSignatures(dockerCli command.Cli, ref string, bndl *bundle.Bundle) error {
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return nil
+	}
+	if enabled, ok := cfg.PluginConfig("app", "cosign-verify"); !ok || enabled != "true" {
+		return nil
+	}
+	verifier := cosignVerifier(cfg)
+	var named reference.Named
+	if ref != "" {
+		var err error
+		if named, err = reference.ParseNormalizedNamed(ref); err != nil {
+			return err
+		}
+	}
+	return verifier.Verify(context.Background(), named, bndl)
+}
+
+// cosignVerifier returns the cosign verifier configured by the "cosign-key",
+// "cosign-certificate-identity" and "cosign-certificate-oidc-issuer" keys of
+// the "app" plugin section of the docker CLI configuration file.
+func cosignVerifier(cfg *configfile.ConfigFile) *signature.Cosign {
+	verifier := &signature.Cosign{}
+	verifier.Key, _ = cfg.PluginConfig("app", "cosign-key")
+	verifier.CertificateIdentity, _ = cfg.PluginConfig("app", "cosign-certificate-identity")
+	verifier.CertificateOIDCIssuer, _ = cfg.PluginConfig("app", "cosign-certificate-oidc-issuer")
+	verifier.Offline = offline.Enabled(cfg)
+	return verifier
+}
+
+// verifyBundleProvenance checks the SLSA provenance attestations of a bundle
+// and of its invocation images, when enabled in the "app" plugin section of
+// the docker CLI configuration file:
+// - "slsa-verify" set to "true" enables the verification
+// - "slsa-builders" is the comma separated list of trusted builder IDs
+// - "slsa-source-repositories" is the comma separated list of trusted
+// source URI prefixes
+// The attestation signatures are verified with the cosign settings.
+func verifyBundleProvenance(dockerCli command.Cli, ref string, bndl *bundle.Bundle) error {
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return nil
+	}
+	if enabled, ok := cfg.PluginConfig("app", "slsa-verify"); !ok || enabled != "true" {
+		return nil
+	}
+	source := cosignVerifier(cfg)
+	var policy verification.Policy
+	if builders, ok := cfg.PluginConfig("app", "slsa-builders"); ok && builders != "" {
+		policy.Builders = strings.Split(builders, ",")
+	}
+	if sources, ok := cfg.PluginConfig("app", "slsa-source-repositories"); ok && sources != "" {
+		policy.SourceRepositories = strings.Split(sources, ",")
+	}
+	var named reference.Named
+	if ref != "" {
+		var err error
+		if named, err = reference.ParseNormalizedNamed(ref); err != nil {
+			return err
+		}
+	}
+	report, err := verification.Verify(context.Background(), source, policy, named, bndl)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// verifyImageDigests checks the registry still serves the digests declared
+// by the bundle images, when "verify-image-digests" is set to "true" in the
+// "app" plugin section of the docker CLI configuration file.
+func verifyImageDigests(dockerCli command.Cli, bndl *bundle.Bundle, insecureRegistries []string) error {
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return nil
+	}
+	if enabled, ok := cfg.PluginConfig("app", "verify-image-digests"); !ok || enabled != "true" {
+		return nil
+	}
+	client, err := registryclient.NewFromConfig(cfg, insecureRegistries)
+	if err != nil {
+		return err
+	}
+	return cnab.VerifyImageDigests(context.Background(), bndl, client.Resolver())
+}
+
 func isInstallationFailed(installation *appstore.Installation) bool {
 	return installation.Result.Action == claim.ActionInstall &&
 		installation.Result.Status == claim.StatusFailure
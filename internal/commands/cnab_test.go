@@ -1,16 +1,34 @@
 package commands
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/driver"
 	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/secrets"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/app/pkg/bundletest"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/cli/cli/config/types"
 	cliflags "github.com/docker/cli/cli/flags"
 	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
 )
 
 func TestRequiresBindMount(t *testing.T) {
@@ -261,3 +279,207 @@ func TestParseCommandlineCredential(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveSecretReferences(t *testing.T) {
+	registry := secrets.NewRegistry()
+	registry.Register("test", secrets.ProviderFunc(func(_ context.Context, path string) (string, error) {
+		return `{"password": "s3cr3t"}`, nil
+	}))
+	os.Setenv("TEST_SECRET_REFERENCE", "test://from-env#password")
+	defer os.Unsetenv("TEST_SECRET_REFERENCE")
+	c := &credentials.CredentialSet{
+		Credentials: []credentials.CredentialStrategy{
+			{Name: "password", Source: credentials.Source{Value: "test://db#password"}},
+			{Name: "plain", Source: credentials.Source{Value: "value"}},
+			{Name: "unknown-scheme", Source: credentials.Source{Value: "http://example.com"}},
+			// Only literal values are resolved
+			{Name: "env", Source: credentials.Source{EnvVar: "TEST_SECRET_REFERENCE"}},
+		},
+	}
+	values, err := c.Resolve()
+	assert.NilError(t, err)
+	assert.NilError(t, resolveSecretReferences(c, values, registry))
+	assert.DeepEqual(t, values, credentials.Set{
+		"password":       "s3cr3t",
+		"plain":          "value",
+		"unknown-scheme": "http://example.com",
+		"env":            "test://from-env#password",
+	})
+
+	c.Credentials = append(c.Credentials, credentials.CredentialStrategy{Name: "missing", Source: credentials.Source{Value: "test://db#user"}})
+	values, err = c.Resolve()
+	assert.NilError(t, err)
+	assert.ErrorContains(t, resolveSecretReferences(c, values, registry), `credential "missing": key "user" not found in secret`)
+}
+
+func TestEncryptOutputs(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("key", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))+"\n"),
+		fs.WithFile("short", base64.StdEncoding.EncodeToString([]byte("short"))))
+	defer dir.Remove()
+	backend := store.NewMemoryInstallationStore()
+	dockerCli := func(keyFile string) command.Cli {
+		return &registryConfigMock{configFile: &configfile.ConfigFile{
+			Plugins: map[string]map[string]string{"app": {"outputs-encryption-key-file": keyFile}},
+		}}
+	}
+
+	installationStore, err := encryptOutputs(dockerCli(""), backend)
+	assert.NilError(t, err)
+	assert.Equal(t, installationStore, backend)
+
+	installationStore, err = encryptOutputs(dockerCli(dir.Join("key")), backend)
+	assert.NilError(t, err)
+	installation, err := store.NewInstallation("app", "")
+	assert.NilError(t, err)
+	installation.Outputs = map[string]string{"password": "s3cr3t"}
+	assert.NilError(t, installationStore.Store(installation))
+	stored, err := backend.Read("app")
+	assert.NilError(t, err)
+	assert.Check(t, stored.EncryptedOutputs)
+
+	_, err = encryptOutputs(dockerCli(dir.Join("short")), backend)
+	assert.ErrorContains(t, err, "invalid outputs encryption key")
+}
+
+// outputsDriver writes the outputs of its operations to the outputs
+// directory.
+type outputsDriver struct {
+	driver.Driver
+	dir     string
+	outputs map[string]string
+}
+
+func (d *outputsDriver) SetOutputsDir(dir string) {
+	d.dir = dir
+}
+
+func (d *outputsDriver) Run(op *driver.Operation) error {
+	for name, value := range d.outputs {
+		if err := ioutil.WriteFile(filepath.Join(d.dir, name), []byte(value), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCollectOutputs(t *testing.T) {
+	bndl := bundletest.NewTestBundle(
+		bundletest.WithCustom(cnab.ParameterSchemasExtensionKey, map[string]interface{}{
+			"definitions": map[string]interface{}{"string": map[string]interface{}{"type": "string"}},
+		}),
+		bundletest.WithCustom(cnab.OutputsExtensionKey, map[string]interface{}{
+			"endpoint": map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/endpoint"},
+			"password": map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/password", "sensitive": true},
+		}),
+	)
+	installation, err := store.NewInstallation("app", "")
+	assert.NilError(t, err)
+	installation.Bundle = bndl
+	installation.Outputs = map[string]string{"endpoint": "previous"}
+
+	d := &outputsDriver{outputs: map[string]string{"endpoint": "http://localhost", "password": "s3cr3t"}}
+	outputs, err := prepareOutputs(drivers.Multi{d})
+	assert.NilError(t, err)
+	assert.Check(t, d.dir != "")
+	assert.NilError(t, d.Run(&driver.Operation{}))
+	outputs.collect(installation, "install")
+	assert.DeepEqual(t, installation.Outputs, d.outputs)
+	outputs.remove()
+	_, err = os.Stat(d.dir)
+	assert.Check(t, os.IsNotExist(err))
+
+	// Drivers which cannot copy the outputs keep the recorded ones
+	outputs, err = prepareOutputs(fake.New())
+	assert.NilError(t, err)
+	outputs.collect(installation, "upgrade")
+	outputs.remove()
+	assert.DeepEqual(t, installation.Outputs, d.outputs)
+}
+
+func TestPrepareRunner(t *testing.T) {
+	dockerCli := func(settings map[string]string) command.Cli {
+		return &registryConfigMock{configFile: &configfile.ConfigFile{
+			Plugins: map[string]map[string]string{"app": settings},
+		}}
+	}
+	installations := store.NewMemoryInstallationStore()
+
+	r, err := prepareRunner(dockerCli(nil), installations, fake.New(), nil)
+	assert.NilError(t, err)
+	assert.Check(t, r.Retry.MaxAttempts == 0)
+	assert.Check(t, r.Succeeded == nil)
+
+	r, err = prepareRunner(dockerCli(map[string]string{"action-max-attempts": "3", "action-retry-backoff": "10s"}), installations, fake.New(), &outputsCollector{})
+	assert.NilError(t, err)
+	assert.Check(t, r.Retry.MaxAttempts == 3)
+	assert.Check(t, r.Retry.Backoff == 10*time.Second)
+	assert.Check(t, r.Succeeded != nil)
+
+	_, err = prepareRunner(dockerCli(map[string]string{"action-max-attempts": "0"}), installations, fake.New(), nil)
+	assert.ErrorContains(t, err, `invalid action-max-attempts "0"`)
+	_, err = prepareRunner(dockerCli(map[string]string{"action-retry-backoff": "soon"}), installations, fake.New(), nil)
+	assert.ErrorContains(t, err, `invalid action-retry-backoff "soon"`)
+}
+
+func TestAdmissionHook(t *testing.T) {
+	hook, err := (&policyOptions{}).admissionHook(policy.Environment{})
+	assert.NilError(t, err)
+	assert.Check(t, hook == nil)
+
+	_, err = (&policyOptions{scanner: "unknown"}).admissionHook(policy.Environment{})
+	assert.Check(t, err != nil)
+
+	// The policies are evaluated before the operation runs
+	hook, err = (&policyOptions{policies: []string{"policy.rego"}}).admissionHook(policy.Environment{})
+	assert.NilError(t, err)
+	assert.Assert(t, hook != nil && hook.BeforeAction != nil)
+}
+
+func TestLoadBundleFileValidatesUnversionedDocuments(t *testing.T) {
+	valid := fs.NewFile(t, "bundle.json", fs.WithContent(`{
+		"name": "myapp",
+		"version": "1.0.0",
+		"invocationImages": [{"image": "myapp-invoc:1.0.0"}],
+		"images": [{"name": "web", "image": "nginx:1.17"}]
+	}`))
+	defer valid.Remove()
+	b, err := loadBundleFile(valid.Path())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Images["web"].Image, "nginx:1.17"))
+
+	invalid := fs.NewFile(t, "bundle.json", fs.WithContent(`{"name": "myapp", "version": "1.0.0", "invocationImages": [{"imageType": "docker"}]}`))
+	defer invalid.Remove()
+	_, err = loadBundleFile(invalid.Path())
+	assert.Check(t, is.ErrorContains(err, "bundle does not match the CNAB schema: - image: image is required"))
+}
+
+func TestLoadBundleFileDecryptsBundles(t *testing.T) {
+	data, err := cnab.Encrypt(&bundle.Bundle{
+		Name:             "myapp",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "myapp-invoc:1.0.0"}}},
+	}, []byte("passphrase"))
+	assert.NilError(t, err)
+	encrypted := fs.NewFile(t, "bundle.json.enc", fs.WithBytes(data))
+	defer encrypted.Remove()
+
+	os.Unsetenv(bundlePassphraseEnvVar)
+	_, err = loadBundleFile(encrypted.Path())
+	assert.Check(t, is.ErrorContains(err, "no passphrase for the encrypted bundle, set "+bundlePassphraseEnvVar))
+
+	os.Setenv(bundlePassphraseEnvVar, "passphrase")
+	defer os.Unsetenv(bundlePassphraseEnvVar)
+	b, err := loadBundleFile(encrypted.Path())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(b.Name, "myapp"))
+}
+
+func TestSanitizeCustom(t *testing.T) {
+	b := &bundle.Bundle{Custom: map[string]interface{}{
+		"com.example.valid":   "value",
+		"com.example.invalid": "value\xff",
+	}}
+	assert.NilError(t, sanitizeCustom(b))
+	assert.Check(t, is.DeepEqual(b.Custom, map[string]interface{}{"com.example.valid": "value"}))
+}
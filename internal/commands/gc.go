@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/docker/app/internal/gc"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/config"
+	units "github.com/docker/go-units"
+	"github.com/spf13/cobra"
+)
+
+const gcWarning = `WARNING! This will remove all the bundles and images not used by any installation.
+Are you sure you want to continue?`
+
+type gcOptions struct {
+	dryRun bool
+	force  bool
+}
+
+func gcCmd(dockerCli command.Cli) *cobra.Command {
+	var opts gcOptions
+	cmd := &cobra.Command{
+		Use:   "gc [OPTIONS]",
+		Short: "Remove the bundles and images no longer used by any installation",
+		Long: `Remove the bundles of the bundle store and of the bundle cache, with their exported images, which are not referenced by any revision of the installations of any context.
+The invocation and component images of the removed bundles are also removed from the engine, unless other installations use them.
+It asks for confirmation, unless --force or --dry-run is set.`,
+		Example: `$ docker app gc --dry-run
+$ docker app gc --force`,
+		Args: cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGC(dockerCli, opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only report what would be removed")
+	cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "Do not prompt for confirmation")
+	return cmd
+}
+
+func runGC(dockerCli command.Cli, opts gcOptions) error {
+	if !opts.dryRun && !opts.force && !command.PromptForConfirmation(dockerCli.In(), dockerCli.Out(), gcWarning) {
+		return nil
+	}
+	appstore, err := store.NewApplicationStore(config.Dir())
+	if err != nil {
+		return err
+	}
+	installations, err := appstore.InstallationStores()
+	if err != nil {
+		return err
+	}
+	bundles, err := appstore.BundleStore()
+	if err != nil {
+		return err
+	}
+	cache, err := appstore.BundleCache(0)
+	if err != nil {
+		return err
+	}
+	collector := &gc.Collector{
+		Installations: installations,
+		Bundles:       bundles,
+		Cache:         cache,
+		Engine:        dockerCli.Client(),
+		DryRun:        opts.dryRun,
+	}
+	report, err := collector.Collect(context.Background())
+	if report != nil {
+		printGCReport(dockerCli.Out(), report)
+		for _, item := range report.Skipped {
+			fmt.Fprintf(dockerCli.Err(), "WARNING: image %s is used by containers, it was not removed\n", item.Name)
+		}
+	}
+	return err
+}
+
+func printGCReport(out io.Writer, report *gc.Report) {
+	if len(report.Removed) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tNAME\tSIZE")
+		for _, item := range report.Removed {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", item.Kind, item.Name, units.HumanSize(float64(item.Size)))
+		}
+		w.Flush() //nolint:errcheck // the output errors are not actionable
+	}
+	if report.DryRun {
+		fmt.Fprintf(out, "Total reclaimable space: %s\n", units.HumanSize(float64(report.Size())))
+	} else {
+		fmt.Fprintf(out, "Total reclaimed space: %s\n", units.HumanSize(float64(report.Size())))
+	}
+}
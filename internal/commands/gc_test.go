@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/app/internal/store"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/config"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestGCPromptsForConfirmation(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	defer config.SetDir(config.Dir())
+	config.SetDir(dir.Path())
+
+	var out bytes.Buffer
+	dockerCli, err := command.NewDockerCli(
+		command.WithInputStream(ioutil.NopCloser(strings.NewReader("n\n"))),
+		command.WithOutputStream(&out),
+	)
+	assert.NilError(t, err)
+	assert.NilError(t, runGC(dockerCli, gcOptions{}))
+	assert.Check(t, is.Contains(out.String(), "Are you sure you want to continue?"))
+	// Nothing was collected, not even the stores were created
+	_, err = os.Stat(filepath.Join(dir.Path(), store.AppConfigDirectory))
+	assert.Check(t, os.IsNotExist(err))
+}
@@ -1,14 +1,24 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/deislabs/cnab-go/action"
-	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers/debug"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/redact"
+	"github.com/docker/app/internal/runner"
 	"github.com/docker/app/internal/store"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -17,9 +27,14 @@ type installOptions struct {
 	credentialOptions
 	registryOptions
 	pullOptions
+	policyOptions
+	lockOptions
+	timeoutOptions
 	orchestrator  string
 	kubeNamespace string
 	stackName     string
+	dryRun        bool
+	labels        []string
 }
 
 type nameKind uint
@@ -60,9 +75,14 @@ func installCmd(dockerCli command.Cli) *cobra.Command {
 	opts.credentialOptions.addFlags(cmd.Flags())
 	opts.registryOptions.addFlags(cmd.Flags())
 	opts.pullOptions.addFlags(cmd.Flags())
+	opts.policyOptions.addFlags(cmd.Flags())
+	opts.lockOptions.addFlags(cmd.Flags())
+	opts.timeoutOptions.addFlags(cmd.Flags())
 	cmd.Flags().StringVar(&opts.orchestrator, "orchestrator", "", "Orchestrator to install on (swarm, kubernetes)")
 	cmd.Flags().StringVar(&opts.kubeNamespace, "kubernetes-namespace", "default", "Kubernetes namespace to install into")
 	cmd.Flags().StringVar(&opts.stackName, "name", "", "Installation name (defaults to application name)")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the resolved invocation image operation instead of running it")
+	cmd.Flags().StringArrayVar(&opts.labels, "label", nil, "Label the installation, as KEY=VALUE")
 
 	return cmd
 }
@@ -75,7 +95,7 @@ func runInstall(dockerCli command.Cli, appname string, opts installOptions) erro
 	if err != nil {
 		return err
 	}
-	bundleStore, installationStore, credentialStore, err := prepareStores(opts.targetContext)
+	bundleStore, installationStore, credentialStore, err := prepareStores(dockerCli, opts.targetContext)
 	if err != nil {
 		return err
 	}
@@ -84,13 +104,38 @@ func runInstall(dockerCli command.Cli, appname string, opts installOptions) erro
 	if err != nil {
 		return err
 	}
-	if err := bndl.Validate(); err != nil {
+	if err := validateBundle(bndl); err != nil {
+		return err
+	}
+	if err := cnab.CheckRequiredExtensions(bndl, cnab.SupportedExtensions); err != nil {
+		return err
+	}
+	if err := verifyBundleSignatures(dockerCli, ref, bndl); err != nil {
+		return err
+	}
+	if err := verifyBundleProvenance(dockerCli, ref, bndl); err != nil {
+		return err
+	}
+	if err := verifyImageDigests(dockerCli, bndl, opts.insecureRegistries); err != nil {
+		return err
+	}
+	if err := cnab.ValidateParameterDestinations(bndl); err != nil {
+		return err
+	}
+	if err := checkEnvironment(bndl); err != nil {
 		return err
 	}
 	installationName := opts.stackName
 	if installationName == "" {
 		installationName = bndl.Name
 	}
+	if !opts.dryRun {
+		unlock, err := opts.lock(opts.targetContext, installationName, claim.ActionInstall)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
 	if installation, err := installationStore.Read(installationName); err == nil {
 		// A failed installation can be overridden, but with a warning
 		if isInstallationFailed(installation) {
@@ -106,13 +151,12 @@ func runInstall(dockerCli command.Cli, appname string, opts installOptions) erro
 		return err
 	}
 
-	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, nil)
-	if err != nil {
+	installation.Bundle = bndl
+	if installation.Labels, err = installationLabels(bndl, opts.labels); err != nil {
 		return err
 	}
-	installation.Bundle = bndl
 
-	if err := mergeBundleParameters(installation,
+	if err := mergeBundleParameters(installation, claim.ActionInstall,
 		withFileParameters(opts.parametersFiles),
 		withCommandLineParameters(opts.overrides),
 		withOrchestratorParameters(opts.orchestrator, opts.kubeNamespace),
@@ -120,28 +164,108 @@ func runInstall(dockerCli command.Cli, appname string, opts installOptions) erro
 	); err != nil {
 		return err
 	}
+	admissionHook, err := opts.policyOptions.admissionHook(policy.Environment{
+		TargetContext: opts.targetContext,
+		Orchestrator:  opts.orchestrator,
+		Namespace:     opts.kubeNamespace,
+	})
+	if err != nil {
+		return err
+	}
 	creds, err := prepareCredentialSet(bndl, opts.CredentialSetOpts(dockerCli, credentialStore)...)
 	if err != nil {
 		return err
 	}
-	if err := credentials.Validate(creds, bndl.Credentials); err != nil {
+	if err := cnab.ValidateCredentials(bndl, creds); err != nil {
 		return err
 	}
-
-	inst := &action.Install{
-		Driver: driverImpl,
+	secrets, err := actionSecrets(installation, creds)
+	if err != nil {
+		return err
 	}
-	err = inst.Run(&installation.Claim, creds, os.Stdout)
-	// Even if the installation failed, the installation is persisted with its failure status,
-	// so any installation needs a clean uninstallation.
-	err2 := installationStore.Store(installation)
+	out := redact.NewWriter(os.Stdout, secrets...)
+	defer out.Flush() //nolint:errcheck // nothing much we can do with an error to write to output.
+	if opts.dryRun {
+		// Nothing is run nor stored
+		if admissionHook != nil {
+			event := runner.HookEvent{Action: claim.ActionInstall, Bundle: bndl, Claim: &installation.Claim}
+			if err := admissionHook.BeforeAction(context.Background(), event); err != nil {
+				return err
+			}
+		}
+		inst := &action.Install{Driver: debug.New(out, secrets...)}
+		if err := inst.Run(&installation.Claim, creds, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Dry run, application %q was not installed\n", installationName)
+		return nil
+	}
+	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, out)
+	if err != nil {
+		return err
+	}
+	outputs, err := prepareOutputs(driverImpl)
+	if err != nil {
+		return err
+	}
+	defer outputs.remove()
+	r, err := prepareRunner(dockerCli, installationStore, driverImpl, outputs)
 	if err != nil {
-		return fmt.Errorf("Installation failed: %s\n%s", errBuf, err)
+		return err
+	}
+	if admissionHook != nil {
+		r.Hooks = append(r.Hooks, *admissionHook)
 	}
-	if err2 != nil {
-		return err2
+
+	ctx, cancel := opts.timeoutOptions.context()
+	defer cancel()
+	start := time.Now()
+	// Once the invocation image ran, the installation is persisted even if it failed, with its
+	// failure status, so it needs a clean uninstallation. The failures before it runs, like a
+	// vetoed or invalid operation, are not persisted as nothing was installed.
+	err = r.Run(ctx, installation, claim.ActionInstall, creds, out)
+	auditAction(dockerCli, claim.ActionInstall, opts.targetContext, installation)
+	notifyAction(dockerCli, claim.ActionInstall, opts.targetContext, installation, start)
+	if err != nil {
+		return fmt.Errorf("Installation failed: %s\n%s", redact.String(errBuf.String(), secrets...), redact.Error(err, secrets...))
 	}
 
 	fmt.Fprintf(os.Stdout, "Application %q installed on context %q\n", installationName, opts.targetContext)
 	return nil
 }
+
+// installationLabels returns the labels of the bundle, overridden by the
+// KEY=VALUE labels of the command line.
+func installationLabels(bndl *bundle.Bundle, overrides []string) (map[string]string, error) {
+	labels, err := cnab.ReadLabels(bndl)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseLabels(overrides)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range parsed {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// parseLabels parses KEY=VALUE labels.
+func parseLabels(labels []string) (map[string]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]string, len(labels))
+	for _, label := range labels {
+		split := strings.SplitN(label, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, errors.Errorf("failed to parse %q as a label KEY=VALUE", label)
+		}
+		parsed[split[0]] = split[1]
+	}
+	return parsed, cnab.ValidateLabels(parsed)
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels([]string{"team=payments", "env=", "example.com/tier=front-end"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(labels, map[string]string{"team": "payments", "env": "", "example.com/tier": "front-end"}))
+
+	labels, err = parseLabels(nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(labels))
+
+	_, err = parseLabels([]string{"team"})
+	assert.Check(t, is.Error(err, `failed to parse "team" as a label KEY=VALUE`))
+	_, err = parseLabels([]string{"=payments"})
+	assert.Check(t, is.ErrorContains(err, "failed to parse"))
+	_, err = parseLabels([]string{"team=pay ments"})
+	assert.Check(t, is.ErrorContains(err, `invalid value "pay ments" of label "team"`))
+}
+
+func TestInstallationLabels(t *testing.T) {
+	bndl := &bundle.Bundle{}
+	assert.NilError(t, cnab.WriteLabels(bndl, map[string]string{"team": "payments", "env": "dev"}))
+	labels, err := installationLabels(bndl, []string{"env=prod"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(labels, map[string]string{"team": "payments", "env": "prod"}))
+
+	labels, err = installationLabels(&bundle.Bundle{}, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(labels))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/deislabs/cnab-go/bundle"
 	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
 	"github.com/docker/app/internal/store"
 	"github.com/docker/app/types/parameters"
 	cliopts "github.com/docker/cli/opts"
@@ -61,7 +62,9 @@ func withOrchestratorParameters(orchestrator string, kubeNamespace string) merge
 	}
 }
 
-func mergeBundleParameters(installation *store.Installation, ops ...mergeBundleOpt) error {
+// mergeBundleParameters sets the parameters of the installation for the given
+// action, from the user values and the defaults.
+func mergeBundleParameters(installation *store.Installation, action string, ops ...mergeBundleOpt) error {
 	bndl := installation.Bundle
 	if installation.Parameters == nil {
 		installation.Parameters = make(map[string]interface{})
@@ -72,12 +75,36 @@ func mergeBundleParameters(installation *store.Installation, ops ...mergeBundleO
 			return err
 		}
 	}
+	names := make([]string, 0, len(userParams))
+	for name := range userParams {
+		names = append(names, name)
+	}
+	if err := cnab.CheckDeclaredParameters(bndl, names...); err != nil {
+		return err
+	}
 	if err := matchAndMergeParametersDefinition(installation.Parameters, userParams, bndl.Parameters); err != nil {
 		return err
 	}
+	// Dependencies are not installed by docker app, only the outputs of the
+	// installation itself are available
+	outputs := func(dependency, output string) (string, bool) {
+		if dependency != "" {
+			return "", false
+		}
+		value, ok := installation.Outputs[output]
+		return value, ok
+	}
 	var err error
-	installation.Parameters, err = bundle.ValuesOrDefaults(installation.Parameters, bndl)
-	return err
+	if installation.Parameters, err = cnab.ResolveParameterSources(bndl, installation.Parameters, outputs); err != nil {
+		return err
+	}
+	// The user values are checked above, the values kept from a previous
+	// version of the bundle are dropped if it no longer declares them
+	installation.Parameters, err = cnab.ValuesOrDefaults(installation.Parameters, bndl, action, cnab.IgnoreUndeclared())
+	if err != nil {
+		return err
+	}
+	return cnab.ValidateParameters(bndl, installation.Parameters)
 }
 
 func matchAndMergeParametersDefinition(currentValues map[string]interface{}, parameterValues map[string]string, parameterDefinitions map[string]bundle.ParameterDefinition) error {
@@ -6,6 +6,7 @@ import (
 	"github.com/deislabs/cnab-go/bundle"
 	"github.com/deislabs/cnab-go/claim"
 	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
 	"github.com/docker/app/internal/store"
 	"gotest.tools/assert"
 	"gotest.tools/assert/cmp"
@@ -108,7 +109,7 @@ func TestMergeBundleParameters(t *testing.T) {
 			},
 		}
 		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
-		err := mergeBundleParameters(i,
+		err := mergeBundleParameters(i, claim.ActionInstall,
 			first,
 			second,
 		)
@@ -129,7 +130,7 @@ func TestMergeBundleParameters(t *testing.T) {
 			},
 		}
 		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
-		err := mergeBundleParameters(i)
+		err := mergeBundleParameters(i, claim.ActionInstall)
 		assert.NilError(t, err)
 		expected := map[string]interface{}{
 			"param": "default",
@@ -151,7 +152,7 @@ func TestMergeBundleParameters(t *testing.T) {
 			},
 		}
 		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
-		err := mergeBundleParameters(i, withIntValue)
+		err := mergeBundleParameters(i, claim.ActionInstall, withIntValue)
 		assert.NilError(t, err)
 		expected := map[string]interface{}{
 			"param": 1,
@@ -169,7 +170,7 @@ func TestMergeBundleParameters(t *testing.T) {
 			},
 		}
 		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
-		err := mergeBundleParameters(i)
+		err := mergeBundleParameters(i, claim.ActionInstall)
 		assert.NilError(t, err)
 		expected := map[string]interface{}{
 			"param": "default",
@@ -186,10 +187,27 @@ func TestMergeBundleParameters(t *testing.T) {
 			Parameters: map[string]bundle.ParameterDefinition{},
 		}
 		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
-		err := mergeBundleParameters(i, withUndefined)
+		err := mergeBundleParameters(i, claim.ActionInstall, withUndefined)
 		assert.ErrorContains(t, err, "is not defined in the bundle")
 	})
 
+	t.Run("Misspelled parameter is rejected with a suggestion", func(t *testing.T) {
+		withMisspelled := func(b *bundle.Bundle, params map[string]string) error {
+			params["replcias"] = "3"
+			return nil
+		}
+		bundle := &bundle.Bundle{
+			Parameters: map[string]bundle.ParameterDefinition{
+				"replicas": {
+					DataType: "int",
+				},
+			},
+		}
+		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
+		err := mergeBundleParameters(i, claim.ActionInstall, withMisspelled)
+		assert.Error(t, err, `parameter "replcias" is not defined in the bundle, did you mean "replicas"?`)
+	})
+
 	t.Run("Invalid type is rejected", func(t *testing.T) {
 		withIntValue := func(b *bundle.Bundle, params map[string]string) error {
 			params["param"] = "foo"
@@ -203,7 +221,7 @@ func TestMergeBundleParameters(t *testing.T) {
 			},
 		}
 		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
-		err := mergeBundleParameters(i, withIntValue)
+		err := mergeBundleParameters(i, claim.ActionInstall, withIntValue)
 		assert.ErrorContains(t, err, "invalid value for parameter")
 	})
 
@@ -221,7 +239,68 @@ func TestMergeBundleParameters(t *testing.T) {
 			},
 		}
 		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
-		err := mergeBundleParameters(i, withIntValue)
+		err := mergeBundleParameters(i, claim.ActionInstall, withIntValue)
 		assert.ErrorContains(t, err, "invalid value for parameter")
 	})
+
+	t.Run("Value violating the parameter schema is rejected", func(t *testing.T) {
+		withPort := func(b *bundle.Bundle, params map[string]string) error {
+			params["port"] = "70000"
+			return nil
+		}
+		bundle := &bundle.Bundle{
+			Parameters: map[string]bundle.ParameterDefinition{
+				"port": {
+					DataType: "int",
+				},
+			},
+			Custom: map[string]interface{}{
+				cnab.ParameterSchemasExtensionKey: map[string]interface{}{
+					"parameters": map[string]interface{}{
+						"port": map[string]interface{}{"type": "integer", "maximum": 65535},
+					},
+				},
+			},
+		}
+		i := &store.Installation{Claim: claim.Claim{Bundle: bundle}}
+		err := mergeBundleParameters(i, claim.ActionInstall, withPort)
+		assert.ErrorContains(t, err, `invalid value for parameter "port": 70000 is greater than the maximum 65535`)
+	})
+	t.Run("Upgrade drops removed parameters", func(t *testing.T) {
+		// The parameter "debug" is removed by the new version of the bundle
+		bundle := &bundle.Bundle{
+			Parameters: map[string]bundle.ParameterDefinition{
+				"port": {DataType: "int", Default: 80},
+			},
+		}
+		i := &store.Installation{Claim: claim.Claim{
+			Bundle:     bundle,
+			Parameters: map[string]interface{}{"port": 8080, "debug": true},
+		}}
+		err := mergeBundleParameters(i, claim.ActionUpgrade)
+		assert.NilError(t, err)
+		assert.Check(t, cmp.DeepEqual(i.Parameters, map[string]interface{}{"port": 8080}))
+
+		// But the values set by the user must still be declared
+		err = mergeBundleParameters(i, claim.ActionUpgrade, withCommandLineParameters([]string{"debug=true"}))
+		assert.ErrorContains(t, err, `parameter "debug" is not defined in the bundle`)
+	})
+	t.Run("Upgrade keeps large stored integers", func(t *testing.T) {
+		bundle := &bundle.Bundle{
+			Parameters: map[string]bundle.ParameterDefinition{
+				"seed": {DataType: "int"},
+			},
+		}
+		installations := store.NewMemoryInstallationStore()
+		i := &store.Installation{Claim: claim.Claim{
+			Name:       "app",
+			Bundle:     bundle,
+			Parameters: map[string]interface{}{"seed": 9007199254740993},
+		}}
+		assert.NilError(t, installations.Store(i))
+		i, err := installations.Read("app")
+		assert.NilError(t, err)
+		assert.NilError(t, mergeBundleParameters(i, claim.ActionUpgrade))
+		assert.Check(t, cmp.DeepEqual(i.Parameters, map[string]interface{}{"seed": 9007199254740993}))
+	})
 }
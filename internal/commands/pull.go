@@ -4,11 +4,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/docker/app/internal/cnab"
 	"github.com/docker/app/internal/store"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/config"
-	"github.com/docker/distribution/reference"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -38,11 +38,15 @@ func runPull(dockerCli command.Cli, name string, opts registryOptions) error {
 		return err
 	}
 
-	ref, err := reference.ParseNormalizedNamed(name)
+	ref, err := cnab.ParseBundleReference(name)
 	if err != nil {
-		return errors.Wrap(err, name)
+		return err
+	}
+	named, err := ref.Named()
+	if err != nil {
+		return err
 	}
-	bndl, err := bundleStore.LookupOrPullBundle(reference.TagNameOnly(ref), true, dockerCli.ConfigFile(), opts.insecureRegistries)
+	bndl, err := bundleStore.LookupOrPullBundle(named, true, dockerCli.ConfigFile(), opts.insecureRegistries)
 	if err != nil {
 		return errors.Wrap(err, name)
 	}
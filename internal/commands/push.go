@@ -11,6 +11,7 @@ import (
 
 	"github.com/containerd/containerd/platforms"
 	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/registryclient"
 	"github.com/docker/app/types/metadata"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
@@ -61,7 +62,7 @@ func runPush(dockerCli command.Cli, name string, opts pushOptions) error {
 	if err != nil {
 		return err
 	}
-	if err := bndl.Validate(); err != nil {
+	if err := validateBundle(bndl); err != nil {
 		return err
 	}
 
@@ -96,7 +97,11 @@ func runPush(dockerCli command.Cli, name string, opts pushOptions) error {
 		return errors.Wrapf(err, "pushing to %q", retag.invocationImageRef.String())
 	}
 
-	resolverConfig := remotes.NewResolverConfigFromDockerConfigFile(dockerCli.ConfigFile(), opts.registry.insecureRegistries...)
+	client, err := registryclient.NewFromConfig(dockerCli.ConfigFile(), opts.registry.insecureRegistries)
+	if err != nil {
+		return err
+	}
+	resolverConfig := client.ResolverConfig()
 	var display fixupDisplay = &plainDisplay{out: os.Stdout}
 	if term.IsTerminal(os.Stdout.Fd()) {
 		display = &interactiveDisplay{out: os.Stdout}
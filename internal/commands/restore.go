@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/app/internal/snapshot"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/spf13/cobra"
+)
+
+type restoreOptions struct {
+	targetContext string
+}
+
+func restoreCmd(dockerCli command.Cli) *cobra.Command {
+	var opts restoreOptions
+	cmd := &cobra.Command{
+		Use:   "restore SNAPSHOT_FILE [--target-context TARGET_CONTEXT]",
+		Short: "Restore an installation from a snapshot",
+		Long: `Restore all the revisions of an installation from a snapshot saved with "docker app snapshot", read from stdin if SNAPSHOT_FILE is -. The installation must not exist on the target context.
+The masked values of the sensitive parameters and outputs are not restored, the sensitive parameters must be given again on the next action.`,
+		Example: `$ docker app restore myinstallation.tgz --target-context=mycontext`,
+		Args:    cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRestore(dockerCli, args[0], opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.targetContext, "target-context", "", "Context to restore the installation on (default: <current-context>)")
+	return cmd
+}
+
+func runRestore(dockerCli command.Cli, snapshotFile string, opts restoreOptions) error {
+	targetContext := getTargetContext(opts.targetContext, dockerCli.CurrentContext())
+	_, installationStore, _, err := prepareStores(dockerCli, targetContext)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = dockerCli.In()
+	if snapshotFile != "-" {
+		f, err := os.Open(snapshotFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	installation, err := snapshot.Restore(installationStore, r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Installation %q restored on context %q at revision %q\n", installation.Name, targetContext, installation.Revision)
+	return nil
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/redact"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/spf13/cobra"
+)
+
+// actionResume is the action of the lock taken while resuming an action.
+const actionResume = "resume"
+
+type resumeOptions struct {
+	credentialOptions
+	lockOptions
+	policyOptions
+	timeoutOptions
+}
+
+func resumeCmd(dockerCli command.Cli) *cobra.Command {
+	var opts resumeOptions
+
+	cmd := &cobra.Command{
+		Use:   "resume INSTALLATION_NAME [--target-context TARGET_CONTEXT] [OPTIONS]",
+		Short: "Resume the failed or interrupted last action of an installation",
+		Long: `Resume the failed or interrupted last action of an installation, with the parameters it was run with.
+The attempts are recorded in the revision of the action instead of a new one.`,
+		Example: `$ docker app resume myinstallation --target-context=mycontext`,
+		Args:    cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runResume(dockerCli, args[0], opts)
+		},
+	}
+	opts.credentialOptions.addFlags(cmd.Flags())
+	opts.lockOptions.addFlags(cmd.Flags())
+	opts.policyOptions.addFlags(cmd.Flags())
+	opts.timeoutOptions.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runResume(dockerCli command.Cli, installationName string, opts resumeOptions) error {
+	defer muteDockerCli(dockerCli)()
+	opts.SetDefaultTargetContext(dockerCli)
+
+	_, installationStore, credentialStore, err := prepareStores(dockerCli, opts.targetContext)
+	if err != nil {
+		return err
+	}
+	unlock, err := opts.lock(opts.targetContext, installationName, actionResume)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	installation, err := installationStore.Read(installationName)
+	if err != nil {
+		return err
+	}
+	// The resumed action is checked again, it may have been vetoed or the
+	// policies may have changed since it failed
+	admissionHook, err := opts.policyOptions.admissionHook(policy.Environment{
+		TargetContext: opts.targetContext,
+		Orchestrator:  stringParameter(installation.Parameters, internal.ParameterOrchestratorName),
+		Namespace:     stringParameter(installation.Parameters, internal.ParameterKubernetesNamespaceName),
+	})
+	if err != nil {
+		return err
+	}
+	bind, err := requiredClaimBindMount(installation.Claim, opts.targetContext, dockerCli)
+	if err != nil {
+		return err
+	}
+	creds, err := prepareCredentialSet(installation.Bundle, opts.CredentialSetOpts(dockerCli, credentialStore)...)
+	if err != nil {
+		return err
+	}
+	if err := cnab.ValidateCredentials(installation.Bundle, creds); err != nil {
+		return err
+	}
+	secrets, err := actionSecrets(installation, creds)
+	if err != nil {
+		return err
+	}
+	out := redact.NewWriter(os.Stdout, secrets...)
+	defer out.Flush() //nolint:errcheck // nothing much we can do with an error to write to output.
+	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, out)
+	if err != nil {
+		return err
+	}
+	outputs, err := prepareOutputs(driverImpl)
+	if err != nil {
+		return err
+	}
+	defer outputs.remove()
+	r, err := prepareRunner(dockerCli, installationStore, driverImpl, outputs)
+	if err != nil {
+		return err
+	}
+	if admissionHook != nil {
+		r.Hooks = append(r.Hooks, *admissionHook)
+	}
+	ctx, cancel := opts.timeoutOptions.context()
+	defer cancel()
+	start := time.Now()
+	resumed, err := r.Resume(ctx, installationName, creds, out)
+	if resumed == nil {
+		return err
+	}
+	actionName := resumed.Result.Action
+	auditAction(dockerCli, actionName, opts.targetContext, resumed)
+	notifyAction(dockerCli, actionName, opts.targetContext, resumed, start)
+	if err != nil {
+		return fmt.Errorf("Resuming %s failed: %s\n%s", actionName, redact.String(errBuf.String(), secrets...), redact.Error(err, secrets...))
+	}
+	if actionName == claim.ActionUninstall {
+		if err := installationStore.Delete(installationName); err != nil {
+			return fmt.Errorf("Failed to delete installation %q from the installation store: %s", installationName, err)
+		}
+	}
+	fmt.Fprintf(os.Stdout, "Application %q resumed its %s on context %q\n", installationName, actionName, opts.targetContext)
+	return nil
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/history"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/redact"
+	"github.com/docker/cli/cli/command"
+	"github.com/spf13/cobra"
+)
+
+// actionRollback is the action recorded in the audit log for a rollback.
+const actionRollback = "rollback"
+
+type rollbackOptions struct {
+	credentialOptions
+	lockOptions
+	policyOptions
+	timeoutOptions
+	revision string
+}
+
+func rollbackCmd(dockerCli command.Cli) *cobra.Command {
+	var opts rollbackOptions
+	cmd := &cobra.Command{
+		Use:     "rollback INSTALLATION_NAME --revision REVISION [--target-context TARGET_CONTEXT] [OPTIONS]",
+		Short:   "Roll back an installation to a previous revision",
+		Long:    "Roll back an installation to a previous revision, upgrading it with the application and parameters of that revision.",
+		Example: `$ docker app rollback myinstallation --revision 01DH6GQ5M8WE4YBFQ59HNWBEAH --target-context=mycontext`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRollback(dockerCli, args[0], opts)
+		},
+	}
+	opts.credentialOptions.addFlags(cmd.Flags())
+	opts.lockOptions.addFlags(cmd.Flags())
+	opts.policyOptions.addFlags(cmd.Flags())
+	opts.timeoutOptions.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&opts.revision, "revision", "", "Revision to roll back to")
+	cmd.MarkFlagRequired("revision") //nolint:errcheck // the flag is defined above
+
+	return cmd
+}
+
+func runRollback(dockerCli command.Cli, installationName string, opts rollbackOptions) error {
+	defer muteDockerCli(dockerCli)()
+	opts.SetDefaultTargetContext(dockerCli)
+
+	_, installationStore, credentialStore, err := prepareStores(dockerCli, opts.targetContext)
+	if err != nil {
+		return err
+	}
+	unlock, err := opts.lock(opts.targetContext, installationName, actionRollback)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	rollback := &history.Rollback{Installations: installationStore}
+	installation, err := rollback.Prepare(installationName, opts.revision)
+	if err != nil {
+		return err
+	}
+	// The bundle of the target revision is checked again, as it is run again
+	if err := verifyBundleSignatures(dockerCli, installation.Reference, installation.Bundle); err != nil {
+		return err
+	}
+	if err := verifyBundleProvenance(dockerCli, installation.Reference, installation.Bundle); err != nil {
+		return err
+	}
+	if err := checkEnvironment(installation.Bundle); err != nil {
+		return err
+	}
+	admissionHook, err := opts.policyOptions.admissionHook(policy.Environment{
+		TargetContext: opts.targetContext,
+		Orchestrator:  stringParameter(installation.Parameters, internal.ParameterOrchestratorName),
+		Namespace:     stringParameter(installation.Parameters, internal.ParameterKubernetesNamespaceName),
+	})
+	if err != nil {
+		return err
+	}
+
+	bind, err := requiredClaimBindMount(installation.Claim, opts.targetContext, dockerCli)
+	if err != nil {
+		return err
+	}
+	creds, err := prepareCredentialSet(installation.Bundle, opts.CredentialSetOpts(dockerCli, credentialStore)...)
+	if err != nil {
+		return err
+	}
+	if err := cnab.ValidateCredentials(installation.Bundle, creds); err != nil {
+		return err
+	}
+	secrets, err := actionSecrets(installation, creds)
+	if err != nil {
+		return err
+	}
+	out := redact.NewWriter(os.Stdout, secrets...)
+	defer out.Flush() //nolint:errcheck // nothing much we can do with an error to write to output.
+	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, out)
+	if err != nil {
+		return err
+	}
+	outputs, err := prepareOutputs(driverImpl)
+	if err != nil {
+		return err
+	}
+	defer outputs.remove()
+	r, err := prepareRunner(dockerCli, installationStore, driverImpl, outputs)
+	if err != nil {
+		return err
+	}
+	if admissionHook != nil {
+		r.Hooks = append(r.Hooks, *admissionHook)
+	}
+	ctx, cancel := opts.timeoutOptions.context()
+	defer cancel()
+	start := time.Now()
+	err = r.Run(ctx, installation, claim.ActionUpgrade, creds, out)
+	auditAction(dockerCli, actionRollback, opts.targetContext, installation)
+	notifyAction(dockerCli, actionRollback, opts.targetContext, installation, start)
+	if err != nil {
+		return fmt.Errorf("Rollback failed: %s\n%s", redact.String(errBuf.String(), secrets...), redact.Error(err, secrets...))
+	}
+	fmt.Fprintf(os.Stdout, "Application %q rolled back to revision %q on context %q\n", installationName, opts.revision, opts.targetContext)
+	return nil
+}
@@ -1,11 +1,25 @@
 package commands
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/docker/app/internal/admission"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/runner"
+	"github.com/docker/app/internal/scan"
 	"github.com/docker/app/internal/store"
+	"github.com/docker/app/pkg/telemetry"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/config"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -18,6 +32,7 @@ func NewRootCmd(use string, dockerCli command.Cli) *cobra.Command {
 		Use:         use,
 		Annotations: map[string]string{"experimentalCLI": "true"},
 	}
+	telemetry.SetLogger(logrusLogger{})
 	addCommands(cmd, dockerCli)
 	return cmd
 }
@@ -27,6 +42,10 @@ func addCommands(cmd *cobra.Command, dockerCli command.Cli) {
 		installCmd(dockerCli),
 		upgradeCmd(dockerCli),
 		uninstallCmd(dockerCli),
+		rollbackCmd(dockerCli),
+		resumeCmd(dockerCli),
+		snapshotCmd(dockerCli),
+		restoreCmd(dockerCli),
 		listCmd(dockerCli),
 		statusCmd(dockerCli),
 		initCmd(dockerCli),
@@ -38,8 +57,11 @@ func addCommands(cmd *cobra.Command, dockerCli command.Cli) {
 		versionCmd(dockerCli),
 		completionCmd(dockerCli, cmd),
 		bundleCmd(dockerCli),
+		channelCmd(dockerCli),
 		pushCmd(dockerCli),
 		pullCmd(dockerCli),
+		gcCmd(dockerCli),
+		serveCmd(dockerCli),
 	)
 }
 
@@ -59,7 +81,7 @@ func muteDockerCli(dockerCli command.Cli) func() {
 	}
 }
 
-func prepareStores(targetContext string) (store.BundleStore, store.InstallationStore, store.CredentialStore, error) {
+func prepareStores(dockerCli command.Cli, targetContext string) (store.BundleStore, store.InstallationStore, store.CredentialStore, error) {
 	appstore, err := store.NewApplicationStore(config.Dir())
 	if err != nil {
 		return nil, nil, nil, err
@@ -68,6 +90,9 @@ func prepareStores(targetContext string) (store.BundleStore, store.InstallationS
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if installationStore, err = encryptOutputs(dockerCli, installationStore); err != nil {
+		return nil, nil, nil, err
+	}
 	bundleStore, err := appstore.BundleStore()
 	if err != nil {
 		return nil, nil, nil, err
@@ -79,6 +104,34 @@ func prepareStores(targetContext string) (store.BundleStore, store.InstallationS
 	return bundleStore, installationStore, credentialStore, nil
 }
 
+// encryptOutputs encrypts the outputs of the installations of the store when
+// the "outputs-encryption-key-file" key of the "app" plugin section of the
+// docker CLI configuration file names a file holding a base64 encoded 16, 24
+// or 32 bytes AES key.
+func encryptOutputs(dockerCli command.Cli, installationStore store.InstallationStore) (store.InstallationStore, error) {
+	cfg := dockerCli.ConfigFile()
+	if cfg == nil {
+		return installationStore, nil
+	}
+	path, ok := cfg.PluginConfig("app", "outputs-encryption-key-file")
+	if !ok || path == "" {
+		return installationStore, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the outputs encryption key")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid outputs encryption key in %q", path)
+	}
+	keys, err := store.NewAESKeyProvider(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid outputs encryption key in %q", path)
+	}
+	return store.NewEncryptedStore(installationStore, keys), nil
+}
+
 func prepareBundleStore() (store.BundleStore, error) {
 	appstore, err := store.NewApplicationStore(config.Dir())
 	if err != nil {
@@ -136,6 +189,44 @@ func (o *registryOptions) addFlags(flags *pflag.FlagSet) {
 	flags.StringSliceVar(&o.insecureRegistries, "insecure-registries", nil, "Use HTTP instead of HTTPS when pulling from/pushing to those registries")
 }
 
+type policyOptions struct {
+	policies     []string
+	scanner      string
+	scanSeverity string
+}
+
+func (o *policyOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&o.policies, "policy", nil, "Check the operation against the Rego policies of this file or directory (requires opa)")
+	flags.StringVar(&o.scanner, "scan", "", "Scan the images with this vulnerability scanner (trivy or grype) and deny the operation if vulnerabilities are found")
+	flags.StringVar(&o.scanSeverity, "scan-severity", "critical", "Minimum severity of the vulnerabilities denying the operation")
+}
+
+// admissionHook returns the runner hook checking the operations against the
+// policies and the vulnerability scanner of the options, before anything
+// runs. It returns nil if there is nothing to check.
+func (o *policyOptions) admissionHook(env policy.Environment) (*runner.Hook, error) {
+	var evaluators []admission.PolicyEvaluator
+	if len(o.policies) > 0 {
+		evaluators = append(evaluators, admission.Policy(&policy.Rego{Modules: o.policies}))
+	}
+	if o.scanner != "" {
+		scanner, err := scan.New(o.scanner)
+		if err != nil {
+			return nil, err
+		}
+		severity, err := scan.ParseSeverity(o.scanSeverity)
+		if err != nil {
+			return nil, err
+		}
+		evaluators = append(evaluators, admission.Policy(&scan.Evaluator{Scanner: scanner, Severity: severity}))
+	}
+	if len(evaluators) == 0 {
+		return nil, nil
+	}
+	hook := admission.Hook(env, evaluators...)
+	return &hook, nil
+}
+
 type pullOptions struct {
 	pull bool
 }
@@ -143,3 +234,78 @@ type pullOptions struct {
 func (o *pullOptions) addFlags(flags *pflag.FlagSet) {
 	flags.BoolVar(&o.pull, "pull", false, "Pull the bundle")
 }
+
+type lockOptions struct {
+	forceUnlock bool
+}
+
+func (o *lockOptions) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.forceUnlock, "force-unlock", false, "Remove the lock left on the installation by another operation before running")
+}
+
+// lock prevents other processes from modifying the installation until the
+// returned function is called.
+func (o *lockOptions) lock(targetContext, installationName, action string) (func(), error) {
+	appstore, err := store.NewApplicationStore(config.Dir())
+	if err != nil {
+		return nil, err
+	}
+	locker, err := appstore.InstallationLocker(targetContext)
+	if err != nil {
+		return nil, err
+	}
+	if o.forceUnlock {
+		if err := locker.ForceUnlock(installationName); err != nil {
+			return nil, err
+		}
+	}
+	lock, err := locker.Lock(installationName, action)
+	if err != nil {
+		if store.IsLocked(err) {
+			return nil, fmt.Errorf("%s, use --force-unlock if it is not running anymore", err)
+		}
+		return nil, err
+	}
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		}
+	}, nil
+}
+
+type timeoutOptions struct {
+	timeout time.Duration
+}
+
+func (o *timeoutOptions) addFlags(flags *pflag.FlagSet) {
+	flags.DurationVar(&o.timeout, "timeout", 0, "Interrupt the operation if it runs longer than this duration, like 10m (default: no timeout)")
+}
+
+// context returns the context of the operation, done on timeout or when the
+// command is interrupted, so the invocation image is stopped gracefully and
+// the interruption is recorded in the installation.
+func (o *timeoutOptions) context() (context.Context, func()) {
+	var (
+		ctx    context.Context
+		cancel func()
+	)
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), o.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, "Interrupting the operation...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
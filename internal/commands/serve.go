@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/audit"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/registryclient"
+	"github.com/docker/app/internal/runner"
+	"github.com/docker/app/internal/server"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type serveOptions struct {
+	policyOptions
+	grpcAddress string
+	httpAddress string
+	tlsCert     string
+	tlsKey      string
+	tlsClientCA string
+	tokenFile   string
+}
+
+func serveCmd(dockerCli command.Cli) *cobra.Command {
+	var opts serveOptions
+	cmd := &cobra.Command{
+		Use:   "serve [OPTIONS]",
+		Short: "Serve the bundle operations over gRPC and REST",
+		Long: `Serve the bundle operations over gRPC and REST, running the actions with the invocation image driver of the CLI.
+The clients must authenticate with a TLS client certificate, verified with --tls-client-ca, or with the bearer token of --token-file.
+Without TLS, the service can only listen on the loopback interface.`,
+		Example: `$ docker app serve --token-file ~/.docker/app-token
+$ docker app serve --http-address 0.0.0.0:8443 --tls-cert server.pem --tls-key server-key.pem --tls-client-ca ca.pem`,
+		Args: cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(dockerCli, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.httpAddress, "http-address", "127.0.0.1:8080", "Address of the REST API, empty to disable it")
+	cmd.Flags().StringVar(&opts.grpcAddress, "grpc-address", "", "Address of the gRPC API, disabled if empty")
+	cmd.Flags().StringVar(&opts.tlsCert, "tls-cert", "", "Certificate of the server, to serve over TLS")
+	cmd.Flags().StringVar(&opts.tlsKey, "tls-key", "", "Private key of the server certificate")
+	cmd.Flags().StringVar(&opts.tlsClientCA, "tls-client-ca", "", "CA certificates the client certificates are verified with")
+	cmd.Flags().StringVar(&opts.tokenFile, "token-file", "", "File holding the bearer token of the clients")
+	opts.policyOptions.addFlags(cmd.Flags())
+	return cmd
+}
+
+func runServe(dockerCli command.Cli, opts serveOptions) error {
+	security, err := opts.security()
+	if err != nil {
+		return err
+	}
+	if opts.grpcAddress == "" && opts.httpAddress == "" {
+		return errors.New("no address to serve on")
+	}
+	if security.TLS == nil {
+		for _, address := range []string{opts.grpcAddress, opts.httpAddress} {
+			if address != "" && !isLoopback(address) {
+				return errors.Errorf("cannot serve on %s without TLS, only the loopback interface is allowed", address)
+			}
+		}
+	}
+
+	driverImpl, _, err := prepareDriver(dockerCli, bindMount{}, nil)
+	if err != nil {
+		return err
+	}
+	client, err := registryclient.NewFromConfig(dockerCli.ConfigFile(), nil)
+	if err != nil {
+		return err
+	}
+	srv := &server.Server{
+		Resolver: client.Resolver(),
+		Driver:   driverImpl,
+		Verify: func(ctx context.Context, bndl *bundle.Bundle) error {
+			if err := verifyBundleSignatures(dockerCli, "", bndl); err != nil {
+				return err
+			}
+			if err := verifyBundleProvenance(dockerCli, "", bndl); err != nil {
+				return err
+			}
+			return verifyImageDigests(dockerCli, bndl, nil)
+		},
+	}
+	admissionHook, err := opts.policyOptions.admissionHook(policy.Environment{})
+	if err != nil {
+		return err
+	}
+	if admissionHook != nil {
+		srv.Hooks = append(srv.Hooks, *admissionHook)
+	}
+	srv.Hooks = append(srv.Hooks, auditHook(dockerCli))
+
+	var grpcListener, httpListener net.Listener
+	if opts.grpcAddress != "" {
+		if grpcListener, err = net.Listen("tcp", opts.grpcAddress); err != nil {
+			return err
+		}
+		defer grpcListener.Close()
+		fmt.Fprintf(dockerCli.Out(), "Serving gRPC on %s\n", grpcListener.Addr())
+	}
+	if opts.httpAddress != "" {
+		if httpListener, err = net.Listen("tcp", opts.httpAddress); err != nil {
+			return err
+		}
+		defer httpListener.Close()
+		fmt.Fprintf(dockerCli.Out(), "Serving REST on %s\n", httpListener.Addr())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return server.Serve(ctx, srv, grpcListener, httpListener, security)
+}
+
+// auditHook records the actions run by the server in the audit log
+// configured in the docker CLI configuration file, if any, like auditAction
+// does for the command line. The actions failing before they run are
+// recorded too.
+func auditHook(dockerCli command.Cli) runner.Hook {
+	record := func(event runner.HookEvent) {
+		logger := newAuditLogger(dockerCli)
+		if !logger.Enabled() {
+			return
+		}
+		r := audit.NewRecord(event.Action, event.Claim.Name, event.Bundle)
+		r.Status = event.Claim.Result.Status
+		r.Message = event.Claim.Result.Message
+		if err := logger.Log(r); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		}
+	}
+	return runner.Hook{
+		AfterAction: func(ctx context.Context, event runner.HookEvent) {
+			record(event)
+		},
+		OnError: func(ctx context.Context, event runner.HookEvent, err error) {
+			if event.Operation == nil {
+				record(event)
+			}
+		},
+	}
+}
+
+func (o serveOptions) security() (server.Security, error) {
+	var security server.Security
+	if o.tlsCert != "" || o.tlsKey != "" {
+		if o.tlsCert == "" || o.tlsKey == "" {
+			return security, errors.New("both --tls-cert and --tls-key are required to serve over TLS")
+		}
+		config, err := server.LoadTLSConfig(o.tlsCert, o.tlsKey, o.tlsClientCA)
+		if err != nil {
+			return security, err
+		}
+		security.TLS = config
+	} else if o.tlsClientCA != "" {
+		return security, errors.New("--tls-client-ca requires --tls-cert and --tls-key")
+	}
+	if o.tokenFile != "" {
+		data, err := ioutil.ReadFile(o.tokenFile)
+		if err != nil {
+			return security, err
+		}
+		if security.Token = strings.TrimSpace(string(data)); security.Token == "" {
+			return security, errors.Errorf("token file %q is empty", o.tokenFile)
+		}
+	}
+	return security, nil
+}
+
+// isLoopback returns true if the host of the address is localhost or a
+// loopback IP.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
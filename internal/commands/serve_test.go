@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestIsLoopback(t *testing.T) {
+	for address, expected := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		"0.0.0.0:8080":   false,
+		":8080":          false,
+		"example.com:80": false,
+		"127.0.0.1":      false,
+	} {
+		assert.Check(t, is.Equal(isLoopback(address), expected), address)
+	}
+}
+
+func TestServeSecurity(t *testing.T) {
+	dir := fs.NewDir(t, "serve", fs.WithFile("token", "s3cr3t\n"), fs.WithFile("empty", ""))
+	defer dir.Remove()
+
+	security, err := serveOptions{tokenFile: dir.Join("token")}.security()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(security.Token, "s3cr3t"))
+	assert.Check(t, security.TLS == nil)
+
+	_, err = serveOptions{tokenFile: dir.Join("empty")}.security()
+	assert.Check(t, is.ErrorContains(err, "is empty"))
+	_, err = serveOptions{tlsCert: "server.pem"}.security()
+	assert.Check(t, is.ErrorContains(err, "both --tls-cert and --tls-key are required"))
+	_, err = serveOptions{tlsClientCA: "ca.pem"}.security()
+	assert.Check(t, is.ErrorContains(err, "--tls-client-ca requires --tls-cert and --tls-key"))
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/docker/app/internal/snapshot"
+	"github.com/docker/cli/cli"
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/spf13/cobra"
+)
+
+type snapshotOptions struct {
+	targetContext string
+	out           string
+}
+
+func snapshotCmd(dockerCli command.Cli) *cobra.Command {
+	var opts snapshotOptions
+	cmd := &cobra.Command{
+		Use:   "snapshot INSTALLATION_NAME [--target-context TARGET_CONTEXT] [--output OUTPUT_FILE]",
+		Short: "Save all the revisions of an installation to an archive",
+		Long: `Save all the revisions of an installation, with the exact application packages they ran, to a gzipped tarball which can be restored on another host with "docker app restore".
+The values of the sensitive parameters and outputs are masked, so the snapshot can be attached to a support request.`,
+		Example: `$ docker app snapshot myinstallation --output myinstallation.tgz`,
+		Args:    cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshot(dockerCli, args[0], opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.targetContext, "target-context", "", "Context on which the application is installed (default: <current-context>)")
+	cmd.Flags().StringVarP(&opts.out, "output", "o", "", "Output file, - for stdout (default: INSTALLATION_NAME.tgz)")
+	return cmd
+}
+
+func runSnapshot(dockerCli command.Cli, installationName string, opts snapshotOptions) error {
+	targetContext := getTargetContext(opts.targetContext, dockerCli.CurrentContext())
+	_, installationStore, _, err := prepareStores(dockerCli, targetContext)
+	if err != nil {
+		return err
+	}
+	out := opts.out
+	if out == "" {
+		out = installationName + ".tgz"
+	}
+	if out == "-" && dockerCli.Out().IsTerminal() {
+		return fmt.Errorf("Refusing to write the snapshot to a terminal, redirect the output or use --output")
+	}
+	buf := bytes.NewBuffer(nil)
+	manifest, err := snapshot.Snapshot(installationStore, installationName, buf)
+	if err != nil {
+		return err
+	}
+	if out == "-" {
+		_, err = dockerCli.Out().Write(buf.Bytes())
+		return err
+	}
+	// The snapshot holds the parameters and outputs which are not sensitive
+	if err := ioutils.AtomicWriteFile(out, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Installation %q saved to %q with its %d revisions\n", installationName, out, len(manifest.Revisions))
+	return nil
+}
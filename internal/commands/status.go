@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,9 +10,10 @@ import (
 	"text/tabwriter"
 	"time"
 
-	"github.com/deislabs/cnab-go/action"
-	"github.com/deislabs/cnab-go/credentials"
 	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers"
+	"github.com/docker/app/internal/redact"
 	"github.com/docker/app/internal/store"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
@@ -19,13 +21,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var (
-	knownStatusActions = []string{
-		internal.ActionStatusName,
-		internal.ActionStatusNameDeprecated,
-	}
-)
-
 func statusCmd(dockerCli command.Cli) *cobra.Command {
 	var opts credentialOptions
 
@@ -48,7 +43,7 @@ func runStatus(dockerCli command.Cli, installationName string, opts credentialOp
 	defer muteDockerCli(dockerCli)()
 	opts.SetDefaultTargetContext(dockerCli)
 
-	_, installationStore, credentialStore, err := prepareStores(opts.targetContext)
+	_, installationStore, credentialStore, err := prepareStores(dockerCli, opts.targetContext)
 	if err != nil {
 		return err
 	}
@@ -60,8 +55,7 @@ func runStatus(dockerCli command.Cli, installationName string, opts credentialOp
 	displayInstallationStatus(os.Stdout, installation)
 
 	// Check if the bundle knows the docker app status action, if not just exit without error.
-	statusAction := resolveStatusAction(installation)
-	if statusAction == "" {
+	if cnab.StatusAction(installation.Bundle) == "" {
 		return nil
 	}
 
@@ -73,7 +67,7 @@ func runStatus(dockerCli command.Cli, installationName string, opts credentialOp
 	if err != nil {
 		return err
 	}
-	if err := mergeBundleParameters(installation,
+	if err := mergeBundleParameters(installation, cnab.StatusAction(installation.Bundle),
 		withSendRegistryAuth(opts.sendRegistryAuth),
 	); err != nil {
 		return err
@@ -82,15 +76,11 @@ func runStatus(dockerCli command.Cli, installationName string, opts credentialOp
 	if err != nil {
 		return err
 	}
-	if err := credentials.Validate(creds, installation.Bundle.Credentials); err != nil {
+	if err := cnab.ValidateCredentials(installation.Bundle, creds); err != nil {
 		return err
 	}
 	printHeader(os.Stdout, "STATUS")
-	status := &action.RunCustom{
-		Action: statusAction,
-		Driver: driverImpl,
-	}
-	if err := status.Run(&installation.Claim, creds, dockerCli.Out()); err != nil {
+	if _, err := cnab.RunStatus(installation.Claim, creds, drivers.WithContext(context.Background(), driverImpl), dockerCli.Out()); err != nil {
 		return fmt.Errorf("status failed: %s\n%s", err, errBuf)
 	}
 	return nil
@@ -131,10 +121,17 @@ func displayInstallationStatus(w io.Writer, installation *store.Installation) {
 		printHeader(w, "PARAMETERS")
 		tab = tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
 		params := sortParameters(installation)
+		sensitive, err := cnab.SensitiveParameters(installation.Bundle)
+		if err != nil {
+			// Better hide everything than leak a secret
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+			sensitive = params
+		}
+		values := redact.Parameters(installation.Parameters, sensitive...)
 		for _, param := range params {
 			if !strings.HasPrefix(param, internal.Namespace) {
 				// TODO: Trim long []byte parameters, maybe add type too (string, int...)
-				printValue(tab, param, fmt.Sprintf("%v", installation.Parameters[param]))
+				printValue(tab, param, fmt.Sprintf("%v", values[param]))
 			}
 		}
 		tab.Flush()
@@ -159,12 +156,3 @@ func printHeader(w io.Writer, header string) {
 func printValue(w io.Writer, key, value string) {
 	fmt.Fprintf(w, "%s:\t%s\n", key, value)
 }
-
-func resolveStatusAction(installation *store.Installation) string {
-	for _, name := range knownStatusActions {
-		if _, ok := installation.Bundle.Actions[name]; ok {
-			return name
-		}
-	}
-	return ""
-}
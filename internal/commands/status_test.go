@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/store"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestDisplayInstallationStatusMasksSensitiveParameters(t *testing.T) {
+	installation, err := store.NewInstallation("myinstallation", "myapp:1.0.0")
+	assert.NilError(t, err)
+	installation.Bundle = &bundle.Bundle{
+		Name: "myapp",
+		Parameters: map[string]bundle.ParameterDefinition{
+			"user":     {DataType: "string"},
+			"password": {DataType: "string"},
+		},
+		Custom: map[string]interface{}{
+			cnab.SensitiveParametersExtensionKey: []interface{}{"password"},
+		},
+	}
+	installation.Parameters = map[string]interface{}{"user": "admin", "password": "s3cr3t"}
+
+	out := bytes.NewBuffer(nil)
+	displayInstallationStatus(out, installation)
+	assert.Check(t, is.Contains(out.String(), "admin"))
+	assert.Check(t, !bytes.Contains(out.Bytes(), []byte("s3cr3t")))
+
+	secrets, err := actionSecrets(installation, map[string]string{"token": "t0ken"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(secrets, []string{"t0ken", "s3cr3t"}))
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/sirupsen/logrus"
+)
+
+// logrusLogger sends the telemetry logs to logrus when the docker CLI runs
+// with --debug. Otherwise nothing is logged, the commands reporting the
+// errors of the operations themselves.
+type logrusLogger struct{}
+
+func (logrusLogger) Log(_ context.Context, level telemetry.Level, msg string, fields telemetry.Fields) {
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	entry := logrus.WithFields(logrus.Fields(fields))
+	switch level {
+	case telemetry.LevelDebug:
+		entry.Debug(msg)
+	case telemetry.LevelInfo:
+		entry.Info(msg)
+	case telemetry.LevelWarn:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
+	}
+}
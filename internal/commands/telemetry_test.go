@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestLogrusLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer logrus.SetOutput(logrus.StandardLogger().Out)
+	defer logrus.SetLevel(logrus.GetLevel())
+	logrus.SetOutput(&buf)
+	telemetry.SetLogger(logrusLogger{})
+	defer telemetry.SetLogger(nil)
+
+	_, op := telemetry.Start(context.Background(), telemetry.SpanPull, telemetry.Fields{"reference": "my-app:1.0.0"})
+	op.End(errors.New("boom"))
+	assert.Check(t, is.Equal(buf.String(), ""))
+
+	logrus.SetLevel(logrus.DebugLevel)
+	_, op = telemetry.Start(context.Background(), telemetry.SpanPull, telemetry.Fields{"reference": "my-app:1.0.0"})
+	op.End(errors.New("boom"))
+	assert.Check(t, is.Contains(buf.String(), `msg="cnab.pull failed"`))
+	assert.Check(t, is.Contains(buf.String(), "error=boom"))
+	assert.Check(t, is.Contains(buf.String(), "reference=\"my-app:1.0.0\""))
+}
@@ -3,9 +3,11 @@ package commands
 import (
 	"fmt"
 	"os"
+	"time"
 
-	"github.com/deislabs/cnab-go/action"
-	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/redact"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
 	"github.com/spf13/cobra"
@@ -13,6 +15,8 @@ import (
 
 type uninstallOptions struct {
 	credentialOptions
+	lockOptions
+	timeoutOptions
 	force bool
 }
 
@@ -28,7 +32,9 @@ func uninstallCmd(dockerCli command.Cli) *cobra.Command {
 			return runUninstall(dockerCli, args[0], opts)
 		},
 	}
-	opts.addFlags(cmd.Flags())
+	opts.credentialOptions.addFlags(cmd.Flags())
+	opts.lockOptions.addFlags(cmd.Flags())
+	opts.timeoutOptions.addFlags(cmd.Flags())
 	cmd.Flags().BoolVar(&opts.force, "force", false, "Force removal of installation")
 
 	return cmd
@@ -38,10 +44,15 @@ func runUninstall(dockerCli command.Cli, installationName string, opts uninstall
 	defer muteDockerCli(dockerCli)()
 	opts.SetDefaultTargetContext(dockerCli)
 
-	_, installationStore, credentialStore, err := prepareStores(opts.targetContext)
+	_, installationStore, credentialStore, err := prepareStores(dockerCli, opts.targetContext)
 	if err != nil {
 		return err
 	}
+	unlock, err := opts.lock(opts.targetContext, installationName, claim.ActionUninstall)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	installation, err := installationStore.Read(installationName)
 	if err != nil {
@@ -63,25 +74,36 @@ func runUninstall(dockerCli command.Cli, installationName string, opts uninstall
 	if err != nil {
 		return err
 	}
-	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, nil)
+	creds, err := prepareCredentialSet(installation.Bundle, opts.CredentialSetOpts(dockerCli, credentialStore)...)
 	if err != nil {
 		return err
 	}
-	creds, err := prepareCredentialSet(installation.Bundle, opts.CredentialSetOpts(dockerCli, credentialStore)...)
+	if err := cnab.ValidateCredentials(installation.Bundle, creds); err != nil {
+		return err
+	}
+	secrets, err := actionSecrets(installation, creds)
 	if err != nil {
 		return err
 	}
-	if err := credentials.Validate(creds, installation.Bundle.Credentials); err != nil {
+	out := redact.NewWriter(os.Stdout, secrets...)
+	defer out.Flush() //nolint:errcheck // nothing much we can do with an error to write to output.
+	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, out)
+	if err != nil {
 		return err
 	}
-	uninst := &action.Uninstall{
-		Driver: driverImpl,
+	// The outputs are not collected, the installation being deleted
+	r, err := prepareRunner(dockerCli, installationStore, driverImpl, nil)
+	if err != nil {
+		return err
 	}
-	if err := uninst.Run(&installation.Claim, creds, os.Stdout); err != nil {
-		if err2 := installationStore.Store(installation); err2 != nil {
-			return fmt.Errorf("%s while %s", err2, errBuf)
-		}
-		return fmt.Errorf("Uninstall failed: %s\n%s", err, errBuf)
+	ctx, cancel := opts.timeoutOptions.context()
+	defer cancel()
+	start := time.Now()
+	err = r.Run(ctx, installation, claim.ActionUninstall, creds, out)
+	auditAction(dockerCli, claim.ActionUninstall, opts.targetContext, installation)
+	notifyAction(dockerCli, claim.ActionUninstall, opts.targetContext, installation, start)
+	if err != nil {
+		return fmt.Errorf("Uninstall failed: %s\n%s", redact.Error(err, secrets...), redact.String(errBuf.String(), secrets...))
 	}
 	if err := installationStore.Delete(installationName); err != nil {
 		return fmt.Errorf("Failed to delete installation %q from the installation store: %s", installationName, err)
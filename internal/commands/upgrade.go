@@ -3,9 +3,15 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/deislabs/cnab-go/action"
-	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/internal/redact"
 	"github.com/docker/cli/cli/command"
 	"github.com/spf13/cobra"
 )
@@ -15,7 +21,11 @@ type upgradeOptions struct {
 	credentialOptions
 	registryOptions
 	pullOptions
+	policyOptions
+	lockOptions
+	timeoutOptions
 	bundleOrDockerApp string
+	force             bool
 }
 
 func upgradeCmd(dockerCli command.Cli) *cobra.Command {
@@ -33,7 +43,11 @@ func upgradeCmd(dockerCli command.Cli) *cobra.Command {
 	opts.credentialOptions.addFlags(cmd.Flags())
 	opts.registryOptions.addFlags(cmd.Flags())
 	opts.pullOptions.addFlags(cmd.Flags())
+	opts.policyOptions.addFlags(cmd.Flags())
+	opts.lockOptions.addFlags(cmd.Flags())
+	opts.timeoutOptions.addFlags(cmd.Flags())
 	cmd.Flags().StringVar(&opts.bundleOrDockerApp, "app-name", "", "Override the installation with another Application Package")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Upgrade even if the Application Package has breaking changes")
 
 	return cmd
 }
@@ -42,10 +56,15 @@ func runUpgrade(dockerCli command.Cli, installationName string, opts upgradeOpti
 	defer muteDockerCli(dockerCli)()
 	opts.SetDefaultTargetContext(dockerCli)
 
-	bundleStore, installationStore, credentialStore, err := prepareStores(opts.targetContext)
+	bundleStore, installationStore, credentialStore, err := prepareStores(dockerCli, opts.targetContext)
 	if err != nil {
 		return err
 	}
+	unlock, err := opts.lock(opts.targetContext, installationName, claim.ActionUpgrade)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	installation, err := installationStore.Read(installationName)
 	if err != nil {
@@ -56,47 +75,158 @@ func runUpgrade(dockerCli command.Cli, installationName string, opts upgradeOpti
 		return fmt.Errorf("Installation %q has failed and cannot be upgraded, reinstall it using 'docker app install'", installationName)
 	}
 
+	// previous is the bundle of the installation, if upgraded to another one
+	var previous *bundle.Bundle
 	if opts.bundleOrDockerApp != "" {
-		b, _, err := resolveBundle(dockerCli, bundleStore, opts.bundleOrDockerApp, opts.pull, opts.insecureRegistries)
+		b, ref, err := resolveBundle(dockerCli, bundleStore, opts.bundleOrDockerApp, opts.pull, opts.insecureRegistries)
 		if err != nil {
 			return err
 		}
+		if err := verifyBundleSignatures(dockerCli, ref, b); err != nil {
+			return err
+		}
+		if err := verifyBundleProvenance(dockerCli, ref, b); err != nil {
+			return err
+		}
+		if err := verifyImageDigests(dockerCli, b, opts.insecureRegistries); err != nil {
+			return err
+		}
+		if err := cnab.CheckRequiredExtensions(b, cnab.SupportedExtensions); err != nil {
+			return err
+		}
+		if err := cnab.ValidateParameterDestinations(b); err != nil {
+			return err
+		}
+		previous = installation.Bundle
 		installation.Bundle = b
 	}
-	if err := mergeBundleParameters(installation,
+	if err := checkEnvironment(installation.Bundle); err != nil {
+		return err
+	}
+	if err := mergeBundleParameters(installation, claim.ActionUpgrade,
 		withFileParameters(opts.parametersFiles),
 		withCommandLineParameters(opts.overrides),
 		withSendRegistryAuth(opts.sendRegistryAuth),
 	); err != nil {
 		return err
 	}
+	if previous != nil {
+		// The breaking changes are checked against the parameter values
+		// the upgrade runs with
+		report, err := cnab.CheckUpgrade(previous, installation.Bundle, installation.Parameters)
+		if err != nil {
+			return err
+		}
+		if err := checkBreakingChanges(previous, installation.Bundle, report, opts.force); err != nil {
+			return err
+		}
+		printUpgradeNotes(previous, installation.Bundle)
+		printBundleChanges(previous, installation.Bundle)
+	}
+	admissionHook, err := opts.policyOptions.admissionHook(policy.Environment{
+		TargetContext: opts.targetContext,
+		Orchestrator:  stringParameter(installation.Parameters, internal.ParameterOrchestratorName),
+		Namespace:     stringParameter(installation.Parameters, internal.ParameterKubernetesNamespaceName),
+	})
+	if err != nil {
+		return err
+	}
 
 	bind, err := requiredClaimBindMount(installation.Claim, opts.targetContext, dockerCli)
 	if err != nil {
 		return err
 	}
-	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, nil)
+	creds, err := prepareCredentialSet(installation.Bundle, opts.CredentialSetOpts(dockerCli, credentialStore)...)
 	if err != nil {
 		return err
 	}
-	creds, err := prepareCredentialSet(installation.Bundle, opts.CredentialSetOpts(dockerCli, credentialStore)...)
+	if err := cnab.ValidateCredentials(installation.Bundle, creds); err != nil {
+		return err
+	}
+	secrets, err := actionSecrets(installation, creds)
 	if err != nil {
 		return err
 	}
-	if err := credentials.Validate(creds, installation.Bundle.Credentials); err != nil {
+	out := redact.NewWriter(os.Stdout, secrets...)
+	defer out.Flush() //nolint:errcheck // nothing much we can do with an error to write to output.
+	driverImpl, errBuf, err := prepareDriver(dockerCli, bind, out)
+	if err != nil {
 		return err
 	}
-	u := &action.Upgrade{
-		Driver: driverImpl,
+	outputs, err := prepareOutputs(driverImpl)
+	if err != nil {
+		return err
 	}
-	err = u.Run(&installation.Claim, creds, os.Stdout)
-	err2 := installationStore.Store(installation)
+	defer outputs.remove()
+	r, err := prepareRunner(dockerCli, installationStore, driverImpl, outputs)
 	if err != nil {
-		return fmt.Errorf("Upgrade failed: %s", errBuf)
+		return err
 	}
-	if err2 != nil {
-		return err2
+	if admissionHook != nil {
+		r.Hooks = append(r.Hooks, *admissionHook)
+	}
+	// A plain upgrade is no longer a rollback
+	installation.Rollback = nil
+	ctx, cancel := opts.timeoutOptions.context()
+	defer cancel()
+	start := time.Now()
+	err = r.Run(ctx, installation, claim.ActionUpgrade, creds, out)
+	auditAction(dockerCli, claim.ActionUpgrade, opts.targetContext, installation)
+	notifyAction(dockerCli, claim.ActionUpgrade, opts.targetContext, installation, start)
+	if err != nil {
+		return fmt.Errorf("Upgrade failed: %s\n%s", redact.String(errBuf.String(), secrets...), redact.Error(err, secrets...))
 	}
 	fmt.Fprintf(os.Stdout, "Application %q upgraded on context %q\n", installationName, opts.targetContext)
 	return nil
 }
+
+// checkBreakingChanges fails if the upgrade has breaking changes, unless
+// forced, in which case they are displayed as warnings.
+func checkBreakingChanges(installed, target *bundle.Bundle, report *cnab.UpgradeReport, force bool) error {
+	if !report.Breaking() {
+		return nil
+	}
+	if force {
+		for _, change := range report.BreakingChanges {
+			fmt.Fprintf(os.Stderr, "WARNING: breaking change: %s\n", change.Message)
+		}
+		return nil
+	}
+	messages := make([]string, len(report.BreakingChanges))
+	for i, change := range report.BreakingChanges {
+		messages[i] = "  - " + change.Message
+	}
+	return fmt.Errorf("Upgrading %s from %s to %s has breaking changes:\n%s\nUse --force to upgrade anyway",
+		installed.Name, installed.Version, target.Version, strings.Join(messages, "\n"))
+}
+
+// printUpgradeNotes displays the changelog of the versions between the
+// installed bundle and the target one.
+func printUpgradeNotes(installed, target *bundle.Bundle) {
+	notes, err := cnab.UpgradeNotes(installed.Version, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		return
+	}
+	if len(notes) == 0 {
+		return
+	}
+	printHeader(os.Stdout, "UPGRADE NOTES")
+	cnab.RenderUpgradeNotes(os.Stdout, notes) //nolint:errcheck // nothing much we can do with an error to write to output.
+}
+
+// printBundleChanges displays the changes of parameters, credentials, images
+// and actions between the installed bundle and the target one.
+func printBundleChanges(installed, target *bundle.Bundle) {
+	changes, err := cnab.Diff(installed, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		return
+	}
+	if changes.Empty() {
+		return
+	}
+	printHeader(os.Stdout, "CHANGES")
+	cnab.RenderChangeSet(os.Stdout, changes) //nolint:errcheck // nothing much we can do with an error to write to output.
+	fmt.Fprintln(os.Stdout)
+}
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestCheckBreakingChanges(t *testing.T) {
+	installed := bundletest.NewTestBundle(bundletest.WithName("myapp"), bundletest.WithVersion("1.0.0"), bundletest.WithCredentials(1))
+	target := bundletest.NewTestBundle(bundletest.WithName("myapp"), bundletest.WithVersion("2.0.0"))
+	report, err := cnab.CheckUpgrade(installed, target, nil)
+	assert.NilError(t, err)
+
+	err = checkBreakingChanges(installed, target, report, false)
+	assert.Check(t, is.Error(err, `Upgrading myapp from 1.0.0 to 2.0.0 has breaking changes:
+  - credential "cred-0" supplied by the installation is removed
+  - major version upgrade from 1.0.0 to 2.0.0
+Use --force to upgrade anyway`))
+	assert.Check(t, checkBreakingChanges(installed, target, report, true))
+	assert.Check(t, checkBreakingChanges(installed, installed, &cnab.UpgradeReport{}, false))
+}
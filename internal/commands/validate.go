@@ -2,18 +2,22 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
 
+	"github.com/docker/app/internal/cnab/lint"
 	"github.com/docker/app/internal/packager"
 	"github.com/docker/app/render"
 	"github.com/docker/app/types"
 	"github.com/docker/cli/cli"
 	cliopts "github.com/docker/cli/opts"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 type validateOptions struct {
 	parametersOptions
+	report string
 }
 
 func validateCmd() *cobra.Command {
@@ -35,10 +39,45 @@ func validateCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if opts.report != "" {
+				return lintApp(os.Stdout, app, opts.report)
+			}
 			fmt.Fprintf(os.Stdout, "Validated %q\n", app.Path)
 			return nil
 		},
 	}
 	opts.parametersOptions.addFlags(cmd.Flags())
+	cmd.Flags().StringVar(&opts.report, "report", "", "Print a lint report of the application bundle (text|json|sarif)")
 	return cmd
 }
+
+// lintApp writes the lint report of the bundle made from the application,
+// and fails if it holds any error.
+func lintApp(w io.Writer, app *types.App, format string) error {
+	invocationImageName, err := makeInvocationImageName(app.Metadata(), nil)
+	if err != nil {
+		return err
+	}
+	bndl, err := packager.ToCNAB(app, invocationImageName)
+	if err != nil {
+		return err
+	}
+	report := lint.Lint(bndl, app.Path)
+	switch format {
+	case "text":
+		err = report.WriteText(w)
+	case "json":
+		err = report.WriteJSON(w)
+	case "sarif":
+		err = report.WriteSARIF(w)
+	default:
+		return errors.Errorf("unknown report format %q, expected text, json or sarif", format)
+	}
+	if err != nil {
+		return err
+	}
+	if report.HasErrors() {
+		return errors.Errorf("%q has lint errors", app.Path)
+	}
+	return nil
+}
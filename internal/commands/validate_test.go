@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/app/internal/packager"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestLintApp(t *testing.T) {
+	app, err := packager.Extract("../../examples/hello-world/example-hello-world.dockerapp")
+	assert.NilError(t, err)
+	defer app.Cleanup()
+
+	buf := bytes.NewBuffer(nil)
+	assert.NilError(t, lintApp(buf, app, "sarif"))
+	assert.Check(t, is.Contains(buf.String(), `"version": "2.1.0"`))
+	assert.Check(t, is.Contains(buf.String(), `"ruleId": "CNAB008"`))
+
+	err = lintApp(buf, app, "xml")
+	assert.Check(t, is.Error(err, `unknown report format "xml", expected text, json or sarif`))
+}
@@ -0,0 +1,98 @@
+package drivers
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/pkg/errors"
+)
+
+// DefaultGracePeriod is how long a cancelled invocation image is given to
+// stop after being asked to, before being killed.
+const DefaultGracePeriod = 10 * time.Second
+
+// interruptedPrefix starts the messages of the interrupted operations, as
+// recorded in their claim.
+const interruptedPrefix = "operation interrupted: "
+
+// InterruptedError is returned when an operation was stopped because its
+// context was done, on timeout or cancellation.
+type InterruptedError struct {
+	Err error
+}
+
+func (e *InterruptedError) Error() string {
+	return interruptedPrefix + e.Err.Error()
+}
+
+// IsInterrupted returns true if the error is an InterruptedError.
+func IsInterrupted(err error) bool {
+	_, ok := errors.Cause(err).(*InterruptedError)
+	return ok
+}
+
+// IsClaimInterrupted returns true if the last action of the claim was
+// interrupted. The actions record the error of the driver as the message of
+// failed claims, so the interruption is kept in the claim.
+func IsClaimInterrupted(c *claim.Claim) bool {
+	return c.Result.Status == claim.StatusFailure && strings.HasPrefix(c.Result.Message, interruptedPrefix)
+}
+
+// WithContext returns a driver running the operations of d until the context
+// is done, see Run, for the actions which do not take a context. An
+// operation stopped this way fails with an InterruptedError.
+func WithContext(ctx context.Context, d driver.Driver) driver.Driver {
+	return &contextDriver{ctx: ctx, driver: d}
+}
+
+type contextDriver struct {
+	ctx    context.Context
+	driver driver.Driver
+}
+
+func (d *contextDriver) Run(op *driver.Operation) error {
+	err := Run(d.ctx, d.driver, op)
+	if err != nil && d.ctx.Err() != nil {
+		return &InterruptedError{Err: d.ctx.Err()}
+	}
+	return err
+}
+
+func (d *contextDriver) Handles(imageType string) bool {
+	return d.driver.Handles(imageType)
+}
+
+// RunCommand runs the command until it exits or the context is done. Once
+// the context is done, the command is asked to terminate, then killed if it
+// is still running after the grace period. The error of the context is
+// returned in that case.
+func RunCommand(ctx context.Context, cmd *exec.Cmd, gracePeriod time.Duration) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	if err := terminate(cmd.Process); err != nil {
+		cmd.Process.Kill() //nolint:errcheck // it may have exited in the meantime
+	}
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		cmd.Process.Kill() //nolint:errcheck // as above
+		<-done
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,67 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/drivers/fake"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestWithContextInterrupted(t *testing.T) {
+	d := &blockingDriver{release: make(chan struct{})}
+	defer close(d.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := WithContext(ctx, d).Run(&driver.Operation{Action: "install"})
+	assert.Check(t, IsInterrupted(err))
+	assert.Check(t, is.Error(err, "operation interrupted: context deadline exceeded"))
+
+	// The actions record the error in the claim
+	c := &claim.Claim{}
+	c.Update(claim.ActionInstall, claim.StatusFailure)
+	c.Result.Message = err.Error()
+	assert.Check(t, IsClaimInterrupted(c))
+}
+
+func TestWithContextFailure(t *testing.T) {
+	d := fake.New().Default(fake.Result{Err: errors.New("boom")})
+	err := WithContext(context.Background(), d).Run(&driver.Operation{Action: "install", ImageType: "docker"})
+	assert.Check(t, is.Error(err, "boom"))
+	assert.Check(t, !IsInterrupted(err))
+
+	c := &claim.Claim{}
+	c.Update(claim.ActionInstall, claim.StatusFailure)
+	c.Result.Message = err.Error()
+	assert.Check(t, !IsClaimInterrupted(c))
+}
+
+func TestRunCommandTerminated(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := RunCommand(ctx, exec.Command("sleep", "10"), time.Minute)
+	assert.Check(t, is.Equal(err, context.DeadlineExceeded))
+	assert.Check(t, time.Since(start) < 5*time.Second)
+}
+
+func TestRunCommandKilledAfterGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// The shell ignores SIGTERM
+	err := RunCommand(ctx, exec.Command("sh", "-c", `trap "" TERM; sleep 10 & wait`), 50*time.Millisecond)
+	assert.Check(t, is.Equal(err, context.DeadlineExceeded))
+	assert.Check(t, time.Since(start) < 5*time.Second)
+}
+
+func TestRunCommandCompleted(t *testing.T) {
+	assert.NilError(t, RunCommand(context.Background(), exec.Command("true"), time.Minute))
+	assert.Check(t, RunCommand(context.Background(), exec.Command("false"), time.Minute) != nil)
+}
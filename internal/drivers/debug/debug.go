@@ -0,0 +1,116 @@
+// Package debug provides a driver which prints the fully resolved operations
+// instead of running the invocation image, to check how parameters and
+// credentials are wired before running an action for real.
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/redact"
+)
+
+// Operation is the printable description of a driver operation.
+type Operation struct {
+	Installation string                 `json:"installation"`
+	Revision     string                 `json:"revision"`
+	Action       string                 `json:"action"`
+	Image        string                 `json:"image"`
+	ImageType    string                 `json:"imageType"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	Environment  map[string]string      `json:"environment,omitempty"`
+	Files        map[string]string      `json:"files,omitempty"`
+}
+
+// Driver prints the operations as JSON and records them. It never runs any
+// invocation image and always succeeds.
+type Driver struct {
+	mu         sync.Mutex
+	out        io.Writer
+	secrets    []string
+	operations []Operation
+}
+
+var _ driver.Driver = &Driver{}
+
+// New returns a driver printing the operations to out, or to the operation
+// output stream if out is nil. The given secrets are masked in the printed
+// operations.
+func New(out io.Writer, secrets ...string) *Driver {
+	return &Driver{out: out, secrets: secrets}
+}
+
+// Run prints the operation.
+func (d *Driver) Run(op *driver.Operation) error {
+	operation := Operation{
+		Installation: op.Installation,
+		Revision:     op.Revision,
+		Action:       op.Action,
+		Image:        op.Image,
+		ImageType:    op.ImageType,
+		Parameters:   op.Parameters,
+		Environment:  op.Environment,
+		Files:        op.Files,
+	}
+	d.mu.Lock()
+	d.operations = append(d.operations, operation)
+	d.mu.Unlock()
+	out := d.out
+	if out == nil {
+		out = op.Out
+	}
+	if out == nil {
+		return nil
+	}
+	// The secrets are masked before encoding, as the escaping of JSON strings
+	// would hide them from the replacement
+	data, err := json.MarshalIndent(d.redacted(operation), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// redacted returns a copy of the operation with the secrets masked.
+func (d *Driver) redacted(operation Operation) Operation {
+	if len(operation.Parameters) > 0 {
+		parameters := make(map[string]interface{}, len(operation.Parameters))
+		for name, value := range operation.Parameters {
+			if s, ok := value.(string); ok {
+				value = redact.String(s, d.secrets...)
+			}
+			parameters[name] = value
+		}
+		operation.Parameters = parameters
+	}
+	operation.Environment = d.redactedValues(operation.Environment)
+	operation.Files = d.redactedValues(operation.Files)
+	return operation
+}
+
+func (d *Driver) redactedValues(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return values
+	}
+	redacted := make(map[string]string, len(values))
+	for key, value := range values {
+		redacted[key] = redact.String(value, d.secrets...)
+	}
+	return redacted
+}
+
+// Handles returns true for all image types, as no image is ever run.
+func (d *Driver) Handles(string) bool {
+	return true
+}
+
+// Operations returns the operations printed so far, oldest first.
+func (d *Driver) Operations() []Operation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Operation(nil), d.operations...)
+}
@@ -0,0 +1,68 @@
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/redact"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestRunPrintsOperation(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	d := New(out, "s3cr3t")
+	err := d.Run(&driver.Operation{
+		Installation: "myapp",
+		Revision:     "01DB",
+		Action:       "install",
+		Image:        "myapp-invoc:1.0.0",
+		ImageType:    driver.ImageTypeDocker,
+		Parameters:   map[string]interface{}{"port": 8080},
+		Environment:  map[string]string{"PORT": "8080", "TOKEN": "s3cr3t"},
+		Files:        map[string]string{"/cnab/app/credentials/token": "s3cr3t"},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, !bytes.Contains(out.Bytes(), []byte("s3cr3t")))
+
+	var printed Operation
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &printed))
+	assert.Check(t, is.Equal(printed.Image, "myapp-invoc:1.0.0"))
+	assert.Check(t, is.Equal(printed.Environment["PORT"], "8080"))
+	assert.Check(t, is.Equal(printed.Environment["TOKEN"], redact.Mask))
+	assert.Check(t, is.Equal(printed.Files["/cnab/app/credentials/token"], redact.Mask))
+
+	// Recorded operations are not redacted
+	operations := d.Operations()
+	assert.Assert(t, is.Len(operations, 1))
+	assert.Check(t, is.Equal(operations[0].Environment["TOKEN"], "s3cr3t"))
+}
+
+func TestRunRedactsEscapedSecrets(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	kubeconfig := "apiVersion: v1\nusers:\n- name: <admin>\n  token: \"a&b\"\n"
+	err := New(out, kubeconfig).Run(&driver.Operation{
+		Action:      "install",
+		Parameters:  map[string]interface{}{"kubeconfig": kubeconfig},
+		Environment: map[string]string{"KUBECONFIG": kubeconfig},
+		Files:       map[string]string{"/root/.kube/config": kubeconfig},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, !bytes.Contains(out.Bytes(), []byte("admin")), out.String())
+	assert.Check(t, !bytes.Contains(out.Bytes(), []byte("token")), out.String())
+
+	var printed Operation
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &printed))
+	assert.Check(t, is.Equal(printed.Parameters["kubeconfig"], redact.Mask))
+	assert.Check(t, is.Equal(printed.Environment["KUBECONFIG"], redact.Mask))
+	assert.Check(t, is.Equal(printed.Files["/root/.kube/config"], redact.Mask))
+}
+
+func TestRunDefaultsToOperationOutput(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	assert.NilError(t, New(nil).Run(&driver.Operation{Action: "status", Out: out}))
+	assert.Check(t, is.Contains(out.String(), `"action": "status"`))
+	assert.Check(t, New(nil).Handles("qcow"))
+}
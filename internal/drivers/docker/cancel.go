@@ -0,0 +1,160 @@
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	duffleDriver "github.com/deislabs/duffle/pkg/driver"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+)
+
+// LabelOperation labels the invocation image containers with the id of
+// their operation, so they can be found and stopped.
+const LabelOperation = "com.docker.app.operation"
+
+// stopRetryInterval is how often a cancelled operation looks for its
+// container, which may not be created yet.
+var stopRetryInterval = time.Second
+
+// CancellableDriver is a Docker driver whose operations can be cancelled.
+// Once the context of an operation is done, its container is stopped: it is
+// sent SIGTERM, then killed after the grace period. Operations are run one
+// at a time.
+type CancellableDriver struct {
+	*duffleDriver.DockerDriver
+	client      apiClient
+	gracePeriod time.Duration
+	outputsDir  string
+	// run runs the operation, it is replaced in tests
+	run func(*driver.Operation) error
+
+	runMu     sync.Mutex
+	mu        sync.Mutex
+	operation string
+}
+
+// apiClient is the part of the Docker API the driver uses.
+type apiClient interface {
+	client.ContainerAPIClient
+	client.VolumeAPIClient
+}
+
+// NewCancellableDriver wraps a Docker driver, stopping the containers of
+// cancelled operations with the client.
+func NewCancellableDriver(d *duffleDriver.DockerDriver, c apiClient, gracePeriod time.Duration) *CancellableDriver {
+	cd := &CancellableDriver{
+		DockerDriver: d,
+		client:       c,
+		gracePeriod:  gracePeriod,
+		run:          d.Run,
+	}
+	d.AddConfigurationOptions(cd.labelContainer, cd.mountOutputs)
+	return cd
+}
+
+// labelContainer labels the container with the id of the running operation.
+func (d *CancellableDriver) labelContainer(config *container.Config, _ *container.HostConfig) error {
+	if config.Labels == nil {
+		config.Labels = map[string]string{}
+	}
+	config.Labels[LabelOperation] = d.currentOperation()
+	return nil
+}
+
+// Run executes the operation.
+func (d *CancellableDriver) Run(op *driver.Operation) error {
+	return d.RunContext(context.Background(), op)
+}
+
+// RunContext executes the operation, stopping its container when the
+// context is done. The error of the context is returned in that case.
+func (d *CancellableDriver) RunContext(ctx context.Context, op *driver.Operation) error {
+	d.runMu.Lock()
+	defer d.runMu.Unlock()
+	id, err := newOperationID()
+	if err != nil {
+		return err
+	}
+	d.setOperation(id)
+	if d.outputsDir != "" {
+		remove, err := d.createOutputsVolume(id)
+		if err != nil {
+			return err
+		}
+		defer remove()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.run(op)
+	}()
+	select {
+	case err := <-done:
+		if err == nil && d.outputsDir != "" {
+			err = d.copyOutputs(id, op.Image)
+		}
+		return err
+	case <-ctx.Done():
+	}
+	// The container may not be created yet, so it is looked for until the
+	// run returns.
+	ticker := time.NewTicker(stopRetryInterval)
+	defer ticker.Stop()
+	for {
+		if err := d.stop(id); err != nil {
+			return errors.Wrapf(err, "failed to stop the invocation image of the %s operation", op.Action)
+		}
+		select {
+		case <-done:
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop stops the containers of the operation.
+func (d *CancellableDriver) stop(id string) error {
+	ctx := context.Background()
+	containers, err := d.client.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", LabelOperation+"="+id)),
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		gracePeriod := d.gracePeriod
+		if err := d.client.ContainerStop(ctx, c.ID, &gracePeriod); err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *CancellableDriver) setOperation(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.operation = id
+}
+
+func (d *CancellableDriver) currentOperation() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.operation
+}
+
+func newOperationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	duffleDriver "github.com/deislabs/duffle/pkg/driver"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// fakeContainers simulates the containers of the operations, stopped
+// containers being removed.
+type fakeContainers struct {
+	client.ContainerAPIClient
+	client.VolumeAPIClient
+	mu      sync.Mutex
+	labels  map[string]map[string]string
+	stopped chan string
+	timeout time.Duration
+}
+
+func (f *fakeContainers) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var containers []types.Container
+	for id, labels := range f.labels {
+		for _, label := range options.Filters.Get("label") {
+			if LabelOperation+"="+labels[LabelOperation] == label {
+				containers = append(containers, types.Container{ID: id})
+			}
+		}
+	}
+	return containers, nil
+}
+
+func (f *fakeContainers) ContainerStop(ctx context.Context, id string, timeout *time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.labels, id)
+	f.timeout = *timeout
+	f.stopped <- id
+	return nil
+}
+
+// create creates a container labelled by the driver.
+func (f *fakeContainers) create(d *CancellableDriver, id string) error {
+	config := &container.Config{}
+	if err := d.labelContainer(config, &container.HostConfig{}); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.labels[id] = config.Labels
+	return nil
+}
+
+func TestCancellableDriverRun(t *testing.T) {
+	containers := &fakeContainers{labels: map[string]map[string]string{}}
+	d := NewCancellableDriver(&duffleDriver.DockerDriver{}, containers, time.Second)
+	d.run = func(op *driver.Operation) error {
+		return containers.create(d, "abc")
+	}
+	assert.NilError(t, d.Run(&driver.Operation{Action: "install"}))
+	assert.Check(t, containers.labels["abc"][LabelOperation] != "")
+}
+
+func TestCancellableDriverStopsContainer(t *testing.T) {
+	defer func(interval time.Duration) { stopRetryInterval = interval }(stopRetryInterval)
+	stopRetryInterval = time.Millisecond
+	containers := &fakeContainers{labels: map[string]map[string]string{}, stopped: make(chan string, 1)}
+	d := NewCancellableDriver(&duffleDriver.DockerDriver{}, containers, 5*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	d.run = func(op *driver.Operation) error {
+		// The container is created once the operation is cancelled
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		if err := containers.create(d, "abc"); err != nil {
+			return err
+		}
+		<-containers.stopped
+		return errors.New("container exit code: 143")
+	}
+	cancel()
+	err := d.RunContext(ctx, &driver.Operation{Action: "install"})
+	assert.Check(t, is.Equal(err, context.Canceled))
+	assert.Check(t, is.Equal(containers.timeout, 5*time.Second))
+	assert.Check(t, is.Len(containers.labels, 0))
+}
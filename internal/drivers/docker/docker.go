@@ -0,0 +1,187 @@
+// Package docker configures the container the Docker driver runs invocation
+// images in, through a typed configuration instead of the raw environment
+// strings of the driver.
+package docker
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	duffleDriver "github.com/deislabs/duffle/pkg/driver"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	units "github.com/docker/go-units"
+	"github.com/google/shlex"
+	"github.com/pkg/errors"
+)
+
+// DockerConfigPath is where the host Docker configuration directory is
+// mounted in the invocation image, DOCKER_CONFIG pointing to it.
+const DockerConfigPath = "/cnab/app/.docker"
+
+// Mount is a host path bind mounted in the invocation image.
+type Mount struct {
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// Configuration is the configuration of the invocation image containers.
+type Configuration struct {
+	// Network is a user defined network the container is attached to. Only
+	// one network can be set, the driver creating the container without
+	// networking configuration.
+	Network string
+	Mounts  []Mount
+	// CPUs limits the number of CPUs, like 1.5.
+	CPUs float64
+	// Memory limits the memory in bytes.
+	Memory int64
+	// Args are passed to the entrypoint of the invocation image.
+	Args []string
+	// DockerConfig is a host Docker configuration directory mounted read
+	// only in the container, so the invocation image can pull from private
+	// registries. Credential helpers of the host cannot be used this way,
+	// only the credentials stored in the configuration file.
+	DockerConfig string
+}
+
+// Validate checks the configuration is usable.
+func (c Configuration) Validate() error {
+	for _, m := range c.Mounts {
+		if !filepath.IsAbs(m.Source) {
+			return errors.Errorf("invalid mount %q: source must be an absolute path", m.Source)
+		}
+		if !path.IsAbs(m.Target) {
+			return errors.Errorf("invalid mount %q: target must be an absolute unix path", m.Target)
+		}
+	}
+	if c.CPUs < 0 {
+		return errors.Errorf("invalid CPU limit %v: must be positive", c.CPUs)
+	}
+	if c.Memory < 0 {
+		return errors.Errorf("invalid memory limit %d: must be positive", c.Memory)
+	}
+	if c.DockerConfig != "" && !filepath.IsAbs(c.DockerConfig) {
+		return errors.Errorf("invalid Docker configuration directory %q: must be an absolute path", c.DockerConfig)
+	}
+	return nil
+}
+
+// Option returns the configuration as an option of the Docker driver. The
+// mounts are added to the ones already set.
+func (c Configuration) Option() duffleDriver.DockerConfigurationOption {
+	return func(config *container.Config, hostConfig *container.HostConfig) error {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if c.Network != "" {
+			hostConfig.NetworkMode = container.NetworkMode(c.Network)
+		}
+		for _, m := range c.Mounts {
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   m.Source,
+				Target:   m.Target,
+				ReadOnly: m.ReadOnly,
+			})
+		}
+		if c.CPUs > 0 {
+			hostConfig.NanoCPUs = int64(c.CPUs * 1e9)
+		}
+		if c.Memory > 0 {
+			hostConfig.Memory = c.Memory
+		}
+		if len(c.Args) > 0 {
+			config.Cmd = append([]string(nil), c.Args...)
+		}
+		if c.DockerConfig != "" {
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   c.DockerConfig,
+				Target:   DockerConfigPath,
+				ReadOnly: true,
+			})
+			config.Env = append(config.Env, "DOCKER_CONFIG="+DockerConfigPath)
+		}
+		return nil
+	}
+}
+
+// Configuration keys of ParseConfiguration.
+const (
+	KeyNetwork      = "docker-driver-network"
+	KeyMounts       = "docker-driver-mounts"
+	KeyCPUs         = "docker-driver-cpus"
+	KeyMemory       = "docker-driver-memory"
+	KeyArgs         = "docker-driver-args"
+	KeyDockerConfig = "docker-driver-docker-config"
+)
+
+// ParseConfiguration reads a configuration from string settings, like the
+// plugin configuration of the docker CLI:
+// - "docker-driver-network" is the network name
+// - "docker-driver-mounts" is a comma separated list of SOURCE:TARGET[:ro]
+// - "docker-driver-cpus" is a number of CPUs, like 1.5
+// - "docker-driver-memory" is a size, like 512m
+// - "docker-driver-args" are shell quoted entrypoint arguments
+// - "docker-driver-docker-config" is a Docker configuration directory
+func ParseConfiguration(get func(key string) (string, bool)) (Configuration, error) {
+	var c Configuration
+	c.Network, _ = get(KeyNetwork)
+	c.DockerConfig, _ = get(KeyDockerConfig)
+	if value, ok := get(KeyMounts); ok && value != "" {
+		for _, spec := range strings.Split(value, ",") {
+			m, err := ParseMount(strings.TrimSpace(spec))
+			if err != nil {
+				return c, err
+			}
+			c.Mounts = append(c.Mounts, m)
+		}
+	}
+	if value, ok := get(KeyCPUs); ok && value != "" {
+		cpus, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return c, errors.Wrapf(err, "invalid %s", KeyCPUs)
+		}
+		c.CPUs = cpus
+	}
+	if value, ok := get(KeyMemory); ok && value != "" {
+		memory, err := units.RAMInBytes(value)
+		if err != nil {
+			return c, errors.Wrapf(err, "invalid %s", KeyMemory)
+		}
+		c.Memory = memory
+	}
+	if value, ok := get(KeyArgs); ok && value != "" {
+		args, err := shlex.Split(value)
+		if err != nil {
+			return c, errors.Wrapf(err, "invalid %s", KeyArgs)
+		}
+		c.Args = args
+	}
+	return c, c.Validate()
+}
+
+// ParseMount parses a SOURCE:TARGET[:ro|rw] mount specification. The source
+// may contain colons, like Windows drive letters.
+func ParseMount(spec string) (Mount, error) {
+	var m Mount
+	rest := spec
+	switch {
+	case strings.HasSuffix(rest, ":ro"):
+		m.ReadOnly = true
+		rest = strings.TrimSuffix(rest, ":ro")
+	case strings.HasSuffix(rest, ":rw"):
+		rest = strings.TrimSuffix(rest, ":rw")
+	}
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 || i == len(rest)-1 {
+		return m, fmt.Errorf("invalid mount %q: expected SOURCE:TARGET[:ro]", spec)
+	}
+	m.Source, m.Target = rest[:i], rest[i+1:]
+	return m, nil
+}
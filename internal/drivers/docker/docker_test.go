@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParseConfiguration(t *testing.T) {
+	settings := map[string]string{
+		KeyNetwork:      "my-network",
+		KeyMounts:       "/data:/var/data:ro, /cache:/cache",
+		KeyCPUs:         "1.5",
+		KeyMemory:       "512m",
+		KeyArgs:         `--verbose --name "my app"`,
+		KeyDockerConfig: "/home/user/.docker",
+	}
+	c, err := ParseConfiguration(func(key string) (string, bool) {
+		value, ok := settings[key]
+		return value, ok
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, Configuration{
+		Network: "my-network",
+		Mounts: []Mount{
+			{Source: "/data", Target: "/var/data", ReadOnly: true},
+			{Source: "/cache", Target: "/cache"},
+		},
+		CPUs:         1.5,
+		Memory:       512 * 1024 * 1024,
+		Args:         []string{"--verbose", "--name", "my app"},
+		DockerConfig: "/home/user/.docker",
+	})
+}
+
+func TestParseConfigurationErrors(t *testing.T) {
+	for _, tc := range []struct {
+		key, value, err string
+	}{
+		{KeyMounts, "/data", "expected SOURCE:TARGET[:ro]"},
+		{KeyMounts, "data:/data", "source must be an absolute path"},
+		{KeyMounts, "/data:data", "target must be an absolute unix path"},
+		{KeyCPUs, "many", "invalid docker-driver-cpus"},
+		{KeyCPUs, "-1", "must be positive"},
+		{KeyMemory, "lots", "invalid docker-driver-memory"},
+		{KeyArgs, `"unterminated`, "invalid docker-driver-args"},
+		{KeyDockerConfig, ".docker", "must be an absolute path"},
+	} {
+		_, err := ParseConfiguration(func(key string) (string, bool) {
+			if key == tc.key {
+				return tc.value, true
+			}
+			return "", false
+		})
+		assert.Check(t, is.ErrorContains(err, tc.err), "%s=%s", tc.key, tc.value)
+	}
+}
+
+func TestParseMountWindowsSource(t *testing.T) {
+	m, err := ParseMount(`C:\data:/data:ro`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, m, Mount{Source: `C:\data`, Target: "/data", ReadOnly: true})
+}
+
+func TestOption(t *testing.T) {
+	c := Configuration{
+		Network:      "my-network",
+		Mounts:       []Mount{{Source: "/data", Target: "/var/data"}},
+		CPUs:         0.5,
+		Memory:       1024,
+		Args:         []string{"--verbose"},
+		DockerConfig: "/home/user/.docker",
+	}
+	config := &container.Config{Env: []string{"CNAB_ACTION=install"}}
+	existing := mount.Mount{Type: mount.TypeBind, Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"}
+	hostConfig := &container.HostConfig{Mounts: []mount.Mount{existing}}
+	assert.NilError(t, c.Option()(config, hostConfig))
+
+	assert.Check(t, is.Equal(hostConfig.NetworkMode, container.NetworkMode("my-network")))
+	assert.Check(t, is.Equal(hostConfig.NanoCPUs, int64(500000000)))
+	assert.Check(t, is.Equal(hostConfig.Memory, int64(1024)))
+	assert.Check(t, is.DeepEqual([]string(config.Cmd), []string{"--verbose"}))
+	assert.Check(t, is.DeepEqual(config.Env, []string{"CNAB_ACTION=install", "DOCKER_CONFIG=" + DockerConfigPath}))
+	assert.Check(t, is.DeepEqual(hostConfig.Mounts, []mount.Mount{
+		existing,
+		{Type: mount.TypeBind, Source: "/data", Target: "/var/data"},
+		{Type: mount.TypeBind, Source: "/home/user/.docker", Target: DockerConfigPath, ReadOnly: true},
+	}))
+}
+
+func TestOptionEmptyConfiguration(t *testing.T) {
+	config := &container.Config{}
+	hostConfig := &container.HostConfig{}
+	assert.NilError(t, Configuration{}.Option()(config, hostConfig))
+	assert.DeepEqual(t, config, &container.Config{})
+	assert.DeepEqual(t, hostConfig, &container.HostConfig{})
+}
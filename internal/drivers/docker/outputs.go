@@ -0,0 +1,135 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/strslice"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/pkg/errors"
+)
+
+var _ drivers.OutputsDriver = &CancellableDriver{}
+
+// SetOutputsDir sets the local directory the outputs directory of the
+// invocation images is copied to once the operations succeed, so the
+// outputs can be collected with drivers.DirOutputOpener. As the Docker
+// driver removes the containers, their outputs directory is a volume created
+// for each operation, read through a container created, but never started,
+// from the invocation image. Files larger than drivers.DefaultMaxOutputSize
+// are truncated, so they fail the collection.
+func (d *CancellableDriver) SetOutputsDir(dir string) {
+	d.outputsDir = dir
+}
+
+// mountOutputs mounts the outputs volume of the running operation.
+func (d *CancellableDriver) mountOutputs(_ *container.Config, hostConfig *container.HostConfig) error {
+	if d.outputsDir != "" {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:   mount.TypeVolume,
+			Source: outputsVolume(d.currentOperation()),
+			Target: cnab.OutputsDirectory,
+		})
+	}
+	return nil
+}
+
+func outputsVolume(operation string) string {
+	return "docker-app-outputs-" + operation
+}
+
+// createOutputsVolume creates the outputs volume of the operation, returning
+// the function removing it.
+func (d *CancellableDriver) createOutputsVolume(operation string) (func(), error) {
+	ctx := context.Background()
+	volume, err := d.client.VolumeCreate(ctx, volumetypes.VolumeCreateBody{
+		Name:   outputsVolume(operation),
+		Labels: map[string]string{LabelOperation: operation},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the outputs volume")
+	}
+	return func() {
+		d.client.VolumeRemove(ctx, volume.Name, true) //nolint:errcheck // the volume is only left behind
+	}, nil
+}
+
+// copyOutputs copies the content of the outputs volume of the operation to
+// the outputs directory.
+func (d *CancellableDriver) copyOutputs(operation, image string) error {
+	ctx := context.Background()
+	created, err := d.client.ContainerCreate(ctx,
+		&container.Config{Image: image, Entrypoint: strslice.StrSlice{"/cnab/app/run"}},
+		&container.HostConfig{Mounts: []mount.Mount{{
+			Type:     mount.TypeVolume,
+			Source:   outputsVolume(operation),
+			Target:   cnab.OutputsDirectory,
+			ReadOnly: true,
+		}}}, nil, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to read the outputs")
+	}
+	defer d.client.ContainerRemove(ctx, created.ID, types.ContainerRemoveOptions{Force: true}) //nolint:errcheck // the container is only left behind
+	content, _, err := d.client.CopyFromContainer(ctx, created.ID, cnab.OutputsDirectory)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the outputs")
+	}
+	defer content.Close()
+	return errors.Wrap(extractOutputs(content, d.outputsDir), "failed to read the outputs")
+}
+
+// extractOutputs extracts the files of an archive of the outputs directory,
+// whose entries are prefixed by its base name, to the local directory.
+func extractOutputs(r io.Reader, dir string) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		split := strings.SplitN(name, "/", 2)
+		if len(split) != 2 || strings.HasPrefix(split[1], "../") || split[1] == ".." {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(split[1]))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := writeOutput(target, archive); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeOutput(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, r, drivers.DefaultMaxOutputSize+1); err != nil && err != io.EOF {
+		f.Close() //nolint:errcheck // the copy error is more relevant
+		return err
+	}
+	return f.Close()
+}
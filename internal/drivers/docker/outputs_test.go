@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	duffleDriver "github.com/deislabs/duffle/pkg/driver"
+	"github.com/docker/app/internal/drivers"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+// fakeOutputs simulates the volumes holding the outputs, and the containers
+// reading them.
+type fakeOutputs struct {
+	fakeContainers
+	volumes map[string][]byte
+	created map[string]string
+	removed []string
+}
+
+func (f *fakeOutputs) VolumeCreate(ctx context.Context, options volumetypes.VolumeCreateBody) (types.Volume, error) {
+	f.volumes[options.Name] = nil
+	return types.Volume{Name: options.Name}, nil
+}
+
+func (f *fakeOutputs) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	delete(f.volumes, volumeID)
+	return nil
+}
+
+func (f *fakeOutputs) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error) {
+	f.created["reader"] = hostConfig.Mounts[0].Source
+	return container.ContainerCreateCreatedBody{ID: "reader"}, nil
+}
+
+func (f *fakeOutputs) ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) error {
+	f.removed = append(f.removed, id)
+	return nil
+}
+
+func (f *fakeOutputs) CopyFromContainer(ctx context.Context, id, srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.volumes[f.created[id]])), types.ContainerPathStat{}, nil
+}
+
+func outputsArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	assert.NilError(t, w.WriteHeader(&tar.Header{Name: "outputs/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range files {
+		assert.NilError(t, w.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err := io.WriteString(w, content)
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCancellableDriverCopiesOutputs(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	client := &fakeOutputs{
+		fakeContainers: fakeContainers{labels: map[string]map[string]string{}},
+		volumes:        map[string][]byte{},
+		created:        map[string]string{},
+	}
+	d := NewCancellableDriver(&duffleDriver.DockerDriver{}, client, time.Second)
+	d.SetOutputsDir(dir.Path())
+	d.run = func(op *driver.Operation) error {
+		// The invocation image writes its outputs in the mounted volume
+		hostConfig := &container.HostConfig{}
+		assert.NilError(t, d.mountOutputs(&container.Config{}, hostConfig))
+		assert.Assert(t, is.Len(hostConfig.Mounts, 1))
+		assert.Check(t, is.Equal(hostConfig.Mounts[0].Target, "/cnab/app/outputs"))
+		volume := hostConfig.Mounts[0].Source
+		assert.Check(t, is.Contains(client.volumes, volume))
+		client.volumes[volume] = outputsArchive(t, map[string]string{
+			"outputs/endpoint":      "http://localhost",
+			"outputs/reports/state": "ok",
+			"outputs/../escape":     "no",
+			"outputs/large":         strings.Repeat("a", drivers.DefaultMaxOutputSize+10),
+		})
+		return nil
+	}
+	assert.NilError(t, d.Run(&driver.Operation{Action: "install", Image: "invocation"}))
+
+	endpoint, err := ioutil.ReadFile(dir.Join("endpoint"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(endpoint), "http://localhost"))
+	state, err := ioutil.ReadFile(filepath.Join(dir.Path(), "reports", "state"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(state), "ok"))
+	large, err := ioutil.ReadFile(dir.Join("large"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(large, drivers.DefaultMaxOutputSize+1))
+	_, err = ioutil.ReadFile(filepath.Join(filepath.Dir(dir.Path()), "escape"))
+	assert.Check(t, err != nil)
+
+	// The volume and the reading container are removed
+	assert.Check(t, is.Len(client.volumes, 0))
+	assert.Check(t, is.DeepEqual(client.removed, []string{"reader"}))
+}
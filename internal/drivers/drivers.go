@@ -0,0 +1,68 @@
+// Package drivers runs operations on cnab-go drivers, which cannot be
+// cancelled through the driver.Driver interface.
+package drivers
+
+import (
+	"context"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/pkg/telemetry"
+)
+
+// ContextDriver is a driver whose operations can be cancelled.
+type ContextDriver interface {
+	driver.Driver
+	// RunContext executes the operation inside of the invocation image,
+	// stopping it when the context is done.
+	RunContext(ctx context.Context, op *driver.Operation) error
+}
+
+// Run executes the operation with the driver, until the context is done. If
+// the driver is not a ContextDriver, Run returns as soon as the context is
+// done, but the operation keeps running in the background until it
+// completes.
+func Run(ctx context.Context, d driver.Driver, op *driver.Operation) (err error) {
+	ctx, end := start(ctx, op)
+	defer func() { end(err) }()
+	return run(ctx, d, op)
+}
+
+// run is Run, without tracing.
+func run(ctx context.Context, d driver.Driver, op *driver.Operation) error {
+	if cd, ok := d.(ContextDriver); ok {
+		return cd.RunContext(ctx, op)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Run(op)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start starts the span of the operation, and returns the function ending
+// it and measuring the duration of the operation.
+func start(ctx context.Context, op *driver.Operation) (context.Context, func(error)) {
+	ctx, span := telemetry.Start(ctx, telemetry.SpanDriver, telemetry.Fields{
+		"installation": op.Installation,
+		"action":       op.Action,
+		"image":        op.Image,
+		"imageType":    op.ImageType,
+	})
+	started := time.Now()
+	return ctx, func(err error) {
+		span.End(err)
+		telemetry.Observe(telemetry.MetricActionDuration, time.Since(started).Seconds(), telemetry.Labels{
+			"action": op.Action,
+			"result": telemetry.Result(err, ctx.Err() != nil),
+		})
+	}
+}
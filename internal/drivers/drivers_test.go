@@ -0,0 +1,100 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/app/pkg/telemetry/telemetrytest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// blockingDriver runs its operations until released.
+type blockingDriver struct {
+	release chan struct{}
+}
+
+func (d *blockingDriver) Run(*driver.Operation) error {
+	<-d.release
+	return nil
+}
+
+func (d *blockingDriver) Handles(string) bool {
+	return true
+}
+
+func TestRunCancelled(t *testing.T) {
+	d := &blockingDriver{release: make(chan struct{})}
+	defer close(d.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, Run(ctx, d, &driver.Operation{Action: "install"}), context.DeadlineExceeded)
+}
+
+func TestRunAlreadyCancelled(t *testing.T) {
+	d := fake.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, Run(ctx, d, &driver.Operation{Action: "install"}), context.Canceled)
+}
+
+func TestRunContextDriver(t *testing.T) {
+	d := fake.New().Default(fake.Result{Delay: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, Run(ctx, d, &driver.Operation{Action: "install"}), context.DeadlineExceeded)
+
+	assert.NilError(t, Run(context.Background(), fake.New(), &driver.Operation{Action: "install"}))
+}
+
+func TestRunTraced(t *testing.T) {
+	recorder, restore := telemetrytest.Install()
+	defer restore()
+	op := &driver.Operation{Installation: "my-app", Action: "install", Image: "my-app:1.0.0", ImageType: "docker"}
+	assert.NilError(t, Run(context.Background(), fake.New(), op))
+	assert.NilError(t, RunStreaming(context.Background(), fake.New(), op, Streams{}))
+
+	// The fallback of RunStreaming is not traced twice
+	spans := recorder.Spans()
+	assert.Assert(t, is.Len(spans, 2))
+	for _, span := range spans {
+		assert.Check(t, is.Equal(span.Name, telemetry.SpanDriver))
+		assert.Check(t, is.DeepEqual(span.Attributes, telemetry.Fields{
+			"installation": "my-app",
+			"action":       "install",
+			"image":        "my-app:1.0.0",
+			"imageType":    "docker",
+		}))
+		assert.Check(t, span.Ended)
+	}
+	assert.Check(t, is.Len(recorder.Measures(telemetry.MetricActionDuration), 2))
+
+	d := fake.New().Default(fake.Result{Err: errors.New("boom")})
+	assert.Check(t, Run(context.Background(), d, op) != nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Check(t, Run(ctx, fake.New(), op) != nil)
+	durations := recorder.Measures(telemetry.MetricActionDuration)
+	assert.Assert(t, is.Len(durations, 4))
+	assert.Check(t, is.DeepEqual(durations[2].Labels, telemetry.Labels{"action": "install", "result": telemetry.ResultFailure}))
+	assert.Check(t, is.DeepEqual(durations[3].Labels, telemetry.Labels{"action": "install", "result": telemetry.ResultInterrupted}))
+}
+
+func TestMulti(t *testing.T) {
+	docker := fake.New("docker", "oci")
+	wasm := fake.New("wasm")
+	m := Multi{docker, wasm}
+	assert.Check(t, m.Handles("oci"))
+	assert.Check(t, m.Handles("wasm"))
+	assert.Check(t, !m.Handles("qcow"))
+
+	assert.NilError(t, Run(context.Background(), m, &driver.Operation{Action: "install", ImageType: "wasm"}))
+	assert.Check(t, is.Len(docker.Operations(), 0))
+	assert.Check(t, is.Len(wasm.Operations(), 1))
+	assert.Check(t, is.ErrorContains(m.Run(&driver.Operation{ImageType: "qcow"}), `no driver handles images of type "qcow"`))
+}
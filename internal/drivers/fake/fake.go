@@ -0,0 +1,165 @@
+// Package fake provides an in-memory driver to test action flows without
+// running any invocation image.
+package fake
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+)
+
+// Result is the scripted outcome of an operation.
+type Result struct {
+	// Err is returned by Run, simulating a failed action.
+	Err error
+	// Output is written to the operation output stream.
+	Output string
+	// Outputs are the values produced by the invocation image, keyed by name.
+	Outputs map[string]string
+	// Delay simulates a slow run.
+	Delay time.Duration
+}
+
+// Driver records the operations it runs and returns scripted results.
+type Driver struct {
+	mu         sync.Mutex
+	imageTypes []string
+	scripts    map[string][]Result
+	fallback   Result
+	operations []driver.Operation
+	outputs    map[string]map[string]string
+}
+
+var _ driver.Driver = &Driver{}
+
+// New returns a driver handling the given image types, or docker and oci
+// images if none are given. By default every operation succeeds.
+func New(imageTypes ...string) *Driver {
+	if len(imageTypes) == 0 {
+		imageTypes = []string{driver.ImageTypeDocker, driver.ImageTypeOCI}
+	}
+	return &Driver{
+		imageTypes: imageTypes,
+		scripts:    map[string][]Result{},
+		outputs:    map[string]map[string]string{},
+	}
+}
+
+// Script queues results for the successive runs of an action. Once they are
+// consumed, the default result is used.
+func (d *Driver) Script(action string, results ...Result) *Driver {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.scripts[action] = append(d.scripts[action], results...)
+	return d
+}
+
+// Default sets the result of the actions which are not scripted.
+func (d *Driver) Default(result Result) *Driver {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fallback = result
+	return d
+}
+
+// Run records the operation and plays the next scripted result.
+func (d *Driver) Run(op *driver.Operation) error {
+	return d.RunContext(context.Background(), op)
+}
+
+// RunContext is Run, interrupting delayed results when the context is done.
+func (d *Driver) RunContext(ctx context.Context, op *driver.Operation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.operations = append(d.operations, copyOperation(op))
+	result := d.fallback
+	if queue := d.scripts[op.Action]; len(queue) > 0 {
+		result, d.scripts[op.Action] = queue[0], queue[1:]
+	}
+	d.mu.Unlock()
+
+	if result.Delay > 0 {
+		timer := time.NewTimer(result.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	if result.Output != "" && op.Out != nil {
+		if _, err := io.WriteString(op.Out, result.Output); err != nil {
+			return err
+		}
+	}
+	if result.Err != nil {
+		return result.Err
+	}
+	if len(result.Outputs) > 0 {
+		d.mu.Lock()
+		outputs := map[string]string{}
+		for k, v := range result.Outputs {
+			outputs[k] = v
+		}
+		d.outputs[op.Installation] = outputs
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+// Handles returns true for the image types given to New.
+func (d *Driver) Handles(imageType string) bool {
+	for _, t := range d.imageTypes {
+		if t == imageType {
+			return true
+		}
+	}
+	return false
+}
+
+// Operations returns the operations run so far, in order.
+func (d *Driver) Operations() []driver.Operation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]driver.Operation(nil), d.operations...)
+}
+
+// LastOperation returns the last operation run, if any.
+func (d *Driver) LastOperation() (driver.Operation, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.operations) == 0 {
+		return driver.Operation{}, false
+	}
+	return d.operations[len(d.operations)-1], true
+}
+
+// Outputs returns the outputs produced by the last successful run for the
+// installation.
+func (d *Driver) Outputs(installation string) map[string]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.outputs[installation]
+}
+
+func copyOperation(op *driver.Operation) driver.Operation {
+	c := *op
+	c.Parameters = map[string]interface{}{}
+	for k, v := range op.Parameters {
+		c.Parameters[k] = v
+	}
+	c.Environment = map[string]string{}
+	for k, v := range op.Environment {
+		c.Environment[k] = v
+	}
+	c.Files = map[string]string{}
+	for k, v := range op.Files {
+		c.Files[k] = v
+	}
+	return c
+}
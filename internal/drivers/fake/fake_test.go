@@ -0,0 +1,53 @@
+package fake_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/action"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+)
+
+func TestInstallFlow(t *testing.T) {
+	d := fake.New().Script(claim.ActionInstall,
+		fake.Result{Err: errors.New("boom"), Output: "failing\n"},
+		fake.Result{Output: "installed\n", Outputs: map[string]string{"url": "http://localhost"}, Delay: time.Millisecond},
+	)
+	c, err := claim.New("myinstallation")
+	assert.NilError(t, err)
+	c.Bundle = bundletest.NewTestBundle(bundletest.WithParameters(1))
+	c.Parameters = map[string]interface{}{"param-0": "value"}
+	out := bytes.NewBuffer(nil)
+
+	inst := &action.Install{Driver: d}
+	err = inst.Run(c, nil, out)
+	assert.Error(t, err, "boom")
+	assert.Equal(t, c.Result.Status, claim.StatusFailure)
+
+	assert.NilError(t, inst.Run(c, nil, out))
+	assert.Equal(t, c.Result.Status, claim.StatusSuccess)
+	assert.Equal(t, out.String(), "failing\ninstalled\n")
+	assert.DeepEqual(t, d.Outputs("myinstallation"), map[string]string{"url": "http://localhost"})
+
+	// unscripted actions succeed
+	assert.NilError(t, (&action.Upgrade{Driver: d}).Run(c, nil, out))
+
+	ops := d.Operations()
+	assert.Equal(t, len(ops), 3)
+	assert.Equal(t, ops[0].Action, claim.ActionInstall)
+	assert.Equal(t, ops[0].Environment["PARAM_0"], "value")
+	last, ok := d.LastOperation()
+	assert.Assert(t, ok)
+	assert.Equal(t, last.Action, claim.ActionUpgrade)
+}
+
+func TestHandles(t *testing.T) {
+	assert.Assert(t, fake.New().Handles("docker"))
+	assert.Assert(t, !fake.New().Handles("qcow"))
+	assert.Assert(t, fake.New("qcow").Handles("qcow"))
+}
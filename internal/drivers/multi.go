@@ -0,0 +1,57 @@
+package drivers
+
+import (
+	"context"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/pkg/errors"
+)
+
+// Multi runs each operation with the first of its drivers handling the type
+// of the invocation image, so the actions select the first invocation image
+// one of them handles.
+type Multi []driver.Driver
+
+var (
+	_ ContextDriver = Multi{}
+	_ OutputsDriver = Multi{}
+)
+
+// Handles returns true if one of the drivers handles the image type.
+func (m Multi) Handles(imageType string) bool {
+	return m.driver(imageType) != nil
+}
+
+// Run runs the operation with the driver handling its image type.
+func (m Multi) Run(op *driver.Operation) error {
+	return m.RunContext(context.Background(), op)
+}
+
+// RunContext runs the operation with the driver handling its image type,
+// until the context is done.
+func (m Multi) RunContext(ctx context.Context, op *driver.Operation) error {
+	d := m.driver(op.ImageType)
+	if d == nil {
+		return errors.Errorf("no driver handles images of type %q", op.ImageType)
+	}
+	return run(ctx, d, op)
+}
+
+// SetOutputsDir sets the outputs directory of the drivers copying the
+// outputs of their operations.
+func (m Multi) SetOutputsDir(dir string) {
+	for _, d := range m {
+		if o, ok := d.(OutputsDriver); ok {
+			o.SetOutputsDir(dir)
+		}
+	}
+}
+
+func (m Multi) driver(imageType string) driver.Driver {
+	for _, d := range m {
+		if d.Handles(imageType) {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Package opdriver builds the operations run by drivers, mapping the
+// parameters and credentials of a bundle to the environment variables and
+// files of its invocation image, per the CNAB specification.
+package opdriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/cnab"
+	"github.com/pkg/errors"
+)
+
+// Environment variables set for every action.
+const (
+	EnvAction           = "CNAB_ACTION"
+	EnvInstallationName = "CNAB_INSTALLATION_NAME"
+	EnvBundleName       = "CNAB_BUNDLE_NAME"
+	EnvBundleVersion    = "CNAB_BUNDLE_VERSION"
+)
+
+// ImageMapPath is the file of the invocation image listing the images of the
+// bundle.
+const ImageMapPath = "/cnab/app/image-map.json"
+
+// Options are the options of BuildOperation.
+type Options struct {
+	arguments map[string]interface{}
+}
+
+// WithArguments gives arguments to the action, see
+// cnab.ActionArgumentsExtensionKey.
+func WithArguments(args map[string]interface{}) func(*Options) {
+	return func(o *Options) {
+		o.arguments = args
+	}
+}
+
+// BuildOperation returns the operation running the action of the bundle for
+// the installation, with the environment variables and files of the
+// invocation image. The templates of the location paths are expanded with
+// the CNAB environment variables, see cnab.LocationVariables. The parameters
+// must be defined by the bundle, and the required ones applying to the action
+// must be given. The arguments of the action are checked and injected along,
+// see cnab.ResolveActionArguments. Credentials are required unless the action
+// is stateless. Two values cannot be injected at the same location, nor
+// override the CNAB environment variables.
+//
+// The invocation image of the operation is left to the caller, as it depends
+// on the driver.
+func BuildOperation(b *bundle.Bundle, installation, action string, params map[string]interface{}, creds credentials.Set, opts ...func(*Options)) (*driver.Operation, error) {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	stateless, err := checkAction(b, action)
+	if err != nil {
+		return nil, err
+	}
+	args, err := cnab.ResolveActionArguments(b, action, o.arguments)
+	if err != nil {
+		return nil, err
+	}
+	inj := injector{
+		env: map[string]string{
+			EnvAction:           action,
+			EnvInstallationName: installation,
+			EnvBundleName:       b.Name,
+			EnvBundleVersion:    b.Version,
+		},
+		files:   map[string]string{},
+		sources: map[string]string{},
+	}
+
+	for _, name := range sortedKeys(params) {
+		if _, ok := b.Parameters[name]; !ok {
+			return nil, errors.Errorf("undefined parameter %q", name)
+		}
+	}
+	applied := map[string]interface{}{}
+	for _, name := range cnab.ParameterNames(b) {
+		def := b.Parameters[name]
+		if !cnab.ParameterAppliesTo(def, action) {
+			continue
+		}
+		raw, ok := params[name]
+		if !ok {
+			if def.Required {
+				return nil, errors.Errorf("missing required parameter %q for action %q", name, action)
+			}
+			continue
+		}
+		value, err := formatValue(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for parameter %q", name)
+		}
+		applied[name] = raw
+		location := bundle.Location{EnvironmentVariable: "CNAB_P_" + strings.ToUpper(name)}
+		if def.Destination != nil {
+			location = *def.Destination
+		}
+		if err := inj.inject(fmt.Sprintf("parameter %q", name), location, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(args) > 0 {
+		defs, err := cnab.ReadActionArguments(b)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sortedKeys(args) {
+			value, err := formatValue(args[name])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value for argument %q", name)
+			}
+			if err := inj.inject(fmt.Sprintf("argument %q", name), cnab.ArgumentLocation(name, defs[action][name]), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, name := range cnab.CredentialNames(b) {
+		value, ok := creds[name]
+		if !ok {
+			if stateless {
+				continue
+			}
+			return nil, errors.Errorf("credential %q is missing from the user-supplied credentials", name)
+		}
+		if err := inj.inject(fmt.Sprintf("credential %q", name), b.Credentials[name], value); err != nil {
+			return nil, err
+		}
+	}
+
+	images := b.Images
+	if images == nil {
+		images = map[string]bundle.Image{}
+	}
+	imageMap, err := json.Marshal(images)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate image map")
+	}
+	if err := inj.inject("image map", bundle.Location{Path: ImageMapPath}, string(imageMap)); err != nil {
+		return nil, err
+	}
+
+	return &driver.Operation{
+		Action:       action,
+		Installation: installation,
+		Parameters:   applied,
+		Environment:  inj.env,
+		Files:        inj.files,
+	}, nil
+}
+
+// checkAction returns whether the action is stateless, failing if the bundle
+// does not define it.
+func checkAction(b *bundle.Bundle, action string) (bool, error) {
+	a, ok := cnab.LookupAction(b, action)
+	if !ok {
+		return false, errors.Errorf("action %q is not defined in the bundle", action)
+	}
+	return a.Stateless, nil
+}
+
+// injector places values in the environment and files, remembering what
+// each location holds to report conflicts.
+type injector struct {
+	env     map[string]string
+	files   map[string]string
+	sources map[string]string
+}
+
+func (i *injector) inject(source string, location bundle.Location, value string) error {
+	if location.EnvironmentVariable == "" && location.Path == "" {
+		return errors.Errorf("%s has no environment variable nor path", source)
+	}
+	if env := location.EnvironmentVariable; env != "" {
+		if err := i.claim(source, "environment variable "+env, isReserved(env)); err != nil {
+			return err
+		}
+		i.env[env] = value
+	}
+	if path := location.Path; path != "" {
+		path, err := cnab.ExpandLocationPath(path, i.env)
+		if err != nil {
+			return errors.Wrap(err, source)
+		}
+		if err := i.claim(source, "file "+path, false); err != nil {
+			return err
+		}
+		i.files[path] = value
+	}
+	return nil
+}
+
+func (i *injector) claim(source, location string, reserved bool) error {
+	if reserved {
+		return errors.Errorf("%s cannot override the %s", source, location)
+	}
+	if other, ok := i.sources[location]; ok {
+		return errors.Errorf("%s and %s are both injected in the %s", other, source, location)
+	}
+	i.sources[location] = source
+	return nil
+}
+
+func isReserved(env string) bool {
+	switch env {
+	case EnvAction, EnvInstallationName, EnvBundleName, EnvBundleVersion:
+		return true
+	}
+	return false
+}
+
+// formatValue formats a parameter value, strings as is and other values as
+// JSON, so numbers are not formatted in scientific notation.
+func formatValue(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
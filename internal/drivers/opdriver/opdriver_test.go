@@ -0,0 +1,129 @@
+package opdriver
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal/cnab"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func testBundle() *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:    "app",
+		Version: "1.0.0",
+		Actions: map[string]bundle.Action{"status": {Stateless: true}},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"port":     {DataType: "int"},
+			"replicas": {DataType: "int", ApplyTo: []string{"install", "upgrade"}, Required: true},
+			"config":   {DataType: "string", Destination: &bundle.Location{Path: "/etc/app/config.yml", EnvironmentVariable: "APP_CONFIG"}},
+		},
+		Credentials: map[string]bundle.Location{
+			"token": {EnvironmentVariable: "TOKEN"},
+			"key":   {Path: "/cnab/app/key"},
+		},
+	}
+}
+
+func TestBuildOperation(t *testing.T) {
+	op, err := BuildOperation(testBundle(), "myapp", "install",
+		map[string]interface{}{"port": 1000000, "replicas": 3, "config": "debug: true"},
+		credentials.Set{"token": "secret", "key": "pem"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(op.Action, "install"))
+	assert.Check(t, is.Equal(op.Installation, "myapp"))
+	assert.Check(t, is.DeepEqual(op.Environment, map[string]string{
+		"CNAB_ACTION":            "install",
+		"CNAB_INSTALLATION_NAME": "myapp",
+		"CNAB_BUNDLE_NAME":       "app",
+		"CNAB_BUNDLE_VERSION":    "1.0.0",
+		"CNAB_P_PORT":            "1000000",
+		"CNAB_P_REPLICAS":        "3",
+		"APP_CONFIG":             "debug: true",
+		"TOKEN":                  "secret",
+	}))
+	assert.Check(t, is.DeepEqual(op.Files, map[string]string{
+		"/etc/app/config.yml":      "debug: true",
+		"/cnab/app/key":            "pem",
+		"/cnab/app/image-map.json": "{}",
+	}))
+}
+
+func TestBuildOperationExpandsLocationTemplates(t *testing.T) {
+	b := testBundle()
+	b.Credentials["key"] = bundle.Location{Path: "/cnab/app/{{ .CNAB_INSTALLATION_NAME }}/{{.CNAB_ACTION}}.key"}
+	op, err := BuildOperation(b, "myapp", "install", map[string]interface{}{"replicas": 3}, credentials.Set{"token": "secret", "key": "pem"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(op.Files["/cnab/app/myapp/install.key"], "pem"))
+
+	b.Credentials["key"] = bundle.Location{Path: "/cnab/app/{{ .HOME }}/key"}
+	_, err = BuildOperation(b, "myapp", "install", map[string]interface{}{"replicas": 3}, credentials.Set{"token": "secret", "key": "pem"})
+	assert.Check(t, is.ErrorContains(err, `credential "key": invalid template in path "/cnab/app/{{ .HOME }}/key": unknown variable HOME`))
+}
+
+func TestBuildOperationParametersApplyToAction(t *testing.T) {
+	op, err := BuildOperation(testBundle(), "myapp", "status",
+		map[string]interface{}{"replicas": 3}, credentials.Set{})
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(op.Parameters, 0))
+	_, ok := op.Environment["CNAB_P_REPLICAS"]
+	assert.Check(t, !ok)
+}
+
+func TestBuildOperationErrors(t *testing.T) {
+	creds := credentials.Set{"token": "secret", "key": "pem"}
+	_, err := BuildOperation(testBundle(), "myapp", "backup", nil, creds)
+	assert.Check(t, is.Error(err, `action "backup" is not defined in the bundle`))
+	_, err = BuildOperation(testBundle(), "myapp", "install", map[string]interface{}{"replicas": 1, "debug": true}, creds)
+	assert.Check(t, is.Error(err, `undefined parameter "debug"`))
+	_, err = BuildOperation(testBundle(), "myapp", "upgrade", nil, creds)
+	assert.Check(t, is.Error(err, `missing required parameter "replicas" for action "upgrade"`))
+	_, err = BuildOperation(testBundle(), "myapp", "uninstall", nil, credentials.Set{"token": "secret"})
+	assert.Check(t, is.Error(err, `credential "key" is missing from the user-supplied credentials`))
+
+	b := testBundle()
+	b.Credentials["token"] = bundle.Location{EnvironmentVariable: "CNAB_P_PORT"}
+	_, err = BuildOperation(b, "myapp", "uninstall", map[string]interface{}{"port": 80}, creds)
+	assert.Check(t, is.Error(err, `parameter "port" and credential "token" are both injected in the environment variable CNAB_P_PORT`))
+
+	b = testBundle()
+	b.Credentials["token"] = bundle.Location{EnvironmentVariable: "CNAB_ACTION"}
+	_, err = BuildOperation(b, "myapp", "uninstall", nil, creds)
+	assert.Check(t, is.Error(err, `credential "token" cannot override the environment variable CNAB_ACTION`))
+}
+
+func TestBuildOperationArguments(t *testing.T) {
+	b := testBundle()
+	b.Actions["backup"] = bundle.Action{}
+	b.Custom = map[string]interface{}{
+		cnab.ActionArgumentsExtensionKey: map[string]interface{}{
+			"backup": map[string]interface{}{
+				"target":  map[string]interface{}{"type": "string", "required": true},
+				"retries": map[string]interface{}{"type": "int", "default": 3},
+				"key":     map[string]interface{}{"type": "string", "destination": map[string]interface{}{"path": "/cnab/app/backup.key"}},
+			},
+		},
+	}
+	creds := credentials.Set{"token": "secret", "key": "pem"}
+	op, err := BuildOperation(b, "myapp", "backup", nil, creds, WithArguments(map[string]interface{}{"target": "s3://bucket/backups"}))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(op.Environment["CNAB_ARG_TARGET"], "s3://bucket/backups"))
+	assert.Check(t, is.Equal(op.Environment["CNAB_ARG_RETRIES"], "3"))
+	_, ok := op.Files["/cnab/app/backup.key"]
+	assert.Check(t, !ok)
+	assert.Check(t, is.DeepEqual(op.Parameters, map[string]interface{}{}))
+
+	_, err = BuildOperation(b, "myapp", "backup", nil, creds)
+	assert.Check(t, is.Error(err, `missing required argument "target" of action "backup"`))
+	_, err = BuildOperation(b, "myapp", "backup", nil, creds, WithArguments(map[string]interface{}{"target": "s3://x", "retries": "many"}))
+	assert.Check(t, is.ErrorContains(err, `invalid value for argument "retries" of action "backup"`))
+	_, err = BuildOperation(b, "myapp", "status", nil, creds, WithArguments(map[string]interface{}{"target": "s3://x"}))
+	assert.Check(t, is.Error(err, `action "status" has no argument "target"`))
+	// The key credential and the key argument do not collide.
+	op, err = BuildOperation(b, "myapp", "backup", nil, creds, WithArguments(map[string]interface{}{"target": "s3://x", "key": "k"}))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(op.Files["/cnab/app/backup.key"], "k"))
+	assert.Check(t, is.Equal(op.Files["/cnab/app/key"], "pem"))
+}
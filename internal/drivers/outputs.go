@@ -0,0 +1,117 @@
+package drivers
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/redact"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxOutputSize is the default size limit of an output.
+const DefaultMaxOutputSize = 1 << 20
+
+// OperationResult is what an operation produced, once its invocation image
+// finished.
+type OperationResult struct {
+	// Outputs are the values of the outputs, keyed by name.
+	Outputs map[string]string
+	// Sensitive lists the sorted names of the outputs which must never be
+	// displayed nor logged.
+	Sensitive []string
+}
+
+// Redacted returns the outputs, the values of the sensitive ones being
+// masked.
+func (r *OperationResult) Redacted() map[string]string {
+	redacted := make(map[string]string, len(r.Outputs))
+	for name, value := range r.Outputs {
+		redacted[name] = value
+	}
+	for _, name := range r.Sensitive {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = redact.Mask
+		}
+	}
+	return redacted
+}
+
+// OutputsDriver is a driver copying the outputs directory of the invocation
+// images to a local directory once the operations succeed, so their outputs
+// can be collected with DirOutputOpener.
+type OutputsDriver interface {
+	driver.Driver
+	SetOutputsDir(dir string)
+}
+
+// OutputOpener opens a file of a finished invocation image, given its path in
+// the image. It returns an error satisfying os.IsNotExist if the file does
+// not exist.
+type OutputOpener func(path string) (io.ReadCloser, error)
+
+// DirOutputOpener opens the outputs copied from the outputs directory of an
+// invocation image to a local directory.
+func DirOutputOpener(dir string) OutputOpener {
+	return func(p string) (io.ReadCloser, error) {
+		rel := strings.TrimPrefix(path.Clean(p), cnab.OutputsDirectory+"/")
+		return os.Open(filepath.Join(dir, filepath.FromSlash(rel)))
+	}
+}
+
+// CollectOutputs reads the outputs the bundle declares for the action, which
+// must not exceed maxSize bytes each. Outputs the invocation image did not
+// write are left out, so ValidateOutputs reports them.
+func CollectOutputs(b *bundle.Bundle, action string, open OutputOpener, maxSize int64) (*OperationResult, error) {
+	outputs, err := cnab.ReadOutputs(b)
+	if err != nil {
+		return nil, err
+	}
+	result := &OperationResult{Outputs: map[string]string{}}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		output := outputs[name]
+		if !output.AppliesTo(action) {
+			continue
+		}
+		value, err := readOutput(open, output.Path, maxSize)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read output %q", name)
+		}
+		result.Outputs[name] = value
+		if output.Sensitive {
+			result.Sensitive = append(result.Sensitive, name)
+		}
+	}
+	return result, nil
+}
+
+func readOutput(open OutputOpener, path string, maxSize int64) (string, error) {
+	f, err := open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > maxSize {
+		return "", errors.Errorf("%s exceeds the maximum size of %d bytes", path, maxSize)
+	}
+	return string(data), nil
+}
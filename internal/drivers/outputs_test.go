@@ -0,0 +1,62 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func outputsBundle() *bundle.Bundle {
+	return bundletest.NewTestBundle(
+		bundletest.WithCustom(cnab.ParameterSchemasExtensionKey, map[string]interface{}{
+			"definitions": map[string]interface{}{"string": map[string]interface{}{"type": "string"}},
+		}),
+		bundletest.WithCustom(cnab.OutputsExtensionKey, map[string]interface{}{
+			"endpoint": map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/endpoint"},
+			"password": map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/password", "sensitive": true},
+			"logs":     map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/logs", "applyTo": []interface{}{"uninstall"}},
+			"report":   map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/reports/report"},
+		}),
+	)
+}
+
+func TestCollectOutputs(t *testing.T) {
+	dir := fs.NewDir(t, "outputs",
+		fs.WithFile("endpoint", "http://localhost"),
+		fs.WithFile("password", "s3cr3t"),
+		fs.WithFile("logs", "done"),
+		fs.WithDir("reports", fs.WithFile("report", "ok")),
+	)
+	defer dir.Remove()
+
+	result, err := CollectOutputs(outputsBundle(), "install", DirOutputOpener(dir.Path()), DefaultMaxOutputSize)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(result.Outputs, map[string]string{
+		"endpoint": "http://localhost",
+		"password": "s3cr3t",
+		"report":   "ok",
+	}))
+	assert.Check(t, is.DeepEqual(result.Sensitive, []string{"password"}))
+	assert.Check(t, is.DeepEqual(result.Redacted(), map[string]string{
+		"endpoint": "http://localhost",
+		"password": "******",
+		"report":   "ok",
+	}))
+}
+
+func TestCollectOutputsMissingAndOversized(t *testing.T) {
+	dir := fs.NewDir(t, "outputs", fs.WithFile("endpoint", "http://localhost"))
+	defer dir.Remove()
+
+	result, err := CollectOutputs(outputsBundle(), "install", DirOutputOpener(dir.Path()), DefaultMaxOutputSize)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(result.Outputs, map[string]string{"endpoint": "http://localhost"}))
+
+	_, err = CollectOutputs(outputsBundle(), "install", DirOutputOpener(dir.Path()), 4)
+	assert.Check(t, is.Error(err, `cannot read output "endpoint": /cnab/app/outputs/endpoint exceeds the maximum size of 4 bytes`))
+}
@@ -0,0 +1,140 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+)
+
+// Stage is a step of an operation reported as progress.
+type Stage string
+
+// Stages of an operation. Drivers report the ones they go through, in
+// order. StageCollectingOutputs is reported by the callers collecting the
+// outputs once the driver is done.
+const (
+	StagePullingImage      Stage = "pulling-image"
+	StageCreatingContainer Stage = "creating-container"
+	StageCopyingFiles      Stage = "copying-files"
+	StageRunning           Stage = "running"
+	StageCollectingOutputs Stage = "collecting-outputs"
+	StageDone              Stage = "done"
+	StageFailed            Stage = "failed"
+)
+
+// ProgressEvent is a structured progress report of an operation.
+type ProgressEvent struct {
+	Stage   Stage     `json:"stage"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Streams are where an operation reports its output and progress. Any of
+// them may be nil to discard it.
+type Streams struct {
+	// Stdout and Stderr receive the output of the invocation image, one
+	// complete line per write.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Progress is called on every stage change.
+	Progress func(ProgressEvent)
+}
+
+// Report sends a progress event, if a progress callback is set.
+func (s Streams) Report(stage Stage, message string) {
+	if s.Progress != nil {
+		s.Progress(ProgressEvent{Stage: stage, Message: message, Time: time.Now()})
+	}
+}
+
+// StreamingDriver is a driver streaming the output of its operations, and
+// reporting their progress, as they run.
+type StreamingDriver interface {
+	driver.Driver
+	// RunStreaming executes the operation until the context is done,
+	// writing the output of the invocation image to the streams.
+	RunStreaming(ctx context.Context, op *driver.Operation, streams Streams) error
+}
+
+// RunStreaming executes the operation with the driver, writing its output to
+// the streams line by line and reporting its progress. The output of drivers
+// which are not StreamingDrivers is written to Stdout as their operations
+// write it to their Out, which is replaced, and only the running, done and
+// failed stages are reported.
+func RunStreaming(ctx context.Context, d driver.Driver, op *driver.Operation, streams Streams) (err error) {
+	ctx, end := start(ctx, op)
+	defer func() { end(err) }()
+	stdout := NewLineWriter(streams.Stdout)
+	stderr := NewLineWriter(streams.Stderr)
+	lines := Streams{Stdout: stdout, Stderr: stderr, Progress: streams.Progress}
+
+	if sd, ok := d.(StreamingDriver); ok {
+		err = sd.RunStreaming(ctx, op, lines)
+	} else {
+		lines.Report(StageRunning, "")
+		withOut := *op
+		withOut.Out = stdout
+		err = run(ctx, d, &withOut)
+	}
+	// The last incomplete lines are written before the final stage
+	stdout.Flush() //nolint:errcheck // the operation error is more relevant
+	stderr.Flush() //nolint:errcheck // as above
+	if err != nil {
+		lines.Report(StageFailed, err.Error())
+		return err
+	}
+	lines.Report(StageDone, "")
+	return nil
+}
+
+// LineWriter writes complete lines to an underlying writer, buffering the
+// last incomplete line until it is completed or flushed. It is safe for
+// concurrent use, so lines of concurrent writers are not mixed.
+type LineWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+// NewLineWriter returns a line writer to w, discarding the lines if w is
+// nil.
+func NewLineWriter(w io.Writer) *LineWriter {
+	if w == nil {
+		w = ioutil.Discard
+	}
+	return &LineWriter{w: w}
+}
+
+// Write writes the complete lines of p, buffering the rest.
+func (l *LineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if _, err := l.w.Write(l.buf[:i+1]); err != nil {
+			return len(p), err
+		}
+		l.buf = l.buf[i+1:]
+	}
+}
+
+// Flush writes the buffered incomplete line, if any.
+func (l *LineWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) == 0 {
+		return nil
+	}
+	_, err := l.w.Write(l.buf)
+	l.buf = nil
+	return err
+}
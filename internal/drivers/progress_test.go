@@ -0,0 +1,65 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/drivers/fake"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// recordingWriter records every write.
+type recordingWriter struct {
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestLineWriter(t *testing.T) {
+	w := &recordingWriter{}
+	l := NewLineWriter(w)
+	for _, s := range []string{"pull", "ing\ncreat", "ing\nrunning\n", "done"} {
+		_, err := l.Write([]byte(s))
+		assert.NilError(t, err)
+	}
+	assert.Check(t, is.DeepEqual(w.writes, []string{"pulling\n", "creating\n", "running\n"}))
+	assert.NilError(t, l.Flush())
+	assert.NilError(t, l.Flush())
+	assert.Check(t, is.DeepEqual(w.writes, []string{"pulling\n", "creating\n", "running\n", "done"}))
+}
+
+func TestRunStreamingFallback(t *testing.T) {
+	d := fake.New().
+		Script("install", fake.Result{Output: "installing\ndone"}).
+		Script("upgrade", fake.Result{Err: errors.New("boom")})
+	var stdout bytes.Buffer
+	var stages []Stage
+	streams := Streams{
+		Stdout:   &stdout,
+		Progress: func(e ProgressEvent) { stages = append(stages, e.Stage) },
+	}
+
+	op := &driver.Operation{Action: "install", ImageType: "docker"}
+	assert.NilError(t, RunStreaming(context.Background(), d, op, streams))
+	assert.Check(t, is.Equal(stdout.String(), "installing\ndone"))
+	assert.Check(t, is.DeepEqual(stages, []Stage{StageRunning, StageDone}))
+	// The operation is not modified
+	assert.Check(t, op.Out == nil)
+
+	stages = nil
+	err := RunStreaming(context.Background(), d, &driver.Operation{Action: "upgrade", ImageType: "docker"}, streams)
+	assert.Check(t, is.ErrorContains(err, "boom"))
+	assert.Check(t, is.DeepEqual(stages, []Stage{StageRunning, StageFailed}))
+}
+
+func TestRunStreamingNilStreams(t *testing.T) {
+	d := fake.New().Default(fake.Result{Output: "installing\n"})
+	assert.NilError(t, RunStreaming(context.Background(), d, &driver.Operation{Action: "install"}, Streams{}))
+}
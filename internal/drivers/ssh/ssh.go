@@ -0,0 +1,296 @@
+// Package ssh provides a driver running operations on a remote host over
+// SSH, for target environments exposing neither a Docker API nor
+// Kubernetes. The ssh CLI is used, so the user's SSH configuration, agent
+// and known hosts apply.
+package ssh
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/drivers"
+	"github.com/pkg/errors"
+)
+
+// runCommand runs the ssh CLI. It is replaced in tests.
+var runCommand = func(ctx context.Context, gracePeriod time.Duration, stdin io.Reader, stdout, stderr io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return drivers.RunCommand(ctx, cmd, gracePeriod)
+}
+
+// DefaultCommand is the containerless payload run by default, the
+// conventional entrypoint of invocation images.
+const DefaultCommand = "/cnab/app/run"
+
+// RootEnvironmentVariable is set, in containerless mode, to the remote
+// directory the files of the operation are staged in, so the payload can
+// find /cnab/app/foo at $CNAB_ROOT/cnab/app/foo.
+const RootEnvironmentVariable = "CNAB_ROOT"
+
+// environmentFile is the file of the staging directory the environment of
+// the operation is copied to, so the values are never on a command line.
+const environmentFile = ".cnab-environment"
+
+var environmentName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Driver copies the files of the operations to a remote host over SSH and
+// runs the docker and OCI invocation images there with the docker CLI of the
+// host, or runs a command directly on the host in containerless mode.
+// Standard output and error are streamed back to the output of the
+// operation. The environment variables are copied with the files, to a file
+// only readable by the user, which the remote shell loads and removes before
+// running the operation, so their values are not visible in the process list
+// of the host.
+type Driver struct {
+	// Host is the SSH destination, like user@example.com.
+	Host string
+	// Port is the SSH port, the one of the SSH configuration if 0.
+	Port int
+	// IdentityFile is the private key to authenticate with, the ones of the
+	// SSH configuration or agent if empty.
+	IdentityFile string
+	// SSH is the ssh CLI, "ssh" if empty.
+	SSH string
+	// Containerless runs Command on the host instead of the invocation
+	// image.
+	Containerless bool
+	// Command is the containerless payload, DefaultCommand if empty. It is
+	// a shell command line, so it can have arguments.
+	Command string
+	// GracePeriod is how long the ssh CLI is given to stop once the context
+	// is done, before being killed, drivers.DefaultGracePeriod if 0. The
+	// remote command is hung up when the connection closes.
+	GracePeriod time.Duration
+}
+
+var _ drivers.StreamingDriver = &Driver{}
+
+// Configuration keys of ParseConfiguration.
+const (
+	KeyHost          = "ssh-driver-host"
+	KeyPort          = "ssh-driver-port"
+	KeyIdentityFile  = "ssh-driver-identity-file"
+	KeyContainerless = "ssh-driver-containerless"
+	KeyCommand       = "ssh-driver-command"
+)
+
+// ParseConfiguration reads a driver from string settings, like the plugin
+// configuration of the docker CLI, or returns nil if no host is set:
+// - "ssh-driver-host" is the SSH destination, like user@example.com
+// - "ssh-driver-port" is the SSH port
+// - "ssh-driver-identity-file" is the private key to authenticate with
+// - "ssh-driver-containerless" is "true" to run a command on the host
+// - "ssh-driver-command" is the containerless payload
+func ParseConfiguration(get func(key string) (string, bool)) (*Driver, error) {
+	host, _ := get(KeyHost)
+	if host == "" {
+		return nil, nil
+	}
+	d := &Driver{Host: host}
+	d.IdentityFile, _ = get(KeyIdentityFile)
+	d.Command, _ = get(KeyCommand)
+	if value, ok := get(KeyPort); ok && value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, errors.Errorf("invalid %s %q", KeyPort, value)
+		}
+		d.Port = port
+	}
+	if value, ok := get(KeyContainerless); ok && value != "" {
+		containerless, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", KeyContainerless)
+		}
+		d.Containerless = containerless
+	}
+	return d, nil
+}
+
+// Handles returns true for the docker and OCI image types.
+func (d *Driver) Handles(imageType string) bool {
+	return imageType == driver.ImageTypeDocker || imageType == driver.ImageTypeOCI
+}
+
+// Run runs the operation on the remote host.
+func (d *Driver) Run(op *driver.Operation) error {
+	return d.RunContext(context.Background(), op)
+}
+
+// RunContext runs the operation on the remote host, stopping the ssh CLI
+// when the context is done.
+func (d *Driver) RunContext(ctx context.Context, op *driver.Operation) error {
+	out := op.Out
+	if out == nil {
+		out = ioutil.Discard
+	}
+	return d.RunStreaming(ctx, op, drivers.Streams{Stdout: out, Stderr: out})
+}
+
+// RunStreaming runs the operation on the remote host, writing its standard
+// output and error to the streams, until the context is done.
+func (d *Driver) RunStreaming(ctx context.Context, op *driver.Operation, streams drivers.Streams) error {
+	if d.Host == "" {
+		return errors.New("no SSH host set")
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := d.ssh(ctx, nil, &stdout, &stderr, "mktemp -d"); err != nil {
+		return errors.Wrapf(err, "failed to create a staging directory on %s: %s", d.Host, strings.TrimSpace(stderr.String()))
+	}
+	dir := strings.TrimSpace(stdout.String())
+	if dir == "" || !path.IsAbs(dir) {
+		return errors.Errorf("unexpected staging directory %q on %s", dir, d.Host)
+	}
+	defer d.ssh(context.Background(), nil, ioutil.Discard, ioutil.Discard, "rm -rf "+quote(dir)) //nolint:errcheck // best effort cleanup
+
+	env, err := d.environment(dir, op)
+	if err != nil {
+		return err
+	}
+	payload, err := tarFiles(op.Files, env)
+	if err != nil {
+		return err
+	}
+	streams.Report(drivers.StageCopyingFiles, d.Host+":"+dir)
+	stderr.Reset()
+	if err := d.ssh(ctx, payload, ioutil.Discard, &stderr, "tar -x -C "+quote(dir)); err != nil {
+		return errors.Wrapf(err, "failed to copy files to %s: %s", d.Host, strings.TrimSpace(stderr.String()))
+	}
+
+	streams.Report(drivers.StageRunning, d.Host)
+	if err := d.ssh(ctx, nil, writerOrDiscard(streams.Stdout), writerOrDiscard(streams.Stderr), d.remoteCommand(dir, op)); err != nil {
+		return errors.Wrapf(err, "failed to run %s on %s", op.Action, d.Host)
+	}
+	return nil
+}
+
+// environment returns the content of the environment file, assignments
+// the remote shell exports.
+func (d *Driver) environment(dir string, op *driver.Operation) (string, error) {
+	var buf strings.Builder
+	if d.Containerless {
+		buf.WriteString(RootEnvironmentVariable + "=" + quote(dir) + "\n")
+	}
+	for _, name := range sortedKeys(op.Environment) {
+		if !environmentName.MatchString(name) {
+			return "", errors.Errorf("invalid environment variable name %q", name)
+		}
+		buf.WriteString(name + "=" + quote(op.Environment[name]) + "\n")
+	}
+	return buf.String(), nil
+}
+
+// remoteCommand returns the shell command running the operation on the
+// remote host, with its files staged in dir.
+func (d *Driver) remoteCommand(dir string, op *driver.Operation) string {
+	envFile := quote(path.Join(dir, environmentFile))
+	load := "set -a && . " + envFile + " && set +a && rm -f " + envFile + " && exec "
+	if d.Containerless {
+		command := d.Command
+		if command == "" {
+			command = DefaultCommand
+		}
+		return load + command
+	}
+	// The docker CLI passes the variables it is given by name
+	args := []string{"docker", "run", "--rm"}
+	for _, name := range sortedKeys(op.Environment) {
+		args = append(args, "-e", name)
+	}
+	for _, name := range sortedKeys(op.Files) {
+		args = append(args, "-v", quote(path.Join(dir, name)+":"+name+":ro"))
+	}
+	return load + strings.Join(append(args, "--entrypoint", DefaultCommand, quote(op.Image)), " ")
+}
+
+// ssh runs a shell command on the remote host.
+func (d *Driver) ssh(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, command string) error {
+	args := []string{"-o", "BatchMode=yes"}
+	if d.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(d.Port))
+	}
+	if d.IdentityFile != "" {
+		args = append(args, "-i", d.IdentityFile)
+	}
+	args = append(args, d.Host, "--", command)
+	cli := d.SSH
+	if cli == "" {
+		cli = "ssh"
+	}
+	gracePeriod := d.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = drivers.DefaultGracePeriod
+	}
+	return runCommand(ctx, gracePeriod, stdin, stdout, stderr, cli, args...)
+}
+
+// tarFiles archives the files of an operation, keyed by their absolute path,
+// relative to the root, and the environment file.
+func tarFiles(files map[string]string, env string) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: environmentFile, Mode: 0600, Size: int64(len(env))}); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(tw, env); err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(files) {
+		if !path.IsAbs(name) {
+			return nil, errors.Errorf("destination path %s should be an absolute unix path", name)
+		}
+		if name == "/"+environmentFile {
+			return nil, errors.Errorf("destination path %s is reserved", name)
+		}
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{
+			Name: strings.TrimPrefix(name, "/"),
+			Mode: 0644,
+			Size: int64(len(content)),
+		}); err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// quote quotes a string for a POSIX shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func writerOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return ioutil.Discard
+	}
+	return w
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
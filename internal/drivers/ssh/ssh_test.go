@@ -0,0 +1,197 @@
+package ssh
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/drivers"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type run struct {
+	name    string
+	args    []string
+	command string
+	files   map[string]string
+	modes   map[string]int64
+}
+
+// fakeSSH records the runs, with the content of the copied files, and
+// answers mktemp with a staging directory.
+func fakeSSH(runs *[]run, fail string) func() {
+	original := runCommand
+	runCommand = func(ctx context.Context, gracePeriod time.Duration, stdin io.Reader, stdout, stderr io.Writer, name string, args ...string) error {
+		r := run{name: name, args: args, command: args[len(args)-1]}
+		if stdin != nil {
+			r.files = map[string]string{}
+			r.modes = map[string]int64{}
+			tr := tar.NewReader(stdin)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				data, err := ioutil.ReadAll(tr)
+				if err != nil {
+					return err
+				}
+				r.files[hdr.Name] = string(data)
+				r.modes[hdr.Name] = hdr.Mode
+			}
+		}
+		*runs = append(*runs, r)
+		if fail != "" && strings.HasPrefix(r.command, fail) {
+			io.WriteString(stderr, "boom\n")
+			return errors.New("exit status 1")
+		}
+		switch {
+		case r.command == "mktemp -d":
+			io.WriteString(stdout, "/tmp/tmp.abc\n")
+		case strings.HasPrefix(r.command, "set -a"):
+			io.WriteString(stderr, "pulling\n")
+			io.WriteString(stdout, "installed\n")
+		}
+		return nil
+	}
+	return func() { runCommand = original }
+}
+
+func testOperation(out io.Writer) *driver.Operation {
+	return &driver.Operation{
+		Action:      "install",
+		Image:       "myapp:1.0.0-invoc",
+		ImageType:   "docker",
+		Environment: map[string]string{"CNAB_ACTION": "install", "MESSAGE": "it's fine", "TOKEN": "s3cr3t\nline"},
+		Files:       map[string]string{"/cnab/app/image-map.json": "{}", "/etc/params/port": "8080"},
+		Out:         out,
+	}
+}
+
+func TestRunContainer(t *testing.T) {
+	var runs []run
+	defer fakeSSH(&runs, "")()
+	var out bytes.Buffer
+	d := &Driver{Host: "user@example.com", Port: 2222, IdentityFile: "/home/user/.ssh/id_ed25519"}
+	assert.Check(t, d.Handles("docker"))
+	assert.Check(t, !d.Handles("wasm"))
+
+	assert.NilError(t, d.Run(testOperation(&out)))
+	assert.Check(t, is.Equal(out.String(), "pulling\ninstalled\n"))
+	assert.Assert(t, is.Len(runs, 4))
+	assert.Check(t, is.Equal(runs[0].name, "ssh"))
+	assert.Check(t, is.DeepEqual(runs[0].args, []string{"-o", "BatchMode=yes", "-p", "2222", "-i", "/home/user/.ssh/id_ed25519", "user@example.com", "--", "mktemp -d"}))
+	assert.Check(t, is.Equal(runs[1].command, "tar -x -C '/tmp/tmp.abc'"))
+	assert.Check(t, is.DeepEqual(runs[1].files, map[string]string{
+		".cnab-environment":       "CNAB_ACTION='install'\nMESSAGE='it'\\''s fine'\nTOKEN='s3cr3t\nline'\n",
+		"cnab/app/image-map.json": "{}",
+		"etc/params/port":         "8080",
+	}))
+	assert.Check(t, is.Equal(runs[1].modes[".cnab-environment"], int64(0600)))
+	assert.Check(t, is.Equal(runs[2].command, `set -a && . '/tmp/tmp.abc/.cnab-environment' && set +a && rm -f '/tmp/tmp.abc/.cnab-environment' && `+
+		`exec docker run --rm -e CNAB_ACTION -e MESSAGE -e TOKEN `+
+		`-v '/tmp/tmp.abc/cnab/app/image-map.json:/cnab/app/image-map.json:ro' -v '/tmp/tmp.abc/etc/params/port:/etc/params/port:ro' `+
+		`--entrypoint /cnab/app/run 'myapp:1.0.0-invoc'`))
+	// The values are only in the environment file
+	for _, r := range runs {
+		assert.Check(t, !strings.Contains(strings.Join(r.args, " "), "s3cr3t"), r.command)
+	}
+	assert.Check(t, is.Equal(runs[3].command, "rm -rf '/tmp/tmp.abc'"))
+}
+
+func TestRunContainerless(t *testing.T) {
+	var runs []run
+	defer fakeSSH(&runs, "")()
+	d := &Driver{Host: "example.com", SSH: "/usr/bin/ssh", Containerless: true}
+
+	assert.NilError(t, d.Run(testOperation(nil)))
+	assert.Assert(t, is.Len(runs, 4))
+	assert.Check(t, is.Equal(runs[2].name, "/usr/bin/ssh"))
+	assert.Check(t, is.Equal(runs[1].files[".cnab-environment"], "CNAB_ROOT='/tmp/tmp.abc'\nCNAB_ACTION='install'\nMESSAGE='it'\\''s fine'\nTOKEN='s3cr3t\nline'\n"))
+	assert.Check(t, is.Equal(runs[2].command, `set -a && . '/tmp/tmp.abc/.cnab-environment' && set +a && rm -f '/tmp/tmp.abc/.cnab-environment' && exec /cnab/app/run`))
+}
+
+func TestRunStreaming(t *testing.T) {
+	var runs []run
+	defer fakeSSH(&runs, "")()
+	var stdout, stderr bytes.Buffer
+	var events []drivers.ProgressEvent
+	streams := drivers.Streams{
+		Stdout:   &stdout,
+		Stderr:   &stderr,
+		Progress: func(e drivers.ProgressEvent) { events = append(events, e) },
+	}
+	assert.NilError(t, drivers.RunStreaming(context.Background(), &Driver{Host: "example.com"}, testOperation(nil), streams))
+	assert.Check(t, is.Equal(stdout.String(), "installed\n"))
+	assert.Check(t, is.Equal(stderr.String(), "pulling\n"))
+	assert.Assert(t, is.Len(events, 3))
+	assert.Check(t, is.Equal(events[0].Stage, drivers.StageCopyingFiles))
+	assert.Check(t, is.Equal(events[0].Message, "example.com:/tmp/tmp.abc"))
+	assert.Check(t, is.Equal(events[1].Stage, drivers.StageRunning))
+	assert.Check(t, is.Equal(events[2].Stage, drivers.StageDone))
+}
+
+func TestRunFailures(t *testing.T) {
+	var runs []run
+	defer fakeSSH(&runs, "tar")()
+	d := &Driver{Host: "example.com"}
+	err := d.Run(testOperation(nil))
+	assert.ErrorContains(t, err, "failed to copy files to example.com: boom")
+	// The staging directory is removed anyway
+	assert.Check(t, is.Equal(runs[len(runs)-1].command, "rm -rf '/tmp/tmp.abc'"))
+
+	runs = nil
+	defer fakeSSH(&runs, "set -a")()
+	err = d.Run(testOperation(nil))
+	assert.ErrorContains(t, err, "failed to run install on example.com")
+
+	err = (&Driver{}).Run(testOperation(nil))
+	assert.ErrorContains(t, err, "no SSH host set")
+
+	op := testOperation(nil)
+	op.Environment["NOT-A-NAME"] = "value"
+	assert.ErrorContains(t, d.Run(op), `invalid environment variable name "NOT-A-NAME"`)
+}
+
+func TestParseConfiguration(t *testing.T) {
+	settings := map[string]string{
+		KeyHost:          "user@example.com",
+		KeyPort:          "2222",
+		KeyIdentityFile:  "/home/user/.ssh/id_ed25519",
+		KeyContainerless: "true",
+		KeyCommand:       "/opt/app/run --verbose",
+	}
+	get := func(key string) (string, bool) {
+		value, ok := settings[key]
+		return value, ok
+	}
+	d, err := ParseConfiguration(get)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(d, &Driver{
+		Host:          "user@example.com",
+		Port:          2222,
+		IdentityFile:  "/home/user/.ssh/id_ed25519",
+		Containerless: true,
+		Command:       "/opt/app/run --verbose",
+	}))
+
+	settings[KeyPort] = "ssh"
+	_, err = ParseConfiguration(get)
+	assert.Check(t, is.ErrorContains(err, `invalid ssh-driver-port "ssh"`))
+
+	// Without a host, the driver is not used
+	d, err = ParseConfiguration(func(string) (string, bool) { return "", false })
+	assert.NilError(t, err)
+	assert.Check(t, d == nil)
+}
@@ -0,0 +1,13 @@
+// +build !windows
+
+package drivers
+
+import (
+	"os"
+	"syscall"
+)
+
+// terminate asks the process to stop.
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
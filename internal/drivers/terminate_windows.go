@@ -0,0 +1,9 @@
+package drivers
+
+import "os"
+
+// terminate stops the process. Processes cannot be asked to stop on
+// Windows, so they are killed right away.
+func terminate(p *os.Process) error {
+	return p.Kill()
+}
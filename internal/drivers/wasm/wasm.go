@@ -0,0 +1,186 @@
+// Package wasm provides a driver running WebAssembly invocation images with
+// a WASI runtime, so bundles can be installed without a container runtime.
+// The runtime is the wasmtime CLI, no WASI runtime being vendored.
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers"
+	"github.com/docker/app/internal/securetemp"
+	"github.com/pkg/errors"
+)
+
+// runCommand runs the WASI runtime with the environment variables added to
+// its own, streaming its output. It is replaced in tests.
+var runCommand = func(ctx context.Context, gracePeriod time.Duration, env []string, stdout, stderr io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return drivers.RunCommand(ctx, cmd, gracePeriod)
+}
+
+// Driver runs the WebAssembly modules of the "wasm" invocation images. The
+// parameters and other environment variables of the operation are set in the
+// environment of the runtime, which only gets their names on its command
+// line, so their values cannot be read from the process list. The credential
+// and parameter files are written to host directories preopened at their
+// destination.
+type Driver struct {
+	// Runtime is the WASI runtime CLI, "wasmtime" if empty.
+	Runtime string
+	// OutputsDir, if set, is preopened as the outputs directory of the
+	// module, so its outputs can be collected with drivers.DirOutputOpener.
+	OutputsDir string
+	// GracePeriod is how long the runtime is given to stop once the context
+	// is done, before being killed, drivers.DefaultGracePeriod if 0.
+	GracePeriod time.Duration
+}
+
+var (
+	_ drivers.StreamingDriver = &Driver{}
+	_ drivers.OutputsDriver   = &Driver{}
+)
+
+// SetOutputsDir sets OutputsDir.
+func (d *Driver) SetOutputsDir(dir string) {
+	d.OutputsDir = dir
+}
+
+// Handles returns true for the wasm image type.
+func (d *Driver) Handles(imageType string) bool {
+	return imageType == cnab.ImageTypeWasm
+}
+
+// Run runs the module of the operation.
+func (d *Driver) Run(op *driver.Operation) error {
+	return d.RunContext(context.Background(), op)
+}
+
+// RunContext runs the module of the operation, killing the runtime when the
+// context is done.
+func (d *Driver) RunContext(ctx context.Context, op *driver.Operation) error {
+	out := op.Out
+	if out == nil {
+		out = ioutil.Discard
+	}
+	return d.RunStreaming(ctx, op, drivers.Streams{Stdout: out, Stderr: out})
+}
+
+// RunStreaming runs the module of the operation, writing the standard output
+// and error of the runtime to the streams, until the context is done.
+func (d *Driver) RunStreaming(ctx context.Context, op *driver.Operation, streams drivers.Streams) error {
+	module, err := modulePath(op.Image)
+	if err != nil {
+		return err
+	}
+	// The files hold the credentials, they are overwritten once the module ran
+	filesDir, err := securetemp.NewDir("docker-app-wasm")
+	if err != nil {
+		return err
+	}
+	defer filesDir.Remove() //nolint:errcheck // nothing much we can do with an error to remove the files
+	preopens, err := writeFiles(filesDir, op.Files)
+	if err != nil {
+		return err
+	}
+	if d.OutputsDir != "" {
+		preopens[cnab.OutputsDirectory] = d.OutputsDir
+	}
+
+	args := []string{"run"}
+	var env []string
+	for _, name := range sortedKeys(op.Environment) {
+		args = append(args, "--env", name)
+		env = append(env, name+"="+op.Environment[name])
+	}
+	for _, guest := range sortedKeys(preopens) {
+		args = append(args, "--dir", fmt.Sprintf("%s::%s", preopens[guest], guest))
+	}
+	args = append(args, module)
+
+	runtime := d.Runtime
+	if runtime == "" {
+		runtime = "wasmtime"
+	}
+	streams.Report(drivers.StageRunning, module)
+	gracePeriod := d.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = drivers.DefaultGracePeriod
+	}
+	if err := runCommand(ctx, gracePeriod, env, writerOrDiscard(streams.Stdout), writerOrDiscard(streams.Stderr), runtime, args...); err != nil {
+		return errors.Wrapf(err, "failed to run wasm module %q", op.Image)
+	}
+	return nil
+}
+
+// modulePath returns the local path of a module. Remote modules must be
+// downloaded first.
+func modulePath(image string) (string, error) {
+	if err := cnab.ValidateWasmReference(image); err != nil {
+		return "", err
+	}
+	if !strings.Contains(image, "://") {
+		return image, nil
+	}
+	u, err := url.Parse(image)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", errors.Errorf("wasm module %q must be downloaded before being run", image)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// writeFiles writes the files of an operation, keyed by their path in the
+// module, to one host directory per guest directory, and returns these host
+// directories keyed by guest directory.
+func writeFiles(dir *securetemp.Dir, files map[string]string) (map[string]string, error) {
+	preopens := map[string]string{}
+	subdirs := map[string]string{}
+	for _, name := range sortedKeys(files) {
+		guest := path.Dir(name)
+		subdir, ok := subdirs[guest]
+		if !ok {
+			subdir = fmt.Sprint(len(subdirs))
+			subdirs[guest] = subdir
+		}
+		file, err := dir.Write(subdir+"/"+path.Base(name), []byte(files[name]))
+		if err != nil {
+			return nil, err
+		}
+		preopens[guest] = filepath.Dir(file)
+	}
+	return preopens, nil
+}
+
+func writerOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return ioutil.Discard
+	}
+	return w
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/drivers"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+type run struct {
+	name  string
+	args  []string
+	env   []string
+	files map[string]string
+}
+
+// fakeRuntime records the runs, with the content of the preopened files.
+func fakeRuntime(runs *[]run) func() {
+	original := runCommand
+	runCommand = func(ctx context.Context, gracePeriod time.Duration, env []string, stdout, stderr io.Writer, name string, args ...string) error {
+		r := run{name: name, args: args, env: env, files: map[string]string{}}
+		for i, arg := range args {
+			if arg != "--dir" {
+				continue
+			}
+			dirs := strings.SplitN(args[i+1], "::", 2)
+			entries, err := ioutil.ReadDir(dirs[0])
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				data, err := ioutil.ReadFile(filepath.Join(dirs[0], e.Name()))
+				if err != nil {
+					return err
+				}
+				r.files[dirs[1]+"/"+e.Name()] = string(data)
+			}
+		}
+		*runs = append(*runs, r)
+		if _, err := io.WriteString(stderr, "compiling\n"); err != nil {
+			return err
+		}
+		_, err := io.WriteString(stdout, "installed\n")
+		return err
+	}
+	return func() { runCommand = original }
+}
+
+func TestRun(t *testing.T) {
+	var runs []run
+	defer fakeRuntime(&runs)()
+	out := bytes.NewBuffer(nil)
+	d := &Driver{}
+	assert.Check(t, d.Handles("wasm"))
+	assert.Check(t, !d.Handles("docker"))
+
+	err := d.Run(&driver.Operation{
+		Action:      "install",
+		Image:       "file:///modules/installer.wasm",
+		ImageType:   "wasm",
+		Environment: map[string]string{"CNAB_P_PORT": "80", "CNAB_ACTION": "install", "TOKEN": "secret"},
+		Files: map[string]string{
+			"/cnab/app/credentials/token": "secret",
+			"/cnab/app/credentials/key":   "key",
+			"/etc/app/config.yml":         "port: 80",
+		},
+		Out: out,
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(out.String(), "compiling\ninstalled\n"))
+	assert.Assert(t, is.Len(runs, 1))
+	assert.Check(t, is.Equal(runs[0].name, "wasmtime"))
+	// Only the names of the variables are on the command line
+	assert.Check(t, is.DeepEqual(runs[0].args[:7], []string{"run", "--env", "CNAB_ACTION", "--env", "CNAB_P_PORT", "--env", "TOKEN"}))
+	assert.Check(t, is.DeepEqual(runs[0].env, []string{"CNAB_ACTION=install", "CNAB_P_PORT=80", "TOKEN=secret"}))
+	for _, arg := range runs[0].args {
+		assert.Check(t, !strings.Contains(arg, "secret"), arg)
+	}
+	assert.Check(t, is.Equal(runs[0].args[len(runs[0].args)-1], "/modules/installer.wasm"))
+	assert.Check(t, is.DeepEqual(runs[0].files, map[string]string{
+		"/cnab/app/credentials/token": "secret",
+		"/cnab/app/credentials/key":   "key",
+		"/etc/app/config.yml":         "port: 80",
+	}))
+	// The files holding the credentials are removed once the module ran
+	for _, arg := range runs[0].args {
+		if host := strings.Split(arg, "::"); len(host) == 2 {
+			_, err := os.Stat(host[0])
+			assert.Check(t, os.IsNotExist(err), host[0])
+		}
+	}
+}
+
+func TestRunInvalidModule(t *testing.T) {
+	var runs []run
+	defer fakeRuntime(&runs)()
+	d := &Driver{Runtime: "wasmer"}
+	assert.Check(t, is.ErrorContains(d.Run(&driver.Operation{Image: "installer"}), "expected a .wasm file"))
+	assert.Check(t, is.ErrorContains(d.Run(&driver.Operation{Image: "https://example.com/installer.wasm"}), "must be downloaded"))
+	assert.Check(t, is.Len(runs, 0))
+}
+
+func TestRunOutputsDir(t *testing.T) {
+	var runs []run
+	defer fakeRuntime(&runs)()
+	outputs := fs.NewDir(t, "outputs")
+	defer outputs.Remove()
+	d := &Driver{OutputsDir: outputs.Path()}
+	assert.NilError(t, d.Run(&driver.Operation{Image: "installer.wasm"}))
+	assert.Check(t, is.DeepEqual(runs[0].args, []string{"run", "--dir", outputs.Path() + "::/cnab/app/outputs", "installer.wasm"}))
+}
+
+func TestRunStreaming(t *testing.T) {
+	var runs []run
+	defer fakeRuntime(&runs)()
+	var stdout, stderr bytes.Buffer
+	var stages []drivers.Stage
+	streams := drivers.Streams{
+		Stdout:   &stdout,
+		Stderr:   &stderr,
+		Progress: func(e drivers.ProgressEvent) { stages = append(stages, e.Stage) },
+	}
+	assert.NilError(t, drivers.RunStreaming(context.Background(), &Driver{}, &driver.Operation{Image: "installer.wasm"}, streams))
+	assert.Check(t, is.Equal(stdout.String(), "installed\n"))
+	assert.Check(t, is.Equal(stderr.String(), "compiling\n"))
+	assert.Check(t, is.DeepEqual(stages, []drivers.Stage{drivers.StageRunning, drivers.StageDone}))
+}
@@ -0,0 +1,317 @@
+// Package gc removes what the installations left behind on a management
+// host: the bundles of the bundle store, the entries of the bundle cache with
+// their exported images, and the invocation and component images of the
+// removed bundles, once no revision of any installation references them.
+package gc
+
+import (
+	"context"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// Kind is the kind of a collected item.
+type Kind string
+
+const (
+	// KindBundle is a bundle of the bundle store.
+	KindBundle Kind = "bundle"
+	// KindCachedBundle is an entry of the bundle cache, including its
+	// exported images.
+	KindCachedBundle Kind = "cached-bundle"
+	// KindImage is an image of the engine.
+	KindImage Kind = "image"
+)
+
+// Item is a collected item.
+type Item struct {
+	Kind Kind
+	// Name is the reference of a bundle or an image, or the digest of a
+	// cached bundle.
+	Name string
+	// Size is the space reclaimed by the removal of the item. It is zero for
+	// an image whose layers are still used by another tag.
+	Size int64
+}
+
+// Report lists the items collected, or which would be on a dry run.
+type Report struct {
+	DryRun  bool
+	Removed []Item
+	// Skipped are the unreferenced images which cannot be removed as
+	// containers use them.
+	Skipped []Item
+}
+
+// Size returns the space reclaimed by the collection.
+func (r *Report) Size() int64 {
+	var size int64
+	for _, item := range r.Removed {
+		size += item.Size
+	}
+	return size
+}
+
+// ImageEngine lists and removes the images of an engine. It is implemented
+// by the docker client.
+type ImageEngine interface {
+	ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error)
+	ImageRemove(ctx context.Context, imageID string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error)
+}
+
+// Collector removes the bundles and images which are not referenced by any
+// revision of the installations. The stores left nil are not collected.
+type Collector struct {
+	// Installations are the installation stores of all the contexts, see
+	// store.ApplicationStore.InstallationStores. Missing one would remove
+	// the bundles of its installations.
+	Installations []store.InstallationStore
+	Bundles       store.BundleStore
+	Cache         *store.BundleCache
+	// Engine holds the images of the collected bundles. Only the images of
+	// the bundles removed from the bundle store are candidates, the other
+	// images of the engine are never removed.
+	Engine ImageEngine
+	// DryRun reports the items to collect without removing them.
+	DryRun bool
+}
+
+// Collect removes the unreferenced bundles and images and reports them.
+// Nothing is removed if the installations cannot all be read.
+func (c *Collector) Collect(ctx context.Context) (*Report, error) {
+	refs, err := c.referenced()
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{DryRun: c.DryRun}
+	images := map[string]bool{}
+	if c.Bundles != nil {
+		if err := c.collectBundles(refs, images, report); err != nil {
+			return report, err
+		}
+	}
+	if c.Cache != nil {
+		if err := c.collectCache(refs, report); err != nil {
+			return report, err
+		}
+	}
+	if c.Engine != nil {
+		for image := range refs.images {
+			delete(images, image)
+		}
+		if err := c.collectImages(ctx, images, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// references are the bundles and images referenced by the installations.
+type references struct {
+	// bundles are the normalized references of the bundles.
+	bundles map[string]bool
+	// digests are the digests of the bundles, see cnab.Digest.
+	digests map[string]bool
+	// images are the normalized references of the images of the bundles.
+	images map[string]bool
+}
+
+func (c *Collector) referenced() (references, error) {
+	refs := references{bundles: map[string]bool{}, digests: map[string]bool{}, images: map[string]bool{}}
+	for _, installations := range c.Installations {
+		names, err := installations.List()
+		if err != nil {
+			return refs, errors.Wrap(err, "failed to list installations")
+		}
+		for _, name := range names {
+			revisions, err := installations.Revisions(name)
+			if err != nil {
+				return refs, errors.Wrapf(err, "failed to read the revisions of installation %q", name)
+			}
+			current, err := installations.Read(name)
+			if err != nil {
+				return refs, errors.Wrapf(err, "failed to read installation %q", name)
+			}
+			for _, revision := range append(revisions, current) {
+				if err := refs.add(revision); err != nil {
+					return refs, errors.Wrapf(err, "installation %q", name)
+				}
+			}
+		}
+	}
+	return refs, nil
+}
+
+func (r references) add(installation *store.Installation) error {
+	if ref := normalize(installation.Reference); ref != "" {
+		r.bundles[ref] = true
+	}
+	if installation.Bundle == nil {
+		return nil
+	}
+	d, err := cnab.Digest(installation.Bundle)
+	if err != nil {
+		return err
+	}
+	r.digests[d] = true
+	for _, image := range bundleImages(installation.Bundle) {
+		r.images[image] = true
+	}
+	return nil
+}
+
+func (c *Collector) collectBundles(refs references, images map[string]bool, report *Report) error {
+	stored, err := c.Bundles.List()
+	if err != nil {
+		return err
+	}
+	for _, s := range stored {
+		if refs.bundles[s.Ref.String()] {
+			continue
+		}
+		b, err := c.Bundles.Read(s.Ref)
+		if err != nil {
+			// An unreadable bundle may belong to something else, keep it
+			continue
+		}
+		if d, err := cnab.Digest(b); err != nil || refs.digests[d] {
+			continue
+		}
+		for _, image := range bundleImages(b) {
+			images[image] = true
+		}
+		if !c.DryRun {
+			if err := c.Bundles.Remove(s.Ref); err != nil {
+				return err
+			}
+		}
+		report.Removed = append(report.Removed, Item{Kind: KindBundle, Name: reference.FamiliarString(s.Ref), Size: s.Size})
+	}
+	return nil
+}
+
+func (c *Collector) collectCache(refs references, report *Report) error {
+	cached, err := c.Cache.List()
+	if err != nil {
+		return errors.Wrap(err, "failed to list the bundle cache")
+	}
+	sort.Slice(cached, func(i, j int) bool { return cached[i].Digest < cached[j].Digest })
+	for _, entry := range cached {
+		if refs.digests[entry.Digest] {
+			continue
+		}
+		if !c.DryRun {
+			if err := c.Cache.Remove(entry.Digest); err != nil {
+				return err
+			}
+		}
+		report.Removed = append(report.Removed, Item{Kind: KindCachedBundle, Name: entry.Digest, Size: entry.Size})
+	}
+	return nil
+}
+
+func (c *Collector) collectImages(ctx context.Context, candidates map[string]bool, report *Report) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+	summaries, err := c.Engine.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list images")
+	}
+	byRef := map[string]types.ImageSummary{}
+	for _, summary := range summaries {
+		for _, ref := range append(append([]string{}, summary.RepoTags...), summary.RepoDigests...) {
+			if ref := normalize(ref); ref != "" {
+				byRef[ref] = summary
+			}
+		}
+	}
+	var names []string
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	counted := map[string]bool{}
+	for _, name := range names {
+		summary, ok := byRef[name]
+		if !ok {
+			continue
+		}
+		item := Item{Kind: KindImage, Name: familiar(name)}
+		// The layers are only reclaimed with the last tag of the image
+		if !counted[summary.ID] && allCandidates(summary, candidates) {
+			item.Size = summary.Size
+			counted[summary.ID] = true
+		}
+		if !c.DryRun {
+			if _, err := c.Engine.ImageRemove(ctx, name, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+				if errdefs.IsConflict(err) {
+					item.Size = 0
+					report.Skipped = append(report.Skipped, item)
+					continue
+				}
+				return errors.Wrapf(err, "failed to remove image %q", item.Name)
+			}
+		}
+		report.Removed = append(report.Removed, item)
+	}
+	return nil
+}
+
+// allCandidates returns true if all the tags of an image are removed.
+func allCandidates(summary types.ImageSummary, candidates map[string]bool) bool {
+	for _, tag := range summary.RepoTags {
+		if !candidates[normalize(tag)] {
+			return false
+		}
+	}
+	return true
+}
+
+// bundleImages returns the normalized references of the invocation images
+// and component container images of a bundle.
+func bundleImages(b *bundle.Bundle) []string {
+	var images []string
+	for _, image := range b.InvocationImages {
+		if ref := normalize(image.Image); ref != "" {
+			images = append(images, ref)
+		}
+	}
+	for _, image := range b.Images {
+		if !cnab.IsContainerImage(image.BaseImage) {
+			continue
+		}
+		if ref := normalize(image.Image); ref != "" {
+			images = append(images, ref)
+		}
+	}
+	return images
+}
+
+// normalize returns the normalized form of a reference, with the default tag
+// if it has none, or an empty string if it is invalid.
+func normalize(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ""
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+func familiar(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ref
+	}
+	return reference.FamiliarString(named)
+}
@@ -0,0 +1,201 @@
+package gc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+type fakeEngine struct {
+	images  []types.ImageSummary
+	inUse   map[string]bool
+	removed []string
+}
+
+func (e *fakeEngine) ImageList(context.Context, types.ImageListOptions) ([]types.ImageSummary, error) {
+	return e.images, nil
+}
+
+func (e *fakeEngine) ImageRemove(_ context.Context, image string, _ types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error) {
+	if e.inUse[image] {
+		return nil, errdefs.Conflict(errors.Errorf("image %s is used by a container", image))
+	}
+	e.removed = append(e.removed, image)
+	return []types.ImageDeleteResponseItem{{Untagged: image}}, nil
+}
+
+func testBundle(version string, images ...string) *bundle.Bundle {
+	b := &bundle.Bundle{
+		Name:             "app",
+		Version:          version,
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app-installer:" + version}}},
+		Images:           map[string]bundle.Image{},
+	}
+	for _, image := range images {
+		b.Images[image] = bundle.Image{BaseImage: bundle.BaseImage{ImageType: "docker", Image: image}}
+	}
+	return b
+}
+
+type fixture struct {
+	installations store.InstallationStore
+	bundles       store.BundleStore
+	cache         *store.BundleCache
+	engine        *fakeEngine
+	digests       map[string]string
+}
+
+// newFixture stores the bundles 1.0.0, installed then upgraded to 1.1.0, and
+// 2.0.0 which is not installed, in both the bundle store and the cache.
+func newFixture(t *testing.T, dir *fs.Dir) fixture {
+	t.Helper()
+	appstore, err := store.NewApplicationStore(dir.Path())
+	assert.NilError(t, err)
+	f := fixture{
+		installations: store.NewMemoryInstallationStore(),
+		digests:       map[string]string{},
+		engine: &fakeEngine{images: []types.ImageSummary{
+			{ID: "sha256:installer1", RepoTags: []string{"app-installer:1.0.0"}, Size: 100},
+			{ID: "sha256:installer11", RepoTags: []string{"app-installer:1.1.0"}, Size: 110},
+			{ID: "sha256:installer2", RepoTags: []string{"app-installer:2.0.0"}, Size: 200},
+			{ID: "sha256:nginx", RepoTags: []string{"nginx:1.17", "nginx:stable"}, Size: 50},
+			{ID: "sha256:redis", RepoTags: []string{"redis:5"}, Size: 30},
+			{ID: "sha256:other", RepoTags: []string{"busybox:latest"}, Size: 1},
+		}},
+	}
+	f.bundles, err = appstore.BundleStore()
+	assert.NilError(t, err)
+	f.cache, err = appstore.BundleCache(0)
+	assert.NilError(t, err)
+	for _, b := range []*bundle.Bundle{testBundle("1.0.0", "redis:5"), testBundle("1.1.0"), testBundle("2.0.0", "nginx:1.17", "redis:5")} {
+		ref, err := reference.ParseNormalizedNamed("my-app:" + b.Version)
+		assert.NilError(t, err)
+		assert.NilError(t, f.bundles.Store(ref, b))
+		d, err := f.cache.Put(b)
+		assert.NilError(t, err)
+		assert.NilError(t, f.cache.PutImages(d, bytes.NewBufferString("image layers")))
+		f.digests[b.Version] = d
+	}
+
+	installation, err := store.NewInstallation("my-installation", "my-app:1.0.0")
+	assert.NilError(t, err)
+	installation.Bundle = testBundle("1.0.0", "redis:5")
+	installation.Update(claim.ActionInstall, claim.StatusSuccess)
+	assert.NilError(t, f.installations.Store(installation))
+	// The upgraded bundle was built locally, not pulled by reference
+	installation.Bundle = testBundle("1.1.0")
+	installation.Reference = ""
+	installation.Update(claim.ActionUpgrade, claim.StatusSuccess)
+	assert.NilError(t, f.installations.Store(installation))
+	return f
+}
+
+func (f fixture) collector(dryRun bool) *Collector {
+	return &Collector{
+		Installations: []store.InstallationStore{f.installations},
+		Bundles:       f.bundles,
+		Cache:         f.cache,
+		Engine:        f.engine,
+		DryRun:        dryRun,
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	f := newFixture(t, dir)
+
+	report, err := f.collector(false).Collect(context.Background())
+	assert.NilError(t, err)
+	var names []string
+	for _, item := range report.Removed {
+		names = append(names, string(item.Kind)+" "+item.Name)
+	}
+	assert.Check(t, is.DeepEqual(names, []string{
+		"bundle my-app:2.0.0",
+		"cached-bundle " + f.digests["2.0.0"],
+		"image app-installer:2.0.0",
+		"image nginx:1.17",
+	}))
+	// The layers of nginx are kept by its other tag
+	assert.Check(t, is.Equal(report.Removed[3].Size, int64(0)))
+	assert.Check(t, report.Size() > 200)
+	assert.Check(t, is.DeepEqual(f.engine.removed, []string{"docker.io/library/app-installer:2.0.0", "docker.io/library/nginx:1.17"}))
+
+	stored, err := f.bundles.List()
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(stored, 2))
+	assert.Check(t, is.Equal(reference.FamiliarString(stored[0].Ref), "my-app:1.0.0"))
+	assert.Check(t, is.Equal(reference.FamiliarString(stored[1].Ref), "my-app:1.1.0"))
+	cached, err := f.cache.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(cached, 2))
+
+	// Nothing is left to collect
+	report, err = f.collector(false).Collect(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(report.Removed, 0))
+}
+
+func TestCollectDryRun(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	f := newFixture(t, dir)
+
+	report, err := f.collector(true).Collect(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, report.DryRun)
+	assert.Check(t, is.Len(report.Removed, 4))
+	assert.Check(t, is.Len(f.engine.removed, 0))
+	stored, err := f.bundles.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(stored, 3))
+	cached, err := f.cache.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(cached, 3))
+}
+
+func TestCollectSkipsImagesInUse(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	f := newFixture(t, dir)
+	f.engine.inUse = map[string]bool{"docker.io/library/app-installer:2.0.0": true}
+
+	report, err := f.collector(false).Collect(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(report.Skipped, []Item{{Kind: KindImage, Name: "app-installer:2.0.0"}}))
+	assert.Check(t, is.DeepEqual(f.engine.removed, []string{"docker.io/library/nginx:1.17"}))
+}
+
+func TestCollectKeepsEverythingOnStoreErrors(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	f := newFixture(t, dir)
+	collector := f.collector(false)
+	collector.Installations = append(collector.Installations, failingStore{f.installations})
+
+	_, err := collector.Collect(context.Background())
+	assert.Check(t, is.ErrorContains(err, "failed to list installations"))
+	stored, err := f.bundles.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(stored, 3))
+}
+
+type failingStore struct {
+	store.InstallationStore
+}
+
+func (failingStore) List() ([]string, error) {
+	return nil, errors.New("permission denied")
+}
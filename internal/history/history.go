@@ -0,0 +1,103 @@
+// Package history lists the revisions of installations and rolls them back
+// to a previous revision.
+package history
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/store"
+)
+
+// Entry is a revision of an installation.
+type Entry struct {
+	Revision string    `json:"revision"`
+	Action   string    `json:"action"`
+	Status   string    `json:"status"`
+	Modified time.Time `json:"modified"`
+	// Reference is the reference of the bundle the revision ran.
+	Reference string `json:"reference,omitempty"`
+	// BundleDigest is the digest of the canonical bundle the revision ran,
+	// see cnab.Digest.
+	BundleDigest string                 `json:"bundleDigest,omitempty"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	// Rollback is set if the revision rolled back to a previous revision.
+	Rollback *store.Rollback `json:"rollback,omitempty"`
+}
+
+// Get returns the revisions of an installation, oldest first.
+func Get(installations store.InstallationStore, installationName string) ([]Entry, error) {
+	revisions, err := installations.Revisions(installationName)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("Installation %q not found", installationName)
+	}
+	entries := make([]Entry, len(revisions))
+	for i, revision := range revisions {
+		entries[i] = Entry{
+			Revision:   revision.Revision,
+			Action:     revision.Result.Action,
+			Status:     revision.Result.Status,
+			Modified:   revision.Modified,
+			Reference:  revision.Reference,
+			Parameters: revision.Parameters,
+			Rollback:   revision.Rollback,
+		}
+		if revision.Bundle != nil {
+			if entries[i].BundleDigest, err = cnab.Digest(revision.Bundle); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Rollback prepares the upgrade of an installation with the bundle and
+// parameters of one of its previous successful revisions. The upgrade is run
+// like any other, by the action runner.
+type Rollback struct {
+	Installations store.InstallationStore
+}
+
+// Prepare reads the installation and restores the bundle and parameters of
+// the target revision on it, recording which revision is reverted.
+func (r *Rollback) Prepare(installationName, revision string) (*store.Installation, error) {
+	installation, err := r.Installations.Read(installationName)
+	if err != nil {
+		return nil, err
+	}
+	target, err := r.Installations.ReadRevision(installationName, revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := Restore(installation, target); err != nil {
+		return nil, err
+	}
+	return installation, nil
+}
+
+// Restore sets the bundle and parameters of the target revision on the
+// installation, which must be a previous successful revision.
+func Restore(installation, target *store.Installation) error {
+	if target.Revision == installation.Revision {
+		return fmt.Errorf("Installation %q is already at revision %q", installation.Name, target.Revision)
+	}
+	if target.Result.Status != claim.StatusSuccess {
+		return fmt.Errorf("Revision %q of installation %q is not a successful %s, it cannot be rolled back to", target.Revision, installation.Name, target.Result.Action)
+	}
+	installation.Bundle = target.Bundle
+	installation.Parameters = target.Parameters
+	installation.Reference = target.Reference
+	// The outputs of the current revision are replaced by those of the
+	// rollback once it succeeded
+	installation.Outputs = nil
+	installation.Rollback = &store.Rollback{
+		From: installation.Revision,
+		To:   target.Revision,
+	}
+	return nil
+}
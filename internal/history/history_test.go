@@ -0,0 +1,93 @@
+package history
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/internal/runner"
+	"github.com/docker/app/internal/store"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestRestore(t *testing.T) {
+	target, err := store.NewInstallation("my-installation", "my-app:1.0.0")
+	assert.NilError(t, err)
+	target.Bundle = &bundle.Bundle{Name: "my-app", Version: "1.0.0"}
+	target.Parameters = map[string]interface{}{"port": "8080"}
+	target.Update(claim.ActionInstall, claim.StatusSuccess)
+
+	current := *target
+	current.Reference = "my-app:2.0.0"
+	current.Bundle = &bundle.Bundle{Name: "my-app", Version: "2.0.0"}
+	current.Parameters = map[string]interface{}{"port": "9090"}
+	current.Update(claim.ActionUpgrade, claim.StatusFailure)
+	failedRevision := current.Revision
+
+	assert.NilError(t, Restore(&current, target))
+	assert.Equal(t, current.Reference, "my-app:1.0.0")
+	assert.Equal(t, current.Bundle.Version, "1.0.0")
+	assert.DeepEqual(t, current.Parameters, map[string]interface{}{"port": "8080"})
+	assert.DeepEqual(t, current.Rollback, &store.Rollback{From: failedRevision, To: target.Revision})
+
+	err = Restore(target, target)
+	assert.Check(t, is.ErrorContains(err, "is already at revision"))
+
+	failed := current
+	failed.Update(claim.ActionUpgrade, claim.StatusFailure)
+	err = Restore(target, &failed)
+	assert.Check(t, is.ErrorContains(err, "is not a successful upgrade, it cannot be rolled back to"))
+}
+
+func TestGetAndRollback(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	installation, err := store.NewInstallation("my-installation", "my-app:1.0.0")
+	assert.NilError(t, err)
+	installation.Bundle = &bundle.Bundle{
+		Name:             "my-app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "my-app:1.0.0"}}},
+		Parameters:       map[string]bundle.ParameterDefinition{"port": {DataType: "string"}},
+	}
+	installation.Parameters = map[string]interface{}{"port": "8080"}
+	installation.Update(claim.ActionInstall, claim.StatusSuccess)
+	assert.NilError(t, installations.Store(installation))
+	installed := installation.Revision
+
+	upgraded := *installation
+	upgraded.Bundle = &bundle.Bundle{Name: "my-app", Version: "2.0.0"}
+	upgraded.Parameters = map[string]interface{}{"port": "9090"}
+	upgraded.Outputs = map[string]string{"url": "http://localhost:9090"}
+	upgraded.Update(claim.ActionUpgrade, claim.StatusFailure)
+	assert.NilError(t, installations.Store(&upgraded))
+
+	entries, err := Get(installations, "my-installation")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(entries, 2))
+	assert.Check(t, is.Equal(entries[0].Revision, installed))
+	assert.Check(t, is.Equal(entries[1].Status, claim.StatusFailure))
+	assert.Check(t, entries[0].BundleDigest != entries[1].BundleDigest)
+	_, err = Get(installations, "unknown")
+	assert.Check(t, is.Error(err, `Installation "unknown" not found`))
+
+	rollback := &Rollback{Installations: installations}
+	rolledBack, err := rollback.Prepare("my-installation", installed)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(rolledBack.Outputs, 0))
+	d := fake.New()
+	r := &runner.Runner{Installations: installations, Driver: d}
+	assert.NilError(t, r.Run(context.Background(), rolledBack, claim.ActionUpgrade, nil, ioutil.Discard))
+	op, _ := d.LastOperation()
+	assert.Check(t, is.Equal(op.Action, claim.ActionUpgrade))
+	assert.Check(t, is.Equal(op.Environment["CNAB_P_PORT"], "8080"))
+
+	entries, err = Get(installations, "my-installation")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(entries, 3))
+	assert.Check(t, is.Equal(entries[2].BundleDigest, entries[0].BundleDigest))
+	assert.Check(t, is.Equal(entries[2].Rollback.To, installed))
+}
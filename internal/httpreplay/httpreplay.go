@@ -0,0 +1,188 @@
+// Package httpreplay records HTTP exchanges, such as registry manifest
+// fetches or tag listings, to a fixture file and replays them offline.
+package httpreplay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// RecordEnvVar switches ModeFromEnv to recording when set to a non-empty
+// value, so fixtures can be refreshed without changing the tests.
+const RecordEnvVar = "DOCKER_APP_HTTP_RECORD"
+
+// Mode selects whether exchanges are recorded or replayed.
+type Mode int
+
+const (
+	// Replay serves the responses from the fixture, without any network access.
+	Replay Mode = iota
+	// Record forwards the requests and saves the exchanges to the fixture.
+	Record
+)
+
+// ModeFromEnv returns Record if RecordEnvVar is set, Replay otherwise.
+func ModeFromEnv() Mode {
+	if os.Getenv(RecordEnvVar) != "" {
+		return Record
+	}
+	return Replay
+}
+
+// sensitiveHeaders are never written to fixtures.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	// Body holds text bodies, BinaryBody base64 encodes the other ones.
+	Body       string `json:"body,omitempty"`
+	BinaryBody string `json:"binaryBody,omitempty"`
+}
+
+// Transport is an http.RoundTripper recording or replaying interactions.
+type Transport struct {
+	mode         Mode
+	path         string
+	base         http.RoundTripper
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewTransport returns a transport backed by the fixture at path. In Replay
+// mode the fixture must exist; in Record mode requests are sent with base
+// (http.DefaultTransport if nil) and the fixture is written by Save.
+func NewTransport(path string, mode Mode, base http.RoundTripper) (*Transport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{mode: mode, path: path, base: base}
+	if mode == Record {
+		return t, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read HTTP fixture %q", path)
+	}
+	if err := json.Unmarshal(data, &t.interactions); err != nil {
+		return nil, errors.Wrapf(err, "failed to read HTTP fixture %q", path)
+	}
+	t.used = make([]bool, len(t.interactions))
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == Record {
+		return t.record(req)
+	}
+	return t.replay(req)
+}
+
+// Save writes the recorded interactions to the fixture. It is a no-op when
+// replaying.
+func (t *Transport) Save() error {
+	if t.mode != Record {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to write HTTP fixture %q", t.path)
+	}
+	err = ioutil.WriteFile(t.path, data, 0644)
+	return errors.Wrapf(err, "failed to write HTTP fixture %q", t.path)
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	interaction := Interaction{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     cloneHeader(resp.Header),
+	}
+	if utf8.Valid(body) {
+		interaction.Body = string(body)
+	} else {
+		interaction.BinaryBody = base64.StdEncoding.EncodeToString(body)
+	}
+	t.mu.Lock()
+	t.interactions = append(t.interactions, interaction)
+	t.mu.Unlock()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// replay serves the first unused interaction matching the request method and
+// URL, so repeated requests get their responses in the recorded order.
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	url := req.URL.String()
+	for i, interaction := range t.interactions {
+		if t.used[i] || interaction.Method != req.Method || interaction.URL != url {
+			continue
+		}
+		t.used[i] = true
+		body := []byte(interaction.Body)
+		if interaction.BinaryBody != "" {
+			decoded, err := base64.StdEncoding.DecodeString(interaction.BinaryBody)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid body recorded for %s %s", req.Method, url)
+			}
+			body = decoded
+		}
+		header := interaction.Header
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        http.StatusText(interaction.StatusCode),
+			StatusCode:    interaction.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        cloneHeader(header),
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, errors.Errorf("no recorded interaction for %s %s in %q", req.Method, url, t.path)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := http.Header{}
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	for _, k := range sensitiveHeaders {
+		c.Del(k)
+	}
+	return c
+}
@@ -0,0 +1,80 @@
+package httpreplay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	return resp, string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Docker-Content-Digest", "sha256:abcd")
+		w.Header().Set("Set-Cookie", "session=secret")
+		switch r.URL.Path {
+		case "/v2/repo/tags/list":
+			w.Write([]byte(`{"name":"repo","tags":["1.0.0"]}`))
+		case "/v2/repo/blobs/sha256:abcd":
+			w.Write([]byte{0xff, 0x00, 0xfe})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	fixture := dir.Join("registry.json")
+
+	recorder, err := NewTransport(fixture, Record, nil)
+	assert.NilError(t, err)
+	client := &http.Client{Transport: recorder}
+	_, tags := get(t, client, server.URL+"/v2/repo/tags/list")
+	assert.Equal(t, tags, `{"name":"repo","tags":["1.0.0"]}`)
+	get(t, client, server.URL+"/v2/repo/blobs/sha256:abcd")
+	get(t, client, server.URL+"/v2/repo/manifests/unknown")
+	assert.NilError(t, recorder.Save())
+	assert.Equal(t, calls, 3)
+
+	fixtureData, err := ioutil.ReadFile(fixture)
+	assert.NilError(t, err)
+	assert.Check(t, !strings.Contains(string(fixtureData), "session=secret"))
+
+	server.Close()
+	replayer, err := NewTransport(fixture, Replay, nil)
+	assert.NilError(t, err)
+	client = &http.Client{Transport: replayer}
+	resp, tags := get(t, client, server.URL+"/v2/repo/tags/list")
+	assert.Equal(t, tags, `{"name":"repo","tags":["1.0.0"]}`)
+	assert.Equal(t, resp.Header.Get("Docker-Content-Digest"), "sha256:abcd")
+	_, blob := get(t, client, server.URL+"/v2/repo/blobs/sha256:abcd")
+	assert.Equal(t, blob, "\xff\x00\xfe")
+	resp, _ = get(t, client, server.URL+"/v2/repo/manifests/unknown")
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	assert.Equal(t, calls, 3)
+
+	// Each recorded interaction is replayed once
+	_, err = client.Get(server.URL + "/v2/repo/tags/list")
+	assert.Check(t, is.ErrorContains(err, "no recorded interaction for GET"))
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	_, err := NewTransport("testdata/missing.json", Replay, nil)
+	assert.Check(t, is.ErrorContains(err, "failed to read HTTP fixture"))
+}
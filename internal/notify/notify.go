@@ -0,0 +1,186 @@
+// Package notify sends notifications when actions complete on installations,
+// so failures are noticed without polling the installations.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/pkg/errors"
+)
+
+// DefaultTimeout bounds the requests sending the notifications, when the
+// senders have no client.
+const DefaultTimeout = 10 * time.Second
+
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// Event describes a completed action.
+type Event struct {
+	Time          time.Time     `json:"time"`
+	Action        string        `json:"action"`
+	Installation  string        `json:"installation"`
+	Context       string        `json:"context,omitempty"`
+	Bundle        string        `json:"bundle,omitempty"`
+	BundleVersion string        `json:"bundleVersion,omitempty"`
+	Status        string        `json:"status"`
+	Message       string        `json:"message,omitempty"`
+	Duration      time.Duration `json:"duration"`
+}
+
+// NewEvent returns the event of an action started at start.
+func NewEvent(action, installation string, bndl *bundle.Bundle, result claim.Result, start time.Time) Event {
+	e := Event{
+		Time:         time.Now().UTC(),
+		Action:       action,
+		Installation: installation,
+		Status:       result.Status,
+		Message:      result.Message,
+		Duration:     time.Since(start),
+	}
+	if bndl != nil {
+		e.Bundle = bndl.Name
+		e.BundleVersion = bndl.Version
+	}
+	return e
+}
+
+// Failed returns true if the action failed.
+func (e Event) Failed() bool {
+	return e.Status == claim.StatusFailure
+}
+
+// Sender delivers events.
+type Sender interface {
+	Send(ctx context.Context, e Event) error
+}
+
+// DefaultTextTemplate is the message sent to chat services.
+const DefaultTextTemplate = `{{ .Action }} of {{ .Installation }}{{ if .Bundle }} ({{ .Bundle }} {{ .BundleVersion }}){{ end }}{{ if .Context }} on {{ .Context }}{{ end }}: {{ .Status }} after {{ .Duration }}{{ if .Message }}: {{ .Message }}{{ end }}`
+
+// ParseTemplate parses a payload template. Templates are executed with an
+// Event.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("notification").Funcs(template.FuncMap{
+		"upper": strings.ToUpper,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// Webhook posts events to an HTTP endpoint, as JSON or rendered with a
+// template.
+type Webhook struct {
+	URL string
+	// Template renders the request body. If nil, the event is sent as JSON.
+	Template *template.Template
+	// Client defaults to a client timing out after DefaultTimeout.
+	Client *http.Client
+}
+
+// Send implements Sender.
+func (w *Webhook) Send(ctx context.Context, e Event) error {
+	var body []byte
+	var err error
+	if w.Template != nil {
+		body, err = render(w.Template, e)
+	} else {
+		body, err = json.Marshal(e)
+	}
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.Client, w.URL, body)
+}
+
+// Slack posts events to a Slack compatible incoming webhook.
+type Slack struct {
+	URL string
+	// Template renders the message text, DefaultTextTemplate if nil.
+	Template *template.Template
+	Client   *http.Client
+}
+
+// Send implements Sender.
+func (s *Slack) Send(ctx context.Context, e Event) error {
+	tmpl := s.Template
+	if tmpl == nil {
+		tmpl = template.Must(ParseTemplate(DefaultTextTemplate))
+	}
+	text, err := render(tmpl, e)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"text": string(text)})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, s.URL, body)
+}
+
+func render(tmpl *template.Template, e Event) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buf, e); err != nil {
+		return nil, errors.Wrap(err, "failed to render notification")
+	}
+	return buf.Bytes(), nil
+}
+
+func post(ctx context.Context, client *http.Client, url string, body []byte) error {
+	if client == nil {
+		client = defaultClient
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send notification to %q", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to send notification to %q: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// Notifier sends events to several senders.
+type Notifier struct {
+	Senders []Sender
+	// OnlyFailures skips the events of successful actions.
+	OnlyFailures bool
+}
+
+// Enabled returns true if the notifier has any sender.
+func (n *Notifier) Enabled() bool {
+	return len(n.Senders) > 0
+}
+
+// Notify sends the event to every sender, even if some fail.
+func (n *Notifier) Notify(ctx context.Context, e Event) error {
+	if n.OnlyFailures && !e.Failed() {
+		return nil
+	}
+	var errs []string
+	for _, s := range n.Senders {
+		if err := s.Send(ctx, e); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func recorder(t *testing.T, status int) (*httptest.Server, *[]string) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, is.Equal(r.Header.Get("Content-Type"), "application/json"))
+		data, err := ioutil.ReadAll(r.Body)
+		assert.Check(t, err)
+		bodies = append(bodies, string(data))
+		w.WriteHeader(status)
+	}))
+	return server, &bodies
+}
+
+func testEvent(status string) Event {
+	e := NewEvent(claim.ActionUpgrade, "my-installation", &bundle.Bundle{Name: "my-app", Version: "1.2.0"},
+		claim.Result{Status: status, Message: "oops"}, time.Now().Add(-3*time.Second))
+	e.Context = "prod"
+	e.Duration = 3 * time.Second
+	return e
+}
+
+func TestWebhook(t *testing.T) {
+	server, bodies := recorder(t, http.StatusOK)
+	defer server.Close()
+
+	assert.NilError(t, (&Webhook{URL: server.URL}).Send(context.Background(), testEvent(claim.StatusFailure)))
+	var decoded Event
+	assert.NilError(t, json.Unmarshal([]byte((*bodies)[0]), &decoded))
+	assert.Equal(t, decoded.Installation, "my-installation")
+	assert.Equal(t, decoded.Duration, 3*time.Second)
+
+	tmpl, err := ParseTemplate(`{"summary":{{ json .Installation }},"status":"{{ upper .Status }}"}`)
+	assert.NilError(t, err)
+	assert.NilError(t, (&Webhook{URL: server.URL, Template: tmpl}).Send(context.Background(), testEvent(claim.StatusFailure)))
+	assert.Equal(t, (*bodies)[1], `{"summary":"my-installation","status":"FAILURE"}`)
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	assert.Check(t, is.Equal(defaultClient.Timeout, DefaultTimeout))
+	defer func(c *http.Client) { defaultClient = c }(defaultClient)
+	defaultClient = &http.Client{Timeout: 10 * time.Millisecond}
+
+	err := (&Webhook{URL: server.URL}).Send(context.Background(), testEvent(claim.StatusFailure))
+	assert.Check(t, is.ErrorContains(err, "Client.Timeout exceeded"))
+}
+
+func TestSlack(t *testing.T) {
+	server, bodies := recorder(t, http.StatusOK)
+	defer server.Close()
+	assert.NilError(t, (&Slack{URL: server.URL}).Send(context.Background(), testEvent(claim.StatusFailure)))
+	assert.Equal(t, (*bodies)[0], `{"text":"upgrade of my-installation (my-app 1.2.0) on prod: failure after 3s: oops"}`)
+}
+
+func TestNotifier(t *testing.T) {
+	server, bodies := recorder(t, http.StatusOK)
+	defer server.Close()
+	failing, _ := recorder(t, http.StatusInternalServerError)
+	defer failing.Close()
+
+	n := &Notifier{Senders: []Sender{&Webhook{URL: failing.URL}, &Webhook{URL: server.URL}}, OnlyFailures: true}
+	assert.Assert(t, n.Enabled())
+	assert.NilError(t, n.Notify(context.Background(), testEvent(claim.StatusSuccess)))
+	assert.Equal(t, len(*bodies), 0)
+
+	err := n.Notify(context.Background(), testEvent(claim.StatusFailure))
+	assert.Check(t, is.ErrorContains(err, "500 Internal Server Error"))
+	assert.Equal(t, len(*bodies), 1)
+}
@@ -0,0 +1,57 @@
+// Package offline implements the offline mode, for environments without
+// network access. In offline mode the operations needing the network fail
+// immediately with an ErrOfflineMode, instead of timing out, unless they
+// can use local data instead.
+package offline
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvVar enables the offline mode when set to a true value.
+	EnvVar = "DOCKER_APP_OFFLINE"
+	// ConfigKey enables the offline mode when set to "true" in the "app"
+	// plugin section of the docker CLI configuration file.
+	ConfigKey = "offline"
+)
+
+// ErrOfflineMode is returned by the operations needing the network in
+// offline mode.
+type ErrOfflineMode struct {
+	// Operation describes what needed the network, like `pulling "my-app"`.
+	Operation string
+}
+
+func (e *ErrOfflineMode) Error() string {
+	return fmt.Sprintf("%s requires network access, which is disabled in offline mode", e.Operation)
+}
+
+// New returns an ErrOfflineMode for the operation.
+func New(format string, args ...interface{}) error {
+	return &ErrOfflineMode{Operation: fmt.Sprintf(format, args...)}
+}
+
+// IsOffline returns true if the cause of the error is an ErrOfflineMode.
+func IsOffline(err error) bool {
+	_, ok := errors.Cause(err).(*ErrOfflineMode)
+	return ok
+}
+
+// Enabled returns true if the offline mode is enabled by the environment or
+// by the docker CLI configuration file, which may be nil.
+func Enabled(cfg *configfile.ConfigFile) bool {
+	if enabled, err := strconv.ParseBool(os.Getenv(EnvVar)); err == nil {
+		return enabled
+	}
+	if cfg == nil {
+		return false
+	}
+	value, ok := cfg.PluginConfig("app", ConfigKey)
+	return ok && value == "true"
+}
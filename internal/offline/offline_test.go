@@ -0,0 +1,48 @@
+package offline
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func setEnv(t *testing.T, value string) func() {
+	t.Helper()
+	previous, wasSet := os.LookupEnv(EnvVar)
+	assert.NilError(t, os.Setenv(EnvVar, value))
+	return func() {
+		if wasSet {
+			os.Setenv(EnvVar, previous)
+		} else {
+			os.Unsetenv(EnvVar)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	restore := setEnv(t, "")
+	defer restore()
+	enabled := &configfile.ConfigFile{Plugins: map[string]map[string]string{"app": {ConfigKey: "true"}}}
+	assert.Check(t, !Enabled(nil))
+	assert.Check(t, !Enabled(&configfile.ConfigFile{}))
+	assert.Check(t, Enabled(enabled))
+
+	// The environment takes precedence over the configuration
+	assert.NilError(t, os.Setenv(EnvVar, "1"))
+	assert.Check(t, Enabled(nil))
+	assert.NilError(t, os.Setenv(EnvVar, "false"))
+	assert.Check(t, !Enabled(enabled))
+}
+
+func TestIsOffline(t *testing.T) {
+	err := New("pulling %q", "my-app")
+	assert.Check(t, is.Error(err, `pulling "my-app" requires network access, which is disabled in offline mode`))
+	assert.Check(t, IsOffline(err))
+	assert.Check(t, IsOffline(errors.Wrap(err, "failed")))
+	assert.Check(t, !IsOffline(errors.New("failed")))
+	assert.Check(t, !IsOffline(nil))
+}
@@ -0,0 +1,356 @@
+package packager
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/registryclient"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// The non-container artifacts of a thick bundle are stored as an OCI image
+// layout in the artifacts directory, their original references being
+// annotated in the index.
+const (
+	exportArtifactsDir    = "artifacts"
+	exportArtifactsIndex  = exportArtifactsDir + "/index.json"
+	exportArtifactsLayout = exportArtifactsDir + "/" + ocispec.ImageLayoutFile
+	exportArtifactsBlobs  = exportArtifactsDir + "/blobs"
+
+	// maxManifestSize bounds the size of the artifact manifests, read in
+	// memory.
+	maxManifestSize = 4 << 20
+)
+
+// ArtifactOptions are the options of Export and Import handling the
+// non-container artifacts of the bundles, like Helm charts, WASM modules or
+// plain blobs.
+type ArtifactOptions struct {
+	// Resolver pulls the artifacts on Export and pushes them on Import, as
+	// the engine cannot save nor load them. It defaults to a resolver
+	// reaching the registries anonymously, see defaultArtifactResolver.
+	Resolver remotes.Resolver
+	// Relocation maps the references of the artifacts to the ones they are
+	// pushed to on Import, instead of their original references.
+	Relocation cnab.RelocationMap
+}
+
+// defaultArtifactResolver returns the resolver of the artifacts when the
+// options have none. It is replaced by tests.
+var defaultArtifactResolver = func() remotes.Resolver {
+	return registryclient.New(registryclient.Options{Retry: registryclient.DefaultRetryPolicy}).Resolver()
+}
+
+func artifactOptions(opts []func(*ArtifactOptions)) ArtifactOptions {
+	var o ArtifactOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Resolver == nil {
+		o.Resolver = defaultArtifactResolver()
+	}
+	return o
+}
+
+// WithArtifactResolver sets the resolver pulling and pushing the artifacts.
+func WithArtifactResolver(resolver remotes.Resolver) func(*ArtifactOptions) {
+	return func(o *ArtifactOptions) {
+		o.Resolver = resolver
+	}
+}
+
+// WithRelocation pushes the artifacts to their relocated references on
+// Import.
+func WithRelocation(m cnab.RelocationMap) func(*ArtifactOptions) {
+	return func(o *ArtifactOptions) {
+		o.Relocation = m
+	}
+}
+
+// bundleArtifacts returns the images of a bundle which are not container
+// images, sorted by reference.
+func bundleArtifacts(b *bundle.Bundle) []bundle.BaseImage {
+	seen := map[string]bool{}
+	var artifacts []bundle.BaseImage
+	for _, name := range cnab.ImageKeys(b) {
+		image := b.Images[name].BaseImage
+		if !cnab.IsContainerImage(image) && !seen[image.Image] {
+			seen[image.Image] = true
+			artifacts = append(artifacts, image)
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Image < artifacts[j].Image })
+	return artifacts
+}
+
+// exportArtifacts pulls the artifacts and writes their blobs and the index
+// of the OCI image layout.
+func exportArtifacts(ctx context.Context, artifacts []bundle.BaseImage, resolver remotes.Resolver, tarout *tar.Writer) error {
+	written := map[digest.Digest]bool{}
+	index := ocispec.Index{Versioned: specs.Versioned{SchemaVersion: 2}}
+	for _, artifact := range artifacts {
+		desc, err := exportArtifact(ctx, artifact, resolver, tarout, written)
+		if err != nil {
+			return errors.Wrapf(err, "failed to export artifact %q", artifact.Image)
+		}
+		desc.Annotations = map[string]string{ocispec.AnnotationRefName: artifact.Image}
+		index.Manifests = append(index.Manifests, desc)
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := tarAddBytes(tarout, exportArtifactsLayout, []byte(`{"imageLayoutVersion":"`+ocispec.ImageLayoutVersion+`"}`)); err != nil {
+		return err
+	}
+	return tarAddBytes(tarout, exportArtifactsIndex, data)
+}
+
+func exportArtifact(ctx context.Context, artifact bundle.BaseImage, resolver remotes.Resolver, tarout *tar.Writer, written map[digest.Digest]bool) (ocispec.Descriptor, error) {
+	ref, err := cnab.ArtifactReference(artifact)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	_, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	fetcher, err := resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	children, err := artifactChildren(desc, func() ([]byte, error) {
+		return fetchManifest(ctx, fetcher, desc)
+	})
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	for _, child := range append(children, desc) {
+		if written[child.Digest] {
+			continue
+		}
+		if err := tarAddBlob(ctx, tarout, fetcher, child); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		written[child.Digest] = true
+	}
+	return desc, nil
+}
+
+// artifactChildren returns the config and layers of an artifact manifest,
+// or nothing for a plain blob. Indexes are not supported.
+func artifactChildren(desc ocispec.Descriptor, read func() ([]byte, error)) ([]ocispec.Descriptor, error) {
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageManifest, images.MediaTypeDockerSchema2Manifest:
+	case ocispec.MediaTypeImageIndex, images.MediaTypeDockerSchema2ManifestList:
+		return nil, errors.Errorf("%s is an index, which is not supported for artifacts", desc.Digest)
+	default:
+		return nil, nil
+	}
+	data, err := read()
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest %s", desc.Digest)
+	}
+	return append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...), nil
+}
+
+func fetchManifest(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	if desc.Size > maxManifestSize {
+		return nil, errors.Errorf("manifest %s is too large", desc.Digest)
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != desc.Size || digest.FromBytes(data) != desc.Digest {
+		return nil, errors.Errorf("manifest %s does not match its descriptor", desc.Digest)
+	}
+	return data, nil
+}
+
+// tarAddBlob streams a blob to the OCI image layout, verifying its digest.
+func tarAddBlob(ctx context.Context, tarout *tar.Writer, fetcher remotes.Fetcher, desc ocispec.Descriptor) error {
+	if err := desc.Digest.Validate(); err != nil {
+		return err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := tarout.WriteHeader(&tar.Header{
+		Name:     blobPath(desc.Digest),
+		Size:     desc.Size,
+		Mode:     0644,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	verifier := desc.Digest.Verifier()
+	n, err := io.Copy(io.MultiWriter(tarout, verifier), io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return err
+	}
+	if n != desc.Size || !verifier.Verified() {
+		return errors.Errorf("blob %s does not match its descriptor", desc.Digest)
+	}
+	return nil
+}
+
+func blobPath(d digest.Digest) string {
+	return path.Join(exportArtifactsBlobs, d.Algorithm().String(), d.Hex())
+}
+
+// importedArtifacts holds the artifacts read from a thick bundle, their
+// blobs being extracted to a temporary directory.
+type importedArtifacts struct {
+	dir   string
+	index *ocispec.Index
+}
+
+func (a *importedArtifacts) close() {
+	if a.dir != "" {
+		os.RemoveAll(a.dir)
+	}
+}
+
+// read stores an entry of the artifacts directory, returning false if the
+// entry is not part of it.
+func (a *importedArtifacts) read(header *tar.Header, r io.Reader) (bool, error) {
+	if !strings.HasPrefix(header.Name, exportArtifactsDir+"/") {
+		return false, nil
+	}
+	switch {
+	case header.Typeflag != tar.TypeReg || header.Name == exportArtifactsLayout:
+	case header.Name == exportArtifactsIndex:
+		var index ocispec.Index
+		if err := json.NewDecoder(io.LimitReader(r, maxManifestSize)).Decode(&index); err != nil {
+			return true, errors.Wrap(err, "invalid thick bundle artifacts index")
+		}
+		a.index = &index
+		return true, nil
+	case path.Dir(path.Dir(header.Name)) == exportArtifactsBlobs:
+		d := digest.NewDigestFromHex(path.Base(path.Dir(header.Name)), path.Base(header.Name))
+		if err := d.Validate(); err != nil || blobPath(d) != header.Name {
+			return true, errors.Errorf("invalid thick bundle artifact blob %q", header.Name)
+		}
+		if a.dir == "" {
+			dir, err := ioutil.TempDir("", "docker-app-import")
+			if err != nil {
+				return true, err
+			}
+			a.dir = dir
+		}
+		f, err := os.Create(filepath.Join(a.dir, d.Hex()))
+		if err != nil {
+			return true, err
+		}
+		defer f.Close()
+		verifier := d.Verifier()
+		if _, err := io.Copy(io.MultiWriter(f, verifier), r); err != nil {
+			return true, err
+		}
+		if !verifier.Verified() {
+			return true, errors.Errorf("thick bundle artifact blob %q does not match its digest", header.Name)
+		}
+	}
+	return true, nil
+}
+
+// push pushes the artifacts to their original or relocated references.
+func (a *importedArtifacts) push(ctx context.Context, opts ArtifactOptions) error {
+	if a.index == nil || len(a.index.Manifests) == 0 {
+		return nil
+	}
+	for _, desc := range a.index.Manifests {
+		ref := desc.Annotations[ocispec.AnnotationRefName]
+		if target, ok := opts.Relocation[ref]; ok {
+			ref = target
+		}
+		// The reference annotation only belongs to the layout
+		desc.Annotations = nil
+		if err := a.pushArtifact(ctx, opts.Resolver, ref, desc); err != nil {
+			return errors.Wrapf(err, "failed to push artifact %q", ref)
+		}
+	}
+	return nil
+}
+
+func (a *importedArtifacts) pushArtifact(ctx context.Context, resolver remotes.Resolver, ref string, desc ocispec.Descriptor) error {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return err
+	}
+	pusher, err := resolver.Pusher(ctx, reference.TagNameOnly(named).String())
+	if err != nil {
+		return err
+	}
+	children, err := artifactChildren(desc, func() ([]byte, error) {
+		return ioutil.ReadFile(a.blobFile(desc.Digest))
+	})
+	if err != nil {
+		return err
+	}
+	for _, child := range append(children, desc) {
+		if err := a.pushBlob(ctx, pusher, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *importedArtifacts) pushBlob(ctx context.Context, pusher remotes.Pusher, desc ocispec.Descriptor) error {
+	f, err := os.Open(a.blobFile(desc.Digest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("invalid thick bundle: missing artifact blob %s", desc.Digest)
+		}
+		return err
+	}
+	defer f.Close()
+	w, err := pusher.Push(ctx, desc)
+	if errdefs.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Commit(ctx, desc.Size, desc.Digest)
+}
+
+func (a *importedArtifacts) blobFile(d digest.Digest) string {
+	if a.dir == "" {
+		return ""
+	}
+	return filepath.Join(a.dir, d.Hex())
+}
@@ -0,0 +1,193 @@
+package packager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	exportBundleFile = "bundle.json"
+	exportImagesFile = "images.tar"
+)
+
+// ImageSaveLoader saves images from, and loads images into, a Docker engine.
+// It is implemented by the Docker API client.
+type ImageSaveLoader interface {
+	ImageSave(ctx context.Context, images []string) (io.ReadCloser, error)
+	ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
+}
+
+// Export writes a thick bundle, a gzipped tarball holding the bundle and the
+// saved layers of its invocation images and component images, so it can be
+// moved to an air-gapped environment. The container images must be present
+// in the engine. The other artifacts, like Helm charts or WASM modules, are
+// pulled with the resolver of the options, anonymously if it has none.
+func Export(ctx context.Context, b *bundle.Bundle, engine ImageSaveLoader, w io.Writer, opts ...func(*ArtifactOptions)) error {
+	o := artifactOptions(opts)
+	artifacts := bundleArtifacts(b)
+	data, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return err
+	}
+	images, err := engine.ImageSave(ctx, bundleImages(b))
+	if err != nil {
+		return errors.Wrap(err, "failed to save bundle images")
+	}
+	defer images.Close()
+	// The images are buffered to a temporary file, as the tar header
+	// needs their size.
+	tmp, err := ioutil.TempFile("", "docker-app-export")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, images)
+	if err != nil {
+		return errors.Wrap(err, "failed to save bundle images")
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tarout := tar.NewWriter(gz)
+	if err := tarAddBytes(tarout, exportBundleFile, data); err != nil {
+		return err
+	}
+	if err := tarout.WriteHeader(&tar.Header{
+		Name:     exportImagesFile,
+		Size:     size,
+		Mode:     0644,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tarout, tmp); err != nil {
+		return err
+	}
+	if len(artifacts) > 0 {
+		if err := exportArtifacts(ctx, artifacts, o.Resolver, tarout); err != nil {
+			return err
+		}
+	}
+	if err := tarout.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Import reads a thick bundle written by Export, loads its images into the
+// engine, from where they can be pushed to a local registry, and returns the
+// bundle. The other artifacts are pushed with the resolver of the options,
+// to their relocated references if any, anonymously if the options have no
+// resolver.
+func Import(ctx context.Context, r io.Reader, engine ImageSaveLoader, opts ...func(*ArtifactOptions)) (*bundle.Bundle, error) {
+	o := artifactOptions(opts)
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid thick bundle")
+	}
+	defer gz.Close()
+	var (
+		b         *bundle.Bundle
+		loaded    bool
+		artifacts importedArtifacts
+	)
+	defer artifacts.close()
+	tarin := tar.NewReader(gz)
+	for {
+		header, err := tarin.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid thick bundle")
+		}
+		if ok, err := artifacts.read(header, tarin); ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		switch header.Name {
+		case exportBundleFile:
+			data, err := ioutil.ReadAll(tarin)
+			if err != nil {
+				return nil, err
+			}
+			if b, err = cnab.Parse(data); err != nil {
+				return nil, err
+			}
+		case exportImagesFile:
+			if err := loadImages(ctx, engine, tarin); err != nil {
+				return nil, err
+			}
+			loaded = true
+		}
+	}
+	if b == nil {
+		return nil, errors.Errorf("invalid thick bundle: missing %s", exportBundleFile)
+	}
+	if !loaded {
+		return nil, errors.Errorf("invalid thick bundle: missing %s", exportImagesFile)
+	}
+	if err := cnab.ValidateRelocation(b, o.Relocation); err != nil {
+		return nil, err
+	}
+	if err := artifacts.push(ctx, o); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func loadImages(ctx context.Context, engine ImageSaveLoader, r io.Reader) error {
+	resp, err := engine.ImageLoad(ctx, r, true)
+	if err != nil {
+		return errors.Wrap(err, "failed to load bundle images")
+	}
+	defer resp.Body.Close()
+	if !resp.JSON {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	if err := jsonmessage.DisplayJSONMessagesStream(resp.Body, ioutil.Discard, 0, false, nil); err != nil {
+		return errors.Wrap(err, "failed to load bundle images")
+	}
+	return nil
+}
+
+// bundleImages returns the sorted references of the invocation images and
+// component container images of a bundle.
+func bundleImages(b *bundle.Bundle) []string {
+	seen := map[string]bool{}
+	var images []string
+	add := func(image string) {
+		if image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	for _, image := range b.InvocationImages {
+		add(image.Image)
+	}
+	for _, image := range b.Images {
+		if cnab.IsContainerImage(image.BaseImage) {
+			add(image.Image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
@@ -0,0 +1,212 @@
+package packager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/docker/api/types"
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type fakeEngine struct {
+	saved  []string
+	loaded []byte
+}
+
+func (e *fakeEngine) ImageSave(_ context.Context, images []string) (io.ReadCloser, error) {
+	e.saved = images
+	return ioutil.NopCloser(bytes.NewBufferString("image layers")), nil
+}
+
+func (e *fakeEngine) ImageLoad(_ context.Context, input io.Reader, _ bool) (types.ImageLoadResponse, error) {
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return types.ImageLoadResponse{}, err
+	}
+	e.loaded = data
+	return types.ImageLoadResponse{
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{"stream":"Loaded image: app-installer:0.1.0"}`)),
+		JSON: true,
+	}, nil
+}
+
+// fakeRegistry is an in-memory registry, whose blobs are shared by all the
+// repositories.
+type fakeRegistry struct {
+	blobs map[digest.Digest][]byte
+	refs  map[string]ocispec.Descriptor
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{blobs: map[digest.Digest][]byte{}, refs: map[string]ocispec.Descriptor{}}
+}
+
+// add stores a blob and returns its descriptor.
+func (r *fakeRegistry) add(mediaType string, data []byte) ocispec.Descriptor {
+	d := digest.FromBytes(data)
+	r.blobs[d] = data
+	return ocispec.Descriptor{MediaType: mediaType, Digest: d, Size: int64(len(data))}
+}
+
+func (r *fakeRegistry) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	desc, ok := r.refs[ref]
+	if !ok {
+		return "", ocispec.Descriptor{}, errors.Wrapf(errdefs.ErrNotFound, "reference %s", ref)
+	}
+	return ref, desc, nil
+}
+
+func (r *fakeRegistry) Fetcher(context.Context, string) (remotes.Fetcher, error) {
+	return remotes.FetcherFunc(func(_ context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		data, ok := r.blobs[desc.Digest]
+		if !ok {
+			return nil, errors.Wrapf(errdefs.ErrNotFound, "blob %s", desc.Digest)
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}), nil
+}
+
+func (r *fakeRegistry) Pusher(_ context.Context, ref string) (remotes.Pusher, error) {
+	return fakePusher{registry: r, ref: ref}, nil
+}
+
+type fakePusher struct {
+	registry *fakeRegistry
+	ref      string
+}
+
+func (p fakePusher) Push(_ context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	return &fakeWriter{pusher: p, desc: desc}, nil
+}
+
+type fakeWriter struct {
+	bytes.Buffer
+	pusher fakePusher
+	desc   ocispec.Descriptor
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+func (w *fakeWriter) Digest() digest.Digest {
+	return digest.FromBytes(w.Bytes())
+}
+
+func (w *fakeWriter) Commit(_ context.Context, size int64, expected digest.Digest, _ ...content.Opt) error {
+	if int64(w.Len()) != size || w.Digest() != expected {
+		return errors.Errorf("unexpected content for %s", expected)
+	}
+	w.pusher.registry.blobs[expected] = w.Bytes()
+	// The last blob pushed is the manifest, or the blob itself
+	w.pusher.registry.refs[w.pusher.ref] = w.desc
+	return nil
+}
+
+func (w *fakeWriter) Status() (content.Status, error) {
+	return content.Status{Offset: int64(w.Len())}, nil
+}
+
+func (w *fakeWriter) Truncate(size int64) error {
+	w.Buffer.Truncate(int(size))
+	return nil
+}
+
+func TestExportImport(t *testing.T) {
+	b := &bundle.Bundle{
+		Name:    "app",
+		Version: "0.1.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app-installer:0.1.0"}},
+		},
+		Images: map[string]bundle.Image{
+			"web": {BaseImage: bundle.BaseImage{ImageType: "docker", Image: "nginx:1.17"}},
+			"api": {BaseImage: bundle.BaseImage{ImageType: "docker", Image: "nginx:1.17"}},
+		},
+	}
+	engine := &fakeEngine{}
+	var buf bytes.Buffer
+	assert.NilError(t, Export(context.Background(), b, engine, &buf))
+	assert.Check(t, is.DeepEqual(engine.saved, []string{"app-installer:0.1.0", "nginx:1.17"}))
+
+	imported, err := Import(context.Background(), &buf, engine)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(imported, b))
+	assert.Check(t, is.Equal(string(engine.loaded), "image layers"))
+}
+
+func TestImportInvalid(t *testing.T) {
+	_, err := Import(context.Background(), bytes.NewBufferString("not a tarball"), &fakeEngine{})
+	assert.ErrorContains(t, err, "invalid thick bundle")
+}
+
+func TestExportImportArtifacts(t *testing.T) {
+	source := newFakeRegistry()
+	config := source.add(cnab.MediaTypeHelmConfig, []byte(`{"name":"wordpress","version":"8.1.0"}`))
+	chart := source.add(cnab.MediaTypeHelmChart, []byte("chart content"))
+	data, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    config,
+		Layers:    []ocispec.Descriptor{chart},
+	})
+	assert.NilError(t, err)
+	manifest := source.add(ocispec.MediaTypeImageManifest, data)
+	source.refs["docker.io/charts/wordpress:8.1.0"] = manifest
+	blob := source.add(cnab.MediaTypeOctetStream, []byte("seed data"))
+	blobRef := "docker.io/data/seed@" + blob.Digest.String()
+	source.refs[blobRef] = blob
+
+	b := &bundle.Bundle{
+		Name:    "app",
+		Version: "0.1.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app-installer:0.1.0"}},
+		},
+		Images: map[string]bundle.Image{
+			"web":   {BaseImage: bundle.BaseImage{ImageType: "docker", Image: "nginx:1.17"}},
+			"chart": {BaseImage: bundle.BaseImage{ImageType: "oci", Image: "charts/wordpress:8.1.0", MediaType: cnab.MediaTypeHelmChart}},
+			"seed":  {BaseImage: bundle.BaseImage{Image: "data/seed@" + blob.Digest.String(), MediaType: cnab.MediaTypeOctetStream}},
+		},
+	}
+	engine := &fakeEngine{}
+	var buf bytes.Buffer
+	assert.NilError(t, Export(context.Background(), b, engine, &buf, WithArtifactResolver(source)))
+	assert.Check(t, is.DeepEqual(engine.saved, []string{"app-installer:0.1.0", "nginx:1.17"}))
+
+	target := newFakeRegistry()
+	imported, err := Import(context.Background(), bytes.NewReader(buf.Bytes()), engine,
+		WithArtifactResolver(target),
+		WithRelocation(cnab.RelocationMap{"charts/wordpress:8.1.0": "registry.local/charts/wordpress:8.1.0"}))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(imported, b))
+	assert.Check(t, is.DeepEqual(target.refs, map[string]ocispec.Descriptor{
+		"registry.local/charts/wordpress:8.1.0": manifest,
+		blobRef:                                 blob,
+	}))
+	assert.Check(t, is.DeepEqual(target.blobs, source.blobs))
+
+	// Without resolver, the artifacts are pulled and pushed anonymously
+	anonymous := source
+	defer func(f func() remotes.Resolver) { defaultArtifactResolver = f }(defaultArtifactResolver)
+	defaultArtifactResolver = func() remotes.Resolver { return anonymous }
+	buf.Reset()
+	assert.NilError(t, Export(context.Background(), b, engine, &buf))
+	anonymous = newFakeRegistry()
+	_, err = Import(context.Background(), bytes.NewReader(buf.Bytes()), engine)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(anonymous.blobs, source.blobs))
+}
@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/redact"
+)
+
+// Environment describes where a bundle is about to be installed, or the
+// channel it is about to be promoted to.
+type Environment struct {
+	TargetContext string `json:"targetContext,omitempty"`
+	Orchestrator  string `json:"orchestrator,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
+	Channel       string `json:"channel,omitempty"`
+}
+
+// Input is the document submitted to a policy evaluator.
+type Input struct {
+	Action       string                 `json:"action"`
+	Installation string                 `json:"installation"`
+	Bundle       *bundle.Bundle         `json:"bundle"`
+	Parameters   map[string]interface{} `json:"parameters"`
+	Environment  Environment            `json:"environment"`
+}
+
+// NewInput builds a policy input. The values of the given sensitive
+// parameters are masked, so they are never disclosed to the policy engine.
+func NewInput(action, installation string, bndl *bundle.Bundle, parameters map[string]interface{}, env Environment, sensitive ...string) Input {
+	return Input{
+		Action:       action,
+		Installation: installation,
+		Bundle:       bndl,
+		Parameters:   redact.Parameters(parameters, sensitive...),
+		Environment:  env,
+	}
+}
+
+// Decision is the result of a policy evaluation.
+type Decision struct {
+	// Violations lists the reasons why the operation is denied. The
+	// operation is allowed if there is none.
+	Violations []string
+}
+
+// Allowed returns true if no policy has been violated.
+func (d Decision) Allowed() bool {
+	return len(d.Violations) == 0
+}
+
+// Err returns an error listing the violations, or nil if the operation is
+// allowed.
+func (d Decision) Err() error {
+	if d.Allowed() {
+		return nil
+	}
+	return fmt.Errorf("denied by policy:\n- %s", strings.Join(d.Violations, "\n- "))
+}
+
+// Evaluator evaluates organization policies before a bundle is installed or
+// upgraded.
+type Evaluator interface {
+	Evaluate(ctx context.Context, input Input) (Decision, error)
+}
+
+// Check evaluates the input with all the evaluators and returns an error if
+// any of them denies the operation.
+func Check(ctx context.Context, input Input, evaluators ...Evaluator) error {
+	var decision Decision
+	for _, e := range evaluators {
+		d, err := e.Evaluate(ctx, input)
+		if err != nil {
+			return err
+		}
+		decision.Violations = append(decision.Violations, d.Violations...)
+	}
+	return decision.Err()
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type staticEvaluator []string
+
+func (s staticEvaluator) Evaluate(context.Context, Input) (Decision, error) {
+	return Decision{Violations: s}, nil
+}
+
+func TestNewInputMasksSensitiveParameters(t *testing.T) {
+	params := map[string]interface{}{"password": "secret", "port": 8080}
+	input := NewInput("install", "myapp", &bundle.Bundle{Name: "myapp"}, params, Environment{TargetContext: "prod"}, "password", "missing")
+	assert.DeepEqual(t, input.Parameters, map[string]interface{}{"password": "******", "port": 8080})
+	// the original parameters are left untouched
+	assert.Equal(t, params["password"], "secret")
+}
+
+func TestCheck(t *testing.T) {
+	input := NewInput("install", "myapp", &bundle.Bundle{}, nil, Environment{})
+	assert.NilError(t, Check(context.Background(), input, staticEvaluator(nil)))
+	err := Check(context.Background(), input, staticEvaluator{"first"}, staticEvaluator(nil), staticEvaluator{"second"})
+	assert.Error(t, err, "denied by policy:\n- first\n- second")
+}
+
+func TestParseRegoValues(t *testing.T) {
+	values, err := parseRegoValues([]byte(`{"result":[{"expressions":[{"value":["image \"web\" uses the latest tag","a violation"],"text":"data.docker.app.admission.deny"}]}]}`))
+	assert.NilError(t, err)
+	decision, err := decide(values)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, decision.Violations, []string{"a violation", `image "web" uses the latest tag`})
+
+	// undefined query
+	values, err = parseRegoValues([]byte(`{}`))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(values, 0))
+	decision, err = decide(values)
+	assert.NilError(t, err)
+	assert.Assert(t, decision.Allowed())
+
+	_, err = parseRegoValues([]byte(`{`))
+	assert.ErrorContains(t, err, "failed to parse policy evaluation result")
+}
+
+func TestDecide(t *testing.T) {
+	decision, err := decide([]interface{}{map[string]interface{}{"b": true, "a": true}, nil})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, decision.Violations, []string{"a", "b"})
+
+	_, err = decide([]interface{}{true})
+	assert.Error(t, err, "unexpected policy evaluation result true")
+}
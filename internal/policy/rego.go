@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRegoQuery is the query evaluated by default by the Rego evaluator.
+// Policies are expected to define a "deny" set of messages in the
+// "docker.app.admission" package, for instance:
+//
+//	package docker.app.admission
+//
+//	deny[msg] {
+//		input.environment.targetContext == "prod"
+//		endswith(input.bundle.images[name].image, ":latest")
+//		msg := sprintf("image %q uses the latest tag", [name])
+//	}
+const DefaultRegoQuery = "data.docker.app.admission.deny"
+
+// Rego evaluates Rego policies using the Open Policy Agent command line.
+type Rego struct {
+	// Modules are the paths to the policy files or directories.
+	Modules []string
+	// Query defaults to DefaultRegoQuery.
+	Query string
+	// Binary is the OPA executable, "opa" by default.
+	Binary string
+}
+
+var _ Evaluator = &Rego{}
+
+// Evaluate runs "opa eval" with the input document on stdin and collects
+// the resulting deny messages.
+func (r *Rego) Evaluate(ctx context.Context, input Input) (Decision, error) {
+	values, err := r.Eval(ctx, input)
+	if err != nil {
+		return Decision{}, err
+	}
+	return decide(values)
+}
+
+// Eval runs "opa eval" with any input document on stdin and returns the
+// values of the query expressions, without interpreting them.
+func (r *Rego) Eval(ctx context.Context, input interface{}) ([]interface{}, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal policy input")
+	}
+	binary := r.Binary
+	if binary == "" {
+		binary = "opa"
+	}
+	query := r.Query
+	if query == "" {
+		query = DefaultRegoQuery
+	}
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, m := range r.Modules {
+		args = append(args, "--data", m)
+	}
+	args = append(args, query)
+
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate policies: %s", stderr.String())
+	}
+	return parseRegoValues(stdout.Bytes())
+}
+
+type regoOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value interface{} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+func parseRegoValues(data []byte) ([]interface{}, error) {
+	var out regoOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, errors.Wrap(err, "failed to parse policy evaluation result")
+	}
+	var values []interface{}
+	for _, result := range out.Result {
+		for _, expr := range result.Expressions {
+			values = append(values, expr.Value)
+		}
+	}
+	return values, nil
+}
+
+// decide collects the deny messages of the query values, which are either
+// arrays or sets of messages.
+func decide(values []interface{}) (Decision, error) {
+	var decision Decision
+	for _, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, msg := range v {
+				decision.Violations = append(decision.Violations, fmt.Sprint(msg))
+			}
+		case map[string]interface{}:
+			for msg := range v {
+				decision.Violations = append(decision.Violations, msg)
+			}
+		case nil:
+		default:
+			return Decision{}, errors.Errorf("unexpected policy evaluation result %v", v)
+		}
+	}
+	sort.Strings(decision.Violations)
+	return decision, nil
+}
@@ -0,0 +1,102 @@
+package reconcile
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/yaml"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// Desired is the desired state of the installations, keyed by installation
+// name. The installations managed by the reconciler which are not declared
+// are uninstalled.
+//
+//	installations:
+//	  my-app:
+//	    bundle: myorg/my-app:1.0.0
+//	    parameters:
+//	      port: "8080"
+//	    credentialSet: production
+type Desired struct {
+	Installations map[string]Spec `yaml:"installations" json:"installations"`
+}
+
+// Spec is the desired state of an installation.
+type Spec struct {
+	// Bundle is the reference of the bundle to install.
+	Bundle string `yaml:"bundle" json:"bundle"`
+	// Parameters are the values of the parameters, as they are written in
+	// parameter files. The other parameters get their default value, the
+	// values set by previous actions are not kept.
+	Parameters map[string]string `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	// CredentialSet is the name of the credential set passed to the actions.
+	// Changing it alone does not run any action.
+	CredentialSet string `yaml:"credentialSet,omitempty" json:"credentialSet,omitempty"`
+	// Uninstall declares the installation as uninstalled, so it is
+	// uninstalled with the credential set, instead of none when it is no
+	// longer declared.
+	Uninstall bool `yaml:"uninstall,omitempty" json:"uninstall,omitempty"`
+}
+
+// Load reads a desired state document and validates it.
+func Load(r io.Reader) (*Desired, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var desired Desired
+	if err := yaml.Unmarshal(data, &desired); err != nil {
+		return nil, errors.Wrap(err, "invalid desired state")
+	}
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+	return &desired, nil
+}
+
+// LoadFile reads a desired state file, see Load.
+func LoadFile(path string) (*Desired, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	desired, err := Load(f)
+	return desired, errors.Wrapf(err, "failed to load desired state %q", path)
+}
+
+// Validate checks the names of the installations and the references of
+// their bundles, reporting the first invalid installation in name order.
+func (d *Desired) Validate() error {
+	for _, name := range d.names() {
+		spec := d.Installations[name]
+		if _, err := claim.New(name); err != nil {
+			return errors.Wrapf(err, "invalid installation name %q", name)
+		}
+		if spec.Bundle == "" {
+			if spec.Uninstall {
+				continue
+			}
+			return errors.Errorf("installation %q: a bundle is required", name)
+		}
+		if _, err := reference.ParseNormalizedNamed(spec.Bundle); err != nil {
+			return errors.Wrapf(err, "installation %q: invalid bundle reference %q", name, spec.Bundle)
+		}
+	}
+	return nil
+}
+
+// names returns the sorted names of the declared installations.
+func (d *Desired) names() []string {
+	names := make([]string, 0, len(d.Installations))
+	for name := range d.Installations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
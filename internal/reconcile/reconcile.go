@@ -0,0 +1,406 @@
+// Package reconcile converges the installations to a declared desired
+// state, installing, upgrading and uninstalling them as needed. It is
+// idempotent: reconciling an already converged state runs no action. It is
+// the building block of a GitOps style controller, watching a desired state
+// from a repository.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/runner"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManagedLabel marks the installations managed by a reconciler. Only
+	// those are upgraded or uninstalled, the other installations are never
+	// modified.
+	ManagedLabel = internal.Namespace + "managed-by"
+	// ManagedValue is the value of the ManagedLabel.
+	ManagedValue = "reconcile"
+)
+
+// BundleFunc returns the bundle of a reference, for instance from the bundle
+// store, pulling it if missing.
+type BundleFunc func(ctx context.Context, ref reference.Named) (*bundle.Bundle, error)
+
+// CredentialsFunc returns the credentials of an action on an installation.
+// The spec is empty when the installation is uninstalled because it is no
+// longer declared.
+type CredentialsFunc func(ctx context.Context, installation string, spec Spec, b *bundle.Bundle) (credentials.Set, error)
+
+// FromCredentialStore returns the credentials of the credential set of the
+// spec, read from the store.
+func FromCredentialStore(credentialStore store.CredentialStore) CredentialsFunc {
+	return func(_ context.Context, _ string, spec Spec, _ *bundle.Bundle) (credentials.Set, error) {
+		if spec.CredentialSet == "" {
+			return credentials.Set{}, nil
+		}
+		set, err := credentialStore.Read(spec.CredentialSet)
+		if err != nil {
+			return nil, err
+		}
+		return set.Resolve()
+	}
+}
+
+// Change is the action converging an installation, and its outcome once
+// reconciled.
+type Change struct {
+	Installation string
+	// Action is the action to run, claim.ActionInstall, claim.ActionUpgrade
+	// or claim.ActionUninstall, or empty if the installation has converged.
+	Action string
+	// Reason tells why the action is needed.
+	Reason string
+	// Err is set if the change cannot be planned or if its action failed.
+	Err error
+
+	spec     Spec
+	declared bool
+	bundle   *bundle.Bundle
+	params   map[string]interface{}
+	current  *store.Installation
+}
+
+// String describes the change.
+func (c Change) String() string {
+	action := c.Action
+	if action == "" {
+		action = "none"
+	}
+	s := fmt.Sprintf("%s: %s", c.Installation, action)
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	if c.Err != nil {
+		s += ": " + c.Err.Error()
+	}
+	return s
+}
+
+// Reconciler converges the installations of the store of its runner to a
+// desired state. Each installation is handled on its own, a failure on one
+// of them does not prevent the others from converging.
+type Reconciler struct {
+	// Runner runs the actions, retrying them with its policy and storing
+	// the installations in its store.
+	Runner  *runner.Runner
+	Bundles BundleFunc
+	// Credentials returns the credentials of the actions. No credentials
+	// are passed if it is nil.
+	Credentials CredentialsFunc
+	// Locker locks the installations while their action runs, if set.
+	Locker *store.InstallationLocker
+	// Out receives the output of the actions, discarded if nil.
+	Out io.Writer
+}
+
+// Plan computes the changes converging the installations to the desired
+// state, sorted by installation name, without running any action. An error
+// is returned if the current installations cannot be read.
+func (r *Reconciler) Plan(ctx context.Context, desired *Desired) ([]Change, error) {
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+	installations := r.Runner.Installations
+	names, err := installations.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list installations")
+	}
+	current := map[string]*store.Installation{}
+	for _, name := range names {
+		installation, err := installations.Read(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read installation %q", name)
+		}
+		current[name] = installation
+	}
+	all := desired.names()
+	for name, installation := range current {
+		if _, ok := desired.Installations[name]; !ok && isManaged(installation) {
+			all = append(all, name)
+		}
+	}
+	sort.Strings(all)
+	changes := make([]Change, 0, len(all))
+	for _, name := range all {
+		spec, declared := desired.Installations[name]
+		if !declared {
+			spec.Uninstall = true
+		}
+		change := Change{Installation: name, spec: spec, declared: declared, current: current[name]}
+		if err := r.plan(ctx, &change); err != nil {
+			change.Action, change.Reason, change.Err = "", "", err
+		}
+		changes = append(changes, change)
+	}
+	return changes, ctx.Err()
+}
+
+func (r *Reconciler) plan(ctx context.Context, c *Change) error {
+	current := c.current
+	if current != nil && !isManaged(current) {
+		return errors.Errorf("installation %q exists and is not managed by the reconciler", c.Installation)
+	}
+	uninstalled := current == nil || (current.Result.Action == claim.ActionUninstall && current.Result.Status == claim.StatusSuccess)
+	if c.spec.Uninstall {
+		if current != nil {
+			c.Action = claim.ActionUninstall
+			c.Reason = "not declared"
+			if c.declared {
+				c.Reason = "declared as uninstalled"
+			}
+			if uninstalled {
+				c.Reason = "already uninstalled, the installation is removed from the store"
+			}
+		}
+		return nil
+	}
+	ref, err := reference.ParseNormalizedNamed(c.spec.Bundle)
+	if err != nil {
+		return err
+	}
+	if c.bundle, err = r.Bundles(ctx, ref); err != nil {
+		return errors.Wrapf(err, "failed to get bundle %q", c.spec.Bundle)
+	}
+	if err := cnab.CheckRequiredExtensions(c.bundle, cnab.SupportedExtensions); err != nil {
+		return err
+	}
+	switch {
+	case uninstalled:
+		c.Action, c.Reason = claim.ActionInstall, "not installed"
+	case current.Result.Action == claim.ActionInstall && current.Result.Status == claim.StatusFailure:
+		c.Action, c.Reason = claim.ActionInstall, "the previous install failed"
+	default:
+		c.Action = claim.ActionUpgrade
+	}
+	var outputs map[string]string
+	if c.Action == claim.ActionUpgrade {
+		outputs = current.Outputs
+	}
+	if c.params, err = desiredParameters(c.bundle, c.spec, c.Action, outputs); err != nil {
+		return err
+	}
+	if c.Action == claim.ActionInstall {
+		return nil
+	}
+	reasons, err := drift(current, c.bundle, c.params)
+	if err != nil {
+		return err
+	}
+	if len(reasons) == 0 {
+		c.Action = ""
+		return nil
+	}
+	c.Reason = strings.Join(reasons, ", ")
+	return nil
+}
+
+// desiredParameters returns the parameter values of the spec, completed with
+// their sources and default values, as they are given to the action.
+func desiredParameters(b *bundle.Bundle, spec Spec, action string, outputs map[string]string) (map[string]interface{}, error) {
+	names := make([]string, 0, len(spec.Parameters))
+	for name := range spec.Parameters {
+		names = append(names, name)
+	}
+	if err := cnab.CheckDeclaredParameters(b, names...); err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{}
+	for name, raw := range spec.Parameters {
+		value, err := b.Parameters[name].ConvertValue(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value for parameter %q", name)
+		}
+		params[name] = value
+	}
+	params, err := cnab.ResolveParameterSources(b, params, func(dependency, output string) (string, bool) {
+		if dependency != "" {
+			return "", false
+		}
+		value, ok := outputs[output]
+		return value, ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	if params, err = cnab.ValuesOrDefaults(params, b, action); err != nil {
+		return nil, err
+	}
+	return params, cnab.ValidateParameters(b, params)
+}
+
+// drift returns the reasons why an installation differs from its desired
+// bundle and parameters, or nothing if it has converged.
+func drift(current *store.Installation, b *bundle.Bundle, params map[string]interface{}) ([]string, error) {
+	var reasons []string
+	switch current.Result.Status {
+	case claim.StatusFailure, claim.StatusUnderway:
+		reasons = append(reasons, fmt.Sprintf("the last %s is %s", current.Result.Action, current.Result.Status))
+	}
+	expected, err := cnab.Digest(b)
+	if err != nil {
+		return nil, err
+	}
+	if current.Bundle == nil || !cnab.MatchesDigest(current.Bundle, expected) {
+		from := "none"
+		if current.Bundle != nil {
+			from = current.Bundle.Version
+		}
+		return append(reasons, fmt.Sprintf("bundle changed from %s to %s", from, b.Version)), nil
+	}
+	// The stored values are normalized the same way, as integers may have
+	// been read back as floats
+	actual, err := cnab.ValuesOrDefaults(current.Parameters, b, claim.ActionUpgrade, cnab.IgnoreUndeclared())
+	if err != nil {
+		return append(reasons, "parameters changed"), nil
+	}
+	var changed []string
+	for _, name := range cnab.ParameterNames(b) {
+		if !reflect.DeepEqual(actual[name], params[name]) {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) > 0 {
+		reasons = append(reasons, "parameters changed: "+strings.Join(changed, ", "))
+	}
+	return reasons, nil
+}
+
+// Reconcile plans and runs the changes converging the installations to the
+// desired state, and returns them with their outcome. An error is returned
+// only if the changes cannot be planned or the context is done, the failures
+// of the installations are reported in their change.
+func (r *Reconciler) Reconcile(ctx context.Context, desired *Desired) ([]Change, error) {
+	changes, err := r.Plan(ctx, desired)
+	if err != nil {
+		return changes, err
+	}
+	for i := range changes {
+		if ctx.Err() != nil {
+			return changes, ctx.Err()
+		}
+		if changes[i].Action != "" && changes[i].Err == nil {
+			changes[i].Err = r.apply(ctx, changes[i])
+		}
+	}
+	return changes, nil
+}
+
+func (r *Reconciler) apply(ctx context.Context, c Change) error {
+	if r.Locker != nil {
+		lock, err := r.Locker.Lock(c.Installation, c.Action)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock() //nolint:errcheck // a stale lock is replaced by the next operation
+	}
+	out := r.Out
+	if out == nil {
+		out = ioutil.Discard
+	}
+	installation := c.current
+	switch c.Action {
+	case claim.ActionInstall:
+		var err error
+		if installation, err = store.NewInstallation(c.Installation, c.spec.Bundle); err != nil {
+			return err
+		}
+		labels, err := cnab.ReadLabels(c.bundle)
+		if err != nil {
+			return err
+		}
+		installation.Labels = labels
+		installation.Bundle = c.bundle
+		installation.Parameters = c.params
+	case claim.ActionUpgrade:
+		installation.Reference = c.spec.Bundle
+		installation.Bundle = c.bundle
+		installation.Parameters = c.params
+	case claim.ActionUninstall:
+		if installation.Result.Action == claim.ActionUninstall && installation.Result.Status == claim.StatusSuccess {
+			return r.Runner.Installations.Delete(c.Installation)
+		}
+	}
+	if installation.Labels == nil {
+		installation.Labels = map[string]string{}
+	}
+	installation.Labels[ManagedLabel] = ManagedValue
+	creds := credentials.Set{}
+	if r.Credentials != nil {
+		var err error
+		if creds, err = r.Credentials(ctx, c.Installation, c.spec, installation.Bundle); err != nil {
+			return errors.Wrap(err, "failed to get credentials")
+		}
+	}
+	if installation.Bundle != nil {
+		if err := cnab.ValidateCredentials(installation.Bundle, creds); err != nil {
+			return err
+		}
+	}
+	if err := r.Runner.Run(ctx, installation, c.Action, creds, out); err != nil {
+		return err
+	}
+	if c.Action == claim.ActionUninstall {
+		return r.Runner.Installations.Delete(c.Installation)
+	}
+	return nil
+}
+
+// Source returns the desired state, read again on every reconciliation.
+type Source func(ctx context.Context) (*Desired, error)
+
+// FileSource reads the desired state from a file, see LoadFile.
+func FileSource(path string) Source {
+	return func(context.Context) (*Desired, error) {
+		return LoadFile(path)
+	}
+}
+
+// Watch reconciles the desired state of the source right away, then every
+// interval until the context is done, and returns the context error. The
+// outcome of each reconciliation is passed to observe, if set; a failure to
+// read the source or to plan is reported there and retried on the next
+// iteration.
+func (r *Reconciler) Watch(ctx context.Context, source Source, interval time.Duration, observe func([]Change, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		desired, err := source(ctx)
+		var changes []Change
+		if err == nil {
+			changes, err = r.Reconcile(ctx, desired)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if observe != nil {
+			observe(changes, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func isManaged(installation *store.Installation) bool {
+	return installation.Labels[ManagedLabel] == ManagedValue
+}
@@ -0,0 +1,237 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/internal/runner"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/distribution/reference"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func testBundle(version string) *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:             "my-app",
+		Version:          version,
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "my-app:" + version}}},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"port": {DataType: "int", Default: 80, Destination: &bundle.Location{EnvironmentVariable: "PORT"}},
+			"host": {DataType: "string", Default: "localhost", Destination: &bundle.Location{EnvironmentVariable: "HOST"}},
+		},
+	}
+}
+
+// registry serves the bundles by familiar reference.
+type registry map[string]*bundle.Bundle
+
+func (r registry) bundle(_ context.Context, ref reference.Named) (*bundle.Bundle, error) {
+	b, ok := r[reference.FamiliarString(ref)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return b, nil
+}
+
+func newReconciler(installations store.InstallationStore, d *fake.Driver) *Reconciler {
+	return &Reconciler{
+		Runner:  &runner.Runner{Installations: installations, Driver: d},
+		Bundles: registry{"my-app:1.0.0": testBundle("1.0.0"), "my-app:1.1.0": testBundle("1.1.0")}.bundle,
+	}
+}
+
+func actions(changes []Change) []string {
+	var actions []string
+	for _, c := range changes {
+		actions = append(actions, c.String())
+	}
+	return actions
+}
+
+func TestReconcileConverges(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New()
+	r := newReconciler(installations, d)
+	desired := &Desired{Installations: map[string]Spec{
+		"front": {Bundle: "my-app:1.0.0", Parameters: map[string]string{"port": "8080"}},
+		"back":  {Bundle: "my-app:1.0.0"},
+	}}
+
+	changes, err := r.Reconcile(context.Background(), desired)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(actions(changes), []string{
+		"back: install (not installed)",
+		"front: install (not installed)",
+	}))
+	front, err := installations.Read("front")
+	assert.NilError(t, err)
+	// The store reads the numbers back as json.Number
+	assert.Check(t, is.DeepEqual(front.Parameters, map[string]interface{}{"port": json.Number("8080"), "host": "localhost"}))
+	assert.Check(t, is.Equal(front.Labels[ManagedLabel], ManagedValue))
+	assert.Check(t, is.Equal(front.Reference, "my-app:1.0.0"))
+
+	// Reconciling again runs nothing
+	changes, err = r.Reconcile(context.Background(), desired)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(actions(changes), []string{"back: none", "front: none"}))
+	assert.Check(t, is.Len(d.Operations(), 2))
+
+	desired.Installations["front"] = Spec{Bundle: "my-app:1.0.0", Parameters: map[string]string{"port": "9090"}}
+	desired.Installations["back"] = Spec{Bundle: "my-app:1.1.0"}
+	changes, err = r.Reconcile(context.Background(), desired)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(actions(changes), []string{
+		"back: upgrade (bundle changed from 1.0.0 to 1.1.0)",
+		"front: upgrade (parameters changed: port)",
+	}))
+	back, err := installations.Read("back")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(back.Bundle.Version, "1.1.0"))
+	assert.Check(t, is.Equal(back.Result.Action, claim.ActionUpgrade))
+
+	// The installations no longer declared are uninstalled and removed
+	delete(desired.Installations, "back")
+	changes, err = r.Reconcile(context.Background(), desired)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(actions(changes), []string{"back: uninstall (not declared)", "front: none"}))
+	names, err := installations.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(names, []string{"front"}))
+}
+
+func TestReconcileIsolatesFailures(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New().Script(claim.ActionInstall, fake.Result{Err: errors.New("registry unavailable")})
+	r := newReconciler(installations, d)
+
+	// An installation which is not managed is never modified
+	manual, err := store.NewInstallation("manual", "my-app:1.0.0")
+	assert.NilError(t, err)
+	manual.Bundle = testBundle("1.0.0")
+	manual.Update(claim.ActionInstall, claim.StatusSuccess)
+	assert.NilError(t, installations.Store(manual))
+
+	desired := &Desired{Installations: map[string]Spec{
+		"a":      {Bundle: "my-app:1.0.0"},
+		"b":      {Bundle: "my-app:1.0.0"},
+		"c":      {Bundle: "my-app:9.9.9"},
+		"d":      {Bundle: "my-app:1.0.0", Parameters: map[string]string{"unknown": "value"}},
+		"manual": {Bundle: "my-app:1.1.0"},
+	}}
+	changes, err := r.Reconcile(context.Background(), desired)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(actions(changes), []string{
+		"a: install (not installed): registry unavailable",
+		"b: install (not installed)",
+		`c: none: failed to get bundle "my-app:9.9.9": not found`,
+		`d: none: parameter "unknown" is not defined in the bundle`,
+		`manual: none: installation "manual" exists and is not managed by the reconciler`,
+	}))
+	current, err := installations.Read("manual")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(current.Bundle.Version, "1.0.0"))
+
+	// The failed install is retried
+	delete(desired.Installations, "c")
+	delete(desired.Installations, "d")
+	changes, err = r.Reconcile(context.Background(), desired)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(changes[0].String(), "a: install (the previous install failed)"))
+	assert.Check(t, is.Equal(changes[1].String(), "b: none"))
+}
+
+func TestReconcileDeclaredUninstall(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New()
+	r := newReconciler(installations, d)
+	var credentialSets []string
+	r.Credentials = func(_ context.Context, _ string, spec Spec, _ *bundle.Bundle) (credentials.Set, error) {
+		credentialSets = append(credentialSets, spec.CredentialSet)
+		return credentials.Set{}, nil
+	}
+	_, err := r.Reconcile(context.Background(), &Desired{Installations: map[string]Spec{
+		"front": {Bundle: "my-app:1.0.0", CredentialSet: "production"},
+	}})
+	assert.NilError(t, err)
+	changes, err := r.Reconcile(context.Background(), &Desired{Installations: map[string]Spec{
+		"front": {Uninstall: true, CredentialSet: "production"},
+	}})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(actions(changes), []string{"front: uninstall (declared as uninstalled)"}))
+	assert.Check(t, is.DeepEqual(credentialSets, []string{"production", "production"}))
+	_, err = installations.Read("front")
+	assert.Check(t, err != nil)
+}
+
+func TestLoad(t *testing.T) {
+	desired, err := Load(strings.NewReader(`
+installations:
+  front:
+    bundle: myorg/my-app:1.0.0
+    parameters:
+      port: 8080
+    credentialSet: production
+  legacy:
+    uninstall: true
+`))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(desired, &Desired{Installations: map[string]Spec{
+		"front":  {Bundle: "myorg/my-app:1.0.0", Parameters: map[string]string{"port": "8080"}, CredentialSet: "production"},
+		"legacy": {Uninstall: true},
+	}}))
+
+	_, err = Load(strings.NewReader("installations:\n  front: {}\n"))
+	assert.Check(t, is.Error(err, `installation "front": a bundle is required`))
+	_, err = Load(strings.NewReader("installations:\n  front:\n    bundle: Invalid\n"))
+	assert.Check(t, is.ErrorContains(err, `installation "front": invalid bundle reference "Invalid"`))
+	_, err = Load(strings.NewReader("installations:\n  front/back:\n    bundle: my-app:1.0.0\n"))
+	assert.Check(t, is.ErrorContains(err, `invalid installation name "front/back"`))
+	_, err = LoadFile("missing.yml")
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestWatch(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	r := newReconciler(installations, fake.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sources := []error{errors.New("repository unavailable"), nil}
+	source := func(context.Context) (*Desired, error) {
+		err := sources[0]
+		if len(sources) > 1 {
+			sources = sources[1:]
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Desired{Installations: map[string]Spec{"front": {Bundle: "my-app:1.0.0"}}}, nil
+	}
+	var observed []string
+	err := r.Watch(ctx, source, time.Millisecond, func(changes []Change, err error) {
+		if err != nil {
+			observed = append(observed, err.Error())
+		} else {
+			observed = append(observed, actions(changes)...)
+		}
+		if len(observed) == 3 {
+			cancel()
+		}
+	})
+	assert.Check(t, is.Equal(err, context.Canceled))
+	assert.Check(t, is.DeepEqual(observed, []string{
+		"repository unavailable",
+		"front: install (not installed)",
+		"front: none",
+	}))
+}
@@ -0,0 +1,156 @@
+package redact
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces any secret value in redacted outputs.
+const Mask = "******"
+
+// Writer masks secret values written to an underlying writer. As a secret
+// may be split across several writes, the longest trailing part of the
+// output which could be the beginning of a secret is held back until more
+// data is written or Flush is called.
+type Writer struct {
+	mu       sync.Mutex
+	out      io.Writer
+	replacer *strings.Replacer
+	secrets  []string
+	pending  string
+}
+
+// NewWriter returns a writer masking all the given secrets. Empty secrets
+// are ignored.
+func NewWriter(w io.Writer, secrets ...string) *Writer {
+	secrets = normalize(secrets)
+	return &Writer{
+		out:      w,
+		replacer: newReplacer(secrets),
+		secrets:  secrets,
+	}
+}
+
+// Write redacts p and writes it to the underlying writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.secrets) == 0 {
+		return w.out.Write(p)
+	}
+	data := w.replacer.Replace(w.pending + string(p))
+	keep := w.partialSecretLength(data)
+	w.pending = data[len(data)-keep:]
+	if _, err := io.WriteString(w.out, data[:len(data)-keep]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes any held back data to the underlying writer.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := w.pending
+	w.pending = ""
+	_, err := io.WriteString(w.out, pending)
+	return err
+}
+
+// Close flushes the writer.
+func (w *Writer) Close() error {
+	return w.Flush()
+}
+
+// partialSecretLength returns the length of the longest suffix of data which
+// is a strict prefix of a secret.
+func (w *Writer) partialSecretLength(data string) int {
+	longest := 0
+	for _, secret := range w.secrets {
+		for l := len(secret) - 1; l > longest; l-- {
+			if strings.HasSuffix(data, secret[:l]) {
+				longest = l
+				break
+			}
+		}
+	}
+	return longest
+}
+
+// String masks all the secrets in s.
+func String(s string, secrets ...string) string {
+	secrets = normalize(secrets)
+	if len(secrets) == 0 {
+		return s
+	}
+	return newReplacer(secrets).Replace(s)
+}
+
+// Parameters returns a copy of the parameter values where the values of the
+// given sensitive parameters are masked.
+func Parameters(values map[string]interface{}, sensitive ...string) map[string]interface{} {
+	masked := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		masked[k] = v
+	}
+	for _, name := range sensitive {
+		if _, ok := masked[name]; ok {
+			masked[name] = Mask
+		}
+	}
+	return masked
+}
+
+// Error masks all the secrets in the message of err. It returns nil if err
+// is nil.
+func Error(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	redacted := String(msg, secrets...)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, cause: err}
+}
+
+type redactedError struct {
+	msg   string
+	cause error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Cause returns the original error, so that its type can still be checked
+// using errors.Cause.
+func (e *redactedError) Cause() error {
+	return e.cause
+}
+
+// normalize drops empty secrets and sorts the others longest first, so that a
+// secret containing another one is fully masked.
+func normalize(secrets []string) []string {
+	result := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		if s != "" {
+			result = append(result, s)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i]) > len(result[j])
+	})
+	return result
+}
+
+func newReplacer(secrets []string) *strings.Replacer {
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		pairs = append(pairs, s, Mask)
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,61 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestWriterMasksSecrets(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	w := NewWriter(out, "password", "", "pass")
+	_, err := w.Write([]byte("my password is secret, my pass too\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, w.Flush())
+	assert.Equal(t, out.String(), "my ****** is secret, my ****** too\n")
+}
+
+func TestWriterMasksSecretsSplitAcrossWrites(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	w := NewWriter(out, "s3cr3t")
+	for _, chunk := range []string{"token=s3", "cr", "3t; trailing s3c"} {
+		n, err := w.Write([]byte(chunk))
+		assert.NilError(t, err)
+		assert.Equal(t, n, len(chunk))
+	}
+	// the possible beginning of a secret is held back
+	assert.Equal(t, out.String(), "token=******; trailing ")
+	assert.NilError(t, w.Close())
+	assert.Equal(t, out.String(), "token=******; trailing s3c")
+}
+
+func TestWriterWithoutSecrets(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	w := NewWriter(out)
+	_, err := w.Write([]byte("nothing to hide"))
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "nothing to hide")
+}
+
+func TestError(t *testing.T) {
+	assert.NilError(t, Error(nil, "secret"))
+
+	cause := errors.New("failed to login with secret")
+	err := Error(errors.Wrap(cause, "install"), "secret")
+	assert.Error(t, err, "install: failed to login with ******")
+	assert.Equal(t, errors.Cause(err), cause)
+
+	untouched := errors.New("nothing to hide")
+	assert.Equal(t, Error(untouched, "secret"), untouched)
+}
+
+func TestParameters(t *testing.T) {
+	values := map[string]interface{}{"user": "admin", "password": "s3cr3t", "port": 5432}
+	masked := Parameters(values, "password", "unset")
+	assert.Check(t, is.DeepEqual(masked, map[string]interface{}{"user": "admin", "password": Mask, "port": 5432}))
+	// The original values are untouched
+	assert.Check(t, is.Equal(values["password"], "s3cr3t"))
+}
@@ -0,0 +1,293 @@
+// Package registryclient provides the registry client used to pull, push,
+// pin and verify application packages and images. It authenticates with the
+// credentials of the docker CLI configuration, including the credential
+// helpers and identity tokens, retries the requests failing because of an
+// overloaded or unavailable registry, limits the concurrent requests per
+// registry and presents client certificates to the registries requiring
+// mutual TLS.
+package registryclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	containerdreference "github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/cli/cli/config/configfile"
+	cnabremotes "github.com/docker/cnab-to-oci/remotes"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Keys of the "app" plugin section of the docker CLI configuration file
+// read by OptionsFromConfig.
+const (
+	// KeyMaxAttempts is the maximum number of attempts of a request.
+	KeyMaxAttempts = "registry-max-attempts"
+	// KeyBackoff is the delay before retrying a request, as a duration.
+	KeyBackoff = "registry-backoff"
+	// KeyMaxConcurrency is the maximum number of concurrent requests per
+	// registry.
+	KeyMaxConcurrency = "registry-max-concurrency"
+	// KeyCertsDir is the directory of the registry certificates.
+	KeyCertsDir = "registry-certs-dir"
+)
+
+// Options configures a registry client.
+type Options struct {
+	// Config holds the registry credentials.
+	Config *configfile.ConfigFile
+	// InsecureRegistries are reached over HTTPS without verifying their
+	// certificate if they support it, over plain HTTP otherwise.
+	InsecureRegistries []string
+	// Retry is the retry policy of the failed requests.
+	Retry RetryPolicy
+	// MaxConcurrency limits the concurrent requests per registry, if set.
+	MaxConcurrency int
+	// CertsDir contains a directory per registry host with its CA and client
+	// certificates, if set.
+	CertsDir string
+	// Offline makes every registry access fail with an
+	// offline.ErrOfflineMode.
+	Offline bool
+}
+
+// OptionsFromConfig returns the options read from the "app" plugin section
+// of the docker CLI configuration file, defaulting to DefaultRetryPolicy.
+// The offline mode is enabled as told by offline.Enabled.
+func OptionsFromConfig(cfg *configfile.ConfigFile, insecureRegistries []string) (Options, error) {
+	opts := Options{
+		Config:             cfg,
+		InsecureRegistries: insecureRegistries,
+		Retry:              DefaultRetryPolicy,
+		Offline:            offline.Enabled(cfg),
+	}
+	if cfg == nil {
+		return opts, nil
+	}
+	get := func(key string) (string, bool) {
+		value, ok := cfg.PluginConfig("app", key)
+		return value, ok && value != ""
+	}
+	if value, ok := get(KeyMaxAttempts); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return Options{}, fmt.Errorf("invalid %s %q: must be a positive integer", KeyMaxAttempts, value)
+		}
+		opts.Retry.MaxAttempts = n
+	}
+	if value, ok := get(KeyBackoff); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return Options{}, fmt.Errorf("invalid %s %q: must be a duration", KeyBackoff, value)
+		}
+		opts.Retry.Backoff = d
+	}
+	if value, ok := get(KeyMaxConcurrency); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return Options{}, fmt.Errorf("invalid %s %q: must be a positive integer", KeyMaxConcurrency, value)
+		}
+		opts.MaxConcurrency = n
+	}
+	if value, ok := get(KeyCertsDir); ok {
+		opts.CertsDir = value
+	}
+	return opts, nil
+}
+
+// Client reaches the registries with the options it was created with.
+type Client struct {
+	opts           Options
+	secure         *http.Client
+	skipTLS        *http.Client
+	originProvider *originProviderWrapper
+	resolver       *multiRegistryResolver
+}
+
+// New creates a registry client. The insecure registries are probed to find
+// out whether they support HTTPS, unless offline.
+func New(opts Options) *Client {
+	c := &Client{
+		opts:           opts,
+		secure:         &http.Client{Transport: wrapTransport(newTLSTransport(opts.CertsDir, false), opts)},
+		skipTLS:        &http.Client{Transport: wrapTransport(newTLSTransport(opts.CertsDir, true), opts)},
+		originProvider: &originProviderWrapper{},
+	}
+	c.resolver = &multiRegistryResolver{
+		offline:             opts.Offline,
+		plainHTTP:           c.newResolver(c.secure, true),
+		secure:              c.newResolver(c.secure, false),
+		skipTLS:             c.newResolver(c.skipTLS, false),
+		plainHTTPRegistries: map[string]struct{}{},
+		skipTLSRegistries:   map[string]struct{}{},
+	}
+	if opts.Offline {
+		return c
+	}
+	for _, r := range opts.InsecureRegistries {
+		if c.supportsHTTPS(r) {
+			c.resolver.skipTLSRegistries[r] = struct{}{}
+		} else {
+			c.resolver.plainHTTPRegistries[r] = struct{}{}
+		}
+	}
+	return c
+}
+
+// NewFromConfig creates a registry client configured by the docker CLI
+// configuration file, see OptionsFromConfig.
+func NewFromConfig(cfg *configfile.ConfigFile, insecureRegistries []string) (*Client, error) {
+	opts, err := OptionsFromConfig(cfg, insecureRegistries)
+	if err != nil {
+		return nil, err
+	}
+	return New(opts), nil
+}
+
+// wrapTransport measures, limits then retries the requests sent with the
+// transport.
+func wrapTransport(transport http.RoundTripper, opts Options) http.RoundTripper {
+	transport = &metricsTransport{next: transport}
+	if opts.MaxConcurrency > 0 {
+		transport = newLimitTransport(transport, opts.MaxConcurrency)
+	}
+	return &retryTransport{next: transport, policy: opts.Retry}
+}
+
+func (c *Client) newResolver(client *http.Client, plainHTTP bool) remotes.Resolver {
+	return docker.NewResolver(docker.ResolverOptions{
+		Authorizer:     docker.NewAuthorizer(client, c.credentials),
+		PlainHTTP:      plainHTTP,
+		Client:         client,
+		OriginProvider: c.originProvider.resolveSource,
+	})
+}
+
+// credentials returns the credentials of the registry host from the docker
+// CLI configuration, asking the credential helpers if needed.
+func (c *Client) credentials(host string) (string, string, error) {
+	if c.opts.Config == nil {
+		return "", "", nil
+	}
+	if host == registry.DefaultV2Registry.Host {
+		host = registry.IndexServer
+	}
+	auth, err := c.opts.Config.GetAuthConfig(host)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to get the credentials of registry %q", host)
+	}
+	if auth.IdentityToken != "" {
+		return "", auth.IdentityToken, nil
+	}
+	return auth.Username, auth.Password, nil
+}
+
+// supportsHTTPS probes the registry once, as plain HTTP registries are not
+// worth retrying.
+func (c *Client) supportsHTTPS(host string) bool {
+	client := &http.Client{Transport: newTLSTransport(c.opts.CertsDir, true), Timeout: 10 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("https://%s/v2/", host))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// Offline returns true if the client is in offline mode.
+func (c *Client) Offline() bool {
+	return c.opts.Offline
+}
+
+// Resolver returns the resolver of the client, for the containerd remotes
+// and the cnab.ImageResolver interface.
+func (c *Client) Resolver() remotes.Resolver {
+	return c.resolver
+}
+
+// ResolverConfig returns the resolver of the client, as configured by
+// cnab-to-oci to fix up and push bundles.
+func (c *Client) ResolverConfig() cnabremotes.ResolverConfig {
+	return cnabremotes.NewResolverConfig(c.resolver, c.originProvider)
+}
+
+// multiRegistryResolver resolves the references with the resolver matching
+// the security of their registry.
+type multiRegistryResolver struct {
+	offline             bool
+	plainHTTP           remotes.Resolver
+	secure              remotes.Resolver
+	skipTLS             remotes.Resolver
+	plainHTTPRegistries map[string]struct{}
+	skipTLSRegistries   map[string]struct{}
+}
+
+func (r *multiRegistryResolver) resolver(ref string) (remotes.Resolver, error) {
+	if r.offline {
+		return nil, offline.New("reaching the registry of %q", ref)
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return nil, err
+	}
+	repoInfo, err := registry.ParseRepositoryInfo(named)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.plainHTTPRegistries[repoInfo.Index.Name]; ok {
+		return r.plainHTTP, nil
+	}
+	if _, ok := r.skipTLSRegistries[repoInfo.Index.Name]; ok {
+		return r.skipTLS, nil
+	}
+	return r.secure, nil
+}
+
+func (r *multiRegistryResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	resolver, err := r.resolver(ref)
+	if err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	return resolver.Resolve(ctx, ref)
+}
+
+func (r *multiRegistryResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	resolver, err := r.resolver(ref)
+	if err != nil {
+		return nil, err
+	}
+	return resolver.Fetcher(ctx, ref)
+}
+
+func (r *multiRegistryResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	resolver, err := r.resolver(ref)
+	if err != nil {
+		return nil, err
+	}
+	return resolver.Pusher(ctx, ref)
+}
+
+// originProviderWrapper lets cnab-to-oci set the origin of the blobs
+// mounted from other repositories after the resolvers are created.
+type originProviderWrapper struct {
+	originProvider func(ocispec.Descriptor) []containerdreference.Spec
+}
+
+func (p *originProviderWrapper) resolveSource(desc ocispec.Descriptor) []containerdreference.Spec {
+	if p.originProvider == nil {
+		return nil
+	}
+	return p.originProvider(desc)
+}
+
+func (p *originProviderWrapper) Wrap(provider func(ocispec.Descriptor) []containerdreference.Spec) {
+	p.originProvider = provider
+}
@@ -0,0 +1,112 @@
+package registryclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/app/pkg/telemetry/telemetrytest"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/types"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const manifestDigest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+
+func TestOptionsFromConfig(t *testing.T) {
+	opts, err := OptionsFromConfig(nil, []string{"localhost:5000"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(opts.Retry, DefaultRetryPolicy))
+	assert.Check(t, is.DeepEqual(opts.InsecureRegistries, []string{"localhost:5000"}))
+
+	cfg := &configfile.ConfigFile{Plugins: map[string]map[string]string{"app": {
+		KeyMaxAttempts:    "5",
+		KeyBackoff:        "200ms",
+		KeyMaxConcurrency: "4",
+		KeyCertsDir:       "/etc/docker/certs.d",
+	}}}
+	opts, err = OptionsFromConfig(cfg, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(opts.Retry, RetryPolicy{MaxAttempts: 5, Backoff: 200 * time.Millisecond, MaxBackoff: DefaultRetryPolicy.MaxBackoff}))
+	assert.Check(t, is.Equal(opts.MaxConcurrency, 4))
+	assert.Check(t, is.Equal(opts.CertsDir, "/etc/docker/certs.d"))
+
+	for key, value := range map[string]string{KeyMaxAttempts: "0", KeyBackoff: "soon", KeyMaxConcurrency: "many"} {
+		cfg := &configfile.ConfigFile{Plugins: map[string]map[string]string{"app": {key: value}}}
+		_, err := OptionsFromConfig(cfg, nil)
+		assert.Check(t, is.ErrorContains(err, "invalid "+key))
+	}
+}
+
+func TestResolverAuthenticatesAndRetries(t *testing.T) {
+	recorder, restore := telemetrytest.Install()
+	defer restore()
+	var (
+		mu       sync.Mutex
+		failures = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v2/my-app/manifests/1.0.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+		w.Header().Set("Docker-Content-Digest", manifestDigest)
+		w.Header().Set("Content-Length", "42")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	cfg := configfile.New("config.json")
+	cfg.AuthConfigs[host] = types.AuthConfig{ServerAddress: host, Username: "user", Password: "secret"}
+	client := New(Options{
+		Config:             cfg,
+		InsecureRegistries: []string{host},
+		Retry:              RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	})
+
+	_, desc, err := client.Resolver().Resolve(context.Background(), host+"/my-app:1.0.0")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(desc.Digest.String(), manifestDigest))
+	assert.Check(t, is.Equal(desc.Size, int64(42)))
+
+	// The retries are counted as round trips
+	assert.Check(t, is.Equal(recorder.Sum(telemetry.MetricRegistryRoundTrips, telemetry.Labels{"host": host, "status": "503"}), 1.0))
+	assert.Check(t, is.Equal(recorder.Sum(telemetry.MetricRegistryRoundTrips, telemetry.Labels{"host": host, "status": "200"}), 1.0))
+}
+
+func TestOfflineClient(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	client := New(Options{InsecureRegistries: []string{host}, Offline: true})
+	assert.Check(t, client.Offline())
+	_, _, err := client.Resolver().Resolve(context.Background(), host+"/my-app:1.0.0")
+	assert.Check(t, offline.IsOffline(err))
+	_, err = client.Resolver().Pusher(context.Background(), host+"/my-app:1.0.0")
+	assert.Check(t, offline.IsOffline(err))
+	assert.Check(t, is.Equal(requests, 0))
+}
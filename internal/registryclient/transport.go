@@ -0,0 +1,314 @@
+package registryclient
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/docker/registry"
+)
+
+// RetryPolicy tells how the requests failing with a network error, a 429
+// (Too Many Requests) or a 5xx status are retried. The zero value does not
+// retry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, 1 if not set.
+	MaxAttempts int
+	// Backoff is the delay before the second attempt, doubled for each of
+	// the following ones. A Retry-After header sent by the registry takes
+	// precedence.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between attempts, if set.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of the registry clients, unless
+// configured otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     time.Second,
+	MaxBackoff:  30 * time.Second,
+}
+
+// delay returns how long to wait before the given attempt, counted from 1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 2; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	return p.cap(d)
+}
+
+func (p RetryPolicy) cap(d time.Duration) time.Duration {
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// retryTransport retries the failed requests with the policy.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	// sleep waits between attempts, it is replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxAttempts := t.policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	sleep := t.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= maxAttempts || !retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		// The body of the request must be read again, which is not possible
+		// for streamed uploads.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = cloneRequest(req)
+			req.Body = body
+		}
+		delay := t.policy.delay(attempt + 1)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				delay = t.policy.cap(after)
+			}
+			io.Copy(ioutil.Discard, resp.Body) // nolint: errcheck
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable tells if the request failed because of the network or an
+// overloaded or unavailable registry.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		_, isNetErr := err.(net.Error)
+		return isNetErr || err == io.ErrUnexpectedEOF || err == io.EOF
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode == http.StatusNotImplemented:
+		return false
+	default:
+		return resp.StatusCode >= http.StatusInternalServerError
+	}
+}
+
+// retryAfter returns the delay of the Retry-After header of the response,
+// either a number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func cloneRequest(req *http.Request) *http.Request {
+	clone := req.WithContext(req.Context())
+	clone.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		clone.Header[k] = append([]string(nil), v...)
+	}
+	return clone
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitTransport limits the number of requests sent concurrently to every
+// registry host. A request holds its slot until its response body is
+// closed, so that concurrent blob transfers are limited too.
+type limitTransport struct {
+	next http.RoundTripper
+	max  int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newLimitTransport(next http.RoundTripper, max int) *limitTransport {
+	return &limitTransport{next: next, max: max, slots: map[string]chan struct{}{}}
+}
+
+func (t *limitTransport) hostSlots(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	slots, ok := t.slots[host]
+	if !ok {
+		slots = make(chan struct{}, t.max)
+		t.slots[host] = slots
+	}
+	return slots
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	slots := t.hostSlots(req.URL.Host)
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func() { <-slots }
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the slot of its request once closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// tlsTransport sends the requests with a transport per registry host,
+// configured with the CA and client certificates of the host found in the
+// certificates directory, as the docker engine does:
+//
+//	<certs-dir>/<host>/*.crt                  CA certificates
+//	<certs-dir>/<host>/*.cert and *.key       client certificate and key
+type tlsTransport struct {
+	certsDir           string
+	insecureSkipVerify bool
+
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func newTLSTransport(certsDir string, insecureSkipVerify bool) *tlsTransport {
+	return &tlsTransport{
+		certsDir:           certsDir,
+		insecureSkipVerify: insecureSkipVerify,
+		transports:         map[string]*http.Transport{},
+	}
+}
+
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.transport(req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+func (t *tlsTransport) transport(host string) (*http.Transport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if transport, ok := t.transports[host]; ok {
+		return transport, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.insecureSkipVerify,
+	}
+	if t.certsDir != "" {
+		if err := registry.ReadCertsDirectory(tlsConfig, filepath.Join(t.certsDir, host)); err != nil {
+			return nil, err
+		}
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	t.transports[host] = transport
+	return transport, nil
+}
+
+// metricsTransport counts the round trips to the registries and the bytes
+// received, see telemetry.MetricRegistryRoundTrips and
+// telemetry.MetricPullBytes.
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	resp, err := t.next.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+		resp.Body = &countingBody{ReadCloser: resp.Body, host: host}
+	}
+	telemetry.Add(telemetry.MetricRegistryRoundTrips, 1, telemetry.Labels{"host": host, "status": status})
+	return resp, err
+}
+
+// countingBody counts the bytes read from the registry host.
+type countingBody struct {
+	io.ReadCloser
+	host string
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		telemetry.Add(telemetry.MetricPullBytes, float64(n), telemetry.Labels{"host": b.host})
+	}
+	return n, err
+}
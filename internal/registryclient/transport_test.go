@@ -0,0 +1,150 @@
+package registryclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// statusSequence serves the statuses in order, then 200.
+func statusSequence(t *testing.T, statuses ...int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Check(t, err)
+		bodies = append(bodies, string(body))
+		if len(statuses) == 0 {
+			return
+		}
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(status)
+	}))
+	return server, &bodies
+}
+
+func newRetryTransport(policy RetryPolicy, delays *[]time.Duration) *retryTransport {
+	return &retryTransport{
+		next:   http.DefaultTransport,
+		policy: policy,
+		sleep: func(ctx context.Context, d time.Duration) error {
+			*delays = append(*delays, d)
+			return nil
+		},
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	var delays []time.Duration
+	for attempt := 2; attempt <= 6; attempt++ {
+		delays = append(delays, p.delay(attempt))
+	}
+	assert.DeepEqual(t, delays, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second})
+}
+
+func TestRetryTransportRetries(t *testing.T) {
+	server, bodies := statusSequence(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+	var delays []time.Duration
+	client := &http.Client{Transport: newRetryTransport(RetryPolicy{MaxAttempts: 3, Backoff: time.Second}, &delays)}
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusOK))
+	// The Retry-After header of the 429 takes precedence over the backoff
+	assert.Check(t, is.DeepEqual(delays, []time.Duration{time.Second, 7 * time.Second}))
+	// The body is sent again with every attempt
+	assert.Check(t, is.DeepEqual(*bodies, []string{"payload", "payload", "payload"}))
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	server, bodies := statusSequence(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	defer server.Close()
+	var delays []time.Duration
+	client := &http.Client{Transport: newRetryTransport(RetryPolicy{MaxAttempts: 2}, &delays)}
+
+	resp, err := client.Get(server.URL)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusBadGateway))
+	assert.Check(t, is.Len(*bodies, 2))
+}
+
+func TestRetryTransportDoesNotRetryClientErrors(t *testing.T) {
+	server, bodies := statusSequence(t, http.StatusNotFound, http.StatusNotImplemented)
+	defer server.Close()
+	var delays []time.Duration
+	client := &http.Client{Transport: newRetryTransport(RetryPolicy{MaxAttempts: 3}, &delays)}
+
+	for _, expected := range []int{http.StatusNotFound, http.StatusNotImplemented} {
+		resp, err := client.Get(server.URL)
+		assert.NilError(t, err)
+		resp.Body.Close()
+		assert.Check(t, is.Equal(resp.StatusCode, expected))
+	}
+	assert.Check(t, is.Len(*bodies, 2))
+	assert.Check(t, is.Len(delays, 0))
+}
+
+func TestLimitTransport(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		running     int
+		maxRunning  int
+		wg          sync.WaitGroup
+		releaseBody = make(chan struct{})
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-releaseBody
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newLimitTransport(http.DefaultTransport, 2)}
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if !assert.Check(t, err) {
+				return
+			}
+			// The slot is held until the body is read and closed
+			ioutil.ReadAll(resp.Body) // nolint: errcheck
+			resp.Body.Close()
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(releaseBody)
+	wg.Wait()
+	assert.Check(t, is.Equal(maxRunning, 2))
+}
@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/pkg/errors"
+)
+
+// HookEvent describes an attempt of an action to the hooks.
+type HookEvent struct {
+	Action string
+	// Attempt counts the attempts of the action, from 1.
+	Attempt int
+	Bundle  *bundle.Bundle
+	// Claim is the claim of the installation, holding the result of the
+	// attempt once it ran.
+	Claim *claim.Claim
+	// Operation is the operation run by the driver. It is nil before the
+	// attempt runs and if the attempt failed before the operation was built,
+	// like on a missing parameter.
+	Operation *driver.Operation
+}
+
+// Hook is called around the attempts of the actions run by a Runner, for
+// instance to gate them behind an approval or to audit them. Any of its
+// functions can be nil.
+type Hook struct {
+	// BeforeAction is called before every attempt, with the claim holding
+	// the parameters of the action. Returning an error vetoes the attempt,
+	// which fails with a VetoedError and is not retried. The installation is
+	// neither updated nor stored.
+	BeforeAction func(ctx context.Context, event HookEvent) error
+	// AfterAction is called once the result of an attempt which ran is
+	// stored, whether it succeeded or not.
+	AfterAction func(ctx context.Context, event HookEvent)
+	// OnError is called once the result of a failed attempt is stored,
+	// including vetoed attempts and those failing before running.
+	OnError func(ctx context.Context, event HookEvent, err error)
+}
+
+// VetoedError is returned when a BeforeAction hook vetoes an attempt.
+type VetoedError struct {
+	Err error
+}
+
+func (e *VetoedError) Error() string {
+	return "action vetoed: " + e.Err.Error()
+}
+
+// IsVetoed returns true if the error is a VetoedError.
+func IsVetoed(err error) bool {
+	_, ok := errors.Cause(err).(*VetoedError)
+	return ok
+}
+
+// hookDriver calls the hooks around the attempts, remembering the last
+// operation it ran.
+type hookDriver struct {
+	ctx     context.Context
+	driver  driver.Driver
+	hooks   []Hook
+	event   HookEvent
+	started bool
+}
+
+func (d *hookDriver) Run(op *driver.Operation) error {
+	d.event.Operation = op
+	d.started = true
+	return d.driver.Run(op)
+}
+
+func (d *hookDriver) Handles(imageType string) bool {
+	return d.driver.Handles(imageType)
+}
+
+// start resets the driver for a new attempt.
+func (d *hookDriver) start(attempt int) {
+	d.event.Attempt = attempt
+	d.event.Operation = nil
+	d.started = false
+}
+
+// before calls the BeforeAction hooks, returning a VetoedError if any of
+// them vetoes the attempt.
+func (d *hookDriver) before() error {
+	for _, hook := range d.hooks {
+		if hook.BeforeAction == nil {
+			continue
+		}
+		if err := hook.BeforeAction(d.ctx, d.event); err != nil {
+			return &VetoedError{Err: err}
+		}
+	}
+	return nil
+}
+
+// done calls the AfterAction and OnError hooks with the result of the
+// attempt.
+func (d *hookDriver) done(err error) {
+	for _, hook := range d.hooks {
+		if d.started && hook.AfterAction != nil {
+			hook.AfterAction(d.ctx, d.event)
+		}
+		if err != nil && hook.OnError != nil {
+			hook.OnError(d.ctx, d.event, err)
+		}
+	}
+}
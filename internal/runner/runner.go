@@ -0,0 +1,194 @@
+// Package runner runs the actions of installations, retrying the failed
+// attempts and resuming the failed or interrupted actions.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/deislabs/cnab-go/action"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/app/pkg/telemetry"
+)
+
+// RetryPolicy tells how failed attempts of an action are retried. The zero
+// value does not retry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, 1 if not set.
+	MaxAttempts int
+	// Backoff is the delay before the second attempt, doubled for each of
+	// the following ones.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between attempts, if set.
+	MaxBackoff time.Duration
+}
+
+// delay returns how long to wait before the given attempt, counted from 1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 2; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// Runner runs the modifying actions of installations and stores their
+// result after every attempt. All the attempts of an action belong to the
+// same installation revision, listed in its attempts. Hooks can veto or
+// observe the attempts.
+type Runner struct {
+	Installations store.InstallationStore
+	Driver        driver.Driver
+	Retry         RetryPolicy
+	// Hooks are called around every attempt, in order.
+	Hooks []Hook
+	// Succeeded, if set, completes the installation once an attempt
+	// succeeded, before it is stored, for instance with the outputs of the
+	// action.
+	Succeeded func(installation *store.Installation, actionName string)
+	// sleep waits between attempts, it is replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Run runs the action on the installation, as a new revision, until it
+// succeeds, the attempts of the retry policy are exhausted or the context is
+// done. Interrupted attempts are not retried.
+func (r *Runner) Run(ctx context.Context, installation *store.Installation, actionName string, creds credentials.Set, out io.Writer) error {
+	installation.Attempts = nil
+	return r.run(ctx, installation, actionName, "", creds, out)
+}
+
+// Resume runs again the failed or interrupted last action of an
+// installation, with the retry policy. The result is recorded in the same
+// revision instead of a new one.
+func (r *Runner) Resume(ctx context.Context, installationName string, creds credentials.Set, out io.Writer) (*store.Installation, error) {
+	installation, err := r.Installations.Read(installationName)
+	if err != nil {
+		return nil, err
+	}
+	switch installation.Result.Status {
+	case claim.StatusFailure, claim.StatusUnderway:
+	default:
+		return nil, fmt.Errorf("Installation %q cannot be resumed, its last %s is not failed nor interrupted", installationName, installation.Result.Action)
+	}
+	return installation, r.run(ctx, installation, installation.Result.Action, installation.Revision, creds, out)
+}
+
+// run attempts the action, keeping the given revision, or the one of the
+// first attempt.
+func (r *Runner) run(ctx context.Context, installation *store.Installation, actionName, revision string, creds credentials.Set, out io.Writer) (err error) {
+	ctx, op := telemetry.Start(ctx, telemetry.SpanAction, telemetry.Fields{
+		"installation": installation.Name,
+		"action":       actionName,
+	})
+	defer func() {
+		op.SetAttributes(telemetry.Fields{"revision": installation.Revision, "attempts": len(installation.Attempts)})
+		op.End(err)
+	}()
+	hooks := &hookDriver{
+		ctx:    ctx,
+		driver: drivers.WithContext(ctx, r.Driver),
+		hooks:  r.Hooks,
+		event:  HookEvent{Action: actionName, Bundle: installation.Bundle, Claim: &installation.Claim},
+	}
+	act, err := r.action(installation, actionName, hooks)
+	if err != nil {
+		return err
+	}
+	maxAttempts := r.Retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	sleep := r.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if err := sleep(ctx, r.Retry.delay(attempt)); err != nil {
+				return err
+			}
+		}
+		before := installation.Revision
+		started := time.Now()
+		hooks.start(attempt)
+		if err := hooks.before(); err != nil {
+			// Vetoed attempts neither run nor are recorded
+			hooks.done(err)
+			return err
+		}
+		err := act.Run(&installation.Claim, creds, out)
+		if installation.Revision == before {
+			// The action failed before running, nothing to retry nor record
+			hooks.done(err)
+			return err
+		}
+		if revision == "" {
+			revision = installation.Revision
+		}
+		installation.Revision = revision
+		installation.Attempts = append(installation.Attempts, store.Attempt{
+			Started:  started,
+			Finished: time.Now(),
+			Status:   installation.Result.Status,
+			Message:  installation.Result.Message,
+		})
+		if err == nil && r.Succeeded != nil {
+			r.Succeeded(installation, actionName)
+		}
+		if err2 := r.Installations.Store(installation); err2 != nil {
+			if err == nil {
+				return err2
+			}
+			return fmt.Errorf("%s while %s", err2, err)
+		}
+		hooks.done(err)
+		if err == nil || attempt >= maxAttempts || drivers.IsInterrupted(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// action returns the cnab-go action running the named action with the
+// driver.
+func (r *Runner) action(installation *store.Installation, name string, d driver.Driver) (action.Action, error) {
+	switch name {
+	case claim.ActionInstall:
+		return &action.Install{Driver: d}, nil
+	case claim.ActionUpgrade:
+		return &action.Upgrade{Driver: d}, nil
+	case claim.ActionUninstall:
+		return &action.Uninstall{Driver: d}, nil
+	}
+	if installation.Bundle == nil {
+		return nil, fmt.Errorf("Installation %q has no bundle", installation.Name)
+	}
+	if def, ok := cnab.LookupAction(installation.Bundle, name); !ok || !def.Modifies {
+		return nil, fmt.Errorf("Action %q of installation %q does not modify it, it cannot be run nor resumed", name, installation.Name)
+	}
+	return &action.RunCustom{Driver: d, Action: name}, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
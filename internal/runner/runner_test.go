@@ -0,0 +1,301 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/app/pkg/telemetry/telemetrytest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func newInstallation(t *testing.T) *store.Installation {
+	t.Helper()
+	installation, err := store.NewInstallation("my-installation", "my-app:1.0.0")
+	assert.NilError(t, err)
+	installation.Bundle = &bundle.Bundle{
+		Name:             "my-app",
+		Version:          "1.0.0",
+		InvocationImages: []bundle.InvocationImage{{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "my-app:1.0.0"}}},
+		Actions: map[string]bundle.Action{
+			"migrate": {Modifies: true},
+			"logs":    {},
+		},
+	}
+	return installation
+}
+
+// recordSleeps replaces the sleep of the runner, recording the delays.
+func recordSleeps(r *Runner, delays *[]time.Duration) {
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	var delays []time.Duration
+	for attempt := 2; attempt <= 6; attempt++ {
+		delays = append(delays, p.delay(attempt))
+	}
+	assert.DeepEqual(t, delays, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second})
+}
+
+func TestRunRetries(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New().Script(claim.ActionInstall,
+		fake.Result{Err: errors.New("registry unavailable")},
+		fake.Result{Err: errors.New("registry unavailable")},
+	)
+	r := &Runner{Installations: installations, Driver: d, Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Second}}
+	var delays []time.Duration
+	recordSleeps(r, &delays)
+
+	installation := newInstallation(t)
+	assert.NilError(t, r.Run(context.Background(), installation, claim.ActionInstall, nil, ioutil.Discard))
+	assert.Check(t, is.Len(d.Operations(), 3))
+	assert.Check(t, is.DeepEqual(delays, []time.Duration{time.Second, 2 * time.Second}))
+	assert.Assert(t, is.Len(installation.Attempts, 3))
+	assert.Check(t, is.Equal(installation.Attempts[0].Status, claim.StatusFailure))
+	assert.Check(t, is.Equal(installation.Attempts[0].Message, "registry unavailable"))
+	assert.Check(t, is.Equal(installation.Attempts[2].Status, claim.StatusSuccess))
+
+	// All the attempts are recorded in a single revision
+	revisions, err := installations.Revisions("my-installation")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(revisions, 1))
+	assert.Check(t, is.Equal(revisions[0].Revision, installation.Revision))
+	assert.Check(t, is.Equal(revisions[0].Result.Status, claim.StatusSuccess))
+}
+
+func TestRunSucceeded(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New().Script(claim.ActionInstall, fake.Result{Err: errors.New("registry unavailable")})
+	var succeeded []string
+	r := &Runner{
+		Installations: installations,
+		Driver:        d,
+		Retry:         RetryPolicy{MaxAttempts: 2},
+		Succeeded: func(installation *store.Installation, actionName string) {
+			succeeded = append(succeeded, actionName)
+			installation.Outputs = map[string]string{"endpoint": "http://localhost"}
+		},
+	}
+	recordSleeps(r, &[]time.Duration{})
+
+	assert.NilError(t, r.Run(context.Background(), newInstallation(t), claim.ActionInstall, nil, ioutil.Discard))
+	// Only the successful attempt completes the installation, before it is
+	// stored
+	assert.Check(t, is.DeepEqual(succeeded, []string{claim.ActionInstall}))
+	stored, err := installations.Read("my-installation")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(stored.Outputs["endpoint"], "http://localhost"))
+}
+
+func TestRunTelemetry(t *testing.T) {
+	recorder, restore := telemetrytest.Install()
+	defer restore()
+	d := fake.New().Script(claim.ActionInstall, fake.Result{Err: errors.New("registry unavailable")})
+	r := &Runner{Installations: store.NewMemoryInstallationStore(), Driver: d, Retry: RetryPolicy{MaxAttempts: 2}}
+	recordSleeps(r, new([]time.Duration))
+
+	installation := newInstallation(t)
+	assert.NilError(t, r.Run(context.Background(), installation, claim.ActionInstall, nil, ioutil.Discard))
+	var actions, runs []telemetrytest.Span
+	for _, span := range recorder.Spans() {
+		switch span.Name {
+		case telemetry.SpanAction:
+			actions = append(actions, span)
+		case telemetry.SpanDriver:
+			runs = append(runs, span)
+		}
+	}
+	assert.Assert(t, is.Len(actions, 1))
+	assert.Check(t, actions[0].Ended)
+	assert.Check(t, is.DeepEqual(actions[0].Attributes, telemetry.Fields{
+		"installation": "my-installation",
+		"action":       claim.ActionInstall,
+		"revision":     installation.Revision,
+		"attempts":     2,
+	}))
+	// The invocation image runs of the attempts are children of the action
+	assert.Assert(t, is.Len(runs, 2))
+	for _, run := range runs {
+		assert.Check(t, is.Equal(run.Parent, telemetry.SpanAction))
+	}
+}
+
+func TestRunGivesUp(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New().Default(fake.Result{Err: errors.New("boom")})
+	r := &Runner{Installations: installations, Driver: d, Retry: RetryPolicy{MaxAttempts: 2}}
+	var delays []time.Duration
+	recordSleeps(r, &delays)
+
+	installation := newInstallation(t)
+	err := r.Run(context.Background(), installation, claim.ActionInstall, nil, ioutil.Discard)
+	assert.Check(t, is.Error(err, "boom"))
+	assert.Check(t, is.Len(installation.Attempts, 2))
+	stored, err := installations.Read("my-installation")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(stored.Result.Status, claim.StatusFailure))
+}
+
+func TestRunDoesNotRetryInterruptions(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New().Default(fake.Result{Delay: time.Minute})
+	r := &Runner{Installations: installations, Driver: d, Retry: RetryPolicy{MaxAttempts: 3}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	installation := newInstallation(t)
+	err := r.Run(ctx, installation, claim.ActionInstall, nil, ioutil.Discard)
+	assert.Check(t, is.ErrorContains(err, "operation interrupted"))
+	assert.Check(t, is.Len(installation.Attempts, 1))
+}
+
+func TestRunRejectsNonModifyingActions(t *testing.T) {
+	r := &Runner{Installations: store.NewMemoryInstallationStore(), Driver: fake.New()}
+	err := r.Run(context.Background(), newInstallation(t), "logs", nil, ioutil.Discard)
+	assert.Check(t, is.ErrorContains(err, `Action "logs" of installation "my-installation" does not modify it`))
+	err = r.Run(context.Background(), newInstallation(t), "unknown", nil, ioutil.Discard)
+	assert.Check(t, is.ErrorContains(err, "does not modify it"))
+}
+
+func TestResume(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New().Script("migrate", fake.Result{Err: errors.New("boom")})
+	r := &Runner{Installations: installations, Driver: d}
+
+	installation := newInstallation(t)
+	installation.Update(claim.ActionInstall, claim.StatusSuccess)
+	assert.NilError(t, installations.Store(installation))
+	assert.Check(t, r.Run(context.Background(), installation, "migrate", nil, ioutil.Discard) != nil)
+	failed := installation.Revision
+
+	resumed, err := r.Resume(context.Background(), "my-installation", nil, ioutil.Discard)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(resumed.Revision, failed))
+	assert.Check(t, is.Equal(resumed.Result.Action, "migrate"))
+	assert.Check(t, is.Equal(resumed.Result.Status, claim.StatusSuccess))
+	assert.Check(t, is.Len(resumed.Attempts, 2))
+
+	revisions, err := installations.Revisions("my-installation")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(revisions, 2))
+	assert.Check(t, is.Equal(revisions[1].Revision, failed))
+	assert.Check(t, is.Equal(revisions[1].Result.Status, claim.StatusSuccess))
+
+	// Successful actions cannot be resumed
+	_, err = r.Resume(context.Background(), "my-installation", nil, ioutil.Discard)
+	assert.Check(t, is.ErrorContains(err, "cannot be resumed, its last migrate is not failed nor interrupted"))
+}
+
+func TestRunHooks(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New().Script(claim.ActionInstall, fake.Result{Err: errors.New("boom")})
+	var calls []string
+	r := &Runner{
+		Installations: installations,
+		Driver:        d,
+		Retry:         RetryPolicy{MaxAttempts: 2},
+		Hooks: []Hook{
+			{
+				BeforeAction: func(ctx context.Context, e HookEvent) error {
+					assert.Check(t, e.Operation == nil)
+					assert.Check(t, is.Equal(e.Claim.Name, "my-installation"))
+					assert.Check(t, is.Equal(e.Bundle.Name, "my-app"))
+					calls = append(calls, fmt.Sprintf("before %s %d", e.Action, e.Attempt))
+					return nil
+				},
+				AfterAction: func(ctx context.Context, e HookEvent) {
+					calls = append(calls, fmt.Sprintf("after %d %s", e.Attempt, e.Claim.Result.Status))
+				},
+			},
+			{
+				OnError: func(ctx context.Context, e HookEvent, err error) {
+					calls = append(calls, fmt.Sprintf("error %d %s", e.Attempt, err))
+				},
+			},
+		},
+	}
+	recordSleeps(r, new([]time.Duration))
+
+	assert.NilError(t, r.Run(context.Background(), newInstallation(t), claim.ActionInstall, nil, ioutil.Discard))
+	assert.Check(t, is.DeepEqual(calls, []string{
+		"before install 1",
+		"after 1 failure",
+		"error 1 boom",
+		"before install 2",
+		"after 2 success",
+	}))
+}
+
+func TestRunHookVeto(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	d := fake.New()
+	var errs []error
+	r := &Runner{
+		Installations: installations,
+		Driver:        d,
+		Retry:         RetryPolicy{MaxAttempts: 3},
+		Hooks: []Hook{{
+			BeforeAction: func(ctx context.Context, e HookEvent) error {
+				return errors.New("not approved")
+			},
+			AfterAction: func(ctx context.Context, e HookEvent) {
+				t.Error("vetoed actions do not run")
+			},
+			OnError: func(ctx context.Context, e HookEvent, err error) {
+				errs = append(errs, err)
+			},
+		}},
+	}
+
+	installation := newInstallation(t)
+	revision := installation.Revision
+	err := r.Run(context.Background(), installation, claim.ActionInstall, nil, ioutil.Discard)
+	assert.Check(t, is.Error(err, "action vetoed: not approved"))
+	assert.Check(t, IsVetoed(err))
+	assert.Check(t, is.Len(d.Operations(), 0))
+	assert.Check(t, is.Len(errs, 1))
+	// The vetoed attempt is neither recorded nor stored
+	assert.Check(t, is.Len(installation.Attempts, 0))
+	assert.Check(t, is.Equal(installation.Revision, revision))
+	assert.Check(t, is.Equal(installation.Result.Status, claim.StatusUnknown))
+	_, err = installations.Read("my-installation")
+	assert.Check(t, err != nil)
+}
+
+func TestRunHookVetoKeepsUpgradedInstallation(t *testing.T) {
+	installations := store.NewMemoryInstallationStore()
+	installation := newInstallation(t)
+	r := &Runner{Installations: installations, Driver: fake.New()}
+	assert.NilError(t, r.Run(context.Background(), installation, claim.ActionInstall, nil, ioutil.Discard))
+	installed := installation.Revision
+
+	r.Hooks = []Hook{{
+		BeforeAction: func(ctx context.Context, e HookEvent) error {
+			return errors.New("denied")
+		},
+	}}
+	installation.Bundle.Version = "2.0.0"
+	err := r.Run(context.Background(), installation, claim.ActionUpgrade, nil, ioutil.Discard)
+	assert.Check(t, IsVetoed(err))
+	stored, err := installations.Read("my-installation")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(stored.Revision, installed))
+	assert.Check(t, is.Equal(stored.Bundle.Version, "1.0.0"))
+	assert.Check(t, is.Equal(stored.Result.Action, claim.ActionInstall))
+	assert.Check(t, is.Equal(stored.Result.Status, claim.StatusSuccess))
+}
@@ -0,0 +1,184 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/pkg/errors"
+)
+
+// Trivy scans images with the Trivy command line.
+type Trivy struct {
+	// Binary is the Trivy executable, "trivy" by default.
+	Binary string
+}
+
+var _ Scanner = &Trivy{}
+
+// Scan runs "trivy image" on the docker and OCI images. Images of other
+// types are not scanned.
+func (t *Trivy) Scan(ctx context.Context, image cnab.InventoryImage) (Report, error) {
+	if !scannable(image) {
+		return Report{Image: image}, nil
+	}
+	out, err := runScanner(ctx, t.Binary, "trivy", "image", "--format", "json", "--quiet", target(image))
+	if err != nil {
+		return Report{}, err
+	}
+	vulnerabilities, err := ParseTrivyReport(out)
+	return Report{Image: image, Vulnerabilities: vulnerabilities}, err
+}
+
+// ParseTrivyReport returns the vulnerabilities of a Trivy JSON report, in
+// the current format or the list of results of older versions.
+func ParseTrivyReport(data []byte) ([]Vulnerability, error) {
+	type trivyResult struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string
+			PkgName          string
+			InstalledVersion string
+			FixedVersion     string
+			Severity         string
+			Title            string
+		}
+	}
+	var results []trivyResult
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, errors.Wrap(err, "invalid Trivy report")
+		}
+	} else {
+		var report struct {
+			Results []trivyResult
+		}
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, errors.Wrap(err, "invalid Trivy report")
+		}
+		results = report.Results
+	}
+	var vulnerabilities []Vulnerability
+	for _, result := range results {
+		for _, v := range result.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, Vulnerability{
+				ID:               v.VulnerabilityID,
+				Severity:         normalizeSeverity(v.Severity),
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Title:            v.Title,
+			})
+		}
+	}
+	return vulnerabilities, nil
+}
+
+// Grype scans images with the Grype command line.
+type Grype struct {
+	// Binary is the Grype executable, "grype" by default.
+	Binary string
+}
+
+var _ Scanner = &Grype{}
+
+// Scan runs grype on the docker and OCI images. Images of other types are
+// not scanned.
+func (g *Grype) Scan(ctx context.Context, image cnab.InventoryImage) (Report, error) {
+	if !scannable(image) {
+		return Report{Image: image}, nil
+	}
+	out, err := runScanner(ctx, g.Binary, "grype", target(image), "--output", "json", "--quiet")
+	if err != nil {
+		return Report{}, err
+	}
+	vulnerabilities, err := ParseGrypeReport(out)
+	return Report{Image: image, Vulnerabilities: vulnerabilities}, err
+}
+
+// ParseGrypeReport returns the vulnerabilities of a Grype JSON report.
+func ParseGrypeReport(data []byte) ([]Vulnerability, error) {
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID          string `json:"id"`
+				Severity    string `json:"severity"`
+				Description string `json:"description"`
+				Fix         struct {
+					Versions []string `json:"versions"`
+				} `json:"fix"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, errors.Wrap(err, "invalid Grype report")
+	}
+	var vulnerabilities []Vulnerability
+	for _, m := range report.Matches {
+		vulnerabilities = append(vulnerabilities, Vulnerability{
+			ID:               m.Vulnerability.ID,
+			Severity:         normalizeSeverity(m.Vulnerability.Severity),
+			Package:          m.Artifact.Name,
+			InstalledVersion: m.Artifact.Version,
+			FixedVersion:     strings.Join(m.Vulnerability.Fix.Versions, ", "),
+			Title:            m.Vulnerability.Description,
+		})
+	}
+	return vulnerabilities, nil
+}
+
+// normalizeSeverity maps the severities of the scanners to the severities
+// of this package, unknown ones to SeverityUnknown.
+func normalizeSeverity(s string) Severity {
+	severity, err := ParseSeverity(s)
+	if err != nil {
+		return SeverityUnknown
+	}
+	return severity
+}
+
+func scannable(image cnab.InventoryImage) bool {
+	return image.ImageType == "docker" || image.ImageType == "oci"
+}
+
+// target returns the reference of the image to scan, pinned to its digest
+// if known.
+func target(image cnab.InventoryImage) string {
+	if image.Digest != "" && !strings.Contains(image.Reference, "@") {
+		return image.Reference + "@" + image.Digest
+	}
+	return image.Reference
+}
+
+func runScanner(ctx context.Context, binary, defaultBinary string, args ...string) ([]byte, error) {
+	if binary == "" {
+		binary = defaultBinary
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "failed to run %s: %s", binary, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// New returns the scanner of the given name, "trivy" or "grype".
+func New(name string) (Scanner, error) {
+	switch name {
+	case "trivy":
+		return &Trivy{}, nil
+	case "grype":
+		return &Grype{}, nil
+	}
+	return nil, errors.Errorf("unknown vulnerability scanner %q, expected trivy or grype", name)
+}
@@ -0,0 +1,145 @@
+// Package scan integrates vulnerability scanners, like Trivy or Grype, to
+// check the images of a bundle before running it.
+package scan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/policy"
+	"github.com/pkg/errors"
+)
+
+// Severity is the severity of a vulnerability.
+type Severity string
+
+// Severities of vulnerabilities, from the least to the most severe.
+const (
+	SeverityUnknown    Severity = "UNKNOWN"
+	SeverityNegligible Severity = "NEGLIGIBLE"
+	SeverityLow        Severity = "LOW"
+	SeverityMedium     Severity = "MEDIUM"
+	SeverityHigh       Severity = "HIGH"
+	SeverityCritical   Severity = "CRITICAL"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityUnknown:    0,
+	SeverityNegligible: 1,
+	SeverityLow:        2,
+	SeverityMedium:     3,
+	SeverityHigh:       4,
+	SeverityCritical:   5,
+}
+
+// ParseSeverity parses a severity, ignoring case.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToUpper(s))
+	if _, ok := severityRanks[severity]; !ok {
+		return "", errors.Errorf("unknown vulnerability severity %q", s)
+	}
+	return severity, nil
+}
+
+// AtLeast returns true if the severity is the same or more severe than the
+// other one.
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// Vulnerability is a vulnerability found in a package of an image.
+type Vulnerability struct {
+	// ID is the identifier of the vulnerability, like a CVE identifier.
+	ID               string   `json:"id"`
+	Severity         Severity `json:"severity"`
+	Package          string   `json:"package,omitempty"`
+	InstalledVersion string   `json:"installedVersion,omitempty"`
+	// FixedVersion is the version of the package fixing the
+	// vulnerability, if any.
+	FixedVersion string `json:"fixedVersion,omitempty"`
+	Title        string `json:"title,omitempty"`
+}
+
+// Report lists the vulnerabilities found in an image.
+type Report struct {
+	Image           cnab.InventoryImage `json:"image"`
+	Vulnerabilities []Vulnerability     `json:"vulnerabilities,omitempty"`
+}
+
+// Scanner finds the vulnerabilities of an image.
+type Scanner interface {
+	Scan(ctx context.Context, image cnab.InventoryImage) (Report, error)
+}
+
+// ScanBundle scans every image of the bundle inventory, see
+// cnab.ImageInventory.
+func ScanBundle(ctx context.Context, scanner Scanner, b *bundle.Bundle) ([]Report, error) {
+	inventory, err := cnab.ImageInventory(b)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]Report, 0, len(inventory))
+	for _, image := range inventory {
+		report, err := scanner.Scan(ctx, image)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to scan image %s", image.Reference)
+		}
+		report.Image = image
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// Evaluator is a policy evaluator denying the bundles whose images have
+// vulnerabilities of the given severity or more.
+type Evaluator struct {
+	Scanner Scanner
+	// Severity defaults to SeverityCritical.
+	Severity Severity
+}
+
+var _ policy.Evaluator = &Evaluator{}
+
+// Evaluate scans the images of the bundle of the input and reports a
+// violation per vulnerability at or above the severity.
+func (e *Evaluator) Evaluate(ctx context.Context, input policy.Input) (policy.Decision, error) {
+	if input.Bundle == nil {
+		return policy.Decision{}, nil
+	}
+	reports, err := ScanBundle(ctx, e.Scanner, input.Bundle)
+	if err != nil {
+		return policy.Decision{}, err
+	}
+	return Decide(reports, e.Severity), nil
+}
+
+// Decide returns a policy decision with a violation per vulnerability of
+// the reports at or above the severity, which defaults to SeverityCritical.
+// It lets the reports produced by any tool be checked against a policy.
+func Decide(reports []Report, severity Severity) policy.Decision {
+	if severity == "" {
+		severity = SeverityCritical
+	}
+	var decision policy.Decision
+	for _, report := range reports {
+		for _, v := range report.Vulnerabilities {
+			if !v.Severity.AtLeast(severity) {
+				continue
+			}
+			msg := fmt.Sprintf("image %s has %s vulnerability %s", report.Image.Reference, strings.ToLower(string(v.Severity)), v.ID)
+			if v.Package != "" {
+				msg += fmt.Sprintf(" in %s %s", v.Package, v.InstalledVersion)
+			}
+			if v.FixedVersion != "" {
+				msg += fmt.Sprintf(", fixed in %s", v.FixedVersion)
+			}
+			decision.Violations = append(decision.Violations, msg)
+		}
+	}
+	sort.Strings(decision.Violations)
+	return decision
+}
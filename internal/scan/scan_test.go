@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/policy"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// fakeScanner returns the vulnerabilities of the images by reference.
+type fakeScanner map[string][]Vulnerability
+
+func (f fakeScanner) Scan(_ context.Context, image cnab.InventoryImage) (Report, error) {
+	if image.Reference == "docker.io/test/broken:0.1.0" {
+		return Report{}, errors.New("boom")
+	}
+	return Report{Vulnerabilities: f[image.Reference]}, nil
+}
+
+func TestSeverity(t *testing.T) {
+	severity, err := ParseSeverity("high")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(severity, SeverityHigh))
+	assert.Check(t, SeverityCritical.AtLeast(SeverityHigh))
+	assert.Check(t, SeverityHigh.AtLeast(SeverityHigh))
+	assert.Check(t, !SeverityMedium.AtLeast(SeverityHigh))
+	_, err = ParseSeverity("severe")
+	assert.Check(t, is.Error(err, `unknown vulnerability severity "severe"`))
+}
+
+func TestEvaluator(t *testing.T) {
+	scanner := fakeScanner{
+		"docker.io/test/test-bundle-invoc:0.1.0": {
+			{ID: "CVE-2020-0001", Severity: SeverityCritical, Package: "openssl", InstalledVersion: "1.1.1", FixedVersion: "1.1.1g"},
+			{ID: "CVE-2020-0002", Severity: SeverityMedium, Package: "zlib", InstalledVersion: "1.2"},
+		},
+		"docker.io/test/image-0:0.1.0": {
+			{ID: "CVE-2020-0003", Severity: SeverityHigh},
+		},
+	}
+	input := policy.NewInput("install", "myapp", bundletest.NewTestBundle(bundletest.WithImages(1)), nil, policy.Environment{})
+
+	decision, err := (&Evaluator{Scanner: scanner}).Evaluate(context.Background(), input)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(decision.Violations, []string{
+		"image docker.io/test/test-bundle-invoc:0.1.0 has critical vulnerability CVE-2020-0001 in openssl 1.1.1, fixed in 1.1.1g",
+	}))
+
+	decision, err = (&Evaluator{Scanner: scanner, Severity: SeverityHigh}).Evaluate(context.Background(), input)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(decision.Violations, []string{
+		"image docker.io/test/image-0:0.1.0 has high vulnerability CVE-2020-0003",
+		"image docker.io/test/test-bundle-invoc:0.1.0 has critical vulnerability CVE-2020-0001 in openssl 1.1.1, fixed in 1.1.1g",
+	}))
+
+	input.Bundle.Images["broken"] = input.Bundle.Images["image-0"]
+	img := input.Bundle.Images["broken"]
+	img.Image = "test/broken:0.1.0"
+	input.Bundle.Images["broken"] = img
+	_, err = (&Evaluator{Scanner: scanner}).Evaluate(context.Background(), input)
+	assert.Check(t, is.Error(err, "failed to scan image docker.io/test/broken:0.1.0: boom"))
+}
+
+func TestParseTrivyReport(t *testing.T) {
+	report := `{"SchemaVersion":2,"Results":[{"Target":"nginx","Vulnerabilities":[
+		{"VulnerabilityID":"CVE-2020-0001","PkgName":"openssl","InstalledVersion":"1.1.1","FixedVersion":"1.1.1g","Severity":"CRITICAL","Title":"overflow"},
+		{"VulnerabilityID":"CVE-2020-0002","PkgName":"zlib","InstalledVersion":"1.2","Severity":"weird"}]}]}`
+	vulnerabilities, err := ParseTrivyReport([]byte(report))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(vulnerabilities, []Vulnerability{
+		{ID: "CVE-2020-0001", Severity: SeverityCritical, Package: "openssl", InstalledVersion: "1.1.1", FixedVersion: "1.1.1g", Title: "overflow"},
+		{ID: "CVE-2020-0002", Severity: SeverityUnknown, Package: "zlib", InstalledVersion: "1.2"},
+	}))
+
+	// Older versions report a list of results
+	vulnerabilities, err = ParseTrivyReport([]byte(`[{"Target":"nginx","Vulnerabilities":[{"VulnerabilityID":"CVE-2020-0001","Severity":"LOW"}]}]`))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(vulnerabilities, []Vulnerability{{ID: "CVE-2020-0001", Severity: SeverityLow}}))
+
+	_, err = ParseTrivyReport([]byte(`{`))
+	assert.Check(t, is.ErrorContains(err, "invalid Trivy report"))
+}
+
+func TestParseGrypeReport(t *testing.T) {
+	report := `{"matches":[{"vulnerability":{"id":"CVE-2020-0001","severity":"Critical","description":"overflow","fix":{"versions":["1.1.1g"],"state":"fixed"}},"artifact":{"name":"openssl","version":"1.1.1"}}]}`
+	vulnerabilities, err := ParseGrypeReport([]byte(report))
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(vulnerabilities, []Vulnerability{
+		{ID: "CVE-2020-0001", Severity: SeverityCritical, Package: "openssl", InstalledVersion: "1.1.1", FixedVersion: "1.1.1g", Title: "overflow"},
+	}))
+}
+
+func TestScannersSkipOtherImageTypes(t *testing.T) {
+	image := cnab.InventoryImage{ImageType: "wasm", Reference: "https://example.com/module.wasm"}
+	for _, scanner := range []Scanner{&Trivy{Binary: "false"}, &Grype{Binary: "false"}} {
+		report, err := scanner.Scan(context.Background(), image)
+		assert.NilError(t, err)
+		assert.Check(t, is.Len(report.Vulnerabilities, 0))
+	}
+	_, err := (&Trivy{Binary: "false"}).Scan(context.Background(), cnab.InventoryImage{ImageType: "docker", Reference: "nginx"})
+	assert.Check(t, is.ErrorContains(err, "failed to run false"))
+	_, err = New("clair")
+	assert.Check(t, is.Error(err, `unknown vulnerability scanner "clair", expected trivy or grype`))
+}
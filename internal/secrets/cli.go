@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// runCommand runs a cloud provider CLI and returns its standard output. It is
+// replaced in tests.
+var runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return nil, errors.Errorf("%s failed: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+func runCLI(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := runCommand(ctx, name, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// AWSSecretsManager reads the string value of a secret, identified by its
+// name or ARN, with the aws CLI and its configured profile.
+var AWSSecretsManager Provider = ProviderFunc(func(ctx context.Context, path string) (string, error) {
+	return runCLI(ctx, "aws", "secretsmanager", "get-secret-value", "--secret-id", path, "--query", "SecretString", "--output", "text")
+})
+
+// AzureKeyVault reads a secret, identified by "<vault>/<secret>", with the az
+// CLI and its logged in account.
+var AzureKeyVault Provider = ProviderFunc(func(ctx context.Context, path string) (string, error) {
+	segments, err := splitPath(path, "<vault>/<secret>", 2, 2)
+	if err != nil {
+		return "", err
+	}
+	return runCLI(ctx, "az", "keyvault", "secret", "show", "--vault-name", segments[0], "--name", segments[1], "--query", "value", "--output", "tsv")
+})
+
+// GCPSecretManager reads a secret version, identified by
+// "<project>/<secret>[/<version>]", with the gcloud CLI and its logged in
+// account. The latest version is read by default.
+var GCPSecretManager Provider = ProviderFunc(func(ctx context.Context, path string) (string, error) {
+	segments, err := splitPath(path, "<project>/<secret>[/<version>]", 2, 3)
+	if err != nil {
+		return "", err
+	}
+	version := "latest"
+	if len(segments) == 3 {
+		version = segments[2]
+	}
+	return runCLI(ctx, "gcloud", "secrets", "versions", "access", version, "--secret", segments[1], "--project", segments[0])
+})
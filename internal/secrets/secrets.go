@@ -0,0 +1,164 @@
+// Package secrets resolves references to secrets held by external secret
+// managers, like "vault://secret/data/db#password", so credential sets do not
+// need to expose secrets through environment variables or files.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Reference identifies a secret in a secret manager, written
+// "<scheme>://<path>[#<key>]". The key selects a field when the secret is a
+// JSON object.
+type Reference struct {
+	Scheme string
+	Path   string
+	Key    string
+}
+
+func (r Reference) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// ParseReference parses a secret reference. It returns false if s is not a
+// reference.
+func ParseReference(s string) (Reference, bool) {
+	split := strings.SplitN(s, "://", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" || strings.ContainsAny(split[0], " /") {
+		return Reference{}, false
+	}
+	ref := Reference{Scheme: split[0], Path: split[1]}
+	if i := strings.LastIndex(ref.Path, "#"); i >= 0 {
+		ref.Path, ref.Key = ref.Path[:i], ref.Path[i+1:]
+	}
+	return ref, ref.Path != ""
+}
+
+// Provider fetches secrets from a secret manager.
+type Provider interface {
+	// Get returns the raw value of the secret at the given path.
+	Get(ctx context.Context, path string) (string, error)
+}
+
+// Registry maps reference schemes to secret providers.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{providers: map[string]Provider{}}
+}
+
+// Default returns a registry with the built-in providers:
+//   - vault://<path>#<key> for HashiCorp Vault, configured with VAULT_ADDR
+//     and VAULT_TOKEN
+//   - awssm://<secret-id>#<key> for AWS Secrets Manager, using the aws CLI
+//   - azkv://<vault>/<secret>#<key> for Azure Key Vault, using the az CLI
+//   - gcpsm://<project>/<secret>[/<version>]#<key> for GCP Secret Manager,
+//     using the gcloud CLI
+func Default() *Registry {
+	r := NewRegistry()
+	r.Register("vault", VaultFromEnv())
+	r.Register("awssm", AWSSecretsManager)
+	r.Register("azkv", AzureKeyVault)
+	r.Register("gcpsm", GCPSecretManager)
+	return r
+}
+
+// Register adds, or replaces, the provider of a scheme.
+func (r *Registry) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// Schemes returns the registered schemes, sorted.
+func (r *Registry) Schemes() []string {
+	schemes := make([]string, 0, len(r.providers))
+	for scheme := range r.providers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// IsReference returns true if s is a reference to a registered provider.
+func (r *Registry) IsReference(s string) bool {
+	ref, ok := ParseReference(s)
+	if !ok {
+		return false
+	}
+	_, ok = r.providers[ref.Scheme]
+	return ok
+}
+
+// Resolve fetches the secret referenced by s. If the reference has a key,
+// the secret must be a JSON object and the value of the key is returned.
+func (r *Registry) Resolve(ctx context.Context, s string) (string, error) {
+	ref, ok := ParseReference(s)
+	if !ok {
+		return "", errors.Errorf("invalid secret reference %q", s)
+	}
+	provider, ok := r.providers[ref.Scheme]
+	if !ok {
+		return "", errors.Errorf("unknown secret provider %q, supported providers are %s", ref.Scheme, strings.Join(r.Schemes(), ", "))
+	}
+	value, err := provider.Get(ctx, ref.Path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to fetch secret %s", ref)
+	}
+	if ref.Key == "" {
+		return value, nil
+	}
+	return selectKey(value, ref.Key)
+}
+
+func selectKey(value, key string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", errors.Errorf("cannot select key %q, the secret is not a JSON object", key)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", errors.Errorf("key %q not found in secret", key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(field)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ProviderFunc adapts a function to a Provider.
+type ProviderFunc func(ctx context.Context, path string) (string, error)
+
+// Get calls f.
+func (f ProviderFunc) Get(ctx context.Context, path string) (string, error) {
+	return f(ctx, path)
+}
+
+// splitPath splits a provider path in exactly min to max non-empty segments.
+func splitPath(path, format string, min, max int) ([]string, error) {
+	segments := strings.Split(path, "/")
+	if len(segments) < min || len(segments) > max {
+		return nil, fmt.Errorf("invalid secret path %q, expected %s", path, format)
+	}
+	for _, s := range segments {
+		if s == "" {
+			return nil, fmt.Errorf("invalid secret path %q, expected %s", path, format)
+		}
+	}
+	return segments, nil
+}
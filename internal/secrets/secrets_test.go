@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParseReference(t *testing.T) {
+	ref, ok := ParseReference("vault://secret/data/db#password")
+	assert.Assert(t, ok)
+	assert.Check(t, is.DeepEqual(ref, Reference{Scheme: "vault", Path: "secret/data/db", Key: "password"}))
+	assert.Check(t, is.Equal(ref.String(), "vault://secret/data/db#password"))
+
+	for _, s := range []string{"password", "vault://", "://path", "my value://x", "vault://#key"} {
+		_, ok := ParseReference(s)
+		assert.Check(t, !ok, s)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	r := NewRegistry()
+	r.Register("test", ProviderFunc(func(_ context.Context, path string) (string, error) {
+		switch path {
+		case "plain":
+			return "s3cr3t", nil
+		case "json":
+			return `{"user": "admin", "password": "s3cr3t", "port": 5432}`, nil
+		}
+		return "", errors.New("not found")
+	}))
+	ctx := context.Background()
+
+	value, err := r.Resolve(ctx, "test://plain")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "s3cr3t"))
+	value, err = r.Resolve(ctx, "test://json#password")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "s3cr3t"))
+	value, err = r.Resolve(ctx, "test://json#port")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "5432"))
+
+	_, err = r.Resolve(ctx, "test://json#missing")
+	assert.Check(t, is.ErrorContains(err, `key "missing" not found in secret`))
+	_, err = r.Resolve(ctx, "test://plain#password")
+	assert.Check(t, is.ErrorContains(err, "the secret is not a JSON object"))
+	_, err = r.Resolve(ctx, "test://unknown")
+	assert.Check(t, is.ErrorContains(err, "failed to fetch secret test://unknown: not found"))
+	_, err = r.Resolve(ctx, "other://plain")
+	assert.Check(t, is.ErrorContains(err, `unknown secret provider "other", supported providers are test`))
+
+	assert.Check(t, r.IsReference("test://plain"))
+	assert.Check(t, !r.IsReference("other://plain"))
+	assert.Check(t, !r.IsReference("plain"))
+}
+
+func TestVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/db":
+			w.Write([]byte(`{"data": {"data": {"password": "s3cr3t"}, "metadata": {"version": 2}}}`)) //nolint:errcheck
+		case "/v1/kv/db":
+			w.Write([]byte(`{"data": {"password": "0ld"}}`)) //nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := NewRegistry()
+	r.Register("vault", &Vault{Address: server.URL, Token: "token"})
+	ctx := context.Background()
+	value, err := r.Resolve(ctx, "vault://secret/data/db#password")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "s3cr3t"))
+	value, err = r.Resolve(ctx, "vault://kv/db#password")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(value, "0ld"))
+	_, err = r.Resolve(ctx, "vault://kv/missing#password")
+	assert.Check(t, is.ErrorContains(err, "vault returned 404 Not Found"))
+
+	r.Register("vault", &Vault{Address: server.URL})
+	_, err = r.Resolve(ctx, "vault://kv/db#password")
+	assert.Check(t, is.ErrorContains(err, "vault returned 403 Forbidden"))
+	r.Register("vault", &Vault{})
+	_, err = r.Resolve(ctx, "vault://kv/db#password")
+	assert.Check(t, is.ErrorContains(err, "vault address is not set"))
+}
+
+func TestCLIProviders(t *testing.T) {
+	defer func(orig func(context.Context, string, ...string) ([]byte, error)) { runCommand = orig }(runCommand)
+	var commands []string
+	runCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return []byte(`{"password": "s3cr3t"}` + "\n"), nil
+	}
+	r := Default()
+	ctx := context.Background()
+	for _, ref := range []string{"awssm://prod/db#password", "azkv://myvault/db#password", "gcpsm://myproject/db#password", "gcpsm://myproject/db/3#password"} {
+		value, err := r.Resolve(ctx, ref)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(value, "s3cr3t"), ref)
+	}
+	assert.Check(t, is.DeepEqual(commands, []string{
+		"aws secretsmanager get-secret-value --secret-id prod/db --query SecretString --output text",
+		"az keyvault secret show --vault-name myvault --name db --query value --output tsv",
+		"gcloud secrets versions access latest --secret db --project myproject",
+		"gcloud secrets versions access 3 --secret db --project myproject",
+	}))
+
+	_, err := r.Resolve(ctx, "azkv://db")
+	assert.Check(t, is.ErrorContains(err, `invalid secret path "db", expected <vault>/<secret>`))
+	_, err = r.Resolve(ctx, "gcpsm://myproject//1")
+	assert.Check(t, is.ErrorContains(err, "invalid secret path"))
+}
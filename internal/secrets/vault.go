@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Vault reads secrets from the HTTP API of HashiCorp Vault. The secret data
+// is returned as a JSON object, for both versions of the key-value engine.
+type Vault struct {
+	Address string
+	Token   string
+	Client  *http.Client
+}
+
+// VaultFromEnv returns a Vault provider configured by the standard VAULT_ADDR
+// and VAULT_TOKEN environment variables.
+func VaultFromEnv() *Vault {
+	return &Vault{Address: os.Getenv("VAULT_ADDR"), Token: os.Getenv("VAULT_TOKEN")}
+}
+
+// Get reads the secret at the given path, for instance "secret/data/db".
+func (v *Vault) Get(ctx context.Context, path string) (string, error) {
+	if v.Address == "" {
+		return "", errors.New("vault address is not set, use VAULT_ADDR")
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(v.Address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	if v.Token != "" {
+		req.Header.Set("X-Vault-Token", v.Token)
+	}
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("vault returned %s", resp.Status)
+	}
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", errors.Wrap(err, "invalid vault response")
+	}
+	data := secret.Data
+	// The version 2 of the key-value engine nests the data with its metadata
+	if nested, ok := data["data"]; ok {
+		if _, ok := data["metadata"]; ok {
+			return string(nested), nil
+		}
+	}
+	encoded, err := json.Marshal(data)
+	return string(encoded), err
+}
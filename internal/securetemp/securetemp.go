@@ -0,0 +1,152 @@
+package securetemp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Dir is a private temporary directory holding sensitive files, such as
+// resolved credentials or parameters injected into an invocation image.
+// The directory is only accessible by the current user and its files are
+// overwritten before being removed.
+type Dir struct {
+	mu      sync.Mutex
+	path    string
+	files   []string
+	removed bool
+}
+
+// NewDir creates a new private temporary directory, readable only by the
+// current user.
+func NewDir(prefix string) (*Dir, error) {
+	path, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary directory")
+	}
+	if err := os.Chmod(path, 0700); err != nil {
+		os.RemoveAll(path) //nolint:errcheck // best effort, the directory is still empty
+		return nil, errors.Wrap(err, "failed to create temporary directory")
+	}
+	return &Dir{path: path}, nil
+}
+
+// Path returns the path of the directory.
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// Write creates a file with 0600 permissions in the directory and returns
+// its path. The name may contain slashes, in which case the intermediate
+// directories are created.
+func (d *Dir) Write(name string, content []byte) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.removed {
+		return "", errors.Errorf("failed to write %q, temporary directory already removed", name)
+	}
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimLeft(name, "/")))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("invalid file name %q", name)
+	}
+	path := filepath.Join(d.path, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", errors.Wrapf(err, "failed to write %q", name)
+	}
+	d.files = append(d.files, path)
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		return "", errors.Wrapf(err, "failed to write %q", name)
+	}
+	return path, nil
+}
+
+// Materialize writes all the given files, keyed by their injection path,
+// and returns their path on the host, keyed the same way.
+func (d *Dir) Materialize(files map[string]string) (map[string]string, error) {
+	paths := make(map[string]string, len(files))
+	for name, content := range files {
+		path, err := d.Write(name, []byte(content))
+		if err != nil {
+			return nil, err
+		}
+		paths[name] = path
+	}
+	return paths, nil
+}
+
+// Remove overwrites all the files written in the directory with zeros and
+// removes the directory. It is safe to call Remove several times.
+func (d *Dir) Remove() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.removed {
+		return nil
+	}
+	d.removed = true
+	var shredErr error
+	for _, f := range d.files {
+		if err := shred(f); err != nil && shredErr == nil {
+			shredErr = err
+		}
+	}
+	if err := os.RemoveAll(d.path); err != nil {
+		return errors.Wrapf(err, "failed to remove temporary directory %q", d.path)
+	}
+	return shredErr
+}
+
+// With runs fn with a new private temporary directory. The directory is
+// removed when fn returns, panics, or as soon as ctx is cancelled.
+func With(ctx context.Context, prefix string, fn func(d *Dir) error) (err error) {
+	d, err := NewDir(prefix)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	cancelled := make(chan error, 1)
+	go func() {
+		defer close(cancelled)
+		select {
+		case <-ctx.Done():
+			cancelled <- d.Remove()
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		removeErr := d.Remove()
+		// the directory may already have been removed on cancellation,
+		// in which case the error of that removal is reported instead
+		if cancelErr := <-cancelled; cancelErr != nil {
+			removeErr = cancelErr
+		}
+		if removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}()
+	return fn(d)
+}
+
+func shred(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to overwrite %q", path)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "failed to overwrite %q", path)
+	}
+	if _, err := f.Write(make([]byte, st.Size())); err != nil {
+		return errors.Wrapf(err, "failed to overwrite %q", path)
+	}
+	return errors.Wrapf(f.Sync(), "failed to overwrite %q", path)
+}
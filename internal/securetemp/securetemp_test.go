@@ -0,0 +1,100 @@
+package securetemp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestMaterialize(t *testing.T) {
+	var dir string
+	err := With(context.Background(), t.Name(), func(d *Dir) error {
+		dir = d.Path()
+		st, err := os.Stat(dir)
+		assert.NilError(t, err)
+		assert.Equal(t, st.Mode().Perm(), os.FileMode(0700))
+
+		paths, err := d.Materialize(map[string]string{
+			"/cnab/app/creds.json": "secret",
+		})
+		assert.NilError(t, err)
+		path := paths["/cnab/app/creds.json"]
+		assert.Equal(t, path, filepath.Join(dir, "cnab", "app", "creds.json"))
+		st, err = os.Stat(path)
+		assert.NilError(t, err)
+		assert.Equal(t, st.Mode().Perm(), os.FileMode(0600))
+		content, err := ioutil.ReadFile(path)
+		assert.NilError(t, err)
+		assert.Equal(t, string(content), "secret")
+		return nil
+	})
+	assert.NilError(t, err)
+	_, err = os.Stat(dir)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestWriteRejectsEscapingNames(t *testing.T) {
+	d, err := NewDir(t.Name())
+	assert.NilError(t, err)
+	defer d.Remove()
+	_, err = d.Write("../escape", []byte("secret"))
+	assert.Check(t, is.ErrorContains(err, "invalid file name"))
+}
+
+func TestCleanupOnPanic(t *testing.T) {
+	var dir string
+	func() {
+		defer func() {
+			assert.Equal(t, recover(), "boom")
+		}()
+		With(context.Background(), t.Name(), func(d *Dir) error { //nolint:errcheck
+			dir = d.Path()
+			_, err := d.Write("secret", []byte("secret"))
+			assert.NilError(t, err)
+			panic("boom")
+		})
+	}()
+	_, err := os.Stat(dir)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestCleanupOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := With(ctx, t.Name(), func(d *Dir) error {
+		path, err := d.Write("secret", []byte("secret"))
+		assert.NilError(t, err)
+		cancel()
+		// wait for the directory to be removed, as the action would
+		// when being cancelled
+		for {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				return errors.New("cancelled")
+			}
+		}
+	})
+	assert.Error(t, err, "cancelled")
+}
+
+func TestCancellationRemoveError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := With(ctx, t.Name(), func(d *Dir) error {
+		path, err := d.Write("secret", []byte("secret"))
+		assert.NilError(t, err)
+		// a directory cannot be overwritten, making the removal fail
+		assert.NilError(t, os.Remove(path))
+		assert.NilError(t, os.Mkdir(path, 0700))
+		cancel()
+		for {
+			if _, err := os.Stat(d.Path()); os.IsNotExist(err) {
+				return nil
+			}
+		}
+	})
+	assert.Check(t, is.ErrorContains(err, "failed to overwrite"))
+}
@@ -0,0 +1,268 @@
+// Package semver parses semantic versions and matches them against ranges
+// such as ">=1.2.0 <2.0.0", "^1.2", "~1.2.3" or "1.x || 2.x".
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version is a semantic version. Build metadata is ignored.
+type Version struct {
+	Major, Minor, Patch int64
+	Prerelease          string
+}
+
+// Parse parses a version, allowing a leading "v".
+func Parse(s string) (Version, error) {
+	v, n, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if n != 3 {
+		return Version{}, errors.Errorf("invalid version %q: major, minor and patch are required", s)
+	}
+	return v, nil
+}
+
+// strictVersion is the regular expression of semantic versions given by the
+// specification.
+var strictVersion = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// ParseStrict parses a version which strictly follows the semantic
+// versioning specification: no leading "v", no leading zeros, and only
+// alphanumerics and hyphens in the prerelease and build metadata.
+func ParseStrict(s string) (Version, error) {
+	if !strictVersion.MatchString(s) {
+		return Version{}, errors.Errorf("invalid version %q: not a semantic version", s)
+	}
+	return Parse(s)
+}
+
+// parsePartial parses a possibly incomplete version ("1", "1.2", "1.x"),
+// returning the number of components actually set.
+func parsePartial(s string) (Version, int, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	var v Version
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if v.Prerelease == "" {
+			return Version{}, 0, errors.Errorf("invalid version %q: empty prerelease", raw)
+		}
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, errors.Errorf("invalid version %q", raw)
+	}
+	fields := []*int64{&v.Major, &v.Minor, &v.Patch}
+	n := 0
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || value < 0 {
+			return Version{}, 0, errors.Errorf("invalid version %q", raw)
+		}
+		*fields[i] = value
+		n++
+	}
+	return v, n, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or greater than o.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int64{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseInt(as[i], 10, 64)
+		bn, bErr := strconv.ParseInt(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(int64(len(as) - len(bs)))
+}
+
+func sign(d int64) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+type comparison struct {
+	op      string
+	version Version
+}
+
+func (c comparison) matches(v Version) bool {
+	r := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return r == 0
+	case "!=":
+		return r != 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	default: // "<="
+		return r <= 0
+	}
+}
+
+// Range is a set of alternatives ("||"), each made of comparisons which must
+// all match.
+type Range struct {
+	raw          string
+	alternatives [][]comparison
+}
+
+// ParseRange parses a version range.
+func ParseRange(s string) (Range, error) {
+	r := Range{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		var comparisons []comparison
+		for _, term := range strings.FieldsFunc(alternative, func(r rune) bool { return r == ' ' || r == ',' }) {
+			c, err := parseTerm(term)
+			if err != nil {
+				return Range{}, errors.Wrapf(err, "invalid version range %q", s)
+			}
+			comparisons = append(comparisons, c...)
+		}
+		r.alternatives = append(r.alternatives, comparisons)
+	}
+	return r, nil
+}
+
+func parseTerm(term string) ([]comparison, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+	v, n, err := parsePartial(strings.TrimPrefix(term, op))
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		// "*" matches any version
+		return nil, nil
+	}
+	switch op {
+	case "^":
+		return []comparison{{">=", v}, {"<", caretUpper(v, n)}}, nil
+	case "~":
+		return []comparison{{">=", v}, {"<", tildeUpper(v, n)}}, nil
+	case "", "=":
+		if n < 3 {
+			// partial versions ("1.2", "1.x") match the whole minor or major line
+			return []comparison{{">=", v}, {"<", tildeUpper(v, n)}}, nil
+		}
+		return []comparison{{"=", v}}, nil
+	}
+	return []comparison{{op, v}}, nil
+}
+
+// caretUpper allows changes which do not modify the left-most non-zero
+// component.
+func caretUpper(v Version, n int) Version {
+	switch {
+	case v.Major > 0 || n == 1:
+		return Version{Major: v.Major + 1, Prerelease: "0"}
+	case v.Minor > 0 || n == 2:
+		return Version{Minor: v.Minor + 1, Prerelease: "0"}
+	}
+	return Version{Patch: v.Patch + 1, Prerelease: "0"}
+}
+
+// tildeUpper allows patch level changes, or minor changes if only the major
+// version is given.
+func tildeUpper(v Version, n int) Version {
+	if n == 1 {
+		return Version{Major: v.Major + 1, Prerelease: "0"}
+	}
+	return Version{Major: v.Major, Minor: v.Minor + 1, Prerelease: "0"}
+}
+
+// Contains returns true if the version matches the range. Prerelease versions
+// only match if allowPrerelease is set.
+func (r Range) Contains(v Version, allowPrerelease bool) bool {
+	if v.Prerelease != "" && !allowPrerelease {
+		return false
+	}
+	for _, comparisons := range r.alternatives {
+		matched := true
+		for _, c := range comparisons {
+			if !c.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Range) String() string {
+	return r.raw
+}
@@ -0,0 +1,88 @@
+package semver
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-beta.1+build")
+	assert.NilError(t, err)
+	assert.Equal(t, v, Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "beta.1"})
+	assert.Equal(t, v.String(), "1.2.3-beta.1")
+
+	for _, invalid := range []string{"", "1.2", "1.2.3.4", "a.b.c", "1.2.3-", "1.-2.3"} {
+		_, err := Parse(invalid)
+		assert.Check(t, is.ErrorContains(err, "invalid version"), invalid)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	v, err := ParseStrict("1.2.3-beta.1+build.5")
+	assert.NilError(t, err)
+	assert.Equal(t, v, Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "beta.1"})
+
+	for _, invalid := range []string{"v1.2.3", "01.2.3", "1.2.3-01", "1.2.3-beta..1", "1.2.3+", "1.2.3-beta_1", " 1.2.3"} {
+		_, err := ParseStrict(invalid)
+		assert.Check(t, is.ErrorContains(err, "not a semantic version"), invalid)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{"0.9.0", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0", "1.0.1", "1.10.0"}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, err := Parse(ordered[i])
+		assert.NilError(t, err)
+		b, err := Parse(ordered[i+1])
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(a.Compare(b), -1), "%s < %s", a, b)
+		assert.Check(t, is.Equal(b.Compare(a), 1), "%s > %s", b, a)
+		assert.Check(t, is.Equal(a.Compare(a), 0))
+	}
+}
+
+func TestRange(t *testing.T) {
+	testCases := []struct {
+		rng      string
+		matching []string
+		other    []string
+	}{
+		{">=1.2.0 <2.0.0", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{">=1.2.0, <2.0.0", []string{"1.5.0"}, []string{"2.1.0"}},
+		{"^1.2", []string{"1.2.0", "1.99.0"}, []string{"1.1.0", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{"1.x || 3.1", []string{"1.0.0", "1.5.2", "3.1.7"}, []string{"2.0.0", "3.2.0"}},
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"*", []string{"0.0.1", "10.0.0"}, []string{"1.0.0-rc1"}},
+	}
+	for _, tc := range testCases {
+		r, err := ParseRange(tc.rng)
+		assert.NilError(t, err)
+		for _, s := range tc.matching {
+			v, err := Parse(s)
+			assert.NilError(t, err)
+			assert.Check(t, r.Contains(v, false), "%s should match %s", s, tc.rng)
+		}
+		for _, s := range tc.other {
+			v, err := Parse(s)
+			assert.NilError(t, err)
+			assert.Check(t, !r.Contains(v, false), "%s should not match %s", s, tc.rng)
+		}
+	}
+}
+
+func TestRangePrerelease(t *testing.T) {
+	r, err := ParseRange(">=1.0.0-0 <2.0.0")
+	assert.NilError(t, err)
+	v, err := Parse("1.0.0-rc1")
+	assert.NilError(t, err)
+	assert.Check(t, !r.Contains(v, false))
+	assert.Check(t, r.Contains(v, true))
+
+	_, err = ParseRange(">=foo")
+	assert.Check(t, is.ErrorContains(err, `invalid version range ">=foo"`))
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Security is how Serve authenticates its clients. As the service runs the
+// actions of any bundle it is given, it is never served without client
+// certificates or a token.
+type Security struct {
+	// TLS serves over TLS. The clients are authenticated by their
+	// certificate if its ClientAuth is tls.RequireAndVerifyClientCert, see
+	// LoadTLSConfig.
+	TLS *tls.Config
+	// Token is the secret the clients must send as a bearer token, in the
+	// Authorization header of the HTTP requests and the "authorization"
+	// metadata of the gRPC calls.
+	Token string
+}
+
+func (s Security) validate() error {
+	if s.Token != "" {
+		return nil
+	}
+	if s.TLS == nil || s.TLS.ClientAuth != tls.RequireAndVerifyClientCert {
+		return errors.New("the server requires TLS client certificates or a token to authenticate its clients")
+	}
+	return nil
+}
+
+// LoadTLSConfig loads the certificate and key of the server, and the CA
+// certificates the client certificates are verified with, if any.
+func LoadTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the server certificate")
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		data, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.Errorf("no certificate found in %q", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// validToken compares the Authorization value to the token in constant time.
+func validToken(authorization, token string) bool {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authorization, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(authorization, prefix)), []byte(token)) == 1
+}
+
+// requireToken rejects the HTTP requests without the bearer token.
+func requireToken(h http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validToken(r.Header.Get("Authorization"), token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, status.Error(codes.Unauthenticated, "a valid bearer token is required"), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// tokenInterceptor rejects the gRPC calls without the bearer token.
+func tokenInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var authorization string
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+		if !validToken(authorization, token) {
+			return nil, status.Error(codes.Unauthenticated, "a valid bearer token is required")
+		}
+		return handler(ctx, req)
+	}
+}
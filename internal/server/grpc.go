@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// The gRPC service of bundles.proto, written by hand as protoc-gen-go would
+// generate it, see proto_test.go.
+
+const serviceName = "docker.app.v1.Bundles"
+
+// BundlesServer is the server API of the Bundles service.
+type BundlesServer interface {
+	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
+	Describe(context.Context, *DescribeRequest) (*DescribeResponse, error)
+	PinDigests(context.Context, *PinDigestsRequest) (*PinDigestsResponse, error)
+	Diff(context.Context, *DiffRequest) (*DiffResponse, error)
+	ResolveParameters(context.Context, *ResolveParametersRequest) (*ResolveParametersResponse, error)
+	RunAction(context.Context, *RunActionRequest) (*RunActionResponse, error)
+}
+
+// RegisterBundlesServer registers the Bundles service on the gRPC server.
+func RegisterBundlesServer(s *grpc.Server, srv BundlesServer) {
+	s.RegisterService(&bundlesServiceDesc, srv)
+}
+
+// unaryHandler adapts a method of the service to a gRPC handler.
+func unaryHandler(method string, newRequest func() interface{}, call func(srv BundlesServer, ctx context.Context, req interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := newRequest()
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(BundlesServer), ctx, req)
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + method}
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
+
+var bundlesServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*BundlesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("Validate", func() interface{} { return new(ValidateRequest) }, func(srv BundlesServer, ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.Validate(ctx, req.(*ValidateRequest))
+		}),
+		unaryHandler("Describe", func() interface{} { return new(DescribeRequest) }, func(srv BundlesServer, ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.Describe(ctx, req.(*DescribeRequest))
+		}),
+		unaryHandler("PinDigests", func() interface{} { return new(PinDigestsRequest) }, func(srv BundlesServer, ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.PinDigests(ctx, req.(*PinDigestsRequest))
+		}),
+		unaryHandler("Diff", func() interface{} { return new(DiffRequest) }, func(srv BundlesServer, ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.Diff(ctx, req.(*DiffRequest))
+		}),
+		unaryHandler("ResolveParameters", func() interface{} { return new(ResolveParametersRequest) }, func(srv BundlesServer, ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ResolveParameters(ctx, req.(*ResolveParametersRequest))
+		}),
+		unaryHandler("RunAction", func() interface{} { return new(RunActionRequest) }, func(srv BundlesServer, ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.RunAction(ctx, req.(*RunActionRequest))
+		}),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bundles.proto",
+}
+
+// BundlesClient is the client API of the Bundles service.
+type BundlesClient interface {
+	Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+	Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeResponse, error)
+	PinDigests(ctx context.Context, in *PinDigestsRequest, opts ...grpc.CallOption) (*PinDigestsResponse, error)
+	Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error)
+	ResolveParameters(ctx context.Context, in *ResolveParametersRequest, opts ...grpc.CallOption) (*ResolveParametersResponse, error)
+	RunAction(ctx context.Context, in *RunActionRequest, opts ...grpc.CallOption) (*RunActionResponse, error)
+}
+
+type bundlesClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewBundlesClient returns a client of the Bundles service.
+func NewBundlesClient(cc *grpc.ClientConn) BundlesClient {
+	return &bundlesClient{cc}
+}
+
+func (c *bundlesClient) invoke(ctx context.Context, method string, in, out interface{}, opts []grpc.CallOption) error {
+	return c.cc.Invoke(ctx, "/"+serviceName+"/"+method, in, out, opts...)
+}
+
+func (c *bundlesClient) Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	out := new(ValidateResponse)
+	if err := c.invoke(ctx, "Validate", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bundlesClient) Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeResponse, error) {
+	out := new(DescribeResponse)
+	if err := c.invoke(ctx, "Describe", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bundlesClient) PinDigests(ctx context.Context, in *PinDigestsRequest, opts ...grpc.CallOption) (*PinDigestsResponse, error) {
+	out := new(PinDigestsResponse)
+	if err := c.invoke(ctx, "PinDigests", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bundlesClient) Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error) {
+	out := new(DiffResponse)
+	if err := c.invoke(ctx, "Diff", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bundlesClient) ResolveParameters(ctx context.Context, in *ResolveParametersRequest, opts ...grpc.CallOption) (*ResolveParametersResponse, error) {
+	out := new(ResolveParametersResponse)
+	if err := c.invoke(ctx, "ResolveParameters", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bundlesClient) RunAction(ctx context.Context, in *RunActionRequest, opts ...grpc.CallOption) (*RunActionResponse, error) {
+	out := new(RunActionResponse)
+	if err := c.invoke(ctx, "RunAction", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
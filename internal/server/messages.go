@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The messages of bundles.proto. They are written by hand, the protobuf
+// library encoding them from their struct tags, which proto_test.go checks
+// against bundles.proto.
+
+// Document is a JSON document, like a bundle or parameter values. It is a
+// string in protobuf messages and a JSON value in REST bodies.
+type Document string
+
+// MarshalJSON returns the document as is, or null if it is empty.
+func (d Document) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	if !json.Valid([]byte(d)) {
+		return nil, &json.UnsupportedValueError{Str: "invalid JSON document"}
+	}
+	return []byte(d), nil
+}
+
+// UnmarshalJSON keeps the JSON value as the document.
+func (d *Document) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = ""
+		return nil
+	}
+	*d = Document(data)
+	return nil
+}
+
+// ValidateRequest is the request of Validate.
+type ValidateRequest struct {
+	Bundle           Document `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	StrictImageTypes bool     `protobuf:"varint,2,opt,name=strict_image_types,json=strictImageTypes,proto3" json:"strictImageTypes,omitempty"`
+}
+
+func (m *ValidateRequest) Reset()         { *m = ValidateRequest{} }
+func (m *ValidateRequest) String() string { return proto.CompactTextString(m) }
+func (*ValidateRequest) ProtoMessage()    {}
+
+// ValidationError is a violation found in a bundle, see cnab.ValidationError.
+type ValidationError struct {
+	Path     string `protobuf:"bytes,1,opt,name=path,proto3" json:"path"`
+	Code     string `protobuf:"bytes,2,opt,name=code,proto3" json:"code"`
+	Severity string `protobuf:"bytes,3,opt,name=severity,proto3" json:"severity"`
+	Message  string `protobuf:"bytes,4,opt,name=message,proto3" json:"message"`
+}
+
+func (m *ValidationError) Reset()         { *m = ValidationError{} }
+func (m *ValidationError) String() string { return proto.CompactTextString(m) }
+func (*ValidationError) ProtoMessage()    {}
+
+// ValidateResponse is the response of Validate.
+type ValidateResponse struct {
+	Valid  bool               `protobuf:"varint,1,opt,name=valid,proto3" json:"valid"`
+	Errors []*ValidationError `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"`
+}
+
+func (m *ValidateResponse) Reset()         { *m = ValidateResponse{} }
+func (m *ValidateResponse) String() string { return proto.CompactTextString(m) }
+func (*ValidateResponse) ProtoMessage()    {}
+
+// DescribeRequest is the request of Describe.
+type DescribeRequest struct {
+	Bundle Document `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	Format string   `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+}
+
+func (m *DescribeRequest) Reset()         { *m = DescribeRequest{} }
+func (m *DescribeRequest) String() string { return proto.CompactTextString(m) }
+func (*DescribeRequest) ProtoMessage()    {}
+
+// DescribeResponse is the response of Describe.
+type DescribeResponse struct {
+	Summary string `protobuf:"bytes,1,opt,name=summary,proto3" json:"summary"`
+}
+
+func (m *DescribeResponse) Reset()         { *m = DescribeResponse{} }
+func (m *DescribeResponse) String() string { return proto.CompactTextString(m) }
+func (*DescribeResponse) ProtoMessage()    {}
+
+// PinDigestsRequest is the request of PinDigests.
+type PinDigestsRequest struct {
+	Bundle Document `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"`
+}
+
+func (m *PinDigestsRequest) Reset()         { *m = PinDigestsRequest{} }
+func (m *PinDigestsRequest) String() string { return proto.CompactTextString(m) }
+func (*PinDigestsRequest) ProtoMessage()    {}
+
+// ImageError is an image which could not be resolved.
+type ImageError struct {
+	Path    string `protobuf:"bytes,1,opt,name=path,proto3" json:"path"`
+	Image   string `protobuf:"bytes,2,opt,name=image,proto3" json:"image"`
+	Message string `protobuf:"bytes,3,opt,name=message,proto3" json:"message"`
+}
+
+func (m *ImageError) Reset()         { *m = ImageError{} }
+func (m *ImageError) String() string { return proto.CompactTextString(m) }
+func (*ImageError) ProtoMessage()    {}
+
+// PinDigestsResponse is the response of PinDigests.
+type PinDigestsResponse struct {
+	Bundle Document      `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle"`
+	Errors []*ImageError `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"`
+}
+
+func (m *PinDigestsResponse) Reset()         { *m = PinDigestsResponse{} }
+func (m *PinDigestsResponse) String() string { return proto.CompactTextString(m) }
+func (*PinDigestsResponse) ProtoMessage()    {}
+
+// DiffRequest is the request of Diff.
+type DiffRequest struct {
+	OldBundle Document `protobuf:"bytes,1,opt,name=old_bundle,json=oldBundle,proto3" json:"oldBundle,omitempty"`
+	NewBundle Document `protobuf:"bytes,2,opt,name=new_bundle,json=newBundle,proto3" json:"newBundle,omitempty"`
+}
+
+func (m *DiffRequest) Reset()         { *m = DiffRequest{} }
+func (m *DiffRequest) String() string { return proto.CompactTextString(m) }
+func (*DiffRequest) ProtoMessage()    {}
+
+// DiffResponse is the response of Diff.
+type DiffResponse struct {
+	Changes Document `protobuf:"bytes,1,opt,name=changes,proto3" json:"changes"`
+}
+
+func (m *DiffResponse) Reset()         { *m = DiffResponse{} }
+func (m *DiffResponse) String() string { return proto.CompactTextString(m) }
+func (*DiffResponse) ProtoMessage()    {}
+
+// ResolveParametersRequest is the request of ResolveParameters.
+type ResolveParametersRequest struct {
+	Bundle Document `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	Action string   `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Values Document `protobuf:"bytes,3,opt,name=values,proto3" json:"values,omitempty"`
+}
+
+func (m *ResolveParametersRequest) Reset()         { *m = ResolveParametersRequest{} }
+func (m *ResolveParametersRequest) String() string { return proto.CompactTextString(m) }
+func (*ResolveParametersRequest) ProtoMessage()    {}
+
+// ResolveParametersResponse is the response of ResolveParameters.
+type ResolveParametersResponse struct {
+	Values Document `protobuf:"bytes,1,opt,name=values,proto3" json:"values"`
+}
+
+func (m *ResolveParametersResponse) Reset()         { *m = ResolveParametersResponse{} }
+func (m *ResolveParametersResponse) String() string { return proto.CompactTextString(m) }
+func (*ResolveParametersResponse) ProtoMessage()    {}
+
+// RunActionRequest is the request of RunAction.
+type RunActionRequest struct {
+	Bundle       Document          `protobuf:"bytes,1,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	Installation string            `protobuf:"bytes,2,opt,name=installation,proto3" json:"installation,omitempty"`
+	Action       string            `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	Parameters   Document          `protobuf:"bytes,4,opt,name=parameters,proto3" json:"parameters,omitempty"`
+	Credentials  map[string]string `protobuf:"bytes,5,rep,name=credentials,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3" json:"credentials,omitempty"`
+	Arguments    Document          `protobuf:"bytes,6,opt,name=arguments,proto3" json:"arguments,omitempty"`
+}
+
+func (m *RunActionRequest) Reset()         { *m = RunActionRequest{} }
+func (m *RunActionRequest) String() string { return proto.CompactTextString(m) }
+func (*RunActionRequest) ProtoMessage()    {}
+
+// RunActionResponse is the response of RunAction.
+type RunActionResponse struct {
+	Output string `protobuf:"bytes,1,opt,name=output,proto3" json:"output"`
+}
+
+func (m *RunActionResponse) Reset()         { *m = RunActionResponse{} }
+func (m *RunActionResponse) String() string { return proto.CompactTextString(m) }
+func (*RunActionResponse) ProtoMessage()    {}
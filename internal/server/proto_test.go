@@ -0,0 +1,179 @@
+package server
+
+import (
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+// messages are the Go types of the messages of bundles.proto, by name.
+var messages = map[string]reflect.Type{
+	"ValidateRequest":           reflect.TypeOf(ValidateRequest{}),
+	"ValidationError":           reflect.TypeOf(ValidationError{}),
+	"ValidateResponse":          reflect.TypeOf(ValidateResponse{}),
+	"DescribeRequest":           reflect.TypeOf(DescribeRequest{}),
+	"DescribeResponse":          reflect.TypeOf(DescribeResponse{}),
+	"PinDigestsRequest":         reflect.TypeOf(PinDigestsRequest{}),
+	"ImageError":                reflect.TypeOf(ImageError{}),
+	"PinDigestsResponse":        reflect.TypeOf(PinDigestsResponse{}),
+	"DiffRequest":               reflect.TypeOf(DiffRequest{}),
+	"DiffResponse":              reflect.TypeOf(DiffResponse{}),
+	"ResolveParametersRequest":  reflect.TypeOf(ResolveParametersRequest{}),
+	"ResolveParametersResponse": reflect.TypeOf(ResolveParametersResponse{}),
+	"RunActionRequest":          reflect.TypeOf(RunActionRequest{}),
+	"RunActionResponse":         reflect.TypeOf(RunActionResponse{}),
+}
+
+type protoField struct {
+	name     string
+	number   int
+	typ      string
+	repeated bool
+}
+
+type protoRPC struct {
+	name, request, response string
+}
+
+var (
+	rpcPattern     = regexp.MustCompile(`^rpc (\w+)\((\w+)\) returns \((\w+)\);$`)
+	messagePattern = regexp.MustCompile(`^message (\w+) \{$`)
+	fieldPattern   = regexp.MustCompile(`^(repeated )?(map<string, string>|\w+) (\w+) = (\d+);$`)
+)
+
+// parseProto reads the RPCs and the fields of the messages of bundles.proto.
+// It only handles the constructs the file uses.
+func parseProto(t *testing.T) ([]protoRPC, map[string][]protoField) {
+	data, err := ioutil.ReadFile("bundles.proto")
+	assert.NilError(t, err)
+	var rpcs []protoRPC
+	fields := map[string][]protoField{}
+	message := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "//"):
+		case line == "}":
+			message = ""
+		case rpcPattern.MatchString(line):
+			m := rpcPattern.FindStringSubmatch(line)
+			rpcs = append(rpcs, protoRPC{name: m[1], request: m[2], response: m[3]})
+		case messagePattern.MatchString(line):
+			message = messagePattern.FindStringSubmatch(line)[1]
+			fields[message] = nil
+		case message != "" && fieldPattern.MatchString(line):
+			m := fieldPattern.FindStringSubmatch(line)
+			number, err := strconv.Atoi(m[4])
+			assert.NilError(t, err)
+			fields[message] = append(fields[message], protoField{name: m[3], number: number, typ: m[2], repeated: m[1] != ""})
+		}
+	}
+	return rpcs, fields
+}
+
+// goFields returns the fields of a message type, as declared by their
+// protobuf struct tags, with their Go types.
+func goFields(t *testing.T, typ reflect.Type) ([]protoField, []reflect.Type) {
+	var fields []protoField
+	var types []reflect.Type
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := strings.Split(f.Tag.Get("protobuf"), ",")
+		assert.Assert(t, len(tag) >= 4, "%s.%s has no protobuf tag", typ.Name(), f.Name)
+		number, err := strconv.Atoi(tag[1])
+		assert.NilError(t, err)
+		field := protoField{number: number, typ: tag[0], repeated: tag[2] == "rep"}
+		for _, option := range tag[3:] {
+			if strings.HasPrefix(option, "name=") {
+				field.name = strings.TrimPrefix(option, "name=")
+			}
+		}
+		fields = append(fields, field)
+		types = append(types, f.Type)
+	}
+	return fields, types
+}
+
+// goType returns the Go type of a protobuf field.
+func goType(field protoField) reflect.Type {
+	var typ reflect.Type
+	switch field.typ {
+	case "string":
+		typ = reflect.TypeOf("")
+	case "bool":
+		typ = reflect.TypeOf(false)
+	case "map<string, string>":
+		return reflect.TypeOf(map[string]string{})
+	default:
+		if message, ok := messages[field.typ]; ok {
+			typ = reflect.PtrTo(message)
+		}
+	}
+	if typ != nil && field.repeated {
+		typ = reflect.SliceOf(typ)
+	}
+	return typ
+}
+
+func TestMessagesMatchProto(t *testing.T) {
+	_, protoMessages := parseProto(t)
+	var names, goNames []string
+	for name := range protoMessages {
+		names = append(names, name)
+	}
+	for name := range messages {
+		goNames = append(goNames, name)
+	}
+	sort.Strings(names)
+	sort.Strings(goNames)
+	assert.Assert(t, is.DeepEqual(names, goNames))
+
+	for _, name := range names {
+		fields, types := goFields(t, messages[name])
+		assert.Assert(t, is.Len(fields, len(protoMessages[name])), name)
+		for i, expected := range protoMessages[name] {
+			actual := fields[i]
+			assert.Check(t, is.Equal(actual.name, expected.name), "%s field %d", name, i)
+			assert.Check(t, is.Equal(actual.number, expected.number), "%s.%s", name, expected.name)
+			wire := "bytes"
+			if expected.typ == "bool" {
+				wire = "varint"
+			}
+			assert.Check(t, is.Equal(actual.typ, wire), "%s.%s", name, expected.name)
+			// Maps are repeated entries on the wire
+			repeated := expected.repeated || strings.HasPrefix(expected.typ, "map<")
+			assert.Check(t, is.Equal(actual.repeated, repeated), "%s.%s", name, expected.name)
+			// Documents are JSON carried as strings
+			typ := types[i]
+			if typ == reflect.TypeOf(Document("")) {
+				typ = reflect.TypeOf("")
+			}
+			assert.Check(t, is.Equal(typ, goType(expected)), "%s.%s", name, expected.name)
+		}
+	}
+}
+
+func TestServiceMatchesProto(t *testing.T) {
+	rpcs, _ := parseProto(t)
+	assert.Assert(t, is.Len(bundlesServiceDesc.Methods, len(rpcs)))
+	server := reflect.TypeOf((*BundlesServer)(nil)).Elem()
+	client := reflect.TypeOf((*BundlesClient)(nil)).Elem()
+	assert.Check(t, is.Equal(server.NumMethod(), len(rpcs)))
+	assert.Check(t, is.Equal(client.NumMethod(), len(rpcs)))
+	for i, rpc := range rpcs {
+		assert.Check(t, is.Equal(bundlesServiceDesc.Methods[i].MethodName, rpc.name))
+		for _, api := range []reflect.Type{server, client} {
+			method, ok := api.MethodByName(rpc.name)
+			assert.Assert(t, ok, "%s has no method %s", api.Name(), rpc.name)
+			assert.Check(t, is.Equal(method.Type.In(1), reflect.PtrTo(messages[rpc.request])), "%s.%s", api.Name(), rpc.name)
+			assert.Check(t, is.Equal(method.Type.Out(0), reflect.PtrTo(messages[rpc.response])), "%s.%s", api.Name(), rpc.name)
+		}
+	}
+}
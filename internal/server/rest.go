@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxRequestSize is the maximum size of the REST request bodies.
+const MaxRequestSize = 64 << 20
+
+// restError is the body of the REST error responses.
+type restError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewHandler returns the REST API of the service: each method is served at
+// POST /v1/<method>, like /v1/validate, taking and returning the JSON form
+// of its messages, see openapi.yaml.
+func NewHandler(srv BundlesServer) http.Handler {
+	mux := http.NewServeMux()
+	route := func(path string, newRequest func() interface{}, call func(ctx context.Context, req interface{}) (interface{}, error)) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				writeError(w, status.Error(codes.Unimplemented, "only POST is allowed"), http.StatusMethodNotAllowed)
+				return
+			}
+			req := newRequest()
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestSize)).Decode(req); err != nil {
+				writeError(w, status.Errorf(codes.InvalidArgument, "invalid request: %s", err), http.StatusBadRequest)
+				return
+			}
+			resp, err := call(r.Context(), req)
+			if err != nil {
+				writeError(w, err, httpStatus(status.Code(err)))
+				return
+			}
+			writeJSON(w, http.StatusOK, resp)
+		})
+	}
+	route("/v1/validate", func() interface{} { return new(ValidateRequest) }, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.Validate(ctx, req.(*ValidateRequest))
+	})
+	route("/v1/describe", func() interface{} { return new(DescribeRequest) }, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.Describe(ctx, req.(*DescribeRequest))
+	})
+	route("/v1/pin-digests", func() interface{} { return new(PinDigestsRequest) }, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.PinDigests(ctx, req.(*PinDigestsRequest))
+	})
+	route("/v1/diff", func() interface{} { return new(DiffRequest) }, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.Diff(ctx, req.(*DiffRequest))
+	})
+	route("/v1/resolve-parameters", func() interface{} { return new(ResolveParametersRequest) }, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.ResolveParameters(ctx, req.(*ResolveParametersRequest))
+	})
+	route("/v1/run-action", func() interface{} { return new(RunActionRequest) }, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.RunAction(ctx, req.(*RunActionRequest))
+	})
+	return mux
+}
+
+func writeError(w http.ResponseWriter, err error, code int) {
+	s := status.Convert(err)
+	writeJSON(w, code, restError{Code: s.Code().String(), Message: s.Message()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		code = http.StatusInternalServerError
+		data, _ = json.Marshal(restError{Code: codes.Internal.String(), Message: err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// httpStatus maps the gRPC codes returned by the service to HTTP statuses.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Canceled:
+		return 499
+	case codes.Aborted:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,426 @@
+// Package server exposes the bundle operations over gRPC and REST, so that
+// tools can share a running service rather than linking docker app. The
+// service is defined by bundles.proto, its REST mapping by openapi.yaml. It
+// is started by "docker app serve".
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/deislabs/cnab-go/driver"
+	"github.com/docker/app/internal"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers"
+	"github.com/docker/app/internal/drivers/opdriver"
+	"github.com/docker/app/internal/redact"
+	"github.com/docker/app/internal/runner"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the Bundles service.
+type Server struct {
+	// Resolver resolves the digests of images, PinDigests failing without.
+	Resolver cnab.ImageResolver
+	// Driver runs the actions, RunAction failing without.
+	Driver driver.Driver
+	// OS and Arch select the invocation image run, by default linux and the
+	// architecture of the server.
+	OS   string
+	Arch string
+	// Verify, if set, checks the signatures, the provenance or the image
+	// digests of the bundles before their actions run.
+	Verify func(ctx context.Context, b *bundle.Bundle) error
+	// Hooks are called around the actions, like the hooks of a
+	// runner.Runner, for instance to check them against policies or to
+	// audit them.
+	Hooks []runner.Hook
+}
+
+var _ BundlesServer = &Server{}
+
+// Validate reports the violations found in a bundle.
+func (s *Server) Validate(ctx context.Context, req *ValidateRequest) (*ValidateResponse, error) {
+	b, err := parseBundle(ctx, "bundle", req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	var opts []func(*cnab.ValidateOptions)
+	if req.StrictImageTypes {
+		opts = append(opts, cnab.WithStrictImageTypes())
+	}
+	errs := cnab.Validate(b, opts...)
+	resp := &ValidateResponse{Valid: len(errs.Errors()) == 0}
+	for _, e := range errs {
+		resp.Errors = append(resp.Errors, &ValidationError{Path: e.Path, Code: e.Code, Severity: string(e.Severity), Message: e.Message})
+	}
+	return resp, nil
+}
+
+// Describe renders the summary of a bundle.
+func (s *Server) Describe(ctx context.Context, req *DescribeRequest) (*DescribeResponse, error) {
+	b, err := parseBundle(ctx, "bundle", req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	format := cnab.DescribeFormat(req.Format)
+	if format == "" {
+		format = cnab.DescribeText
+	}
+	summary, err := cnab.Describe(b, format)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &DescribeResponse{Summary: string(summary)}, nil
+}
+
+// PinDigests records the digests of the images of a bundle. The images which
+// could not be resolved are reported along the bundle.
+func (s *Server) PinDigests(ctx context.Context, req *PinDigestsRequest) (*PinDigestsResponse, error) {
+	if s.Resolver == nil {
+		return nil, status.Error(codes.Unimplemented, "the server has no image resolver")
+	}
+	b, err := parseBundle(ctx, "bundle", req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	resp := &PinDigestsResponse{}
+	err = cnab.PinDigests(ctx, b, s.Resolver)
+	if imageErrs, ok := err.(cnab.ImagesError); ok {
+		for _, e := range imageErrs {
+			resp.Errors = append(resp.Errors, &ImageError{Path: e.Path, Image: e.Image, Message: e.Err.Error()})
+		}
+	} else if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if resp.Bundle, err = marshalDocument(cnab.Canonical{Bundle: b}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Diff lists the changes between two bundles.
+func (s *Server) Diff(ctx context.Context, req *DiffRequest) (*DiffResponse, error) {
+	old, err := parseBundle(ctx, "old bundle", req.OldBundle)
+	if err != nil {
+		return nil, err
+	}
+	new, err := parseBundle(ctx, "new bundle", req.NewBundle)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := cnab.Diff(old, new)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &DiffResponse{}
+	if resp.Changes, err = marshalDocument(changes); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ResolveParameters checks the parameter values and completes them with the
+// defaults of the parameters applying to the action.
+func (s *Server) ResolveParameters(ctx context.Context, req *ResolveParametersRequest) (*ResolveParametersResponse, error) {
+	b, err := parseBundle(ctx, "bundle", req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseValues("values", req.Values)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := resolveParameters(b, values, actionOrInstall(req.Action))
+	if err != nil {
+		return nil, err
+	}
+	resp := &ResolveParametersResponse{}
+	if resp.Values, err = marshalDocument(resolved); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RunAction runs an action of the bundle with the driver of the server, and
+// returns the output of the invocation image. The bundle, the parameters
+// and the credentials go through the checks of "docker app install", and
+// the action through the hooks of the server.
+func (s *Server) RunAction(ctx context.Context, req *RunActionRequest) (*RunActionResponse, error) {
+	if s.Driver == nil {
+		return nil, status.Error(codes.Unimplemented, "the server has no driver")
+	}
+	if req.Installation == "" || req.Action == "" {
+		return nil, status.Error(codes.InvalidArgument, "the installation and the action are required")
+	}
+	b, err := parseBundle(ctx, "bundle", req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBundle(ctx, b); err != nil {
+		return nil, err
+	}
+	params, err := parseValues("parameters", req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	args, err := parseValues("arguments", req.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	if params, err = resolveParameters(b, params, req.Action); err != nil {
+		return nil, err
+	}
+	creds := credentials.Set(req.Credentials)
+	if err := cnab.ValidateCredentials(b, creds); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	secrets, err := cnab.SensitiveParameterValues(b, params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, value := range creds {
+		secrets = append(secrets, value)
+	}
+	c, err := claim.New(req.Installation)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	c.Bundle = b
+	c.Parameters = params
+	event := runner.HookEvent{Action: req.Action, Attempt: 1, Bundle: b, Claim: c}
+	for _, hook := range s.Hooks {
+		if hook.BeforeAction == nil {
+			continue
+		}
+		if err := hook.BeforeAction(ctx, event); err != nil {
+			err = &runner.VetoedError{Err: err}
+			s.actionDone(ctx, event, err)
+			return nil, status.Error(codes.FailedPrecondition, redact.String(err.Error(), secrets...))
+		}
+	}
+	op, err := s.buildOperation(b, req.Installation, req.Action, params, creds, args)
+	if err != nil {
+		s.actionDone(ctx, event, err)
+		return nil, status.Error(status.Code(err), redact.String(status.Convert(err).Message(), secrets...))
+	}
+	var out bytes.Buffer
+	w := redact.NewWriter(&out, secrets...)
+	op.Out = w
+	event.Operation = op
+	err = drivers.Run(ctx, s.Driver, op)
+	w.Flush() //nolint:errcheck // writing to a buffer does not fail
+	if err != nil {
+		c.Update(req.Action, claim.StatusFailure)
+		c.Result.Message = err.Error()
+	} else {
+		c.Update(req.Action, claim.StatusSuccess)
+	}
+	s.actionDone(ctx, event, err)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Errorf(codes.Aborted, "action %q failed: %s\n%s", req.Action, redact.Error(err, secrets...), out.String())
+	}
+	return &RunActionResponse{Output: out.String()}, nil
+}
+
+// checkBundle runs the checks of "docker app install" on a bundle, before
+// the parameters are resolved.
+func (s *Server) checkBundle(ctx context.Context, b *bundle.Bundle) error {
+	if err := cnab.Validate(b).Err(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := cnab.CheckRequiredExtensions(b, cnab.SupportedExtensions); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if s.Verify != nil {
+		if err := s.Verify(ctx, b); err != nil {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+	}
+	if err := cnab.ValidateParameterDestinations(b); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	var capabilities []string
+	for _, imageType := range []string{driver.ImageTypeDocker, driver.ImageTypeOCI} {
+		if s.Driver.Handles(imageType) {
+			capabilities = append(capabilities, imageType)
+		}
+	}
+	err := cnab.CheckEnvironment(b, cnab.Descriptor{
+		RuntimeVersion:     internal.Version,
+		DriverCapabilities: capabilities,
+	})
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+// buildOperation builds the operation of the action, run by the invocation
+// image of the server platform.
+func (s *Server) buildOperation(b *bundle.Bundle, installation, action string, params map[string]interface{}, creds credentials.Set, args map[string]interface{}) (*driver.Operation, error) {
+	op, err := opdriver.BuildOperation(b, installation, action, params, creds, opdriver.WithArguments(args))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	os, arch := s.platform()
+	image, err := cnab.SelectInvocationImage(b, os, arch)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if !s.Driver.Handles(image.ImageType) {
+		return nil, status.Errorf(codes.FailedPrecondition, "the driver of the server does not handle %q images", image.ImageType)
+	}
+	op.Image = image.Image
+	op.ImageType = image.ImageType
+	return op, nil
+}
+
+// actionDone calls the AfterAction and OnError hooks with the result of the
+// action, as a runner.Runner does.
+func (s *Server) actionDone(ctx context.Context, event runner.HookEvent, err error) {
+	for _, hook := range s.Hooks {
+		if event.Operation != nil && hook.AfterAction != nil {
+			hook.AfterAction(ctx, event)
+		}
+		if err != nil && hook.OnError != nil {
+			hook.OnError(ctx, event, err)
+		}
+	}
+}
+
+func (s *Server) platform() (string, string) {
+	os, arch := s.OS, s.Arch
+	if os == "" {
+		os = "linux"
+	}
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+	return os, arch
+}
+
+// Serve serves the service over gRPC and REST on the listeners, either
+// being optional, until the context is done or one of them fails. The
+// clients are authenticated as set by the security, which is required.
+func Serve(ctx context.Context, srv BundlesServer, grpcListener, httpListener net.Listener, security Security) error {
+	if err := security.validate(); err != nil {
+		return err
+	}
+	errs := make(chan error, 2)
+	if grpcListener != nil {
+		opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(MaxRequestSize)}
+		if security.TLS != nil {
+			opts = append(opts, grpc.Creds(grpccredentials.NewTLS(security.TLS)))
+		}
+		if security.Token != "" {
+			opts = append(opts, grpc.UnaryInterceptor(tokenInterceptor(security.Token)))
+		}
+		g := grpc.NewServer(opts...)
+		RegisterBundlesServer(g, srv)
+		go func() { errs <- errors.Wrap(g.Serve(grpcListener), "gRPC server") }()
+		defer g.Stop()
+	}
+	if httpListener != nil {
+		handler := NewHandler(srv)
+		if security.Token != "" {
+			handler = requireToken(handler, security.Token)
+		}
+		h := &http.Server{Handler: handler, TLSConfig: security.TLS, ReadHeaderTimeout: 30 * time.Second}
+		go func() {
+			if security.TLS != nil {
+				errs <- errors.Wrap(h.ServeTLS(httpListener, "", ""), "HTTP server")
+				return
+			}
+			errs <- errors.Wrap(h.Serve(httpListener), "HTTP server")
+		}()
+		defer h.Close()
+	}
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// parseBundle decodes a bundle of a request, within the limits of the
+// untrusted bundles, see cnab.ParseReaderLimited.
+func parseBundle(ctx context.Context, name string, doc Document) (*bundle.Bundle, error) {
+	if doc == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "the %s is required", name)
+	}
+	b, err := cnab.ParseReaderContext(ctx, strings.NewReader(string(doc)), MaxRequestSize)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s: %s", name, err)
+	}
+	return b, nil
+}
+
+// parseValues decodes a JSON object of values, keeping the precision of the
+// numbers, see cnab.NormalizeParameterValue.
+func parseValues(name string, doc Document) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if doc == "" {
+		return values, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(doc)))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s, a JSON object is expected: %s", name, err)
+	}
+	return values, nil
+}
+
+// resolveParameters checks the parameter values are declared and completes
+// them with the defaults of the parameters applying to the action, then
+// checks them against the parameter schemas, as mergeBundleParameters does
+// for the command line.
+func resolveParameters(b *bundle.Bundle, values map[string]interface{}, action string) (map[string]interface{}, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	if err := cnab.CheckDeclaredParameters(b, names...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resolved, err := cnab.ValuesOrDefaults(values, b, action)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := cnab.ValidateParameters(b, resolved); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return resolved, nil
+}
+
+func marshalDocument(v interface{}) (Document, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	return Document(data), nil
+}
+
+func actionOrInstall(action string) string {
+	if action == "" {
+		return "install"
+	}
+	return action
+}
@@ -0,0 +1,440 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/internal/runner"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+const testBundle = `{
+	"name": "myapp",
+	"version": "1.0.0",
+	"invocationImages": [{"imageType": "docker", "image": "myapp-installer:1.0.0"}],
+	"images": {"web": {"imageType": "docker", "image": "nginx:1.17"}},
+	"actions": {"backup": {}},
+	"parameters": {
+		"port": {"type": "int", "default": 80},
+		"replicas": {"type": "int", "required": true, "apply-to": ["install"]}
+	},
+	"credentials": {"token": {"env": "TOKEN"}},
+	"custom": {"com.docker.app.action-arguments": {"backup": {"target": {"type": "string", "required": true}}}}
+}`
+
+// schemaBundle bounds the replicas with a parameter schema.
+const schemaBundle = `{
+	"name": "myapp",
+	"version": "1.0.0",
+	"invocationImages": [{"imageType": "docker", "image": "myapp-installer:1.0.0"}],
+	"parameters": {"replicas": {"type": "int", "required": true}},
+	"credentials": {"token": {"env": "TOKEN"}},
+	"custom": {"com.docker.app.parameter-schemas": {"parameters": {"replicas": {"maximum": 10}}}}
+}`
+
+// fakeResolver serves the descriptors by normalized reference.
+type fakeResolver map[string]ocispec.Descriptor
+
+func (r fakeResolver) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	desc, ok := r[ref]
+	if !ok {
+		return "", ocispec.Descriptor{}, errors.New("not found")
+	}
+	return ref, desc, nil
+}
+
+func newTestServer() (*Server, *fake.Driver) {
+	d := fake.New().Default(fake.Result{Output: "done\n"})
+	return &Server{
+		Resolver: fakeResolver{"docker.io/library/nginx:1.17": {Digest: digest.FromString("nginx"), Size: 42}},
+		Driver:   d,
+	}, d
+}
+
+func TestValidate(t *testing.T) {
+	s, _ := newTestServer()
+	resp, err := s.Validate(context.Background(), &ValidateRequest{Bundle: testBundle})
+	assert.NilError(t, err)
+	assert.Check(t, resp.Valid)
+	assert.Check(t, is.Len(resp.Errors, 0))
+
+	resp, err = s.Validate(context.Background(), &ValidateRequest{Bundle: `{"name": "myapp", "version": "latest"}`})
+	assert.NilError(t, err)
+	assert.Check(t, !resp.Valid)
+	assert.Check(t, len(resp.Errors) > 0)
+
+	_, err = s.Validate(context.Background(), &ValidateRequest{Bundle: "{"})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	_, err = s.Validate(context.Background(), &ValidateRequest{})
+	assert.Check(t, is.Error(err, "rpc error: code = InvalidArgument desc = the bundle is required"))
+}
+
+func TestDescribeAndDiff(t *testing.T) {
+	s, _ := newTestServer()
+	resp, err := s.Describe(context.Background(), &DescribeRequest{Bundle: testBundle, Format: "markdown"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(resp.Summary, "# myapp 1.0.0"))
+	_, err = s.Describe(context.Background(), &DescribeRequest{Bundle: testBundle, Format: "yaml"})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+
+	newBundle := strings.Replace(testBundle, `"default": 80`, `"default": 8080`, 1)
+	diff, err := s.Diff(context.Background(), &DiffRequest{OldBundle: testBundle, NewBundle: Document(newBundle)})
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(diff.Changes), `"path":"$.parameters[\"port\"]"`))
+}
+
+func TestPinDigests(t *testing.T) {
+	s, _ := newTestServer()
+	resp, err := s.PinDigests(context.Background(), &PinDigestsRequest{Bundle: testBundle})
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(resp.Bundle), digest.FromString("nginx").String()))
+	assert.Assert(t, is.Len(resp.Errors, 1))
+	assert.Check(t, is.Equal(resp.Errors[0].Path, "$.invocationImages[0]"))
+
+	_, err = (&Server{}).PinDigests(context.Background(), &PinDigestsRequest{Bundle: testBundle})
+	assert.Check(t, is.Equal(status.Code(err), codes.Unimplemented))
+}
+
+func TestResolveParameters(t *testing.T) {
+	s, _ := newTestServer()
+	resp, err := s.ResolveParameters(context.Background(), &ResolveParametersRequest{Bundle: testBundle, Values: `{"replicas": 10000000000000001}`})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(resp.Values), `{"port":80,"replicas":10000000000000001}`))
+
+	_, err = s.ResolveParameters(context.Background(), &ResolveParametersRequest{Bundle: testBundle})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	assert.Check(t, is.ErrorContains(err, "replicas"))
+	_, err = s.ResolveParameters(context.Background(), &ResolveParametersRequest{Bundle: testBundle, Values: `[1]`})
+	assert.Check(t, is.ErrorContains(err, "invalid values, a JSON object is expected"))
+	_, err = s.ResolveParameters(context.Background(), &ResolveParametersRequest{Bundle: testBundle, Values: `{"replicas": 3, "unknown": 1}`})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	assert.Check(t, is.ErrorContains(err, "unknown"))
+	_, err = s.ResolveParameters(context.Background(), &ResolveParametersRequest{Bundle: schemaBundle, Values: `{"replicas": 12}`})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	assert.Check(t, is.ErrorContains(err, "replicas"))
+}
+
+func TestRunAction(t *testing.T) {
+	s, d := newTestServer()
+	resp, err := s.RunAction(context.Background(), &RunActionRequest{
+		Bundle:       testBundle,
+		Installation: "myapp",
+		Action:       "backup",
+		Credentials:  map[string]string{"token": "secret"},
+		Arguments:    `{"target": "s3://bucket"}`,
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(resp.Output, "done\n"))
+	op, ok := d.LastOperation()
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal(op.Image, "myapp-installer:1.0.0"))
+	assert.Check(t, is.Equal(op.Environment["CNAB_ARG_TARGET"], "s3://bucket"))
+	assert.Check(t, is.Equal(op.Environment["CNAB_P_PORT"], "80"))
+	assert.Check(t, is.Equal(op.Environment["TOKEN"], "secret"))
+
+	d.Script("backup", fake.Result{Err: errors.New("boom"), Output: "oops\n"})
+	_, err = s.RunAction(context.Background(), &RunActionRequest{Bundle: testBundle, Installation: "myapp", Action: "backup", Credentials: map[string]string{"token": "secret"}, Arguments: `{"target": "s3://bucket"}`})
+	assert.Check(t, is.Equal(status.Code(err), codes.Aborted))
+	assert.Check(t, is.ErrorContains(err, "boom\noops"))
+
+	_, err = s.RunAction(context.Background(), &RunActionRequest{Bundle: testBundle, Installation: "myapp", Action: "backup", Credentials: map[string]string{"token": "secret"}})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	_, err = (&Server{}).RunAction(context.Background(), &RunActionRequest{})
+	assert.Check(t, is.Equal(status.Code(err), codes.Unimplemented))
+}
+
+func TestRunActionChecks(t *testing.T) {
+	s, d := newTestServer()
+	request := func(bndl Document) *RunActionRequest {
+		return &RunActionRequest{
+			Bundle:       bndl,
+			Installation: "myapp",
+			Action:       "install",
+			Parameters:   `{"replicas": 3}`,
+			Credentials:  map[string]string{"token": "secret"},
+		}
+	}
+	_, err := s.RunAction(context.Background(), request(`{"name": "myapp", "version": "latest"}`))
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	_, err = s.RunAction(context.Background(), request(Document(strings.Replace(testBundle, `"custom": {`, `"custom": {"com.docker.app.required-extensions": ["io.example.unknown"], `, 1))))
+	assert.Check(t, is.Equal(status.Code(err), codes.FailedPrecondition))
+	_, err = s.RunAction(context.Background(), &RunActionRequest{Bundle: schemaBundle, Installation: "myapp", Action: "install", Parameters: `{"replicas": 12}`, Credentials: map[string]string{"token": "secret"}})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	_, err = s.RunAction(context.Background(), &RunActionRequest{Bundle: testBundle, Installation: "myapp", Action: "install", Parameters: `{"replicas": 3}`})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+	assert.Check(t, is.ErrorContains(err, "token"))
+
+	s.Verify = func(ctx context.Context, b *bundle.Bundle) error { return errors.New("unsigned bundle") }
+	_, err = s.RunAction(context.Background(), request(testBundle))
+	assert.Check(t, is.Equal(status.Code(err), codes.FailedPrecondition))
+	assert.Check(t, is.ErrorContains(err, "unsigned bundle"))
+	_, ran := d.LastOperation()
+	assert.Check(t, !ran)
+}
+
+func TestRunActionHooks(t *testing.T) {
+	s, d := newTestServer()
+	var events []string
+	s.Hooks = []runner.Hook{{
+		BeforeAction: func(ctx context.Context, event runner.HookEvent) error {
+			if fmt.Sprint(event.Claim.Parameters["replicas"]) == "5" {
+				return errors.New("too many replicas")
+			}
+			return nil
+		},
+		AfterAction: func(ctx context.Context, event runner.HookEvent) {
+			events = append(events, "after "+event.Claim.Result.Status)
+		},
+		OnError: func(ctx context.Context, event runner.HookEvent, err error) {
+			events = append(events, "error "+err.Error())
+		},
+	}}
+	req := &RunActionRequest{Bundle: testBundle, Installation: "myapp", Action: "install", Parameters: `{"replicas": 5}`, Credentials: map[string]string{"token": "secret"}}
+	_, err := s.RunAction(context.Background(), req)
+	assert.Check(t, is.Equal(status.Code(err), codes.FailedPrecondition))
+	assert.Check(t, is.ErrorContains(err, "too many replicas"))
+	_, ran := d.LastOperation()
+	assert.Check(t, !ran)
+
+	req.Parameters = `{"replicas": 3}`
+	_, err = s.RunAction(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(events, []string{"error action vetoed: too many replicas", "after success"}))
+}
+
+func TestRunActionRedactsSecrets(t *testing.T) {
+	s, d := newTestServer()
+	bndl := Document(strings.Replace(testBundle, `"custom": {`, `"custom": {"com.docker.app.sensitive-parameters": ["port"], `, 1))
+	req := &RunActionRequest{Bundle: bndl, Installation: "myapp", Action: "install", Parameters: `{"replicas": 3, "port": 8443}`, Credentials: map[string]string{"token": "secret"}}
+	d.Script("install", fake.Result{Output: "token secret on port 8443\n"})
+	resp, err := s.RunAction(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(resp.Output, "token ****** on port ******\n"))
+
+	d.Script("install", fake.Result{Err: errors.New("secret rejected"), Output: "token secret\n"})
+	_, err = s.RunAction(context.Background(), req)
+	assert.Check(t, is.Equal(status.Code(err), codes.Aborted))
+	assert.Check(t, !strings.Contains(err.Error(), "secret"), err.Error())
+}
+
+func TestGRPC(t *testing.T) {
+	s, _ := newTestServer()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	g := grpc.NewServer()
+	RegisterBundlesServer(g, s)
+	go g.Serve(listener)
+	defer g.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	assert.NilError(t, err)
+	defer conn.Close()
+	client := NewBundlesClient(conn)
+
+	resp, err := client.Validate(context.Background(), &ValidateRequest{Bundle: `{"name": "myapp", "version": "latest"}`, StrictImageTypes: true})
+	assert.NilError(t, err)
+	assert.Check(t, !resp.Valid)
+	assert.Check(t, resp.Errors[0].Code != "")
+
+	run, err := client.RunAction(context.Background(), &RunActionRequest{
+		Bundle:       testBundle,
+		Installation: "myapp",
+		Action:       "install",
+		Parameters:   `{"replicas": 3}`,
+		Credentials:  map[string]string{"token": "secret"},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(run.Output, "done\n"))
+
+	_, err = client.Describe(context.Background(), &DescribeRequest{})
+	assert.Check(t, is.Equal(status.Code(err), codes.InvalidArgument))
+}
+
+func TestREST(t *testing.T) {
+	s, _ := newTestServer()
+	server := httptest.NewServer(NewHandler(s))
+	defer server.Close()
+
+	post := func(path, body string) (int, map[string]interface{}) {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		assert.NilError(t, err)
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		assert.NilError(t, err)
+		var decoded map[string]interface{}
+		assert.NilError(t, json.Unmarshal(data, &decoded), string(data))
+		return resp.StatusCode, decoded
+	}
+
+	code, body := post("/v1/resolve-parameters", `{"bundle": `+testBundle+`, "values": {"replicas": 3}}`)
+	assert.Check(t, is.Equal(code, http.StatusOK))
+	assert.Check(t, is.DeepEqual(body["values"], map[string]interface{}{"port": float64(80), "replicas": float64(3)}))
+
+	code, body = post("/v1/validate", `{"bundle": `+testBundle+`}`)
+	assert.Check(t, is.Equal(code, http.StatusOK))
+	assert.Check(t, is.Equal(body["valid"], true))
+
+	code, body = post("/v1/resolve-parameters", `{"bundle": `+testBundle+`}`)
+	assert.Check(t, is.Equal(code, http.StatusBadRequest))
+	assert.Check(t, is.Equal(body["code"], "InvalidArgument"))
+
+	code, _ = post("/v1/validate", `not json`)
+	assert.Check(t, is.Equal(code, http.StatusBadRequest))
+
+	resp, err := http.Get(server.URL + "/v1/validate")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusMethodNotAllowed))
+}
+
+func TestServe(t *testing.T) {
+	s, _ := newTestServer()
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Serve(ctx, s, grpcListener, httpListener, Security{Token: "s3cr3t"}) }()
+
+	describe := func(authorization string) int {
+		req, err := http.NewRequest(http.MethodPost, "http://"+httpListener.Addr().String()+"/v1/describe", strings.NewReader(`{"bundle": `+testBundle+`, "format": "json"}`))
+		assert.NilError(t, err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NilError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Check(t, is.Equal(describe("Bearer s3cr3t"), http.StatusOK))
+	assert.Check(t, is.Equal(describe(""), http.StatusUnauthorized))
+	assert.Check(t, is.Equal(describe("Bearer wrong"), http.StatusUnauthorized))
+
+	conn, err := grpc.Dial(grpcListener.Addr().String(), grpc.WithInsecure())
+	assert.NilError(t, err)
+	defer conn.Close()
+	client := NewBundlesClient(conn)
+	_, err = client.Describe(context.Background(), &DescribeRequest{Bundle: testBundle})
+	assert.Check(t, is.Equal(status.Code(err), codes.Unauthenticated))
+	_, err = client.Describe(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cr3t"), &DescribeRequest{Bundle: testBundle})
+	assert.Check(t, err)
+
+	cancel()
+	assert.NilError(t, <-done)
+}
+
+func TestServeRequiresAuthentication(t *testing.T) {
+	s, _ := newTestServer()
+	err := Serve(context.Background(), s, nil, nil, Security{})
+	assert.Check(t, is.ErrorContains(err, "requires TLS client certificates or a token"))
+	err = Serve(context.Background(), s, nil, nil, Security{TLS: &tls.Config{}})
+	assert.Check(t, is.ErrorContains(err, "requires TLS client certificates or a token"))
+}
+
+func TestServeTLSClientCertificates(t *testing.T) {
+	dir := fs.NewDir(t, "certs")
+	defer dir.Remove()
+	ca := newTestCertificate(t, nil, "ca")
+	writeTestCertificate(t, dir.Join("ca"), ca)
+	writeTestCertificate(t, dir.Join("server"), newTestCertificate(t, ca, "127.0.0.1"))
+	config, err := LoadTLSConfig(dir.Join("server.pem"), dir.Join("server-key.pem"), dir.Join("ca.pem"))
+	assert.NilError(t, err)
+
+	s, _ := newTestServer()
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Serve(ctx, s, nil, httpListener, Security{TLS: config}) }()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	validate := func(certificates ...tls.Certificate) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates}}}
+		resp, err := client.Post("https://"+httpListener.Addr().String()+"/v1/validate", "application/json", strings.NewReader(`{"bundle": `+testBundle+`}`))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return errors.New(resp.Status)
+		}
+		return nil
+	}
+	clientCert := newTestCertificate(t, ca, "client")
+	assert.Check(t, validate(tls.Certificate{Certificate: [][]byte{clientCert.cert.Raw}, PrivateKey: clientCert.key}))
+	assert.Check(t, validate() != nil)
+
+	cancel()
+	assert.NilError(t, <-done)
+}
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCertificate returns a certificate signed by the CA, or a CA if nil.
+func newTestCertificate(t *testing.T, ca *testCertificate, name string) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, signer := template, key
+	if ca == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		parent, signer = ca.cert, ca.key
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = []net.IP{ip}
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+	return &testCertificate{cert: cert, key: key}
+}
+
+func writeTestCertificate(t *testing.T, prefix string, c *testCertificate) {
+	key, err := x509.MarshalECPrivateKey(c.key)
+	assert.NilError(t, err)
+	assert.NilError(t, ioutil.WriteFile(prefix+".pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+	assert.NilError(t, ioutil.WriteFile(prefix+"-key.pem", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600))
+}
@@ -0,0 +1,153 @@
+package signature
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// Verifier checks the signatures of a bundle pulled from a registry, and of
+// its invocation images, before the bundle is run.
+type Verifier interface {
+	// Verify returns an error if the bundle or one of its invocation images
+	// is not signed by a trusted identity. The ref is the reference the
+	// bundle was pulled from, which must be pinned by digest, nil for a
+	// bundle read from a file, in which case only the invocation images are
+	// checked.
+	Verify(ctx context.Context, ref reference.Named, b *bundle.Bundle) error
+}
+
+// runCommand runs the cosign CLI and returns its standard output. It is
+// replaced in tests.
+var runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return nil, errors.Errorf("%s failed: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// Cosign verifies sigstore signatures with the cosign CLI, on the bundle
+// OCI manifest and on each invocation image digest.
+type Cosign struct {
+	// Key is the path or KMS URI of the public key. When empty, signatures
+	// are verified keyless, against the sigstore transparency log.
+	Key string
+	// CertificateIdentity is the identity, usually an email or a workflow
+	// URL, keyless signing certificates must be issued to.
+	CertificateIdentity string
+	// CertificateOIDCIssuer is the OIDC issuer of the keyless signing
+	// certificates.
+	CertificateOIDCIssuer string
+	// Offline makes the verifications fail with an offline.ErrOfflineMode,
+	// as cosign fetches the signatures from the registry.
+	Offline bool
+}
+
+var _ Verifier = &Cosign{}
+
+// Verify implements Verifier.
+func (c *Cosign) Verify(ctx context.Context, ref reference.Named, b *bundle.Bundle) error {
+	if c.Offline {
+		return offline.New("verifying signatures")
+	}
+	args, err := c.args()
+	if err != nil {
+		return err
+	}
+	if ref != nil {
+		canonical, ok := ref.(reference.Canonical)
+		if !ok {
+			return errors.Errorf("bundle %q is not pinned by digest, its signature cannot be verified", reference.FamiliarString(ref))
+		}
+		pinned, err := reference.WithDigest(reference.TrimNamed(ref), canonical.Digest())
+		if err != nil {
+			return err
+		}
+		if err := c.verify(ctx, args, pinned.String()); err != nil {
+			return errors.Wrapf(err, "invalid signature of bundle %q", reference.FamiliarString(ref))
+		}
+	}
+	for _, image := range b.InvocationImages {
+		pinned, err := PinnedImage(image.BaseImage)
+		if err != nil {
+			return err
+		}
+		if err := c.verify(ctx, args, pinned); err != nil {
+			return errors.Wrapf(err, "invalid signature of invocation image %q", image.Image)
+		}
+	}
+	return nil
+}
+
+func (c *Cosign) args() ([]string, error) {
+	if c.Key != "" {
+		return []string{"--key", c.Key}, nil
+	}
+	if c.CertificateIdentity == "" || c.CertificateOIDCIssuer == "" {
+		return nil, errors.New("keyless signature verification needs a certificate identity and OIDC issuer")
+	}
+	return []string{
+		"--certificate-identity", c.CertificateIdentity,
+		"--certificate-oidc-issuer", c.CertificateOIDCIssuer,
+	}, nil
+}
+
+func (c *Cosign) verify(ctx context.Context, args []string, image string) error {
+	_, err := runCommand(ctx, "cosign", append(append([]string{"verify"}, args...), image)...)
+	return err
+}
+
+// Attestations returns the in-toto attestations of the given predicate type
+// attached to an image, once their signature is verified, as DSSE envelopes.
+func (c *Cosign) Attestations(ctx context.Context, image, predicateType string) ([][]byte, error) {
+	if c.Offline {
+		return nil, offline.New("fetching the attestations of %q", image)
+	}
+	args, err := c.args()
+	if err != nil {
+		return nil, err
+	}
+	out, err := runCommand(ctx, "cosign", append(append([]string{"verify-attestation", "--type", predicateType}, args...), image)...)
+	if err != nil {
+		return nil, err
+	}
+	var envelopes [][]byte
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			envelopes = append(envelopes, []byte(line))
+		}
+	}
+	return envelopes, nil
+}
+
+// PinnedImage returns the reference of an image by digest, as signatures
+// are attached to a digest, a tag could be moved to an unsigned image.
+func PinnedImage(image bundle.BaseImage) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image.Image)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid invocation image %q", image.Image)
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.String(), nil
+	}
+	if image.Digest == "" {
+		return "", errors.Errorf("invocation image %q is not pinned by digest, its signature cannot be verified", image.Image)
+	}
+	d, err := digest.Parse(image.Digest)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid digest of invocation image %q", image.Image)
+	}
+	canonical, err := reference.WithDigest(reference.TrimNamed(named), d)
+	if err != nil {
+		return "", err
+	}
+	return canonical.String(), nil
+}
@@ -0,0 +1,111 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/distribution/reference"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const testDigest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+
+// fakeCosign records the cosign commands, failing the verification of the
+// unsigned image. It returns the recorded commands and a restore function.
+func fakeCosign(unsigned string) (*[]string, func()) {
+	var calls []string
+	original := runCommand
+	runCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		if args[len(args)-1] == unsigned {
+			return nil, errors.New("no matching signatures")
+		}
+		return nil, nil
+	}
+	return &calls, func() { runCommand = original }
+}
+
+func cosignBundle() *bundle.Bundle {
+	return &bundle.Bundle{
+		Name: "app",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{Image: "example.com/app-installer:0.1.0", Digest: testDigest}},
+		},
+	}
+}
+
+func TestCosignVerifyWithKey(t *testing.T) {
+	calls, restore := fakeCosign("")
+	defer restore()
+	ref, err := reference.ParseNormalizedNamed("example.com/app:0.1.0@" + testDigest)
+	assert.NilError(t, err)
+
+	c := &Cosign{Key: "cosign.pub"}
+	assert.NilError(t, c.Verify(context.Background(), ref, cosignBundle()))
+	assert.DeepEqual(t, *calls, []string{
+		"cosign verify --key cosign.pub example.com/app@" + testDigest,
+		"cosign verify --key cosign.pub example.com/app-installer@" + testDigest,
+	})
+
+	// A tag could be moved once verified
+	tagged, err := reference.ParseNormalizedNamed("example.com/app:0.1.0")
+	assert.NilError(t, err)
+	assert.Error(t, c.Verify(context.Background(), tagged, cosignBundle()), `bundle "example.com/app:0.1.0" is not pinned by digest, its signature cannot be verified`)
+	assert.Check(t, is.Len(*calls, 2))
+}
+
+func TestCosignVerifyKeyless(t *testing.T) {
+	calls, restore := fakeCosign("example.com/app-installer@" + testDigest)
+	defer restore()
+
+	c := &Cosign{}
+	err := c.Verify(context.Background(), nil, cosignBundle())
+	assert.ErrorContains(t, err, "needs a certificate identity")
+
+	c = &Cosign{CertificateIdentity: "me@example.com", CertificateOIDCIssuer: "https://accounts.example.com"}
+	err = c.Verify(context.Background(), nil, cosignBundle())
+	assert.Error(t, err, `invalid signature of invocation image "example.com/app-installer:0.1.0": no matching signatures`)
+	assert.Check(t, is.Len(*calls, 1))
+}
+
+func TestCosignVerifyUnpinnedImage(t *testing.T) {
+	_, restore := fakeCosign("")
+	defer restore()
+	b := cosignBundle()
+	b.InvocationImages[0].Digest = ""
+	err := (&Cosign{Key: "cosign.pub"}).Verify(context.Background(), nil, b)
+	assert.ErrorContains(t, err, "is not pinned by digest")
+}
+
+func TestCosignAttestations(t *testing.T) {
+	calls, restore := fakeCosign("")
+	defer restore()
+	original := runCommand
+	runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		_, _ = original(ctx, name, args...)
+		return []byte("{\"payload\":\"a\"}\n{\"payload\":\"b\"}\n"), nil
+	}
+
+	c := &Cosign{Key: "cosign.pub"}
+	envelopes, err := c.Attestations(context.Background(), "example.com/app:0.1.0", "slsaprovenance")
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(envelopes, 2))
+	assert.DeepEqual(t, *calls, []string{"cosign verify-attestation --type slsaprovenance --key cosign.pub example.com/app:0.1.0"})
+}
+
+func TestCosignOffline(t *testing.T) {
+	calls, restore := fakeCosign("")
+	defer restore()
+
+	c := &Cosign{Key: "cosign.pub", Offline: true}
+	err := c.Verify(context.Background(), nil, cosignBundle())
+	assert.Check(t, offline.IsOffline(err))
+	_, err = c.Attestations(context.Background(), "example.com/app:0.1.0", "slsaprovenance")
+	assert.Check(t, offline.IsOffline(err))
+	assert.Check(t, is.Len(*calls, 0))
+}
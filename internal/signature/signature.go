@@ -0,0 +1,353 @@
+// Package signature signs bundles and verifies signed bundles, so a bundle
+// can be checked to come unmodified from a trusted publisher before it is
+// run.
+//
+// A signature covers the canonical JSON serialization of the bundle. It can
+// be kept detached from the bundle, or embedded with the bundle in a
+// clear-signed document, conventionally named bundle.cnab:
+//
+//	{"signed": <bundle>, "signatures": [{"keyid": "...", "sig": "..."}]}
+//
+// Keys are PEM encoded ECDSA or RSA keys. OpenPGP keys are not supported, as
+// no OpenPGP implementation is vendored.
+//
+// Bundles are hashed with the algorithm of the checksum configuration,
+// SHA-256 by default, see checksum.Config.
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256" // register sha256
+	_ "crypto/sha512" // register sha384 and sha512
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	"github.com/docker/app/internal/cnab"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// ErrUnsigned is returned when verifying a document without any signature.
+var ErrUnsigned = errors.New("bundle is not signed")
+
+// hashes are the hashes of the digest algorithms signatures support.
+var hashes = map[digest.Algorithm]crypto.Hash{
+	digest.SHA256: crypto.SHA256,
+	digest.SHA384: crypto.SHA384,
+	digest.SHA512: crypto.SHA512,
+}
+
+// Signature is a signature of a bundle by a key.
+type Signature struct {
+	// KeyID identifies the public key verifying the signature, see KeyID.
+	// It is computed with the hash of the signature.
+	KeyID string `json:"keyid"`
+	// Signature is the signature of the digest of the canonical bundle,
+	// ASN.1 encoded for ECDSA keys, PKCS #1 v1.5 for RSA keys.
+	Signature []byte `json:"sig"`
+	// Hash is the digest algorithm of the signature, like "sha512",
+	// SHA-256 if empty.
+	Hash string `json:"hash,omitempty"`
+	// Canonicalization is the name of the canonicalization scheme of the
+	// signed bundle, like "jcs", the docker canonical JSON if empty. See
+	// cnab.LookupCanonicalizer.
+	Canonicalization string `json:"canonicalization,omitempty"`
+}
+
+// Document is a clear-signed bundle.
+type Document struct {
+	Signed     json.RawMessage `json:"signed"`
+	Signatures []Signature     `json:"signatures"`
+}
+
+// Keyring holds the trusted public keys, by key ID.
+type Keyring map[string]crypto.PublicKey
+
+// Add adds a public key to the keyring.
+func (k Keyring) Add(key crypto.PublicKey) error {
+	id, err := KeyID(key)
+	if err != nil {
+		return err
+	}
+	k[id] = key
+	return nil
+}
+
+// lookup returns the key of the given identifier, computed with the given
+// hash, which may not be the one the keyring is indexed with.
+func (k Keyring) lookup(id string, hash crypto.Hash) (crypto.PublicKey, bool) {
+	if key, ok := k[id]; ok {
+		return key, true
+	}
+	for _, key := range k {
+		if keyID, err := keyID(key, hash); err == nil && keyID == id {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// KeyID returns the identifier of a public key, the hex encoded digest of
+// its PKIX serialization, with the algorithm configured by the environment.
+func KeyID(key crypto.PublicKey) (string, error) {
+	config, err := checksum.FromEnv()
+	if err != nil {
+		return "", err
+	}
+	_, hash, err := signatureHash(config, config.Algorithm)
+	if err != nil {
+		return "", err
+	}
+	return keyID(key, hash)
+}
+
+func keyID(key crypto.PublicKey, hash crypto.Hash) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum(hash, der)), nil
+}
+
+// signatureHash returns the hash of a digest algorithm, SHA-256 if empty,
+// failing if the configuration does not allow it.
+func signatureHash(config checksum.Config, alg digest.Algorithm) (digest.Algorithm, crypto.Hash, error) {
+	if alg == "" {
+		alg = digest.SHA256
+	}
+	if err := (checksum.Config{Algorithm: alg, FIPS: config.FIPS}).Validate(); err != nil {
+		return "", 0, err
+	}
+	hash, ok := hashes[alg]
+	if !ok {
+		return "", 0, errors.Errorf("unsupported signature hash %q", alg)
+	}
+	return alg, hash, nil
+}
+
+func sum(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data) //nolint:errcheck // hashes never fail to write
+	return h.Sum(nil)
+}
+
+// SignDetached signs the bundle and returns the signature alone. The bundle
+// is canonicalized with the docker canonical JSON, unless another scheme is
+// selected with cnab.WithCanonicalizer, and hashed with the algorithm of the
+// environment, unless another one is selected with cnab.WithDigestConfig.
+func SignDetached(b *bundle.Bundle, key crypto.Signer, opts ...func(*cnab.CanonicalOptions)) (Signature, error) {
+	c := cnab.SelectCanonicalizer(opts...)
+	config, err := cnab.DigestConfig(opts...)
+	if err != nil {
+		return Signature{}, err
+	}
+	data, err := c.Marshal(b)
+	if err != nil {
+		return Signature{}, err
+	}
+	return sign(data, key, c, config)
+}
+
+// Sign signs the bundle and returns the clear-signed document, see
+// SignDetached.
+func Sign(b *bundle.Bundle, key crypto.Signer, opts ...func(*cnab.CanonicalOptions)) ([]byte, error) {
+	c := cnab.SelectCanonicalizer(opts...)
+	config, err := cnab.DigestConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(data, key, c, config)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(Document{Signed: data, Signatures: []Signature{sig}}, "", "  ")
+}
+
+func sign(data []byte, key crypto.Signer, c cnab.Canonicalizer, config checksum.Config) (Signature, error) {
+	alg, hash, err := signatureHash(config, config.Algorithm)
+	if err != nil {
+		return Signature{}, err
+	}
+	id, err := keyID(key.Public(), hash)
+	if err != nil {
+		return Signature{}, err
+	}
+	sig, err := key.Sign(rand.Reader, sum(hash, data), hash)
+	if err != nil {
+		return Signature{}, errors.Wrap(err, "failed to sign bundle")
+	}
+	signature := Signature{KeyID: id, Signature: sig}
+	if c != cnab.DockerCanonicalJSON {
+		signature.Canonicalization = c.Name()
+	}
+	if alg != digest.SHA256 {
+		signature.Hash = alg.String()
+	}
+	return signature, nil
+}
+
+// Verify checks a clear-signed document has a valid signature by one of the
+// keyring keys, and returns the signed bundle.
+func Verify(data []byte, keyring Keyring) (*bundle.Bundle, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "invalid signed bundle")
+	}
+	if len(doc.Signed) == 0 {
+		return nil, errors.New("invalid signed bundle: missing signed bundle")
+	}
+	b, err := cnab.Parse(doc.Signed)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyDetached(b, keyring, doc.Signatures...); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// VerifyDetached checks at least one of the signatures of the bundle is
+// valid and made by one of the keyring keys. The signatures hashed with an
+// algorithm the checksum configuration of the environment does not allow
+// are ignored.
+func VerifyDetached(b *bundle.Bundle, keyring Keyring, signatures ...Signature) error {
+	if len(signatures) == 0 {
+		return ErrUnsigned
+	}
+	config, err := checksum.FromEnv()
+	if err != nil {
+		return err
+	}
+	// The bundle is serialized again, with the canonicalization scheme of
+	// each signature, so formatting changes of the signed document do not
+	// invalidate the signature.
+	documents := map[string][]byte{}
+	var unknown []string
+	for _, sig := range signatures {
+		_, hash, err := signatureHash(config, digest.Algorithm(sig.Hash))
+		if err != nil {
+			// signed with an unsupported or disallowed hash
+			continue
+		}
+		key, ok := keyring.lookup(sig.KeyID, hash)
+		if !ok {
+			unknown = append(unknown, sig.KeyID)
+			continue
+		}
+		data, ok := documents[sig.Canonicalization]
+		if !ok {
+			c, err := cnab.LookupCanonicalizer(sig.Canonicalization)
+			if err != nil {
+				// signed with an unsupported scheme
+				continue
+			}
+			if data, err = c.Marshal(b); err != nil {
+				return err
+			}
+			documents[sig.Canonicalization] = data
+		}
+		if verify(key, hash, sum(hash, data), sig.Signature) {
+			return nil
+		}
+	}
+	if len(unknown) == len(signatures) {
+		return fmt.Errorf("bundle is not signed by a trusted key (signed by %q)", unknown)
+	}
+	return errors.New("invalid bundle signature")
+}
+
+func verify(key crypto.PublicKey, hash crypto.Hash, hashed, sig []byte) bool {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		var s struct {
+			R, S *big.Int
+		}
+		if rest, err := asn1.Unmarshal(sig, &s); err != nil || len(rest) != 0 {
+			return false
+		}
+		return ecdsa.Verify(k, hashed, s.R, s.S)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, hash, hashed, sig) == nil
+	default:
+		return false
+	}
+}
+
+// ParseKeyring parses PEM encoded public keys, as PKIX public keys or X.509
+// certificates.
+func ParseKeyring(data []byte) (Keyring, error) {
+	keyring := Keyring{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var key crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			k, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid public key")
+			}
+			key = k
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid certificate")
+			}
+			key = cert.PublicKey
+		default:
+			return nil, fmt.Errorf("unsupported PEM block %q in keyring", block.Type)
+		}
+		if err := keyring.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	if len(keyring) == 0 {
+		return nil, errors.New("no public key found in keyring")
+	}
+	return keyring, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS #8, PKCS #1 or SEC 1 private key.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no private key found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case *ecdsa.PrivateKey:
+			return k, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
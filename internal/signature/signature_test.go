@@ -0,0 +1,165 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/checksum"
+	"github.com/docker/app/internal/cnab"
+	digest "github.com/opencontainers/go-digest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func testBundle() *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:    "app",
+		Version: "0.1.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "app:0.1.0"}},
+		},
+	}
+}
+
+func testKeys(t *testing.T) []crypto.Signer {
+	t.Helper()
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	rs, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	return []crypto.Signer{ec, rs}
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, key := range testKeys(t) {
+		keyring := Keyring{}
+		assert.NilError(t, keyring.Add(key.Public()))
+
+		data, err := Sign(testBundle(), key)
+		assert.NilError(t, err)
+		b, err := Verify(data, keyring)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, b, testBundle())
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	key := testKeys(t)[0]
+	keyring := Keyring{}
+	assert.NilError(t, keyring.Add(key.Public()))
+	data, err := Sign(testBundle(), key)
+	assert.NilError(t, err)
+
+	var doc Document
+	assert.NilError(t, json.Unmarshal(data, &doc))
+	tampered := testBundle()
+	tampered.InvocationImages[0].Image = "evil:0.1.0"
+	doc.Signed, err = json.Marshal(tampered)
+	assert.NilError(t, err)
+	data, err = json.Marshal(doc)
+	assert.NilError(t, err)
+
+	_, err = Verify(data, keyring)
+	assert.Error(t, err, "invalid bundle signature")
+}
+
+func TestVerifyUntrustedKey(t *testing.T) {
+	keys := testKeys(t)
+	keyring := Keyring{}
+	assert.NilError(t, keyring.Add(keys[1].Public()))
+	sig, err := SignDetached(testBundle(), keys[0])
+	assert.NilError(t, err)
+
+	err = VerifyDetached(testBundle(), keyring, sig)
+	assert.ErrorContains(t, err, "not signed by a trusted key")
+	assert.Equal(t, VerifyDetached(testBundle(), keyring), ErrUnsigned)
+}
+
+func TestParseKeys(t *testing.T) {
+	key := testKeys(t)[0].(*ecdsa.PrivateKey)
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+	signer, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	assert.NilError(t, err)
+
+	der, err = x509.MarshalPKIXPublicKey(key.Public())
+	assert.NilError(t, err)
+	keyring, err := ParseKeyring(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(keyring, 1))
+
+	sig, err := SignDetached(testBundle(), signer)
+	assert.NilError(t, err)
+	assert.NilError(t, VerifyDetached(testBundle(), keyring, sig))
+
+	_, err = ParseKeyring([]byte("garbage"))
+	assert.Error(t, err, "no public key found in keyring")
+}
+
+func TestSignVerifyJCS(t *testing.T) {
+	key := testKeys(t)[0]
+	keyring := Keyring{}
+	assert.NilError(t, keyring.Add(key.Public()))
+	b := testBundle()
+	// Signed by JCS only, as the docker canonical JSON sorts the keys
+	// differently
+	b.Custom = map[string]interface{}{"\ufb33": 1, "\U0001F600": 2}
+
+	sig, err := SignDetached(b, key, cnab.WithCanonicalizer(cnab.JCS))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(sig.Canonicalization, "jcs"))
+	assert.NilError(t, VerifyDetached(b, keyring, sig))
+
+	data, err := Sign(b, key, cnab.WithCanonicalizer(cnab.JCS))
+	assert.NilError(t, err)
+	_, err = Verify(data, keyring)
+	assert.NilError(t, err)
+
+	// The signature does not verify with another canonicalization
+	sig.Canonicalization = ""
+	assert.Error(t, VerifyDetached(b, keyring, sig), "invalid bundle signature")
+	sig.Canonicalization = "unknown"
+	assert.Error(t, VerifyDetached(b, keyring, sig), "invalid bundle signature")
+
+	// Signatures with the default canonicalization do not record it
+	sig, err = SignDetached(b, key)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(sig.Canonicalization, ""))
+}
+
+func TestSignVerifyHash(t *testing.T) {
+	for _, key := range testKeys(t) {
+		keyring := Keyring{}
+		assert.NilError(t, keyring.Add(key.Public()))
+
+		sig, err := SignDetached(testBundle(), key, cnab.WithDigestConfig(checksum.Config{Algorithm: digest.SHA384}))
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(sig.Hash, "sha384"))
+		// The key ID is computed with the hash of the signature
+		_, found := keyring[sig.KeyID]
+		assert.Check(t, !found)
+		assert.NilError(t, VerifyDetached(testBundle(), keyring, sig))
+
+		// The signature does not verify with another hash
+		sig.Hash = "sha512"
+		assert.Check(t, VerifyDetached(testBundle(), keyring, sig) != nil)
+		sig.Hash = "md5"
+		assert.Error(t, VerifyDetached(testBundle(), keyring, sig), "invalid bundle signature")
+
+		// Signatures with the default hash do not record it
+		sig, err = SignDetached(testBundle(), key)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(sig.Hash, ""))
+	}
+
+	_, err := SignDetached(testBundle(), testKeys(t)[0], cnab.WithDigestConfig(checksum.Config{Algorithm: "md5"}))
+	assert.Check(t, is.ErrorContains(err, `unsupported digest algorithm "md5"`))
+}
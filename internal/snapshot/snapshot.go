@@ -0,0 +1,347 @@
+// Package snapshot exports the full state of an installation to a portable
+// archive, and restores it on another host, for instance to migrate
+// installations between management hosts or to attach them to a support
+// request.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/redact"
+	"github.com/docker/app/internal/store"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Version is the version of the snapshot format.
+	Version = "1"
+
+	manifestFile = "snapshot.json"
+	bundlesDir   = "bundles"
+	revisionsDir = "revisions"
+	// maxFileSize bounds the files read from an archive.
+	maxFileSize = 64 << 20
+)
+
+// Manifest describes the content of a snapshot.
+type Manifest struct {
+	Version      string    `json:"version"`
+	Installation string    `json:"installation"`
+	Created      time.Time `json:"created"`
+	// Revisions are the revisions of the installation, oldest first. The
+	// last one is the current state of the installation.
+	Revisions []Revision `json:"revisions"`
+}
+
+// Revision describes a revision of the installation in a snapshot.
+type Revision struct {
+	Revision string `json:"revision"`
+	// BundleDigest is the digest of the canonical bundle the revision ran,
+	// see cnab.Digest. It is empty if the revision has no bundle.
+	BundleDigest string `json:"bundleDigest,omitempty"`
+	// RedactedParameters are the sensitive parameters whose values are
+	// masked in the snapshot.
+	RedactedParameters []string `json:"redactedParameters,omitempty"`
+	// RedactedOutputs are the sensitive outputs whose values are masked in
+	// the snapshot.
+	RedactedOutputs []string `json:"redactedOutputs,omitempty"`
+}
+
+// Snapshot writes all the revisions of an installation, with the exact
+// bundles they ran, to a gzipped tarball. The values of the sensitive
+// parameters and outputs are masked, so the snapshot can be shared. The
+// outputs must be read in clear text, through store.EncryptedStore if they
+// are encrypted, as the snapshot is restored with another key.
+func Snapshot(installations store.InstallationStore, installationName string, w io.Writer) (*Manifest, error) {
+	revisions, err := installations.Revisions(installationName)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("Installation %q not found", installationName)
+	}
+	manifest := &Manifest{
+		Version:      Version,
+		Installation: installationName,
+		Created:      time.Now().UTC(),
+	}
+	bundles := map[string][]byte{}
+	records := make([][]byte, len(revisions))
+	for i, installation := range revisions {
+		revision, record, err := redactRevision(installation)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to snapshot revision %q of installation %q", installation.Revision, installationName)
+		}
+		if installation.Bundle != nil {
+			data, err := canonicaljson.MarshalCanonical(installation.Bundle)
+			if err != nil {
+				return nil, err
+			}
+			bundles[revision.BundleDigest] = data
+		}
+		manifest.Revisions = append(manifest.Revisions, revision)
+		records[i] = record
+	}
+
+	gz := gzip.NewWriter(w)
+	tarout := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := addFile(tarout, manifestFile, data); err != nil {
+		return nil, err
+	}
+	digests := make([]string, 0, len(bundles))
+	for d := range bundles {
+		digests = append(digests, d)
+	}
+	sort.Strings(digests)
+	for _, d := range digests {
+		if err := addFile(tarout, bundleFile(d), bundles[d]); err != nil {
+			return nil, err
+		}
+	}
+	for i, record := range records {
+		if err := addFile(tarout, revisionFile(i), record); err != nil {
+			return nil, err
+		}
+	}
+	if err := tarout.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+// redactRevision returns the description of a revision and its record,
+// without bundle and with the sensitive values masked.
+func redactRevision(installation *store.Installation) (Revision, []byte, error) {
+	revision := Revision{Revision: installation.Revision}
+	if installation.EncryptedOutputs {
+		return Revision{}, nil, errors.New("the outputs are encrypted")
+	}
+	record := *installation
+	record.Bundle = nil
+	if b := installation.Bundle; b != nil {
+		var err error
+		if revision.BundleDigest, err = cnab.Digest(b); err != nil {
+			return Revision{}, nil, err
+		}
+		sensitive, err := cnab.SensitiveParameters(b)
+		if err != nil {
+			return Revision{}, nil, err
+		}
+		revision.RedactedParameters = present(sensitive, installation.Parameters)
+		record.Parameters = redact.Parameters(installation.Parameters, sensitive...)
+
+		outputs, err := cnab.ReadOutputs(b)
+		if err != nil {
+			return Revision{}, nil, err
+		}
+		var sensitiveOutputs []string
+		for name, output := range outputs {
+			if output.Sensitive {
+				sensitiveOutputs = append(sensitiveOutputs, name)
+			}
+		}
+		sort.Strings(sensitiveOutputs)
+		revision.RedactedOutputs = presentString(sensitiveOutputs, installation.Outputs)
+		if len(revision.RedactedOutputs) > 0 {
+			record.Outputs = make(map[string]string, len(installation.Outputs))
+			for name, value := range installation.Outputs {
+				record.Outputs[name] = value
+			}
+			for _, name := range revision.RedactedOutputs {
+				record.Outputs[name] = redact.Mask
+			}
+		}
+	}
+	data, err := json.MarshalIndent(&record, "", "  ")
+	if err != nil {
+		return Revision{}, nil, err
+	}
+	return revision, data, nil
+}
+
+// Restore stores the revisions of the installation of a snapshot, verifying
+// the digests of their bundles. The installation must not exist yet. The
+// masked values of the sensitive parameters and outputs are dropped, so the
+// sensitive parameters must be given again on the next action.
+func Restore(installations store.InstallationStore, r io.Reader) (*store.Installation, error) {
+	manifest, bundles, records, err := read(r)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := installations.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range existing {
+		if name == manifest.Installation {
+			return nil, fmt.Errorf("Installation %q already exists", manifest.Installation)
+		}
+	}
+	restored := make([]*store.Installation, len(manifest.Revisions))
+	for i, revision := range manifest.Revisions {
+		installation, err := restoreRevision(manifest, revision, bundles, records[revisionFile(i)])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid revision %q of installation %q", revision.Revision, manifest.Installation)
+		}
+		restored[i] = installation
+	}
+	for _, installation := range restored {
+		if err := installations.Store(installation); err != nil {
+			return nil, err
+		}
+	}
+	return restored[len(restored)-1], nil
+}
+
+func restoreRevision(manifest *Manifest, revision Revision, bundles map[string]*bundle.Bundle, record []byte) (*store.Installation, error) {
+	if record == nil {
+		return nil, errors.New("missing record")
+	}
+	var installation store.Installation
+	if err := json.Unmarshal(record, &installation); err != nil {
+		return nil, err
+	}
+	if installation.Name != manifest.Installation || installation.Revision != revision.Revision {
+		return nil, fmt.Errorf("record of revision %q of installation %q does not match the manifest", installation.Revision, installation.Name)
+	}
+	if installation.EncryptedOutputs {
+		return nil, errors.New("the outputs are encrypted")
+	}
+	if revision.BundleDigest != "" {
+		b, ok := bundles[revision.BundleDigest]
+		if !ok {
+			return nil, fmt.Errorf("missing bundle %s", revision.BundleDigest)
+		}
+		installation.Bundle = b
+	}
+	for _, name := range revision.RedactedParameters {
+		delete(installation.Parameters, name)
+	}
+	for _, name := range revision.RedactedOutputs {
+		delete(installation.Outputs, name)
+	}
+	return &installation, nil
+}
+
+// read reads the manifest, the bundles keyed by digest and the revision
+// records keyed by file name of a snapshot.
+func read(r io.Reader) (*Manifest, map[string]*bundle.Bundle, map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid snapshot")
+	}
+	defer gz.Close()
+	var manifest *Manifest
+	bundles := map[string]*bundle.Bundle{}
+	records := map[string][]byte{}
+	tarin := tar.NewReader(gz)
+	for {
+		header, err := tarin.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "invalid snapshot")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxFileSize {
+			return nil, nil, nil, fmt.Errorf("invalid snapshot: %s is too large", header.Name)
+		}
+		data, err := ioutil.ReadAll(tarin)
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "invalid snapshot")
+		}
+		switch dir := path.Dir(header.Name); {
+		case header.Name == manifestFile:
+			manifest = &Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, nil, errors.Wrap(err, "invalid snapshot manifest")
+			}
+		case dir == bundlesDir:
+			b, err := cnab.Parse(data)
+			if err != nil {
+				return nil, nil, nil, errors.Wrapf(err, "invalid snapshot bundle %s", header.Name)
+			}
+			d, err := cnab.Digest(b)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if bundleFile(d) != header.Name {
+				return nil, nil, nil, fmt.Errorf("invalid snapshot: bundle %s does not match its digest %s", header.Name, d)
+			}
+			bundles[d] = b
+		case dir == revisionsDir:
+			records[header.Name] = data
+		}
+	}
+	if manifest == nil {
+		return nil, nil, nil, fmt.Errorf("invalid snapshot: missing %s", manifestFile)
+	}
+	if manifest.Version != Version {
+		return nil, nil, nil, fmt.Errorf("unsupported snapshot version %q, expected %q", manifest.Version, Version)
+	}
+	if len(manifest.Revisions) == 0 {
+		return nil, nil, nil, errors.New("invalid snapshot: no revision")
+	}
+	return manifest, bundles, records, nil
+}
+
+func bundleFile(digest string) string {
+	return path.Join(bundlesDir, strings.Replace(digest, ":", "-", 1)+".json")
+}
+
+func revisionFile(index int) string {
+	return path.Join(revisionsDir, fmt.Sprintf("%06d.json", index))
+}
+
+func addFile(tarout *tar.Writer, name string, data []byte) error {
+	if err := tarout.WriteHeader(&tar.Header{
+		Name:     name,
+		Size:     int64(len(data)),
+		Mode:     0644,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err := tarout.Write(data)
+	return err
+}
+
+// present returns the names having a value.
+func present(names []string, values map[string]interface{}) []string {
+	var result []string
+	for _, name := range names {
+		if _, ok := values[name]; ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func presentString(names []string, values map[string]string) []string {
+	var result []string
+	for _, name := range names {
+		if _, ok := values[name]; ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
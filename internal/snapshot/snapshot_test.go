@@ -0,0 +1,206 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/store"
+	"github.com/docker/app/pkg/bundletest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func testBundle(version string) *bundle.Bundle {
+	return bundletest.NewTestBundle(
+		bundletest.WithVersion(version),
+		bundletest.WithParameter("password", bundle.ParameterDefinition{DataType: "string", Destination: &bundle.Location{EnvironmentVariable: "PASSWORD"}}),
+		bundletest.WithParameter("port", bundle.ParameterDefinition{DataType: "int", Destination: &bundle.Location{EnvironmentVariable: "PORT"}}),
+		bundletest.WithCustom(cnab.SensitiveParametersExtensionKey, []interface{}{"password"}),
+		bundletest.WithCustom(cnab.ParameterSchemasExtensionKey, map[string]interface{}{
+			"definitions": map[string]interface{}{
+				"string": map[string]interface{}{"type": "string"},
+			},
+		}),
+		bundletest.WithCustom(cnab.OutputsExtensionKey, map[string]interface{}{
+			"url":   map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/url"},
+			"token": map[string]interface{}{"definition": "string", "path": "/cnab/app/outputs/token", "sensitive": true},
+		}),
+	)
+}
+
+// storeRevisions stores an installation with two revisions.
+func storeRevisions(t *testing.T, installations store.InstallationStore) *store.Installation {
+	t.Helper()
+	installation, err := store.NewInstallation("my-installation", "my-app:1.0.0")
+	assert.NilError(t, err)
+	installation.Bundle = testBundle("1.0.0")
+	installation.Parameters = map[string]interface{}{"password": "secret", "port": "8080"}
+	installation.Outputs = map[string]string{"url": "http://localhost:8080", "token": "s3cr3t"}
+	installation.Update(claim.ActionInstall, claim.StatusSuccess)
+	assert.NilError(t, installations.Store(installation))
+	installation.Bundle = testBundle("1.1.0")
+	installation.Reference = "my-app:1.1.0"
+	installation.Labels = map[string]string{"team": "payments"}
+	installation.Update(claim.ActionUpgrade, claim.StatusSuccess)
+	assert.NilError(t, installations.Store(installation))
+	return installation
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	source := store.NewMemoryInstallationStore()
+	installation := storeRevisions(t, source)
+
+	buf := bytes.NewBuffer(nil)
+	manifest, err := Snapshot(source, "my-installation", buf)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(manifest.Installation, "my-installation"))
+	assert.Assert(t, is.Len(manifest.Revisions, 2))
+	digest, err := cnab.Digest(installation.Bundle)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(manifest.Revisions[1].BundleDigest, digest))
+	assert.Check(t, is.DeepEqual(manifest.Revisions[1].RedactedParameters, []string{"password"}))
+	assert.Check(t, is.DeepEqual(manifest.Revisions[1].RedactedOutputs, []string{"token"}))
+	assert.Check(t, !bytes.Contains(archiveContent(t, buf.Bytes()), []byte("secret")), "the snapshot discloses secrets")
+
+	target := store.NewMemoryInstallationStore()
+	restored, err := Restore(target, bytes.NewReader(buf.Bytes()))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(restored.Revision, installation.Revision))
+	assert.Check(t, is.Equal(restored.Reference, "my-app:1.1.0"))
+	assert.Check(t, is.DeepEqual(restored.Labels, map[string]string{"team": "payments"}))
+	assert.Check(t, is.DeepEqual(restored.Parameters, map[string]interface{}{"port": "8080"}))
+	assert.Check(t, is.DeepEqual(restored.Outputs, map[string]string{"url": "http://localhost:8080"}))
+	assert.Check(t, cnab.MatchesDigest(restored.Bundle, digest))
+
+	revisions, err := target.Revisions("my-installation")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(revisions, 2))
+	assert.Check(t, is.Equal(revisions[0].Result.Action, claim.ActionInstall))
+	assert.Check(t, is.Equal(revisions[0].Bundle.Version, "1.0.0"))
+	current, err := target.Read("my-installation")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(current.Revision, installation.Revision))
+
+	// The installation cannot be restored twice
+	_, err = Restore(target, bytes.NewReader(buf.Bytes()))
+	assert.Check(t, is.Error(err, `Installation "my-installation" already exists`))
+}
+
+func TestSnapshotUnknownInstallation(t *testing.T) {
+	_, err := Snapshot(store.NewMemoryInstallationStore(), "unknown", bytes.NewBuffer(nil))
+	assert.Check(t, is.Error(err, `Installation "unknown" not found`))
+}
+
+func TestSnapshotEncryptedOutputs(t *testing.T) {
+	newStore := func(raw store.InstallationStore, key string) store.InstallationStore {
+		keys, err := store.NewAESKeyProvider([]byte(strings.Repeat(key, 32)))
+		assert.NilError(t, err)
+		return store.NewEncryptedStore(raw, keys)
+	}
+	raw := store.NewMemoryInstallationStore()
+	storeRevisions(t, newStore(raw, "a"))
+
+	// The encrypted outputs cannot be restored with another key
+	_, err := Snapshot(raw, "my-installation", bytes.NewBuffer(nil))
+	assert.Check(t, is.ErrorContains(err, "the outputs are encrypted"))
+
+	buf := bytes.NewBuffer(nil)
+	_, err = Snapshot(newStore(raw, "a"), "my-installation", buf)
+	assert.NilError(t, err)
+	target := newStore(store.NewMemoryInstallationStore(), "b")
+	_, err = Restore(target, bytes.NewReader(buf.Bytes()))
+	assert.NilError(t, err)
+	restored, err := target.Read("my-installation")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(restored.Outputs, map[string]string{"url": "http://localhost:8080"}))
+}
+
+func TestRestoreVerifiesBundleDigests(t *testing.T) {
+	source := store.NewMemoryInstallationStore()
+	storeRevisions(t, source)
+	buf := bytes.NewBuffer(nil)
+	_, err := Snapshot(source, "my-installation", buf)
+	assert.NilError(t, err)
+
+	// Replace the content of the bundles
+	tampered := rewriteArchive(t, buf.Bytes(), func(name string, data []byte) []byte {
+		if !strings.HasPrefix(name, bundlesDir+"/") {
+			return data
+		}
+		return bytes.Replace(data, []byte(`"1.`), []byte(`"9.`), 1)
+	})
+	_, err = Restore(store.NewMemoryInstallationStore(), bytes.NewReader(tampered))
+	assert.Check(t, is.ErrorContains(err, "does not match its digest"))
+}
+
+func TestRestoreInvalidSnapshots(t *testing.T) {
+	_, err := Restore(store.NewMemoryInstallationStore(), bytes.NewReader([]byte("not a snapshot")))
+	assert.Check(t, is.ErrorContains(err, "invalid snapshot"))
+
+	_, err = Restore(store.NewMemoryInstallationStore(), bytes.NewReader(writeArchive(t, map[string][]byte{})))
+	assert.Check(t, is.Error(err, "invalid snapshot: missing snapshot.json"))
+
+	_, err = Restore(store.NewMemoryInstallationStore(), bytes.NewReader(writeArchive(t, map[string][]byte{
+		manifestFile: []byte(`{"version":"2"}`),
+	})))
+	assert.Check(t, is.Error(err, `unsupported snapshot version "2", expected "1"`))
+
+	_, err = Restore(store.NewMemoryInstallationStore(), bytes.NewReader(writeArchive(t, map[string][]byte{
+		manifestFile: []byte(`{"version":"1","installation":"my-installation","revisions":[{"revision":"01"}]}`),
+	})))
+	assert.Check(t, is.Error(err, `invalid revision "01" of installation "my-installation": missing record`))
+}
+
+// archiveContent returns the concatenated files of an archive.
+func archiveContent(t *testing.T, archive []byte) []byte {
+	t.Helper()
+	var content []byte
+	rewriteArchive(t, archive, func(name string, data []byte) []byte {
+		content = append(content, data...)
+		return data
+	})
+	return content
+}
+
+// rewriteArchive rewrites the files of an archive, in order.
+func rewriteArchive(t *testing.T, archive []byte, rewrite func(name string, data []byte) []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	assert.NilError(t, err)
+	tarin := tar.NewReader(gz)
+	out := bytes.NewBuffer(nil)
+	gzout := gzip.NewWriter(out)
+	tarout := tar.NewWriter(gzout)
+	for {
+		header, err := tarin.Next()
+		if err != nil {
+			break
+		}
+		data := bytes.NewBuffer(nil)
+		_, err = data.ReadFrom(tarin)
+		assert.NilError(t, err)
+		assert.NilError(t, addFile(tarout, header.Name, rewrite(header.Name, data.Bytes())))
+	}
+	assert.NilError(t, tarout.Close())
+	assert.NilError(t, gzout.Close())
+	return out.Bytes()
+}
+
+func writeArchive(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	out := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(out)
+	tarout := tar.NewWriter(gz)
+	for name, data := range files {
+		assert.NilError(t, addFile(tarout, name, data))
+	}
+	assert.NilError(t, tarout.Close())
+	assert.NilError(t, gz.Close())
+	return out.Bytes()
+}
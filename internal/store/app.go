@@ -2,6 +2,7 @@ package store
 
 import (
 	_ "crypto/sha256" // ensure ids can be computed
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -19,12 +20,20 @@ const (
 	CredentialStoreDirectory = "credentials"
 	// InstallationStoreDirectory is the installations store directory name
 	InstallationStoreDirectory = "installations"
+	// InstallationRevisionsDirectory is the directory name, inside an installation store, holding
+	// all the installations revisions
+	InstallationRevisionsDirectory = "revisions"
+	// ChannelStoreDirectory is the channel store directory name
+	ChannelStoreDirectory = "channels"
+	// BundleCacheDirectory is the bundle cache directory name
+	BundleCacheDirectory = "cache"
 )
 
 // ApplicationStore is the main point to access different stores:
 // - Bundle store persists all bundles built or fetched locally
 // - Credential store persists all the credentials, per context basis
 // - Installation store persists all the installations, per context basis
+// - Channel store persists which bundle versions are published on each channel
 type ApplicationStore struct {
 	path string
 }
@@ -41,6 +50,7 @@ func NewApplicationStore(configDir string) (*ApplicationStore, error) {
 		{BundleStoreDirectory, 0755},
 		{CredentialStoreDirectory, 0700},
 		{InstallationStoreDirectory, 0755},
+		{ChannelStoreDirectory, 0755},
 	}
 	for _, d := range directories {
 		if err := os.MkdirAll(filepath.Join(storePath, d.dir), d.perm); err != nil {
@@ -56,7 +66,31 @@ func (a ApplicationStore) InstallationStore(context string) (InstallationStore,
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, errors.Wrapf(err, "failed to create installation store directory for context %q", context)
 	}
-	return &installationStore{store: crud.NewFileSystemStore(path, "json")}, nil
+	return newFileSystemInstallationStore(path), nil
+}
+
+// InstallationStores returns the installation stores of all the contexts
+// which have one. The names of the contexts are not kept by the stores.
+func (a ApplicationStore) InstallationStores() ([]InstallationStore, error) {
+	dirs, err := ioutil.ReadDir(filepath.Join(a.path, InstallationStoreDirectory))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list installation stores")
+	}
+	var stores []InstallationStore
+	for _, dir := range dirs {
+		if dir.IsDir() {
+			stores = append(stores, newFileSystemInstallationStore(filepath.Join(a.path, InstallationStoreDirectory, dir.Name())))
+		}
+	}
+	return stores, nil
+}
+
+func newFileSystemInstallationStore(path string) InstallationStore {
+	return &installationStore{
+		store:     crud.NewFileSystemStore(path, "json"),
+		revisions: crud.NewFileSystemStore(filepath.Join(path, InstallationRevisionsDirectory), "json"),
+		labels:    labelIndex{crud.NewFileSystemStore(filepath.Join(path, InstallationLabelsDirectory), "json")},
+	}
 }
 
 // CredentialStore initializes and returns a context based credential store
@@ -74,7 +108,31 @@ func (a ApplicationStore) BundleStore() (BundleStore, error) {
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, errors.Wrapf(err, "failed to create bundle store directory %q", path)
 	}
-	return &bundleStore{path: path}, nil
+	cache, err := a.BundleCache(0)
+	if err != nil {
+		return nil, err
+	}
+	return &bundleStore{path: path, cache: cache}, nil
+}
+
+// ChannelStore initializes and returns a channel store. The given policies
+// are evaluated, in order, before any promotion.
+func (a ApplicationStore) ChannelStore(policies ...PromotionPolicy) (ChannelStore, error) {
+	bundleStore, err := a.BundleStore()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(a.path, ChannelStoreDirectory)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create channel store directory %q", path)
+	}
+	return &channelStore{path: path, bundles: bundleStore, policies: policies}, nil
+}
+
+// BundleCache initializes and returns the bundle cache, evicted when it
+// exceeds maxSize bytes
+func (a ApplicationStore) BundleCache(maxSize int64) (*BundleCache, error) {
+	return NewBundleCache(filepath.Join(a.path, BundleCacheDirectory), maxSize)
 }
 
 func makeDigestedDirectory(context string) string {
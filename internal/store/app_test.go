@@ -1,9 +1,11 @@
 package store
 
 import (
+	"sort"
 	"testing"
 
 	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
 	"gotest.tools/fs"
 )
 
@@ -29,6 +31,7 @@ func TestNewApplicationStoreInitializesDirectories(t *testing.T) {
 		fs.WithMode(0755),
 		fs.WithDir("app",
 			fs.WithDir("bundles"),
+			fs.WithDir("channels"),
 			fs.WithDir("credentials", fs.WithMode(0700),
 				fs.WithDir("60b9683c6c2b05b8adc06ff4d150b15a5c69d74c7a7ee35bd733df12861dd2b0", fs.WithMode(0700))),
 			fs.WithDir("installations",
@@ -36,3 +39,28 @@ func TestNewApplicationStoreInitializesDirectories(t *testing.T) {
 	)
 	assert.Assert(t, fs.Equal(dockerConfigDir.Path(), manifest))
 }
+
+func TestInstallationStores(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	for _, context := range []string{"default", "remote"} {
+		installations, err := appstore.InstallationStore(context)
+		assert.NilError(t, err)
+		installation, err := NewInstallation(context+"-installation", "my-app:1.0.0")
+		assert.NilError(t, err)
+		assert.NilError(t, installations.Store(installation))
+	}
+
+	stores, err := appstore.InstallationStores()
+	assert.NilError(t, err)
+	var names []string
+	for _, installations := range stores {
+		list, err := installations.List()
+		assert.NilError(t, err)
+		names = append(names, list...)
+	}
+	sort.Strings(names)
+	assert.Check(t, is.DeepEqual(names, []string{"default-installation", "remote-installation"}))
+}
@@ -1,18 +1,26 @@
 package store
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	containerdremotes "github.com/containerd/containerd/remotes"
 	"github.com/docker/cli/cli/config/configfile"
 
 	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/app/internal/registryclient"
+	"github.com/docker/app/pkg/telemetry"
 	"github.com/docker/cnab-to-oci/remotes"
 	"github.com/docker/distribution/reference"
+	ocischemav1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
 
@@ -20,14 +28,31 @@ import (
 type BundleStore interface {
 	Store(ref reference.Named, bndle *bundle.Bundle) error
 	Read(ref reference.Named) (*bundle.Bundle, error)
+	// List returns the stored bundles, sorted by reference.
+	List() ([]StoredBundle, error)
+	// Remove deletes a stored bundle.
+	Remove(ref reference.Named) error
 
 	LookupOrPullBundle(ref reference.Named, pullRef bool, config *configfile.ConfigFile, insecureRegistries []string) (*bundle.Bundle, error)
+	// ResolveDigest pins the reference to the digest of the manifest it
+	// points to in the registry.
+	ResolveDigest(ref reference.Named, config *configfile.ConfigFile, insecureRegistries []string) (reference.Named, error)
 }
 
 var _ BundleStore = &bundleStore{}
 
+// StoredBundle is a bundle of the bundle store.
+type StoredBundle struct {
+	Ref reference.Named
+	// Size is the size of the stored bundle file.
+	Size int64
+}
+
 type bundleStore struct {
 	path string
+	// cache holds the pulled bundles by the digest of their manifest, if
+	// not nil.
+	cache *BundleCache
 }
 
 func (b *bundleStore) Store(ref reference.Named, bndle *bundle.Bundle) error {
@@ -38,7 +63,7 @@ func (b *bundleStore) Store(ref reference.Named, bndle *bundle.Bundle) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return errors.Wrapf(err, "failed to store bundle %q", ref)
 	}
-	err = bndle.WriteFile(path, 0644)
+	err = cnab.WriteFile(bndle, path, 0644, cnab.WriteFileOptions{Force: true})
 	return errors.Wrapf(err, "failed to store bundle %q", ref)
 }
 
@@ -47,23 +72,80 @@ func (b *bundleStore) Read(ref reference.Named) (*bundle.Bundle, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read bundle %q", ref)
 	}
-	data, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read bundle %q", ref)
 	}
-	var bndle bundle.Bundle
-	if err := json.Unmarshal(data, &bndle); err != nil {
-		return nil, errors.Wrapf(err, "failed to read bundle %q", ref)
+	defer f.Close()
+	bndle, err := cnab.ParseReaderLimited(f, cnab.MaxBundleSize)
+	return bndle, errors.Wrapf(err, "failed to read bundle %q", ref)
+}
+
+func (b *bundleStore) List() ([]StoredBundle, error) {
+	var bundles []StoredBundle
+	err := filepath.Walk(b.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		rel, err := filepath.Rel(b.path, path)
+		if err != nil {
+			return err
+		}
+		ref, err := storedReference(strings.TrimSuffix(filepath.ToSlash(rel), ".json"))
+		if err != nil {
+			// Not a bundle of the store
+			return nil
+		}
+		bundles = append(bundles, StoredBundle{Ref: ref, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list bundles")
+	}
+	sort.Slice(bundles, func(i, j int) bool { return bundles[i].Ref.String() < bundles[j].Ref.String() })
+	return bundles, nil
+}
+
+func (b *bundleStore) Remove(ref reference.Named) error {
+	path, err := b.storePath(ref)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove bundle %q", ref)
 	}
-	return &bndle, nil
+	return errors.Wrapf(os.Remove(path), "failed to remove bundle %q", ref)
+}
+
+// storedReference returns the reference of a bundle from its path in the
+// store, without the extension. It reverses storePath.
+func storedReference(path string) (reference.Named, error) {
+	var name, suffix string
+	if i := strings.Index(path, "/_tags/"); i >= 0 {
+		name, suffix = path[:i], ":"+path[i+len("/_tags/"):]
+	} else if i := strings.Index(path, "/_digests/"); i >= 0 {
+		name, suffix = path[:i], "@"+strings.Replace(path[i+len("/_digests/"):], "/", ":", 1)
+	} else {
+		return nil, errors.Errorf("%q is not a stored bundle", path)
+	}
+	// The domain cannot hold a "_", which replaces the ":" before its port
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = strings.Replace(name[:i], "_", ":", 1) + name[i:]
+	}
+	return reference.ParseNormalizedNamed(name + suffix)
 }
 
 // LookupOrPullBundle will fetch the given bundle from the local
 // bundle store, or if it is missing from the registry, and returns
-// it. Always pulls if pullRef is true. If it pulls then the local
-// bundle store is updated.
+// it. Always pulls if pullRef is true, except in offline mode. If it
+// pulls then the local bundle store is updated, the bundle cache avoiding to
+// pull the same manifest again.
 func (b *bundleStore) LookupOrPullBundle(ref reference.Named, pullRef bool, config *configfile.ConfigFile, insecureRegistries []string) (*bundle.Bundle, error) {
-	if !pullRef {
+	client, err := registryclient.NewFromConfig(config, insecureRegistries)
+	if err != nil {
+		return nil, err
+	}
+	if !pullRef || client.Offline() {
 		bndl, err := b.Read(ref)
 		if err == nil {
 			return bndl, nil
@@ -72,7 +154,12 @@ func (b *bundleStore) LookupOrPullBundle(ref reference.Named, pullRef bool, conf
 			return nil, err
 		}
 	}
-	bndl, err := remotes.Pull(context.TODO(), reference.TagNameOnly(ref), remotes.NewResolverConfigFromDockerConfigFile(config, insecureRegistries...).Resolver)
+	if client.Offline() {
+		return nil, offline.New("pulling %q", reference.FamiliarString(ref))
+	}
+	ctx, op := telemetry.Start(context.Background(), telemetry.SpanPull, telemetry.Fields{"reference": reference.FamiliarString(ref)})
+	bndl, err := b.pull(ctx, reference.TagNameOnly(ref), client.Resolver())
+	op.End(err)
 	if err != nil {
 		return nil, errors.Wrap(err, ref.String())
 	}
@@ -82,6 +169,108 @@ func (b *bundleStore) LookupOrPullBundle(ref reference.Named, pullRef bool, conf
 	return bndl, nil
 }
 
+// ResolveDigest returns the reference pinned to the digest of the manifest
+// its tag currently points to in the registry, so the bundle checked against
+// its signature is pulled from the same manifest even if the tag is moved in
+// between. References already pinned by digest are returned as is.
+func (b *bundleStore) ResolveDigest(ref reference.Named, config *configfile.ConfigFile, insecureRegistries []string) (reference.Named, error) {
+	if _, ok := ref.(reference.Digested); ok {
+		return ref, nil
+	}
+	client, err := registryclient.NewFromConfig(config, insecureRegistries)
+	if err != nil {
+		return nil, err
+	}
+	if client.Offline() {
+		return nil, offline.New("resolving %q", reference.FamiliarString(ref))
+	}
+	tagged := reference.TagNameOnly(ref)
+	_, descriptor, err := client.Resolver().Resolve(context.Background(), tagged.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve %q", reference.FamiliarString(ref))
+	}
+	return reference.WithDigest(tagged, descriptor.Digest)
+}
+
+// pull pulls the bundle, unless the manifest the reference resolves to was
+// already pulled to the cache. The bundle is pulled by the digest of the
+// resolved manifest, which is recorded in the cache.
+func (b *bundleStore) pull(ctx context.Context, ref reference.Named, resolver containerdremotes.Resolver) (*bundle.Bundle, error) {
+	resolver = limitedResolver{resolver}
+	if b.cache == nil {
+		return pullLimited(ctx, ref, resolver)
+	}
+	_, descriptor, err := resolver.Resolve(ctx, ref.String())
+	if err != nil {
+		// Let the pull report the error
+		return pullLimited(ctx, ref, resolver)
+	}
+	manifest := descriptor.Digest.String()
+	if bndl, err := b.cache.Lookup(manifest); err == nil {
+		return bndl, nil
+	}
+	digested, err := reference.WithDigest(reference.TrimNamed(ref), descriptor.Digest)
+	if err != nil {
+		return nil, err
+	}
+	bndl, err := pullLimited(ctx, digested, resolver)
+	if err != nil {
+		return nil, err
+	}
+	// The cache is only an optimization, failing to fill it is not an error
+	if d, err := b.cache.Put(bndl); err == nil {
+		b.cache.Link(d, manifest) //nolint:errcheck
+	}
+	return bndl, nil
+}
+
+// pullLimited pulls a bundle from an untrusted registry, applying the limits
+// of cnab.ParseReaderLimited: as the bundle is stored as an OCI config rather
+// than a bundle document, the fetched payloads are checked by
+// limitedResolver before being decoded, and the custom extensions once
+// converted.
+func pullLimited(ctx context.Context, ref reference.Named, resolver containerdremotes.Resolver) (*bundle.Bundle, error) {
+	bndl, err := remotes.Pull(ctx, ref, resolver)
+	if err != nil {
+		return nil, err
+	}
+	if err := cnab.DefaultCustomLimits.Check(bndl); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	return bndl, nil
+}
+
+// limitedResolver fails fetching payloads larger than cnab.MaxBundleSize or
+// nested too deeply to be decoded safely.
+type limitedResolver struct {
+	containerdremotes.Resolver
+}
+
+func (r limitedResolver) Fetcher(ctx context.Context, ref string) (containerdremotes.Fetcher, error) {
+	fetcher, err := r.Resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return containerdremotes.FetcherFunc(func(ctx context.Context, desc ocischemav1.Descriptor) (io.ReadCloser, error) {
+		if desc.Size > cnab.MaxBundleSize {
+			return nil, errors.Errorf("invalid bundle: %s is larger than %d bytes", desc.Digest, cnab.MaxBundleSize)
+		}
+		rc, err := fetcher.Fetch(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		data, err := cnab.ReadLimited(rc, cnab.MaxBundleSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := cnab.CheckNesting(data); err != nil {
+			return nil, errors.Wrap(err, "invalid bundle")
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}), nil
+}
+
 func (b *bundleStore) storePath(ref reference.Named) (string, error) {
 	name := ref.Name()
 	// A name is safe for use as a filesystem path (it is
@@ -1,13 +1,25 @@
 package store
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/containerd/containerd/remotes"
 	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
 	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
 	"gotest.tools/fs"
 )
 
@@ -156,3 +168,162 @@ func TestStorePath(t *testing.T) {
 		})
 	}
 }
+
+func TestLookupOrPullBundleOffline(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	bundleStore, err := appstore.BundleStore()
+	assert.NilError(t, err)
+	config := &configfile.ConfigFile{Plugins: map[string]map[string]string{"app": {offline.ConfigKey: "true"}}}
+
+	// The local bundle is used even when asked to pull
+	ref := parseRefOrDie(t, "my-repo/my-bundle:my-tag")
+	expectedBundle := &bundle.Bundle{Name: "bundle-name"}
+	assert.NilError(t, bundleStore.Store(ref, expectedBundle))
+	actualBundle, err := bundleStore.LookupOrPullBundle(ref, true, config, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, expectedBundle, actualBundle)
+
+	_, err = bundleStore.LookupOrPullBundle(parseRefOrDie(t, "my-repo/other-bundle:my-tag"), false, config, nil)
+	assert.Check(t, offline.IsOffline(err))
+	assert.Check(t, is.Error(err, `pulling "my-repo/other-bundle:my-tag" requires network access, which is disabled in offline mode`))
+}
+
+func TestResolveDigest(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	bundleStore, err := appstore.BundleStore()
+	assert.NilError(t, err)
+	config := &configfile.ConfigFile{Plugins: map[string]map[string]string{"app": {offline.ConfigKey: "true"}}}
+
+	// Pinned references are not resolved again
+	pinned := parseRefOrDie(t, "my-repo/my-bundle:my-tag@sha256:"+testSha)
+	resolved, err := bundleStore.ResolveDigest(pinned, config, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(resolved.String(), pinned.String()))
+
+	_, err = bundleStore.ResolveDigest(parseRefOrDie(t, "my-repo/my-bundle:my-tag"), config, nil)
+	assert.Check(t, offline.IsOffline(err))
+}
+
+func TestListAndRemoveBundles(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	bundleStore, err := appstore.BundleStore()
+	assert.NilError(t, err)
+
+	// Sorted by normalized reference
+	refs := []string{
+		"my-repo/my-bundle:my-tag",
+		"my-repo/my-bundle@sha256:" + testSha,
+		"localhost:5000/my_repo/my-bundle:1.0.0",
+	}
+	for _, ref := range refs {
+		assert.NilError(t, bundleStore.Store(parseRefOrDie(t, ref), &bundle.Bundle{Name: "bundle-name"}))
+	}
+	stored, err := bundleStore.List()
+	assert.NilError(t, err)
+	var actual []string
+	for _, s := range stored {
+		actual = append(actual, reference.FamiliarString(s.Ref))
+		assert.Check(t, s.Size > 0)
+	}
+	assert.Check(t, is.DeepEqual(actual, refs))
+
+	assert.NilError(t, bundleStore.Remove(parseRefOrDie(t, refs[2])))
+	stored, err = bundleStore.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(stored, 2))
+	err = bundleStore.Remove(parseRefOrDie(t, refs[2]))
+	assert.Check(t, os.IsNotExist(errors.Cause(err)))
+}
+
+// cachedResolver resolves any reference to the same manifest, which cannot
+// be fetched.
+type cachedResolver struct {
+	manifest digest.Digest
+}
+
+func (r cachedResolver) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	return ref, ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex, Digest: r.manifest, Size: 42}, nil
+}
+
+func (r cachedResolver) Fetcher(context.Context, string) (remotes.Fetcher, error) {
+	return nil, errors.New("cannot fetch")
+}
+
+func (r cachedResolver) Pusher(context.Context, string) (remotes.Pusher, error) {
+	return nil, errors.New("cannot push")
+}
+
+func TestPullFromBundleCache(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	store, err := appstore.BundleStore()
+	assert.NilError(t, err)
+	bs := store.(*bundleStore)
+	cache, err := appstore.BundleCache(0)
+	assert.NilError(t, err)
+	resolver := cachedResolver{manifest: digest.FromString("manifest")}
+	ref := parseRefOrDie(t, "my-repo/my-bundle:my-tag")
+
+	// Not cached yet, so it is fetched
+	_, err = bs.pull(context.Background(), ref, resolver)
+	assert.Check(t, is.ErrorContains(err, "cannot fetch"))
+
+	expected := &bundle.Bundle{Name: "bundle-name"}
+	d, err := cache.Put(expected)
+	assert.NilError(t, err)
+	assert.NilError(t, cache.Link(d, resolver.manifest.String()))
+	actual, err := bs.pull(context.Background(), ref, resolver)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(actual, expected))
+
+	_, err = cache.Lookup(digest.FromString("other").String())
+	assert.Check(t, os.IsNotExist(errors.Cause(err)))
+	assert.Check(t, is.ErrorContains(cache.Link("sha256:"+testSha, resolver.manifest.String()), "is not cached"))
+}
+
+// payloadResolver fetches the same payload for any descriptor.
+type payloadResolver struct {
+	cachedResolver
+	payload string
+}
+
+func (r payloadResolver) Fetcher(context.Context, string) (remotes.Fetcher, error) {
+	return remotes.FetcherFunc(func(context.Context, ocispec.Descriptor) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(r.payload)), nil
+	}), nil
+}
+
+func TestLimitedResolver(t *testing.T) {
+	fetch := func(payload string, size int64) (string, error) {
+		fetcher, err := limitedResolver{payloadResolver{payload: payload}}.Fetcher(context.Background(), "my-repo/my-bundle:my-tag")
+		assert.NilError(t, err)
+		rc, err := fetcher.Fetch(context.Background(), ocispec.Descriptor{Digest: digest.FromString(payload), Size: size})
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		data, err := ioutil.ReadAll(rc)
+		return string(data), err
+	}
+
+	data, err := fetch(`{"name":"bundle-name"}`, 22)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(data, `{"name":"bundle-name"}`))
+
+	_, err = fetch(`{}`, cnab.MaxBundleSize+1)
+	assert.Check(t, is.ErrorContains(err, "is larger than"))
+
+	_, err = fetch(strings.Repeat("[", cnab.MaxNestingDepth+1), 0)
+	assert.Check(t, is.ErrorContains(err, "nested too deeply"))
+}
@@ -0,0 +1,339 @@
+package store
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/docker/pkg/ioutils"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+const (
+	cacheBundleFile = "bundle.json"
+	cacheImagesFile = "images.tar"
+	// cacheManifestPrefix prefixes the files marking the registry manifests
+	// an entry was pulled from.
+	cacheManifestPrefix = "manifest-"
+)
+
+// now is replaced by tests.
+var now = time.Now
+
+// BundleCache stores bundles by the digest of their content, along with the
+// exported images of their invocation and component images, if any. Each
+// access marks the entry as used, the least recently used entries being
+// evicted first when the cache exceeds its maximum size.
+type BundleCache struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+}
+
+// NewBundleCache returns a cache stored in the given directory. A zero
+// maximum size means the cache is never evicted, only pruned.
+func NewBundleCache(path string, maxSize int64) (*BundleCache, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create bundle cache directory %q", path)
+	}
+	return &BundleCache{path: path, maxSize: maxSize}, nil
+}
+
+// Put stores the bundle and returns its digest, see cnab.Digest.
+func (c *BundleCache) Put(b *bundle.Bundle) (string, error) {
+	d, err := cnab.Digest(b)
+	if err != nil {
+		return "", err
+	}
+	dir, err := c.entryPath(d)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to cache bundle %s", d)
+	}
+	if err := cnab.WriteFile(b, filepath.Join(dir, cacheBundleFile), 0644, cnab.WriteFileOptions{Force: true}); err != nil {
+		return "", errors.Wrapf(err, "failed to cache bundle %s", d)
+	}
+	if err := c.touch(dir); err != nil {
+		return "", err
+	}
+	return d, c.evict(d)
+}
+
+// Get returns the bundle with the given digest. Entries whose content does
+// not match their digest are removed. The returned error satisfies
+// os.IsNotExist, once unwrapped with errors.Cause, if the bundle is missing.
+func (c *BundleCache) Get(d string) (*bundle.Bundle, error) {
+	dir, err := c.entryPath(d)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(d, dir)
+}
+
+func (c *BundleCache) get(d, dir string) (*bundle.Bundle, error) {
+	f, err := os.Open(filepath.Join(dir, cacheBundleFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cached bundle %s", d)
+	}
+	b, err := cnab.ParseReaderLimited(f, cnab.MaxBundleSize)
+	f.Close() //nolint:errcheck // the file is only read
+	if err != nil || !cnab.MatchesDigest(b, d) {
+		os.RemoveAll(dir) //nolint:errcheck // the entry is unusable anyway
+		return nil, errors.Wrapf(&os.PathError{Op: "read", Path: dir, Err: os.ErrNotExist}, "cached bundle %s is corrupted", d)
+	}
+	return b, c.touch(dir)
+}
+
+// Link records that the cached bundle with the given digest was pulled from
+// the registry manifest with the given digest, so Lookup finds it.
+func (c *BundleCache) Link(d, manifest string) error {
+	dir, err := c.entryPath(d)
+	if err != nil {
+		return err
+	}
+	name, err := manifestFile(manifest)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := os.Stat(filepath.Join(dir, cacheBundleFile)); err != nil {
+		return errors.Wrapf(err, "bundle %s is not cached", d)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+		return errors.Wrapf(err, "failed to link cached bundle %s to manifest %s", d, manifest)
+	}
+	return c.touch(dir)
+}
+
+// Lookup returns the cached bundle pulled from the registry manifest with the
+// given digest, see Link. The returned error satisfies os.IsNotExist, once
+// unwrapped with errors.Cause, if no such bundle is cached.
+func (c *BundleCache) Lookup(manifest string) (*bundle.Bundle, error) {
+	name, err := manifestFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(e.dir, name)); err == nil {
+			return c.get(e.digest, e.dir)
+		}
+	}
+	return nil, errors.Wrapf(&os.PathError{Op: "read", Path: c.path, Err: os.ErrNotExist}, "no cached bundle was pulled from manifest %s", manifest)
+}
+
+// PutImages stores the exported images of a cached bundle.
+func (c *BundleCache) PutImages(d string, r io.Reader) error {
+	dir, err := c.entryPath(d)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := os.Stat(filepath.Join(dir, cacheBundleFile)); err != nil {
+		return errors.Wrapf(err, "bundle %s is not cached", d)
+	}
+	w, err := ioutils.NewAtomicFileWriter(filepath.Join(dir, cacheImagesFile), 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close() //nolint:errcheck // the copy error is more relevant
+		return errors.Wrapf(err, "failed to cache images of bundle %s", d)
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrapf(err, "failed to cache images of bundle %s", d)
+	}
+	if err := c.touch(dir); err != nil {
+		return err
+	}
+	return c.evict(d)
+}
+
+// OpenImages opens the exported images of a cached bundle.
+func (c *BundleCache) OpenImages(d string) (io.ReadCloser, error) {
+	dir, err := c.entryPath(d)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.Open(filepath.Join(dir, cacheImagesFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cached images of bundle %s", d)
+	}
+	return f, c.touch(dir)
+}
+
+// Prune removes the entries which have not been used for the given duration,
+// and returns their digests.
+func (c *BundleCache) Prune(olderThan time.Duration) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	limit := now().Add(-olderThan)
+	var pruned []string
+	for _, e := range entries {
+		if e.used.Before(limit) {
+			if err := os.RemoveAll(e.dir); err != nil {
+				return pruned, err
+			}
+			pruned = append(pruned, e.digest)
+		}
+	}
+	return pruned, nil
+}
+
+// CachedBundle is an entry of the bundle cache.
+type CachedBundle struct {
+	Digest string
+	// Size is the size of the bundle and of its exported images.
+	Size int64
+	// Used is the last time the entry was used.
+	Used time.Time
+}
+
+// List returns the entries of the cache, from the least recently used.
+func (c *BundleCache) List() ([]CachedBundle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	cached := make([]CachedBundle, 0, len(entries))
+	for _, e := range entries {
+		cached = append(cached, CachedBundle{Digest: e.digest, Size: e.size, Used: e.used})
+	}
+	return cached, nil
+}
+
+// Remove removes an entry of the cache. Removing a missing entry is not an
+// error.
+func (c *BundleCache) Remove(d string) error {
+	dir, err := c.entryPath(d)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return errors.Wrapf(os.RemoveAll(dir), "failed to remove cached bundle %s", d)
+}
+
+type cacheEntry struct {
+	digest string
+	dir    string
+	used   time.Time
+	size   int64
+}
+
+// entries returns the cache entries, from the least recently used.
+func (c *BundleCache) entries() ([]cacheEntry, error) {
+	var entries []cacheEntry
+	algorithms, err := ioutil.ReadDir(c.path)
+	if err != nil {
+		return nil, err
+	}
+	for _, algorithm := range algorithms {
+		if !algorithm.IsDir() {
+			continue
+		}
+		encoded, err := ioutil.ReadDir(filepath.Join(c.path, algorithm.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range encoded {
+			if !e.IsDir() {
+				continue
+			}
+			entry := cacheEntry{
+				digest: algorithm.Name() + ":" + e.Name(),
+				dir:    filepath.Join(c.path, algorithm.Name(), e.Name()),
+				used:   e.ModTime(),
+			}
+			files, err := ioutil.ReadDir(entry.dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range files {
+				entry.size += f.Size()
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	return entries, nil
+}
+
+// evict removes the least recently used entries, except the given one, until
+// the cache fits its maximum size.
+func (c *BundleCache) evict(keep string) error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+	for _, e := range entries {
+		if size <= c.maxSize {
+			break
+		}
+		if e.digest == keep {
+			continue
+		}
+		if err := os.RemoveAll(e.dir); err != nil {
+			return err
+		}
+		size -= e.size
+	}
+	return nil
+}
+
+// touch marks an entry as used.
+func (c *BundleCache) touch(dir string) error {
+	t := now()
+	return os.Chtimes(dir, t, t)
+}
+
+func (c *BundleCache) entryPath(d string) (string, error) {
+	parsed, err := digest.Parse(d)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid bundle digest %q", d)
+	}
+	return filepath.Join(c.path, parsed.Algorithm().String(), parsed.Encoded()), nil
+}
+
+func manifestFile(manifest string) (string, error) {
+	parsed, err := digest.Parse(manifest)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid manifest digest %q", manifest)
+	}
+	return cacheManifestPrefix + parsed.Algorithm().String() + "-" + parsed.Encoded(), nil
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+// withClock freezes the cache clock, returning functions to advance and
+// restore it.
+func withClock(start time.Time) (func(time.Duration), func()) {
+	current := start
+	now = func() time.Time { return current }
+	return func(d time.Duration) { current = current.Add(d) }, func() { now = time.Now }
+}
+
+func TestBundleCachePutGet(t *testing.T) {
+	dir := fs.NewDir(t, "cache")
+	defer dir.Remove()
+	cache, err := NewBundleCache(dir.Path(), 0)
+	assert.NilError(t, err)
+
+	b := &bundle.Bundle{Name: "app", Version: "1.0.0"}
+	d, err := cache.Put(b)
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasPrefix(d, "sha256:"))
+
+	cached, err := cache.Get(d)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(cached, b))
+
+	_, err = cache.Get("sha256:" + testSha)
+	assert.Check(t, os.IsNotExist(errors.Cause(err)))
+	_, err = cache.Get("invalid")
+	assert.Check(t, is.ErrorContains(err, `invalid bundle digest "invalid"`))
+
+	assert.NilError(t, cache.PutImages(d, strings.NewReader("images")))
+	images, err := cache.OpenImages(d)
+	assert.NilError(t, err)
+	defer images.Close()
+	data, err := ioutil.ReadAll(images)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(data), "images"))
+	assert.Check(t, is.ErrorContains(cache.PutImages("sha256:"+testSha, strings.NewReader("")), "is not cached"))
+}
+
+func TestBundleCacheCorruptedEntry(t *testing.T) {
+	dir := fs.NewDir(t, "cache")
+	defer dir.Remove()
+	cache, err := NewBundleCache(dir.Path(), 0)
+	assert.NilError(t, err)
+	d, err := cache.Put(&bundle.Bundle{Name: "app"})
+	assert.NilError(t, err)
+	path, err := cache.entryPath(d)
+	assert.NilError(t, err)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(path, cacheBundleFile), []byte(`{"name":"other"}`), 0644))
+
+	_, err = cache.Get(d)
+	assert.Check(t, os.IsNotExist(errors.Cause(err)))
+	_, err = os.Stat(path)
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestBundleCachePrune(t *testing.T) {
+	advance, restore := withClock(time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC))
+	defer restore()
+	dir := fs.NewDir(t, "cache")
+	defer dir.Remove()
+	cache, err := NewBundleCache(dir.Path(), 0)
+	assert.NilError(t, err)
+
+	old, err := cache.Put(&bundle.Bundle{Name: "old"})
+	assert.NilError(t, err)
+	advance(48 * time.Hour)
+	recent, err := cache.Put(&bundle.Bundle{Name: "recent"})
+	assert.NilError(t, err)
+
+	pruned, err := cache.Prune(24 * time.Hour)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(pruned, []string{old}))
+	_, err = cache.Get(recent)
+	assert.NilError(t, err)
+}
+
+func TestBundleCacheEviction(t *testing.T) {
+	advance, restore := withClock(time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC))
+	defer restore()
+	dir := fs.NewDir(t, "cache")
+	defer dir.Remove()
+	cache, err := NewBundleCache(dir.Path(), 250)
+	assert.NilError(t, err)
+
+	first, err := cache.Put(&bundle.Bundle{Name: "first"})
+	assert.NilError(t, err)
+	advance(time.Minute)
+	second, err := cache.Put(&bundle.Bundle{Name: "second"})
+	assert.NilError(t, err)
+	advance(time.Minute)
+	// Using the first bundle makes the second one the least recently used
+	_, err = cache.Get(first)
+	assert.NilError(t, err)
+	advance(time.Minute)
+	third, err := cache.Put(&bundle.Bundle{Name: "third"})
+	assert.NilError(t, err)
+
+	_, err = cache.Get(second)
+	assert.Check(t, os.IsNotExist(errors.Cause(err)))
+	_, err = cache.Get(first)
+	assert.NilError(t, err)
+	_, err = cache.Get(third)
+	assert.NilError(t, err)
+}
+
+func TestBundleCacheListRemove(t *testing.T) {
+	dir := fs.NewDir(t, "cache")
+	defer dir.Remove()
+	cache, err := NewBundleCache(dir.Path(), 0)
+	assert.NilError(t, err)
+	advance, restore := withClock(time.Now())
+	defer restore()
+
+	d1, err := cache.Put(&bundle.Bundle{Name: "app", Version: "1.0.0"})
+	assert.NilError(t, err)
+	advance(time.Hour)
+	d2, err := cache.Put(&bundle.Bundle{Name: "app", Version: "2.0.0"})
+	assert.NilError(t, err)
+	assert.NilError(t, cache.PutImages(d2, strings.NewReader("image layers")))
+
+	cached, err := cache.List()
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(cached, 2))
+	assert.Check(t, is.Equal(cached[0].Digest, d1))
+	assert.Check(t, is.Equal(cached[1].Digest, d2))
+	assert.Check(t, cached[1].Size > cached[0].Size)
+
+	assert.NilError(t, cache.Remove(d1))
+	assert.NilError(t, cache.Remove(d1))
+	cached, err = cache.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(cached, 1))
+}
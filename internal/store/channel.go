@@ -0,0 +1,185 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/pkg/errors"
+)
+
+// PromotionPolicy is consulted before a bundle is promoted from one channel
+// to another. Returning an error vetoes the promotion.
+type PromotionPolicy func(bndl *bundle.Bundle, fromChannel, toChannel string) error
+
+// Promotion records a bundle version moving between two channels.
+// An empty From denotes the initial publication into a channel.
+type Promotion struct {
+	Version string    `json:"version"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to"`
+	Date    time.Time `json:"date"`
+}
+
+// ChannelStore tracks which version of a bundle is available on each
+// channel (for instance "staging" or "prod"). Promoting a version only moves
+// a pointer, the bundle itself is never copied or re-uploaded.
+type ChannelStore interface {
+	// Publish makes the given version of a stored bundle available on a channel.
+	Publish(name, version, channel string) error
+	// Promote moves the version currently published on fromChannel to toChannel.
+	Promote(name, version, fromChannel, toChannel string) error
+	// Lookup returns the version published on the given channel.
+	Lookup(name, channel string) (string, error)
+	// History returns all the promotions of a bundle, oldest first.
+	History(name string) ([]Promotion, error)
+}
+
+var _ ChannelStore = &channelStore{}
+
+type channelStore struct {
+	path     string
+	bundles  BundleStore
+	policies []PromotionPolicy
+}
+
+// channelState is the persisted form of a bundle channels.
+type channelState struct {
+	Channels map[string]string `json:"channels"`
+	History  []Promotion       `json:"history"`
+}
+
+func (c *channelStore) Publish(name, version, channel string) error {
+	if channel == "" {
+		return errors.New("failed to publish bundle, channel is empty")
+	}
+	if _, err := c.readBundle(name, version); err != nil {
+		return errors.Wrapf(err, "failed to publish %s:%s on channel %q", name, version, channel)
+	}
+	return c.update(name, func(state *channelState) error {
+		state.publish(version, "", channel)
+		return nil
+	})
+}
+
+func (c *channelStore) Promote(name, version, fromChannel, toChannel string) error {
+	if fromChannel == "" || toChannel == "" {
+		return errors.New("failed to promote bundle, source and target channels are required")
+	}
+	// The source channel is checked under the lock, so a concurrent
+	// promotion cannot move it in the meantime
+	return c.update(name, func(state *channelState) error {
+		current, ok := state.Channels[fromChannel]
+		if !ok {
+			return errors.Errorf("no version of %q published on channel %q", name, fromChannel)
+		}
+		if current != version {
+			return errors.Errorf("failed to promote %s:%s, channel %q holds version %q", name, version, fromChannel, current)
+		}
+		bndl, err := c.readBundle(name, version)
+		if err != nil {
+			return errors.Wrapf(err, "failed to promote %s:%s", name, version)
+		}
+		for _, policy := range c.policies {
+			if err := policy(bndl, fromChannel, toChannel); err != nil {
+				return errors.Wrapf(err, "promotion of %s:%s from %q to %q rejected", name, version, fromChannel, toChannel)
+			}
+		}
+		state.publish(version, fromChannel, toChannel)
+		return nil
+	})
+}
+
+func (c *channelStore) Lookup(name, channel string) (string, error) {
+	state, err := c.read(name)
+	if err != nil {
+		return "", err
+	}
+	version, ok := state.Channels[channel]
+	if !ok {
+		return "", errors.Errorf("no version of %q published on channel %q", name, channel)
+	}
+	return version, nil
+}
+
+func (c *channelStore) History(name string) ([]Promotion, error) {
+	state, err := c.read(name)
+	if err != nil {
+		return nil, err
+	}
+	return state.History, nil
+}
+
+func (c *channelStore) readBundle(name, version string) (*bundle.Bundle, error) {
+	ref, err := reference.ParseNormalizedNamed(name + ":" + version)
+	if err != nil {
+		return nil, err
+	}
+	return c.bundles.Read(ref)
+}
+
+// update applies a change to the channels of a bundle. The state file is
+// locked from the read to the write, so concurrent updates are applied one
+// after the other instead of overwriting each other. It is replaced
+// atomically, as readers do not take the lock.
+func (c *channelStore) update(name string, change func(*channelState) error) error {
+	f, err := os.OpenFile(c.statePath(name)+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to lock channels of %q", name)
+	}
+	defer f.Close() //nolint:errcheck // closing the file releases the lock
+	if err := waitLockFile(f); err != nil {
+		return errors.Wrapf(err, "failed to lock channels of %q", name)
+	}
+	state, err := c.read(name)
+	if err != nil {
+		return err
+	}
+	if err := change(state); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to store channels of %q", name)
+	}
+	err = ioutils.AtomicWriteFile(c.statePath(name), data, 0644)
+	return errors.Wrapf(err, "failed to store channels of %q", name)
+}
+
+func (c *channelStore) read(name string) (*channelState, error) {
+	state := &channelState{Channels: map[string]string{}}
+	data, err := ioutil.ReadFile(c.statePath(name))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read channels of %q", name)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to read channels of %q", name)
+	}
+	if state.Channels == nil {
+		state.Channels = map[string]string{}
+	}
+	return state, nil
+}
+
+// publish points a channel to a version and records it in the history.
+func (s *channelState) publish(version, fromChannel, toChannel string) {
+	s.Channels[toChannel] = version
+	s.History = append(s.History, Promotion{
+		Version: version,
+		From:    fromChannel,
+		To:      toChannel,
+		Date:    time.Now().UTC(),
+	})
+}
+
+func (c *channelStore) statePath(name string) string {
+	return filepath.Join(c.path, makeDigestedDirectory(name)+".json")
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func TestPromoteBundle(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	bundleStore, err := appstore.BundleStore()
+	assert.NilError(t, err)
+	assert.NilError(t, bundleStore.Store(parseRefOrDie(t, "my-repo/my-bundle:1.0.0"), &bundle.Bundle{Name: "my-bundle", Version: "1.0.0"}))
+
+	var promoted []string
+	channelStore, err := appstore.ChannelStore(func(bndl *bundle.Bundle, from, to string) error {
+		if to == "forbidden" {
+			return errors.New("forbidden channel")
+		}
+		promoted = append(promoted, bndl.Version)
+		return nil
+	})
+	assert.NilError(t, err)
+
+	// The bundle must exist in the bundle store to be published
+	err = channelStore.Publish("my-repo/my-bundle", "2.0.0", "staging")
+	assert.Check(t, is.ErrorContains(err, "failed to publish my-repo/my-bundle:2.0.0"))
+
+	assert.NilError(t, channelStore.Publish("my-repo/my-bundle", "1.0.0", "staging"))
+	_, err = channelStore.Lookup("my-repo/my-bundle", "prod")
+	assert.Check(t, is.ErrorContains(err, `no version of "my-repo/my-bundle" published on channel "prod"`))
+
+	// Only the version currently on the source channel can be promoted
+	err = channelStore.Promote("my-repo/my-bundle", "0.9.0", "staging", "prod")
+	assert.Check(t, is.ErrorContains(err, `channel "staging" holds version "1.0.0"`))
+
+	// Policies can veto a promotion
+	err = channelStore.Promote("my-repo/my-bundle", "1.0.0", "staging", "forbidden")
+	assert.Check(t, is.ErrorContains(err, "forbidden channel"))
+
+	assert.NilError(t, channelStore.Promote("my-repo/my-bundle", "1.0.0", "staging", "prod"))
+	version, err := channelStore.Lookup("my-repo/my-bundle", "prod")
+	assert.NilError(t, err)
+	assert.Equal(t, version, "1.0.0")
+	assert.DeepEqual(t, promoted, []string{"1.0.0"})
+
+	history, err := channelStore.History("my-repo/my-bundle")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(history, 2))
+	assert.Equal(t, history[0].From, "")
+	assert.Equal(t, history[0].To, "staging")
+	assert.Equal(t, history[1].From, "staging")
+	assert.Equal(t, history[1].To, "prod")
+}
+
+func TestConcurrentPublications(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	bundleStore, err := appstore.BundleStore()
+	assert.NilError(t, err)
+	assert.NilError(t, bundleStore.Store(parseRefOrDie(t, "my-repo/my-bundle:1.0.0"), &bundle.Bundle{Name: "my-bundle", Version: "1.0.0"}))
+	channelStore, err := appstore.ChannelStore()
+	assert.NilError(t, err)
+
+	// Each publication goes through its own store, like separate processes
+	const count = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			channelStore, err := appstore.ChannelStore()
+			if err == nil {
+				err = channelStore.Publish("my-repo/my-bundle", "1.0.0", fmt.Sprintf("channel-%d", i))
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NilError(t, err)
+	}
+
+	history, err := channelStore.History("my-repo/my-bundle")
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(history, count))
+	for i := 0; i < count; i++ {
+		version, err := channelStore.Lookup("my-repo/my-bundle", fmt.Sprintf("channel-%d", i))
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(version, "1.0.0"))
+	}
+}
@@ -0,0 +1,243 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// KeyProvider protects the data keys encrypting the output values, for
+// instance with an age recipient or a KMS key.
+type KeyProvider interface {
+	// WrapKey encrypts a data key.
+	WrapKey(key []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey.
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// aesKeyProvider wraps data keys with a static AES key.
+type aesKeyProvider struct {
+	aead cipher.AEAD
+}
+
+// NewAESKeyProvider returns a key provider wrapping data keys with AES-GCM,
+// using a 16, 24 or 32 bytes master key.
+func NewAESKeyProvider(masterKey []byte) (KeyProvider, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKeyProvider{aead: aead}, nil
+}
+
+func (p *aesKeyProvider) WrapKey(key []byte) ([]byte, error) {
+	return seal(p.aead, key, nil)
+}
+
+func (p *aesKeyProvider) UnwrapKey(wrapped []byte) ([]byte, error) {
+	return open(p.aead, wrapped, nil)
+}
+
+// encryptedValue is an output value encrypted with its own data key.
+type encryptedValue struct {
+	Key        []byte `json:"key"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptedStore is an installation store encrypting the output values of
+// the installations before storing them, as outputs may hold secrets like
+// generated passwords or kubeconfigs. Each value is encrypted with its own
+// data key, wrapped by the key provider, and bound to the installation and
+// output names, so it cannot be swapped with another value. The stored
+// installations are flagged with EncryptedOutputs and decrypted on read, the
+// ones stored in clear text before the store was encrypted are read as is.
+type EncryptedStore struct {
+	store InstallationStore
+	keys  KeyProvider
+}
+
+var _ InstallationStore = &EncryptedStore{}
+
+// NewEncryptedStore returns a store encrypting the output values of the
+// installations stored in the given store.
+func NewEncryptedStore(store InstallationStore, keys KeyProvider) *EncryptedStore {
+	return &EncryptedStore{store: store, keys: keys}
+}
+
+// List implements InstallationStore.
+func (s *EncryptedStore) List() ([]string, error) {
+	return s.store.List()
+}
+
+// Store implements InstallationStore. The given installation is not
+// modified.
+func (s *EncryptedStore) Store(installation *Installation) error {
+	if len(installation.Outputs) == 0 || installation.EncryptedOutputs {
+		return s.store.Store(installation)
+	}
+	encrypted := *installation
+	encrypted.EncryptedOutputs = true
+	encrypted.Outputs = make(map[string]string, len(installation.Outputs))
+	for name, value := range installation.Outputs {
+		v, err := s.encrypt(value, outputData(installation.Name, name))
+		if err != nil {
+			return errors.Wrapf(err, "failed to encrypt output %q", name)
+		}
+		encrypted.Outputs[name] = v
+	}
+	return s.store.Store(&encrypted)
+}
+
+// Read implements InstallationStore.
+func (s *EncryptedStore) Read(installationName string) (*Installation, error) {
+	installation, err := s.store.Read(installationName)
+	if err != nil {
+		return nil, err
+	}
+	return installation, s.decryptOutputs(installation)
+}
+
+// Delete implements InstallationStore.
+func (s *EncryptedStore) Delete(installationName string) error {
+	return s.store.Delete(installationName)
+}
+
+// Query implements InstallationStore.
+func (s *EncryptedStore) Query(q Query) (*QueryResult, error) {
+	result, err := s.store.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	for _, installation := range result.Installations {
+		if err := s.decryptOutputs(installation); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Revisions implements InstallationStore.
+func (s *EncryptedStore) Revisions(installationName string) ([]*Installation, error) {
+	revisions, err := s.store.Revisions(installationName)
+	if err != nil {
+		return nil, err
+	}
+	for _, installation := range revisions {
+		if err := s.decryptOutputs(installation); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+// ReadRevision implements InstallationStore.
+func (s *EncryptedStore) ReadRevision(installationName, revision string) (*Installation, error) {
+	installation, err := s.store.ReadRevision(installationName, revision)
+	if err != nil {
+		return nil, err
+	}
+	return installation, s.decryptOutputs(installation)
+}
+
+// outputData is the additional data authenticated with an output value.
+func outputData(installationName, outputName string) []byte {
+	return []byte(installationName + "\x00" + outputName)
+}
+
+func (s *EncryptedStore) encrypt(value string, additionalData []byte) (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, []byte(value), additionalData)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := s.keys.WrapKey(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(encryptedValue{Key: wrapped, Ciphertext: ciphertext})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (s *EncryptedStore) decryptOutputs(installation *Installation) error {
+	if !installation.EncryptedOutputs {
+		return nil
+	}
+	for name, value := range installation.Outputs {
+		v, err := s.decrypt(value, outputData(installation.Name, name))
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt output %q of installation %q", name, installation.Name)
+		}
+		installation.Outputs[name] = v
+	}
+	installation.EncryptedOutputs = false
+	return nil
+}
+
+func (s *EncryptedStore) decrypt(value string, additionalData []byte) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	var encrypted encryptedValue
+	if err := json.Unmarshal(data, &encrypted); err != nil {
+		return "", err
+	}
+	key, err := s.keys.UnwrapKey(encrypted.Key)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, encrypted.Ciphertext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and authenticates the additional data, prefixing
+// the ciphertext with a random nonce.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, errors.New("wrong key or corrupted value")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	keys, err := NewAESKeyProvider(bytes.Repeat([]byte{1}, 32))
+	assert.NilError(t, err)
+	backend := NewMemoryInstallationStore()
+	encrypted := NewEncryptedStore(backend, keys)
+
+	installation, err := NewInstallation("app", "")
+	assert.NilError(t, err)
+	installation.Outputs = map[string]string{"password": "s3cr3t", "kubeconfig": "apiVersion: v1"}
+	assert.NilError(t, encrypted.Store(installation))
+	assert.Check(t, is.Equal(installation.Outputs["password"], "s3cr3t"))
+
+	// Outputs are encrypted at rest
+	stored, err := backend.Read("app")
+	assert.NilError(t, err)
+	assert.Check(t, stored.EncryptedOutputs)
+	for _, value := range stored.Outputs {
+		assert.Check(t, !strings.Contains(value, "s3cr3t"))
+	}
+
+	read, err := encrypted.Read("app")
+	assert.NilError(t, err)
+	assert.Check(t, !read.EncryptedOutputs)
+	assert.Check(t, is.DeepEqual(read.Outputs, installation.Outputs))
+	revisions, err := encrypted.Revisions("app")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(revisions[0].Outputs, installation.Outputs))
+	revision, err := encrypted.ReadRevision("app", installation.Revision)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(revision.Outputs, installation.Outputs))
+
+	otherKeys, err := NewAESKeyProvider(bytes.Repeat([]byte{2}, 32))
+	assert.NilError(t, err)
+	_, err = NewEncryptedStore(backend, otherKeys).Read("app")
+	assert.Check(t, is.ErrorContains(err, "failed to decrypt output"))
+}
+
+func TestEncryptedStoreReadsClearTextOutputs(t *testing.T) {
+	keys, err := NewAESKeyProvider(bytes.Repeat([]byte{1}, 16))
+	assert.NilError(t, err)
+	backend := NewMemoryInstallationStore()
+	installation, err := NewInstallation("app", "")
+	assert.NilError(t, err)
+	installation.Outputs = map[string]string{"endpoint": "http://localhost", "note": "encrypted:v1:not really"}
+	assert.NilError(t, backend.Store(installation))
+
+	read, err := NewEncryptedStore(backend, keys).Read("app")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(read.Outputs, installation.Outputs))
+}
+
+func TestEncryptedStoreBindsValuesToOutputs(t *testing.T) {
+	keys, err := NewAESKeyProvider(bytes.Repeat([]byte{1}, 32))
+	assert.NilError(t, err)
+	backend := NewMemoryInstallationStore()
+	encrypted := NewEncryptedStore(backend, keys)
+	installation, err := NewInstallation("app", "")
+	assert.NilError(t, err)
+	installation.Outputs = map[string]string{"password": "s3cr3t", "username": "admin"}
+	assert.NilError(t, encrypted.Store(installation))
+
+	// Swapping the encrypted values of two outputs is detected
+	stored, err := backend.Read("app")
+	assert.NilError(t, err)
+	stored.Outputs["password"], stored.Outputs["username"] = stored.Outputs["username"], stored.Outputs["password"]
+	assert.NilError(t, backend.Store(stored))
+	_, err = encrypted.Read("app")
+	assert.Check(t, is.ErrorContains(err, "failed to decrypt output"))
+
+	// And so is moving them to another installation
+	stored.Name = "other"
+	assert.NilError(t, backend.Store(stored))
+	_, err = encrypted.Read("other")
+	assert.Check(t, is.ErrorContains(err, "failed to decrypt output"))
+}
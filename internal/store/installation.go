@@ -1,26 +1,70 @@
 package store
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/deislabs/cnab-go/claim"
 	"github.com/deislabs/cnab-go/utils/crud"
+	"github.com/docker/app/internal/cnab"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"github.com/pkg/errors"
 )
 
 // InstallationStore is an interface to persist, delete, list and read installations.
+// Every stored revision of an installation is kept until it is deleted.
 type InstallationStore interface {
 	List() ([]string, error)
 	Store(installation *Installation) error
 	Read(installationName string) (*Installation, error)
 	Delete(installationName string) error
+	// Revisions returns all the stored revisions of an installation, oldest first.
+	Revisions(installationName string) ([]*Installation, error)
+	// ReadRevision reads a given revision of an installation.
+	ReadRevision(installationName, revision string) (*Installation, error)
+	// Query returns a page of the installations selected by the query.
+	Query(q Query) (*QueryResult, error)
 }
 
 // Installation is a CNAB claim with an information of where the bundle comes from.
 // It persists the result of an installation and its parameters and context.
 type Installation struct {
 	claim.Claim
-	Reference string `json:"reference,omitempty"`
+	Reference string    `json:"reference,omitempty"`
+	Rollback  *Rollback `json:"rollback,omitempty"`
+	// Outputs are the values produced by the invocation image during the
+	// last action, keyed by output name.
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// EncryptedOutputs is set when the output values are encrypted, see
+	// EncryptedStore.
+	EncryptedOutputs bool `json:"encryptedOutputs,omitempty"`
+	// Attempts are the runs of the action of this revision, when it was
+	// retried or resumed.
+	Attempts []Attempt `json:"attempts,omitempty"`
+	// Labels are set by the user to organize the installations, like
+	// "team=payments", see Query.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Attempt is a run of the action of an installation revision.
+type Attempt struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Status   string    `json:"status"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// Rollback records that an installation revision restores a previous one.
+type Rollback struct {
+	// From is the revision which was reverted.
+	From string `json:"from"`
+	// To is the revision whose bundle and parameters were restored.
+	To string `json:"to"`
 }
 
 func NewInstallation(name string, reference string) (*Installation, error) {
@@ -34,22 +78,68 @@ func NewInstallation(name string, reference string) (*Installation, error) {
 	}, nil
 }
 
+// MarshalCanonical returns the canonical JSON encoding of the installation,
+// with sorted keys and no insignificant whitespace, suitable for hashing or
+// signing the record.
+func (i *Installation) MarshalCanonical() ([]byte, error) {
+	return canonicaljson.MarshalCanonical(i)
+}
+
 var _ InstallationStore = &installationStore{}
 
 type installationStore struct {
 	store crud.Store
+	// revisions holds every revision, keyed by installation name and revision
+	revisions crud.Store
+	// labels indexes the installations by label
+	labels labelIndex
 }
 
+// revisionSeparator separates the installation name from the revision in
+// the revisions keys. It is not allowed in installation names.
+const revisionSeparator = "@"
+
 func (i installationStore) List() ([]string, error) {
 	return i.store.List()
 }
 
 func (i installationStore) Store(installation *Installation) error {
+	if err := cnab.ValidateLabels(installation.Labels); err != nil {
+		return errors.Wrapf(err, "invalid labels of installation %q", installation.Name)
+	}
+	previous, err := i.storedLabels(installation.Name)
+	if err != nil {
+		return err
+	}
 	data, err := json.MarshalIndent(installation, "", "  ")
 	if err != nil {
 		return err
 	}
-	return i.store.Store(installation.Name, data)
+	if err := i.store.Store(installation.Name, data); err != nil {
+		return err
+	}
+	if err := i.revisions.Store(installation.Name+revisionSeparator+installation.Revision, data); err != nil {
+		return err
+	}
+	return i.labels.update(installation.Name, previous, installation.Labels)
+}
+
+// storedLabels returns the labels of the stored installation, if any.
+func (i installationStore) storedLabels(installationName string) (map[string]string, error) {
+	data, err := i.store.Read(installationName)
+	if err == crud.ErrFileDoesNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	return stored.Labels, nil
 }
 
 func (i installationStore) Read(installationName string) (*Installation, error) {
@@ -61,13 +151,119 @@ func (i installationStore) Read(installationName string) (*Installation, error)
 		}
 		return nil, err
 	}
+	return decodeInstallation(data)
+}
+
+func (i installationStore) Delete(installationName string) error {
+	labels, err := i.storedLabels(installationName)
+	if err != nil {
+		return err
+	}
+	if err := i.store.Delete(installationName); err != nil {
+		return err
+	}
+	if err := i.labels.update(installationName, labels, nil); err != nil {
+		return err
+	}
+	keys, err := i.revisionKeys(installationName)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := i.revisions.Delete(key); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query looks the labels of the query up in the index, if any, so that only
+// the installations having them are read.
+func (i installationStore) Query(q Query) (*QueryResult, error) {
+	var (
+		names []string
+		err   error
+	)
+	if len(q.Labels) > 0 {
+		names, err = i.labels.lookup(q.Labels)
+	} else {
+		names, err = i.store.List()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return queryInstallations(names, i.Read, q)
+}
+
+func (i installationStore) Revisions(installationName string) ([]*Installation, error) {
+	keys, err := i.revisionKeys(installationName)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []*Installation
+	for _, key := range keys {
+		installation, err := i.readRevision(key)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, installation)
+	}
+	sort.Slice(revisions, func(a, b int) bool {
+		if !revisions[a].Modified.Equal(revisions[b].Modified) {
+			return revisions[a].Modified.Before(revisions[b].Modified)
+		}
+		return revisions[a].Revision < revisions[b].Revision
+	})
+	return revisions, nil
+}
+
+func (i installationStore) ReadRevision(installationName, revision string) (*Installation, error) {
+	installation, err := i.readRevision(installationName + revisionSeparator + revision)
+	if err == crud.ErrFileDoesNotExist {
+		return nil, fmt.Errorf("Revision %q of installation %q not found", revision, installationName)
+	}
+	return installation, err
+}
+
+func (i installationStore) readRevision(key string) (*Installation, error) {
+	data, err := i.revisions.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeInstallation(data)
+}
+
+// decodeInstallation decodes a stored installation. The values of the
+// parameters are decoded as json.Number, as float64 cannot hold the integers
+// above 2^53, see cnab.NormalizeParameterValue. The bundle keeps the float64
+// numbers cnab-go expects.
+func decodeInstallation(data []byte) (*Installation, error) {
 	var installation Installation
 	if err := json.Unmarshal(data, &installation); err != nil {
 		return nil, err
 	}
+	var parameters struct {
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parameters); err != nil {
+		return nil, err
+	}
+	installation.Parameters = parameters.Parameters
 	return &installation, nil
 }
 
-func (i installationStore) Delete(installationName string) error {
-	return i.store.Delete(installationName)
+func (i installationStore) revisionKeys(installationName string) ([]string, error) {
+	keys, err := i.revisions.List()
+	if err != nil {
+		return nil, err
+	}
+	var matching []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, installationName+revisionSeparator) {
+			matching = append(matching, key)
+		}
+	}
+	return matching, nil
 }
@@ -1,11 +1,13 @@
 package store
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
 
 	"github.com/deislabs/cnab-go/claim"
 	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
 	"gotest.tools/fs"
 )
 
@@ -38,3 +40,87 @@ func TestStoreAndReadInstallation(t *testing.T) {
 	assert.NilError(t, err)
 	assert.DeepEqual(t, expectedInstallation, actualInstallation)
 }
+
+func TestInstallationLargeIntegers(t *testing.T) {
+	installationStore := NewMemoryInstallationStore()
+	installation := &Installation{Claim: claim.Claim{
+		Name:       "installation-name",
+		Revision:   "01DB",
+		Parameters: map[string]interface{}{"seed": 9007199254740993, "name": "app"},
+	}}
+	assert.NilError(t, installationStore.Store(installation))
+
+	// float64 would round the value to 9007199254740992
+	for _, read := range []func() (*Installation, error){
+		func() (*Installation, error) { return installationStore.Read("installation-name") },
+		func() (*Installation, error) { return installationStore.ReadRevision("installation-name", "01DB") },
+	} {
+		actual, err := read()
+		assert.NilError(t, err)
+		assert.Check(t, is.DeepEqual(actual.Parameters, map[string]interface{}{"seed": json.Number("9007199254740993"), "name": "app"}))
+	}
+}
+
+func TestInstallationRevisions(t *testing.T) {
+	dockerConfigDir := fs.NewDir(t, t.Name(), fs.WithMode(0755))
+	defer dockerConfigDir.Remove()
+	appstore, err := NewApplicationStore(dockerConfigDir.Path())
+	assert.NilError(t, err)
+	installationStore, err := appstore.InstallationStore("my-context")
+	assert.NilError(t, err)
+
+	installation, err := NewInstallation("installation-name", "mybundle:1.0.0")
+	assert.NilError(t, err)
+	installation.Update(claim.ActionInstall, claim.StatusSuccess)
+	assert.NilError(t, installationStore.Store(installation))
+	first := installation.Revision
+	installation.Reference = "mybundle:2.0.0"
+	installation.Update(claim.ActionUpgrade, claim.StatusSuccess)
+	assert.NilError(t, installationStore.Store(installation))
+	other, err := NewInstallation("other-installation", "other:1.0.0")
+	assert.NilError(t, err)
+	assert.NilError(t, installationStore.Store(other))
+
+	// Revisions are not listed as installations
+	names, err := installationStore.List()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"installation-name", "other-installation"})
+
+	revisions, err := installationStore.Revisions("installation-name")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(revisions, 2))
+	assert.Equal(t, revisions[0].Reference, "mybundle:1.0.0")
+	assert.Equal(t, revisions[1].Reference, "mybundle:2.0.0")
+
+	revision, err := installationStore.ReadRevision("installation-name", first)
+	assert.NilError(t, err)
+	assert.Equal(t, revision.Result.Action, claim.ActionInstall)
+	_, err = installationStore.ReadRevision("installation-name", "unknown")
+	assert.Check(t, is.ErrorContains(err, `Revision "unknown" of installation "installation-name" not found`))
+
+	// Deleting an installation deletes its revisions
+	assert.NilError(t, installationStore.Delete("installation-name"))
+	revisions, err = installationStore.Revisions("installation-name")
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(revisions, 0))
+	revisions, err = installationStore.Revisions("other-installation")
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(revisions, 1))
+}
+
+func TestInstallationMarshalCanonical(t *testing.T) {
+	installation, err := NewInstallation("installation-name", "mybundle:1.0.0")
+	assert.NilError(t, err)
+	installation.Outputs = map[string]string{"url": "http://localhost", "port": "8080"}
+	installation.Parameters = map[string]interface{}{"b": 1, "a": "x"}
+
+	data, err := installation.MarshalCanonical()
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(data), `"outputs":{"port":"8080","url":"http://localhost"}`))
+	assert.Check(t, is.Contains(string(data), `"parameters":{"a":"x","b":1}`))
+
+	// The encoding is stable
+	again, err := installation.MarshalCanonical()
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), string(again))
+}
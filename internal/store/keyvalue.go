@@ -0,0 +1,84 @@
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/deislabs/cnab-go/utils/crud"
+	"github.com/pkg/errors"
+)
+
+// ErrKeyNotFound is returned by key-value backends reading a missing key.
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyValueBackend is a generic key-value database, like etcd, consul or a
+// cloud key-value service, installations can be persisted into.
+type KeyValueBackend interface {
+	// Get returns the value of a key, or ErrKeyNotFound.
+	Get(key string) ([]byte, error)
+	// Put creates or replaces the value of a key.
+	Put(key string, value []byte) error
+	// Delete removes a key, or returns ErrKeyNotFound.
+	Delete(key string) error
+	// Keys returns all the keys starting with the given prefix.
+	Keys(prefix string) ([]string, error)
+}
+
+// NewKeyValueInstallationStore returns an installation store persisting the
+// installations into a key-value backend, under keys prefixed by the given
+// namespace, for instance a hash of the target context. It has the same
+// behavior as the file system backed store.
+func NewKeyValueInstallationStore(backend KeyValueBackend, namespace string) InstallationStore {
+	prefix := strings.TrimSuffix(namespace, "/") + "/"
+	return &installationStore{
+		store:     &keyValueStore{backend: backend, prefix: prefix + InstallationStoreDirectory + "/"},
+		revisions: &keyValueStore{backend: backend, prefix: prefix + InstallationRevisionsDirectory + "/"},
+		labels:    labelIndex{&keyValueStore{backend: backend, prefix: prefix + InstallationLabelsDirectory + "/"}},
+	}
+}
+
+var _ crud.Store = &keyValueStore{}
+
+// keyValueStore is a crud.Store on the keys of a backend sharing a prefix.
+type keyValueStore struct {
+	backend KeyValueBackend
+	prefix  string
+}
+
+func (s *keyValueStore) List() ([]string, error) {
+	keys, err := s.backend.Keys(s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, s.prefix)
+		// Keys of nested prefixes do not belong to this store
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *keyValueStore) Store(name string, data []byte) error {
+	return s.backend.Put(s.prefix+name, data)
+}
+
+func (s *keyValueStore) Read(name string) ([]byte, error) {
+	data, err := s.backend.Get(s.prefix + name)
+	if err == ErrKeyNotFound {
+		return nil, crud.ErrFileDoesNotExist
+	}
+	return data, err
+}
+
+func (s *keyValueStore) Delete(name string) error {
+	err := s.backend.Delete(s.prefix + name)
+	if err == ErrKeyNotFound {
+		return crud.ErrFileDoesNotExist
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/cnab-go/claim"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type mapBackend map[string][]byte
+
+func (m mapBackend) Get(key string) ([]byte, error) {
+	value, ok := m[key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (m mapBackend) Put(key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapBackend) Delete(key string) error {
+	if _, ok := m[key]; !ok {
+		return ErrKeyNotFound
+	}
+	delete(m, key)
+	return nil
+}
+
+func (m mapBackend) Keys(prefix string) ([]string, error) {
+	var keys []string
+	for key := range m {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func TestKeyValueInstallationStore(t *testing.T) {
+	backend := mapBackend{}
+	installationStore := NewKeyValueInstallationStore(backend, "my-context")
+	otherStore := NewKeyValueInstallationStore(backend, "other-context")
+
+	installation, err := NewInstallation("installation-name", "mybundle:1.0.0")
+	assert.NilError(t, err)
+	installation.Update(claim.ActionInstall, claim.StatusSuccess)
+	assert.NilError(t, installationStore.Store(installation))
+	installation.Reference = "mybundle:2.0.0"
+	installation.Update(claim.ActionUpgrade, claim.StatusSuccess)
+	assert.NilError(t, installationStore.Store(installation))
+	other, err := NewInstallation("other-installation", "other:1.0.0")
+	assert.NilError(t, err)
+	assert.NilError(t, otherStore.Store(other))
+
+	var keys []string
+	for key := range backend {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.Check(t, is.Len(keys, 5))
+	assert.Check(t, is.Equal(keys[0], "my-context/installations/installation-name"))
+
+	// Namespaces are isolated
+	names, err := installationStore.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(names, []string{"installation-name"}))
+
+	actual, err := installationStore.Read("installation-name")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(actual.Reference, "mybundle:2.0.0"))
+	_, err = otherStore.Read("installation-name")
+	assert.Check(t, is.ErrorContains(err, `Installation "installation-name" not found`))
+
+	revisions, err := installationStore.Revisions("installation-name")
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(revisions, 2))
+	assert.Check(t, is.Equal(revisions[0].Reference, "mybundle:1.0.0"))
+
+	assert.NilError(t, installationStore.Delete("installation-name"))
+	revisions, err = installationStore.Revisions("installation-name")
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(revisions, 0))
+	assert.Check(t, is.Len(backend, 2))
+}
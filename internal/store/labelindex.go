@@ -0,0 +1,131 @@
+package store
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"sort"
+
+	"github.com/deislabs/cnab-go/utils/crud"
+)
+
+// InstallationLabelsDirectory is the directory name, inside an installation
+// store, holding the index of the installation labels.
+const InstallationLabelsDirectory = "labels"
+
+// labelIndex lists the installations having each label, so that querying
+// installations by label does not read all of them. Each entry is keyed by
+// the escaped "key=value" label and holds the sorted installation names.
+type labelIndex struct {
+	store crud.Store
+}
+
+func labelIndexKey(key, value string) string {
+	return url.PathEscape(key + "=" + value)
+}
+
+// names returns the installations having the label.
+func (x labelIndex) names(key, value string) ([]string, error) {
+	data, err := x.store.Read(labelIndexKey(key, value))
+	if err == crud.ErrFileDoesNotExist || os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// update moves the installation from the entries of its old labels to the
+// ones of its new labels.
+func (x labelIndex) update(name string, old, new map[string]string) error {
+	for key, value := range old {
+		if v, ok := new[key]; ok && v == value {
+			continue
+		}
+		if err := x.edit(key, value, func(names []string) []string {
+			i := sort.SearchStrings(names, name)
+			if i < len(names) && names[i] == name {
+				names = append(names[:i], names[i+1:]...)
+			}
+			return names
+		}); err != nil {
+			return err
+		}
+	}
+	for key, value := range new {
+		if v, ok := old[key]; ok && v == value {
+			continue
+		}
+		if err := x.edit(key, value, func(names []string) []string {
+			i := sort.SearchStrings(names, name)
+			if i < len(names) && names[i] == name {
+				return names
+			}
+			names = append(names, "")
+			copy(names[i+1:], names[i:])
+			names[i] = name
+			return names
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x labelIndex) edit(key, value string, edit func(names []string) []string) error {
+	names, err := x.names(key, value)
+	if err != nil {
+		return err
+	}
+	names = edit(names)
+	if len(names) == 0 {
+		if err := x.store.Delete(labelIndexKey(key, value)); err != nil && err != crud.ErrFileDoesNotExist && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	return x.store.Store(labelIndexKey(key, value), data)
+}
+
+// lookup returns the installations having all the labels.
+func (x labelIndex) lookup(labels map[string]string) ([]string, error) {
+	var selected []string
+	first := true
+	for key, value := range labels {
+		names, err := x.names(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			selected, first = names, false
+			continue
+		}
+		selected = intersectSorted(selected, names)
+	}
+	return selected, nil
+}
+
+func intersectSorted(a, b []string) []string {
+	var result []string
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] == b[0]:
+			result = append(result, a[0])
+			a, b = a[1:], b[1:]
+		case a[0] < b[0]:
+			a = a[1:]
+		default:
+			b = b[1:]
+		}
+	}
+	return result
+}
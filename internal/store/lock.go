@@ -0,0 +1,207 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/pkg/errors"
+)
+
+// InstallationLocksDirectory is the directory name, inside an installation
+// store, holding the locks of the installations
+const InstallationLocksDirectory = "locks"
+
+// errLocked is returned by lockFile if another open file holds the lock.
+var errLocked = errors.New("file is locked")
+
+// LockInfo describes the holder of an installation lock.
+type LockInfo struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Action   string    `json:"action"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// LockedError is returned when an installation is locked by another process.
+type LockedError struct {
+	Installation string
+	Holder       LockInfo
+}
+
+func (e *LockedError) Error() string {
+	if e.Holder.PID == 0 {
+		return fmt.Sprintf("installation %q is locked by another process", e.Installation)
+	}
+	return fmt.Sprintf("installation %q is locked by process %d on %q running %q since %s",
+		e.Installation, e.Holder.PID, e.Holder.Hostname, e.Holder.Action, e.Holder.Acquired.Format(time.RFC3339))
+}
+
+// IsLocked returns true if the error is a LockedError.
+func IsLocked(err error) bool {
+	_, ok := errors.Cause(err).(*LockedError)
+	return ok
+}
+
+// InstallationLocker hands out advisory locks on installations, so two
+// processes cannot run modifying actions against the same installation at
+// the same time. Locks are files of the installation store locked with the
+// file locks of the operating system, which releases them when their holder
+// exits, so a crashed process never leaves a stale lock behind. They only
+// protect callers using them.
+type InstallationLocker struct {
+	path string
+	// hostname is replaceable for tests
+	hostname func() (string, error)
+}
+
+// InstallationLocker initializes and returns a context based installation
+// locker
+func (a ApplicationStore) InstallationLocker(context string) (*InstallationLocker, error) {
+	path := filepath.Join(a.path, InstallationStoreDirectory, makeDigestedDirectory(context), InstallationLocksDirectory)
+	return NewInstallationLocker(path)
+}
+
+// NewInstallationLocker returns a locker keeping its locks in the given
+// directory.
+func NewInstallationLocker(path string) (*InstallationLocker, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create installation locks directory %q", path)
+	}
+	return &InstallationLocker{
+		path:     path,
+		hostname: os.Hostname,
+	}, nil
+}
+
+// Lock is a held installation lock.
+type Lock struct {
+	file *os.File
+	info LockInfo
+}
+
+// Info returns the description of the lock.
+func (l *Lock) Info() LockInfo {
+	return l.info
+}
+
+// Unlock releases the lock. The lock file is emptied but kept, removing it
+// would let a process which opened it in the meantime lock a file nobody
+// else sees. Releasing a lock twice or one which was forcibly removed is not
+// an error.
+func (l *Lock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	f := l.file
+	l.file = nil
+	truncateErr := f.Truncate(0)
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to release installation lock")
+	}
+	return errors.Wrap(truncateErr, "failed to clear installation lock")
+}
+
+// Lock locks the installation for the given action. It fails with a
+// LockedError if another process holds the lock.
+func (l *InstallationLocker) Lock(installation, action string) (*Lock, error) {
+	path, err := l.lockPath(installation)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := l.hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get hostname")
+	}
+	info := LockInfo{
+		PID:      os.Getpid(),
+		Hostname: hostname,
+		Action:   action,
+		Acquired: time.Now().UTC(),
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to lock installation %q", installation)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close() //nolint:errcheck // the lock error is more relevant
+		if err != errLocked {
+			return nil, errors.Wrapf(err, "failed to lock installation %q", installation)
+		}
+		holder, err := l.Holder(installation)
+		if err != nil {
+			return nil, err
+		}
+		if holder == nil {
+			// The holder has not described itself yet
+			holder = &LockInfo{}
+		}
+		return nil, &LockedError{Installation: installation, Holder: *holder}
+	}
+	// The content left by a holder which did not release the lock is replaced
+	if err := f.Truncate(0); err == nil {
+		_, err = f.WriteAt(data, 0)
+	}
+	if err != nil {
+		f.Close() //nolint:errcheck // the write error is more relevant
+		return nil, errors.Wrapf(err, "failed to lock installation %q", installation)
+	}
+	return &Lock{file: f, info: info}, nil
+}
+
+// Holder returns the description of the last holder of the lock on an
+// installation, or nil if it was released. A process which exited without
+// releasing the lock is still described, although the lock is free.
+func (l *InstallationLocker) Holder(installation string) (*LockInfo, error) {
+	path, err := l.lockPath(installation)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read lock of installation %q", installation)
+	}
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		// The description is being written
+		return &LockInfo{}, nil
+	}
+	return &info, nil
+}
+
+// ForceUnlock removes the lock file of an installation whoever holds it, for
+// the file systems which do not release the locks of their dead holders.
+// The holder, if still running, keeps its lock on the removed file, so other
+// processes can lock the installation again. Unlocking an installation which
+// is not locked is not an error.
+func (l *InstallationLocker) ForceUnlock(installation string) error {
+	path, err := l.lockPath(installation)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to unlock installation %q", installation)
+	}
+	return nil
+}
+
+// lockPath returns the path of the lock file of an installation, rejecting
+// the names which are not valid installation names, as they could point
+// outside of the locks directory.
+func (l *InstallationLocker) lockPath(installation string) (string, error) {
+	if !claim.ValidName.MatchString(installation) {
+		return "", errors.Errorf("invalid installation name %q", installation)
+	}
+	return filepath.Join(l.path, installation+".lock"), nil
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+func newTestLocker(t *testing.T, dir *fs.Dir, hostname string) *InstallationLocker {
+	t.Helper()
+	locker, err := NewInstallationLocker(dir.Join("locks"))
+	assert.NilError(t, err)
+	locker.hostname = func() (string, error) { return hostname, nil }
+	return locker
+}
+
+func TestLockAndUnlock(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	locker := newTestLocker(t, dir, "host")
+
+	lock, err := locker.Lock("my-app", "upgrade")
+	assert.NilError(t, err)
+	assert.Equal(t, lock.Info().PID, os.Getpid())
+	assert.Equal(t, lock.Info().Action, "upgrade")
+
+	holder, err := locker.Holder("my-app")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(holder.Hostname, "host"))
+	assert.Check(t, is.Equal(holder.Action, "upgrade"))
+
+	// The lock is held through another open file
+	_, err = locker.Lock("my-app", "uninstall")
+	assert.Check(t, IsLocked(err))
+	assert.ErrorContains(t, err, `installation "my-app" is locked by process`)
+
+	// Other installations are not locked
+	other, err := locker.Lock("other-app", "install")
+	assert.NilError(t, err)
+	assert.NilError(t, other.Unlock())
+
+	assert.NilError(t, lock.Unlock())
+	holder, err = locker.Holder("my-app")
+	assert.NilError(t, err)
+	assert.Check(t, holder == nil)
+	lock, err = locker.Lock("my-app", "uninstall")
+	assert.NilError(t, err)
+	assert.NilError(t, lock.Unlock())
+}
+
+func TestLockReplacesReleasedLocks(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	locker := newTestLocker(t, dir, "host")
+
+	// A holder which exited without releasing the lock leaves its
+	// description, but not the file lock.
+	data, err := json.Marshal(LockInfo{PID: 1, Hostname: "remote", Action: "upgrade"})
+	assert.NilError(t, err)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir.Join("locks"), "my-app.lock"), data, 0644))
+	holder, err := locker.Holder("my-app")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(holder.Hostname, "remote"))
+
+	lock, err := locker.Lock("my-app", "uninstall")
+	assert.NilError(t, err)
+	holder, err = locker.Holder("my-app")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(holder.Hostname, "host"))
+	assert.Check(t, is.Equal(holder.Action, "uninstall"))
+	assert.NilError(t, lock.Unlock())
+	// Releasing twice is fine
+	assert.NilError(t, lock.Unlock())
+}
+
+func TestLockConcurrently(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+
+	const contenders = 8
+	var (
+		holders  int32
+		overlaps int32
+		acquired int32
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < contenders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			locker := newTestLocker(t, dir, "host")
+			for j := 0; j < 50; j++ {
+				lock, err := locker.Lock("my-app", "upgrade")
+				if IsLocked(err) {
+					continue
+				}
+				assert.Check(t, err)
+				if err != nil {
+					return
+				}
+				if atomic.AddInt32(&holders, 1) > 1 {
+					atomic.AddInt32(&overlaps, 1)
+				}
+				atomic.AddInt32(&acquired, 1)
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&holders, -1)
+				assert.Check(t, lock.Unlock())
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Check(t, is.Equal(overlaps, int32(0)))
+	assert.Check(t, acquired > 0)
+}
+
+func TestForceUnlock(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	locker := newTestLocker(t, dir, "host")
+
+	lock, err := locker.Lock("my-app", "upgrade")
+	assert.NilError(t, err)
+	assert.NilError(t, locker.ForceUnlock("my-app"))
+	// The installation can be locked again while the previous holder runs
+	other, err := locker.Lock("my-app", "uninstall")
+	assert.NilError(t, err)
+	assert.NilError(t, other.Unlock())
+	// Unlocking a removed lock or a missing one is fine
+	assert.NilError(t, lock.Unlock())
+	assert.NilError(t, locker.ForceUnlock("my-app"))
+}
+
+func TestLockRejectsInvalidNames(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("outside.lock", ""))
+	defer dir.Remove()
+	locker := newTestLocker(t, dir, "host")
+
+	_, err := locker.Lock("../outside", "upgrade")
+	assert.Check(t, is.ErrorContains(err, `invalid installation name "../outside"`))
+	_, err = locker.Holder("../outside")
+	assert.Check(t, is.ErrorContains(err, `invalid installation name "../outside"`))
+	err = locker.ForceUnlock("../outside")
+	assert.Check(t, is.ErrorContains(err, `invalid installation name "../outside"`))
+	_, err = os.Stat(dir.Join("outside.lock"))
+	assert.Check(t, err)
+}
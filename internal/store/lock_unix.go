@@ -0,0 +1,24 @@
+// +build !windows
+
+package store
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on the file without waiting, failing with
+// errLocked if another open file holds it.
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLocked
+	}
+	return err
+}
+
+// waitLockFile takes an exclusive lock on the file, waiting for any other
+// open file holding it to release it.
+func waitLockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// lockFile takes an exclusive lock on the file without waiting, failing with
+// errLocked if another open file holds it. The locked range starts past any
+// content, as a locked range cannot be read by other processes.
+func lockFile(f *os.File) error {
+	var overlapped syscall.Overlapped
+	overlapped.OffsetHigh = 0x7fffffff
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return errLocked
+	}
+	return err
+}
+
+// waitLockFile takes an exclusive lock on the file, waiting for any other
+// open file holding it to release it.
+func waitLockFile(f *os.File) error {
+	var overlapped syscall.Overlapped
+	overlapped.OffsetHigh = 0x7fffffff
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return nil
+	}
+	return err
+}
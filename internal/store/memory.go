@@ -0,0 +1,74 @@
+package store
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/deislabs/cnab-go/utils/crud"
+)
+
+// NewMemoryInstallationStore returns an installation store keeping the
+// installations in memory. It behaves like the file system backed store and
+// is safe for concurrent use, which makes it suitable for tests and throwaway
+// executions which must not leave any state behind.
+func NewMemoryInstallationStore() InstallationStore {
+	return &installationStore{store: newMemoryStore(), revisions: newMemoryStore(), labels: labelIndex{newMemoryStore()}}
+}
+
+// NewMemoryStore returns a crud.Store keeping the entries in memory, for
+// instance to back a cnab-go claim store in tests.
+func NewMemoryStore() crud.Store {
+	return newMemoryStore()
+}
+
+var _ crud.Store = &memoryStore{}
+
+// memoryStore is a crud.Store holding serialized entries in a map, so
+// callers never share data with the store.
+type memoryStore struct {
+	mu      sync.RWMutex
+	entries map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: map[string][]byte{}}
+}
+
+func (m *memoryStore) List() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.entries))
+	for name := range m.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *memoryStore) Store(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memoryStore) Read(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.entries[name]
+	if !ok {
+		return nil, crud.ErrFileDoesNotExist
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *memoryStore) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(m.entries, name)
+	return nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestMemoryInstallationStore(t *testing.T) {
+	installationStore := NewMemoryInstallationStore()
+
+	installation, err := NewInstallation("installation-name", "mybundle:mytag")
+	assert.NilError(t, err)
+	assert.NilError(t, installationStore.Store(installation))
+
+	// Stored installations are copies
+	installation.Reference = "changed"
+	actual, err := installationStore.Read("installation-name")
+	assert.NilError(t, err)
+	assert.Equal(t, actual.Reference, "mybundle:mytag")
+	assert.Equal(t, actual.Revision, installation.Revision)
+
+	_, err = installationStore.Read("unknown")
+	assert.Check(t, is.Error(err, `Installation "unknown" not found`))
+
+	assert.NilError(t, installationStore.Delete("installation-name"))
+	err = installationStore.Delete("installation-name")
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestMemoryInstallationStoreConcurrency(t *testing.T) {
+	installationStore := NewMemoryInstallationStore()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			installation, err := NewInstallation(fmt.Sprintf("installation-%02d", i), "")
+			assert.Check(t, err)
+			assert.Check(t, installationStore.Store(installation))
+			_, err = installationStore.List()
+			assert.Check(t, err)
+		}(i)
+	}
+	wg.Wait()
+	names, err := installationStore.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(names, 20))
+	assert.Check(t, is.Equal(names[0], "installation-00"))
+}
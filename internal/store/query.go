@@ -0,0 +1,117 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"github.com/docker/app/internal/semver"
+	"github.com/pkg/errors"
+)
+
+// Query selects installations by the bundle they run, their labels, the
+// status of their last action and when it happened. The zero value selects
+// all the installations.
+type Query struct {
+	// BundleName selects the installations of the named bundle.
+	BundleName string
+	// BundleVersion is a version range, like "<2.0.0", the version of the
+	// bundle of the installations must be in.
+	BundleVersion string
+	// Labels selects the installations having all these labels.
+	Labels map[string]string
+	// Status selects the installations whose last action has this status.
+	Status string
+	// ModifiedAfter and ModifiedBefore select the installations modified
+	// within this time range, if set.
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// Limit is the maximum number of installations returned at once, all of
+	// them if zero.
+	Limit int
+	// Continue is the token of the page to return, from the previous
+	// QueryResult.
+	Continue string
+}
+
+// QueryResult is a page of the installations selected by a query, sorted
+// by name.
+type QueryResult struct {
+	Installations []*Installation
+	// Continue is the token of the next page, empty on the last page.
+	Continue string
+}
+
+// matcher returns a function checking whether an installation is selected
+// by the query.
+func (q Query) matcher() (func(*Installation) bool, error) {
+	var versions semver.Range
+	if q.BundleVersion != "" {
+		r, err := semver.ParseRange(q.BundleVersion)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid bundle version range")
+		}
+		versions = r
+	}
+	return func(installation *Installation) bool {
+		if q.BundleName != "" && (installation.Bundle == nil || installation.Bundle.Name != q.BundleName) {
+			return false
+		}
+		if q.BundleVersion != "" {
+			if installation.Bundle == nil {
+				return false
+			}
+			v, err := semver.Parse(installation.Bundle.Version)
+			if err != nil || !versions.Contains(v, true) {
+				return false
+			}
+		}
+		for key, value := range q.Labels {
+			if actual, ok := installation.Labels[key]; !ok || actual != value {
+				return false
+			}
+		}
+		if q.Status != "" && installation.Result.Status != q.Status {
+			return false
+		}
+		if !q.ModifiedAfter.IsZero() && !installation.Modified.After(q.ModifiedAfter) {
+			return false
+		}
+		if !q.ModifiedBefore.IsZero() && !installation.Modified.Before(q.ModifiedBefore) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// queryInstallations runs the query on the named installations, reading
+// them one at a time so that only the selected page is kept in memory.
+func queryInstallations(names []string, read func(name string) (*Installation, error), q Query) (*QueryResult, error) {
+	if q.Limit < 0 {
+		return nil, errors.Errorf("invalid query limit %d", q.Limit)
+	}
+	match, err := q.matcher()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	start := sort.SearchStrings(names, q.Continue)
+	if q.Continue != "" && start < len(names) && names[start] == q.Continue {
+		start++
+	}
+	result := &QueryResult{}
+	for _, name := range names[start:] {
+		installation, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if !match(installation) {
+			continue
+		}
+		if q.Limit > 0 && len(result.Installations) == q.Limit {
+			result.Continue = result.Installations[q.Limit-1].Name
+			break
+		}
+		result.Installations = append(result.Installations, installation)
+	}
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/fs"
+)
+
+var queryEpoch = time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func storeQueryFixtures(t *testing.T, s InstallationStore) {
+	t.Helper()
+	fixtures := []struct {
+		name, bundle, version, status string
+		labels                        map[string]string
+		days                          int
+	}{
+		{"billing", "payments", "1.2.0", claim.StatusSuccess, map[string]string{"team": "payments", "env": "prod"}, 1},
+		{"billing-staging", "payments", "2.0.0", claim.StatusSuccess, map[string]string{"team": "payments", "env": "staging"}, 2},
+		{"invoices", "payments", "1.0.0", claim.StatusFailure, map[string]string{"team": "payments", "env": "prod"}, 3},
+		{"wiki", "wiki", "3.1.0", claim.StatusSuccess, nil, 4},
+		{"legacy", "payments", "not-a-version", claim.StatusSuccess, nil, 5},
+	}
+	for _, f := range fixtures {
+		installation, err := NewInstallation(f.name, f.bundle+":"+f.version)
+		assert.NilError(t, err)
+		installation.Bundle = &bundle.Bundle{Name: f.bundle, Version: f.version}
+		installation.Labels = f.labels
+		installation.Update(claim.ActionInstall, f.status)
+		installation.Modified = queryEpoch.AddDate(0, 0, f.days)
+		assert.NilError(t, s.Store(installation))
+	}
+}
+
+func queryNames(t *testing.T, s InstallationStore, q Query) ([]string, string) {
+	t.Helper()
+	result, err := s.Query(q)
+	assert.NilError(t, err)
+	var names []string
+	for _, installation := range result.Installations {
+		names = append(names, installation.Name)
+	}
+	return names, result.Continue
+}
+
+func TestQuery(t *testing.T) {
+	keys, err := NewAESKeyProvider(bytes.Repeat([]byte{1}, 32))
+	assert.NilError(t, err)
+	stores := map[string]InstallationStore{
+		"memory":    NewMemoryInstallationStore(),
+		"key-value": NewKeyValueInstallationStore(mapBackend{}, "my-context"),
+		"encrypted": NewEncryptedStore(NewMemoryInstallationStore(), keys),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			storeQueryFixtures(t, s)
+			testCases := []struct {
+				name     string
+				query    Query
+				expected []string
+			}{
+				{"all", Query{}, []string{"billing", "billing-staging", "invoices", "legacy", "wiki"}},
+				{"bundle", Query{BundleName: "payments"}, []string{"billing", "billing-staging", "invoices", "legacy"}},
+				{"older than", Query{BundleName: "payments", BundleVersion: "<2.0.0"}, []string{"billing", "invoices"}},
+				{"labels", Query{Labels: map[string]string{"team": "payments", "env": "prod"}}, []string{"billing", "invoices"}},
+				{"status", Query{Status: claim.StatusFailure}, []string{"invoices"}},
+				{"modified", Query{ModifiedAfter: queryEpoch.AddDate(0, 0, 2), ModifiedBefore: queryEpoch.AddDate(0, 0, 5)}, []string{"invoices", "wiki"}},
+			}
+			for _, tc := range testCases {
+				names, next := queryNames(t, s, tc.query)
+				assert.Check(t, is.DeepEqual(names, tc.expected), tc.name)
+				assert.Check(t, is.Equal(next, ""), tc.name)
+			}
+		})
+	}
+}
+
+func TestQueryPagination(t *testing.T) {
+	s := NewMemoryInstallationStore()
+	storeQueryFixtures(t, s)
+
+	var pages [][]string
+	q := Query{BundleName: "payments", Limit: 2}
+	for {
+		names, next := queryNames(t, s, q)
+		pages = append(pages, names)
+		if next == "" {
+			break
+		}
+		q.Continue = next
+	}
+	assert.Check(t, is.DeepEqual(pages, [][]string{{"billing", "billing-staging"}, {"invoices", "legacy"}}))
+
+	names, next := queryNames(t, s, Query{Limit: 4})
+	assert.Check(t, is.Len(names, 4))
+	assert.Check(t, is.Equal(next, "legacy"))
+	names, next = queryNames(t, s, Query{Limit: 4, Continue: next})
+	assert.Check(t, is.DeepEqual(names, []string{"wiki"}))
+	assert.Check(t, is.Equal(next, ""))
+}
+
+func TestQueryErrors(t *testing.T) {
+	s := NewMemoryInstallationStore()
+	_, err := s.Query(Query{BundleVersion: "<<2"})
+	assert.Check(t, is.ErrorContains(err, "invalid bundle version range"))
+	_, err = s.Query(Query{Limit: -1})
+	assert.Check(t, is.Error(err, "invalid query limit -1"))
+}
+
+func TestQueryLabelIndex(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	defer dir.Remove()
+	appstore, err := NewApplicationStore(dir.Path())
+	assert.NilError(t, err)
+	s, err := appstore.InstallationStore("my-context")
+	assert.NilError(t, err)
+	storeQueryFixtures(t, s)
+
+	names, _ := queryNames(t, s, Query{Labels: map[string]string{"env": "prod"}})
+	assert.Check(t, is.DeepEqual(names, []string{"billing", "invoices"}))
+
+	// Relabeling and deleting installations updates the index
+	billing, err := s.Read("billing")
+	assert.NilError(t, err)
+	billing.Labels = map[string]string{"team": "payments", "env": "staging"}
+	assert.NilError(t, s.Store(billing))
+	assert.NilError(t, s.Delete("invoices"))
+	names, _ = queryNames(t, s, Query{Labels: map[string]string{"env": "prod"}})
+	assert.Check(t, is.Len(names, 0))
+	names, _ = queryNames(t, s, Query{Labels: map[string]string{"env": "staging", "team": "payments"}})
+	assert.Check(t, is.DeepEqual(names, []string{"billing", "billing-staging"}))
+
+	// Label keys with a prefix are escaped in the index
+	billing.Labels = map[string]string{"example.com/tier": "back-end"}
+	assert.NilError(t, s.Store(billing))
+	names, _ = queryNames(t, s, Query{Labels: map[string]string{"example.com/tier": "back-end"}})
+	assert.Check(t, is.DeepEqual(names, []string{"billing"}))
+
+	// The installations are not listed with the index entries
+	all, err := s.List()
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(all, 4))
+}
+
+func TestStoreRejectsInvalidLabels(t *testing.T) {
+	s := NewMemoryInstallationStore()
+	installation, err := NewInstallation("app", "")
+	assert.NilError(t, err)
+	installation.Labels = map[string]string{"team name": "payments"}
+	err = s.Store(installation)
+	assert.Check(t, is.ErrorContains(err, `invalid labels of installation "app": invalid label key "team name"`))
+}
@@ -0,0 +1,255 @@
+// Package verification checks the SLSA provenance attestations of a bundle
+// and of its invocation images against a policy, before the bundle is run.
+package verification
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/app/internal/signature"
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// PredicateSLSAProvenance is the predicate type of SLSA provenance
+// attestations.
+const PredicateSLSAProvenance = "https://slsa.dev/provenance/v0.2"
+
+// AttestationSource returns the attestations of an image whose signature
+// has been verified, as DSSE envelopes. signature.Cosign is an
+// AttestationSource.
+type AttestationSource interface {
+	Attestations(ctx context.Context, image, predicateType string) ([][]byte, error)
+}
+
+var _ AttestationSource = &signature.Cosign{}
+
+// Statement is an in-toto attestation statement.
+type Statement struct {
+	Type          string          `json:"_type"`
+	PredicateType string          `json:"predicateType"`
+	Subject       []Subject       `json:"subject"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// Subject is an artifact an attestation is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is the part of a SLSA provenance predicate checked by policies.
+type Provenance struct {
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	BuildType  string `json:"buildType"`
+	Invocation struct {
+		ConfigSource struct {
+			URI string `json:"uri"`
+		} `json:"configSource"`
+	} `json:"invocation"`
+}
+
+// Policy lists what the provenance of the bundle and of its invocation
+// images must match. Empty lists accept anything, so the zero policy only
+// requires a provenance attestation.
+type Policy struct {
+	// Builders are the trusted builder IDs.
+	Builders []string `json:"builders,omitempty"`
+	// SourceRepositories are the prefixes of the trusted source URIs, like
+	// "git+https://github.com/docker/".
+	SourceRepositories []string `json:"sourceRepositories,omitempty"`
+}
+
+// Result is the verification result of an artifact.
+type Result struct {
+	// Subject is the reference of the bundle or invocation image.
+	Subject string `json:"subject"`
+	// Builder and Source are read from the accepted provenance, if any.
+	Builder    string   `json:"builder,omitempty"`
+	Source     string   `json:"source,omitempty"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+// Report lists the verification results of the bundle and of its
+// invocation images.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Passed returns true if every artifact has an accepted provenance.
+func (r *Report) Passed() bool {
+	for _, result := range r.Results {
+		if len(result.Violations) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns an error listing the violations, or nil if the verification
+// passed.
+func (r *Report) Err() error {
+	var msgs []string
+	for _, result := range r.Results {
+		for _, violation := range result.Violations {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", result.Subject, violation))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.Errorf("provenance verification failed:\n%s", strings.Join(msgs, "\n"))
+}
+
+// Verify checks the provenance of the bundle pulled from ref, and of its
+// invocation images, which must be pinned by digest. The ref is nil for a
+// bundle read from a file, in which case only the invocation images are
+// checked. An error is returned when the verification cannot run, like an
+// offline.ErrOfflineMode of the source, while violations are reported.
+func Verify(ctx context.Context, source AttestationSource, policy Policy, ref reference.Named, b *bundle.Bundle) (*Report, error) {
+	report := &Report{}
+	if ref != nil {
+		var dgst digest.Digest
+		if canonical, ok := ref.(reference.Canonical); ok {
+			dgst = canonical.Digest()
+		}
+		result, err := verify(ctx, source, policy, ref.String(), reference.TrimNamed(ref).String(), dgst)
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, result)
+	}
+	for _, image := range b.InvocationImages {
+		pinned, err := signature.PinnedImage(image.BaseImage)
+		if err != nil {
+			return nil, err
+		}
+		named, err := reference.ParseNormalizedNamed(pinned)
+		if err != nil {
+			return nil, err
+		}
+		result, err := verify(ctx, source, policy, pinned, reference.TrimNamed(named).String(), named.(reference.Canonical).Digest())
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// verify checks the attestations of an artifact, accepting the first one
+// about the artifact matching the policy. Without digest, the artifact is
+// matched by name. Only the offline errors of the source are returned, the
+// other ones are violations.
+func verify(ctx context.Context, source AttestationSource, policy Policy, subject, name string, dgst digest.Digest) (Result, error) {
+	result := Result{Subject: subject}
+	envelopes, err := source.Attestations(ctx, subject, PredicateSLSAProvenance)
+	if offline.IsOffline(err) {
+		return result, err
+	}
+	if err != nil {
+		result.Violations = []string{fmt.Sprintf("no verified attestation: %s", err)}
+		return result, nil
+	}
+	var violations []string
+	for _, envelope := range envelopes {
+		statement, err := decodeStatement(envelope)
+		if err != nil {
+			violations = append(violations, err.Error())
+			continue
+		}
+		if statement.PredicateType != PredicateSLSAProvenance || !statement.about(name, dgst) {
+			continue
+		}
+		var provenance Provenance
+		if err := json.Unmarshal(statement.Predicate, &provenance); err != nil {
+			violations = append(violations, fmt.Sprintf("invalid provenance: %s", err))
+			continue
+		}
+		if v := policy.check(provenance); len(v) > 0 {
+			violations = append(violations, v...)
+			continue
+		}
+		result.Builder = provenance.Builder.ID
+		result.Source = provenance.Invocation.ConfigSource.URI
+		return result, nil
+	}
+	if len(violations) == 0 {
+		violations = []string{"no provenance attestation"}
+	}
+	result.Violations = violations
+	return result, nil
+}
+
+// decodeStatement decodes the in-toto statement of a DSSE envelope.
+func decodeStatement(envelope []byte) (*Statement, error) {
+	var dsse struct {
+		PayloadType string `json:"payloadType"`
+		Payload     string `json:"payload"`
+	}
+	if err := json.Unmarshal(envelope, &dsse); err != nil {
+		return nil, errors.Wrap(err, "invalid attestation envelope")
+	}
+	payload, err := base64.StdEncoding.DecodeString(dsse.Payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid attestation payload")
+	}
+	var statement Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, errors.Wrap(err, "invalid attestation statement")
+	}
+	return &statement, nil
+}
+
+func (s *Statement) about(name string, dgst digest.Digest) bool {
+	for _, subject := range s.Subject {
+		if dgst != "" {
+			if subject.Digest[dgst.Algorithm().String()] == dgst.Hex() {
+				return true
+			}
+			continue
+		}
+		if named, err := reference.ParseNormalizedNamed(subject.Name); err == nil && reference.TrimNamed(named).String() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) check(provenance Provenance) []string {
+	var violations []string
+	if len(p.Builders) > 0 && !contains(p.Builders, provenance.Builder.ID) {
+		violations = append(violations, fmt.Sprintf("untrusted builder %q", provenance.Builder.ID))
+	}
+	if len(p.SourceRepositories) > 0 {
+		uri := provenance.Invocation.ConfigSource.URI
+		trusted := false
+		for _, prefix := range p.SourceRepositories {
+			if strings.HasPrefix(uri, prefix) {
+				trusted = true
+				break
+			}
+		}
+		if !trusted {
+			violations = append(violations, fmt.Sprintf("untrusted source %q", uri))
+		}
+	}
+	return violations
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package verification
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/offline"
+	"github.com/docker/distribution/reference"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+const (
+	testHex    = "0123456789012345678901234567890123456789012345678901234567890123"
+	testDigest = "sha256:" + testHex
+)
+
+// fakeSource serves attestations by image.
+type fakeSource map[string][][]byte
+
+func (s fakeSource) Attestations(_ context.Context, image, _ string) ([][]byte, error) {
+	envelopes, ok := s[image]
+	if !ok {
+		return nil, errors.New("no matching attestations")
+	}
+	return envelopes, nil
+}
+
+func envelope(t *testing.T, subject Subject, builder, source string) []byte {
+	t.Helper()
+	var provenance Provenance
+	provenance.Builder.ID = builder
+	provenance.Invocation.ConfigSource.URI = source
+	predicate, err := json.Marshal(provenance)
+	assert.NilError(t, err)
+	payload, err := json.Marshal(Statement{
+		Type:          "https://in-toto.io/Statement/v0.1",
+		PredicateType: PredicateSLSAProvenance,
+		Subject:       []Subject{subject},
+		Predicate:     predicate,
+	})
+	assert.NilError(t, err)
+	data, err := json.Marshal(map[string]string{
+		"payloadType": "application/vnd.in-toto+json",
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+	})
+	assert.NilError(t, err)
+	return data
+}
+
+func testBundle() *bundle.Bundle {
+	return &bundle.Bundle{InvocationImages: []bundle.InvocationImage{
+		{BaseImage: bundle.BaseImage{Image: "example.com/app-installer:0.1.0", Digest: testDigest}},
+	}}
+}
+
+var policy = Policy{
+	Builders:           []string{"https://github.com/actions/runner"},
+	SourceRepositories: []string{"git+https://github.com/docker/"},
+}
+
+func TestVerify(t *testing.T) {
+	ref, err := reference.ParseNormalizedNamed("example.com/app:0.1.0")
+	assert.NilError(t, err)
+	source := fakeSource{
+		"example.com/app:0.1.0": {envelope(t, Subject{Name: "example.com/app"}, "https://github.com/actions/runner", "git+https://github.com/docker/app@refs/tags/v0.1.0")},
+		"example.com/app-installer@" + testDigest: {
+			envelope(t, Subject{Name: "example.com/other", Digest: map[string]string{"sha256": "abc"}}, "https://evil.example.com", ""),
+			envelope(t, Subject{Name: "example.com/app-installer", Digest: map[string]string{"sha256": testHex}}, "https://github.com/actions/runner", "git+https://github.com/docker/app"),
+		},
+	}
+
+	report, err := Verify(context.Background(), source, policy, ref, testBundle())
+	assert.NilError(t, err)
+	assert.Check(t, report.Passed())
+	assert.NilError(t, report.Err())
+	assert.Check(t, is.Len(report.Results, 2))
+	assert.Check(t, is.Equal(report.Results[1].Source, "git+https://github.com/docker/app"))
+}
+
+func TestVerifyViolations(t *testing.T) {
+	source := fakeSource{
+		"example.com/app-installer@" + testDigest: {
+			envelope(t, Subject{Name: "example.com/app-installer", Digest: map[string]string{"sha256": testHex}}, "https://evil.example.com", "git+https://evil.example.com/app"),
+		},
+	}
+	report, err := Verify(context.Background(), source, policy, nil, testBundle())
+	assert.NilError(t, err)
+	assert.Check(t, !report.Passed())
+	assert.Check(t, is.Error(report.Err(), `provenance verification failed:
+example.com/app-installer@`+testDigest+`: untrusted builder "https://evil.example.com"
+example.com/app-installer@`+testDigest+`: untrusted source "git+https://evil.example.com/app"`))
+
+	report, err = Verify(context.Background(), fakeSource{}, Policy{}, nil, testBundle())
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(report.Results[0].Violations, []string{"no verified attestation: no matching attestations"}))
+
+	b := testBundle()
+	b.InvocationImages[0].Digest = ""
+	_, err = Verify(context.Background(), source, policy, nil, b)
+	assert.Check(t, is.ErrorContains(err, "is not pinned by digest"))
+}
+
+// offlineSource fails as cosign does in offline mode.
+type offlineSource struct{}
+
+func (offlineSource) Attestations(_ context.Context, image, _ string) ([][]byte, error) {
+	return nil, offline.New("fetching the attestations of %q", image)
+}
+
+func TestVerifyOffline(t *testing.T) {
+	_, err := Verify(context.Background(), offlineSource{}, policy, nil, testBundle())
+	assert.Check(t, offline.IsOffline(err))
+}
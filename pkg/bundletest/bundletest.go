@@ -0,0 +1,150 @@
+// Package bundletest provides builders and fixtures of bundles for tests,
+// along with the fakes needed to run their actions: a driver, claim and
+// installation stores and credentials.
+package bundletest
+
+import (
+	"fmt"
+
+	"github.com/deislabs/cnab-go/bundle"
+)
+
+// Option customizes a test bundle.
+type Option func(b *bundle.Bundle)
+
+// NewTestBundle returns a valid bundle, with a single docker invocation
+// image, customized by the given options.
+func NewTestBundle(opts ...Option) *bundle.Bundle {
+	b := &bundle.Bundle{
+		Name:        "test-bundle",
+		Version:     "0.1.0",
+		Description: "A bundle for tests",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "test/test-bundle-invoc:0.1.0"}},
+		},
+		Images:      map[string]bundle.Image{},
+		Parameters:  map[string]bundle.ParameterDefinition{},
+		Credentials: map[string]bundle.Location{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// WithName sets the name of the bundle.
+func WithName(name string) Option {
+	return func(b *bundle.Bundle) {
+		b.Name = name
+	}
+}
+
+// WithVersion sets the version of the bundle.
+func WithVersion(version string) Option {
+	return func(b *bundle.Bundle) {
+		b.Version = version
+	}
+}
+
+// WithParameters adds n string parameters, named "param-0" to "param-<n-1>",
+// with a default value and injected as environment variables.
+func WithParameters(n int) Option {
+	return func(b *bundle.Bundle) {
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("param-%d", i)
+			b.Parameters[name] = bundle.ParameterDefinition{
+				DataType: "string",
+				Default:  fmt.Sprintf("default-%d", i),
+				Destination: &bundle.Location{
+					EnvironmentVariable: fmt.Sprintf("PARAM_%d", i),
+				},
+			}
+		}
+	}
+}
+
+// WithParameter adds a single parameter.
+func WithParameter(name string, def bundle.ParameterDefinition) Option {
+	return func(b *bundle.Bundle) {
+		b.Parameters[name] = def
+	}
+}
+
+// WithImages adds n component images, named "image-0" to "image-<n-1>".
+func WithImages(n int) Option {
+	return func(b *bundle.Bundle) {
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("image-%d", i)
+			b.Images[name] = bundle.Image{
+				BaseImage: bundle.BaseImage{
+					ImageType: "docker",
+					Image:     fmt.Sprintf("test/%s:0.1.0", name),
+				},
+				Description: name,
+			}
+		}
+	}
+}
+
+// WithCredentials adds n credentials, named "cred-0" to "cred-<n-1>",
+// injected as files.
+func WithCredentials(n int) Option {
+	return func(b *bundle.Bundle) {
+		for i := 0; i < n; i++ {
+			b.Credentials[fmt.Sprintf("cred-%d", i)] = bundle.Location{
+				Path: fmt.Sprintf("/cnab/app/cred-%d", i),
+			}
+		}
+	}
+}
+
+// WithAction adds a custom action.
+func WithAction(name string, action bundle.Action) Option {
+	return func(b *bundle.Bundle) {
+		if b.Actions == nil {
+			b.Actions = map[string]bundle.Action{}
+		}
+		b.Actions[name] = action
+	}
+}
+
+// WithCustom sets a custom extension.
+func WithCustom(name string, value interface{}) Option {
+	return func(b *bundle.Bundle) {
+		if b.Custom == nil {
+			b.Custom = map[string]interface{}{}
+		}
+		b.Custom[name] = value
+	}
+}
+
+// WithoutInvocationImages makes the bundle invalid by removing all its
+// invocation images.
+func WithoutInvocationImages() Option {
+	return func(b *bundle.Bundle) {
+		b.InvocationImages = nil
+	}
+}
+
+// WithLatestVersion makes the bundle invalid by using "latest" as version.
+func WithLatestVersion() Option {
+	return WithVersion("latest")
+}
+
+// WithUntaggedInvocationImage makes the bundle invalid by removing the tag of
+// its invocation image.
+func WithUntaggedInvocationImage() Option {
+	return func(b *bundle.Bundle) {
+		b.InvocationImages[0].Image = "test/test-bundle-invoc"
+	}
+}
+
+// InvalidBundles returns a set of bundles which are invalid by construction,
+// keyed by a description of what makes them invalid.
+func InvalidBundles() map[string]*bundle.Bundle {
+	return map[string]*bundle.Bundle{
+		"no invocation image":       NewTestBundle(WithoutInvocationImages()),
+		"latest version":            NewTestBundle(WithLatestVersion()),
+		"untagged invocation image": NewTestBundle(WithUntaggedInvocationImage()),
+	}
+}
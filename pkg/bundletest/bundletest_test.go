@@ -0,0 +1,52 @@
+package bundletest
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/deislabs/cnab-go/action"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestNewTestBundle(t *testing.T) {
+	b := NewTestBundle(WithName("myapp"), WithParameters(3), WithImages(2), WithCredentials(1))
+	assert.NilError(t, b.Validate())
+	assert.Equal(t, b.Name, "myapp")
+	assert.Check(t, is.Len(b.Parameters, 3))
+	assert.Check(t, is.Len(b.Images, 2))
+	assert.Check(t, is.Len(b.Credentials, 1))
+}
+
+func TestInvalidBundles(t *testing.T) {
+	for name, b := range InvalidBundles() {
+		assert.Check(t, b.Validate() != nil, name)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	assert.NilError(t, Minimal().Validate())
+	assert.NilError(t, Maximal().Validate())
+	assert.Check(t, RoundTripStable(Maximal()))
+}
+
+func TestFakes(t *testing.T) {
+	claims := NewClaimStore()
+	c, err := claim.New("my-installation")
+	assert.NilError(t, err)
+	c.Bundle = Maximal()
+	c.Parameters = map[string]interface{}{"hostname": "localhost"}
+	assert.NilError(t, claims.Store(*c))
+	stored, err := claims.Read("my-installation")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(stored.Bundle.Name, "maximal"))
+
+	creds := StubCredentials(Maximal())
+	assert.Check(t, is.DeepEqual(creds, credentials.Set{"db-password": "stub-db-password", "kubeconfig": "stub-kubeconfig"}))
+
+	d := NewDriver()
+	assert.NilError(t, (&action.Install{Driver: d}).Run(c, creds, ioutil.Discard))
+	assert.Check(t, is.Len(d.Operations(), 1))
+}
@@ -0,0 +1,59 @@
+package bundletest
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Document is a named bundle document of the corpus.
+type Document struct {
+	Name string
+	Data []byte
+}
+
+// ValidDocuments returns bundle documents which decode and validate, to seed
+// fuzz targets and to test the code handling decoded bundles.
+func ValidDocuments() []Document {
+	return []Document{
+		{Name: "minimal", Data: []byte(`{"name":"minimal","version":"0.1.0","invocationImages":[{"imageType":"docker","image":"test/minimal:0.1.0"}]}`)},
+		{Name: "maximal", Data: mustMarshal(Maximal())},
+		{Name: "test bundle", Data: mustMarshal(NewTestBundle())},
+		{Name: "parameters", Data: mustMarshal(NewTestBundle(WithParameters(3)))},
+		{Name: "images and credentials", Data: mustMarshal(NewTestBundle(WithImages(2), WithCredentials(2)))},
+		{Name: "custom extension", Data: mustMarshal(NewTestBundle(WithCustom("com.example.test", map[string]interface{}{
+			"list":   []interface{}{1, "two", 3.5, nil, true},
+			"nested": map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+		})))},
+		{Name: "unicode", Data: []byte(`{"name":"unicode","version":"0.1.0","description":"déploiement 🐳","invocationImages":[{"imageType":"docker","image":"test/unicode:0.1.0"}]}`)},
+	}
+}
+
+// InvalidDocuments returns malformed bundle documents, which the decoder
+// must reject with an error rather than panic, exhaust the stack or decode
+// silently.
+func InvalidDocuments() []Document {
+	return []Document{
+		{Name: "empty", Data: []byte(``)},
+		{Name: "truncated", Data: []byte(`{"name":"truncated","version":`)},
+		{Name: "trailing data", Data: []byte(`{"name":"trailing"}{}`)},
+		{Name: "not an object", Data: []byte(`["name"]`)},
+		{Name: "wrong type", Data: []byte(`{"name":["a"]}`)},
+		{Name: "wrong nested type", Data: []byte(`{"images":{"a":{"size":-1}}}`)},
+		{Name: "huge number", Data: []byte(`{"parameters":{"p":{"type":"int","minValue":1e400}}}`)},
+		{Name: "huge custom number", Data: []byte(`{"custom":{"a":1` + strings.Repeat("0", 400) + `}}`)},
+		{Name: "deeply nested custom array", Data: []byte(`{"custom":{"a":` + strings.Repeat("[", 10000) + strings.Repeat("]", 10000) + `}}`)},
+		{Name: "deeply nested custom object", Data: []byte(`{"custom":` + strings.Repeat(`{"a":`, 10000) + `1` + strings.Repeat("}", 10000) + `}`)},
+		{Name: "invalid UTF-8 value", Data: []byte("{\"name\":\"invalid\xff\"}")},
+		{Name: "invalid UTF-8 key", Data: []byte("{\"custom\":{\"\xc3\x28\":1}}")},
+		{Name: "invalid escape", Data: []byte(`{"name":"\x"}`)},
+		{Name: "control character", Data: []byte("{\"name\":\"a\x00b\"}")},
+	}
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,33 @@
+package bundletest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/app/internal/cnab"
+	"gotest.tools/assert"
+)
+
+func TestValidDocuments(t *testing.T) {
+	for _, doc := range ValidDocuments() {
+		b, err := cnab.Parse(doc.Data)
+		assert.NilError(t, err, doc.Name)
+		assert.Check(t, b.Validate(), doc.Name)
+		_, err = cnab.UnmarshalStrict(doc.Data)
+		assert.Check(t, err, doc.Name)
+		_, err = cnab.ParseReaderLimited(bytes.NewReader(doc.Data), 1<<20)
+		assert.Check(t, err, doc.Name)
+	}
+}
+
+func TestInvalidDocuments(t *testing.T) {
+	for _, doc := range InvalidDocuments() {
+		b, err := cnab.Parse(doc.Data)
+		assert.Check(t, err != nil, doc.Name)
+		assert.Check(t, b == nil, doc.Name)
+		_, err = cnab.UnmarshalStrict(doc.Data)
+		assert.Check(t, err != nil, doc.Name)
+		_, err = cnab.ParseReaderLimited(bytes.NewReader(doc.Data), 1<<20)
+		assert.Check(t, err != nil, doc.Name)
+	}
+}
@@ -0,0 +1,38 @@
+package bundletest
+
+import (
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/deislabs/cnab-go/claim"
+	"github.com/deislabs/cnab-go/credentials"
+	"github.com/docker/app/internal/cnab"
+	"github.com/docker/app/internal/drivers/fake"
+	"github.com/docker/app/internal/store"
+)
+
+// NewDriver returns a fake driver handling the docker and oci invocation
+// images of the test bundles, on which every action succeeds until scripted
+// otherwise, see package fake.
+func NewDriver() *fake.Driver {
+	return fake.New()
+}
+
+// NewClaimStore returns a claim store keeping the claims in memory.
+func NewClaimStore() claim.Store {
+	return claim.NewClaimStore(store.NewMemoryStore())
+}
+
+// NewInstallationStore returns an installation store keeping the
+// installations in memory.
+func NewInstallationStore() store.InstallationStore {
+	return store.NewMemoryInstallationStore()
+}
+
+// StubCredentials returns a credential set giving the value
+// "stub-<name>" to every credential of the bundle.
+func StubCredentials(b *bundle.Bundle) credentials.Set {
+	creds := credentials.Set{}
+	for _, name := range cnab.CredentialNames(b) {
+		creds[name] = "stub-" + name
+	}
+	return creds
+}
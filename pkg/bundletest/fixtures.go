@@ -0,0 +1,104 @@
+package bundletest
+
+import (
+	"github.com/deislabs/cnab-go/bundle"
+)
+
+// Minimal returns the smallest valid bundle: a name, a version and a single
+// docker invocation image.
+func Minimal() *bundle.Bundle {
+	return &bundle.Bundle{
+		Name:    "minimal",
+		Version: "0.1.0",
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{ImageType: "docker", Image: "test/minimal-invoc:0.1.0"}},
+		},
+	}
+}
+
+// Maximal returns a valid bundle setting every field of the bundle format,
+// to check that nothing is lost along the way.
+func Maximal() *bundle.Bundle {
+	intPtr := func(i int) *int { return &i }
+	return &bundle.Bundle{
+		Name:        "maximal",
+		Version:     "1.2.3-beta.1+build.4",
+		Description: "A bundle setting every field",
+		Keywords:    []string{"test", "maximal"},
+		Maintainers: []bundle.Maintainer{
+			{Name: "Jane Doe", Email: "jane.doe@example.com", URL: "https://example.com/jane"},
+			{Name: "Example Org"},
+		},
+		InvocationImages: []bundle.InvocationImage{
+			{BaseImage: bundle.BaseImage{
+				ImageType:     "docker",
+				Image:         "test/maximal-invoc@sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b",
+				OriginalImage: "test/maximal-invoc:1.2.3",
+				Digest:        "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b",
+				Size:          1024,
+				Platform:      &bundle.ImagePlatform{Architecture: "amd64", OS: "linux"},
+				MediaType:     "application/vnd.docker.distribution.manifest.v2+json",
+			}},
+			{BaseImage: bundle.BaseImage{
+				ImageType: "docker",
+				Image:     "test/maximal-invoc-arm64:1.2.3",
+				Platform:  &bundle.ImagePlatform{Architecture: "arm64", OS: "linux"},
+			}},
+		},
+		Images: map[string]bundle.Image{
+			"web": {
+				BaseImage: bundle.BaseImage{
+					ImageType:     "docker",
+					Image:         "test/web@sha256:0b46ee4ce07a2e8fe8c5dfd15e2e0bd05e8bc6a8c3cbf5e5bb2a6f0b4f9cd7f2",
+					OriginalImage: "test/web:1.2.3",
+					Digest:        "sha256:0b46ee4ce07a2e8fe8c5dfd15e2e0bd05e8bc6a8c3cbf5e5bb2a6f0b4f9cd7f2",
+					Size:          2048,
+					MediaType:     "application/vnd.oci.image.manifest.v1+json",
+				},
+				Description: "the web server",
+			},
+			"worker": {
+				BaseImage:   bundle.BaseImage{ImageType: "oci", Image: "test/worker:1.2.3"},
+				Description: "the background worker",
+			},
+		},
+		Actions: map[string]bundle.Action{
+			"migrate": {Modifies: true, Description: "Migrate the database"},
+			"status":  {Stateless: true, Description: "Print the status"},
+		},
+		Parameters: map[string]bundle.ParameterDefinition{
+			"port": {
+				DataType:      "int",
+				Default:       8080,
+				AllowedValues: []interface{}{80, 8080},
+				MinValue:      intPtr(1),
+				MaxValue:      intPtr(65535),
+				Metadata:      &bundle.ParameterMetadata{Description: "the listening port"},
+				Destination:   &bundle.Location{EnvironmentVariable: "PORT"},
+				ApplyTo:       []string{"install", "upgrade"},
+			},
+			"hostname": {
+				DataType:    "string",
+				Required:    true,
+				MinLength:   intPtr(1),
+				MaxLength:   intPtr(253),
+				Destination: &bundle.Location{Path: "/cnab/app/hostname"},
+			},
+			"debug": {
+				DataType:    "bool",
+				Default:     false,
+				Destination: &bundle.Location{EnvironmentVariable: "DEBUG", Path: "/cnab/app/debug"},
+			},
+		},
+		Credentials: map[string]bundle.Location{
+			"kubeconfig":  {Path: "/root/.kube/config"},
+			"db-password": {EnvironmentVariable: "DB_PASSWORD"},
+		},
+		Custom: map[string]interface{}{
+			"com.example.test": map[string]interface{}{
+				"list":   []interface{}{"a", true, 42},
+				"nested": map[string]interface{}{"key": "value"},
+			},
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package bundletest
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/deislabs/cnab-go/bundle"
+	canonicaljson "github.com/docker/go/canonical/json"
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+	"gotest.tools/golden"
+)
+
+// CanonicalJSON marshals the bundle canonically, as it is signed and stored,
+// then indents it so that differences are readable line by line.
+func CanonicalJSON(b *bundle.Bundle) ([]byte, error) {
+	data, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.NewBuffer(nil)
+	if err := json.Indent(out, data, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// Golden compares the canonical form of the bundle to the content of the
+// golden file in the testdata directory, displaying a unified diff of the
+// JSON documents on mismatch. Run the tests with the -test.update-golden
+// flag to update the golden files.
+func Golden(b *bundle.Bundle, filename string) cmp.Comparison {
+	return func() cmp.Result {
+		data, err := CanonicalJSON(b)
+		if err != nil {
+			return cmp.ResultFromError(err)
+		}
+		return golden.String(string(data), filename)()
+	}
+}
+
+// AssertGolden fails the test if the canonical form of the bundle does not
+// match the golden file.
+func AssertGolden(t assert.TestingT, b *bundle.Bundle, filename string, msgAndArgs ...interface{}) {
+	if ht, ok := t.(interface{ Helper() }); ok {
+		ht.Helper()
+	}
+	assert.Assert(t, Golden(b, filename), msgAndArgs...)
+}
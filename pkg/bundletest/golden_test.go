@@ -0,0 +1,22 @@
+package bundletest
+
+import (
+	"flag"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestAssertGolden(t *testing.T) {
+	b := NewTestBundle(WithParameters(1), WithImages(1), WithCredentials(1))
+	AssertGolden(t, b, "test-bundle.golden")
+	if f := flag.Lookup("test.update-golden"); f != nil && f.Value.String() == "true" {
+		return
+	}
+
+	b.Version = "0.2.0"
+	result := Golden(b, "test-bundle.golden")()
+	assert.Assert(t, !result.Success())
+	assert.Check(t, is.Contains(result.(interface{ FailureMessage() string }).FailureMessage(), `+  "version": "0.2.0"`))
+}
@@ -0,0 +1,102 @@
+package bundletest
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"github.com/docker/app/internal/cnab"
+	canonicaljson "github.com/docker/go/canonical/json"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// The invariants below hold for any bundle. They return a descriptive error
+// when violated, so they can be used as properties with testing/quick or any
+// property-based testing framework:
+//
+//	err := quick.Check(func(b bundle.Bundle) bool {
+//		return bundletest.RoundTripStable(&b) == nil
+//	}, nil)
+
+// RoundTripStable checks that decoding the canonical form of a bundle and
+// encoding it again produces the exact same document.
+func RoundTripStable(b *bundle.Bundle) error {
+	first, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %s", err)
+	}
+	decoded, err := cnab.Parse(first)
+	if err != nil {
+		return fmt.Errorf("failed to decode canonical bundle: %s", err)
+	}
+	second, err := canonicaljson.MarshalCanonical(decoded)
+	if err != nil {
+		return fmt.Errorf("failed to marshal decoded bundle: %s", err)
+	}
+	if !bytes.Equal(first, second) {
+		return fmt.Errorf("round trip is not stable:\n%s\n%s", first, second)
+	}
+	return nil
+}
+
+// DigestStable checks that the digest of a bundle does not change when it is
+// computed again, nor after a round trip through its canonical form.
+func DigestStable(b *bundle.Bundle) error {
+	first, err := canonicalDigest(b)
+	if err != nil {
+		return err
+	}
+	second, err := canonicalDigest(b)
+	if err != nil {
+		return err
+	}
+	if first != second {
+		return fmt.Errorf("digest is not stable: %s != %s", first, second)
+	}
+	data, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %s", err)
+	}
+	decoded, err := cnab.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode canonical bundle: %s", err)
+	}
+	third, err := canonicalDigest(decoded)
+	if err != nil {
+		return err
+	}
+	if first != third {
+		return fmt.Errorf("digest changed after a round trip: %s != %s", first, third)
+	}
+	return nil
+}
+
+// ValidateIdempotent checks that validating a bundle does not modify it and
+// always gives the same result.
+func ValidateIdempotent(b *bundle.Bundle) error {
+	before, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %s", err)
+	}
+	first := b.Validate()
+	second := b.Validate()
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		return fmt.Errorf("validation is not idempotent: %v != %v", first, second)
+	}
+	after, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %s", err)
+	}
+	if !bytes.Equal(before, after) {
+		return fmt.Errorf("validation modified the bundle:\n%s\n%s", before, after)
+	}
+	return nil
+}
+
+func canonicalDigest(b *bundle.Bundle) (digest.Digest, error) {
+	data, err := canonicaljson.MarshalCanonical(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal bundle: %s", err)
+	}
+	return digest.FromBytes(data), nil
+}
@@ -0,0 +1,49 @@
+package bundletest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/deislabs/cnab-go/bundle"
+	"gotest.tools/assert"
+)
+
+func TestInvariantsOnBuiltBundles(t *testing.T) {
+	bundles := InvalidBundles()
+	bundles["valid"] = NewTestBundle(WithParameters(2), WithImages(2), WithCredentials(2), WithCustom("com.example", map[string]interface{}{"key": []interface{}{"a", true}}))
+	for name, b := range bundles {
+		assert.Check(t, RoundTripStable(b), name)
+		assert.Check(t, DigestStable(b), name)
+		assert.Check(t, ValidateIdempotent(b), name)
+	}
+}
+
+func TestInvariantsOnGeneratedBundles(t *testing.T) {
+	config := &quick.Config{
+		MaxCount: 50,
+		Values: func(values []reflect.Value, r *rand.Rand) {
+			values[0] = reflect.ValueOf(NewTestBundle(
+				WithName(randomString(r)),
+				WithVersion(randomString(r)),
+				WithParameters(r.Intn(5)),
+				WithImages(r.Intn(5)),
+				WithCredentials(r.Intn(5)),
+			))
+		},
+	}
+	property := func(b *bundle.Bundle) bool {
+		return RoundTripStable(b) == nil && DigestStable(b) == nil && ValidateIdempotent(b) == nil
+	}
+	assert.NilError(t, quick.Check(property, config))
+}
+
+func randomString(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789.-"
+	b := make([]byte, 1+r.Intn(16))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,113 @@
+package telemetry
+
+// Names of the metrics of the bundle operations.
+const (
+	// MetricParseDuration is the histogram of the bundle parsing durations,
+	// in seconds, by result.
+	MetricParseDuration = "bundle_parse_duration_seconds"
+	// MetricValidationFailures counts the validation errors and warnings of
+	// the bundles, by code and severity.
+	MetricValidationFailures = "bundle_validation_failures_total"
+	// MetricRegistryRoundTrips counts the requests sent to the registries,
+	// retries included, by host and status code, "error" if no response
+	// was received.
+	MetricRegistryRoundTrips = "registry_round_trips_total"
+	// MetricPullBytes counts the bytes received from the registries, by
+	// host.
+	MetricPullBytes = "registry_pull_bytes_total"
+	// MetricActionDuration is the histogram of the invocation image run
+	// durations, in seconds, by action and result.
+	MetricActionDuration = "action_duration_seconds"
+)
+
+// Results of the operations, as label values.
+const (
+	ResultSuccess     = "success"
+	ResultFailure     = "failure"
+	ResultInterrupted = "interrupted"
+)
+
+// MetricKind is the type of a metric.
+type MetricKind int
+
+// Metric kinds.
+const (
+	KindCounter MetricKind = iota
+	KindHistogram
+)
+
+// MetricDefinition describes a metric, so it can be declared beforehand by
+// the metrics systems requiring it, like Prometheus.
+type MetricDefinition struct {
+	Name   string
+	Help   string
+	Kind   MetricKind
+	Labels []string
+}
+
+// Definitions are the metrics sent to the Metrics hook.
+var Definitions = []MetricDefinition{
+	{Name: MetricParseDuration, Help: "Duration of the bundle parsing, in seconds.", Kind: KindHistogram, Labels: []string{"result"}},
+	{Name: MetricValidationFailures, Help: "Number of bundle validation errors and warnings.", Kind: KindCounter, Labels: []string{"code", "severity"}},
+	{Name: MetricRegistryRoundTrips, Help: "Number of requests sent to the registries.", Kind: KindCounter, Labels: []string{"host", "status"}},
+	{Name: MetricPullBytes, Help: "Number of bytes received from the registries.", Kind: KindCounter, Labels: []string{"host"}},
+	{Name: MetricActionDuration, Help: "Duration of the invocation image runs, in seconds.", Kind: KindHistogram, Labels: []string{"action", "result"}},
+}
+
+// Labels are the label values of a measure, by label name.
+type Labels map[string]string
+
+// Metrics receives the measures of the bundle operations, see Definitions.
+type Metrics interface {
+	// Add adds the value to the counter.
+	Add(name string, value float64, labels Labels)
+	// Observe records the value in the histogram.
+	Observe(name string, value float64, labels Labels)
+}
+
+var metrics Metrics = nopMetrics{}
+
+// SetMetrics registers the metrics hook of the package, nil disables the
+// metrics.
+func SetMetrics(m Metrics) {
+	mu.Lock()
+	defer mu.Unlock()
+	if m == nil {
+		m = nopMetrics{}
+	}
+	metrics = m
+}
+
+func currentMetrics() Metrics {
+	mu.RLock()
+	defer mu.RUnlock()
+	return metrics
+}
+
+// Add adds the value to a counter of the registered metrics hook.
+func Add(name string, value float64, labels Labels) {
+	currentMetrics().Add(name, value, labels)
+}
+
+// Observe records the value in a histogram of the registered metrics hook.
+func Observe(name string, value float64, labels Labels) {
+	currentMetrics().Observe(name, value, labels)
+}
+
+// Result returns the result label value of an operation, interrupted
+// telling if it was stopped before completion.
+func Result(err error, interrupted bool) string {
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case interrupted:
+		return ResultInterrupted
+	default:
+		return ResultFailure
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Add(string, float64, Labels)     {}
+func (nopMetrics) Observe(string, float64, Labels) {}
@@ -0,0 +1,95 @@
+// Package prommetrics exposes the metrics of the telemetry package to
+// Prometheus.
+package prommetrics
+
+import (
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a telemetry.Metrics hook backed by Prometheus metric vectors,
+// and a prometheus.Collector of them. Register it, then install it with
+// telemetry.SetMetrics.
+type Metrics struct {
+	counters   map[string]*prometheus.CounterVec
+	histograms map[string]*prometheus.HistogramVec
+	labels     map[string][]string
+}
+
+var (
+	_ telemetry.Metrics    = &Metrics{}
+	_ prometheus.Collector = &Metrics{}
+)
+
+// New creates the Prometheus metrics of telemetry.Definitions, in the
+// namespace if not empty. The histograms have the default buckets.
+func New(namespace string) *Metrics {
+	m := &Metrics{
+		counters:   map[string]*prometheus.CounterVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+		labels:     map[string][]string{},
+	}
+	for _, def := range telemetry.Definitions {
+		m.labels[def.Name] = def.Labels
+		switch def.Kind {
+		case telemetry.KindCounter:
+			m.counters[def.Name] = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      def.Name,
+				Help:      def.Help,
+			}, def.Labels)
+		case telemetry.KindHistogram:
+			m.histograms[def.Name] = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      def.Name,
+				Help:      def.Help,
+			}, def.Labels)
+		}
+	}
+	return m
+}
+
+// Add implements telemetry.Metrics. Unknown counters are ignored.
+func (m *Metrics) Add(name string, value float64, labels telemetry.Labels) {
+	if c, ok := m.counters[name]; ok {
+		c.WithLabelValues(m.values(name, labels)...).Add(value)
+	}
+}
+
+// Observe implements telemetry.Metrics. Unknown histograms are ignored.
+func (m *Metrics) Observe(name string, value float64, labels telemetry.Labels) {
+	if h, ok := m.histograms[name]; ok {
+		h.WithLabelValues(m.values(name, labels)...).Observe(value)
+	}
+}
+
+// values returns the label values in the order of the definition, missing
+// ones being empty.
+func (m *Metrics) values(name string, labels telemetry.Labels) []string {
+	names := m.labels[name]
+	values := make([]string, len(names))
+	for i, label := range names {
+		values[i] = labels[label]
+	}
+	return values
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.counters {
+		c.Describe(ch)
+	}
+	for _, h := range m.histograms {
+		h.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.counters {
+		c.Collect(ch)
+	}
+	for _, h := range m.histograms {
+		h.Collect(ch)
+	}
+}
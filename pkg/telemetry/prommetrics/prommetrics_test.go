@@ -0,0 +1,52 @@
+package prommetrics
+
+import (
+	"testing"
+
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func gather(t *testing.T, registry *prometheus.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+	families, err := registry.Gather()
+	assert.NilError(t, err)
+	byName := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	return byName
+}
+
+func TestMetrics(t *testing.T) {
+	m := New("app")
+	registry := prometheus.NewRegistry()
+	assert.NilError(t, registry.Register(m))
+
+	m.Add(telemetry.MetricValidationFailures, 1, telemetry.Labels{"code": "invalid-image", "severity": "error"})
+	m.Add(telemetry.MetricValidationFailures, 1, telemetry.Labels{"code": "invalid-image", "severity": "error"})
+	m.Observe(telemetry.MetricActionDuration, 3, telemetry.Labels{"action": "install", "result": telemetry.ResultSuccess})
+	// Unknown metrics and metrics of the other kind are ignored
+	m.Add("unknown", 1, nil)
+	m.Add(telemetry.MetricActionDuration, 1, nil)
+
+	families := gather(t, registry)
+	assert.Check(t, is.Len(families, 2))
+	failures := families["app_"+telemetry.MetricValidationFailures]
+	assert.Assert(t, failures != nil)
+	assert.Assert(t, is.Len(failures.GetMetric(), 1))
+	assert.Check(t, is.Equal(failures.GetMetric()[0].GetCounter().GetValue(), 2.0))
+	var labels []string
+	for _, label := range failures.GetMetric()[0].GetLabel() {
+		labels = append(labels, label.GetName()+"="+label.GetValue())
+	}
+	assert.Check(t, is.DeepEqual(labels, []string{"code=invalid-image", "severity=error"}))
+
+	durations := families["app_"+telemetry.MetricActionDuration]
+	assert.Assert(t, durations != nil)
+	assert.Check(t, is.Equal(durations.GetMetric()[0].GetHistogram().GetSampleCount(), uint64(1)))
+	assert.Check(t, is.Equal(durations.GetMetric()[0].GetHistogram().GetSampleSum(), 3.0))
+}
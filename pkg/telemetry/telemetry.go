@@ -0,0 +1,181 @@
+// Package telemetry lets the applications embedding docker app correlate
+// the bundle operations with the rest of their telemetry. They register a
+// Logger and a Tracer, adapting their logging library or OpenTelemetry, to
+// receive the logs and spans of the parsing, validation, image resolution
+// and pulling of bundles, of the actions run on the installations and of
+// their invocation image runs. Nothing is logged nor traced until then.
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Names of the spans of the traced operations.
+const (
+	SpanParse    = "cnab.parse"
+	SpanValidate = "cnab.validate"
+	SpanResolve  = "cnab.resolve"
+	SpanPull     = "cnab.pull"
+	SpanDriver   = "driver.run"
+	// SpanAction is an action run on an installation, parent of the spans
+	// of the invocation image runs of its attempts.
+	SpanAction = "runner.action"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+// Log levels.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Fields are the structured data of a log entry, or the attributes of a
+// span.
+type Fields map[string]interface{}
+
+// Logger receives the log entries. The context carries the span of the
+// operation, if traced.
+type Logger interface {
+	Log(ctx context.Context, level Level, msg string, fields Fields)
+}
+
+// Tracer starts the spans of the operations, as OpenTelemetry tracers do.
+type Tracer interface {
+	// Start starts a span, child of the span of the context if any, and
+	// returns a context carrying it.
+	Start(ctx context.Context, name string, attributes Fields) (context.Context, Span)
+}
+
+// Span is a traced operation.
+type Span interface {
+	SetAttributes(attributes Fields)
+	// End ends the span, recording the error of the operation if it
+	// failed.
+	End(err error)
+}
+
+var (
+	mu     sync.RWMutex
+	logger Logger = nopLogger{}
+	tracer Tracer = nopTracer{}
+)
+
+// SetLogger registers the logger of the package, nil disables logging.
+func SetLogger(l Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger = l
+}
+
+// SetTracer registers the tracer of the package, nil disables tracing.
+func SetTracer(t Tracer) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t == nil {
+		t = nopTracer{}
+	}
+	tracer = t
+}
+
+func current() (Logger, Tracer) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger, tracer
+}
+
+// Log sends an entry to the registered logger.
+func Log(ctx context.Context, level Level, msg string, fields Fields) {
+	l, _ := current()
+	l.Log(ctx, level, msg, fields)
+}
+
+// Operation is a traced and logged operation.
+type Operation struct {
+	name    string
+	ctx     context.Context
+	span    Span
+	logger  Logger
+	fields  Fields
+	started time.Time
+}
+
+// Start starts the span of an operation, with the fields as attributes. The
+// returned context carries the span, for the nested operations.
+func Start(ctx context.Context, name string, fields Fields) (context.Context, *Operation) {
+	l, t := current()
+	ctx, span := t.Start(ctx, name, fields)
+	op := &Operation{
+		name:    name,
+		ctx:     ctx,
+		span:    span,
+		logger:  l,
+		fields:  Fields{},
+		started: time.Now(),
+	}
+	for k, v := range fields {
+		op.fields[k] = v
+	}
+	return ctx, op
+}
+
+// SetAttributes adds attributes to the span, also logged when it ends.
+func (o *Operation) SetAttributes(fields Fields) {
+	o.span.SetAttributes(fields)
+	for k, v := range fields {
+		o.fields[k] = v
+	}
+}
+
+// End ends the span, and logs the operation: at debug level if it
+// succeeded, at error level with the error otherwise.
+func (o *Operation) End(err error) {
+	o.span.End(err)
+	fields := Fields{"duration": time.Since(o.started)}
+	for k, v := range o.fields {
+		fields[k] = v
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		o.logger.Log(o.ctx, LevelError, o.name+" failed", fields)
+		return
+	}
+	o.logger.Log(o.ctx, LevelDebug, o.name+" done", fields)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(context.Context, Level, string, Fields) {}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ Fields) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(Fields) {}
+func (nopSpan) End(error)            {}
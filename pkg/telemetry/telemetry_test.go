@@ -0,0 +1,72 @@
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/app/pkg/telemetry"
+	"github.com/docker/app/pkg/telemetry/telemetrytest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestOperation(t *testing.T) {
+	recorder, restore := telemetrytest.Install()
+	defer restore()
+
+	ctx, parent := telemetry.Start(context.Background(), "parent", telemetry.Fields{"bundle": "my-app"})
+	_, child := telemetry.Start(ctx, "child", nil)
+	child.SetAttributes(telemetry.Fields{"digest": "sha256:abc"})
+	child.End(errors.New("boom"))
+	parent.End(nil)
+
+	spans := recorder.Spans()
+	assert.Assert(t, is.Len(spans, 2))
+	assert.Check(t, is.DeepEqual(spans[0], telemetrytest.Span{Name: "parent", Attributes: telemetry.Fields{"bundle": "my-app"}, Ended: true}))
+	assert.Check(t, is.Equal(spans[1].Parent, "parent"))
+	assert.Check(t, is.DeepEqual(spans[1].Attributes, telemetry.Fields{"digest": "sha256:abc"}))
+	assert.Check(t, is.Error(spans[1].Err, "boom"))
+
+	entries := recorder.Entries()
+	assert.Assert(t, is.Len(entries, 2))
+	assert.Check(t, is.Equal(entries[0].Level, telemetry.LevelError))
+	assert.Check(t, is.Equal(entries[0].Msg, "child failed"))
+	assert.Check(t, is.Equal(entries[0].Span, "child"))
+	assert.Check(t, is.Equal(entries[0].Fields["error"], "boom"))
+	assert.Check(t, is.Equal(entries[0].Fields["digest"], "sha256:abc"))
+	assert.Check(t, is.Equal(entries[1].Level, telemetry.LevelDebug))
+	assert.Check(t, is.Equal(entries[1].Msg, "parent done"))
+	assert.Check(t, is.Equal(entries[1].Fields["bundle"], "my-app"))
+}
+
+func TestDisabled(t *testing.T) {
+	telemetry.SetLogger(nil)
+	telemetry.SetTracer(nil)
+	telemetry.SetMetrics(nil)
+	ctx := context.Background()
+	spanCtx, op := telemetry.Start(ctx, "operation", nil)
+	assert.Check(t, is.Equal(spanCtx, ctx))
+	op.End(nil)
+	telemetry.Log(ctx, telemetry.LevelInfo, "nothing", nil)
+	telemetry.Add(telemetry.MetricPullBytes, 1, nil)
+}
+
+func TestMetrics(t *testing.T) {
+	recorder, restore := telemetrytest.Install()
+	defer restore()
+
+	telemetry.Add(telemetry.MetricPullBytes, 10, telemetry.Labels{"host": "a"})
+	telemetry.Add(telemetry.MetricPullBytes, 5, telemetry.Labels{"host": "b"})
+	telemetry.Add(telemetry.MetricPullBytes, 1, telemetry.Labels{"host": "a"})
+	telemetry.Observe(telemetry.MetricActionDuration, 2.5, telemetry.Labels{"action": "install", "result": telemetry.ResultSuccess})
+	assert.Check(t, is.Equal(recorder.Sum(telemetry.MetricPullBytes, telemetry.Labels{"host": "a"}), 11.0))
+	assert.Check(t, is.Equal(recorder.Sum(telemetry.MetricPullBytes, nil), 16.0))
+	assert.Check(t, is.Len(recorder.Measures(telemetry.MetricActionDuration), 1))
+}
+
+func TestResult(t *testing.T) {
+	assert.Check(t, is.Equal(telemetry.Result(nil, true), telemetry.ResultSuccess))
+	assert.Check(t, is.Equal(telemetry.Result(errors.New("boom"), false), telemetry.ResultFailure))
+	assert.Check(t, is.Equal(telemetry.Result(errors.New("boom"), true), telemetry.ResultInterrupted))
+}
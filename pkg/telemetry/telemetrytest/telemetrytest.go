@@ -0,0 +1,179 @@
+// Package telemetrytest records the logs, spans and metrics of the
+// telemetry package, for tests.
+package telemetrytest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/docker/app/pkg/telemetry"
+)
+
+// Span is a recorded span.
+type Span struct {
+	Name string
+	// Parent is the name of the parent span, empty for a root span.
+	Parent     string
+	Attributes telemetry.Fields
+	Ended      bool
+	Err        error
+}
+
+// Entry is a recorded log entry.
+type Entry struct {
+	Level  telemetry.Level
+	Msg    string
+	Fields telemetry.Fields
+	// Span is the name of the span of the context, if any.
+	Span string
+}
+
+// Measure is a recorded counter increment or histogram observation.
+type Measure struct {
+	Name   string
+	Value  float64
+	Labels telemetry.Labels
+}
+
+// Recorder is a telemetry.Logger, telemetry.Tracer and telemetry.Metrics
+// recording the spans, log entries and measures.
+type Recorder struct {
+	mu       sync.Mutex
+	spans    []*Span
+	entries  []Entry
+	measures []Measure
+}
+
+var (
+	_ telemetry.Logger  = &Recorder{}
+	_ telemetry.Tracer  = &Recorder{}
+	_ telemetry.Metrics = &Recorder{}
+)
+
+// Install registers a new recorder as the logger, tracer and metrics hook
+// of the telemetry package, and returns it with a function restoring the
+// no-op ones.
+func Install() (*Recorder, func()) {
+	r := &Recorder{}
+	telemetry.SetLogger(r)
+	telemetry.SetTracer(r)
+	telemetry.SetMetrics(r)
+	return r, func() {
+		telemetry.SetLogger(nil)
+		telemetry.SetTracer(nil)
+		telemetry.SetMetrics(nil)
+	}
+}
+
+type spanKey struct{}
+
+// Start implements telemetry.Tracer.
+func (r *Recorder) Start(ctx context.Context, name string, attributes telemetry.Fields) (context.Context, telemetry.Span) {
+	s := &Span{Name: name, Attributes: telemetry.Fields{}}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		s.Parent = parent.Name
+	}
+	for k, v := range attributes {
+		s.Attributes[k] = v
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), &recordedSpan{recorder: r, span: s}
+}
+
+// Log implements telemetry.Logger.
+func (r *Recorder) Log(ctx context.Context, level telemetry.Level, msg string, fields telemetry.Fields) {
+	e := Entry{Level: level, Msg: msg, Fields: fields}
+	if s, ok := ctx.Value(spanKey{}).(*Span); ok {
+		e.Span = s.Name
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+}
+
+// Add implements telemetry.Metrics.
+func (r *Recorder) Add(name string, value float64, labels telemetry.Labels) {
+	r.record(name, value, labels)
+}
+
+// Observe implements telemetry.Metrics.
+func (r *Recorder) Observe(name string, value float64, labels telemetry.Labels) {
+	r.record(name, value, labels)
+}
+
+func (r *Recorder) record(name string, value float64, labels telemetry.Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.measures = append(r.measures, Measure{Name: name, Value: value, Labels: labels})
+}
+
+// Measures returns the recorded measures of the metric.
+func (r *Recorder) Measures(name string) []Measure {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var measures []Measure
+	for _, m := range r.measures {
+		if m.Name == name {
+			measures = append(measures, m)
+		}
+	}
+	return measures
+}
+
+// Sum returns the sum of the recorded values of the metric having all the
+// given labels, as the value of a counter.
+func (r *Recorder) Sum(name string, labels telemetry.Labels) float64 {
+	var sum float64
+	for _, m := range r.Measures(name) {
+		matches := true
+		for k, v := range labels {
+			if m.Labels[k] != v {
+				matches = false
+			}
+		}
+		if matches {
+			sum += m.Value
+		}
+	}
+	return sum
+}
+
+// Spans returns copies of the recorded spans, in the order they started.
+func (r *Recorder) Spans() []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]Span, len(r.spans))
+	for i, s := range r.spans {
+		spans[i] = *s
+	}
+	return spans
+}
+
+// Entries returns the recorded log entries.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+type recordedSpan struct {
+	recorder *Recorder
+	span     *Span
+}
+
+func (s *recordedSpan) SetAttributes(attributes telemetry.Fields) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	for k, v := range attributes {
+		s.span.Attributes[k] = v
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.Ended = true
+	s.span.Err = err
+}
@@ -209,6 +209,29 @@ func _escFSMustString(useLocal bool, name string) string {
 
 var _escData = map[string]*_escFile{
 
+	"/schemas/bundle_schema_v1.0.0-WD.json": {
+		name:    "bundle_schema_v1.0.0-WD.json",
+		local:   "schemas/bundle_schema_v1.0.0-WD.json",
+		size:    5378,
+		modtime: 1518458244,
+		compressed: `
+H4sIAAAAAAAC/8VYO2/bMBDe9SsCJqNfATpl62MxULQdinQwjOAinm2mFKlSlFPX8H8vRcmyLVM0lTiO
+AC883vG7j/ei19GV+chNFi8wAXJ3RRZap3fD4VMmRb9cHUg1H1IFM90ffRiWa9ekV2oyWig95oJyfChl
+D8vbwWgw6v/6MiisbHdqpjkWmz9/+/jpqtSoZavUiuTjE8Z6u5oqmaLSDDMjW9s1u14ec48qY8b8vujA
+WKYVE/PKWC1FkSdGOiE1SjKtd2x2m4mABLsaT5j4imKuF2bLrdPq8mWoTxummMWKpTrEuNPAb1w9S0Wz
+Vu0JAaVgRXqGm5xzMm1gZBqTY23v+RaDE00CTGjzM3S9BaAbhbMC0PWQ4owJVtCWDXeHhkBkYiljKDTH
+CcyxHecW5tGVjiuAt68FzgoAQZj9SCd1BrZQCpTaI4H/cCfnbifn3wukkyNR8a29jmx6biXnqq9UOPf6
+MsWp4Ive5reJukmOV6cB1wixpesS9+i+pxJASMSloEwh1f48fmO0NYYQwLFCikIz4O+ImFeVJQhwnmmZ
+dMAaHdqrbBGFf3JmnLc6tvv1dv2q56h3ldNtzs4Mg9iLoirpaucOm/lezW2tnwdjQWjO1w28mb+O6kLM
+PMF46OZcubce3lUD7TG7gTFjaWwv5udmzRr9WZoIYsONImyuOTHftHBpNaRic2a4Gm+PD8FKmYlbHbo7
+Y/8CHDPhi3MTve2escSOnKMgv1IOeiZVcvpg5/127YhmNokXZt6Ida5CadzdQXY6Dzy+hke9j7AEKYMu
+Advo/x3Stgz1V+dt1TzPnLiJpGxWSWtTj1JyBOEObw0aOWbhGg3muhXAzjTVPfDMRKVgy0xYXxDLC3i6
+m0/O7GqlfeHSbHo95NyWWZfYvAzkM9J74LmnODUeT0EH7yVrWEAb3yyMA4VtQXcqwN+OCnvsXbaRGKjv
+dTJqoKDhUi2sU1l6835k0GgzmHiflwTEyvM6Ll/IWzfs0O5pw+tTbwg3AdMgbyBN+aqvZWietrBa/6Nx
++nI69GNr62XtuHz5RJvoP9BBw18CFQAA
+`,
+	},
+
 	"/schemas/metadata_schema_v0.1.json": {
 		name:    "metadata_schema_v0.1.json",
 		local:   "schemas/metadata_schema_v0.1.json",
@@ -251,6 +274,7 @@ RsQGAAA=
 var _escDirs = map[string][]os.FileInfo{
 
 	"schemas": {
+		_escData["/schemas/bundle_schema_v1.0.0-WD.json"],
 		_escData["/schemas/metadata_schema_v0.1.json"],
 		_escData["/schemas/metadata_schema_v0.2.json"],
 	},
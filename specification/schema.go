@@ -11,15 +11,29 @@ import (
 
 //go:generate esc -o bindata.go -pkg specification -ignore .*\.go -private -modtime=1518458244 schemas
 
+// BundleSchemaVersion is the latest version of the CNAB bundle schema.
+const BundleSchemaVersion = "v1.0.0-WD"
+
 // Validate uses the jsonschema to validate the configuration
 func Validate(config map[string]interface{}, version string) error {
 	schemaData, err := _escFSByte(false, fmt.Sprintf("/schemas/metadata_schema_%s.json", version))
 	if err != nil {
 		return errors.Errorf("unsupported metadata version: %s", version)
 	}
+	return validate(schemaData, gojsonschema.NewGoLoader(config))
+}
+
+// ValidateBundle uses the CNAB bundle jsonschema to validate a bundle document
+func ValidateBundle(document []byte, version string) error {
+	schemaData, err := _escFSByte(false, fmt.Sprintf("/schemas/bundle_schema_%s.json", version))
+	if err != nil {
+		return errors.Errorf("unsupported bundle schema version: %s", version)
+	}
+	return validate(schemaData, gojsonschema.NewStringLoader(string(document)))
+}
 
+func validate(schemaData []byte, dataLoader gojsonschema.JSONLoader) error {
 	schemaLoader := gojsonschema.NewStringLoader(string(schemaData))
-	dataLoader := gojsonschema.NewGoLoader(config)
 
 	result, err := gojsonschema.Validate(schemaLoader, dataLoader)
 	if err != nil {
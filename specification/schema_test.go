@@ -10,6 +10,16 @@ func TestValidateUnknownVersion(t *testing.T) {
 	assert.Error(t, Validate(nil, "unknown-version"), "unsupported metadata version: unknown-version")
 }
 
+func TestValidateBundleUnknownVersion(t *testing.T) {
+	assert.Error(t, ValidateBundle([]byte(`{}`), "unknown-version"), "unsupported bundle schema version: unknown-version")
+}
+
+func TestValidateBundle(t *testing.T) {
+	assert.NilError(t, ValidateBundle([]byte(`{"name": "my-name", "version": "my-version", "invocationImages": [{"image": "my-image:1.0"}]}`), BundleSchemaVersion))
+	assert.Error(t, ValidateBundle([]byte(`{"name": "my-name", "version": "my-version", "invocationImages": []}`), BundleSchemaVersion),
+		"- invocationImages: Array must have at least 1 items")
+}
+
 func TestValidateInvalidMetadata(t *testing.T) {
 	metadata := map[string]interface{}{
 		"name": "_INVALID",